@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"podmanview/internal/config"
+	"podmanview/internal/storage"
+)
+
+const usageText = `Usage: podmanview [flags] [command] [command flags]
+
+Runs the PodmanView server when no command is given.
+
+Commands:
+  genconfig        Write a default config file (.env or config.yaml)
+  reset-password   Change the system password of a user (runs passwd)
+  export-backup    Export the database and config file to a .tar.gz archive
+  help             Show this help
+
+Flags:
+`
+
+// options holds global command-line flags
+type options struct {
+	configFile string
+	addr       string
+	dataDir    string
+	logLevel   string
+}
+
+// parseFlags parses global flags and returns the remaining arguments (command and its flags)
+func parseFlags(args []string) (*options, []string, error) {
+	opts := &options{}
+
+	fs := flag.NewFlagSet("podmanview", flag.ContinueOnError)
+	fs.StringVar(&opts.configFile, "config", "", "config file path (default: config.yaml or .env in data dir)")
+	fs.StringVar(&opts.addr, "addr", "", "server address, overrides "+config.EnvAddr)
+	fs.StringVar(&opts.dataDir, "data-dir", ".", "directory for the database and config file")
+	fs.StringVar(&opts.logLevel, "log-level", "", "log level: debug, info, warn or error, overrides "+config.EnvLogLevel)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageText)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	return opts, fs.Args(), nil
+}
+
+// configPath returns the config file to load.
+// Without --config, config.yaml in the data dir takes precedence over .env.
+func (o *options) configPath() string {
+	if o.configFile != "" {
+		return o.configFile
+	}
+
+	yamlPath := o.dataPath(yamlConfigFile)
+	if _, err := os.Stat(yamlPath); err == nil {
+		return yamlPath
+	}
+	return o.dataPath(envConfigFile)
+}
+
+// dataPath returns the path of a file inside the data dir
+func (o *options) dataPath(name string) string {
+	return filepath.Join(o.dataDir, name)
+}
+
+// overrides returns config values set by flags
+func (o *options) overrides() map[string]string {
+	values := make(map[string]string)
+	if o.addr != "" {
+		values[config.EnvAddr] = o.addr
+	}
+	if o.logLevel != "" {
+		values[config.EnvLogLevel] = o.logLevel
+	}
+	return values
+}
+
+// runCommand runs a headless administration command and returns the exit code
+func runCommand(opts *options, args []string) int {
+	var err error
+
+	switch args[0] {
+	case "genconfig":
+		err = runGenConfig(opts, args[1:])
+	case "reset-password":
+		err = runResetPassword(args[1:])
+	case "export-backup":
+		err = runExportBackup(opts, args[1:])
+	case "help":
+		fmt.Print(usageText)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\nRun 'podmanview help' for usage.\n", args[0])
+		return 2
+	}
+
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runGenConfig writes a config file with default values and a generated JWT secret.
+// When generating config.yaml next to an existing .env, its settings are converted.
+func runGenConfig(opts *options, args []string) error {
+	fs := flag.NewFlagSet("genconfig", flag.ContinueOnError)
+	format := fs.String("format", "env", "config format: env or yaml")
+	output := fs.String("output", "", "output file (default: .env or config.yaml in data dir)")
+	force := fs.Bool("force", false, "overwrite an existing file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		switch *format {
+		case "env":
+			path = opts.dataPath(envConfigFile)
+		case "yaml":
+			path = opts.dataPath(yamlConfigFile)
+		default:
+			return fmt.Errorf("unknown format: %s (expected env or yaml)", *format)
+		}
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if !*force {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	// Load creates the file with defaults when it doesn't exist
+	if _, err := config.Load(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Config written to %s\n", path)
+	return nil
+}
+
+// runResetPassword changes a local user's password.
+// PodmanView authenticates against system accounts via PAM, so this runs passwd.
+func runResetPassword(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: podmanview reset-password <username>")
+	}
+	username := args[0]
+
+	if _, err := user.Lookup(username); err != nil {
+		return fmt.Errorf("unknown user %q: %w", username, err)
+	}
+
+	cmd := exec.Command("passwd", username)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("passwd failed: %w", err)
+	}
+
+	return nil
+}
+
+// runExportBackup archives the database, as the backup API does, and the config file.
+// With the bolt backend the server must be stopped, since the database is locked while it runs.
+func runExportBackup(opts *options, args []string) error {
+	fs := flag.NewFlagSet("export-backup", flag.ContinueOnError)
+	output := fs.String("output", "", "archive path (default: podmanview-backup-<timestamp>.tar.gz)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("podmanview-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	// Load writes a default config when there is none, which would then be
+	// backed up as if it were the real one
+	configPath := opts.configPath()
+	configData, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("config %s not found (use --data-dir or --config)", configPath)
+	} else if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+
+	backend := cfg.StorageBackend()
+	dbFile := storageFiles[backend]
+	store, err := storage.Open(backend, opts.dataPath(dbFile))
+	if err != nil {
+		return fmt.Errorf("%w (is the server running?)", err)
+	}
+	defer store.Close()
+
+	files := map[string][]byte{filepath.Base(configPath): configData}
+	if err := writeArchive(path, store, backend, files); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	fmt.Printf("Backup written to %s\n", path)
+	return nil
+}
+
+// writeArchive writes a backup archive in the format of the backup API,
+// which POST /api/system/backup/restore takes
+func writeArchive(path string, store storage.Storage, backend string, files map[string][]byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := storage.WriteArchive(f, store, backend, files); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"podmanview/internal/config"
+)
+
+// sdListenFdsStart is the first file descriptor passed by systemd socket activation
+const sdListenFdsStart = 3
+
+// serverListener is an open listener with its serving mode
+type serverListener struct {
+	net.Listener
+	tls  bool
+	desc string
+}
+
+// listenAll opens all server listeners.
+// Sockets passed by systemd socket activation (LISTEN_FDS) replace the configured
+// addresses; they serve HTTPS when a certificate is configured.
+func listenAll(cfg *config.Config) ([]serverListener, error) {
+	activated, err := systemdListeners()
+	if err != nil {
+		return nil, err
+	}
+	if len(activated) > 0 {
+		result := make([]serverListener, len(activated))
+		for i, ln := range activated {
+			result[i] = serverListener{Listener: ln, tls: cfg.TLSEnabled(), desc: "systemd socket " + ln.Addr().String()}
+		}
+		return result, nil
+	}
+
+	var result []serverListener
+	for _, l := range cfg.Listeners() {
+		ln, desc, err := listen(l.Addr)
+		if err != nil {
+			closeListeners(result)
+			return nil, fmt.Errorf("failed to listen on %s: %w", l.Addr, err)
+		}
+		result = append(result, serverListener{Listener: ln, tls: l.TLS, desc: desc})
+	}
+
+	return result, nil
+}
+
+// closeListeners closes already opened listeners after a startup failure
+func closeListeners(listeners []serverListener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// listen creates a listener for a unix:// socket path or a TCP address
+func listen(addr string) (net.Listener, string, error) {
+	if path, ok := strings.CutPrefix(addr, config.UnixSocketPrefix); ok {
+		ln, err := listenUnix(path)
+		if err != nil {
+			return nil, "", err
+		}
+		return ln, "unix socket " + path, nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", err
+	}
+	return ln, addr, nil
+}
+
+// systemdListeners returns the sockets passed by systemd, or nil if the
+// process was not socket-activated
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+
+	// Don't pass the sockets on to child processes (terminals, passwd, etc.)
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, fds)
+	for fd := sdListenFdsStart; fd < sdListenFdsStart+fds; fd++ {
+		f := os.NewFile(uintptr(fd), "systemd-socket-"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("failed to use systemd socket %d: %w", fd, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	return listeners, nil
+}
+
+// listenUnix listens on a unix socket, replacing a stale socket file left by a previous run
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Owner and group only: the reverse proxy should run in the socket's group
+	if err := os.Chmod(path, 0660); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	return ln, nil
+}
@@ -2,71 +2,116 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"strconv"
 	"syscall"
 	"time"
 
 	"podmanview/internal/api"
+	"podmanview/internal/auth"
 	"podmanview/internal/config"
-	"podmanview/internal/events"
+	"podmanview/internal/demo"
+	"podmanview/internal/idle"
 	"podmanview/internal/logger"
 	"podmanview/internal/plugins"
+	"podmanview/internal/plugins/fileshare"
 	"podmanview/internal/plugins/led"
+	"podmanview/internal/plugins/monitors"
 	"podmanview/internal/plugins/picoder"
+	"podmanview/internal/plugins/portmap"
 	"podmanview/internal/plugins/reactor"
 	"podmanview/internal/plugins/temperature"
 	"podmanview/internal/podman"
 	"podmanview/internal/storage"
+	"podmanview/web/static"
 )
 
 const (
 	pluginInitTimeout  = 30 * time.Second
 	pluginStartTimeout = 10 * time.Second
-	shutdownTimeout    = 10 * time.Second
+	pluginStopTimeout  = 10 * time.Second
 	pluginsDBFile      = "podmanview.db"
+	sqliteDBFile       = "podmanview.sqlite"
+	envConfigFile      = ".env"
+	yamlConfigFile     = "config.yaml"
 )
 
 // Version is set at build time via -ldflags "-X main.Version=vX.Y.Z"
 var Version = "dev"
 
 func main() {
-	ctx := context.Background()
+	opts, args, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+
+	// Headless administration commands
+	if len(args) > 0 {
+		os.Exit(runCommand(opts, args))
+	}
 
-	// Load configuration from .env file first (to get log directory)
-	cfg, err := config.Load(".env")
+	// Cancelled on shutdown, stops background tasks of the server and plugins
+	ctx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Load configuration first (to get log directory)
+	cfg, err := config.Load(opts.configPath())
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := cfg.ApplyOverrides(opts.overrides(), config.SourceFlag); err != nil {
+		log.Fatalf("Invalid command-line flags: %v", err)
+	}
 
 	// Initialize logger with configured directory
-	appLogger, err := logger.New(cfg.LogDir(), cfg.LogMaxSize(), cfg.LogMaxBackups())
+	appLogger, err := logger.NewWithSinks(cfg.LogDir(), cfg.LogMaxSize(), cfg.LogMaxBackups(), logger.Sinks{
+		Console:    cfg.LogConsole(),
+		File:       cfg.LogFileFormat(),
+		System:     cfg.LogSystem(),
+		SyslogAddr: cfg.LogSyslogAddr(),
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer appLogger.Close()
+	appLogger.SetRetention(cfg.LogMaxAge(), cfg.LogMaxTotal(), cfg.LogCompress())
+
+	logLevel, err := logger.ParseLevel(cfg.LogLevel())
+	if err != nil {
+		appLogger.Fatalf("Invalid log level: %v", err)
+	}
+	appLogger.SetLevel(logLevel)
 
-	// Create standard logger for compatibility with plugins and other components
-	// It will write to our custom logger's app.log
-	stdLogger := log.New(appLogger.Writer(), "", log.LstdFlags)
+	// Create standard logger for compatibility with plugins and other components.
+	// Their "[name] " prefixes become the module field.
+	stdLogger := appLogger.StdLogger()
+	log.SetOutput(appLogger.Writer())
+	log.SetFlags(0)
 
-	// Generate static files version (timestamp for cache busting)
-	staticVersion := fmt.Sprintf("%d", time.Now().Unix())
-	appLogger.Printf("Static files version: %s", staticVersion)
+	// Static files version (hash of the embedded assets for cache busting)
+	staticVersion := static.Version()
+	appLogger.Info("Static files version", "version", staticVersion)
 
-	appLogger.Printf("Configuration loaded: %s", cfg)
-	appLogger.Printf("Logs directory: %s", cfg.LogDir())
+	appLogger.Info("Configuration loaded", "config", cfg.String())
+	appLogger.Info("Logs directory", "dir", cfg.LogDir())
 
-	// Create Podman client
+	// Create Podman client (mock data in demo mode)
 	var client *podman.Client
 
 	socketPath := cfg.SocketPath()
-	if socketPath != "" {
+	if cfg.DemoMode() {
+		client = podman.NewClientWithHandler(demo.Podman())
+	} else if socketPath != "" {
 		client, err = podman.NewClientWithSocket(socketPath)
 	} else {
 		client, err = podman.NewClient()
@@ -81,28 +126,49 @@ func main() {
 		appLogger.Fatalf("Failed to ping Podman: %v", err)
 	}
 
-	// Create event store
-	eventStore := events.NewStore(100)
+	// Create event store (shared by the API server and plugins)
+	eventStore := api.NewEventStore(cfg, appLogger)
+	if cfg.DemoMode() {
+		demo.SeedEvents(eventStore)
+	}
 
-	// Create or open BoltDB storage for application data
+	// Create or open storage for application data (bbolt or SQLite)
 	// This stores: plugin configs, plugin data, command history, etc.
-	pluginStorage, err := storage.NewBoltStorage(pluginsDBFile)
+	if err := os.MkdirAll(opts.dataDir, 0755); err != nil {
+		appLogger.Fatalf("Failed to create data directory: %v", err)
+	}
+	pluginStorage, migratedFrom, err := openStorage(opts, cfg.StorageBackend())
 	if err != nil {
 		appLogger.Fatalf("Failed to create application storage: %v", err)
 	}
-	defer pluginStorage.Close()
+	if migratedFrom != "" {
+		appLogger.Info("Migrated application data", "from", migratedFrom, "to", cfg.StorageBackend())
+	}
+
+	// Encrypt passwords, tokens and keys stored in the database
+	if cipher := cfg.SecretCipher(); cipher != nil {
+		pluginStorage.SetCipher(cipher)
+		if n, err := pluginStorage.EncryptSensitive(); err != nil {
+			appLogger.Fatalf("Failed to encrypt sensitive storage: %v", err)
+		} else if n > 0 {
+			appLogger.Info("Encrypted plaintext values in sensitive storage buckets", "count", n)
+		}
+	}
+
+	// Version plugin settings together with the config file
+	cfg.SetSettingsSnapshotter(pluginStorage)
 
 	// Initialize default plugin configurations if not present
 	// Check if temperature plugin exists in storage
 	_, err = pluginStorage.GetPluginConfig("temperature")
 	if err == storage.ErrPluginNotFound {
 		// Set default configuration for temperature plugin
-		appLogger.Printf("Initializing default configuration for temperature plugin")
+		appLogger.Info("Initializing default plugin configuration", logger.KeyModule, "temperature")
 		if err := pluginStorage.SetPluginConfig("temperature", &storage.PluginConfig{
 			Enabled: true,
 			Name:    "Temperature Monitoring",
 		}); err != nil {
-			appLogger.Printf("Warning: Failed to set default temperature plugin config: %v", err)
+			appLogger.Warn("Failed to set default plugin config", logger.KeyModule, "temperature", logger.KeyError, err)
 		}
 	}
 
@@ -110,36 +176,73 @@ func main() {
 	_, err = pluginStorage.GetPluginConfig("led")
 	if err == storage.ErrPluginNotFound {
 		// Set default configuration for led plugin
-		appLogger.Printf("Initializing default configuration for LED plugin")
+		appLogger.Info("Initializing default plugin configuration", logger.KeyModule, "LED")
 		if err := pluginStorage.SetPluginConfig("led", &storage.PluginConfig{
 			Enabled: true,
 			Name:    "LED Control",
 		}); err != nil {
-			appLogger.Printf("Warning: Failed to set default led plugin config: %v", err)
+			appLogger.Warn("Failed to set default plugin config", logger.KeyModule, "led", logger.KeyError, err)
 		}
 	}
 
 	// Check if reactor plugin exists in storage
 	_, err = pluginStorage.GetPluginConfig("reactor")
 	if err == storage.ErrPluginNotFound {
-		appLogger.Printf("Initializing default configuration for reactor plugin")
+		appLogger.Info("Initializing default plugin configuration", logger.KeyModule, "reactor")
 		if err := pluginStorage.SetPluginConfig("reactor", &storage.PluginConfig{
 			Enabled: true,
 			Name:    "MQTT Reactor",
 		}); err != nil {
-			appLogger.Printf("Warning: Failed to set default reactor plugin config: %v", err)
+			appLogger.Warn("Failed to set default plugin config", logger.KeyModule, "reactor", logger.KeyError, err)
 		}
 	}
 
 	// Check if picoder plugin exists in storage
 	_, err = pluginStorage.GetPluginConfig("picoder")
 	if err == storage.ErrPluginNotFound {
-		appLogger.Printf("Initializing default configuration for picoder plugin")
+		appLogger.Info("Initializing default plugin configuration", logger.KeyModule, "picoder")
 		if err := pluginStorage.SetPluginConfig("picoder", &storage.PluginConfig{
 			Enabled: true,
 			Name:    "Pi Coding Agent",
 		}); err != nil {
-			appLogger.Printf("Warning: Failed to set default picoder plugin config: %v", err)
+			appLogger.Warn("Failed to set default plugin config", logger.KeyModule, "picoder", logger.KeyError, err)
+		}
+	}
+
+	// Check if fileshare plugin exists in storage. It serves host
+	// directories, so it starts disabled.
+	_, err = pluginStorage.GetPluginConfig("fileshare")
+	if err == storage.ErrPluginNotFound {
+		appLogger.Info("Initializing default plugin configuration", logger.KeyModule, "fileshare")
+		if err := pluginStorage.SetPluginConfig("fileshare", &storage.PluginConfig{
+			Enabled: false,
+			Name:    "File Sharing",
+		}); err != nil {
+			appLogger.Warn("Failed to set default plugin config", logger.KeyModule, "fileshare", logger.KeyError, err)
+		}
+	}
+
+	// Check if monitors plugin exists in storage
+	_, err = pluginStorage.GetPluginConfig("monitors")
+	if err == storage.ErrPluginNotFound {
+		appLogger.Info("Initializing default plugin configuration", logger.KeyModule, "monitors")
+		if err := pluginStorage.SetPluginConfig("monitors", &storage.PluginConfig{
+			Enabled: true,
+			Name:    "Endpoint Monitors",
+		}); err != nil {
+			appLogger.Warn("Failed to set default plugin config", logger.KeyModule, "monitors", logger.KeyError, err)
+		}
+	}
+
+	// Check if portmap plugin exists in storage
+	_, err = pluginStorage.GetPluginConfig("portmap")
+	if err == storage.ErrPluginNotFound {
+		appLogger.Info("Initializing default plugin configuration", logger.KeyModule, "portmap")
+		if err := pluginStorage.SetPluginConfig("portmap", &storage.PluginConfig{
+			Enabled: false,
+			Name:    "Port Mapping",
+		}); err != nil {
+			appLogger.Warn("Failed to set default plugin config", logger.KeyModule, "portmap", logger.KeyError, err)
 		}
 	}
 
@@ -164,18 +267,37 @@ func main() {
 		appLogger.Fatalf("Failed to register picoder plugin: %v", err)
 	}
 
-	appLogger.Printf("Registered %d plugins", pluginRegistry.Count())
+	if err := pluginRegistry.Register(fileshare.New()); err != nil {
+		appLogger.Fatalf("Failed to register fileshare plugin: %v", err)
+	}
+
+	if err := pluginRegistry.Register(monitors.New()); err != nil {
+		appLogger.Fatalf("Failed to register monitors plugin: %v", err)
+	}
+
+	if err := pluginRegistry.Register(portmap.New()); err != nil {
+		appLogger.Fatalf("Failed to register portmap plugin: %v", err)
+	}
+
+	appLogger.Info("Registered plugins", "count", pluginRegistry.Count())
 
 	// Get enabled plugin names from storage
 	enabledPluginNames, err := pluginStorage.ListEnabledPlugins()
 	if err != nil {
 		appLogger.Fatalf("Failed to list enabled plugins: %v", err)
 	}
-	appLogger.Printf("Enabled plugins from storage: %v", enabledPluginNames)
+	appLogger.Info("Enabled plugins from storage", "plugins", enabledPluginNames)
 
-	// Get enabled plugins by config (before Init, so we can't use IsEnabled())
+	// Get enabled plugins by config (before Init, so we can't use IsEnabled()).
+	// Plugins read the host, so none run in demo mode.
+	if cfg.DemoMode() {
+		enabledPluginNames = nil
+	}
 	enabledPlugins := pluginRegistry.EnabledByConfig(enabledPluginNames)
-	appLogger.Printf("Found %d/%d enabled plugins", len(enabledPlugins), pluginRegistry.Count())
+	appLogger.Info("Found enabled plugins", "enabled", len(enabledPlugins), "total", pluginRegistry.Count())
+
+	// Power saving while no client is connected (nil when disabled)
+	idleMonitor := idle.New(cfg.IdleAfter())
 
 	// Initialize enabled plugins with timeout
 	pluginDeps := &plugins.PluginDependencies{
@@ -184,6 +306,7 @@ func main() {
 		EventStore:   eventStore,
 		Logger:       stdLogger,
 		Storage:      pluginStorage,
+		Idle:         idleMonitor,
 	}
 
 	// Set dependencies in registry
@@ -218,76 +341,183 @@ func main() {
 			if err := runner.StartBackgroundTasks(ctx); err != nil {
 				appLogger.Fatalf("Failed to start background tasks for plugin %s: %v", p.Name(), err)
 			}
-			appLogger.Printf("Started background tasks for plugin: %s", p.Name())
+			appLogger.Info("Started background tasks", logger.KeyModule, p.Name())
 		}
 	}
 
 	// Create API server with ALL plugins (not just enabled)
 	// This allows the API to show all available plugins with their enabled status
 	allPlugins := pluginRegistry.All()
-	server := api.NewServerWithPlugins(client, cfg, Version, staticVersion, allPlugins, pluginRegistry, pluginStorage, appLogger)
+	server := api.NewServerWithPlugins(client, cfg, Version, staticVersion, allPlugins, pluginRegistry, pluginStorage, eventStore, appLogger)
+	server.SetIdleMonitor(idleMonitor)
+
+	// Check and compact storage periodically
+	maintenanceCtx, stopMaintenance := context.WithCancel(ctx)
+	defer stopMaintenance()
+	server.StartStorageMaintenance(maintenanceCtx, cfg.StorageMaintenanceInterval())
+	server.StartScheduler(maintenanceCtx)
+	server.StartEventPruning(maintenanceCtx)
+	server.StartEngineEvents(maintenanceCtx)
+	server.StartWebhooks(maintenanceCtx)
+	server.StartNotifications(maintenanceCtx)
+	server.StartHostSampling(maintenanceCtx)
+	server.StartAlertMonitors(maintenanceCtx)
 
 	// Start server
-	addr := cfg.Addr()
-	fmt.Printf("PodmanView starting on %s\n", addr)
+	listeners, err := listenAll(cfg)
+	if err != nil {
+		appLogger.Fatalf("Failed to start listeners: %v", err)
+	}
+	for _, l := range listeners {
+		fmt.Printf("PodmanView starting on %s\n", l.desc)
+	}
 
-	if cfg.NoAuth() {
+	if cfg.DemoMode() {
+		fmt.Println("Demo mode: serving mock data, changes are disabled")
+	} else if cfg.NoAuth() {
 		fmt.Println("WARNING: Authentication is DISABLED!")
+	} else if cfg.AuthMode() == config.AuthModeMTLS {
+		fmt.Println("Client certificate authentication is enabled")
+	}
+
+	// Print access URLs (TCP only)
+	for _, l := range listeners {
+		if tcpAddr, ok := l.Addr().(*net.TCPAddr); ok {
+			scheme := "http"
+			if l.tls {
+				scheme = "https"
+			}
+			printAccessURLs(scheme, strconv.Itoa(tcpAddr.Port))
+		}
 	}
 
-	// Print access URLs
-	port := addr
-	if strings.HasPrefix(port, ":") {
-		port = port[1:]
-	} else if idx := strings.LastIndex(port, ":"); idx != -1 {
-		port = port[idx+1:]
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		appLogger.Fatalf("Failed to configure TLS: %v", err)
 	}
-	printAccessURLs(port)
 
 	// Setup graceful shutdown
+	// One server serves all listeners, so Shutdown closes them together.
+	// Shutdown does not wait for event streams and hijacked WebSocket
+	// connections (terminals), the API server closes them itself.
 	httpServer := &http.Server{
-		Addr:    addr,
-		Handler: server.Router(),
+		Handler:   server.Router(),
+		TLSConfig: tlsConfig,
 	}
+	httpServer.RegisterOnShutdown(server.CloseConnections)
 
 	// Channel to listen for interrupt signals
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
-	// Start HTTP server in goroutine
-	go func() {
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			appLogger.Fatalf("Server failed: %v", err)
-		}
-	}()
+	// Start HTTP server on each listener
+	for _, l := range listeners {
+		go func(l serverListener) {
+			var err error
+			if l.tls {
+				err = httpServer.ServeTLS(l, cfg.TLSCertFile(), cfg.TLSKeyFile())
+			} else {
+				err = httpServer.Serve(l)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				appLogger.Fatalf("Server failed on %s: %v", l.desc, err)
+			}
+		}(l)
+	}
 
-	appLogger.Println("Server started. Press Ctrl+C to stop.")
+	appLogger.Info("Server started. Press Ctrl+C to stop.")
+
+	// Let users on the LAN find the web UI at <name>.local
+	responder := startMDNS(cfg, listeners, appLogger)
+
+	// Tell systemd (Type=notify) that the server is up, and keep its
+	// watchdog (WatchdogSec=) fed while PodmanView is healthy
+	if err := sdNotify("READY=1"); err != nil {
+		appLogger.Warn("Failed to notify systemd", logger.KeyError, err)
+	}
+	go runWatchdog(maintenanceCtx, server.Live, appLogger.Module("systemd"))
 
 	// Wait for interrupt signal
-	<-stop
+	sig := <-stop
 
-	appLogger.Println("Shutting down gracefully...")
+	timeout := cfg.ShutdownTimeout()
+	appLogger.Info("Shutting down gracefully...", "signal", sig.String(), "timeout", timeout)
+	sdNotify("STOPPING=1")
 
-	// Create shutdown context with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
-	defer cancel()
+	// A second signal skips draining
+	go func() {
+		<-stop
+		appLogger.Warn("Forced shutdown")
+		os.Exit(1)
+	}()
+
+	// Tell the network the web UI is going away
+	if responder != nil {
+		responder.Close()
+	}
 
-	// Shutdown HTTP server
+	// Stop accepting connections, close event streams and terminals and
+	// wait for in-flight requests to finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		appLogger.Errorf("HTTP server shutdown error: %v", err)
+		appLogger.Warn("Requests still running after shutdown timeout, closing connections", logger.KeyError, err)
+		httpServer.Close()
+	}
+	if err := server.WaitConnections(shutdownCtx); err != nil {
+		appLogger.Warn("Terminals still open after shutdown timeout", logger.KeyError, err)
 	}
 
+	// Stop background tasks (storage maintenance, event pruning, webhooks,
+	// plugin tasks)
+	stopMaintenance()
+	stopBackground()
+
+	// Archive events before they are lost with the process
+	server.CloseEvents()
+
 	// Stop all enabled plugins in reverse order
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), pluginStopTimeout)
+	defer cancelStop()
 	for i := len(enabledPlugins) - 1; i >= 0; i-- {
 		p := enabledPlugins[i]
-		if err := p.Stop(shutdownCtx); err != nil {
-			appLogger.Errorf("Error stopping plugin %s: %v", p.Name(), err)
+		if err := p.Stop(stopCtx); err != nil {
+			appLogger.Error("Error stopping plugin", logger.KeyModule, p.Name(), logger.KeyError, err)
 		} else {
-			appLogger.Printf("Stopped plugin: %s", p.Name())
+			appLogger.Info("Stopped plugin", logger.KeyModule, p.Name())
+		}
+		pluginRegistry.SetRunning(p.Name(), false)
+	}
+
+	// Flush and close storage after plugins, which may save state on Stop
+	if err := pluginStorage.Close(); err != nil {
+		appLogger.Error("Failed to close storage", logger.KeyError, err)
+	}
+
+	appLogger.Info("Server stopped")
+}
+
+// buildTLSConfig returns TLS settings for the HTTP server, or nil when TLS is disabled.
+// In mtls auth mode clients must present a certificate signed by the configured CA.
+func buildTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.TLSEnabled() {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.AuthMode() == config.AuthModeMTLS {
+		pool, err := auth.LoadClientCAPool(cfg.TLSClientCAFile())
+		if err != nil {
+			return nil, err
 		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 	}
 
-	appLogger.Println("Server stopped")
+	return tlsConfig, nil
 }
 
 // getLocalIPs returns all local IP addresses
@@ -332,16 +562,16 @@ func getLocalIPs() []string {
 }
 
 // printAccessURLs prints all available access URLs
-func printAccessURLs(port string) {
+func printAccessURLs(scheme, port string) {
 	ips := getLocalIPs()
 	if len(ips) == 0 {
-		fmt.Printf("\nOpen http://localhost:%s in your browser\n", port)
+		fmt.Printf("\nOpen %s://localhost:%s in your browser\n", scheme, port)
 		return
 	}
 
 	fmt.Println("\nAccess URLs:")
 	for _, ip := range ips {
-		fmt.Printf("  http://%s:%s\n", ip, port)
+		fmt.Printf("  %s://%s:%s\n", scheme, ip, port)
 	}
 	fmt.Println()
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net"
+
+	"podmanview/internal/config"
+	"podmanview/internal/logger"
+	"podmanview/internal/mdns"
+)
+
+// startMDNS announces the web UI on the local network when enabled. The
+// first TCP listener reachable from other hosts is announced; it returns
+// nil when there is none or the mDNS port can't be used.
+func startMDNS(cfg *config.Config, listeners []serverListener, appLogger *logger.Logger) *mdns.Responder {
+	if !cfg.MDNSEnabled() {
+		return nil
+	}
+	log := appLogger.Module("mdns")
+
+	var announced *serverListener
+	for i, l := range listeners {
+		if tcpAddr, ok := l.Addr().(*net.TCPAddr); ok && !tcpAddr.IP.IsLoopback() {
+			announced = &listeners[i]
+			break
+		}
+	}
+	if announced == nil {
+		log.Warn("mDNS is enabled, but no listener is reachable from the network")
+		return nil
+	}
+
+	tcpAddr := announced.Addr().(*net.TCPAddr)
+	service := mdns.Service{
+		Instance: cfg.MDNSName(),
+		Host:     mdns.HostLabel(cfg.MDNSName()),
+		Port:     tcpAddr.Port,
+		Path:     cfg.BasePath() + "/",
+		TLS:      announced.tls,
+	}
+	if !tcpAddr.IP.IsUnspecified() {
+		service.Addrs = []net.IP{tcpAddr.IP} // bound to one address
+	}
+	responder, err := mdns.Start(service, log)
+	if err != nil {
+		log.Warn("Failed to start mDNS announcement", logger.KeyError, err)
+		return nil
+	}
+	log.Info("Announcing web UI over mDNS", "host", service.Host+".local", "name", service.Instance, "port", service.Port)
+	return responder
+}
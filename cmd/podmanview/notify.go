@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmanview/internal/logger"
+)
+
+// sdNotify sends a state change (READY=1, STOPPING=1, WATCHDOG=1) to systemd.
+// Does nothing when the service is not of Type=notify (no NOTIFY_SOCKET).
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// Abstract namespace sockets start with @
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns how often systemd expects a watchdog ping
+// (WatchdogSec= in the unit), or 0 if the watchdog is disabled
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// runWatchdog pings the systemd watchdog at half its interval while live
+// reports no error, so systemd restarts the service when it hangs or its
+// database stops responding. Stops with ctx.
+func runWatchdog(ctx context.Context, live func(context.Context) error, log *logger.Logger) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := live(ctx); err != nil {
+				log.Warn("Health check failed, skipping watchdog ping", logger.KeyError, err)
+				continue
+			}
+			if err := sdNotify("WATCHDOG=1"); err != nil {
+				log.Warn("Failed to ping systemd watchdog", logger.KeyError, err)
+			}
+		}
+	}
+}
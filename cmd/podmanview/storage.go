@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"podmanview/internal/storage"
+)
+
+// storageFiles maps storage backends to their database file in the data dir
+var storageFiles = map[string]string{
+	storage.BackendBolt:   pluginsDBFile,
+	storage.BackendSQLite: sqliteDBFile,
+}
+
+// openStorage opens the configured storage backend in the data dir.
+// When switching to a backend whose database doesn't exist yet, settings and
+// command history are copied from the other backend's database if present,
+// and the name of that backend is returned.
+func openStorage(opts *options, backend string) (storage.Backend, string, error) {
+	path := opts.dataPath(storageFiles[backend])
+	_, statErr := os.Stat(path)
+
+	store, err := storage.Open(backend, path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !os.IsNotExist(statErr) {
+		return store, "", nil
+	}
+
+	for other, file := range storageFiles {
+		otherPath := opts.dataPath(file)
+		if other == backend {
+			continue
+		}
+		if _, err := os.Stat(otherPath); err != nil {
+			continue
+		}
+
+		src, err := storage.Open(other, otherPath)
+		if err != nil {
+			store.Close()
+			return nil, "", fmt.Errorf("failed to open %s storage for migration: %w", other, err)
+		}
+		err = storage.Migrate(src, store)
+		src.Close()
+		if err != nil {
+			store.Close()
+			os.Remove(path)
+			return nil, "", fmt.Errorf("failed to migrate %s storage: %w", other, err)
+		}
+		return store, other, nil
+	}
+
+	return store, "", nil
+}
@@ -0,0 +1,398 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/events"
+	"podmanview/internal/idle"
+	"podmanview/internal/logger"
+	"podmanview/internal/storage"
+)
+
+// bucket is the storage namespace of alert rules
+const bucket = "alerts"
+
+const (
+	// maxWindow limits event windows and metric durations (1 day)
+	maxWindow = 24 * 60 * 60
+
+	// evaluateInterval is how often metrics are sampled and windows re-evaluated
+	evaluateInterval = 15 * time.Second
+
+	// subscribeBuffer is the number of events queued for evaluation
+	subscribeBuffer = 256
+
+	// actionTimeout limits running the actions of a fired rule
+	actionTimeout = time.Minute
+)
+
+// MetricsFunc returns the current metric values by name (see Metric*)
+type MetricsFunc func() map[string]float64
+
+// Actions runs the actions of fired rules
+type Actions interface {
+	// Notify sends an event to a notification channel, or to all enabled channels if channelID is empty
+	Notify(ctx context.Context, channelID string, event events.Event) error
+
+	// Webhook delivers an event to a webhook
+	Webhook(ctx context.Context, webhookID string, event events.Event) error
+
+	// RestartContainer restarts a container by name or ID
+	RestartContainer(ctx context.Context, nameOrID string) error
+}
+
+// State is the evaluation state of a rule
+type State struct {
+	RuleID   string     `json:"ruleId"`
+	Firing   bool       `json:"firing"`
+	FiredAt  *time.Time `json:"firedAt,omitempty"`
+	Pending  *time.Time `json:"pendingSince,omitempty"` // metric condition true since, not long enough yet
+	Value    float64    `json:"value"`                  // last metric value or event count in the window
+	Silenced bool       `json:"silenced"`
+}
+
+// ruleState is the internal evaluation state of a rule
+type ruleState struct {
+	seen    []time.Time // matching events in the window, oldest first
+	pending time.Time   // metric condition true since
+	firing  bool
+	firedAt time.Time
+	value   float64
+}
+
+// Engine stores alert rules and evaluates them
+type Engine struct {
+	storage    storage.Storage
+	eventStore *events.Store
+	metrics    MetricsFunc
+	actions    Actions
+	logger     *logger.Logger
+	idle       *idle.Monitor // metrics are sampled less often while idle
+	started    time.Time
+
+	mu    sync.Mutex
+	rules map[string]*Rule
+	state map[string]*ruleState
+}
+
+// NewEngine creates an alert engine and loads the stored rules.
+// Fired and resolved alerts are recorded in eventStore.
+func NewEngine(store storage.Storage, eventStore *events.Store, metrics MetricsFunc, actions Actions, appLogger *logger.Logger) (*Engine, error) {
+	e := &Engine{
+		storage:    store,
+		eventStore: eventStore,
+		metrics:    metrics,
+		actions:    actions,
+		logger:     appLogger,
+		started:    time.Now(),
+		rules:      make(map[string]*Rule),
+		state:      make(map[string]*ruleState),
+	}
+
+	data, err := store.List(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert rules: %w", err)
+	}
+	for id, value := range data {
+		var rule Rule
+		if err := json.Unmarshal(value, &rule); err != nil {
+			return nil, fmt.Errorf("failed to load alert rule %s: %w", id, err)
+		}
+		e.rules[id] = &rule
+		e.state[id] = &ruleState{}
+	}
+
+	return e, nil
+}
+
+// List returns all rules, oldest first
+func (e *Engine) List() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	list := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		list = append(list, *rule)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Get returns a rule by ID
+func (e *Engine) Get(id string) (*Rule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rule, ok := e.rules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+// Save validates and stores a rule, resetting its evaluation state.
+// A rule without ID is created.
+func (e *Engine) Save(rule *Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if rule.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		rule.ID = id
+		rule.CreatedAt = time.Now()
+	} else if _, ok := e.rules[rule.ID]; !ok {
+		return ErrNotFound
+	}
+
+	if err := e.storage.SetJSON(bucket, rule.ID, rule); err != nil {
+		return fmt.Errorf("failed to save alert rule: %w", err)
+	}
+	copied := *rule
+	e.rules[rule.ID] = &copied
+	e.state[rule.ID] = &ruleState{}
+	return nil
+}
+
+// Delete removes a rule
+func (e *Engine) Delete(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.rules[id]; !ok {
+		return ErrNotFound
+	}
+	if err := e.storage.Delete(bucket, id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	delete(e.rules, id)
+	delete(e.state, id)
+	return nil
+}
+
+// States returns the evaluation state of all rules
+func (e *Engine) States() []State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	states := make([]State, 0, len(e.state))
+	for id, st := range e.state {
+		s := State{
+			RuleID:   id,
+			Firing:   st.firing,
+			Value:    st.value,
+			Silenced: e.rules[id].Silenced(now),
+		}
+		if st.firing {
+			firedAt := st.firedAt
+			s.FiredAt = &firedAt
+		}
+		if !st.pending.IsZero() && !st.firing {
+			pending := st.pending
+			s.Pending = &pending
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].RuleID < states[j].RuleID })
+	return states
+}
+
+// SetIdleMonitor makes metric sampling slow down while no client is
+// connected. Call before Run.
+func (e *Engine) SetIdleMonitor(m *idle.Monitor) {
+	e.idle = m
+}
+
+// Run evaluates rules until ctx is cancelled: event conditions as events
+// are added to the event store, metric conditions every evaluateInterval
+// (stretched while idle)
+func (e *Engine) Run(ctx context.Context) {
+	sub, unsubscribe := e.eventStore.Subscribe(subscribeBuffer)
+	defer unsubscribe()
+
+	timer := time.NewTimer(e.idle.Interval(evaluateInterval))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub:
+			e.Observe(ctx, event)
+		case <-timer.C:
+			e.evaluate(ctx, time.Now())
+			timer.Reset(e.idle.Interval(evaluateInterval))
+		case <-e.idle.Woken():
+			timer.Reset(evaluateInterval)
+		}
+	}
+}
+
+// Observe counts an event for the event conditions of all rules.
+// Used for events from the event store and for Podman engine events.
+func (e *Engine) Observe(ctx context.Context, event events.Event) {
+	// Alerts don't trigger alerts
+	if strings.HasPrefix(string(event.Type), "alert_") {
+		return
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, rule := range e.rules {
+		c := &rule.Condition
+		if !rule.Enabled || c.Kind != ConditionEvent || !c.matches(&event) {
+			continue
+		}
+		st := e.state[id]
+		st.seen = append(st.seen, now)
+		e.evaluateEvents(ctx, rule, st, now)
+	}
+}
+
+// evaluate checks all rules against current metrics and event windows
+func (e *Engine) evaluate(ctx context.Context, now time.Time) {
+	var values map[string]float64
+	if e.metrics != nil {
+		values = e.metrics()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, rule := range e.rules {
+		st := e.state[id]
+		if !rule.Enabled {
+			*st = ruleState{}
+			continue
+		}
+
+		switch rule.Condition.Kind {
+		case ConditionEvent:
+			// Absent events ("<" conditions) can only be judged after a full window
+			if now.Sub(e.started) >= time.Duration(rule.Condition.Window)*time.Second {
+				e.evaluateEvents(ctx, rule, st, now)
+			}
+		case ConditionMetric:
+			value, ok := values[rule.Condition.Metric]
+			if !ok {
+				continue
+			}
+			e.evaluateMetric(ctx, rule, st, value, now)
+		}
+	}
+}
+
+// evaluateEvents checks an event condition. Must be called with the lock held.
+func (e *Engine) evaluateEvents(ctx context.Context, rule *Rule, st *ruleState, now time.Time) {
+	cutoff := now.Add(-time.Duration(rule.Condition.Window) * time.Second)
+	drop := 0
+	for drop < len(st.seen) && !st.seen[drop].After(cutoff) {
+		drop++
+	}
+	st.seen = st.seen[drop:]
+	st.value = float64(len(st.seen))
+
+	e.transition(ctx, rule, st, rule.Condition.compare(st.value), now)
+}
+
+// evaluateMetric checks a metric condition. Must be called with the lock held.
+func (e *Engine) evaluateMetric(ctx context.Context, rule *Rule, st *ruleState, value float64, now time.Time) {
+	st.value = value
+	if !rule.Condition.compare(value) {
+		st.pending = time.Time{}
+		e.transition(ctx, rule, st, false, now)
+		return
+	}
+
+	if st.pending.IsZero() {
+		st.pending = now
+	}
+	held := now.Sub(st.pending) >= time.Duration(rule.Condition.For)*time.Second
+	e.transition(ctx, rule, st, held, now)
+}
+
+// transition fires or resolves a rule. A silenced rule doesn't fire,
+// it fires after the silence if the condition still holds.
+// Must be called with the lock held.
+func (e *Engine) transition(ctx context.Context, rule *Rule, st *ruleState, active bool, now time.Time) {
+	switch {
+	case active && !st.firing:
+		if rule.Silenced(now) {
+			return
+		}
+		st.firing = true
+		st.firedAt = now
+
+		details := fmt.Sprintf("[%s] %s: %s (value %g)", rule.Severity, rule.Name, rule.Condition.describe(), st.value)
+		e.eventStore.AddWithSeverity(events.EventAlertFired, rule.Severity, "system", "", false, details)
+		e.runActions(ctx, *rule, events.Event{
+			Type:      events.EventAlertFired,
+			Timestamp: now,
+			Username:  "system",
+			Details:   details,
+			Category:  events.CategoryAlert,
+			Severity:  rule.Severity,
+		})
+	case !active && st.firing:
+		st.firing = false
+		details := fmt.Sprintf("[%s] %s: resolved after %s", rule.Severity, rule.Name, now.Sub(st.firedAt).Round(time.Second))
+		e.eventStore.Add(events.EventAlertResolved, "system", "", true, details)
+	}
+}
+
+// runActions runs the actions of a fired rule in the background
+func (e *Engine) runActions(ctx context.Context, rule Rule, event events.Event) {
+	if e.actions == nil || len(rule.Actions) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+		defer cancel()
+
+		for _, action := range rule.Actions {
+			var err error
+			switch action.Type {
+			case ActionNotify:
+				err = e.actions.Notify(ctx, action.Target, event)
+			case ActionWebhook:
+				err = e.actions.Webhook(ctx, action.Target, event)
+			case ActionRestart:
+				err = e.actions.RestartContainer(ctx, action.Target)
+			}
+			if err != nil {
+				e.logger.Warn("Alert rule action failed", "rule", rule.Name, "action", action.Type, "error", err)
+			}
+		}
+	}()
+}
+
+// newID generates a random rule ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
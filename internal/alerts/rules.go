@@ -0,0 +1,277 @@
+// Package alerts evaluates user-defined alert rules over metrics and events
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"podmanview/internal/events"
+)
+
+// Condition kinds
+const (
+	// ConditionEvent counts matching events in a sliding window
+	ConditionEvent = "event"
+
+	// ConditionMetric compares a metric with a threshold for a duration
+	ConditionMetric = "metric"
+)
+
+// Action types
+const (
+	ActionNotify  = "notify"  // send to a notification channel (target = channel ID, empty = all enabled)
+	ActionWebhook = "webhook" // deliver to a webhook (target = webhook ID)
+	ActionRestart = "restart" // restart a container (target = container name or ID)
+)
+
+// podmanEventPrefix is the type prefix of Podman engine events
+const podmanEventPrefix = "podman_"
+
+// Metrics available to metric conditions, in percent
+const (
+	MetricCPU    = "cpu"    // host CPU usage
+	MetricMemory = "memory" // host memory usage
+	MetricDisk   = "disk"   // usage of the fullest filesystem
+)
+
+var (
+	// ErrNotFound is returned for an unknown rule ID
+	ErrNotFound = errors.New("alert rule not found")
+
+	// ErrInvalid is returned by Validate for an invalid rule
+	ErrInvalid = errors.New("invalid alert rule")
+)
+
+// Condition is what triggers a rule
+type Condition struct {
+	Kind string `json:"kind"` // ConditionEvent or ConditionMetric
+
+	// Event conditions: events whose type starts with EventType and whose
+	// details contain Match (case-insensitive), counted over Window seconds.
+	// Podman engine events have the type "podman_<type>_<action>", e.g.
+	// "podman_container_restart", and the container name as details.
+	// Severity optionally only counts events at least as severe.
+	EventType string          `json:"eventType,omitempty"`
+	Match     string          `json:"match,omitempty"`
+	Severity  events.Severity `json:"severity,omitempty"`
+	Window    int             `json:"window,omitempty"`
+
+	// Metric conditions: Metric compared with Threshold for For seconds
+	Metric string `json:"metric,omitempty"`
+	For    int    `json:"for,omitempty"`
+
+	// Operator compares the event count or metric with Threshold: ">", ">=", "<", "<="
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+}
+
+// compare applies the condition operator
+func (c *Condition) compare(value float64) bool {
+	switch c.Operator {
+	case ">":
+		return value > c.Threshold
+	case ">=":
+		return value >= c.Threshold
+	case "<":
+		return value < c.Threshold
+	case "<=":
+		return value <= c.Threshold
+	}
+	return false
+}
+
+// matches reports whether an event counts for an event condition
+func (c *Condition) matches(e *events.Event) bool {
+	if !strings.HasPrefix(string(e.Type), c.EventType) {
+		return false
+	}
+	if c.Severity != "" && e.Severity.Level() < c.Severity.Level() {
+		return false
+	}
+	return c.Match == "" || strings.Contains(strings.ToLower(e.Details), strings.ToLower(c.Match))
+}
+
+// describe returns a readable form of the condition
+func (c *Condition) describe() string {
+	if c.Kind == ConditionEvent {
+		subject := c.EventType + " events"
+		if c.Match != "" {
+			subject += fmt.Sprintf(" matching %q", c.Match)
+		}
+		return fmt.Sprintf("%s %s %g in %s", subject, c.Operator, c.Threshold, time.Duration(c.Window)*time.Second)
+	}
+	return fmt.Sprintf("%s %s %g%% for %s", c.Metric, c.Operator, c.Threshold, time.Duration(c.For)*time.Second)
+}
+
+// knownEventType reports whether an event type prefix matches a type in
+// the event catalog or a Podman engine event
+func knownEventType(prefix string) bool {
+	if strings.HasPrefix(prefix, podmanEventPrefix) || strings.HasPrefix(podmanEventPrefix, prefix) {
+		return true
+	}
+	for _, info := range events.Types() {
+		if strings.HasPrefix(string(info.Type), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Action is run when a rule fires
+type Action struct {
+	Type   string `json:"type"`
+	Target string `json:"target,omitempty"`
+}
+
+// Silence is a window in which a rule doesn't fire: a time range
+// (From/Until, open-ended at the start without From) or a daily range
+// (DailyFrom/DailyUntil, "HH:MM" server local time, may wrap past midnight)
+type Silence struct {
+	From       *time.Time `json:"from,omitempty"`
+	Until      *time.Time `json:"until,omitempty"`
+	DailyFrom  string     `json:"dailyFrom,omitempty"`
+	DailyUntil string     `json:"dailyUntil,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+}
+
+// active reports whether the silence covers t
+func (s *Silence) active(t time.Time) bool {
+	if s.Until != nil && (s.From == nil || !t.Before(*s.From)) && t.Before(*s.Until) {
+		return true
+	}
+	if s.DailyFrom == "" || s.DailyUntil == "" {
+		return false
+	}
+
+	from, err1 := parseClock(s.DailyFrom)
+	until, err2 := parseClock(s.DailyUntil)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if from <= until {
+		return now >= from && now < until
+	}
+	return now >= from || now < until // wraps past midnight
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Rule is a user-defined alert
+type Rule struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Enabled   bool            `json:"enabled"`
+	Severity  events.Severity `json:"severity"` // of the alert_fired event
+	Condition Condition       `json:"condition"`
+	Actions   []Action        `json:"actions"`
+	Silences  []Silence       `json:"silences"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Silenced reports whether one of the rule's silences covers t
+func (r *Rule) Silenced(t time.Time) bool {
+	for i := range r.Silences {
+		if r.Silences[i].active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the rule and fills in defaults
+func (r *Rule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+
+	if r.Severity == "" {
+		r.Severity = events.SeverityWarning
+	}
+	if r.Severity.Level() < 0 {
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalid, r.Severity)
+	}
+
+	c := &r.Condition
+	switch c.Operator {
+	case ">", ">=", "<", "<=":
+	default:
+		return fmt.Errorf("%w: operator must be >, >=, < or <=", ErrInvalid)
+	}
+	switch c.Kind {
+	case ConditionEvent:
+		if c.EventType == "" {
+			return fmt.Errorf("%w: eventType is required", ErrInvalid)
+		}
+		if !knownEventType(c.EventType) {
+			return fmt.Errorf("%w: no event type starts with %q", ErrInvalid, c.EventType)
+		}
+		if c.Severity != "" && c.Severity.Level() < 0 {
+			return fmt.Errorf("%w: unknown event severity %q", ErrInvalid, c.Severity)
+		}
+		if c.Window <= 0 || c.Window > maxWindow {
+			return fmt.Errorf("%w: window must be between 1 and %d seconds", ErrInvalid, maxWindow)
+		}
+		c.Metric, c.For = "", 0
+	case ConditionMetric:
+		switch c.Metric {
+		case MetricCPU, MetricMemory, MetricDisk:
+		default:
+			return fmt.Errorf("%w: unknown metric %q", ErrInvalid, c.Metric)
+		}
+		if c.For < 0 || c.For > maxWindow {
+			return fmt.Errorf("%w: for must be between 0 and %d seconds", ErrInvalid, maxWindow)
+		}
+		c.EventType, c.Match, c.Severity, c.Window = "", "", "", 0
+	default:
+		return fmt.Errorf("%w: condition kind must be %q or %q", ErrInvalid, ConditionEvent, ConditionMetric)
+	}
+
+	for _, a := range r.Actions {
+		switch a.Type {
+		case ActionNotify:
+		case ActionWebhook, ActionRestart:
+			if a.Target == "" {
+				return fmt.Errorf("%w: %s action requires a target", ErrInvalid, a.Type)
+			}
+		default:
+			return fmt.Errorf("%w: unknown action %q", ErrInvalid, a.Type)
+		}
+	}
+
+	for _, s := range r.Silences {
+		if s.From != nil && (s.Until == nil || !s.Until.After(*s.From)) {
+			return fmt.Errorf("%w: silence must end after it starts", ErrInvalid)
+		}
+		if (s.DailyFrom == "") != (s.DailyUntil == "") {
+			return fmt.Errorf("%w: daily silence requires dailyFrom and dailyUntil", ErrInvalid)
+		}
+		if s.DailyFrom != "" {
+			if _, err := parseClock(s.DailyFrom); err != nil {
+				return fmt.Errorf("%w: dailyFrom must be HH:MM", ErrInvalid)
+			}
+			if _, err := parseClock(s.DailyUntil); err != nil {
+				return fmt.Errorf("%w: dailyUntil must be HH:MM", ErrInvalid)
+			}
+		}
+	}
+
+	if r.Actions == nil {
+		r.Actions = []Action{}
+	}
+	if r.Silences == nil {
+		r.Silences = []Silence{}
+	}
+	return nil
+}
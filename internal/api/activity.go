@@ -0,0 +1,345 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+const (
+	defaultActivityDays = 30
+	maxActivityDays     = 365
+
+	// Entries of each user summary
+	activityTopContainers = 5
+	activityRecentLogins  = 5
+
+	// Location lookups of login IPs
+	geoLookupTimeout = 5 * time.Second
+	geoCacheTTL      = 24 * time.Hour
+	geoErrorTTL      = time.Hour // failed lookups are retried after
+)
+
+// containerActivityTypes are the events of users acting on a container,
+// whose details start with the container ID or name
+var containerActivityTypes = map[events.EventType]bool{
+	events.EventContainerStart:    true,
+	events.EventContainerStop:     true,
+	events.EventContainerRestart:  true,
+	events.EventContainerRemove:   true,
+	events.EventContainerCreate:   true,
+	events.EventContainerRestore:  true,
+	events.EventContainerEnv:      true,
+	events.EventContainerUpdate:   true,
+	events.EventContainerTags:     true,
+	events.EventTerminalContainer: true,
+}
+
+// UserActivity summarizes the events of a user
+type UserActivity struct {
+	Username   string    `json:"username"`
+	Actions    int       `json:"actions"`
+	Failed     int       `json:"failed"`
+	LastActive time.Time `json:"lastActive"`
+	// PerDay counts the actions of each day of the period (UTC), oldest
+	// first
+	PerDay        []DayActivity       `json:"perDay"`
+	TopContainers []ContainerActivity `json:"topContainers"`
+	RecentLogins  []LoginActivity     `json:"recentLogins"`
+}
+
+// DayActivity is the number of actions on a day
+type DayActivity struct {
+	Date    string `json:"date"` // 2006-01-02
+	Actions int    `json:"actions"`
+}
+
+// ContainerActivity is the number of actions of a user on a container
+type ContainerActivity struct {
+	ID      string `json:"id,omitempty"` // empty for containers that no longer exist
+	Name    string `json:"name"`
+	Actions int    `json:"actions"`
+}
+
+// LoginActivity is a login attempt
+type LoginActivity struct {
+	Timestamp time.Time `json:"timestamp"`
+	IP        string    `json:"ip"`
+	Success   bool      `json:"success"`
+	// Location is "Local", "Private network", or the city and country from
+	// the lookup service (PODMANVIEW_GEOIP_URL), empty if unknown
+	Location string `json:"location,omitempty"`
+}
+
+// ActivityHandler summarizes the event log by user
+type ActivityHandler struct {
+	client     *podman.Client
+	eventStore *events.Store
+	geo        *geoLocator
+}
+
+// NewActivityHandler creates new user activity handler
+func NewActivityHandler(client *podman.Client, cfg *config.Config, eventStore *events.Store) *ActivityHandler {
+	return &ActivityHandler{
+		client:     client,
+		eventStore: eventStore,
+		geo:        newGeoLocator(cfg.GeoIPURL()),
+	}
+}
+
+// Activity handles GET /api/events/activity?days=30
+// Returns the actions of each user in the last days: per day, on which
+// containers, and their recent logins with IP and location. System events
+// are left out. Only the events kept in memory (PODMANVIEW_EVENTS_MAX) are
+// counted. Users are sorted by actions, most active first.
+func (h *ActivityHandler) Activity(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	days := defaultActivityDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxActivityDays {
+			writeError(w, r, http.StatusBadRequest, "Invalid days, expected 1 to "+strconv.Itoa(maxActivityDays))
+			return
+		}
+		days = n
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, 1-days)
+	eventList, _ := h.eventStore.Query(events.Query{From: from, Limit: math.MaxInt})
+
+	type summary struct {
+		*UserActivity
+		perDay     map[string]int
+		containers map[string]int
+	}
+	byUser := make(map[string]*summary)
+	// Events are newest first
+	for _, e := range eventList {
+		if e.Username == "" || e.Username == "system" {
+			continue
+		}
+		s := byUser[e.Username]
+		if s == nil {
+			s = &summary{
+				UserActivity: &UserActivity{Username: e.Username, LastActive: e.Timestamp},
+				perDay:       make(map[string]int),
+				containers:   make(map[string]int),
+			}
+			byUser[e.Username] = s
+		}
+		s.Actions++
+		if !e.Success {
+			s.Failed++
+		}
+		s.perDay[e.Timestamp.UTC().Format(time.DateOnly)]++
+		if containerActivityTypes[e.Type] {
+			if ref := activityContainer(e.Details); ref != "" {
+				s.containers[ref]++
+			}
+		}
+		if (e.Type == events.EventLogin || e.Type == events.EventLoginFailed) && len(s.RecentLogins) < activityRecentLogins {
+			s.RecentLogins = append(s.RecentLogins, LoginActivity{Timestamp: e.Timestamp, IP: e.IP, Success: e.Success})
+		}
+	}
+
+	var containers []podman.Container
+	if h.client != nil && len(byUser) > 0 {
+		// Without Podman, containers are shown as logged
+		containers, _ = h.client.ListContainers(r.Context())
+	}
+
+	result := make([]UserActivity, 0, len(byUser))
+	for _, s := range byUser {
+		for d := from; !d.After(today); d = d.AddDate(0, 0, 1) {
+			date := d.Format(time.DateOnly)
+			s.PerDay = append(s.PerDay, DayActivity{Date: date, Actions: s.perDay[date]})
+		}
+
+		// Count IDs and names of the same container together
+		merged := make(map[string]*ContainerActivity)
+		for ref, n := range s.containers {
+			c := resolveActivityContainer(ref, containers)
+			key := c.ID
+			if key == "" {
+				key = c.Name
+			}
+			if m, ok := merged[key]; ok {
+				m.Actions += n
+				continue
+			}
+			c.Actions = n
+			merged[key] = &c
+		}
+		s.TopContainers = make([]ContainerActivity, 0, len(merged))
+		for _, c := range merged {
+			s.TopContainers = append(s.TopContainers, *c)
+		}
+		slices.SortFunc(s.TopContainers, func(a, b ContainerActivity) int {
+			if a.Actions != b.Actions {
+				return b.Actions - a.Actions
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+		if len(s.TopContainers) > activityTopContainers {
+			s.TopContainers = s.TopContainers[:activityTopContainers]
+		}
+
+		if s.RecentLogins == nil {
+			s.RecentLogins = []LoginActivity{}
+		}
+		for i := range s.RecentLogins {
+			s.RecentLogins[i].Location = h.geo.locate(r.Context(), s.RecentLogins[i].IP)
+		}
+		result = append(result, *s.UserActivity)
+	}
+	slices.SortFunc(result, func(a, b UserActivity) int {
+		if a.Actions != b.Actions {
+			return b.Actions - a.Actions
+		}
+		return strings.Compare(a.Username, b.Username)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":  from,
+		"days":  days,
+		"users": result,
+	})
+}
+
+// activityContainer returns the container ID or name that the details of
+// a container event start with ("abc123def456", "web (kept in trash)",
+// "web: error")
+func activityContainer(details string) string {
+	ref, _, _ := strings.Cut(details, " ")
+	return strings.TrimSuffix(ref, ":")
+}
+
+// resolveActivityContainer returns the container with the ID (or ID
+// prefix) or name ref, or ref as name if there is none
+func resolveActivityContainer(ref string, containers []podman.Container) ContainerActivity {
+	for _, c := range containers {
+		name := firstOf(c.Names)
+		if name == ref || c.ID == ref || (len(ref) >= 12 && strings.HasPrefix(c.ID, ref)) {
+			return ContainerActivity{ID: shortID(c.ID), Name: name}
+		}
+	}
+	return ContainerActivity{Name: ref}
+}
+
+// geoLocator finds the location of IP addresses. Private addresses are
+// located without lookups; public ones are looked up at the configured
+// URL, which returns JSON with city and country (or country_name), and
+// cached.
+type geoLocator struct {
+	url    string // with {ip} in place of the address, empty = no lookups
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]geoEntry
+}
+
+// geoEntry is a cached lookup result
+type geoEntry struct {
+	location string
+	expires  time.Time
+}
+
+func newGeoLocator(lookupURL string) *geoLocator {
+	return &geoLocator{
+		url:    lookupURL,
+		client: &http.Client{Timeout: geoLookupTimeout},
+		cache:  make(map[string]geoEntry),
+	}
+}
+
+// locate returns the location of an IP address, empty if unknown
+func (g *geoLocator) locate(ctx context.Context, ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	switch {
+	case addr.IsLoopback():
+		return "Local"
+	case addr.IsPrivate() || addr.IsLinkLocalUnicast():
+		return "Private network"
+	case g.url == "":
+		return ""
+	}
+
+	g.mu.Lock()
+	entry, ok := g.cache[addr.String()]
+	g.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.location
+	}
+
+	location, err := g.lookup(ctx, addr)
+	entry = geoEntry{location: location, expires: time.Now().Add(geoCacheTTL)}
+	if err != nil {
+		entry.expires = time.Now().Add(geoErrorTTL)
+	}
+	g.mu.Lock()
+	g.cache[addr.String()] = entry
+	g.mu.Unlock()
+	return location
+}
+
+// lookup asks the lookup service for the location of an address
+func (g *geoLocator) lookup(ctx context.Context, addr netip.Addr) (string, error) {
+	lookupURL := strings.ReplaceAll(g.url, "{ip}", url.PathEscape(addr.String()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lookupURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("location lookup: %s", resp.Status)
+	}
+
+	var result struct {
+		City        string `json:"city"`
+		Country     string `json:"country"`
+		CountryName string `json:"country_name"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil {
+		return "", err
+	}
+	country := result.CountryName
+	if country == "" {
+		country = result.Country
+	}
+	var parts []string
+	for _, part := range []string{result.City, country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", "), nil
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/alerts"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+)
+
+// AlertsHandler handles alert rule configuration
+type AlertsHandler struct {
+	engine     *alerts.Engine
+	eventStore *events.Store
+}
+
+// NewAlertsHandler creates new alerts handler
+func NewAlertsHandler(engine *alerts.Engine, eventStore *events.Store) *AlertsHandler {
+	return &AlertsHandler{engine: engine, eventStore: eventStore}
+}
+
+// available checks that alert rules are available
+func (h *AlertsHandler) available(w http.ResponseWriter, r *http.Request) bool {
+	if h.engine == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return false
+	}
+	return true
+}
+
+// admin checks admin access and that alert rules are available
+func (h *AlertsHandler) admin(w http.ResponseWriter, r *http.Request) (*auth.User, bool) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return nil, false
+	}
+	return user, h.available(w, r)
+}
+
+// Status handles GET /api/alerts
+// Returns the evaluation state of every rule (firing, pending, last value).
+func (h *AlertsHandler) Status(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, h.engine.States())
+}
+
+// List handles GET /api/alerts/rules
+func (h *AlertsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, h.engine.List())
+}
+
+// Create handles POST /api/alerts/rules
+func (h *AlertsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+
+	rule := alerts.Rule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule.ID = ""
+
+	if err := h.engine.Save(&rule); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventAlertUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("created %s", rule.Name))
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// Update handles PUT /api/alerts/rules/{id}
+// The body replaces the rule.
+func (h *AlertsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.engine.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	var rule alerts.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule.ID = existing.ID
+	rule.CreatedAt = existing.CreatedAt
+
+	if err := h.engine.Save(&rule); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventAlertUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("updated %s", rule.Name))
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// Delete handles DELETE /api/alerts/rules/{id}
+func (h *AlertsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.engine.Get(chi.URLParam(r, "id"))
+	if err == nil {
+		err = h.engine.Delete(rule.ID)
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventAlertUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("deleted %s", rule.Name))
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// writeError writes an alert engine error
+func (h *AlertsHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, alerts.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, alerts.ErrInvalid):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	default:
+		writeErr(w, r, err, "")
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"podmanview/internal/alerts"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+const (
+	// diskCheckInterval is how often disk usage is checked for disk_full alerts
+	diskCheckInterval = time.Minute
+
+	// diskFullPercent raises a disk_full event, which is raised again only
+	// after usage dropped below diskClearPercent
+	diskFullPercent  = 90
+	diskClearPercent = 85
+)
+
+// StartAlertMonitors records alert-class events and evaluates alert rules
+// in the background until ctx is cancelled: container_died for containers
+// exiting with an error, container_crash_loop for containers failing
+// repeatedly, disk_full for filesystems running out of space,
+// raid_degraded for md arrays losing a device and registry_token_expiring
+// for registry tokens about to expire
+func (s *Server) StartAlertMonitors(ctx context.Context) {
+	go s.watchEngineEvents(ctx)
+	go s.watchDiskUsage(ctx)
+	go s.registry.watchTokenExpiry(ctx)
+	if s.alerts != nil {
+		go s.alerts.Run(ctx)
+	}
+}
+
+// watchEngineEvents records Podman "died" engine events with a failure
+// exit code, tracks container exits and uptime and passes all engine
+// events to the alert rules
+func (s *Server) watchEngineEvents(ctx context.Context) {
+	ch, unsubscribe := s.engineEvents.subscribe()
+	defer unsubscribe()
+	if containers, err := s.podmanClient.ListContainers(ctx); err == nil {
+		s.uptime.reconcile(containers)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-ch:
+			if details, ok := containerDeathDetails(event); ok {
+				s.eventStore.Add(events.EventContainerDied, "system", "", false, details)
+			}
+			s.crashes.observe(ctx, event)
+			s.uptime.observe(event)
+			if s.alerts != nil {
+				s.alerts.Observe(ctx, engineAlertEvent(event))
+			}
+		}
+	}
+}
+
+// engineAlertEvent converts a Podman engine event for alert rules:
+// type "podman_<type>_<action>", details the name of the object
+func engineAlertEvent(event podman.EngineEvent) events.Event {
+	name := event.Actor.Attributes["name"]
+	if name == "" {
+		name = shortID(event.Actor.ID)
+	}
+	category := events.CategoryOther
+	switch event.Type {
+	case "container":
+		category = events.CategoryContainer
+	case "image":
+		category = events.CategoryImage
+	}
+	return events.Event{
+		Type:      events.EventType("podman_" + event.Type + "_" + event.Action),
+		Timestamp: time.Now(),
+		Username:  "system",
+		Success:   true,
+		Details:   name,
+		Category:  category,
+		Severity:  events.SeverityInfo,
+	}
+}
+
+// containerDeathDetails describes a container that died with an error.
+// Exit code 0 and 143 (SIGTERM from a regular stop) are not reported.
+func containerDeathDetails(event podman.EngineEvent) (string, bool) {
+	if event.Type != "container" || event.Action != "died" {
+		return "", false
+	}
+	code := event.Actor.Attributes["containerExitCode"]
+	if code == "" || code == "0" || code == "143" {
+		return "", false
+	}
+
+	name := event.Actor.Attributes["name"]
+	if name == "" {
+		name = shortID(event.Actor.ID)
+	}
+	return fmt.Sprintf("%s exited with code %s", name, code), true
+}
+
+// watchDiskUsage records a disk_full event when a filesystem fills up and
+// a raid_degraded event when an md array degrades. Disks are checked less
+// often while idle.
+func (s *Server) watchDiskUsage(ctx context.Context) {
+	full := make(map[string]bool)     // mount points over the threshold
+	degraded := make(map[string]bool) // degraded arrays
+
+	for {
+		s.metrics.timeJob(jobDiskUsageCheck, func() bool {
+			for _, disk := range append(getAllDisksUsage(), poolDisks()...) {
+				if disk.Total == 0 {
+					continue
+				}
+				percent := float64(disk.Total-disk.Free) * 100 / float64(disk.Total)
+				switch {
+				case !full[disk.MountPoint] && percent >= diskFullPercent:
+					full[disk.MountPoint] = true
+					details := fmt.Sprintf("%s (%s) is %.0f%% full, %s free", disk.MountPoint, disk.Device, percent, formatBytes(disk.Free))
+					s.eventStore.Add(events.EventDiskFull, "system", "", false, details)
+				case full[disk.MountPoint] && percent < diskClearPercent:
+					delete(full, disk.MountPoint)
+				}
+			}
+			for _, array := range getRaidArrays() {
+				switch {
+				case !degraded[array.Name] && array.Degraded:
+					degraded[array.Name] = true
+					s.eventStore.Add(events.EventRaidDegraded, "system", "", false, raidDegradedDetails(array))
+				case degraded[array.Name] && !array.Degraded:
+					delete(degraded, array.Name)
+				}
+			}
+			return true
+		})
+
+		if !s.idle.Sleep(ctx, diskCheckInterval) {
+			return
+		}
+	}
+}
+
+// alertMetrics returns the host metrics available to alert rules
+func alertMetrics() map[string]float64 {
+	metrics := map[string]float64{
+		alerts.MetricCPU: hostCPU.Usage(),
+	}
+
+	if total, free := getMemoryInfo(); total > 0 {
+		metrics[alerts.MetricMemory] = float64(total-free) * 100 / float64(total)
+	}
+
+	var fullest float64
+	for _, disk := range append(getAllDisksUsage(), poolDisks()...) {
+		if disk.Total > 0 {
+			fullest = max(fullest, float64(disk.Total-disk.Free)*100/float64(disk.Total))
+		}
+	}
+	metrics[alerts.MetricDisk] = fullest
+
+	return metrics
+}
+
+// alertActions runs the actions of alert rules
+type alertActions struct {
+	server *Server
+}
+
+// Notify sends an alert to a notification channel (all enabled if channelID is empty)
+func (a *alertActions) Notify(ctx context.Context, channelID string, event events.Event) error {
+	if a.server.notifications == nil {
+		return fmt.Errorf("notifications not available")
+	}
+	return a.server.notifications.SendTo(ctx, channelID, event)
+}
+
+// Webhook delivers an alert to a webhook
+func (a *alertActions) Webhook(ctx context.Context, webhookID string, event events.Event) error {
+	if a.server.webhooks == nil {
+		return fmt.Errorf("webhooks not available")
+	}
+	return a.server.webhooks.DeliverTo(ctx, webhookID, event)
+}
+
+// RestartContainer restarts a container and records it in the event log
+func (a *alertActions) RestartContainer(ctx context.Context, nameOrID string) error {
+	err := a.server.podmanClient.RestartContainer(ctx, nameOrID)
+	details := nameOrID + " (alert rule action)"
+	if err != nil {
+		details += ": " + err.Error()
+	}
+	a.server.eventStore.Add(events.EventContainerRestart, "system", "", err == nil, details)
+	return err
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"podmanview/internal/podman"
+)
+
+// ContainerAllocation is the resource limits of a running container.
+// Zero means unlimited.
+type ContainerAllocation struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Memory int64   `json:"memory"` // bytes
+	CPUs   float64 `json:"cpus"`
+}
+
+// ResourceAllocation compares the limits of a resource to the host capacity
+type ResourceAllocation struct {
+	Capacity      float64 `json:"capacity"`      // bytes of memory or number of CPUs
+	Allocated     float64 `json:"allocated"`     // sum of the limits
+	Percent       float64 `json:"percent"`       // allocated of capacity
+	Overcommitted bool    `json:"overcommitted"` // limits exceed the capacity
+	Unlimited     int     `json:"unlimited"`     // containers without a limit
+}
+
+// Allocations is the resource reservation overview of the host
+type Allocations struct {
+	Memory     ResourceAllocation    `json:"memory"`
+	CPU        ResourceAllocation    `json:"cpu"`
+	Containers []ContainerAllocation `json:"containers"`
+}
+
+// Allocations handles GET /api/system/allocations
+// Sums up the memory and CPU limits of running containers and compares
+// them to the host. Containers are listed with the largest memory limit first.
+func (h *SystemHandler) Allocations(w http.ResponseWriter, r *http.Request) {
+	containers, err := h.client.ListContainers(r.Context())
+	if err != nil {
+		writeErr(w, r, err, "Failed to list containers")
+		return
+	}
+
+	stats := h.readHostStats()
+	result := Allocations{
+		Memory:     ResourceAllocation{Capacity: float64(stats.MemTotal)},
+		CPU:        ResourceAllocation{Capacity: float64(stats.CPUs)},
+		Containers: []ContainerAllocation{},
+	}
+	for _, c := range containers {
+		if c.IsInfra || c.State != "running" {
+			continue
+		}
+		info, err := h.client.InspectContainer(r.Context(), c.ID)
+		if err != nil {
+			continue // removed meanwhile
+		}
+		allocation := ContainerAllocation{
+			ID:     c.ID,
+			Name:   strings.TrimPrefix(firstOf(c.Names), "/"),
+			Memory: info.HostConfig.Memory,
+			CPUs:   cpuLimit(info),
+		}
+		if allocation.Memory > 0 {
+			result.Memory.Allocated += float64(allocation.Memory)
+		} else {
+			result.Memory.Unlimited++
+		}
+		if allocation.CPUs > 0 {
+			result.CPU.Allocated += allocation.CPUs
+		} else {
+			result.CPU.Unlimited++
+		}
+		result.Containers = append(result.Containers, allocation)
+	}
+	result.CPU.Allocated = math.Round(result.CPU.Allocated*100) / 100
+	result.Memory.summarize()
+	result.CPU.summarize()
+	slices.SortStableFunc(result.Containers, func(a, b ContainerAllocation) int {
+		switch {
+		case a.Memory > b.Memory:
+			return -1
+		case a.Memory < b.Memory:
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// summarize sets the percentage and whether the resource is overcommitted
+func (a *ResourceAllocation) summarize() {
+	if a.Capacity > 0 {
+		a.Percent = math.Round(a.Allocated*1000/a.Capacity) / 10
+		a.Overcommitted = a.Allocated > a.Capacity
+	}
+}
+
+// cpuLimit returns the CPU limit of a container in CPUs, 0 if unlimited.
+// --cpus sets NanoCpus; Podman may report it as a quota instead.
+func cpuLimit(info *podman.ContainerInspect) float64 {
+	host := info.HostConfig
+	if host.NanoCpus > 0 {
+		return float64(host.NanoCpus) / 1e9
+	}
+	if host.CpuQuota > 0 {
+		period := host.CpuPeriod
+		if period <= 0 {
+			period = 100000 // kernel default
+		}
+		return float64(host.CpuQuota) / float64(period)
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"path"
+	"strings"
+)
+
+// iconBaseURL serves icons given by name, as in homepage.icon labels
+// ("jellyfin.png"), from the dashboard-icons collection
+const iconBaseURL = "https://cdn.jsdelivr.net/gh/walkxcode/dashboard-icons/"
+
+// Labels describing the app of a container. homepage.* labels are the
+// ones of the Homepage dashboard; OCI image labels are set by most images.
+const (
+	labelHomepageName        = "homepage.name"
+	labelHomepageIcon        = "homepage.icon"
+	labelHomepageHref        = "homepage.href"
+	labelHomepageDescription = "homepage.description"
+	labelImageTitle          = "org.opencontainers.image.title"
+	labelImageURL            = "org.opencontainers.image.url"
+	labelImageDescription    = "org.opencontainers.image.description"
+)
+
+// AppInfo is what the dashboard shows on the tile of a container: a
+// recognizable name and icon, and where to open the app
+type AppInfo struct {
+	Name        string `json:"name"`
+	Icon        string `json:"icon,omitempty"`     // icon URL
+	Homepage    string `json:"homepage,omitempty"` // URL of the app or its project
+	Description string `json:"description,omitempty"`
+}
+
+// appCatalog maps image names (without registry, namespace and tag) to
+// well-known apps. Icons are dashboard-icons names.
+var appCatalog = map[string]AppInfo{
+	"adguardhome":         {Name: "AdGuard Home", Icon: "adguard-home.png", Homepage: "https://adguard.com/adguard-home/overview.html"},
+	"audiobookshelf":      {Name: "Audiobookshelf", Icon: "audiobookshelf.png", Homepage: "https://www.audiobookshelf.org"},
+	"bazarr":              {Name: "Bazarr", Icon: "bazarr.png", Homepage: "https://www.bazarr.media"},
+	"caddy":               {Name: "Caddy", Icon: "caddy.png", Homepage: "https://caddyserver.com"},
+	"gitea":               {Name: "Gitea", Icon: "gitea.png", Homepage: "https://about.gitea.com"},
+	"grafana":             {Name: "Grafana", Icon: "grafana.png", Homepage: "https://grafana.com"},
+	"home-assistant":      {Name: "Home Assistant", Icon: "home-assistant.png", Homepage: "https://www.home-assistant.io"},
+	"homeassistant":       {Name: "Home Assistant", Icon: "home-assistant.png", Homepage: "https://www.home-assistant.io"},
+	"immich-server":       {Name: "Immich", Icon: "immich.png", Homepage: "https://immich.app"},
+	"influxdb":            {Name: "InfluxDB", Icon: "influxdb.png", Homepage: "https://www.influxdata.com"},
+	"jellyfin":            {Name: "Jellyfin", Icon: "jellyfin.png", Homepage: "https://jellyfin.org"},
+	"lidarr":              {Name: "Lidarr", Icon: "lidarr.png", Homepage: "https://lidarr.audio"},
+	"mariadb":             {Name: "MariaDB", Icon: "mariadb.png", Homepage: "https://mariadb.org"},
+	"minio":               {Name: "MinIO", Icon: "minio.png", Homepage: "https://min.io"},
+	"mongo":               {Name: "MongoDB", Icon: "mongodb.png", Homepage: "https://www.mongodb.com"},
+	"mosquitto":           {Name: "Mosquitto", Icon: "mosquitto.png", Homepage: "https://mosquitto.org"},
+	"mysql":               {Name: "MySQL", Icon: "mysql.png", Homepage: "https://www.mysql.com"},
+	"nextcloud":           {Name: "Nextcloud", Icon: "nextcloud.png", Homepage: "https://nextcloud.com"},
+	"nginx":               {Name: "nginx", Icon: "nginx.png", Homepage: "https://nginx.org"},
+	"nginx-proxy-manager": {Name: "Nginx Proxy Manager", Icon: "nginx-proxy-manager.png", Homepage: "https://nginxproxymanager.com"},
+	"node-red":            {Name: "Node-RED", Icon: "node-red.png", Homepage: "https://nodered.org"},
+	"paperless-ngx":       {Name: "Paperless-ngx", Icon: "paperless-ngx.png", Homepage: "https://docs.paperless-ngx.com"},
+	"pihole":              {Name: "Pi-hole", Icon: "pi-hole.png", Homepage: "https://pi-hole.net"},
+	"plex":                {Name: "Plex", Icon: "plex.png", Homepage: "https://www.plex.tv"},
+	"portainer-ce":        {Name: "Portainer", Icon: "portainer.png", Homepage: "https://www.portainer.io"},
+	"postgres":            {Name: "PostgreSQL", Icon: "postgres.png", Homepage: "https://www.postgresql.org"},
+	"prometheus":          {Name: "Prometheus", Icon: "prometheus.png", Homepage: "https://prometheus.io"},
+	"prowlarr":            {Name: "Prowlarr", Icon: "prowlarr.png", Homepage: "https://prowlarr.com"},
+	"qbittorrent":         {Name: "qBittorrent", Icon: "qbittorrent.png", Homepage: "https://www.qbittorrent.org"},
+	"radarr":              {Name: "Radarr", Icon: "radarr.png", Homepage: "https://radarr.video"},
+	"redis":               {Name: "Redis", Icon: "redis.png", Homepage: "https://redis.io"},
+	"sonarr":              {Name: "Sonarr", Icon: "sonarr.png", Homepage: "https://sonarr.tv"},
+	"syncthing":           {Name: "Syncthing", Icon: "syncthing.png", Homepage: "https://syncthing.net"},
+	"traefik":             {Name: "Traefik", Icon: "traefik.png", Homepage: "https://traefik.io"},
+	"transmission":        {Name: "Transmission", Icon: "transmission.png", Homepage: "https://transmissionbt.com"},
+	"uptime-kuma":         {Name: "Uptime Kuma", Icon: "uptime-kuma.png", Homepage: "https://uptime.kuma.pet"},
+	"vaultwarden":         {Name: "Vaultwarden", Icon: "vaultwarden.png", Homepage: "https://github.com/dani-garcia/vaultwarden"},
+	"wireguard":           {Name: "WireGuard", Icon: "wireguard.png", Homepage: "https://www.wireguard.com"},
+}
+
+// imageAppName returns the name of an image without registry, namespace,
+// tag and digest: "ghcr.io/linuxserver/sonarr:latest" is "sonarr"
+func imageAppName(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	name := path.Base(image)
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+// iconURL returns the URL of an icon label value. Names are served from
+// iconBaseURL, URLs and absolute paths are kept.
+func iconURL(icon string) string {
+	if icon == "" || strings.Contains(icon, "://") || strings.HasPrefix(icon, "/") {
+		return icon
+	}
+	ext := path.Ext(icon)
+	switch ext {
+	case ".png", ".svg", ".webp":
+	default:
+		ext = ".png"
+		icon += ext
+	}
+	return iconBaseURL + ext[1:] + "/" + icon
+}
+
+// resolveAppInfo returns the app of a container from its labels, falling
+// back to the built-in catalog by image name. Returns nil for unknown
+// apps.
+func resolveAppInfo(image string, labels map[string]string) *AppInfo {
+	info, known := appCatalog[imageAppName(image)]
+	for _, field := range []struct {
+		target *string
+		labels []string
+	}{
+		{&info.Name, []string{labelHomepageName, labelImageTitle}},
+		{&info.Icon, []string{labelHomepageIcon}},
+		{&info.Homepage, []string{labelHomepageHref, labelImageURL}},
+		{&info.Description, []string{labelHomepageDescription, labelImageDescription}},
+	} {
+		for i, label := range field.labels {
+			// The first label wins; the others only fill in for the catalog
+			if value := strings.TrimSpace(labels[label]); value != "" && (i == 0 || *field.target == "") {
+				*field.target = value
+				known = true
+				break
+			}
+		}
+	}
+	if !known {
+		return nil
+	}
+	if info.Name == "" {
+		info.Name = imageAppName(image)
+	}
+	info.Icon = iconURL(info.Icon)
+	return &info
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+)
+
+// defaultDetachKeys detach from a container, as in podman attach
+const defaultDetachKeys = "ctrl-p,ctrl-q"
+
+var (
+	errDetachKeys       = apierror.New(http.StatusBadRequest, "Invalid detach keys, use keys like ctrl-p,ctrl-q")
+	errAttachNotRunning = apierror.New(http.StatusConflict, "Container is not running")
+	errAttachNoStdin    = apierror.New(http.StatusConflict, "Container has no open stdin, start it with -i to attach")
+	errAttachPolicy     = apierror.New(http.StatusForbidden, "Attaching is not available under a terminal command policy")
+)
+
+// parseDetachKeys parses a detach key sequence in the format of podman:
+// comma-separated keys, each a character or ctrl- and a letter or one of
+// @ [ \ ] ^ _
+func parseDetachKeys(keys string) ([]byte, error) {
+	var seq []byte
+	for _, key := range strings.Split(keys, ",") {
+		switch {
+		case len(key) == 1:
+			seq = append(seq, key[0])
+		case len(key) == 6 && strings.HasPrefix(strings.ToLower(key), "ctrl-"):
+			c := key[5]
+			switch {
+			case c >= 'a' && c <= 'z':
+				seq = append(seq, c-'a'+1)
+			case c >= 'A' && c <= 'Z':
+				seq = append(seq, c-'A'+1)
+			case strings.IndexByte("@[\\]^_", c) >= 0:
+				seq = append(seq, c-'@')
+			default:
+				return nil, errDetachKeys
+			}
+		default:
+			return nil, errDetachKeys
+		}
+	}
+	return seq, nil
+}
+
+// detachDetector watches the input of an attached session for the detach
+// key sequence. Keys of a started sequence are held back, and passed on
+// when the sequence breaks off.
+type detachDetector struct {
+	keys    []byte
+	matched int // keys of the sequence typed so far
+}
+
+// input returns the input to pass on, and whether the sequence was
+// completed (the rest of the input is dropped)
+func (d *detachDetector) input(data []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c == d.keys[d.matched] {
+			d.matched++
+			if d.matched == len(d.keys) {
+				d.matched = 0
+				return out, true
+			}
+			continue
+		}
+		out = append(out, d.keys[:d.matched]...)
+		d.matched = 0
+		if c == d.keys[0] {
+			d.matched = 1
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, false
+}
+
+// demuxOutput copies the multiplexed stdout and stderr frames of a container
+// without a TTY to fn, with line feeds turned into the CRLF terminals need
+func demuxOutput(r io.Reader, fn func([]byte) error) error {
+	br := bufio.NewReader(r)
+	frame := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return err
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(frame[4:]))
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return err
+		}
+		payload = bytes.ReplaceAll(bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+		if err := fn(payload); err != nil {
+			return err
+		}
+	}
+}
+
+// Attach handles GET /api/containers/{id}/attach (WebSocket, admin)
+// Attaches to the main process of a running container, like podman attach,
+// for containers running interactive programs. Typing the detach keys
+// (?detachKeys=ctrl-p,ctrl-q by default) or closing the connection detaches
+// and leaves the container running. Messages are those of terminals: stdin
+// and resize. The input goes to a program rather than a shell, so it can't
+// be checked against a command policy: users limited by one can't attach.
+func (h *TerminalHandler) Attach(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	policy, err := terminalPolicy(h.storage, user)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read terminal policy")
+		return
+	}
+	if policy.Mode != PolicyOff {
+		writeErr(w, r, errAttachPolicy, "")
+		return
+	}
+
+	keys := firstNonEmpty(r.URL.Query().Get("detachKeys"), defaultDetachKeys)
+	detachKeys, err := parseDetachKeys(keys)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	containerID := chi.URLParam(r, "id")
+	info, err := h.client.InspectContainer(r.Context(), containerID)
+	if err != nil {
+		writeErr(w, r, err, "Failed to inspect container")
+		return
+	}
+	if !info.State.Running {
+		writeErr(w, r, errAttachNotRunning, "")
+		return
+	}
+	if !info.Config.OpenStdin {
+		writeErr(w, r, errAttachNoStdin, "")
+		return
+	}
+
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	name := strings.TrimPrefix(info.Name, "/")
+	reqLog := requestLog(r, h.logger).With("container", name)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	conn, err := h.client.AttachContainer(ctx, info.ID, keys)
+	if err != nil {
+		reqLog.Error("Failed to attach", logger.KeyError, err)
+		writeErr(w, r, err, "Failed to attach to container")
+		return
+	}
+	defer conn.Close()
+
+	wsConn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		reqLog.Error("WebSocket upgrade failed", logger.KeyError, err)
+		return
+	}
+	ws := &terminalSocket{Conn: wsConn}
+	defer ws.Close()
+
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error {
+		ws.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	h.eventStore.Add(events.EventTerminalAttach, user.Username, getClientIP(r), true, name)
+
+	go closeWebSocketOnDrain(ctx, ws.Conn, closing)
+
+	// Ping ticker to keep connection alive
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ws.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+					reqLog.Debug("Failed to send ping", logger.KeyError, err)
+					return
+				}
+			}
+		}
+	}()
+
+	// Read from container -> write to WebSocket
+	go func() {
+		write := func(data []byte) error {
+			return ws.WriteMessage(websocket.TextMessage, data)
+		}
+		var err error
+		if info.Config.Tty {
+			buf := make([]byte, 4096)
+			for err == nil {
+				var n int
+				if n, err = conn.Read(buf); n > 0 {
+					if werr := write(buf[:n]); werr != nil {
+						err = werr
+					}
+				}
+			}
+		} else {
+			err = demuxOutput(conn, write)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !errors.Is(err, io.EOF) {
+			reqLog.Warn("Read from container failed", logger.KeyError, err)
+		}
+		// The process exited, or Podman detached
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "Container detached"))
+		ws.Close()
+	}()
+
+	detector := &detachDetector{keys: detachKeys}
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
+				reqLog.Warn("WebSocket read failed", logger.KeyError, err)
+			}
+			return
+		}
+
+		var msg ExecMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			// Treat as raw stdin
+			msg = ExecMessage{Type: "stdin", Data: string(message)}
+		}
+
+		switch msg.Type {
+		case "stdin":
+			input, detach := detector.input([]byte(msg.Data))
+			if len(input) > 0 {
+				if _, err := conn.Write(input); err != nil {
+					reqLog.Warn("Container write failed", logger.KeyError, err)
+					return
+				}
+			}
+			if detach {
+				cancel()
+				ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "Detached"))
+				return
+			}
+		case "resize":
+			if info.Config.Tty && msg.Cols > 0 && msg.Rows > 0 {
+				if err := h.client.ResizeContainer(ctx, info.ID, msg.Cols, msg.Rows); err != nil {
+					reqLog.Debug("Failed to resize", logger.KeyError, err)
+				}
+			}
+		}
+	}
+}
@@ -2,11 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"podmanview/internal/auth"
 	"podmanview/internal/events"
+	"podmanview/internal/storage"
+)
+
+const (
+	// authStorageNamespace is the storage namespace for authentication data
+	authStorageNamespace = "auth"
+	// jwtKeysStorageKey stores the JWT signing key ring
+	jwtKeysStorageKey = "jwtKeys"
 )
 
 // AuthHandler handles authentication endpoints
@@ -16,19 +25,58 @@ type AuthHandler struct {
 	wsTokenStore *auth.WSTokenStore
 	eventStore   *events.Store
 	rateLimiter  *auth.LoginRateLimiter
+	storage      storage.Storage
 }
 
 // NewAuthHandler creates new auth handler
-func NewAuthHandler(pamAuth *auth.PAMAuth, jwtManager *auth.JWTManager, wsTokenStore *auth.WSTokenStore, eventStore *events.Store) *AuthHandler {
+func NewAuthHandler(pamAuth *auth.PAMAuth, jwtManager *auth.JWTManager, wsTokenStore *auth.WSTokenStore, eventStore *events.Store, store storage.Storage) *AuthHandler {
 	return &AuthHandler{
 		pamAuth:      pamAuth,
 		jwtManager:   jwtManager,
 		wsTokenStore: wsTokenStore,
 		eventStore:   eventStore,
 		rateLimiter:  auth.NewLoginRateLimiter(),
+		storage:      store,
 	}
 }
 
+// loadJWTKeys restores the persisted JWT key ring and creates a signing key
+// for the configured algorithm if the ring doesn't have one yet
+func loadJWTKeys(jwtManager *auth.JWTManager, store storage.Storage) error {
+	if store != nil {
+		var stored []auth.StoredKey
+		err := store.GetSecretJSON(authStorageNamespace, jwtKeysStorageKey, &stored)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		if err := jwtManager.LoadKeys(stored); err != nil {
+			return err
+		}
+	}
+
+	changed, err := jwtManager.EnsureSigningKey()
+	if err != nil {
+		return err
+	}
+	if changed {
+		return saveJWTKeys(jwtManager, store)
+	}
+	return nil
+}
+
+// saveJWTKeys persists the JWT key ring
+func saveJWTKeys(jwtManager *auth.JWTManager, store storage.Storage) error {
+	if store == nil {
+		return nil
+	}
+
+	keys, err := jwtManager.ExportKeys()
+	if err != nil {
+		return err
+	}
+	return store.SetSecretJSON(authStorageNamespace, jwtKeysStorageKey, keys)
+}
+
 // LoginRequest represents login request body
 type LoginRequest struct {
 	Username string `json:"username"`
@@ -115,6 +163,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// LoginDisabled handles POST /api/auth/login when client certificate auth is enabled
+func (h *AuthHandler) LoginDisabled(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusForbidden, LoginResponse{
+		Success: false,
+		Message: "Password login is disabled, use a client certificate",
+	})
+}
+
 // Logout handles POST /api/auth/logout
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
@@ -135,12 +191,15 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if user == nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Not authenticated"})
+		writeError(w, r, http.StatusUnauthorized, "Not authenticated")
 		return
 	}
 
+	// Read-only users can open container terminals with an allow list
+	policy, _ := terminalPolicy(h.storage, user)
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"user": user,
+		"user":              user,
+		"containerTerminal": policy != nil,
 	})
 }
 
@@ -149,21 +208,76 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) WSToken(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if user == nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Not authenticated"})
+		writeError(w, r, http.StatusUnauthorized, "Not authenticated")
 		return
 	}
 
-	// Only admins can get WebSocket tokens (terminals require admin)
-	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+	// Only users who can open terminals get WebSocket tokens (admins, and
+	// read-only users with an allow list)
+	policy, err := terminalPolicy(h.storage, user)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read terminal policy")
+		return
+	}
+	if policy == nil {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
-	token, err := h.wsTokenStore.Generate(user.Username)
+	token, err := h.wsTokenStore.Generate(user.Username, clientFingerprint(r))
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		writeError(w, r, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"token": token})
 }
+
+// JWKS handles GET /api/auth/jwks
+// Publishes public keys so other services can validate tokens (asymmetric algorithms only)
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": h.jwtManager.PublicKeys(),
+	})
+}
+
+// Keys handles GET /api/auth/keys
+func (h *AuthHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"algorithm": h.jwtManager.Algorithm(),
+		"keys":      h.jwtManager.Keys(),
+	})
+}
+
+// RotateKey handles POST /api/auth/keys/rotate
+// Generates a new active signing key; tokens signed with previous keys stay valid
+// until those keys are rotated out of the ring
+func (h *AuthHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	key, err := h.jwtManager.Rotate()
+	if err != nil {
+		h.eventStore.Add(events.EventKeyRotate, user.Username, getClientIP(r), false, "")
+		writeError(w, r, http.StatusInternalServerError, "Failed to rotate key: "+err.Error())
+		return
+	}
+
+	if err := saveJWTKeys(h.jwtManager, h.storage); err != nil {
+		h.eventStore.Add(events.EventKeyRotate, user.Username, getClientIP(r), false, key.ID)
+		writeError(w, r, http.StatusInternalServerError, "Failed to save keys: "+err.Error())
+		return
+	}
+
+	h.eventStore.Add(events.EventKeyRotate, user.Username, getClientIP(r), true, key.ID)
+	writeJSON(w, http.StatusOK, key)
+}
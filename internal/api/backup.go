@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/idle"
+	"podmanview/internal/logger"
+	"podmanview/internal/storage"
+)
+
+const (
+	// maxRestoreUpload limits the size of an uploaded backup archive
+	maxRestoreUpload = 512 << 20 // 512 MB
+
+	// maxBackupEvents limits the number of events included in a backup
+	maxBackupEvents = 10000
+
+	// eventsArchiveFile is the name of the events file inside a backup archive
+	eventsArchiveFile = "events.json"
+)
+
+// BackupHandler handles storage backup, restore and maintenance
+type BackupHandler struct {
+	storage    storage.Storage
+	config     *config.Config
+	eventStore *events.Store
+	logger     *logger.Logger
+	metrics    *selfMetrics // times maintenance runs, nil outside a server
+
+	mu              sync.Mutex
+	lastMaintenance *storage.MaintenanceResult
+}
+
+// NewBackupHandler creates new backup handler
+func NewBackupHandler(store storage.Storage, cfg *config.Config, eventStore *events.Store, appLogger *logger.Logger) *BackupHandler {
+	return &BackupHandler{
+		storage:    store,
+		config:     cfg,
+		eventStore: eventStore,
+		logger:     appLogger,
+	}
+}
+
+// Download handles GET /api/system/backup
+// Streams a .tar.gz archive with a consistent database snapshot
+// (plugin configs, plugin data, command history) and the event log.
+func (h *BackupHandler) Download(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	filename := backupFilename(time.Now())
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	if err := h.writeArchive(w); err != nil {
+		// Headers are already sent, the client gets a truncated archive
+		requestLog(r, h.logger).Error("Failed to write backup archive", "error", err)
+		h.eventStore.Add(events.EventStorageBackup, user.Username, getClientIP(r), false, err.Error())
+		return
+	}
+
+	h.eventStore.Add(events.EventStorageBackup, user.Username, getClientIP(r), true, filename)
+}
+
+// writeArchive writes a backup archive of the storage and the event log
+func (h *BackupHandler) writeArchive(w io.Writer) error {
+	eventsData, err := json.Marshal(h.eventStore.GetLast(maxBackupEvents))
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{eventsArchiveFile: eventsData}
+	return storage.WriteArchive(w, h.storage, h.config.StorageBackend(), files)
+}
+
+// backupFilename returns the name of a backup archive created at t
+func backupFilename(t time.Time) string {
+	return fmt.Sprintf("podmanview-backup-%s.tar.gz", t.Format("20060102-150405"))
+}
+
+// Stats handles GET /api/system/storage
+// Returns the database file size, space used per bucket and the last maintenance result.
+func (h *BackupHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	stats, err := h.storage.Stats()
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	last := h.lastMaintenance
+	h.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"stats":           stats,
+		"lastMaintenance": last,
+	})
+}
+
+// Maintenance handles POST /api/system/storage/maintenance
+// Runs an integrity check and compacts the database if it is intact.
+func (h *BackupHandler) Maintenance(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	result, err := h.RunMaintenance()
+	if err != nil {
+		h.eventStore.Add(events.EventStorageMaint, user.Username, getClientIP(r), false, err.Error())
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.eventStore.Add(events.EventStorageMaint, user.Username, getClientIP(r), len(result.Errors) == 0, maintenanceDetails(result))
+	writeJSON(w, http.StatusOK, result)
+}
+
+// RunMaintenance checks and compacts the storage, logs and remembers the result.
+// Also called periodically from main.
+func (h *BackupHandler) RunMaintenance() (*storage.MaintenanceResult, error) {
+	var result *storage.MaintenanceResult
+	var err error
+	h.metrics.timeJob(jobStorageMaintain, func() bool {
+		result, err = storage.Maintain(h.storage)
+		return err == nil && len(result.Errors) == 0
+	})
+	if err != nil {
+		h.logger.Error("Storage maintenance failed", "error", err)
+		return nil, err
+	}
+
+	if len(result.Errors) > 0 {
+		h.logger.Error("Storage integrity check failed, compaction skipped", "errors", strings.Join(result.Errors, "; "))
+	} else {
+		h.logger.Info("Storage maintenance", "result", maintenanceDetails(result))
+	}
+
+	h.mu.Lock()
+	h.lastMaintenance = result
+	h.mu.Unlock()
+
+	return result, nil
+}
+
+// ScheduleMaintenance runs RunMaintenance every interval until ctx is
+// cancelled. While idle, maintenance waits until a client connects.
+func (h *BackupHandler) ScheduleMaintenance(ctx context.Context, interval time.Duration, idleMonitor *idle.Monitor) {
+	idleMonitor.RunPeriodic(ctx, interval, func() {
+		h.RunMaintenance()
+	})
+}
+
+// maintenanceDetails formats a maintenance result for logs and events
+func maintenanceDetails(result *storage.MaintenanceResult) string {
+	if len(result.Errors) > 0 {
+		return fmt.Sprintf("integrity check failed: %d errors", len(result.Errors))
+	}
+	return fmt.Sprintf("compacted %d -> %d bytes, %d expired keys removed", result.SizeBefore, result.SizeAfter, result.Expired)
+}
+
+// Restore handles POST /api/system/backup/restore
+// Expects a multipart form with the archive in the "file" field.
+// The archive is validated before any data is replaced.
+func (h *BackupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRestoreUpload)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Archive too large or missing file field")
+		return
+	}
+	defer file.Close()
+
+	manifest, files, err := storage.RestoreArchive(file, h.storage)
+	if err != nil {
+		h.eventStore.Add(events.EventStorageRestore, user.Username, getClientIP(r), false, err.Error())
+		if errors.Is(err, storage.ErrInvalidArchive) {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeErr(w, r, err, "")
+		return
+	}
+
+	if data, ok := files[eventsArchiveFile]; ok {
+		var restored []events.Event
+		if err := json.Unmarshal(data, &restored); err != nil {
+			requestLog(r, h.logger).Warn("Backup restore: skipping invalid events file", "error", err)
+		} else {
+			h.eventStore.Restore(restored)
+		}
+	}
+
+	details := fmt.Sprintf("backend=%s created=%s", manifest.Backend, manifest.CreatedAt.Format(time.RFC3339))
+	h.eventStore.Add(events.EventStorageRestore, user.Username, getClientIP(r), true, details)
+	requestLog(r, h.logger).Info("Storage restored from backup", "backup", details)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"restored":        true,
+		"backend":         manifest.Backend,
+		"createdAt":       manifest.CreatedAt,
+		"restartRequired": true, // plugins keep their loaded settings until restart
+	})
+}
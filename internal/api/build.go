@@ -0,0 +1,448 @@
+package api
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+const (
+	// maxBuildRequest limits the size of a build request body
+	maxBuildRequest = 64 << 10
+
+	// buildCloneTimeout limits cloning the repository of a build
+	buildCloneTimeout = 5 * time.Minute
+
+	// gitProtocols are the transports git may use to clone, not file:// or
+	// ext:: commands
+	gitProtocols = "http:https:ssh:git"
+)
+
+var (
+	// gitURLRe matches the repository URLs of builds: https://, http://,
+	// ssh:// and git:// URLs or user@host:path
+	gitURLRe = regexp.MustCompile(`^((https?|ssh|git)://[A-Za-z0-9]|[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:)[^\s]*$`)
+
+	// gitRefRe matches branch, tag and commit names; not an option
+	gitRefRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._/-]*$`)
+
+	// buildArgRe matches the names of build arguments
+	buildArgRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+var (
+	errBuildURL        = apierror.New(http.StatusBadRequest, "Invalid repository URL, use https://, http://, ssh://, git:// or user@host:path")
+	errBuildRef        = apierror.New(http.StatusBadRequest, "Invalid ref")
+	errBuildContext    = apierror.New(http.StatusBadRequest, "Invalid context directory")
+	errBuildDockerfile = apierror.New(http.StatusBadRequest, "Invalid Dockerfile path")
+	errBuildTag        = apierror.New(http.StatusBadRequest, "Image tag is required")
+	errBuildArg        = apierror.New(http.StatusBadRequest, "Invalid build argument name")
+	errNoGit           = apierror.New(http.StatusNotImplemented, "git is not installed on the host")
+)
+
+// BuildHandler builds images from Git repositories, cloned on the host,
+// and runs the saved builds
+type BuildHandler struct {
+	client     *podman.Client
+	storage    storage.Storage
+	eventStore *events.Store
+	drainer    *drainer
+
+	mu      sync.Mutex      // guards running
+	running map[string]bool // IDs of the saved builds running
+	saveMu  sync.Mutex      // serializes changes of saved builds
+}
+
+// NewBuildHandler creates new build handler
+func NewBuildHandler(client *podman.Client, store storage.Storage, eventStore *events.Store, drainer *drainer) *BuildHandler {
+	return &BuildHandler{client: client, storage: store, eventStore: eventStore, drainer: drainer, running: make(map[string]bool)}
+}
+
+// BuildRequest is the repository and options of a build
+type BuildRequest struct {
+	URL        string            `json:"url"`                  // https, http, ssh or git URL of the repository
+	Ref        string            `json:"ref,omitempty"`        // branch, tag or commit, the default branch if empty
+	Context    string            `json:"context,omitempty"`    // subdirectory of the build context, the root if empty
+	Dockerfile string            `json:"dockerfile,omitempty"` // relative to the context, Containerfile or Dockerfile if empty
+	Tag        string            `json:"tag"`                  // name of the image, e.g. localhost/app:latest
+	BuildArgs  map[string]string `json:"buildArgs,omitempty"`
+	Platform   string            `json:"platform,omitempty"` // os/architecture[/variant], the host's if empty
+}
+
+// validate checks a build request before anything is cloned
+func (b *BuildRequest) validate() error {
+	if !gitURLRe.MatchString(b.URL) {
+		return errBuildURL
+	}
+	if b.Ref != "" && (!gitRefRe.MatchString(b.Ref) || strings.Contains(b.Ref, "..")) {
+		return errBuildRef
+	}
+	if b.Context != "" && !filepath.IsLocal(b.Context) {
+		return errBuildContext
+	}
+	if b.Dockerfile != "" && !filepath.IsLocal(b.Dockerfile) {
+		return errBuildDockerfile
+	}
+	if strings.TrimSpace(b.Tag) == "" || strings.ContainsAny(b.Tag, " \t\n") {
+		return errBuildTag
+	}
+	for name := range b.BuildArgs {
+		if !buildArgRe.MatchString(name) {
+			return errBuildArg
+		}
+	}
+	if !validPlatform(b.Platform) {
+		return apierror.New(http.StatusBadRequest, "Invalid platform")
+	}
+	return nil
+}
+
+// BuildLog is a line of build output
+type BuildLog struct {
+	Line string `json:"line"`
+}
+
+// BuildResult ends a successful build
+type BuildResult struct {
+	ID        string `json:"id"`
+	Tag       string `json:"tag"`
+	Commit    string `json:"commit"`              // the commit that was built
+	BaseImage string `json:"baseImage,omitempty"` // FROM of the last stage
+}
+
+// Build handles POST /api/images/build
+// Clones the repository (shallow) and builds the image, streaming the
+// output as server-sent events: log events with the lines, then a done
+// event with the image or an error event.
+func (h *BuildHandler) Build(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req BuildRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBuildRequest)).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		writeErr(w, r, errNoGit, "")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	logs := make(chan string, streamBuffer)
+	type outcome struct {
+		built BuildResult
+		err   error
+	}
+	result := make(chan outcome, 1)
+	go func() {
+		defer close(logs)
+		built, err := h.build(ctx, &req, false, func(line string) {
+			select {
+			case logs <- line:
+			case <-ctx.Done():
+			}
+		})
+		result <- outcome{built, err}
+	}()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case line, ok := <-logs:
+			if !ok {
+				end := <-result
+				details := buildDetails(&req, end.built)
+				if end.err != nil {
+					h.eventStore.Add(events.EventImageBuild, user.Username, getClientIP(r), false, details+": "+end.err.Error())
+					writeSSE(w, "error", "", map[string]string{"message": end.err.Error()})
+				} else {
+					h.eventStore.Add(events.EventImageBuild, user.Username, getClientIP(r), true, details)
+					writeSSE(w, "done", "", end.built)
+				}
+				flusher.Flush()
+				return
+			}
+			writeSSE(w, "log", "", BuildLog{Line: line})
+			flusher.Flush()
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-closing:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// buildDetails describes a build in events: the tag, repository and commit
+func buildDetails(req *BuildRequest, built BuildResult) string {
+	details := req.Tag + " from " + req.URL
+	if built.Commit != "" {
+		details += "@" + shortID(built.Commit)
+	}
+	return details
+}
+
+// build clones the repository of a request and builds it, passing the
+// output lines to log. With pull, newer base images are pulled first. The
+// result has the commit even if the build failed.
+func (h *BuildHandler) build(ctx context.Context, req *BuildRequest, pull bool, log func(line string)) (BuildResult, error) {
+	var built BuildResult
+	dir, err := os.MkdirTemp("", "podmanview-build-")
+	if err != nil {
+		return built, err
+	}
+	defer os.RemoveAll(dir)
+
+	ref := req.Ref
+	if ref == "" {
+		ref = "default branch"
+	}
+	log("Cloning " + req.URL + " (" + ref + ")")
+	cloneCtx, cancel := context.WithTimeout(ctx, buildCloneTimeout)
+	commit, err := cloneRepository(cloneCtx, req.URL, req.Ref, dir)
+	cancel()
+	if err != nil {
+		return built, err
+	}
+	built.Commit = commit
+	log("Checked out " + commit)
+
+	contextDir := filepath.Join(dir, req.Context)
+	if info, err := os.Stat(contextDir); err != nil || !info.IsDir() {
+		return built, fmt.Errorf("context directory %s not found in the repository", req.Context)
+	}
+	built.BaseImage = dockerfileBase(contextDir, req.Dockerfile)
+
+	// The context is streamed to Podman as it is archived
+	archive, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeBuildContext(writer, contextDir))
+	}()
+	defer archive.Close()
+
+	stream, err := h.client.BuildImage(ctx, archive, podman.BuildOptions{
+		Tags:       []string{req.Tag},
+		Dockerfile: filepath.ToSlash(req.Dockerfile),
+		BuildArgs:  req.BuildArgs,
+		Platform:   req.Platform,
+		Pull:       pull,
+	})
+	if err != nil {
+		return built, err
+	}
+	defer stream.Close()
+
+	decoder := json.NewDecoder(stream)
+	for {
+		var message podman.BuildMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			break
+		} else if err != nil {
+			return built, fmt.Errorf("reading build output: %w", err)
+		}
+		if message.Error != "" {
+			return built, errors.New(strings.TrimSpace(message.Error))
+		}
+		if message.Aux.ID != "" {
+			built.ID = message.Aux.ID
+		}
+		for _, line := range strings.Split(strings.TrimRight(message.Stream, "\n"), "\n") {
+			if line != "" {
+				log(line)
+			}
+		}
+	}
+	if built.ID == "" {
+		return built, errors.New("build ended without an image")
+	}
+	built.Tag = req.Tag
+	return built, nil
+}
+
+// dockerfileBase returns the base image of the last stage of the
+// Dockerfile of a context, following FROM of earlier stages. It is empty
+// for scratch images, images named by build arguments and unreadable files.
+func dockerfileBase(contextDir, dockerfile string) string {
+	var data []byte
+	var err error
+	if dockerfile != "" {
+		data, err = os.ReadFile(filepath.Join(contextDir, dockerfile))
+	} else {
+		for _, name := range []string{"Containerfile", "Dockerfile"} {
+			if data, err = os.ReadFile(filepath.Join(contextDir, name)); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return ""
+	}
+
+	stages := make(map[string]string) // stage name -> its FROM
+	base := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		fields = fields[1:]
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+			fields = fields[1:] // --platform=...
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		base = fields[0]
+		if from, ok := stages[strings.ToLower(base)]; ok {
+			base = from
+		}
+		if len(fields) == 3 && strings.EqualFold(fields[1], "AS") {
+			stages[strings.ToLower(fields[2])] = base
+		}
+	}
+	if base == "scratch" || strings.Contains(base, "$") {
+		return ""
+	}
+	return base
+}
+
+// cloneRepository fetches a single commit of a repository into dir, the
+// head of the default branch if ref is empty, and returns its hash
+func cloneRepository(ctx context.Context, url, ref, dir string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_TERMINAL_PROMPT=0",
+			"GIT_ALLOW_PROTOCOL="+gitProtocols,
+			"GIT_SSH_COMMAND=ssh -o BatchMode=yes",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return "", errors.New("cloning the repository timed out")
+			}
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	// Fetching a ref works for branches, tags and, on most servers, commits
+	steps := [][]string{
+		{"init", "-q"},
+		{"fetch", "-q", "--depth", "1", "--", url, ref},
+		{"checkout", "-q", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		if _, err := git(args...); err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); err == nil {
+		if _, err := git("submodule", "update", "-q", "--init", "--recursive", "--depth", "1"); err != nil {
+			return "", err
+		}
+	}
+	return git("rev-parse", "HEAD")
+}
+
+// writeBuildContext writes dir as a tar archive without the .git
+// directories; symlinks are kept as links
+func writeBuildContext(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil // the .git file of a submodule
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Uname, header.Gname = "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,576 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+const (
+	// buildsBucket stores saved builds as "def/<id>" and their runs as
+	// "run/<id>/<start>", which sort by time
+	buildsBucket = "builds"
+
+	// maxBuildHistory is how many runs are kept per saved build
+	maxBuildHistory = 20
+	// maxBuildLog limits the output kept per run, the end is kept
+	maxBuildLog = 64 << 10
+	// maxBuildDuration limits builds that run without a request
+	maxBuildDuration = 2 * time.Hour
+	// maxBuildName limits the length of saved build names
+	maxBuildName = 64
+)
+
+// Build triggers
+const (
+	BuildTriggerManual   = "manual"   // run from the API
+	BuildTriggerSchedule = "schedule" // run by the builds job
+	BuildTriggerBase     = "base"     // the base image changed
+)
+
+var (
+	errBuildNotFound = apierror.New(http.StatusNotFound, "Build not found")
+	errBuildName     = apierror.New(http.StatusBadRequest, fmt.Sprintf("Build name is required, up to %d characters", maxBuildName))
+	errBuildRunning  = apierror.New(http.StatusConflict, "Build is already running")
+	errRunNotFound   = apierror.New(http.StatusNotFound, "Build run not found")
+)
+
+// BuildDefinition is a saved build. It is run on demand, at every run of
+// the builds job if scheduled, or by the builds job when its base image
+// has a new version if watched.
+type BuildDefinition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	BuildRequest
+	Scheduled bool   `json:"scheduled"`           // built at every run of the builds job
+	WatchBase bool   `json:"watchBase"`           // built when the builds job pulls a new base image
+	Redeploy  bool   `json:"redeploy"`            // recreate the containers of the image after a build
+	BaseImage string `json:"baseImage,omitempty"` // image watched, the FROM of the last stage if empty
+
+	LastBase   string    `json:"lastBase,omitempty"`   // base image of the last successful build
+	LastBaseID string    `json:"lastBaseId,omitempty"` // and its ID then
+	LastRun    *BuildRun `json:"lastRun,omitempty"`    // without the log
+	Running    bool      `json:"running"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// BuildRun is a run of a saved build, kept in its history
+type BuildRun struct {
+	ID         string    `json:"id"`
+	Trigger    string    `json:"trigger"` // manual, schedule or base
+	User       string    `json:"user"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Image      string    `json:"image,omitempty"` // ID of the built image
+	Commit     string    `json:"commit,omitempty"`
+	BaseImage  string    `json:"baseImage,omitempty"`
+	Redeployed []string  `json:"redeployed,omitempty"` // containers recreated on the new image
+	Log        string    `json:"log,omitempty"`        // the end of the output
+}
+
+// validate checks the fields of a saved build that clients set
+func (d *BuildDefinition) validate() error {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" || len(d.Name) > maxBuildName {
+		return errBuildName
+	}
+	if err := d.BuildRequest.validate(); err != nil {
+		return err
+	}
+	if d.BaseImage != "" && strings.ContainsAny(d.BaseImage, " \t\n") {
+		return apierror.New(http.StatusBadRequest, "Invalid base image")
+	}
+	return nil
+}
+
+// available checks for an admin user and storage
+func (h *BuildHandler) available(w http.ResponseWriter, r *http.Request) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return false
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return false
+	}
+	return true
+}
+
+// ListDefinitions handles GET /api/builds
+// Returns the saved builds by name with their last run
+func (h *BuildHandler) ListDefinitions(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	defs, err := h.definitions()
+	if err != nil {
+		writeErr(w, r, err, "Failed to read builds")
+		return
+	}
+	writeJSON(w, http.StatusOK, defs)
+}
+
+// GetDefinition handles GET /api/builds/{id}
+func (h *BuildHandler) GetDefinition(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	def, err := h.definition(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, def)
+}
+
+// CreateDefinition handles POST /api/builds
+func (h *BuildHandler) CreateDefinition(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	var def BuildDefinition
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBuildRequest)).Decode(&def); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := def.validate(); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		writeErr(w, r, err, "Failed to save build")
+		return
+	}
+	def.ID, def.LastBase, def.LastBaseID, def.LastRun, def.Running = id, "", "", nil, false
+	def.CreatedAt = time.Now()
+	def.UpdatedAt = def.CreatedAt
+
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+	if err := h.storage.SetJSON(buildsBucket, "def/"+def.ID, def); err != nil {
+		h.eventStore.Add(events.EventBuildUpdate, user.Username, getClientIP(r), false, def.Name)
+		writeErr(w, r, err, "Failed to save build")
+		return
+	}
+	h.eventStore.Add(events.EventBuildUpdate, user.Username, getClientIP(r), true, def.Name)
+	writeJSON(w, http.StatusCreated, def)
+}
+
+// UpdateDefinition handles PUT /api/builds/{id}
+// The body replaces the saved build; its history is kept.
+func (h *BuildHandler) UpdateDefinition(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	var def BuildDefinition
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBuildRequest)).Decode(&def); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := def.validate(); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+	existing, err := h.definition(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	def.ID, def.CreatedAt, def.LastRun, def.Running = existing.ID, existing.CreatedAt, existing.LastRun, existing.Running
+	def.LastBase, def.LastBaseID = existing.LastBase, existing.LastBaseID
+	def.UpdatedAt = time.Now()
+	if err := h.storage.SetJSON(buildsBucket, "def/"+def.ID, def); err != nil {
+		h.eventStore.Add(events.EventBuildUpdate, user.Username, getClientIP(r), false, def.Name)
+		writeErr(w, r, err, "Failed to save build")
+		return
+	}
+	h.eventStore.Add(events.EventBuildUpdate, user.Username, getClientIP(r), true, def.Name)
+	writeJSON(w, http.StatusOK, def)
+}
+
+// DeleteDefinition handles DELETE /api/builds/{id}
+// Removes the saved build and its history; the built images are kept.
+func (h *BuildHandler) DeleteDefinition(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+	def, err := h.definition(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	runs, err := h.storage.ListPrefix(buildsBucket, "run/"+def.ID+"/")
+	if err == nil {
+		for key := range runs {
+			h.storage.Delete(buildsBucket, key)
+		}
+		err = h.storage.Delete(buildsBucket, "def/"+def.ID)
+	}
+	if err != nil {
+		h.eventStore.Add(events.EventBuildUpdate, user.Username, getClientIP(r), false, "delete "+def.Name)
+		writeErr(w, r, err, "Failed to delete build")
+		return
+	}
+	h.eventStore.Add(events.EventBuildUpdate, user.Username, getClientIP(r), true, "delete "+def.Name)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted", "name": def.Name})
+}
+
+// RunDefinition handles POST /api/builds/{id}/run
+// Starts the saved build in the background; its run appears in the
+// history when it ends.
+func (h *BuildHandler) RunDefinition(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	def, err := h.definition(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if !h.start(def.ID) {
+		writeErr(w, r, errBuildRunning, "")
+		return
+	}
+
+	// The build outlives the request
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), maxBuildDuration)
+		defer cancel()
+		defer h.finish(def.ID)
+		h.runDefinition(ctx, def.ID, BuildTriggerManual, user.Username)
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// Runs handles GET /api/builds/{id}/runs
+// Returns the history of a saved build, newest first, without the logs
+func (h *BuildHandler) Runs(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	def, err := h.definition(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	runs, err := h.runs(def.ID)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read builds")
+		return
+	}
+	for i := range runs {
+		runs[i].Log = ""
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// GetRun handles GET /api/builds/{id}/runs/{run}
+// Returns a run with its log
+func (h *BuildHandler) GetRun(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	var run BuildRun
+	if err := h.storage.GetJSON(buildsBucket, "run/"+chi.URLParam(r, "id")+"/"+chi.URLParam(r, "run"), &run); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			err = errRunNotFound
+		}
+		writeErr(w, r, err, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// definitions returns the saved builds by name
+func (h *BuildHandler) definitions() ([]BuildDefinition, error) {
+	data, err := h.storage.ListPrefix(buildsBucket, "def/")
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]BuildDefinition, 0, len(data))
+	for _, value := range data {
+		var def BuildDefinition
+		if json.Unmarshal(value, &def) == nil {
+			def.Running = h.isRunning(def.ID)
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return strings.ToLower(defs[i].Name) < strings.ToLower(defs[j].Name) })
+	return defs, nil
+}
+
+// definition returns a saved build
+func (h *BuildHandler) definition(id string) (*BuildDefinition, error) {
+	var def BuildDefinition
+	if err := h.storage.GetJSON(buildsBucket, "def/"+id, &def); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errBuildNotFound
+		}
+		return nil, err
+	}
+	def.Running = h.isRunning(def.ID)
+	return &def, nil
+}
+
+// runs returns the history of a saved build, newest first
+func (h *BuildHandler) runs(id string) ([]BuildRun, error) {
+	data, err := h.storage.ListPrefix(buildsBucket, "run/"+id+"/")
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]BuildRun, 0, len(data))
+	for _, value := range data {
+		var run BuildRun
+		if json.Unmarshal(value, &run) == nil {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+	return runs, nil
+}
+
+// start marks a saved build as running, false if it already is
+func (h *BuildHandler) start(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.running[id] {
+		return false
+	}
+	h.running[id] = true
+	return true
+}
+
+// finish marks a saved build as no longer running
+func (h *BuildHandler) finish(id string) {
+	h.mu.Lock()
+	delete(h.running, id)
+	h.mu.Unlock()
+}
+
+// isRunning reports whether a saved build is running
+func (h *BuildHandler) isRunning(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.running[id]
+}
+
+// runDefinition builds a saved build, which must be marked as running,
+// records the run in its history and redeploys the containers of the
+// image if enabled
+func (h *BuildHandler) runDefinition(ctx context.Context, id, trigger, username string) *BuildRun {
+	run := &BuildRun{ID: fmt.Sprintf("%020d", time.Now().UnixNano()), Trigger: trigger, User: username, Started: time.Now()}
+	def, err := h.definition(id)
+	if err != nil {
+		return nil
+	}
+
+	var log strings.Builder
+	built, err := h.build(ctx, &def.BuildRequest, true, func(line string) {
+		log.WriteString(line)
+		log.WriteByte('\n')
+		// Keep the end of long outputs
+		if log.Len() > 2*maxBuildLog {
+			rest := log.String()[log.Len()-maxBuildLog:]
+			log.Reset()
+			log.WriteString(rest)
+		}
+	})
+	run.Image, run.Commit, run.BaseImage = built.ID, built.Commit, built.BaseImage
+	if def.BaseImage != "" {
+		run.BaseImage = def.BaseImage
+	}
+
+	details := def.Name + ": " + buildDetails(&def.BuildRequest, built)
+	if err != nil {
+		run.Error = err.Error()
+		h.eventStore.Add(events.EventImageBuild, username, "", false, details+": "+err.Error())
+	} else {
+		run.Success = true
+		h.eventStore.Add(events.EventImageBuild, username, "", true, details)
+		if def.Redeploy {
+			run.Redeployed, err = h.redeploy(ctx, def.Tag, built.ID, username)
+			if err != nil {
+				run.Error = err.Error()
+				fmt.Fprintf(&log, "Redeploy failed: %v\n", err)
+			}
+		}
+	}
+	run.Finished = time.Now()
+	run.Log = log.String()
+	if len(run.Log) > maxBuildLog {
+		run.Log = run.Log[len(run.Log)-maxBuildLog:]
+	}
+	baseID := ""
+	if run.Success && run.BaseImage != "" {
+		if base, err := h.client.InspectImage(ctx, run.BaseImage); err == nil {
+			baseID = base.ID
+		}
+	}
+
+	// The saved build may have been changed or deleted during the build
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+	current, err := h.definition(id)
+	if err != nil {
+		return run
+	}
+	h.storage.SetJSON(buildsBucket, "run/"+id+"/"+run.ID, run)
+	if runs, err := h.runs(id); err == nil {
+		for _, old := range runs[min(len(runs), maxBuildHistory):] {
+			h.storage.Delete(buildsBucket, "run/"+id+"/"+old.ID)
+		}
+	}
+	summary := *run
+	summary.Log = ""
+	current.LastRun, current.Running = &summary, false
+	if run.Success && run.BaseImage != "" {
+		current.LastBase, current.LastBaseID = run.BaseImage, baseID
+	}
+	h.storage.SetJSON(buildsBucket, "def/"+id, current)
+	return run
+}
+
+// redeploy recreates the containers created from the image name on the
+// newly built image
+func (h *BuildHandler) redeploy(ctx context.Context, tag, imageID, username string) ([]string, error) {
+	containers, err := h.client.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := localImageName(tag)
+	var redeployed, failed []string
+	for _, c := range containers {
+		if localImageName(c.Image) != name || strings.TrimPrefix(imageID, "sha256:") == strings.TrimPrefix(c.ImageID, "sha256:") {
+			continue
+		}
+		containerName := firstOf(c.Names)
+		err := func() error {
+			info, err := h.client.InspectContainer(ctx, c.ID)
+			if err != nil {
+				return err
+			}
+			current, err := h.client.InspectImage(ctx, info.Image)
+			if err != nil {
+				current = &podman.ImageInspect{}
+			}
+			return recreateContainer(ctx, h.client, info, current)
+		}()
+		if err != nil {
+			failed = append(failed, containerName)
+			h.eventStore.Add(events.EventContainerUpdate, username, "", false, containerName+": "+err.Error())
+			continue
+		}
+		redeployed = append(redeployed, containerName)
+		h.eventStore.Add(events.EventContainerUpdate, username, "", true, containerName)
+	}
+	if len(failed) > 0 {
+		return redeployed, fmt.Errorf("failed to redeploy %s", strings.Join(failed, ", "))
+	}
+	return redeployed, nil
+}
+
+// localImageName qualifies an image name as Podman names built images:
+// localhost/ for names without a registry, :latest without a tag
+func localImageName(name string) string {
+	if first, _, ok := strings.Cut(name, "/"); !ok || !(strings.ContainsAny(first, ".:") || first == "localhost") {
+		name = "localhost/" + name
+	}
+	if i := strings.LastIndex(name, ":"); i <= strings.LastIndex(name, "/") && !strings.Contains(name, "@") {
+		name += ":latest"
+	}
+	return name
+}
+
+// runScheduled runs the builds job: saved builds that are scheduled, and
+// watched builds whose base image has a new version after a pull
+func (h *BuildHandler) runScheduled(ctx context.Context) (string, error) {
+	if h.storage == nil {
+		return "", errors.New("storage not available")
+	}
+	defs, err := h.definitions()
+	if err != nil {
+		return "", err
+	}
+
+	var built, failed []string
+	for _, def := range defs {
+		trigger := ""
+		switch {
+		case def.Scheduled:
+			trigger = BuildTriggerSchedule
+		case def.WatchBase:
+			if h.baseChanged(ctx, &def) {
+				trigger = BuildTriggerBase
+			}
+		}
+		if trigger == "" || !h.start(def.ID) {
+			continue
+		}
+		buildCtx, cancel := context.WithTimeout(ctx, maxBuildDuration)
+		run := h.runDefinition(buildCtx, def.ID, trigger, "system")
+		cancel()
+		h.finish(def.ID)
+		if run == nil || !run.Success || run.Error != "" {
+			failed = append(failed, def.Name)
+		} else {
+			built = append(built, def.Name)
+		}
+	}
+
+	result := fmt.Sprintf("%d images built", len(built))
+	if len(built) > 0 {
+		result += ": " + strings.Join(built, ", ")
+	}
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to build %s", strings.Join(failed, ", "))
+	}
+	return result, nil
+}
+
+// baseChanged pulls the base image of a watched build and reports whether
+// it differs from the one of its last build. Builds that never succeeded
+// don't know their base yet and are built.
+func (h *BuildHandler) baseChanged(ctx context.Context, def *BuildDefinition) bool {
+	base := def.BaseImage
+	if base == "" {
+		base = def.LastBase
+	}
+	if base == "" || def.LastBaseID == "" {
+		return true
+	}
+	if err := h.client.PullImagePlatform(ctx, base, def.Platform); err != nil {
+		return false
+	}
+	image, err := h.client.InspectImage(ctx, base)
+	return err == nil && image.ID != def.LastBaseID
+}
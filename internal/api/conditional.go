@@ -0,0 +1,60 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// writeJSONConditional writes a JSON list response that clients can revalidate:
+// it has a weak ETag of the body (weak, as the compress middleware changes the
+// encoding) and, if modified is not zero, Last-Modified. Requests with a
+// matching If-None-Match, or without it and with an If-Modified-Since not
+// before modified, get 304 Not Modified without a body.
+func writeJSONConditional(w http.ResponseWriter, r *http.Request, data interface{}, modified time.Time) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		writeErr(w, r, err, "Failed to encode response")
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "private, no-cache") // browsers revalidate on every request
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+// notModified evaluates the conditional request headers (RFC 9110 13.2.2),
+// If-None-Match takes precedence over If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+)
+
+// editableSetting describes a setting that can be changed through the settings API
+type editableSetting struct {
+	key             string // config key
+	restartRequired bool   // change takes effect only after restart
+}
+
+// editableSettings maps API field names to config keys
+var editableSettings = map[string]editableSetting{
+	"addr":           {key: config.EnvAddr, restartRequired: true},
+	"jwt_expiration": {key: config.EnvJWTExpiration, restartRequired: true},
+	"socket_path":    {key: config.EnvSocket, restartRequired: true},
+	"log_level":      {key: config.EnvLogLevel, restartRequired: false},
+}
+
+// SettingField is a single editable setting in API responses
+type SettingField struct {
+	Name            string `json:"name"`
+	Value           string `json:"value"`
+	Source          string `json:"source"`
+	RestartRequired bool   `json:"restartRequired"`
+	PendingRestart  bool   `json:"pendingRestart"` // changed since startup, not yet active
+}
+
+// ConfigHandler handles configuration endpoints
+type ConfigHandler struct {
+	config     *config.Config
+	eventStore *events.Store
+	logger     *logger.Logger
+	startup    map[string]string // values active since startup
+}
+
+// NewConfigHandler creates new config handler
+func NewConfigHandler(cfg *config.Config, eventStore *events.Store, appLogger *logger.Logger) *ConfigHandler {
+	startup := make(map[string]string)
+	for _, s := range cfg.Settings() {
+		startup[s.Key] = s.Value
+	}
+
+	return &ConfigHandler{
+		config:     cfg,
+		eventStore: eventStore,
+		logger:     appLogger,
+		startup:    startup,
+	}
+}
+
+// Effective handles GET /api/config
+// Returns the effective value of each setting and where it came from (default, file or env)
+func (h *ConfigHandler) Effective(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"file":     h.config.FilePath(),
+		"settings": h.config.Settings(),
+	})
+}
+
+// GetSettings handles GET /api/settings
+func (h *ConfigHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"settings": h.settingFields(),
+	})
+}
+
+// UpdateSettings handles PATCH /api/settings
+// Body is a partial object, e.g. {"log_level": "debug", "jwt_expiration": 3600}
+func (h *ConfigHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+
+	var req map[string]interface{}
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req) == 0 {
+		writeError(w, r, http.StatusBadRequest, "No settings provided")
+		return
+	}
+
+	values := make(map[string]string, len(req))
+	names := make([]string, 0, len(req))
+	for name, raw := range req {
+		value, err := parseSettingValue(name, raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		values[editableSettings[name].key] = value
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	details := strings.Join(names, ", ")
+
+	if err := h.config.Update(values); err != nil {
+		h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), false, details)
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Log level is applied without restart
+	if _, ok := values[config.EnvLogLevel]; ok && h.logger != nil {
+		if level, err := logger.ParseLevel(h.config.LogLevel()); err == nil {
+			h.logger.SetLevel(level)
+		}
+	}
+
+	h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), true, details)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"settings": h.settingFields(),
+	})
+}
+
+// ListBackups handles GET /api/config/backups
+func (h *ConfigHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	backups, err := h.config.Backups()
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keep":    h.config.ConfigBackups(),
+		"backups": backups,
+	})
+}
+
+// RestoreBackup handles POST /api/config/backups/{id}/restore
+// Restores the config file and plugin settings saved in the backup.
+func (h *ConfigHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	if err := h.config.RestoreBackup(id); err != nil {
+		h.eventStore.Add(events.EventConfigRestore, user.Username, getClientIP(r), false, id)
+		if errors.Is(err, config.ErrBackupNotFound) {
+			writeError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Log level is applied without restart, like in UpdateSettings
+	if h.logger != nil {
+		if level, err := logger.ParseLevel(h.config.LogLevel()); err == nil {
+			h.logger.SetLevel(level)
+		}
+	}
+
+	h.eventStore.Add(events.EventConfigRestore, user.Username, getClientIP(r), true, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"restored":        id,
+		"restartRequired": true,
+		"settings":        h.settingFields(),
+	})
+}
+
+// settingFields returns the current state of editable settings
+func (h *ConfigHandler) settingFields() []SettingField {
+	all := make(map[string]config.Setting)
+	for _, s := range h.config.Settings() {
+		all[s.Key] = s
+	}
+
+	names := make([]string, 0, len(editableSettings))
+	for name := range editableSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]SettingField, 0, len(names))
+	for _, name := range names {
+		def := editableSettings[name]
+		s := all[def.key]
+		fields = append(fields, SettingField{
+			Name:            name,
+			Value:           s.Value,
+			Source:          s.Source,
+			RestartRequired: def.restartRequired,
+			PendingRestart:  def.restartRequired && s.Value != h.startup[def.key],
+		})
+	}
+	return fields
+}
+
+// parseSettingValue validates the JSON type of a setting and converts it to its config string form.
+// Range checks are done by config validation.
+func parseSettingValue(name string, raw interface{}) (string, error) {
+	if _, ok := editableSettings[name]; !ok {
+		return "", fmt.Errorf("unknown or read-only setting: %s", name)
+	}
+
+	switch name {
+	case "jwt_expiration":
+		num, ok := raw.(json.Number)
+		if !ok {
+			return "", fmt.Errorf("%s must be a number of seconds", name)
+		}
+		seconds, err := num.Int64()
+		if err != nil || seconds <= 0 {
+			return "", fmt.Errorf("%s must be a positive integer", name)
+		}
+		return num.String(), nil
+	case "addr", "log_level":
+		str, ok := raw.(string)
+		if !ok || strings.TrimSpace(str) == "" {
+			return "", fmt.Errorf("%s must be a non-empty string", name)
+		}
+		return strings.TrimSpace(str), nil
+	default:
+		// socket_path: empty string means auto-detect
+		str, ok := raw.(string)
+		if !ok {
+			return "", fmt.Errorf("%s must be a string", name)
+		}
+		return strings.TrimSpace(str), nil
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+)
+
+// confirmTTL is how long a confirmation token can be echoed
+const confirmTTL = time.Minute
+
+// confirmHeader carries the confirmation token of the second call; the
+// confirm query parameter works too
+const confirmHeader = "X-Confirm-Token"
+
+// Confirmation describes a destructive operation waiting to be confirmed.
+// It is sent as the details of a 428 response.
+type Confirmation struct {
+	Token     string      `json:"token"`
+	Action    string      `json:"action"`
+	Target    string      `json:"target,omitempty"`
+	Impact    interface{} `json:"impact"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+}
+
+type pendingConfirmation struct {
+	action    string
+	target    string
+	username  string
+	expiresAt time.Time
+}
+
+// confirmStore issues the single-use tokens of destructive operations.
+// A token is bound to the action, its target and the user it was issued to.
+type confirmStore struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+func newConfirmStore() *confirmStore {
+	return &confirmStore{pending: make(map[string]pendingConfirmation)}
+}
+
+// confirmed reports whether r echoes a valid token for action on target and
+// consumes it. Otherwise it writes a 428 response with a new token and the
+// impact of the operation, and the handler must return.
+func (s *confirmStore) confirmed(w http.ResponseWriter, r *http.Request, action, target string, impact func() (interface{}, error)) bool {
+	username := auth.GetUserFromContext(r.Context()).Username
+	token := r.Header.Get(confirmHeader)
+	if token == "" {
+		token = r.URL.Query().Get("confirm")
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	for t, p := range s.pending {
+		if now.After(p.expiresAt) {
+			delete(s.pending, t)
+		}
+	}
+	if token != "" {
+		p, ok := s.pending[token]
+		delete(s.pending, token)
+		if ok && p.action == action && p.target == target && p.username == username {
+			s.mu.Unlock()
+			return true
+		}
+	}
+	s.mu.Unlock()
+
+	details, err := impact()
+	if err != nil {
+		writeErr(w, r, err, "Failed to determine impact")
+		return false
+	}
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		writeErr(w, r, err, "Failed to generate token")
+		return false
+	}
+	confirmation := &Confirmation{
+		Token:     hex.EncodeToString(bytes),
+		Action:    action,
+		Target:    target,
+		Impact:    details,
+		ExpiresAt: now.Add(confirmTTL),
+	}
+	s.mu.Lock()
+	s.pending[confirmation.Token] = pendingConfirmation{action: action, target: target, username: username, expiresAt: confirmation.ExpiresAt}
+	s.mu.Unlock()
+
+	message := "Confirmation required"
+	if token != "" {
+		message = "Confirmation token is invalid or expired"
+	}
+	apierror.WriteError(w, r, apierror.New(http.StatusPreconditionRequired, message).WithCode("confirmation_required").WithDetails(confirmation))
+	return false
+}
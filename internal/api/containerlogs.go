@@ -0,0 +1,403 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/podman"
+)
+
+const (
+	// maxLogContainers limits the containers of a merged log stream
+	maxLogContainers = 20
+	// maxLogTail limits the lines per container sent before following
+	maxLogTail = 10000
+	// maxLogContext limits the context lines around a search match
+	maxLogContext = 20
+)
+
+// errLogSearchDone stops reading a log once a search page is complete
+var errLogSearchDone = errors.New("search done")
+
+// logColors are the colors hinted for the containers of a merged log
+// stream, by their position in the request
+var logColors = []string{"#3b82f6", "#22c55e", "#f59e0b", "#ec4899", "#8b5cf6", "#14b8a6", "#ef4444", "#84cc16"}
+
+// LogEntry is a line of a merged log stream
+type LogEntry struct {
+	Container string    `json:"container"` // name
+	ID        string    `json:"id"`
+	Color     string    `json:"color"`
+	Stream    string    `json:"stream"` // stdout or stderr
+	Time      time.Time `json:"time"`
+	Line      string    `json:"line"`
+}
+
+// openLogs opens the log of a container. Logs of the journald log driver
+// are read from the systemd journal.
+func (h *ContainerHandler) openLogs(ctx context.Context, id, driver string, opts podman.LogOptions) (io.ReadCloser, error) {
+	if driver == "journald" {
+		return podman.JournalLogs(ctx, id, opts)
+	}
+	return h.client.ContainerLogs(ctx, id, opts)
+}
+
+// DownloadLogs handles GET /api/containers/{id}/logs/download
+// Returns the full log of the container as a text file, oldest line first,
+// each line prefixed with its timestamp unless timestamps is false.
+func (h *ContainerHandler) DownloadLogs(w http.ResponseWriter, r *http.Request) {
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	logs, err := h.openLogs(r.Context(), info.ID, info.HostConfig.LogConfig.Type, podman.LogOptions{})
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	defer logs.Close()
+
+	timestamps := r.URL.Query().Get("timestamps") != "false"
+	name := sanitizeFilename(strings.TrimPrefix(info.Name, "/"))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.log"`)
+	w.WriteHeader(http.StatusOK)
+
+	// Errors after the headers can only cut the file short
+	out := bufio.NewWriter(w)
+	podman.ReadLogs(logs, func(line podman.LogLine) error {
+		if timestamps && !line.Time.IsZero() {
+			out.WriteString(line.Time.UTC().Format(time.RFC3339Nano))
+			out.WriteByte(' ')
+		}
+		out.WriteString(line.Text)
+		return out.WriteByte('\n')
+	})
+	out.Flush()
+}
+
+// LogSearchLine is a line of a log search result
+type LogSearchLine struct {
+	Line   int       `json:"line"` // number of the line in the searched range, from 1
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+}
+
+// LogMatch is a matching line with the lines around it
+type LogMatch struct {
+	LogSearchLine
+	Before []LogSearchLine `json:"before"`
+	After  []LogSearchLine `json:"after"`
+}
+
+// SearchLogs handles GET /api/containers/{id}/logs/search
+// GET /api/containers/{id}/logs/search?q=error|timeout&from=...&to=...&context=2&limit=100&cursor=100
+// Searches the log of the container for lines matching the regular
+// expression q, oldest first, each with context lines before and after it.
+// Returns nextCursor to fetch the following page, 0 on the last page. Only
+// the log up to the last match of the page is read.
+func (h *ContainerHandler) SearchLogs(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	pattern := params.Get("q")
+	if pattern == "" {
+		writeError(w, r, http.StatusBadRequest, "Search pattern is required")
+		return
+	}
+	if params.Get("ignoreCase") == "true" {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid search pattern: "+err.Error())
+		return
+	}
+
+	var opts podman.LogOptions
+	for param, t := range map[string]*time.Time{"from": &opts.Since, "to": &opts.Until} {
+		if v := params.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "Invalid "+param+" time, expected RFC 3339")
+				return
+			}
+			*t = parsed
+		}
+	}
+	contextLines := 0
+	if v := params.Get("context"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 || parsed > maxLogContext {
+			writeError(w, r, http.StatusBadRequest, "Invalid context, maximum: "+strconv.Itoa(maxLogContext))
+			return
+		}
+		contextLines = parsed
+	}
+	limit := 100 // default
+	if v := params.Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+	cursor := 0
+	if v := params.Get("cursor"); v != "" {
+		cursor, err = strconv.Atoi(v)
+		if err != nil || cursor < 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	logs, err := h.openLogs(r.Context(), info.ID, info.HostConfig.LogConfig.Type, opts)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	defer logs.Close()
+
+	matches := []LogMatch{}
+	var (
+		before  []LogSearchLine // last lines, for the next match
+		pending []int           // matches still missing lines after them
+		skipped int
+		number  int
+		more    bool
+	)
+	err = podman.ReadLogs(logs, func(l podman.LogLine) error {
+		// since and until of Podman are not exact at the boundaries
+		if !l.Time.IsZero() && !opts.Since.IsZero() && l.Time.Before(opts.Since) {
+			return nil
+		}
+		if !l.Time.IsZero() && !opts.Until.IsZero() && l.Time.After(opts.Until) {
+			return errLogSearchDone
+		}
+		number++
+		line := LogSearchLine{Line: number, Stream: l.Stream, Time: l.Time, Text: l.Text}
+
+		waiting := pending[:0]
+		for _, i := range pending {
+			matches[i].After = append(matches[i].After, line)
+			if len(matches[i].After) < contextLines {
+				waiting = append(waiting, i)
+			}
+		}
+		pending = waiting
+
+		if re.MatchString(l.Text) {
+			switch {
+			case skipped < cursor:
+				skipped++
+			case len(matches) == limit:
+				more = true
+			default:
+				matches = append(matches, LogMatch{LogSearchLine: line, Before: append([]LogSearchLine{}, before...), After: []LogSearchLine{}})
+				if contextLines > 0 {
+					pending = append(pending, len(matches)-1)
+				}
+			}
+		}
+		if more && len(pending) == 0 {
+			return errLogSearchDone
+		}
+
+		if contextLines > 0 {
+			if len(before) == contextLines {
+				before = slices.Delete(before, 0, 1)
+			}
+			before = append(before, line)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLogSearchDone) {
+		writeError(w, r, http.StatusBadGateway, "Failed to read logs: "+err.Error())
+		return
+	}
+
+	next := 0
+	if more {
+		next = cursor + len(matches)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"matches":    matches,
+		"nextCursor": next,
+	})
+}
+
+// logSource is a container of a merged log stream
+type logSource struct {
+	id, name, color string
+	driver          string    // log driver
+	since           time.Time // follow lines after this time
+}
+
+// StreamLogs handles GET /api/containers/logs/stream?ids=web,db&tail=100
+// Server-Sent Events: "log" events with the last tail lines of every
+// container merged by time, then new lines as they are written. Each line
+// is tagged with its container and a color hint. An "end" event with the
+// container is sent when a container's log ends, e.g. when it stops. The
+// stream ends with the logs of all containers.
+func (h *ContainerHandler) StreamLogs(w http.ResponseWriter, r *http.Request) {
+	ids := parseNameList(r.URL.Query().Get("ids"))
+	if len(ids) == 0 {
+		writeError(w, r, http.StatusBadRequest, "Containers are required")
+		return
+	}
+	if len(ids) > maxLogContainers {
+		writeError(w, r, http.StatusBadRequest, "Too many containers, maximum: "+strconv.Itoa(maxLogContainers))
+		return
+	}
+	tail := 100
+	if v := r.URL.Query().Get("tail"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 || parsed > maxLogTail {
+			writeError(w, r, http.StatusBadRequest, "Invalid tail, maximum: "+strconv.Itoa(maxLogTail))
+			return
+		}
+		tail = parsed
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	var sources []*logSource
+	for _, id := range ids {
+		info, err := h.client.InspectContainer(r.Context(), id)
+		if err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+		if slices.ContainsFunc(sources, func(s *logSource) bool { return s.id == info.ID }) {
+			continue
+		}
+		sources = append(sources, &logSource{
+			id:     info.ID,
+			name:   strings.TrimPrefix(info.Name, "/"),
+			color:  logColors[len(sources)%len(logColors)],
+			driver: info.HostConfig.LogConfig.Type,
+			since:  time.Now(),
+		})
+	}
+
+	// History: the last lines of every container, merged by time. Each
+	// container is followed from its last line, or from now without lines.
+	var history []LogEntry
+	for _, src := range sources {
+		src.since = time.Now()
+		if tail == 0 {
+			continue
+		}
+		logs, err := h.openLogs(r.Context(), src.id, src.driver, podman.LogOptions{Tail: tail})
+		if err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+		podman.ReadLogs(logs, func(line podman.LogLine) error {
+			history = append(history, src.entry(line))
+			if !line.Time.IsZero() {
+				src.since = line.Time
+			}
+			return nil
+		})
+		logs.Close()
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Time.Before(history[j].Time) })
+
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+	for _, entry := range history {
+		writeSSE(w, "log", "", entry)
+	}
+	flusher.Flush()
+
+	// Live: follow every container from its last sent line
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// The end of a log is sent after its lines on the same channel
+	type message struct {
+		entry LogEntry
+		end   bool
+	}
+	messages := make(chan message, streamBuffer)
+	for _, src := range sources {
+		go func() {
+			defer func() {
+				select {
+				case messages <- message{entry: LogEntry{Container: src.name, ID: src.id}, end: true}:
+				case <-ctx.Done():
+				}
+			}()
+			logs, err := h.openLogs(ctx, src.id, src.driver, podman.LogOptions{Follow: true, Since: src.since})
+			if err != nil {
+				return
+			}
+			defer logs.Close()
+			podman.ReadLogs(logs, func(line podman.LogLine) error {
+				// Podman includes lines written at the since time
+				if !line.Time.IsZero() && !line.Time.After(src.since) {
+					return nil
+				}
+				select {
+				case messages <- message{entry: src.entry(line)}:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}()
+	}
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	running := len(sources)
+	for running > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closing:
+			return
+		case msg := <-messages:
+			if msg.end {
+				running--
+				writeSSE(w, "end", "", map[string]string{"container": msg.entry.Container, "id": msg.entry.ID})
+			} else {
+				writeSSE(w, "log", "", msg.entry)
+			}
+		case <-keepalive.C:
+			w.Write([]byte(": keepalive\n\n"))
+		}
+		flusher.Flush()
+	}
+}
+
+// entry tags a log line with the container
+func (s *logSource) entry(line podman.LogLine) LogEntry {
+	return LogEntry{Container: s.name, ID: s.id, Color: s.color, Stream: line.Stream, Time: line.Time, Line: line.Text}
+}
@@ -0,0 +1,357 @@
+package api
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Sources of ContainerNetwork
+const (
+	netSourceNamespace = "netns"
+	netSourceInspect   = "inspect"
+)
+
+// socketStates are the states of listening sockets in /proc/net/tcp and
+// unbound UDP sockets in /proc/net/udp
+var socketStates = map[string]string{"tcp": "0A", "tcp6": "0A", "udp": "07", "udp6": "07"}
+
+// NetInterface is a network interface of a container
+type NetInterface struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac,omitempty"`
+	MTU       int      `json:"mtu,omitempty"`
+	Up        bool     `json:"up"`
+	Addresses []string `json:"addresses"` // in CIDR notation
+	Network   string   `json:"network,omitempty"`
+	RxBytes   uint64   `json:"rxBytes"`
+	TxBytes   uint64   `json:"txBytes"`
+}
+
+// NetRoute is a route of a container
+type NetRoute struct {
+	Destination string `json:"destination"` // in CIDR notation, 0.0.0.0/0 or ::/0 for the default route
+	Gateway     string `json:"gateway,omitempty"`
+	Interface   string `json:"interface"`
+	Metric      int    `json:"metric"`
+}
+
+// ListeningSocket is a TCP socket accepting connections or a UDP socket
+// receiving datagrams in a container
+type ListeningSocket struct {
+	Protocol string `json:"protocol"` // tcp, tcp6, udp or udp6
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+}
+
+// ContainerNetwork is the network namespace of a container. It is read
+// from the namespace of a running container; otherwise, or without the
+// privileges to enter it, only the interfaces known to Podman are listed
+// and Error says why.
+type ContainerNetwork struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	NetworkMode string            `json:"networkMode"`
+	Source      string            `json:"source"` // netns or inspect
+	Interfaces  []NetInterface    `json:"interfaces"`
+	Routes      []NetRoute        `json:"routes"`
+	Listening   []ListeningSocket `json:"listening"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// Network handles GET /api/containers/{id}/network
+// Returns the interfaces, addresses, routes and listening sockets of a
+// container, to diagnose network issues without exec.
+func (h *ContainerHandler) Network(w http.ResponseWriter, r *http.Request) {
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	result := ContainerNetwork{
+		ID:          info.ID,
+		Name:        strings.TrimPrefix(info.Name, "/"),
+		NetworkMode: info.HostConfig.NetworkMode,
+		Source:      netSourceInspect,
+		Interfaces:  []NetInterface{},
+		Routes:      []NetRoute{},
+		Listening:   []ListeningSocket{},
+	}
+	err = errToolNotRunning
+	if info.State.Running && info.State.Pid > 0 {
+		ns := result
+		if err = readNetNamespace(info.State.Pid, &ns); err == nil {
+			result = ns
+			result.Source = netSourceNamespace
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	// Interfaces are matched to the Podman networks by MAC address
+	for name, n := range info.NetworkSettings.Networks {
+		matched := false
+		for i := range result.Interfaces {
+			if n.MacAddress != "" && strings.EqualFold(result.Interfaces[i].MAC, n.MacAddress) {
+				result.Interfaces[i].Network = name
+				matched = true
+			}
+		}
+		if matched || result.Source == netSourceNamespace {
+			continue
+		}
+		iface := NetInterface{Network: name, MAC: n.MacAddress, Addresses: []string{}}
+		if n.IPAddress != "" {
+			iface.Addresses = append(iface.Addresses, fmt.Sprintf("%s/%d", n.IPAddress, n.IPPrefixLen))
+		}
+		if n.GlobalIPv6Address != "" {
+			iface.Addresses = append(iface.Addresses, fmt.Sprintf("%s/%d", n.GlobalIPv6Address, n.GlobalIPv6PrefixLen))
+		}
+		result.Interfaces = append(result.Interfaces, iface)
+		if n.Gateway != "" {
+			result.Routes = append(result.Routes, NetRoute{Destination: "0.0.0.0/0", Gateway: n.Gateway})
+		}
+	}
+	sort.Slice(result.Interfaces, func(i, j int) bool {
+		if result.Interfaces[i].Name != result.Interfaces[j].Name {
+			return result.Interfaces[i].Name < result.Interfaces[j].Name
+		}
+		return result.Interfaces[i].Network < result.Interfaces[j].Network
+	})
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readNetNamespace reads the interfaces from the network namespace of
+// process pid, and the routes and sockets from its /proc/{pid}/net
+func readNetNamespace(pid int, result *ContainerNetwork) error {
+	procNet := "/proc/" + strconv.Itoa(pid) + "/net/"
+	var interfaces []net.Interface
+	addresses := make(map[string][]string)
+	err := inNetNS(pid, func() error {
+		var err error
+		if interfaces, err = net.Interfaces(); err != nil {
+			return err
+		}
+		for _, iface := range interfaces {
+			addrs, err := iface.Addrs()
+			if err != nil {
+				return err
+			}
+			for _, addr := range addrs {
+				addresses[iface.Name] = append(addresses[iface.Name], addr.String())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	counters, err := readNetDev(procNet + "dev")
+	if err != nil {
+		return err
+	}
+	for _, iface := range interfaces {
+		ni := NetInterface{
+			Name:      iface.Name,
+			MAC:       iface.HardwareAddr.String(),
+			MTU:       iface.MTU,
+			Up:        iface.Flags&net.FlagUp != 0,
+			Addresses: addresses[iface.Name],
+			RxBytes:   counters[iface.Name][0],
+			TxBytes:   counters[iface.Name][1],
+		}
+		if ni.Addresses == nil {
+			ni.Addresses = []string{}
+		}
+		result.Interfaces = append(result.Interfaces, ni)
+	}
+
+	if result.Routes, err = readRoutes(procNet); err != nil {
+		return err
+	}
+	if result.Listening, err = readListening(procNet); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readNetDev returns the received and sent bytes of the interfaces in a
+// /proc/net/dev file
+func readNetDev(path string) (map[string][2]uint64, error) {
+	lines, err := readProcLines(path, 2)
+	if err != nil {
+		return nil, err
+	}
+	counters := make(map[string][2]uint64)
+	for _, line := range lines {
+		name, stats, ok := strings.Cut(line, ":")
+		fields := strings.Fields(stats)
+		if !ok || len(fields) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseUint(fields[0], 10, 64)
+		tx, _ := strconv.ParseUint(fields[8], 10, 64)
+		counters[strings.TrimSpace(name)] = [2]uint64{rx, tx}
+	}
+	return counters, nil
+}
+
+// readRoutes reads the IPv4 and IPv6 routes of /proc/net, except local
+// and multicast routes on the loopback interface
+func readRoutes(procNet string) ([]NetRoute, error) {
+	routes := []NetRoute{}
+	lines, err := readProcLines(procNet+"route", 1)
+	if err != nil {
+		return nil, err
+	}
+	// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) < 8 {
+			continue
+		}
+		dest, gateway, mask := procIPv4(f[1]), procIPv4(f[2]), procIPv4(f[7])
+		if dest == nil || gateway == nil || mask == nil {
+			continue
+		}
+		ones, _ := net.IPMask(mask.To4()).Size()
+		route := NetRoute{Destination: fmt.Sprintf("%s/%d", dest, ones), Interface: f[0]}
+		route.Metric, _ = strconv.Atoi(f[6])
+		if !gateway.IsUnspecified() {
+			route.Gateway = gateway.String()
+		}
+		routes = append(routes, route)
+	}
+
+	// IPv6 is optional
+	lines, err = readProcLines(procNet+"ipv6_route", 0)
+	if os.IsNotExist(err) {
+		return routes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Destination PrefixLen Source SourcePrefixLen NextHop Metric RefCnt Use Flags Iface
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) < 10 || f[9] == "lo" {
+			continue
+		}
+		dest, nextHop := procIPv6(f[0], false), procIPv6(f[4], false)
+		prefix, err1 := strconv.ParseUint(f[1], 16, 8)
+		metric, err2 := strconv.ParseUint(f[5], 16, 32)
+		if dest == nil || nextHop == nil || err1 != nil || err2 != nil || dest.IsMulticast() {
+			continue
+		}
+		route := NetRoute{Destination: fmt.Sprintf("%s/%d", dest, prefix), Interface: f[9], Metric: int(metric)}
+		if !nextHop.IsUnspecified() {
+			route.Gateway = nextHop.String()
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// readListening reads the listening TCP and UDP sockets of /proc/net
+func readListening(procNet string) ([]ListeningSocket, error) {
+	seen := make(map[ListeningSocket]bool)
+	sockets := []ListeningSocket{}
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		lines, err := readProcLines(procNet+protocol, 1)
+		if os.IsNotExist(err) {
+			continue // no IPv6
+		}
+		if err != nil {
+			return nil, err
+		}
+		// sl local_address rem_address st ...
+		for _, line := range lines {
+			f := strings.Fields(line)
+			if len(f) < 4 || f[3] != socketStates[protocol] {
+				continue
+			}
+			host, port, ok := strings.Cut(f[1], ":")
+			n, err := strconv.ParseUint(port, 16, 16)
+			if !ok || err != nil {
+				continue
+			}
+			ip := procIPv4(host)
+			if strings.HasSuffix(protocol, "6") {
+				ip = procIPv6(host, true)
+			}
+			if ip == nil {
+				continue
+			}
+			socket := ListeningSocket{Protocol: protocol, Address: ip.String(), Port: int(n)}
+			if !seen[socket] {
+				seen[socket] = true // SO_REUSEPORT sockets repeat
+				sockets = append(sockets, socket)
+			}
+		}
+	}
+	sort.Slice(sockets, func(i, j int) bool {
+		if sockets[i].Port != sockets[j].Port {
+			return sockets[i].Port < sockets[j].Port
+		}
+		if sockets[i].Protocol != sockets[j].Protocol {
+			return sockets[i].Protocol < sockets[j].Protocol
+		}
+		return sockets[i].Address < sockets[j].Address
+	})
+	return sockets, nil
+}
+
+// readProcLines returns the lines of a /proc file after skip header lines
+func readProcLines(path string, skip int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		if i >= skip {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// procIPv4 decodes an IPv4 address of /proc/net, 8 hex digits in host
+// byte order, which is little-endian on the supported platforms
+func procIPv4(s string) net.IP {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return nil
+	}
+	return net.IPv4(b[3], b[2], b[1], b[0])
+}
+
+// procIPv6 decodes an IPv6 address of /proc/net. Routes write it as 32 hex
+// digits in network order, sockets as four 32-bit words in host order.
+func procIPv6(s string, words bool) net.IP {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 16 {
+		return nil
+	}
+	if words {
+		for i := 0; i < 16; i += 4 {
+			b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+		}
+	}
+	return net.IP(b)
+}
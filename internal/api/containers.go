@@ -1,13 +1,19 @@
 package api
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"podmanview/internal/apierror"
 	"podmanview/internal/auth"
 	"podmanview/internal/events"
 	"podmanview/internal/podman"
@@ -17,11 +23,17 @@ import (
 type ContainerHandler struct {
 	client     *podman.Client
 	eventStore *events.Store
+	drainer    *drainer       // closes log streams on shutdown
+	trash      *TrashHandler  // keeps removed containers with trash=true
+	volumes    *VolumeHandler // snapshots volumes with backupVolumes=true
+	uptime     *uptimeTracker
+	tags       *containerTags
+	stackEnv   *StackEnvHandler // environment files set in the containers of stacks
 }
 
 // NewContainerHandler creates new container handler
-func NewContainerHandler(client *podman.Client, eventStore *events.Store) *ContainerHandler {
-	return &ContainerHandler{client: client, eventStore: eventStore}
+func NewContainerHandler(client *podman.Client, eventStore *events.Store, drainer *drainer, trash *TrashHandler, volumes *VolumeHandler, uptime *uptimeTracker, tags *containerTags, stackEnv *StackEnvHandler) *ContainerHandler {
+	return &ContainerHandler{client: client, eventStore: eventStore, drainer: drainer, trash: trash, volumes: volumes, uptime: uptime, tags: tags, stackEnv: stackEnv}
 }
 
 // ContainerWithStats extends Container with resource stats
@@ -30,6 +42,7 @@ type ContainerWithStats struct {
 	Names       []string `json:"Names"`
 	Image       string   `json:"Image"`
 	State       string   `json:"State"`
+	Created     int64    `json:"Created"`
 	CPU         float64  `json:"CPU"`
 	MemUsage    uint64   `json:"MemUsage"`
 	MemLimit    uint64   `json:"MemLimit"`
@@ -39,15 +52,51 @@ type ContainerWithStats struct {
 	BlockInput  uint64   `json:"BlockInput"`
 	BlockOutput uint64   `json:"BlockOutput"`
 	PIDs        uint64   `json:"PIDs"`
+
+	Uptime *ContainerUptime `json:"Uptime"` // null before the first recorded state
+
+	Tags     []string `json:"Tags"`     // user-defined tags
+	Favorite bool     `json:"Favorite"` // favorite of the current user
+
+	App *AppInfo `json:"App"` // name, icon and homepage for tiles, null if unknown
+}
+
+// containerSortFields are the sort fields of the container list
+var containerSortFields = map[string]func(a, b ContainerWithStats) int{
+	"name":    func(a, b ContainerWithStats) int { return compareFold(firstOf(a.Names), firstOf(b.Names)) },
+	"image":   func(a, b ContainerWithStats) int { return compareFold(a.Image, b.Image) },
+	"state":   func(a, b ContainerWithStats) int { return compareFold(a.State, b.State) },
+	"created": func(a, b ContainerWithStats) int { return cmp.Compare(a.Created, b.Created) },
+	"cpu":     func(a, b ContainerWithStats) int { return cmp.Compare(a.CPU, b.CPU) },
+	"memory":  func(a, b ContainerWithStats) int { return cmp.Compare(a.MemUsage, b.MemUsage) },
+	"uptime":  func(a, b ContainerWithStats) int { return cmp.Compare(weekUptime(a), weekUptime(b)) },
+}
+
+// weekUptime returns the uptime over 7 days for sorting, -1 when unknown
+func weekUptime(c ContainerWithStats) float64 {
+	if c.Uptime == nil || c.Uptime.Week == nil {
+		return -1
+	}
+	return *c.Uptime.Week
 }
 
 // List handles GET /api/containers
+// GET /api/containers?page=1&limit=50&sort=-cpu&status=running&label=app=web&q=nginx&tag=media&favorite=true
+// Filters by state, label selector and name/image/ID search; see parseListQuery.
+// tag keeps containers with any of the given tags, favorite=true the
+// favorites of the user.
 func (h *ContainerHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	query, err := parseListQuery(r, slices.Sorted(maps.Keys(containerSortFields))...)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
 	containers, err := h.client.ListContainers(ctx)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -58,29 +107,65 @@ func (h *ContainerHandler) List(w http.ResponseWriter, r *http.Request) {
 		statsMap[stats[i].ContainerID] = &stats[i]
 	}
 
+	h.uptime.reconcile(containers)
+	now := time.Now()
+	user := auth.GetUserFromContext(ctx)
+	var tagFilter []string
+	for _, v := range r.URL.Query()["tag"] {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				tagFilter = append(tagFilter, tag)
+			}
+		}
+	}
+	onlyFavorites := r.URL.Query().Get("favorite") == "true"
+
 	// Build response with stats
-	result := make([]ContainerWithStats, len(containers))
-	for i, c := range containers {
-		result[i] = ContainerWithStats{
-			ID:    c.ID,
-			Names: c.Names,
-			Image: c.Image,
-			State: c.State,
+	result := make([]ContainerWithStats, 0, len(containers))
+	for _, c := range containers {
+		if !query.matchStatus(c.State) || !query.matchLabels(c.Labels) ||
+			!query.matchSearch(append([]string{c.ID, c.Image}, c.Names...)...) {
+			continue
+		}
+		name := strings.TrimPrefix(firstOf(c.Names), "/")
+		tags := h.tags.get(name)
+		favorite := h.tags.isFavorite(user.Username, name)
+		if (len(tagFilter) > 0 && !slices.ContainsFunc(tagFilter, func(tag string) bool { return slices.Contains(tags, tag) })) ||
+			(onlyFavorites && !favorite) {
+			continue
+		}
+		if tags == nil {
+			tags = []string{}
+		}
+		item := ContainerWithStats{
+			ID:       c.ID,
+			Names:    c.Names,
+			Image:    c.Image,
+			State:    c.State,
+			Uptime:   h.uptime.uptime(firstOf(c.Names), now),
+			Tags:     tags,
+			Favorite: favorite,
+			App:      resolveAppInfo(c.Image, c.Labels),
+		}
+		if !c.Created.IsZero() {
+			item.Created = c.Created.Unix()
 		}
 		if stat := statsMap[c.ID]; stat != nil {
-			result[i].CPU = stat.CPU
-			result[i].MemUsage = stat.MemUsage
-			result[i].MemLimit = stat.MemLimit
-			result[i].MemPerc = stat.MemPerc
-			result[i].NetInput = stat.NetInput
-			result[i].NetOutput = stat.NetOutput
-			result[i].BlockInput = stat.BlockInput
-			result[i].BlockOutput = stat.BlockOutput
-			result[i].PIDs = stat.PIDs
+			item.CPU = stat.CPU
+			item.MemUsage = stat.MemUsage
+			item.MemLimit = stat.MemLimit
+			item.MemPerc = stat.MemPerc
+			item.NetInput = stat.NetInput
+			item.NetOutput = stat.NetOutput
+			item.BlockInput = stat.BlockInput
+			item.BlockOutput = stat.BlockOutput
+			item.PIDs = stat.PIDs
 		}
+		result = append(result, item)
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	sortList(result, query, containerSortFields)
+	writeList(w, r, result, query)
 }
 
 // Inspect handles GET /api/containers/{id}
@@ -89,7 +174,7 @@ func (h *ContainerHandler) Inspect(w http.ResponseWriter, r *http.Request) {
 
 	info, err := h.client.InspectContainer(r.Context(), id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -100,7 +185,7 @@ func (h *ContainerHandler) Inspect(w http.ResponseWriter, r *http.Request) {
 func (h *ContainerHandler) Start(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -108,7 +193,7 @@ func (h *ContainerHandler) Start(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.client.StartContainer(r.Context(), id); err != nil {
 		h.eventStore.Add(events.EventContainerStart, user.Username, getClientIP(r), false, shortID(id))
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -120,7 +205,7 @@ func (h *ContainerHandler) Start(w http.ResponseWriter, r *http.Request) {
 func (h *ContainerHandler) Stop(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -128,7 +213,7 @@ func (h *ContainerHandler) Stop(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.client.StopContainer(r.Context(), id); err != nil {
 		h.eventStore.Add(events.EventContainerStop, user.Username, getClientIP(r), false, shortID(id))
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -140,7 +225,7 @@ func (h *ContainerHandler) Stop(w http.ResponseWriter, r *http.Request) {
 func (h *ContainerHandler) Restart(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -148,7 +233,7 @@ func (h *ContainerHandler) Restart(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.client.RestartContainer(r.Context(), id); err != nil {
 		h.eventStore.Add(events.EventContainerRestart, user.Username, getClientIP(r), false, shortID(id))
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -156,25 +241,84 @@ func (h *ContainerHandler) Restart(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "restarted"})
 }
 
-// Remove handles DELETE /api/containers/{id}
+// Remove handles DELETE /api/containers/{id}?force=true&trash=true&backupVolumes=true
+// With trash=true the spec of the container is kept first, so it can be
+// restored from /api/containers/trash. With backupVolumes=true its named
+// volumes are snapshotted first (see VolumeBackup).
 func (h *ContainerHandler) Remove(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	force := r.URL.Query().Get("force") == "true"
 
+	// Named volumes are saved while the container is stopped, so their data
+	// is consistent
+	var backups []VolumeBackup
+	if r.URL.Query().Get("backupVolumes") == "true" {
+		if _, err := h.volumes.snapshotDir(); err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+		info, err := h.client.InspectContainer(r.Context(), id)
+		if err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+		if info.State.Running {
+			if !force {
+				writeError(w, r, http.StatusConflict, "Container is running; stop it or remove it with force")
+				return
+			}
+			if err := h.client.StopContainer(r.Context(), info.ID); err != nil {
+				h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), false, shortID(id))
+				writeErr(w, r, err, "Failed to stop container")
+				return
+			}
+		}
+		if backups, err = h.volumes.snapshot(r.Context(), info, "remove", user.Username); err != nil {
+			h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), false, shortID(id))
+			writeErr(w, r, err, "Failed to back up volumes")
+			return
+		}
+	}
+
+	var kept *TrashEntry
+	if r.URL.Query().Get("trash") == "true" {
+		var err error
+		if kept, err = h.trash.keep(r.Context(), id, user.Username); err != nil {
+			h.volumes.discard(backups)
+			h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), false, shortID(id))
+			writeErr(w, r, err, "Failed to keep container in trash")
+			return
+		}
+	}
+
 	if err := h.client.RemoveContainer(r.Context(), id, force); err != nil {
+		if kept != nil {
+			h.trash.discard(kept.ID)
+		}
+		h.volumes.discard(backups)
 		h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), false, shortID(id))
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
-	h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), true, shortID(id))
-	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+	resp := map[string]interface{}{"status": "removed"}
+	if backups != nil {
+		resp["volumeBackups"] = backups
+	}
+	if kept == nil {
+		h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), true, shortID(id))
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	resp["trashId"] = kept.ID
+	h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), true, kept.Name+" (kept in trash)")
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // LogsResponse represents the response for container logs
@@ -183,6 +327,7 @@ type LogsResponse struct {
 }
 
 // Logs handles GET /api/containers/{id}/logs
+// Returns the last tail lines, newest first
 func (h *ContainerHandler) Logs(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -193,11 +338,25 @@ func (h *ContainerHandler) Logs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	logs, err := h.client.GetContainerLogs(r.Context(), id, tail)
+	lines, err := containerLogLines(r.Context(), h.client, id, tail)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
+	writeJSON(w, http.StatusOK, LogsResponse{Lines: lines})
+}
+
+// containerLogLines returns the last tail log lines of a container
+func containerLogLines(ctx context.Context, client *podman.Client, id string, tail int) ([]string, error) {
+	// Podman can't read the logs of the journald log driver
+	if info, err := client.InspectContainer(ctx, id); err == nil && info.HostConfig.LogConfig.Type == "journald" {
+		return journalLogLines(ctx, info.ID, tail)
+	}
+
+	logs, err := client.GetContainerLogs(ctx, id, tail)
+	if err != nil {
+		return nil, err
+	}
 
 	// Split logs into lines
 	var lines []string
@@ -208,8 +367,27 @@ func (h *ContainerHandler) Logs(w http.ResponseWriter, r *http.Request) {
 			lines = lines[:len(lines)-1]
 		}
 	}
+	return lines, nil
+}
 
-	writeJSON(w, http.StatusOK, LogsResponse{Lines: lines})
+// journalLogLines returns the last tail lines of a container from the
+// systemd journal, newest first
+func journalLogLines(ctx context.Context, id string, tail int) ([]string, error) {
+	logs, err := podman.JournalLogs(ctx, id, podman.LogOptions{Tail: tail})
+	if err != nil {
+		return nil, err
+	}
+	defer logs.Close()
+
+	lines := []string{}
+	if err := podman.ReadLogs(logs, func(line podman.LogLine) error {
+		lines = append(lines, line.Text)
+		return nil
+	}); err != nil {
+		return nil, apierror.New(http.StatusBadGateway, "Failed to read logs: "+err.Error())
+	}
+	slices.Reverse(lines)
+	return lines, nil
 }
 
 // CreateContainerRequest represents the request body for creating a container
@@ -220,28 +398,13 @@ type CreateContainerRequest struct {
 	Volumes string `json:"volumes"`
 	Env     string `json:"env"`
 	Command string `json:"command"`
-	Start   bool   `json:"start"`
+	// Requires lists containers ("db, cache") Podman starts before this one
+	Requires string `json:"requires"`
+	Start    bool   `json:"start"`
 }
 
-// Create handles POST /api/containers
-func (h *ContainerHandler) Create(w http.ResponseWriter, r *http.Request) {
-	user := auth.GetUserFromContext(r.Context())
-	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
-		return
-	}
-
-	var req CreateContainerRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
-		return
-	}
-
-	if req.Image == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Image is required"})
-		return
-	}
-
+// createConfig builds the Podman create config of the request
+func (req *CreateContainerRequest) createConfig() *podman.ContainerCreateConfig {
 	config := &podman.ContainerCreateConfig{
 		Image: req.Image,
 		Name:  req.Name,
@@ -267,10 +430,33 @@ func (h *ContainerHandler) Create(w http.ResponseWriter, r *http.Request) {
 		config.Mounts = parseVolumeMounts(req.Volumes)
 	}
 
-	result, err := h.client.CreateContainer(r.Context(), config)
+	config.Dependencies = parseNameList(req.Requires)
+	return config
+}
+
+// Create handles POST /api/containers
+func (h *ContainerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req CreateContainerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Image == "" {
+		writeError(w, r, http.StatusBadRequest, "Image is required")
+		return
+	}
+
+	result, err := h.client.CreateContainer(r.Context(), req.createConfig())
 	if err != nil {
 		h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), false, req.Image)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -296,6 +482,17 @@ func (h *ContainerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, map[string]string{"id": result.ID, "status": status})
 }
 
+// parseNameList parses container names from string like "db, cache"
+func parseNameList(names string) []string {
+	var list []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
 // parsePortMappings parses port mappings from string like "80:80, 443:443"
 func parsePortMappings(ports string) []podman.PortMapping {
 	var mappings []podman.PortMapping
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+// containerSpecVersion is the version of the ContainerSpec format
+const containerSpecVersion = 1
+
+// ContainerSpec is the portable creation spec of a container: everything
+// needed to create the same container on another host. Values inherited
+// from the image (command, environment, labels) are left out, so the spec
+// keeps working when the image is updated.
+type ContainerSpec struct {
+	Version        int               `json:"version"`
+	Name           string            `json:"name,omitempty"`
+	Image          string            `json:"image"`
+	Platform       string            `json:"platform,omitempty"` // os/architecture[/variant] to pull, if not the host's
+	Entrypoint     []string          `json:"entrypoint,omitempty"`
+	Command        []string          `json:"command,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Hostname       string            `json:"hostname,omitempty"`
+	User           string            `json:"user,omitempty"`
+	WorkingDir     string            `json:"workingDir,omitempty"`
+	Ports          []SpecPort        `json:"ports,omitempty"`
+	Mounts         []SpecMount       `json:"mounts,omitempty"`
+	NetworkMode    string            `json:"networkMode,omitempty"` // host, none, ...; networks if empty
+	Networks       []string          `json:"networks,omitempty"`
+	RestartPolicy  string            `json:"restartPolicy,omitempty"`
+	RestartRetries uint              `json:"restartRetries,omitempty"`
+	Privileged     bool              `json:"privileged,omitempty"`
+	CapAdd         []string          `json:"capAdd,omitempty"`
+	ReadOnly       bool              `json:"readOnly,omitempty"`
+	MemoryLimit    int64             `json:"memoryLimit,omitempty"` // bytes
+}
+
+// SpecPort is a published port of a ContainerSpec
+type SpecPort struct {
+	HostIP        string `json:"hostIp,omitempty"`
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// SpecMount is a bind mount (source is a host path) or a named volume
+// (source is the volume name) of a ContainerSpec
+type SpecMount struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ReadOnly    bool   `json:"readOnly,omitempty"`
+}
+
+// runtimeEnv are variables Podman sets in every container
+var runtimeEnv = []string{"HOSTNAME", "container"}
+
+// Export handles GET /api/containers/{id}/export
+func (h *ContainerHandler) Export(w http.ResponseWriter, r *http.Request) {
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	// Without the image the inherited values can't be told apart; keep them
+	image, err := h.client.InspectImage(r.Context(), info.Image)
+	if err != nil {
+		image = &podman.ImageInspect{}
+	}
+
+	spec := buildContainerSpec(info, image)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+spec.Name+`.json"`)
+	writeJSON(w, http.StatusOK, spec)
+}
+
+// buildContainerSpec builds the spec of a container created from image
+func buildContainerSpec(info *podman.ContainerInspect, image *podman.ImageInspect) *ContainerSpec {
+	spec := &ContainerSpec{
+		Version:    containerSpecVersion,
+		Name:       strings.TrimPrefix(info.Name, "/"),
+		Image:      info.ImageName,
+		User:       info.Config.User,
+		WorkingDir: info.Config.WorkingDir,
+		Privileged: info.HostConfig.Privileged,
+		CapAdd:     info.HostConfig.CapAdd,
+		ReadOnly:   info.HostConfig.ReadonlyRootfs,
+		Platform:   foreignPlatform(image),
+	}
+	if spec.Image == "" {
+		spec.Image = info.Image
+	}
+	if !slices.Equal(info.Config.Entrypoint, image.Config.Entrypoint) {
+		spec.Entrypoint = info.Config.Entrypoint
+	}
+	if !slices.Equal(info.Config.Cmd, image.Config.Cmd) {
+		spec.Command = info.Config.Cmd
+	}
+	if info.Config.WorkingDir == "/" {
+		spec.WorkingDir = ""
+	}
+	// Podman defaults the hostname to the short ID
+	if info.Config.Hostname != shortID(info.ID) {
+		spec.Hostname = info.Config.Hostname
+	}
+	if info.HostConfig.Memory > 0 {
+		spec.MemoryLimit = info.HostConfig.Memory
+	}
+
+	imageEnv := parseEnvList(image.Config.Env)
+	for name, value := range parseEnvList(info.Config.Env) {
+		if inherited, ok := imageEnv[name]; (ok && inherited == value) || slices.Contains(runtimeEnv, name) {
+			continue
+		}
+		if spec.Env == nil {
+			spec.Env = make(map[string]string)
+		}
+		spec.Env[name] = value
+	}
+	for name, value := range info.Config.Labels {
+		if inherited, ok := image.Config.Labels[name]; ok && inherited == value {
+			continue
+		}
+		if spec.Labels == nil {
+			spec.Labels = make(map[string]string)
+		}
+		spec.Labels[name] = value
+	}
+
+	for _, port := range slices.Sorted(maps.Keys(info.HostConfig.PortBindings)) {
+		number, protocol, _ := strings.Cut(port, "/")
+		containerPort, err := strconv.Atoi(number)
+		if err != nil {
+			continue
+		}
+		for _, binding := range info.HostConfig.PortBindings[port] {
+			hostPort, _ := strconv.Atoi(binding.HostPort)
+			spec.Ports = append(spec.Ports, SpecPort{
+				HostIP:        binding.HostIP,
+				HostPort:      hostPort,
+				ContainerPort: containerPort,
+				Protocol:      protocol,
+			})
+		}
+	}
+
+	for _, m := range info.Mounts {
+		switch m.Type {
+		case "bind":
+			spec.Mounts = append(spec.Mounts, SpecMount{Type: "bind", Source: m.Source, Destination: m.Destination, ReadOnly: !m.RW})
+		case "volume":
+			spec.Mounts = append(spec.Mounts, SpecMount{Type: "volume", Source: m.Name, Destination: m.Destination, ReadOnly: !m.RW})
+		}
+	}
+
+	switch mode := info.HostConfig.NetworkMode; mode {
+	case "", "bridge", "default":
+		spec.Networks = slices.Sorted(maps.Keys(info.NetworkSettings.Networks))
+	default:
+		spec.NetworkMode = mode
+	}
+
+	if policy := info.HostConfig.RestartPolicy; policy.Name != "" && policy.Name != "no" {
+		spec.RestartPolicy = policy.Name
+		spec.RestartRetries = policy.MaximumRetryCount
+	}
+	return spec
+}
+
+// parseEnvList parses KEY=value entries into a map
+func parseEnvList(env []string) map[string]string {
+	vars := make(map[string]string, len(env))
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		vars[name] = value
+	}
+	return vars
+}
+
+// Import handles POST /api/containers/import. The body is a ContainerSpec;
+// the name query parameter overrides its name and start=true starts the
+// container. The image is pulled if it isn't available.
+func (h *ContainerHandler) Import(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var spec ContainerSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if spec.Version > containerSpecVersion {
+		writeError(w, r, http.StatusBadRequest, "Unsupported spec version: "+strconv.Itoa(spec.Version))
+		return
+	}
+	if spec.Image == "" {
+		writeError(w, r, http.StatusBadRequest, "Image is required")
+		return
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		spec.Name = name
+	}
+	config, err := spec.createConfig()
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	if _, err := h.client.InspectImage(r.Context(), spec.Image); err != nil {
+		if err := h.client.PullImagePlatform(r.Context(), spec.Image, spec.Platform); err != nil {
+			h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), false, spec.Image)
+			writeErr(w, r, err, "Failed to pull image")
+			return
+		}
+	}
+
+	result, err := h.client.CreateContainer(r.Context(), config)
+	if err != nil {
+		h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), false, spec.Image)
+		writeErr(w, r, err, "")
+		return
+	}
+	h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), true, shortID(result.ID))
+
+	status := "created"
+	if r.URL.Query().Get("start") == "true" {
+		if err := h.client.StartContainer(r.Context(), result.ID); err != nil {
+			writeJSON(w, http.StatusCreated, map[string]string{
+				"id":      result.ID,
+				"status":  status,
+				"warning": "Container created but failed to start: " + err.Error(),
+			})
+			return
+		}
+		status = "started"
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"id": result.ID, "status": status})
+}
+
+// createConfig converts the spec into Podman create options
+func (s *ContainerSpec) createConfig() (*podman.ContainerCreateConfig, error) {
+	if !validPlatform(s.Platform) {
+		return nil, apierror.New(http.StatusBadRequest, "Invalid platform: "+s.Platform)
+	}
+	config := &podman.ContainerCreateConfig{
+		Name:         s.Name,
+		Image:        s.Image,
+		Entrypoint:   s.Entrypoint,
+		Command:      s.Command,
+		Env:          s.Env,
+		Labels:       s.Labels,
+		Hostname:     s.Hostname,
+		User:         s.User,
+		WorkDir:      s.WorkingDir,
+		Restart:      s.RestartPolicy,
+		RestartTries: s.RestartRetries,
+		Privileged:   s.Privileged,
+		CapAdd:       s.CapAdd,
+		ReadOnly:     s.ReadOnly,
+	}
+
+	for _, p := range s.Ports {
+		if p.ContainerPort <= 0 || p.ContainerPort > 65535 || p.HostPort < 0 || p.HostPort > 65535 {
+			return nil, apierror.New(http.StatusBadRequest, "Invalid port: "+strconv.Itoa(p.HostPort)+":"+strconv.Itoa(p.ContainerPort))
+		}
+		config.PortMappings = append(config.PortMappings, podman.PortMapping{
+			ContainerPort: p.ContainerPort,
+			HostPort:      p.HostPort,
+			HostIP:        p.HostIP,
+			Protocol:      p.Protocol,
+		})
+	}
+
+	for _, m := range s.Mounts {
+		if m.Source == "" || !strings.HasPrefix(m.Destination, "/") {
+			return nil, apierror.New(http.StatusBadRequest, "Invalid mount: "+m.Source+":"+m.Destination)
+		}
+		var options []string
+		if m.ReadOnly {
+			options = []string{"ro"}
+		}
+		switch m.Type {
+		case "bind":
+			config.Mounts = append(config.Mounts, podman.Mount{Type: "bind", Source: m.Source, Destination: m.Destination, Options: options})
+		case "volume":
+			config.Volumes = append(config.Volumes, podman.NamedVolume{Name: m.Source, Dest: m.Destination, Options: options})
+		default:
+			return nil, apierror.New(http.StatusBadRequest, "Invalid mount type, expected bind or volume: "+m.Type)
+		}
+	}
+
+	if s.NetworkMode != "" {
+		config.NetNS = &podman.Namespace{Mode: s.NetworkMode}
+	} else if len(s.Networks) > 0 {
+		config.NetNS = &podman.Namespace{Mode: "bridge"}
+		config.Networks = make(map[string]interface{}, len(s.Networks))
+		for _, network := range s.Networks {
+			config.Networks[network] = map[string]interface{}{}
+		}
+	}
+
+	if s.MemoryLimit > 0 {
+		config.Resources = &podman.Resources{}
+		config.Resources.Memory.Limit = s.MemoryLimit
+	}
+	return config, nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight response, in seconds
+const corsMaxAge = "600"
+
+// corsExposedHeaders are the response headers readable by cross-origin clients
+const corsExposedHeaders = "ETag, Last-Modified, X-Request-Id, Deprecation, Sunset, Link"
+
+// cors answers preflight requests and adds CORS headers to responses for
+// the configured origins. Requests from other origins get no CORS headers,
+// so browsers block them. Credentials (the session cookie) are allowed for
+// listed origins but not for the "*" wildcard.
+type cors struct {
+	origins []string
+	any     bool // "*" allows any origin, without credentials
+	methods string
+	headers string
+}
+
+// newCORS creates the CORS middleware, nil when no origins are configured
+func newCORS(origins, methods, headers []string) *cors {
+	if len(origins) == 0 {
+		return nil
+	}
+	return &cors{
+		origins: origins,
+		any:     slices.Contains(origins, "*"),
+		methods: strings.Join(methods, ", "),
+		headers: strings.Join(headers, ", "),
+	}
+}
+
+// Middleware must run before authentication, since preflight requests
+// carry no credentials
+func (c *cors) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		allowed := c.any || slices.Contains(c.origins, origin)
+		if allowed {
+			if slices.Contains(c.origins, origin) {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Credentials", "true")
+			} else {
+				h.Set("Access-Control-Allow-Origin", "*")
+			}
+		}
+
+		if !preflight {
+			if allowed {
+				h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed {
+			h.Set("Access-Control-Allow-Methods", c.methods)
+			h.Set("Access-Control-Allow-Headers", c.headers)
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
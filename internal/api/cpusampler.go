@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"podmanview/internal/idle"
+)
+
+const (
+	// hostSampleInterval is how often the background collector samples
+	// CPU usage and disk I/O
+	hostSampleInterval = 2 * time.Second
+	// hostSampleMaxAge is the age after which samplers sample by themselves
+	// when read, as the collector is not running
+	hostSampleMaxAge = 30 * time.Second
+	// hostPrimeWindow is how long the first read measures when the sampler
+	// was created too recently for a delta
+	hostPrimeWindow = 100 * time.Millisecond
+)
+
+// hostCPU samples the CPU usage of the host for the dashboard, GraphQL and
+// alert rules. It is sampled by Server.StartHostSampling.
+var hostCPU = NewCPUSampler(readCPUStat)
+
+// CPUTimesFunc returns the total and idle CPU time since boot, in ticks,
+// or zeros when they can't be read
+type CPUTimesFunc func() (total, idle int64)
+
+// CPUSampler measures CPU usage as the share of non-idle time between two
+// readings. A background collector calls Sample periodically and callers
+// read the result with Usage, so concurrent callers don't shorten each
+// other's measurement windows.
+type CPUSampler struct {
+	read CPUTimesFunc
+
+	mu        sync.Mutex
+	prevTotal int64
+	prevIdle  int64
+	usage     float64   // percent, from the last sample
+	sampledAt time.Time // zero until a sample had a delta
+}
+
+// NewCPUSampler creates a sampler and takes the first reading, so the
+// first sample has a delta to compare to
+func NewCPUSampler(read CPUTimesFunc) *CPUSampler {
+	s := &CPUSampler{read: read}
+	s.prevTotal, s.prevIdle = read()
+	return s
+}
+
+// Sample measures the CPU usage since the previous sample and returns it in
+// percent (0-100). Keeps the last usage when the times can't be read or
+// haven't advanced.
+func (s *CPUSampler) Sample() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sampleLocked()
+}
+
+// sampleLocked is Sample with s.mu held
+func (s *CPUSampler) sampleLocked() float64 {
+	total, idle := s.read()
+	if total == 0 {
+		return s.usage
+	}
+	totalDelta := total - s.prevTotal
+	idleDelta := idle - s.prevIdle
+	if totalDelta <= 0 {
+		return s.usage
+	}
+	s.prevTotal, s.prevIdle = total, idle
+
+	// CPU usage = (total - idle) / total * 100
+	usage := float64(totalDelta-idleDelta) / float64(totalDelta) * 100
+	if usage < 0 {
+		usage = 0
+	} else if usage > 100 {
+		usage = 100
+	}
+	s.usage = usage
+	s.sampledAt = time.Now()
+	return usage
+}
+
+// Usage returns the CPU usage in percent from the last sample. Samples by
+// itself when there is no recent sample; the very first call waits
+// briefly if the sampler was just created, instead of returning 0.
+func (s *CPUSampler) Usage() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.sampledAt.IsZero() && time.Since(s.sampledAt) < hostSampleMaxAge {
+		return s.usage
+	}
+	s.sampleLocked()
+	if s.sampledAt.IsZero() {
+		time.Sleep(hostPrimeWindow)
+		s.sampleLocked()
+	}
+	return s.usage
+}
+
+// Run samples every interval until ctx is cancelled, less often while idle
+func (s *CPUSampler) Run(ctx context.Context, interval time.Duration, idleMonitor *idle.Monitor) {
+	for {
+		s.Sample()
+		if !idleMonitor.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// StartHostSampling samples host CPU usage and disk I/O in the background
+// until ctx is cancelled, so dashboards, GraphQL and alert rules read
+// shared values
+func (s *Server) StartHostSampling(ctx context.Context) {
+	go hostCPU.Run(ctx, hostSampleInterval, s.idle)
+	go hostDiskIO.Run(ctx, hostSampleInterval, s.idle)
+}
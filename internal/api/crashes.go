@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// crashBucket is the storage namespace of container exit histories
+const crashBucket = "crashes"
+
+const (
+	// crashRetention is how long exits are kept
+	crashRetention = 30 * 24 * time.Hour
+
+	// maxContainerExits limits the exits kept per container
+	maxContainerExits = 200
+
+	// crashLoopCount crashes within crashLoopWindow raise a
+	// container_crash_loop event, which is raised again only after
+	// the container stayed below the count for a window
+	crashLoopCount  = 3
+	crashLoopWindow = 10 * time.Minute
+
+	// maxCrashDays limits the period of the exit history endpoints
+	maxCrashDays = 30
+)
+
+// ContainerExit is an exit of a container recorded from a Podman "died" event
+type ContainerExit struct {
+	Time      time.Time `json:"time"`
+	ID        string    `json:"id"` // the container is recreated under the same name with a new ID
+	ExitCode  int       `json:"exitCode"`
+	OOMKilled bool      `json:"oomKilled"`
+	Crash     bool      `json:"crash"` // OOM kill or exit code other than 0 and 143 (regular stop)
+}
+
+// UnstableContainer sums up the crashes of a container
+type UnstableContainer struct {
+	Name         string    `json:"name"`
+	Crashes      int       `json:"crashes"`
+	OOMKills     int       `json:"oomKills"`
+	Exits        int       `json:"exits"`
+	LastExitCode int       `json:"lastExitCode"`
+	LastCrash    time.Time `json:"lastCrash"`
+}
+
+// crashTracker records the exits of containers by name and raises
+// container_crash_loop events for containers failing repeatedly
+type crashTracker struct {
+	client     *podman.Client
+	storage    storage.Storage // nil keeps the history in memory only
+	eventStore *events.Store
+	logger     *logger.Logger
+
+	mu      sync.Mutex
+	exits   map[string][]ContainerExit // by container name, oldest first
+	looping map[string]bool            // crash loop raised and not cleared yet
+}
+
+// newCrashTracker creates a crash tracker and loads the stored histories
+func newCrashTracker(client *podman.Client, store storage.Storage, eventStore *events.Store, appLogger *logger.Logger) *crashTracker {
+	t := &crashTracker{
+		client:     client,
+		storage:    store,
+		eventStore: eventStore,
+		logger:     appLogger,
+		exits:      make(map[string][]ContainerExit),
+		looping:    make(map[string]bool),
+	}
+	if store == nil {
+		return t
+	}
+
+	data, err := store.List(crashBucket)
+	if err != nil {
+		appLogger.Warn("Failed to load container exits", logger.KeyError, err)
+		return t
+	}
+	for name, value := range data {
+		var exits []ContainerExit
+		if err := json.Unmarshal(value, &exits); err != nil {
+			appLogger.Warn("Failed to load container exits", "container", name, logger.KeyError, err)
+			continue
+		}
+		t.exits[name] = exits
+	}
+	return t
+}
+
+// observe records the exit of a container from a Podman "died" event
+func (t *crashTracker) observe(ctx context.Context, event podman.EngineEvent) {
+	if event.Type != "container" || event.Action != "died" {
+		return
+	}
+	code, err := strconv.Atoi(event.Actor.Attributes["containerExitCode"])
+	if err != nil {
+		return
+	}
+	name := event.Actor.Attributes["name"]
+	if name == "" {
+		name = shortID(event.Actor.ID)
+	}
+
+	exit := ContainerExit{Time: time.Now(), ID: event.Actor.ID, ExitCode: code}
+	if event.TimeNano > 0 {
+		exit.Time = time.Unix(0, event.TimeNano)
+	}
+	// The event doesn't tell OOM kills from other SIGKILLs
+	if code == 137 {
+		if info, err := t.client.InspectContainer(ctx, event.Actor.ID); err == nil {
+			exit.OOMKilled = info.State.OOMKilled
+		}
+	}
+	exit.Crash = exit.OOMKilled || (code != 0 && code != 143)
+
+	t.mu.Lock()
+	exits := append(t.exits[name], exit)
+	exits = pruneExits(exits, exit.Time)
+	t.exits[name] = exits
+
+	recent := 0
+	for _, e := range exits {
+		if e.Crash && exit.Time.Sub(e.Time) < crashLoopWindow {
+			recent++
+		}
+	}
+	raise := recent >= crashLoopCount && !t.looping[name]
+	t.looping[name] = recent >= crashLoopCount
+	t.mu.Unlock()
+
+	if t.storage != nil {
+		if err := t.storage.SetJSON(crashBucket, name, exits); err != nil {
+			t.logger.Warn("Failed to save container exits", "container", name, logger.KeyError, err)
+		}
+	}
+	if raise {
+		details := fmt.Sprintf("%s crashed %d times in %s, last exit code %d", name, recent, crashLoopWindow, code)
+		if exit.OOMKilled {
+			details += " (out of memory)"
+		}
+		t.eventStore.Add(events.EventContainerCrash, "system", "", false, details)
+	}
+}
+
+// pruneExits drops exits older than the retention and over the limit
+func pruneExits(exits []ContainerExit, now time.Time) []ContainerExit {
+	start := 0
+	for start < len(exits) && now.Sub(exits[start].Time) > crashRetention {
+		start++
+	}
+	start = max(start, len(exits)-maxContainerExits)
+	return exits[start:]
+}
+
+// history returns the exits of a container since a time, newest first
+func (t *crashTracker) history(name string, since time.Time) []ContainerExit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := []ContainerExit{}
+	exits := t.exits[name]
+	for i := len(exits) - 1; i >= 0 && exits[i].Time.After(since); i-- {
+		list = append(list, exits[i])
+	}
+	return list
+}
+
+// unstable returns the containers that crashed since a time, most crashes first
+func (t *crashTracker) unstable(since time.Time) []UnstableContainer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := []UnstableContainer{}
+	for name, exits := range t.exits {
+		summary := UnstableContainer{Name: name}
+		for _, exit := range exits {
+			if !exit.Time.After(since) {
+				continue
+			}
+			summary.Exits++
+			summary.LastExitCode = exit.ExitCode
+			if exit.Crash {
+				summary.Crashes++
+				summary.LastCrash = exit.Time
+			}
+			if exit.OOMKilled {
+				summary.OOMKills++
+			}
+		}
+		if summary.Crashes > 0 {
+			list = append(list, summary)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Crashes != list[j].Crashes {
+			return list[i].Crashes > list[j].Crashes
+		}
+		if list[i].OOMKills != list[j].OOMKills {
+			return list[i].OOMKills > list[j].OOMKills
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// CrashHandler serves the exit and crash history of containers
+type CrashHandler struct {
+	client  *podman.Client
+	crashes *crashTracker
+}
+
+// NewCrashHandler creates new crash history handler
+func NewCrashHandler(client *podman.Client, crashes *crashTracker) *CrashHandler {
+	return &CrashHandler{client: client, crashes: crashes}
+}
+
+// parseDays reads the days parameter, the period of the history
+func parseDays(w http.ResponseWriter, r *http.Request, def int) (time.Time, bool) {
+	days := def
+	if v := r.URL.Query().Get("days"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxCrashDays {
+			writeError(w, r, http.StatusBadRequest, "Invalid days, maximum: "+strconv.Itoa(maxCrashDays))
+			return time.Time{}, false
+		}
+		days = parsed
+	}
+	return time.Now().Add(-time.Duration(days) * 24 * time.Hour), true
+}
+
+// Exits handles GET /api/containers/{id}/exits?days=30
+// Returns the exits of the container, newest first. The history is kept by
+// name, so it covers earlier containers of the same name and removed ones.
+func (h *CrashHandler) Exits(w http.ResponseWriter, r *http.Request) {
+	since, ok := parseDays(w, r, maxCrashDays)
+	if !ok {
+		return
+	}
+
+	name := chi.URLParam(r, "id")
+	info, err := h.client.InspectContainer(r.Context(), name)
+	if err == nil {
+		name = strings.TrimPrefix(info.Name, "/")
+	}
+	exits := h.crashes.history(name, since)
+	if err != nil && len(exits) == 0 {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	crashes, oomKills := 0, 0
+	for _, exit := range exits {
+		if exit.Crash {
+			crashes++
+		}
+		if exit.OOMKilled {
+			oomKills++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"container": name,
+		"exits":     exits,
+		"crashes":   crashes,
+		"oomKills":  oomKills,
+	})
+}
+
+// Unstable handles GET /api/containers/unstable?days=7&limit=10
+// Returns the containers with the most crashes in the period
+func (h *CrashHandler) Unstable(w http.ResponseWriter, r *http.Request) {
+	since, ok := parseDays(w, r, 7)
+	if !ok {
+		return
+	}
+	limit := 10 // default
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	list := h.crashes.unstable(since)
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"containers": list,
+	})
+}
@@ -0,0 +1,502 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/plugins"
+	"podmanview/internal/storage"
+)
+
+const (
+	// dashboardsBucket is the storage namespace of dashboard layouts. User
+	// layouts are stored as "<username>/<id>", the layout for new users as
+	// defaultLayoutKey.
+	dashboardsBucket = "dashboards"
+	defaultLayoutKey = "default"
+
+	// dashboardColumns is the width of the dashboard grid
+	dashboardColumns = 12
+	// maxWidgetHeight limits the height of a widget in grid rows
+	maxWidgetHeight = 24
+	// maxWidgets limits the widgets of a layout
+	maxWidgets = 50
+	// maxLayoutName limits the length of layout names
+	maxLayoutName = 64
+	// defaultEventLimit and maxEventLimit are the events shown by event feeds
+	defaultEventLimit = 10
+	maxEventLimit     = 100
+)
+
+// Widget types
+const (
+	WidgetHostStats = "host_stats" // CPU, memory, disk and load of the host
+	WidgetContainer = "container"  // state and stats of one container
+	WidgetPlugin    = "plugin"     // panel of a plugin
+	WidgetEvents    = "events"     // latest events
+)
+
+var (
+	errLayoutName       = apierror.New(http.StatusBadRequest, fmt.Sprintf("Layout name is required, up to %d characters", maxLayoutName))
+	errLayoutExists     = apierror.New(http.StatusConflict, "A layout with this name already exists")
+	errLayoutNotFound   = apierror.New(http.StatusNotFound, "Layout not found")
+	errTooManyWidgets   = apierror.New(http.StatusBadRequest, fmt.Sprintf("A layout can have up to %d widgets", maxWidgets))
+	errWidgetType       = apierror.New(http.StatusBadRequest, "Invalid widget type, use host_stats, container, plugin or events")
+	errWidgetPosition   = apierror.New(http.StatusBadRequest, fmt.Sprintf("Widget position must fit in %d columns, with a height from 1 to %d rows", dashboardColumns, maxWidgetHeight))
+	errWidgetID         = apierror.New(http.StatusBadRequest, "Widget IDs must be unique")
+	errWidgetContainer  = apierror.New(http.StatusBadRequest, "Container widgets need a container")
+	errWidgetPlugin     = apierror.New(http.StatusBadRequest, "Plugin not found")
+	errWidgetCategory   = apierror.New(http.StatusBadRequest, "Invalid event category")
+	errWidgetEventLimit = apierror.New(http.StatusBadRequest, fmt.Sprintf("Event limit must be between 1 and %d", maxEventLimit))
+)
+
+// Widget is a panel of a dashboard, placed on a grid of dashboardColumns
+// columns. Which fields apply depends on the type.
+type Widget struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title,omitempty"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	W         int    `json:"w"`
+	H         int    `json:"h"`
+	Container string `json:"container,omitempty"` // container widgets: name or ID
+	Plugin    string `json:"plugin,omitempty"`    // plugin widgets: plugin name
+	Category  string `json:"category,omitempty"`  // event feeds: only this category
+	Limit     int    `json:"limit,omitempty"`     // event feeds: number of events
+}
+
+// DashboardLayout is a named set of widgets of a user. The default layout
+// is shown after login.
+type DashboardLayout struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Default   bool      `json:"default"`
+	Widgets   []Widget  `json:"widgets"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// builtinLayout is the layout for new users until an admin sets another
+func builtinLayout() DashboardLayout {
+	return DashboardLayout{
+		Name: "Overview",
+		Widgets: []Widget{
+			{ID: "host", Type: WidgetHostStats, X: 0, Y: 0, W: 8, H: 4},
+			{ID: "events", Type: WidgetEvents, X: 8, Y: 0, W: 4, H: 8, Limit: defaultEventLimit},
+		},
+	}
+}
+
+// DashboardHandler stores the dashboard layouts of users
+type DashboardHandler struct {
+	storage    storage.Storage
+	server     *Server // plugins for plugin widgets
+	eventStore *events.Store
+
+	// mu serializes changes, which can touch several layouts of a user
+	mu sync.Mutex
+}
+
+// NewDashboardHandler creates new dashboard handler
+func NewDashboardHandler(store storage.Storage, server *Server, eventStore *events.Store) *DashboardHandler {
+	return &DashboardHandler{storage: store, server: server, eventStore: eventStore}
+}
+
+// available checks that storage is available
+func (h *DashboardHandler) available(w http.ResponseWriter, r *http.Request) bool {
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return false
+	}
+	return true
+}
+
+// List handles GET /api/dashboards
+// Returns the layouts of the user by name. Users without layouts get a
+// copy of the default layout.
+func (h *DashboardHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	layouts, err := h.layouts(user.Username)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read layouts")
+		return
+	}
+	if len(layouts) == 0 {
+		layout, err := h.defaultLayout()
+		if err != nil {
+			writeErr(w, r, err, "Failed to read layouts")
+			return
+		}
+		layout.Default = true
+		if err := h.save(user.Username, &layout); err != nil {
+			writeErr(w, r, err, "Failed to save layout")
+			return
+		}
+		layouts = append(layouts, layout)
+	}
+	writeJSON(w, http.StatusOK, layouts)
+}
+
+// Get handles GET /api/dashboards/{id}
+func (h *DashboardHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	layout, err := h.get(user.Username, chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, layout)
+}
+
+// Create handles POST /api/dashboards
+func (h *DashboardHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	var layout DashboardLayout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	layout.ID = ""
+	if err := h.validate(&layout); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.put(user.Username, &layout); err != nil {
+		writeErr(w, r, err, "Failed to save layout")
+		return
+	}
+	writeJSON(w, http.StatusCreated, layout)
+}
+
+// Update handles PUT /api/dashboards/{id}
+// The body replaces the layout.
+func (h *DashboardHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	var layout DashboardLayout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.validate(&layout); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	existing, err := h.get(user.Username, chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	layout.ID, layout.CreatedAt = existing.ID, existing.CreatedAt
+	// A layout stays the default until another one is made the default
+	layout.Default = layout.Default || existing.Default
+	if err := h.put(user.Username, &layout); err != nil {
+		writeErr(w, r, err, "Failed to save layout")
+		return
+	}
+	writeJSON(w, http.StatusOK, layout)
+}
+
+// Delete handles DELETE /api/dashboards/{id}
+// When the default layout is deleted, the first remaining one by name
+// becomes the default.
+func (h *DashboardHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	layout, err := h.get(user.Username, chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if err := h.storage.Delete(dashboardsBucket, user.Username+"/"+layout.ID); err != nil {
+		writeErr(w, r, err, "Failed to delete layout")
+		return
+	}
+	if layout.Default {
+		layouts, err := h.layouts(user.Username)
+		if err == nil && len(layouts) > 0 {
+			layouts[0].Default = true
+			err = h.storage.SetJSON(dashboardsBucket, user.Username+"/"+layouts[0].ID, layouts[0])
+		}
+		if err != nil {
+			writeErr(w, r, err, "Failed to save layout")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted", "name": layout.Name})
+}
+
+// GetDefault handles GET /api/dashboards/default
+// Returns the layout new users start with
+func (h *DashboardHandler) GetDefault(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	layout, err := h.defaultLayout()
+	if err != nil {
+		writeErr(w, r, err, "Failed to read layouts")
+		return
+	}
+	writeJSON(w, http.StatusOK, layout)
+}
+
+// SetDefault handles PUT /api/dashboards/default
+// Sets the layout new users start with. Existing layouts are not changed.
+func (h *DashboardHandler) SetDefault(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if !h.available(w, r) {
+		return
+	}
+
+	var layout DashboardLayout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.validate(&layout); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	layout.ID, layout.Default, layout.CreatedAt = "", false, time.Time{}
+	layout.UpdatedAt = time.Now()
+	if err := h.storage.SetJSON(dashboardsBucket, defaultLayoutKey, layout); err != nil {
+		h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), false, "default dashboard")
+		writeErr(w, r, err, "Failed to save layout")
+		return
+	}
+	h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), true, "default dashboard")
+	writeJSON(w, http.StatusOK, layout)
+}
+
+// ResetDefault handles DELETE /api/dashboards/default
+// New users start with the built-in layout again
+func (h *DashboardHandler) ResetDefault(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if !h.available(w, r) {
+		return
+	}
+
+	if err := h.storage.Delete(dashboardsBucket, defaultLayoutKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		writeErr(w, r, err, "Failed to delete layout")
+		return
+	}
+	h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), true, "default dashboard reset")
+	writeJSON(w, http.StatusOK, builtinLayout())
+}
+
+// layouts returns the layouts of a user by name
+func (h *DashboardHandler) layouts(username string) ([]DashboardLayout, error) {
+	data, err := h.storage.ListPrefix(dashboardsBucket, username+"/")
+	if err != nil {
+		return nil, err
+	}
+	layouts := make([]DashboardLayout, 0, len(data))
+	for _, value := range data {
+		var layout DashboardLayout
+		if json.Unmarshal(value, &layout) == nil {
+			layouts = append(layouts, layout)
+		}
+	}
+	sort.Slice(layouts, func(i, j int) bool {
+		return strings.ToLower(layouts[i].Name) < strings.ToLower(layouts[j].Name)
+	})
+	return layouts, nil
+}
+
+// get returns a layout of a user
+func (h *DashboardHandler) get(username, id string) (*DashboardLayout, error) {
+	var layout DashboardLayout
+	if err := h.storage.GetJSON(dashboardsBucket, username+"/"+id, &layout); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errLayoutNotFound
+		}
+		return nil, err
+	}
+	return &layout, nil
+}
+
+// defaultLayout returns the layout for new users
+func (h *DashboardHandler) defaultLayout() (DashboardLayout, error) {
+	var layout DashboardLayout
+	err := h.storage.GetJSON(dashboardsBucket, defaultLayoutKey, &layout)
+	if errors.Is(err, storage.ErrNotFound) {
+		return builtinLayout(), nil
+	}
+	return layout, err
+}
+
+// put checks that the name is unique and saves a layout of a user. A new
+// default layout replaces the previous one. Call with h.mu held.
+func (h *DashboardHandler) put(username string, layout *DashboardLayout) error {
+	layouts, err := h.layouts(username)
+	if err != nil {
+		return err
+	}
+	for _, other := range layouts {
+		if other.ID != layout.ID && strings.EqualFold(other.Name, layout.Name) {
+			return errLayoutExists
+		}
+	}
+	if len(layouts) == 0 || (len(layouts) == 1 && layouts[0].ID == layout.ID) {
+		layout.Default = true
+	}
+	if err := h.save(username, layout); err != nil {
+		return err
+	}
+	if layout.Default {
+		for _, other := range layouts {
+			if other.ID != layout.ID && other.Default {
+				other.Default = false
+				if err := h.storage.SetJSON(dashboardsBucket, username+"/"+other.ID, other); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// save stores a layout of a user, assigning an ID to new layouts
+func (h *DashboardHandler) save(username string, layout *DashboardLayout) error {
+	now := time.Now()
+	if layout.ID == "" {
+		id, err := randomHex(8)
+		if err != nil {
+			return err
+		}
+		layout.ID, layout.CreatedAt = id, now
+	}
+	layout.UpdatedAt = now
+	return h.storage.SetJSON(dashboardsBucket, username+"/"+layout.ID, layout)
+}
+
+// validate checks a layout and fills in widget IDs and defaults
+func (h *DashboardHandler) validate(layout *DashboardLayout) error {
+	layout.Name = strings.TrimSpace(layout.Name)
+	if layout.Name == "" || len(layout.Name) > maxLayoutName {
+		return errLayoutName
+	}
+	if layout.Widgets == nil {
+		layout.Widgets = []Widget{}
+	}
+	if len(layout.Widgets) > maxWidgets {
+		return errTooManyWidgets
+	}
+
+	ids := make(map[string]bool, len(layout.Widgets))
+	for i := range layout.Widgets {
+		widget := &layout.Widgets[i]
+		if widget.ID == "" {
+			id, err := randomHex(4)
+			if err != nil {
+				return err
+			}
+			widget.ID = id
+		}
+		if ids[widget.ID] {
+			return errWidgetID
+		}
+		ids[widget.ID] = true
+
+		if widget.X < 0 || widget.Y < 0 || widget.W < 1 || widget.X+widget.W > dashboardColumns || widget.H < 1 || widget.H > maxWidgetHeight {
+			return errWidgetPosition
+		}
+		switch widget.Type {
+		case WidgetHostStats:
+		case WidgetContainer:
+			widget.Container = strings.TrimSpace(widget.Container)
+			if widget.Container == "" {
+				return errWidgetContainer
+			}
+		case WidgetPlugin:
+			if !h.server.hasPlugin(widget.Plugin) {
+				return errWidgetPlugin
+			}
+		case WidgetEvents:
+			if widget.Category != "" && !slices.Contains(events.Categories, events.Category(widget.Category)) {
+				return errWidgetCategory
+			}
+			if widget.Limit == 0 {
+				widget.Limit = defaultEventLimit
+			}
+			if widget.Limit < 1 || widget.Limit > maxEventLimit {
+				return errWidgetEventLimit
+			}
+		default:
+			return errWidgetType
+		}
+	}
+	return nil
+}
+
+// hasPlugin reports whether a plugin is registered, enabled or not
+func (s *Server) hasPlugin(name string) bool {
+	if name == "" {
+		return false
+	}
+	if s.pluginRegistry != nil {
+		_, ok := s.pluginRegistry.Get(name)
+		return ok
+	}
+	return slices.ContainsFunc(s.plugins, func(p plugins.Plugin) bool { return p.Name() == name })
+}
+
+// randomHex returns n random bytes in hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"podmanview/internal/auth"
+)
+
+// demoReadPaths are the API paths readable in demo mode. Everything else
+// under /api is rejected: changes, terminals, files, logs, configuration,
+// command history and backups would expose or modify the host.
+var demoReadPaths = map[string]bool{
+	"/api/health":                 true,
+	"/api/openapi.json":           true,
+	"/api/docs":                   true,
+	"/api/auth/me":                true,
+	"/api/events":                 true,
+	"/api/events/types":           true,
+	"/api/events/stream":          true,
+	"/api/graphql":                true,
+	"/api/graphql/schema":         true,
+	"/api/webhooks":               true,
+	"/api/notifications/types":    true,
+	"/api/notifications/channels": true,
+	"/api/alerts":                 true,
+	"/api/alerts/rules":           true,
+	"/api/containers":             true,
+	"/api/images":                 true,
+	"/api/system/dashboard":       true,
+	"/api/system/info":            true,
+	"/api/system/df":              true,
+	"/api/system/maintenance":     true,
+	"/api/system/version":         true,
+	"/api/plugins":                true,
+}
+
+// demoReadPrefixes are path prefixes readable in demo mode (inspect, logs)
+var demoReadPrefixes = []string{"/api/containers/", "/api/images/"}
+
+// demoWritePaths are the non-GET requests allowed in demo mode
+var demoWritePaths = map[string]bool{
+	"/api/auth/logout": true, // nothing to log out of, answered normally
+	"/api/graphql":     true, // queries only, no mutations
+}
+
+// demoMiddleware rejects everything but reading mock data with 403. The
+// container proxy is rejected too, since mock containers have no ports.
+func demoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (strings.HasPrefix(r.URL.Path, "/api/") && !demoAllowed(r)) || strings.HasPrefix(r.URL.Path, "/proxy/") {
+			writeError(w, r, http.StatusForbidden, "Not available in demo mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// demoAllowed reports whether an API request is allowed in demo mode
+func demoAllowed(r *http.Request) bool {
+	path := r.URL.Path
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return demoWritePaths[path]
+	}
+	if demoReadPaths[path] {
+		return true
+	}
+	if strings.HasSuffix(path, "/terminal") || strings.HasSuffix(path, "/attach") || (strings.HasPrefix(path, "/api/images/") && strings.HasSuffix(path, "/export")) {
+		return false
+	}
+	for _, prefix := range demoReadPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// demoAuthMiddleware signs every visitor in as the demo user. The user is
+// an admin so the UI shows all features; demoMiddleware blocks their use.
+func demoAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := &auth.User{Username: "demo", UID: "1000", Role: auth.RoleAdmin}
+		next.ServeHTTP(w, r.WithContext(auth.SetUserContext(r.Context(), user)))
+	})
+}
+
+// demoHostStats returns mock host stats that vary slowly over time
+func demoHostStats() *HostStats {
+	t := float64(time.Now().Unix()) / 60
+	return &HostStats{
+		CPUUsage: math.Round((22+12*math.Sin(t)+4*math.Sin(4.3*t))*10) / 10,
+		CPUs:     4,
+		MemTotal: 8 << 30,
+		MemFree:  uint64((4.6 + 0.3*math.Sin(t/2)) * (1 << 30)),
+		Temperatures: []Temperature{
+			{Label: "CPU", Temp: math.Round((48+5*math.Sin(t))*10) / 10},
+			{Label: "GPU", Temp: math.Round((44+3*math.Sin(t+1))*10) / 10},
+		},
+		StorageTemps: []StorageTemp{
+			{Device: "nvme0n1", Sensors: []Temperature{{Label: "Composite", Temp: 39}}},
+		},
+		Uptime:    int64(time.Since(demoBoot).Seconds()),
+		DiskTotal: 58 << 30,
+		DiskFree:  31 << 30,
+		Disks: []DiskInfo{
+			{Device: "mmcblk0p2", MountPoint: "/", Total: 58 << 30, Free: 31 << 30, Used: 27 << 30,
+				DiskIO: DiskIO{ReadBytesPerSec: 48 << 10, WriteBytesPerSec: 310 << 10, Utilization: 6.5}},
+			{Device: "nvme0n1p1", MountPoint: "/srv", Total: 476 << 30, Free: 390 << 30, Used: 86 << 30,
+				DiskIO: DiskIO{ReadBytesPerSec: 12 << 20, WriteBytesPerSec: 3 << 20, Utilization: 21.3}},
+		},
+		// A power supply that sagged once since boot
+		Throttling: &Throttling{Raw: "0x10000", UnderVoltageOccurred: true},
+	}
+}
+
+// demoBoot is the mock boot time, twelve days before the demo started
+var demoBoot = time.Now().Add(-12 * 24 * time.Hour)
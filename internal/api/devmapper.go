@@ -0,0 +1,131 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sysBlockPath describes the block devices of the host; device-mapper
+// devices have their name and UUID in dm/
+const sysBlockPath = "/sys/block"
+
+// Device-mapper types
+const (
+	MappingLVM   = "lvm"
+	MappingLUKS  = "luks"
+	MappingCrypt = "crypt" // plain dm-crypt
+)
+
+// DeviceMapping identifies a device-mapper device (dm-0), which the disk
+// list would otherwise show as an opaque kernel name
+type DeviceMapping struct {
+	Kernel  string   `json:"kernel"`            // kernel name, e.g. dm-0
+	Name    string   `json:"name"`              // mapping name, e.g. vg0-root or cryptroot
+	Type    string   `json:"type,omitempty"`    // lvm, luks, crypt or the UUID prefix of other targets
+	VG      string   `json:"vg,omitempty"`      // LVM volume group
+	LV      string   `json:"lv,omitempty"`      // LVM logical volume
+	Backing []string `json:"backing,omitempty"` // underlying devices, e.g. nvme0n1p3
+}
+
+// DisplayName is the name shown in the disk list: vg/lv for LVM, the
+// mapping name otherwise
+func (m *DeviceMapping) DisplayName() string {
+	if m.VG != "" && m.LV != "" {
+		return m.VG + "/" + m.LV
+	}
+	return m.Name
+}
+
+// ResolveDeviceMapper returns the mapping of a /dev/mapper/<name> or
+// /dev/dm-<n> device from the sysfs tree at sysBlock, nil for other devices
+func ResolveDeviceMapper(sysBlock, device string) *DeviceMapping {
+	kernel := dmKernelName(sysBlock, device)
+	if kernel == "" {
+		return nil
+	}
+	dir := filepath.Join(sysBlock, kernel)
+	name, err := os.ReadFile(filepath.Join(dir, "dm", "name"))
+	if err != nil {
+		return nil
+	}
+	mapping := &DeviceMapping{Kernel: kernel, Name: strings.TrimSpace(string(name))}
+
+	uuid, _ := os.ReadFile(filepath.Join(dir, "dm", "uuid"))
+	mapping.Type = dmType(strings.TrimSpace(string(uuid)))
+	if mapping.Type == MappingLVM {
+		mapping.VG, mapping.LV = SplitLVMName(mapping.Name)
+	}
+
+	if slaves, err := os.ReadDir(filepath.Join(dir, "slaves")); err == nil {
+		for _, slave := range slaves {
+			mapping.Backing = append(mapping.Backing, slave.Name())
+		}
+	}
+	return mapping
+}
+
+// dmKernelName returns the kernel name (dm-0) of a device-mapper device.
+// /dev/mapper names are symlinks to it; where they can't be followed (a
+// container without /dev/mapper) the names in sysfs are searched.
+func dmKernelName(sysBlock, device string) string {
+	if name, ok := strings.CutPrefix(device, "/dev/"); ok && strings.HasPrefix(name, "dm-") {
+		return name
+	}
+	name, ok := strings.CutPrefix(device, "/dev/mapper/")
+	if !ok {
+		return ""
+	}
+	if target, err := filepath.EvalSymlinks(device); err == nil && strings.HasPrefix(filepath.Base(target), "dm-") {
+		return filepath.Base(target)
+	}
+
+	entries, err := os.ReadDir(sysBlock)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "dm-") {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(sysBlock, entry.Name(), "dm", "name")); err == nil && strings.TrimSpace(string(data)) == name {
+			return entry.Name()
+		}
+	}
+	return ""
+}
+
+// dmType classifies a mapping by its UUID: LVM-<vg uuid><lv uuid>,
+// CRYPT-LUKS2-<uuid>-<name>, CRYPT-PLAIN-<name>, mpath-<wwid>...
+func dmType(uuid string) string {
+	switch {
+	case uuid == "":
+		return ""
+	case strings.HasPrefix(uuid, "LVM-"):
+		return MappingLVM
+	case strings.HasPrefix(uuid, "CRYPT-LUKS"):
+		return MappingLUKS
+	case strings.HasPrefix(uuid, "CRYPT-"):
+		return MappingCrypt
+	}
+	prefix, _, _ := strings.Cut(uuid, "-")
+	return strings.ToLower(prefix)
+}
+
+// SplitLVMName splits a device-mapper name of a logical volume into the
+// volume group and logical volume: vg0-root, my--vg-my--lv (dashes in
+// the names are doubled)
+func SplitLVMName(name string) (vg, lv string) {
+	for i := 0; i < len(name); i++ {
+		if name[i] != '-' {
+			continue
+		}
+		if i+1 < len(name) && name[i+1] == '-' {
+			i++ // escaped dash
+			continue
+		}
+		unescape := func(s string) string { return strings.ReplaceAll(s, "--", "-") }
+		return unescape(name[:i]), unescape(name[i+1:])
+	}
+	return "", ""
+}
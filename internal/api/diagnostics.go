@@ -0,0 +1,223 @@
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+)
+
+const (
+	// diagnosticsLogEntries is the default number of recent log entries
+	// in a diagnostics bundle
+	diagnosticsLogEntries = 1000
+
+	// maxDiagnosticsLogEntries limits the logs parameter
+	maxDiagnosticsLogEntries = 10000
+
+	// redacted replaces secrets in a diagnostics bundle
+	redacted = "[redacted]"
+)
+
+// secretFieldNames are parts of log field names whose values are redacted
+var secretFieldNames = []string{"password", "passwd", "secret", "token", "key", "credential", "authorization", "cookie"}
+
+// DiagnosticsSummary is summary.json of a diagnostics bundle
+type DiagnosticsSummary struct {
+	Version       string            `json:"version"`
+	StaticVersion string            `json:"staticVersion"`
+	GoVersion     string            `json:"goVersion"`
+	OS            string            `json:"os"`
+	Arch          string            `json:"arch"`
+	DemoMode      bool              `json:"demoMode"`
+	GeneratedAt   time.Time         `json:"generatedAt"`
+	Checks        map[string]string `json:"checks"` // component -> ok or the error
+}
+
+// PluginDiagnostics is the status of a plugin in plugins.json
+type PluginDiagnostics struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Enabled bool   `json:"enabled"`
+	Running bool   `json:"running"`
+}
+
+// bundleFile is a file of a diagnostics bundle
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// Diagnostics handles GET /api/system/diagnostics?logs=1000
+// Streams a .tar.gz support bundle to attach to bug reports: version and
+// check results, the effective configuration, recent log entries, plugin
+// status and podman info. Secrets are redacted.
+func (s *Server) Diagnostics(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	limit := diagnosticsLogEntries
+	if v := r.URL.Query().Get("logs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxDiagnosticsLogEntries {
+			writeError(w, r, http.StatusBadRequest, "Invalid logs parameter")
+			return
+		}
+		limit = n
+	}
+
+	files, err := s.diagnosticsFiles(r.Context(), limit)
+	if err != nil {
+		s.eventStore.Add(events.EventDiagnostics, user.Username, getClientIP(r), false, err.Error())
+		writeErr(w, r, err, "Failed to create diagnostics bundle")
+		return
+	}
+
+	now := time.Now()
+	filename := fmt.Sprintf("podmanview-diagnostics-%s.tar.gz", now.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	if err := writeBundle(w, files, now); err != nil {
+		// Headers are already sent, the client gets a truncated archive
+		requestLog(r, s.logger).Error("Failed to write diagnostics bundle", "error", err)
+		return
+	}
+	s.eventStore.Add(events.EventDiagnostics, user.Username, getClientIP(r), true, filename)
+}
+
+// diagnosticsFiles collects the files of a diagnostics bundle. Components
+// that fail are reported in the checks of summary.json instead.
+func (s *Server) diagnosticsFiles(ctx context.Context, logLimit int) ([]bundleFile, error) {
+	summary := DiagnosticsSummary{
+		Version:       s.version,
+		StaticVersion: s.staticVersion,
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		DemoMode:      s.config.DemoMode(),
+		GeneratedAt:   time.Now(),
+		Checks:        make(map[string]string),
+	}
+	check := func(name string, err error) {
+		summary.Checks[name] = checkOK
+		if err != nil {
+			summary.Checks[name] = err.Error()
+		}
+	}
+
+	podmanInfo := json.RawMessage("null")
+	if s.podmanClient != nil {
+		pctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		info, err := s.podmanClient.RawSystemInfo(pctx)
+		cancel()
+		if err == nil {
+			podmanInfo = info
+		}
+		check("podman", err)
+	}
+	if s.storage != nil {
+		check("storage", s.checkStorage(ctx))
+		if s.pluginRegistry != nil && !s.config.DemoMode() {
+			check("plugins", s.checkPlugins())
+		}
+	}
+
+	plugins := make([]PluginDiagnostics, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		status := PluginDiagnostics{Name: p.Name(), Version: p.Version(), Enabled: p.IsEnabled()}
+		if s.pluginRegistry != nil {
+			status.Running = s.pluginRegistry.IsRunning(p.Name())
+		}
+		plugins = append(plugins, status)
+	}
+
+	entries := []logger.Entry{}
+	if s.logger != nil && logLimit > 0 {
+		recent, _, err := s.logger.Entries(logger.Query{Level: logger.LevelDebug, Limit: logLimit})
+		for _, e := range recent {
+			entries = append(entries, redactLogEntry(e))
+		}
+		check("logs", err)
+	}
+
+	config := map[string]interface{}{
+		"file":     s.config.FilePath(),
+		"settings": s.config.Settings(), // secret settings are masked
+	}
+
+	// Secret values are removed from every file, e.g. from log messages
+	replacer := strings.NewReplacer()
+	if secret := s.config.JWTSecret(); len(secret) >= 8 {
+		replacer = strings.NewReplacer(secret, redacted)
+	}
+	files := []bundleFile{}
+	for _, f := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"summary.json", summary},
+		{"config.json", config},
+		{"logs.json", entries},
+		{"plugins.json", plugins},
+		{"podman-info.json", podmanInfo},
+	} {
+		data, err := json.MarshalIndent(f.value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, bundleFile{name: f.name, data: []byte(replacer.Replace(string(data)))})
+	}
+	return files, nil
+}
+
+// redactLogEntry masks the values of log fields named like secrets
+func redactLogEntry(e logger.Entry) logger.Entry {
+	if len(e.Fields) == 0 {
+		return e
+	}
+	fields := make(map[string]string, len(e.Fields))
+	for name, value := range e.Fields {
+		lower := strings.ToLower(name)
+		for _, secret := range secretFieldNames {
+			if strings.Contains(lower, secret) {
+				value = redacted
+				break
+			}
+		}
+		fields[name] = value
+	}
+	e.Fields = fields
+	return e
+}
+
+// writeBundle writes files as a .tar.gz archive
+func writeBundle(w io.Writer, files []bundleFile, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.data)), ModTime: modTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/podman"
+)
+
+// maxDiffStats limits the files whose size is looked up for a diff, one
+// Podman request each
+const maxDiffStats = 500
+
+// changeKinds names the kinds of podman.Change
+var changeKinds = map[int]string{
+	podman.ChangeModified: "modified",
+	podman.ChangeAdded:    "added",
+	podman.ChangeDeleted:  "deleted",
+}
+
+// ContainerChange is a file changed in a container compared to its image.
+// Size is set for added and modified files, not for directories.
+type ContainerChange struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Dir  bool   `json:"dir,omitempty"`
+	Size *int64 `json:"size,omitempty"`
+}
+
+// ContainerDiff is the filesystem diff of a container
+type ContainerDiff struct {
+	Changes  []ContainerChange `json:"changes"`
+	Added    int               `json:"added"`
+	Modified int               `json:"modified"`
+	Deleted  int               `json:"deleted"`
+	// Size is the total size of the added and modified files with a size
+	Size int64 `json:"size"`
+	// SizesTruncated is set when sizes were only looked up for the first
+	// maxDiffStats files
+	SizesTruncated bool `json:"sizesTruncated,omitempty"`
+}
+
+// Diff handles GET /api/containers/{id}/diff
+// Lists the files the container changed, added and deleted compared to its
+// image, with their sizes; sizes=false skips the size lookup.
+func (h *ContainerHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	changes, err := h.client.ContainerChanges(r.Context(), id)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	sizes := r.URL.Query().Get("sizes") != "false"
+	diff := ContainerDiff{Changes: make([]ContainerChange, 0, len(changes))}
+	stats := 0
+	for _, c := range changes {
+		change := ContainerChange{Path: c.Path, Kind: changeKinds[c.Kind]}
+		switch c.Kind {
+		case podman.ChangeAdded:
+			diff.Added++
+		case podman.ChangeModified:
+			diff.Modified++
+		case podman.ChangeDeleted:
+			diff.Deleted++
+		}
+
+		if sizes && c.Kind != podman.ChangeDeleted {
+			if stats == maxDiffStats {
+				diff.SizesTruncated = true
+			} else {
+				stats++
+				// Files can disappear meanwhile; they are listed without a size
+				if stat, err := h.client.StatContainerPath(r.Context(), id, c.Path); err == nil {
+					change.Dir = stat.Mode.IsDir()
+					if !change.Dir {
+						size := stat.Size
+						change.Size = &size
+						diff.Size += size
+					}
+				}
+			}
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+
+	writeJSON(w, http.StatusOK, diff)
+}
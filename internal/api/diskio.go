@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/idle"
+)
+
+// diskSectorSize is the unit of the sector counts in /proc/diskstats,
+// regardless of the sector size of the device
+const diskSectorSize = 512
+
+// hostDiskIO samples the I/O throughput of the block devices of the host
+// for the dashboard. It is sampled by Server.StartHostSampling.
+var hostDiskIO = NewDiskIOSampler(readDiskStats)
+
+// DiskCounters are the cumulative I/O counters of a block device
+type DiskCounters struct {
+	ReadBytes    uint64
+	WrittenBytes uint64
+	IOTime       time.Duration // time with I/O in flight
+}
+
+// DiskStatsFunc returns the I/O counters by device name, or nil when they
+// can't be read
+type DiskStatsFunc func() map[string]DiskCounters
+
+// DiskIO is the I/O throughput of a block device between two samples
+type DiskIO struct {
+	ReadBytesPerSec  uint64  `json:"readBytesPerSec"`
+	WriteBytesPerSec uint64  `json:"writeBytesPerSec"`
+	Utilization      float64 `json:"utilization"` // percent of the time with I/O in flight
+}
+
+// DiskIOSampler measures the I/O throughput of block devices from the
+// difference between two readings of their counters. Like CPUSampler, a
+// background collector calls Sample and callers read the result with
+// Usage.
+type DiskIOSampler struct {
+	read DiskStatsFunc
+
+	mu        sync.Mutex
+	prev      map[string]DiskCounters
+	prevAt    time.Time
+	usage     map[string]DiskIO
+	sampledAt time.Time // zero until the first sample
+}
+
+// NewDiskIOSampler creates a sampler and takes the first reading
+func NewDiskIOSampler(read DiskStatsFunc) *DiskIOSampler {
+	return &DiskIOSampler{read: read, prev: read(), prevAt: time.Now(), usage: map[string]DiskIO{}}
+}
+
+// Sample measures the throughput of every device since the previous sample
+func (s *DiskIOSampler) Sample() map[string]DiskIO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sampleLocked()
+	return s.copyLocked()
+}
+
+// sampleLocked is Sample with s.mu held
+func (s *DiskIOSampler) sampleLocked() {
+	counters := s.read()
+	if counters == nil {
+		return
+	}
+	now := time.Now()
+	elapsed := now.Sub(s.prevAt)
+	if elapsed <= 0 {
+		return
+	}
+
+	usage := make(map[string]DiskIO, len(counters))
+	for device, c := range counters {
+		p, ok := s.prev[device]
+		if !ok {
+			continue // new device, no delta yet
+		}
+		usage[device] = DiskIO{
+			ReadBytesPerSec:  uint64(float64(counterDelta(c.ReadBytes, p.ReadBytes)) / elapsed.Seconds()),
+			WriteBytesPerSec: uint64(float64(counterDelta(c.WrittenBytes, p.WrittenBytes)) / elapsed.Seconds()),
+			Utilization:      min(float64(counterDelta(uint64(c.IOTime), uint64(p.IOTime)))*100/float64(elapsed), 100),
+		}
+	}
+	s.prev, s.prevAt = counters, now
+	s.usage = usage
+	s.sampledAt = now
+}
+
+// counterDelta returns cur - prev, or 0 when the counter was reset
+func counterDelta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// copyLocked returns a copy of the last usage. Call with s.mu held.
+func (s *DiskIOSampler) copyLocked() map[string]DiskIO {
+	usage := make(map[string]DiskIO, len(s.usage))
+	for device, io := range s.usage {
+		usage[device] = io
+	}
+	return usage
+}
+
+// Usage returns the throughput by device from the last sample. Samples by
+// itself when there is no recent sample, waiting briefly if the sampler
+// was just created.
+func (s *DiskIOSampler) Usage() map[string]DiskIO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sampledAt.IsZero() || time.Since(s.sampledAt) >= hostSampleMaxAge {
+		if wait := hostPrimeWindow - time.Since(s.prevAt); wait > 0 {
+			time.Sleep(wait)
+		}
+		s.sampleLocked()
+	}
+	return s.copyLocked()
+}
+
+// Run samples every interval until ctx is cancelled, less often while idle
+func (s *DiskIOSampler) Run(ctx context.Context, interval time.Duration, idleMonitor *idle.Monitor) {
+	for {
+		s.Sample()
+		if !idleMonitor.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// readDiskStats reads the I/O counters of block devices from /proc/diskstats
+func readDiskStats() map[string]DiskCounters {
+	data, err := os.ReadFile("/proc/diskstats")
+	if err != nil {
+		return nil
+	}
+	return ParseDiskStats(string(data))
+}
+
+// ParseDiskStats parses the content of /proc/diskstats. Lines have the
+// major and minor number, the device name, then reads completed, reads
+// merged, sectors read, ms reading, writes completed, writes merged,
+// sectors written, ms writing, I/Os in progress and ms doing I/O.
+// Loop and RAM devices are skipped.
+func ParseDiskStats(data string) map[string]DiskCounters {
+	counters := make(map[string]DiskCounters)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 13 {
+			continue
+		}
+		device := fields[2]
+		if strings.HasPrefix(device, "loop") || strings.HasPrefix(device, "ram") {
+			continue
+		}
+		sectorsRead, err1 := strconv.ParseUint(fields[5], 10, 64)
+		sectorsWritten, err2 := strconv.ParseUint(fields[9], 10, 64)
+		ioMillis, err3 := strconv.ParseUint(fields[12], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		counters[device] = DiskCounters{
+			ReadBytes:    sectorsRead * diskSectorSize,
+			WrittenBytes: sectorsWritten * diskSectorSize,
+			IOTime:       time.Duration(ioMillis) * time.Millisecond,
+		}
+	}
+	return counters
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// drainer tracks long-lived connections that http.Server.Shutdown cannot
+// drain: event streams never become idle, and hijacked WebSocket connections
+// (terminals) are not tracked by the server at all
+type drainer struct {
+	mu      sync.Mutex
+	closing chan struct{} // closed when the server starts shutting down
+	closed  bool
+	active  sync.WaitGroup
+
+	// open connections by kind and connections since start, for the
+	// internal metrics
+	streams, websockets int
+	total               uint64
+}
+
+func newDrainer() *drainer {
+	return &drainer{closing: make(chan struct{})}
+}
+
+// track registers a long-lived request. The returned channel is closed when
+// the connection has to end; done must be called when it has ended.
+// Answers 503 and returns ok = false when the server is shutting down.
+func (d *drainer) track(w http.ResponseWriter, r *http.Request) (closing <-chan struct{}, done func(), ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		writeError(w, r, http.StatusServiceUnavailable, "Server is shutting down")
+		return nil, nil, false
+	}
+	d.active.Add(1)
+	open := &d.streams
+	if websocket.IsWebSocketUpgrade(r) {
+		open = &d.websockets
+	}
+	*open++
+	d.total++
+	var once sync.Once
+	return d.closing, func() {
+		once.Do(func() {
+			d.mu.Lock()
+			*open--
+			d.mu.Unlock()
+			d.active.Done()
+		})
+	}, true
+}
+
+// counts returns the open event streams and WebSocket connections and the
+// number of connections tracked since start
+func (d *drainer) counts() (streams, websockets int, total uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.streams, d.websockets, d.total
+}
+
+// close signals all tracked connections to end and rejects new ones
+func (d *drainer) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.closed = true
+		close(d.closing)
+	}
+}
+
+// isClosed reports whether the server is shutting down
+func (d *drainer) isClosed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// wait waits until all tracked connections have ended or ctx is done
+func (d *drainer) wait(ctx context.Context) error {
+	finished := make(chan struct{})
+	go func() {
+		d.active.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeWebSocketOnDrain closes ws with a "going away" close frame when the
+// server shuts down, which ends the handler's read loop. Stops with ctx.
+func closeWebSocketOnDrain(ctx context.Context, ws *websocket.Conn, closing <-chan struct{}) {
+	select {
+	case <-ctx.Done():
+	case <-closing:
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server shutting down")
+		ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+		ws.Close()
+	}
+}
+
+// CloseConnections starts draining: open event streams and terminals are
+// closed and new ones are rejected. Registered with
+// http.Server.RegisterOnShutdown, as Shutdown does not close them.
+func (s *Server) CloseConnections() {
+	s.drainer.close()
+}
+
+// WaitConnections waits until the event streams and terminals closed by
+// CloseConnections have finished (e.g. saved their command history)
+func (s *Server) WaitConnections(ctx context.Context) error {
+	return s.drainer.wait(ctx)
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+// envSecretMask replaces the values of secret variables. Sent back
+// unchanged in an update, it keeps the current value.
+const envSecretMask = "********"
+
+// EnvHandler reads and edits the environment of containers
+type EnvHandler struct {
+	client     *podman.Client
+	config     *config.Config
+	eventStore *events.Store
+	volumes    *VolumeHandler // snapshots volumes with backupVolumes=true
+}
+
+// NewEnvHandler creates new container environment handler
+func NewEnvHandler(client *podman.Client, cfg *config.Config, eventStore *events.Store, volumes *VolumeHandler) *EnvHandler {
+	return &EnvHandler{client: client, config: cfg, eventStore: eventStore, volumes: volumes}
+}
+
+// EnvVar is an environment variable of a container
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Secret is set when the name matches PODMANVIEW_SECRET_ENV; the value
+	// is masked
+	Secret bool `json:"secret,omitempty"`
+	// Inherited is set when the image sets the same value
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// EnvUpdateRequest represents the request body for replacing the
+// environment of a container
+type EnvUpdateRequest struct {
+	Env map[string]string `json:"env"`
+}
+
+// Get handles GET /api/containers/{id}/env
+func (h *EnvHandler) Get(w http.ResponseWriter, r *http.Request) {
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	image, err := h.client.InspectImage(r.Context(), info.Image)
+	if err != nil {
+		image = &podman.ImageInspect{}
+	}
+
+	imageEnv := parseEnvList(image.Config.Env)
+	patterns := h.config.SecretEnvPatterns()
+	vars := make([]EnvVar, 0, len(info.Config.Env))
+	for name, value := range parseEnvList(info.Config.Env) {
+		if slices.Contains(runtimeEnv, name) {
+			continue
+		}
+		v := EnvVar{Name: name, Value: value, Secret: isSecretEnv(name, patterns)}
+		if inherited, ok := imageEnv[name]; ok && inherited == value {
+			v.Inherited = true
+		}
+		if v.Secret {
+			v.Value = envSecretMask
+		}
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"env": vars})
+}
+
+// Update handles PUT /api/containers/{id}/env?backupVolumes=true
+// Replaces the environment and recreates the container with it: the
+// container is stopped, removed and created again from its spec (see
+// buildContainerSpec), then started if it was running. If the new container
+// can't be created, the original one is restored. With backupVolumes=true
+// its named volumes are snapshotted once it is stopped (see VolumeBackup).
+func (h *EnvHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req EnvUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	image, err := h.client.InspectImage(r.Context(), info.Image)
+	if err != nil {
+		image = &podman.ImageInspect{}
+	}
+
+	current := parseEnvList(info.Config.Env)
+	imageEnv := parseEnvList(image.Config.Env)
+	env := make(map[string]string, len(req.Env))
+	for name, value := range req.Env {
+		if name == "" || strings.ContainsAny(name, "= \t\r\n") {
+			writeError(w, r, http.StatusBadRequest, "Invalid variable name: "+name)
+			return
+		}
+		if value == envSecretMask {
+			old, ok := current[name]
+			if !ok {
+				writeError(w, r, http.StatusBadRequest, "Masked value for a new variable: "+name)
+				return
+			}
+			value = old
+		}
+		// Image values come back on their own; keep them out of the spec
+		if inherited, ok := imageEnv[name]; (ok && inherited == value) || slices.Contains(runtimeEnv, name) {
+			continue
+		}
+		env[name] = value
+	}
+
+	original, err := buildContainerSpec(info, image).createConfig()
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	spec := buildContainerSpec(info, image)
+	spec.Env = env
+	updated, err := spec.createConfig()
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	backupVolumes := r.URL.Query().Get("backupVolumes") == "true"
+	if backupVolumes {
+		if _, err := h.volumes.snapshotDir(); err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+	}
+
+	running := info.State.Running
+	if running {
+		if err := h.client.StopContainer(r.Context(), info.ID); err != nil {
+			h.eventStore.Add(events.EventContainerEnv, user.Username, getClientIP(r), false, shortID(info.ID))
+			writeErr(w, r, err, "Failed to stop container")
+			return
+		}
+	}
+	var backups []VolumeBackup
+	if backupVolumes {
+		if backups, err = h.volumes.snapshot(r.Context(), info, "recreate", user.Username); err != nil {
+			if running {
+				h.client.StartContainer(r.Context(), info.ID)
+			}
+			h.eventStore.Add(events.EventContainerEnv, user.Username, getClientIP(r), false, shortID(info.ID))
+			writeErr(w, r, err, "Failed to back up volumes")
+			return
+		}
+	}
+	if err := h.client.RemoveContainer(r.Context(), info.ID, false); err != nil {
+		h.volumes.discard(backups)
+		if running {
+			h.client.StartContainer(r.Context(), info.ID)
+		}
+		h.eventStore.Add(events.EventContainerEnv, user.Username, getClientIP(r), false, shortID(info.ID))
+		writeErr(w, r, err, "Failed to remove container")
+		return
+	}
+
+	result, err := h.client.CreateContainer(r.Context(), updated)
+	if err != nil {
+		// Put the original container back
+		if restored, rerr := h.client.CreateContainer(r.Context(), original); rerr == nil && running {
+			h.client.StartContainer(r.Context(), restored.ID)
+		}
+		h.eventStore.Add(events.EventContainerEnv, user.Username, getClientIP(r), false, shortID(info.ID))
+		writeErr(w, r, err, "Failed to recreate container")
+		return
+	}
+	h.eventStore.Add(events.EventContainerEnv, user.Username, getClientIP(r), true, shortID(info.ID)+" -> "+shortID(result.ID))
+
+	resp := map[string]interface{}{"id": result.ID, "status": "created"}
+	if backups != nil {
+		resp["volumeBackups"] = backups
+	}
+	if running {
+		if err := h.client.StartContainer(r.Context(), result.ID); err != nil {
+			resp["warning"] = "Container created but failed to start: " + err.Error()
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		resp["status"] = "started"
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// isSecretEnv reports whether a variable name matches one of the upper-case
+// patterns, ignoring case
+func isSecretEnv(name string, patterns []string) bool {
+	name = strings.ToUpper(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
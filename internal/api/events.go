@@ -3,32 +3,41 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"podmanview/internal/events"
 )
 
 // EventsHandler handles event log endpoints
 type EventsHandler struct {
-	store *events.Store
+	store   *events.Store
+	engine  *engineEventHub // Podman engine events for Stream, nil = not streamed
+	drainer *drainer        // closes streams on shutdown
 }
 
 // NewEventsHandler creates new events handler
-func NewEventsHandler(store *events.Store) *EventsHandler {
-	return &EventsHandler{store: store}
+func NewEventsHandler(store *events.Store, engine *engineEventHub, drainer *drainer) *EventsHandler {
+	return &EventsHandler{store: store, engine: engine, drainer: drainer}
 }
 
+// eventFilterParams are the query parameters that select List's filtered mode
+var eventFilterParams = []string{"type", "category", "severity", "user", "success", "from", "to", "q", "cursor"}
+
 // List returns events from the store
 // GET /api/events?limit=50&since=123
+// GET /api/events?type=container_&category=container&severity=warning&user=alice&success=false&from=...&to=...&q=nginx&cursor=123
+// Filtered requests return nextCursor to fetch the following (older) page.
 func (h *EventsHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Check for since parameter (get events after ID)
 	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
 		sinceID, err := strconv.ParseInt(sinceStr, 10, 64)
 		if err == nil {
+			modified := h.store.Modified()
 			eventList := h.store.GetSince(sinceID)
-			writeJSON(w, http.StatusOK, map[string]interface{}{
+			writeJSONConditional(w, r, map[string]interface{}{
 				"events": eventList,
 				"lastId": h.store.LastID(),
-			})
+			}, modified)
 			return
 		}
 	}
@@ -41,9 +50,79 @@ func (h *EventsHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	for _, param := range eventFilterParams {
+		if r.URL.Query().Has(param) {
+			h.query(w, r, limit)
+			return
+		}
+	}
+
+	modified := h.store.Modified()
 	eventList := h.store.GetLast(limit)
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	writeJSONConditional(w, r, map[string]interface{}{
 		"events": eventList,
 		"lastId": h.store.LastID(),
+	}, modified)
+}
+
+// query handles List with filters and cursor-based pagination
+func (h *EventsHandler) query(w http.ResponseWriter, r *http.Request, limit int) {
+	params := r.URL.Query()
+	q := events.Query{
+		TypePrefix: params.Get("type"),
+		Category:   events.Category(params.Get("category")),
+		Severity:   events.Severity(params.Get("severity")),
+		Username:   params.Get("user"),
+		Search:     params.Get("q"),
+		Limit:      limit,
+	}
+
+	if q.Severity != "" && q.Severity.Level() < 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid severity, expected info, warning, error or critical")
+		return
+	}
+	if v := params.Get("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid success value")
+			return
+		}
+		q.Success = &success
+	}
+	for param, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := params.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "Invalid "+param+" time, expected RFC 3339")
+				return
+			}
+			*t = parsed
+		}
+	}
+	if v := params.Get("cursor"); v != "" {
+		cursor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || cursor < 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		q.Before = cursor
+	}
+
+	modified := h.store.Modified()
+	eventList, next := h.store.Query(q)
+	writeJSONConditional(w, r, map[string]interface{}{
+		"events":     eventList,
+		"lastId":     h.store.LastID(),
+		"nextCursor": next,
+	}, modified)
+}
+
+// Types returns the event type catalog
+// GET /api/events/types
+func (h *EventsHandler) Types(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"types":      events.Types(),
+		"categories": events.Categories,
+		"severities": events.Severities,
 	})
 }
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"podmanview/internal/logger"
+	"podmanview/internal/podman"
+)
+
+const (
+	// streamBuffer is the number of events buffered per stream client
+	streamBuffer = 64
+
+	// streamKeepalive is how often a comment is sent to keep idle streams open
+	streamKeepalive = 30 * time.Second
+
+	// engineRetryMax limits the delay between reconnects to the Podman event stream
+	engineRetryMax = time.Minute
+)
+
+// engineEventHub reads the Podman engine event stream once and
+// fans the events out to all connected stream clients
+type engineEventHub struct {
+	client *podman.Client
+	logger *logger.Logger
+
+	mu          sync.Mutex
+	subscribers map[chan podman.EngineEvent]struct{}
+}
+
+// newEngineEventHub creates a hub for the Podman engine events
+func newEngineEventHub(client *podman.Client, appLogger *logger.Logger) *engineEventHub {
+	return &engineEventHub{
+		client:      client,
+		logger:      appLogger,
+		subscribers: make(map[chan podman.EngineEvent]struct{}),
+	}
+}
+
+// subscribe returns a channel receiving engine events and a function to unsubscribe
+func (h *engineEventHub) subscribe() (<-chan podman.EngineEvent, func()) {
+	ch := make(chan podman.EngineEvent, streamBuffer)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publish sends an engine event to all subscribers, slow ones miss it
+func (h *engineEventHub) publish(event podman.EngineEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// run follows the Podman event stream until ctx is cancelled,
+// reconnecting with backoff when the stream fails
+func (h *engineEventHub) run(ctx context.Context) {
+	delay := time.Second
+	for {
+		started := time.Now()
+		err := h.client.StreamEvents(ctx, h.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > engineRetryMax {
+			delay = time.Second
+		}
+		h.logger.Debug("Podman event stream closed, reconnecting", "error", err, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, engineRetryMax)
+	}
+}
+
+// Stream handles GET /api/events/stream
+// Server-Sent Events: "audit" events from the event log (with their ID, so
+// the browser resumes via Last-Event-ID after a reconnect) and "engine"
+// events from Podman. ?since=ID replays logged events newer than ID first.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	since := r.Header.Get("Last-Event-ID")
+	if v := r.URL.Query().Get("since"); v != "" {
+		since = v
+	}
+
+	// Subscribe before replaying, so no event is lost in between
+	audit, unsubscribe := h.store.Subscribe(streamBuffer)
+	defer unsubscribe()
+
+	var engine <-chan podman.EngineEvent
+	if h.engine != nil {
+		ch, unsubscribeEngine := h.engine.subscribe()
+		defer unsubscribeEngine()
+		engine = ch
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	var lastID int64
+	if sinceID, err := strconv.ParseInt(since, 10, 64); err == nil {
+		replay := h.store.GetSince(sinceID)
+		for i := len(replay) - 1; i >= 0; i-- {
+			writeSSE(w, "audit", strconv.FormatInt(replay[i].ID, 10), replay[i])
+			lastID = replay[i].ID
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-closing:
+			return // server shutting down, the browser reconnects
+		case event := <-audit:
+			if event.ID <= lastID {
+				continue // already replayed
+			}
+			writeSSE(w, "audit", strconv.FormatInt(event.ID, 10), event)
+		case event := <-engine:
+			writeSSE(w, "engine", "", event)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSE writes a single Server-Sent Event with JSON data
+func writeSSE(w http.ResponseWriter, name, id string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+}
@@ -119,7 +119,7 @@ func NewFileManagerHandler(eventStore *events.Store, baseDir string, logger *log
 	baseDir, err := filepath.Abs(baseDir)
 	if err != nil {
 		if logger != nil {
-			logger.Printf("Warning: failed to resolve base directory, using /: %v", err)
+			logger.Warn("Failed to resolve base directory, using /", "error", err)
 		}
 		baseDir = "/"
 	}
@@ -247,7 +247,7 @@ func (h *FileManagerHandler) getRelativePath(absPath string) string {
 func (h *FileManagerHandler) Browse(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -273,7 +273,7 @@ func (h *FileManagerHandler) Browse(w http.ResponseWriter, r *http.Request) {
 	// Validate and get absolute path
 	absPath, err := h.validatePath(requestedPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -281,23 +281,23 @@ func (h *FileManagerHandler) Browse(w http.ResponseWriter, r *http.Request) {
 	stat, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			http.Error(w, "Directory not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "Directory not found")
 		} else {
-			http.Error(w, "Failed to access directory", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to access directory")
 		}
 		return
 	}
 
 	if !stat.IsDir() {
-		http.Error(w, "Path is not a directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Path is not a directory")
 		return
 	}
 
 	// Read directory contents
 	entries, err := os.ReadDir(absPath)
 	if err != nil {
-		http.Error(w, "Failed to read directory", http.StatusInternalServerError)
-		h.logger.Printf("Failed to read directory %s: %v", absPath, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to read directory")
+		requestLog(r, h.logger).Error("Failed to read directory", "path", absPath, "error", err)
 		return
 	}
 
@@ -364,20 +364,20 @@ func (h *FileManagerHandler) Browse(w http.ResponseWriter, r *http.Request) {
 func (h *FileManagerHandler) Download(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	requestedPath := r.URL.Query().Get("path")
 	if requestedPath == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Path is required")
 		return
 	}
 
 	// Validate and get absolute path
 	absPath, err := h.validatePath(requestedPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -385,23 +385,23 @@ func (h *FileManagerHandler) Download(w http.ResponseWriter, r *http.Request) {
 	stat, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			http.Error(w, "File not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "File not found")
 		} else {
-			http.Error(w, "Failed to access file", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to access file")
 		}
 		return
 	}
 
 	if stat.IsDir() {
-		http.Error(w, "Cannot download directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Cannot download directory")
 		return
 	}
 
 	// Open file
 	file, err := os.Open(absPath)
 	if err != nil {
-		http.Error(w, "Failed to open file", http.StatusInternalServerError)
-		h.logger.Printf("Failed to open file %s: %v", absPath, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to open file")
+		requestLog(r, h.logger).Error("Failed to open file", "path", absPath, "error", err)
 		return
 	}
 	defer file.Close()
@@ -422,7 +422,7 @@ func (h *FileManagerHandler) Download(w http.ResponseWriter, r *http.Request) {
 	// Stream file to client
 	_, err = io.Copy(w, file)
 	if err != nil {
-		h.logger.Printf("Failed to send file %s: %v", absPath, err)
+		requestLog(r, h.logger).Warn("Failed to send file", "path", absPath, "error", err)
 		return
 	}
 
@@ -435,7 +435,7 @@ func (h *FileManagerHandler) Download(w http.ResponseWriter, r *http.Request) {
 func (h *FileManagerHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -445,7 +445,7 @@ func (h *FileManagerHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form
 	err := r.ParseMultipartForm(h.maxUploadSize)
 	if err != nil {
-		http.Error(w, "File too large or invalid form data", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "File too large or invalid form data")
 		return
 	}
 
@@ -458,21 +458,21 @@ func (h *FileManagerHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	// Validate target directory
 	absTargetDir, err := h.validatePath(targetPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Check if target is a directory
 	stat, err := os.Stat(absTargetDir)
 	if err != nil || !stat.IsDir() {
-		http.Error(w, "Target path is not a directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Target path is not a directory")
 		return
 	}
 
 	// Get uploaded files
 	files := r.MultipartForm.File["files"]
 	if len(files) == 0 {
-		http.Error(w, "No files uploaded", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "No files uploaded")
 		return
 	}
 
@@ -522,8 +522,8 @@ func (h *FileManagerHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		for _, filename := range uploadedFiles {
 			os.Remove(filepath.Join(absTargetDir, filename))
 		}
-		http.Error(w, uploadErr.Error(), http.StatusInternalServerError)
-		h.logger.Printf("Upload failed: %v", uploadErr)
+		writeError(w, r, http.StatusInternalServerError, uploadErr.Error())
+		requestLog(r, h.logger).Error("Upload failed", "error", uploadErr)
 		return
 	}
 
@@ -542,26 +542,26 @@ func (h *FileManagerHandler) Upload(w http.ResponseWriter, r *http.Request) {
 func (h *FileManagerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	requestedPath := r.URL.Query().Get("path")
 	if requestedPath == "" || requestedPath == "/" {
-		http.Error(w, "Cannot delete root directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Cannot delete root directory")
 		return
 	}
 
 	// Validate and get absolute path
 	absPath, err := h.validatePath(requestedPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Prevent deleting baseDir
 	if absPath == h.baseDir {
-		http.Error(w, "Cannot delete base directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Cannot delete base directory")
 		return
 	}
 
@@ -569,9 +569,9 @@ func (h *FileManagerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	stat, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			http.Error(w, "File or directory not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "File or directory not found")
 		} else {
-			http.Error(w, "Failed to access path", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to access path")
 		}
 		return
 	}
@@ -579,8 +579,8 @@ func (h *FileManagerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// Remove file or directory (recursively if directory)
 	err = os.RemoveAll(absPath)
 	if err != nil {
-		http.Error(w, "Failed to delete", http.StatusInternalServerError)
-		h.logger.Printf("Failed to delete %s: %v", absPath, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete")
+		requestLog(r, h.logger).Error("Failed to delete", "path", absPath, "error", err)
 		return
 	}
 
@@ -602,7 +602,7 @@ func (h *FileManagerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 func (h *FileManagerHandler) MkDir(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -612,19 +612,19 @@ func (h *FileManagerHandler) MkDir(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Name == "" {
-		http.Error(w, "Directory name is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Directory name is required")
 		return
 	}
 
 	// Validate directory name (prevent path traversal)
 	dirName := filepath.Base(req.Name)
 	if dirName == "" || dirName == "." || dirName == ".." || strings.Contains(dirName, "/") || strings.Contains(dirName, "\\") {
-		http.Error(w, "Invalid directory name", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid directory name")
 		return
 	}
 
@@ -636,14 +636,14 @@ func (h *FileManagerHandler) MkDir(w http.ResponseWriter, r *http.Request) {
 
 	absParentDir, err := h.validatePath(parentPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Check if parent is a directory
 	stat, err := os.Stat(absParentDir)
 	if err != nil || !stat.IsDir() {
-		http.Error(w, "Parent path is not a directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Parent path is not a directory")
 		return
 	}
 
@@ -652,10 +652,10 @@ func (h *FileManagerHandler) MkDir(w http.ResponseWriter, r *http.Request) {
 	err = os.Mkdir(newDirPath, 0755)
 	if err != nil {
 		if os.IsExist(err) {
-			http.Error(w, "Directory already exists", http.StatusConflict)
+			writeError(w, r, http.StatusConflict, "Directory already exists")
 		} else {
-			http.Error(w, "Failed to create directory", http.StatusInternalServerError)
-			h.logger.Printf("Failed to create directory %s: %v", newDirPath, err)
+			writeError(w, r, http.StatusInternalServerError, "Failed to create directory")
+			requestLog(r, h.logger).Error("Failed to create directory", "path", newDirPath, "error", err)
 		}
 		return
 	}
@@ -675,7 +675,7 @@ func (h *FileManagerHandler) MkDir(w http.ResponseWriter, r *http.Request) {
 func (h *FileManagerHandler) CreateFile(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -685,19 +685,19 @@ func (h *FileManagerHandler) CreateFile(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Name == "" {
-		http.Error(w, "File name is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "File name is required")
 		return
 	}
 
 	// Validate file name (prevent path traversal)
 	fileName := filepath.Base(req.Name)
 	if fileName == "" || fileName == "." || fileName == ".." || strings.Contains(fileName, "/") || strings.Contains(fileName, "\\") {
-		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid file name")
 		return
 	}
 
@@ -709,14 +709,14 @@ func (h *FileManagerHandler) CreateFile(w http.ResponseWriter, r *http.Request)
 
 	absParentDir, err := h.validatePath(parentPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Check if parent is a directory
 	stat, err := os.Stat(absParentDir)
 	if err != nil || !stat.IsDir() {
-		http.Error(w, "Parent path is not a directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Parent path is not a directory")
 		return
 	}
 
@@ -725,15 +725,15 @@ func (h *FileManagerHandler) CreateFile(w http.ResponseWriter, r *http.Request)
 
 	// Check if file already exists
 	if _, err := os.Stat(newFilePath); err == nil {
-		http.Error(w, "File already exists", http.StatusConflict)
+		writeError(w, r, http.StatusConflict, "File already exists")
 		return
 	}
 
 	// Create empty file
 	file, err := os.Create(newFilePath)
 	if err != nil {
-		http.Error(w, "Failed to create file", http.StatusInternalServerError)
-		h.logger.Printf("Failed to create file %s: %v", newFilePath, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to create file")
+		requestLog(r, h.logger).Error("Failed to create file", "path", newFilePath, "error", err)
 		return
 	}
 	file.Close()
@@ -753,7 +753,7 @@ func (h *FileManagerHandler) CreateFile(w http.ResponseWriter, r *http.Request)
 func (h *FileManagerHandler) Rename(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -763,41 +763,41 @@ func (h *FileManagerHandler) Rename(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.OldPath == "" || req.NewName == "" {
-		http.Error(w, "Both old_path and new_name are required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Both old_path and new_name are required")
 		return
 	}
 
 	// Validate new name (prevent path traversal)
 	newName := filepath.Base(req.NewName)
 	if newName == "" || newName == "." || newName == ".." || strings.Contains(newName, "/") || strings.Contains(newName, "\\") {
-		http.Error(w, "Invalid new name", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid new name")
 		return
 	}
 
 	// Validate old path
 	absOldPath, err := h.validatePath(req.OldPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Prevent renaming baseDir
 	if absOldPath == h.baseDir {
-		http.Error(w, "Cannot rename base directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Cannot rename base directory")
 		return
 	}
 
 	// Check if old path exists
 	if _, err := os.Stat(absOldPath); err != nil {
 		if os.IsNotExist(err) {
-			http.Error(w, "File or directory not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "File or directory not found")
 		} else {
-			http.Error(w, "Failed to access path", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to access path")
 		}
 		return
 	}
@@ -808,15 +808,15 @@ func (h *FileManagerHandler) Rename(w http.ResponseWriter, r *http.Request) {
 
 	// Check if new path already exists
 	if _, err := os.Stat(absNewPath); err == nil {
-		http.Error(w, "A file or directory with that name already exists", http.StatusConflict)
+		writeError(w, r, http.StatusConflict, "A file or directory with that name already exists")
 		return
 	}
 
 	// Rename
 	err = os.Rename(absOldPath, absNewPath)
 	if err != nil {
-		http.Error(w, "Failed to rename", http.StatusInternalServerError)
-		h.logger.Printf("Failed to rename %s to %s: %v", absOldPath, absNewPath, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to rename")
+		requestLog(r, h.logger).Error("Failed to rename", "path", absOldPath, "new_path", absNewPath, "error", err)
 		return
 	}
 
@@ -835,20 +835,20 @@ func (h *FileManagerHandler) Rename(w http.ResponseWriter, r *http.Request) {
 func (h *FileManagerHandler) ReadFile(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	requestedPath := r.URL.Query().Get("path")
 	if requestedPath == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Path is required")
 		return
 	}
 
 	// Validate and get absolute path
 	absPath, err := h.validatePath(requestedPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -856,22 +856,22 @@ func (h *FileManagerHandler) ReadFile(w http.ResponseWriter, r *http.Request) {
 	stat, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			http.Error(w, "File not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "File not found")
 		} else {
-			http.Error(w, "Failed to access file", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to access file")
 		}
 		return
 	}
 
 	if stat.IsDir() {
-		http.Error(w, "Cannot read directory as file", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Cannot read directory as file")
 		return
 	}
 
 	// Check file size (limit to 10MB for editing)
 	const maxEditSize = 10 * 1024 * 1024
 	if stat.Size() > maxEditSize {
-		http.Error(w, "File too large to edit (max 10MB)", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "File too large to edit (max 10MB)")
 		return
 	}
 
@@ -888,8 +888,8 @@ func (h *FileManagerHandler) ReadFile(w http.ResponseWriter, r *http.Request) {
 		// Read file content
 		content, err := os.ReadFile(absPath)
 		if err != nil {
-			http.Error(w, "Failed to read file", http.StatusInternalServerError)
-			h.logger.Printf("Failed to read file %s: %v", absPath, err)
+			writeError(w, r, http.StatusInternalServerError, "Failed to read file")
+			requestLog(r, h.logger).Error("Failed to read file", "path", absPath, "error", err)
 			return
 		}
 
@@ -948,20 +948,20 @@ func (h *FileManagerHandler) ReadFile(w http.ResponseWriter, r *http.Request) {
 func (h *FileManagerHandler) StreamFile(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	requestedPath := r.URL.Query().Get("path")
 	if requestedPath == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Path is required")
 		return
 	}
 
 	// Validate and get absolute path
 	absPath, err := h.validatePath(requestedPath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -969,23 +969,23 @@ func (h *FileManagerHandler) StreamFile(w http.ResponseWriter, r *http.Request)
 	stat, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			http.Error(w, "File not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "File not found")
 		} else {
-			http.Error(w, "Failed to access file", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to access file")
 		}
 		return
 	}
 
 	if stat.IsDir() {
-		http.Error(w, "Cannot stream directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Cannot stream directory")
 		return
 	}
 
 	// Open file
 	file, err := os.Open(absPath)
 	if err != nil {
-		http.Error(w, "Failed to open file", http.StatusInternalServerError)
-		h.logger.Printf("Failed to open file %s: %v", absPath, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to open file")
+		requestLog(r, h.logger).Error("Failed to open file", "path", absPath, "error", err)
 		return
 	}
 	defer file.Close()
@@ -1017,7 +1017,7 @@ func (h *FileManagerHandler) StreamFile(w http.ResponseWriter, r *http.Request)
 	// Stream entire file
 	_, err = io.Copy(w, file)
 	if err != nil {
-		h.logger.Printf("Failed to stream file %s: %v", absPath, err)
+		requestLog(r, h.logger).Warn("Failed to stream file", "path", absPath, "error", err)
 		return
 	}
 
@@ -1035,7 +1035,7 @@ func (h *FileManagerHandler) serveFileRange(w http.ResponseWriter, r *http.Reque
 	if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
 		// Try parsing "bytes=start-" format
 		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &start); err != nil {
-			http.Error(w, "Invalid range", http.StatusRequestedRangeNotSatisfiable)
+			writeError(w, r, http.StatusRequestedRangeNotSatisfiable, "Invalid range")
 			return
 		}
 		end = fileSize - 1
@@ -1044,13 +1044,13 @@ func (h *FileManagerHandler) serveFileRange(w http.ResponseWriter, r *http.Reque
 	// Validate range
 	if start < 0 || start >= fileSize || end < start || end >= fileSize {
 		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
-		http.Error(w, "Invalid range", http.StatusRequestedRangeNotSatisfiable)
+		writeError(w, r, http.StatusRequestedRangeNotSatisfiable, "Invalid range")
 		return
 	}
 
 	// Seek to start position
 	if _, err := file.Seek(start, 0); err != nil {
-		http.Error(w, "Failed to seek file", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to seek file")
 		return
 	}
 
@@ -1065,7 +1065,7 @@ func (h *FileManagerHandler) serveFileRange(w http.ResponseWriter, r *http.Reque
 	// Stream the requested range
 	_, err := io.CopyN(w, file, contentLength)
 	if err != nil && err != io.EOF {
-		h.logger.Printf("Failed to stream file range: %v", err)
+		requestLog(r, h.logger).Warn("Failed to stream file range", "path", file.Name(), "error", err)
 	}
 }
 
@@ -1073,7 +1073,7 @@ func (h *FileManagerHandler) serveFileRange(w http.ResponseWriter, r *http.Reque
 func (h *FileManagerHandler) WriteFile(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -1083,19 +1083,19 @@ func (h *FileManagerHandler) WriteFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Path is required")
 		return
 	}
 
 	// Validate and get absolute path
 	absPath, err := h.validatePath(req.Path)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -1103,23 +1103,23 @@ func (h *FileManagerHandler) WriteFile(w http.ResponseWriter, r *http.Request) {
 	stat, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			http.Error(w, "File not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "File not found")
 		} else {
-			http.Error(w, "Failed to access file", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to access file")
 		}
 		return
 	}
 
 	if stat.IsDir() {
-		http.Error(w, "Cannot write to directory", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Cannot write to directory")
 		return
 	}
 
 	// Write file content
 	err = os.WriteFile(absPath, []byte(req.Content), stat.Mode())
 	if err != nil {
-		http.Error(w, "Failed to write file", http.StatusInternalServerError)
-		h.logger.Printf("Failed to write file %s: %v", absPath, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to write file")
+		requestLog(r, h.logger).Error("Failed to write file", "path", absPath, "error", err)
 		return
 	}
 
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"podmanview/internal/podman"
+)
+
+// Graph node types
+const (
+	graphNodeContainer = "container"
+	graphNodePod       = "pod"
+	graphNodeNetwork   = "network"
+	graphNodeVolume    = "volume"
+	graphNodeProject   = "project"
+)
+
+// Compose labels set by docker compose and podman-compose
+const (
+	labelComposeProject   = "com.docker.compose.project"
+	labelComposeService   = "com.docker.compose.service"
+	labelComposeDependsOn = "com.docker.compose.depends_on"
+	labelComposeNumber    = "com.docker.compose.container-number"
+	labelPodmanProject    = "io.podman.compose.project"
+)
+
+// GraphNode is a container, pod, network, volume or compose project.
+// IDs are prefixed with the node type ("network:podman").
+type GraphNode struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	State string `json:"state,omitempty"` // containers only
+	Image string `json:"image,omitempty"` // containers only
+}
+
+// GraphEdge links a container to a node it depends on. Type is the node
+// type it links to, or "depends_on" for a compose dependency between two
+// containers.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// ContainerGraph is the dependency graph of all containers
+type ContainerGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// Graph handles GET /api/containers/graph. It returns the containers and the
+// pods, networks, named volumes and compose projects that link them, with a
+// depends_on edge for each compose dependency. Pod infra containers are left
+// out; their pod node stands for them.
+func (h *ContainerHandler) Graph(w http.ResponseWriter, r *http.Request) {
+	containers, err := h.client.ListContainers(r.Context())
+	if err != nil {
+		writeErr(w, r, err, "Failed to list containers")
+		return
+	}
+
+	// Volume names are only reported by inspect
+	volumes := make(map[string][]string, len(containers))
+	for _, c := range containers {
+		if c.IsInfra {
+			continue
+		}
+		info, err := h.client.InspectContainer(r.Context(), c.ID)
+		if err != nil {
+			continue // removed meanwhile
+		}
+		for _, m := range info.Mounts {
+			if m.Type == "volume" && m.Name != "" {
+				volumes[c.ID] = append(volumes[c.ID], m.Name)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, buildContainerGraph(containers, volumes))
+}
+
+// buildContainerGraph builds the graph of containers, given the named
+// volumes of each container by ID
+func buildContainerGraph(containers []podman.Container, volumes map[string][]string) *ContainerGraph {
+	graph := &ContainerGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	seen := make(map[string]bool)
+	addNode := func(node GraphNode) string {
+		if !seen[node.ID] {
+			seen[node.ID] = true
+			graph.Nodes = append(graph.Nodes, node)
+		}
+		return node.ID
+	}
+	link := func(source, target, typ string) {
+		graph.Edges = append(graph.Edges, GraphEdge{Source: source, Target: target, Type: typ})
+	}
+
+	// Compose services by project, to resolve depends_on
+	services := make(map[string]string)
+	for _, c := range containers {
+		if project := composeProject(c); project != "" && c.Labels[labelComposeService] != "" {
+			services[project+"/"+c.Labels[labelComposeService]] = graphNodeContainer + ":" + c.ID
+		}
+	}
+
+	for _, c := range containers {
+		if c.IsInfra {
+			continue
+		}
+		id := addNode(GraphNode{
+			ID:    graphNodeContainer + ":" + c.ID,
+			Type:  graphNodeContainer,
+			Name:  strings.TrimPrefix(firstOf(c.Names), "/"),
+			State: c.State,
+			Image: c.Image,
+		})
+
+		if c.Pod != "" {
+			name := c.PodName
+			if name == "" {
+				name = shortID(c.Pod)
+			}
+			link(id, addNode(GraphNode{ID: graphNodePod + ":" + c.Pod, Type: graphNodePod, Name: name}), graphNodePod)
+		}
+		for _, network := range c.Networks {
+			link(id, addNode(GraphNode{ID: graphNodeNetwork + ":" + network, Type: graphNodeNetwork, Name: network}), graphNodeNetwork)
+		}
+		for _, volume := range volumes[c.ID] {
+			link(id, addNode(GraphNode{ID: graphNodeVolume + ":" + volume, Type: graphNodeVolume, Name: volume}), graphNodeVolume)
+		}
+
+		project := composeProject(c)
+		if project == "" {
+			continue
+		}
+		link(id, addNode(GraphNode{ID: graphNodeProject + ":" + project, Type: graphNodeProject, Name: project}), graphNodeProject)
+		for _, dep := range composeDependencies(c.Labels[labelComposeDependsOn]) {
+			if target, ok := services[project+"/"+dep]; ok {
+				link(id, target, "depends_on")
+			}
+		}
+	}
+
+	slices.SortStableFunc(graph.Nodes, func(a, b GraphNode) int { return strings.Compare(a.ID, b.ID) })
+	return graph
+}
+
+// composeProject returns the compose project of a container, if any
+func composeProject(c podman.Container) string {
+	if project := c.Labels[labelComposeProject]; project != "" {
+		return project
+	}
+	return c.Labels[labelPodmanProject]
+}
+
+// composeDependencies parses the depends_on label of docker compose
+// ("db:service_healthy:false,cache:service_started:false") into service names
+func composeDependencies(label string) []string {
+	var deps []string
+	for _, entry := range strings.Split(label, ",") {
+		service, _, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if service != "" {
+			deps = append(deps, service)
+		}
+	}
+	return deps
+}
@@ -0,0 +1,687 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/events"
+	"podmanview/internal/graphql"
+	"podmanview/internal/plugins"
+	"podmanview/internal/podman"
+)
+
+// maxGraphQLRequest limits the size of a GraphQL request body
+const maxGraphQLRequest = 1 << 20
+
+// GraphQLHandler serves the read-only GraphQL API: containers, images,
+// pods, host stats and events, with subscriptions over Server-Sent Events
+type GraphQLHandler struct {
+	client   *podman.Client
+	store    *events.Store
+	engine   *engineEventHub
+	registry *plugins.Registry
+	drainer  *drainer
+	schema   *graphql.Schema
+
+	readHostStats func() *HostStats // GetHostStats, mock stats in demo mode
+}
+
+// NewGraphQLHandler creates new GraphQL handler
+func NewGraphQLHandler(client *podman.Client, store *events.Store, engine *engineEventHub, registry *plugins.Registry, drainer *drainer) *GraphQLHandler {
+	h := &GraphQLHandler{client: client, store: store, engine: engine, registry: registry, drainer: drainer, readHostStats: GetHostStats}
+	h.schema = h.buildSchema()
+	return h
+}
+
+// Query handles GET and POST /api/graphql
+// POST {"query": "{ containers(status: \"running\") { name cpu: stats { cpu } } }", "variables": {...}}
+// GET ?query=...&variables=... (JSON). Requests with Accept: text/event-stream
+// are answered as a stream (graphql-sse): one "next" event per result, then
+// "complete". Subscriptions require a stream.
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if v := params.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeError(w, r, http.StatusBadRequest, "Invalid variables: "+err.Error())
+				return
+			}
+		}
+	} else {
+		decoder := json.NewDecoder(io.LimitReader(r.Body, maxGraphQLRequest))
+		decoder.UseNumber()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), graphQLLoaderKey{}, &graphQLLoader{client: h.client})
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.stream(w, r.WithContext(ctx), req)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.schema.Execute(ctx, req))
+}
+
+// stream writes the results of a subscription as Server-Sent Events
+func (h *GraphQLHandler) stream(w http.ResponseWriter, r *http.Request, req graphql.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	results, errResp := h.schema.Subscribe(ctx, req)
+	if errResp != nil {
+		writeJSON(w, http.StatusBadRequest, errResp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closing:
+			return // server shutting down, the client reconnects
+		case result, ok := <-results:
+			if !ok {
+				fmt.Fprint(w, "event: complete\ndata:\n\n")
+				flusher.Flush()
+				return
+			}
+			writeSSE(w, "next", "", result)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// Schema handles GET /api/graphql/schema
+// Returns the schema in SDL form (introspection queries are not supported).
+func (h *GraphQLHandler) Schema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, h.schema.SDL())
+}
+
+// graphQLLoaderKey is the context key of the request's graphQLLoader
+type graphQLLoaderKey struct{}
+
+// graphQLLoader loads data shared by the fields of one request once,
+// and only if a selected field needs it
+type graphQLLoader struct {
+	client    *podman.Client
+	statsOnce sync.Once
+	stats     map[string]*podman.ContainerStats
+}
+
+// containerStats returns the stats of running containers by ID
+func (l *graphQLLoader) containerStats(ctx context.Context) map[string]*podman.ContainerStats {
+	l.statsOnce.Do(func() {
+		l.stats = make(map[string]*podman.ContainerStats)
+		stats, _ := l.client.GetContainersStats(ctx)
+		for i := range stats {
+			l.stats[stats[i].ContainerID] = &stats[i]
+		}
+	})
+	return l.stats
+}
+
+// graphQLImage is the source of the Image type
+type graphQLImage struct {
+	podman.Image
+	InUse bool `json:"inUse"`
+}
+
+// graphQLLabel is a label as a key-value pair
+type graphQLLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// labelList converts labels to a list sorted by key
+func labelList(labels map[string]string) []graphQLLabel {
+	list := make([]graphQLLabel, 0, len(labels))
+	for k, v := range labels {
+		list = append(list, graphQLLabel{Key: k, Value: v})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// Sort fields of the GraphQL lists
+var (
+	graphQLContainerSort = map[string]func(a, b podman.Container) int{
+		"name":    func(a, b podman.Container) int { return compareFold(firstOf(a.Names), firstOf(b.Names)) },
+		"image":   func(a, b podman.Container) int { return compareFold(a.Image, b.Image) },
+		"state":   func(a, b podman.Container) int { return compareFold(a.State, b.State) },
+		"created": func(a, b podman.Container) int { return a.Created.Compare(b.Created) },
+	}
+	graphQLImageSort = map[string]func(a, b graphQLImage) int{
+		"name":    func(a, b graphQLImage) int { return compareFold(firstOf(a.RepoTags), firstOf(b.RepoTags)) },
+		"created": func(a, b graphQLImage) int { return cmp.Compare(a.Created, b.Created) },
+		"size":    func(a, b graphQLImage) int { return cmp.Compare(a.Size, b.Size) },
+	}
+)
+
+// graphQLListQuery builds the filters of a list field from its arguments,
+// the same as the query parameters of the REST lists
+func graphQLListQuery(args map[string]interface{}, sortFields []string) (listQuery, error) {
+	var q listQuery
+	if v, ok := args["search"].(string); ok {
+		q.search = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := args["sort"].(string); ok && v != "" {
+		q.sort, q.desc = strings.TrimPrefix(v, "-"), strings.HasPrefix(v, "-")
+		if !slices.Contains(sortFields, q.sort) {
+			return q, fmt.Errorf("invalid sort field %q, expected one of: %s", q.sort, strings.Join(sortFields, ", "))
+		}
+	}
+	for _, v := range stringArgs(args["status"]) {
+		q.status = append(q.status, strings.ToLower(v))
+	}
+	for _, term := range stringArgs(args["label"]) {
+		req, ok := parseLabelRequirement(term)
+		if !ok {
+			return q, fmt.Errorf("invalid label selector %q", term)
+		}
+		q.labels = append(q.labels, req)
+	}
+	return q, nil
+}
+
+// stringArgs returns the strings of a list argument
+func stringArgs(v interface{}) []string {
+	list, _ := v.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// window applies the offset and limit arguments of a list field
+func window[T any](items []T, args map[string]interface{}) ([]T, error) {
+	offset, _ := args["offset"].(int)
+	limit, hasLimit := args["limit"].(int)
+	if offset < 0 || (hasLimit && limit < 0) {
+		return nil, fmt.Errorf("offset and limit must not be negative")
+	}
+	items = items[min(offset, len(items)):]
+	if hasLimit && limit < len(items) {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// forward sends the values of in accepted by convert to the returned
+// channel until ctx is done, then calls stop
+func forward[T any](ctx context.Context, in <-chan T, stop func(), convert func(T) (interface{}, bool)) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		defer stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				value, ok := convert(v)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- value:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// hostStats returns the host stats with the temperature plugin data
+func (h *GraphQLHandler) hostStats() *HostStats {
+	stats := h.readHostStats()
+	stats.DiskTotal, stats.DiskFree = 0, 0 // deprecated, use disks
+	addPluginTemperatures(h.registry, stats)
+	return stats
+}
+
+// eventsQuery builds an event filter from the arguments of an events field
+func eventsQuery(args map[string]interface{}) (events.Query, error) {
+	q := events.Query{}
+	q.TypePrefix, _ = args["type"].(string)
+	q.Username, _ = args["user"].(string)
+	q.Search, _ = args["search"].(string)
+	if v, ok := args["category"].(string); ok {
+		q.Category = events.Category(v)
+	}
+	if v, ok := args["severity"].(string); ok {
+		q.Severity = events.Severity(v)
+		if q.Severity.Level() < 0 {
+			return q, fmt.Errorf("invalid severity %q, expected info, warning, error or critical", v)
+		}
+	}
+	return q, nil
+}
+
+// buildSchema defines the GraphQL types and their resolvers
+func (h *GraphQLHandler) buildSchema() *graphql.Schema {
+	str := graphql.NonNull(graphql.String)
+	strList := graphql.NonNull(graphql.ListOf(str))
+	num := graphql.NonNull(graphql.Float)
+	listOf := func(o *graphql.Object) *graphql.Type {
+		return graphql.NonNull(graphql.ListOf(graphql.NonNull(graphql.ObjectOf(o))))
+	}
+	listArgs := func(statusDescription string) []*graphql.Argument {
+		return []*graphql.Argument{
+			{Name: "status", Type: graphql.ListOf(str), Description: statusDescription},
+			{Name: "label", Type: graphql.ListOf(str), Description: "Label selector terms: key, !key, key=value or key!=value"},
+			{Name: "search", Type: graphql.String, Description: "Case-insensitive text search"},
+			{Name: "sort", Type: graphql.String, Description: "Sort field, prefixed with - for descending"},
+			{Name: "offset", Type: graphql.Int},
+			{Name: "limit", Type: graphql.Int},
+		}
+	}
+	eventArgs := []*graphql.Argument{
+		{Name: "type", Type: graphql.String, Description: "Event type prefix, e.g. container_"},
+		{Name: "category", Type: graphql.String},
+		{Name: "severity", Type: graphql.String, Description: "Minimum severity: info, warning, error or critical"},
+		{Name: "user", Type: graphql.String},
+		{Name: "search", Type: graphql.String, Description: "Words that must all appear in the details"},
+	}
+	labels := func(get func(source interface{}) map[string]string) graphql.ResolveFunc {
+		return func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return labelList(get(source)), nil
+		}
+	}
+
+	label := &graphql.Object{Name: "Label", Fields: []*graphql.Field{
+		{Name: "key", Type: str},
+		{Name: "value", Type: str},
+	}}
+	port := &graphql.Object{Name: "Port", Fields: []*graphql.Field{
+		{Name: "ip", Type: graphql.String},
+		{Name: "privatePort", Type: graphql.NonNull(graphql.Int)},
+		{Name: "publicPort", Type: graphql.NonNull(graphql.Int)},
+		{Name: "type", Type: str, Description: "Protocol: tcp or udp"},
+	}}
+	containerStats := &graphql.Object{Name: "ContainerStats", Fields: []*graphql.Field{
+		{Name: "cpu", Type: num, Description: "CPU usage in percent"},
+		{Name: "memUsage", Type: num, Description: "Bytes"},
+		{Name: "memLimit", Type: num, Description: "Bytes"},
+		{Name: "memPerc", Type: num, Description: "Memory usage in percent"},
+		{Name: "netInput", Type: num},
+		{Name: "netOutput", Type: num},
+		{Name: "blockInput", Type: num},
+		{Name: "blockOutput", Type: num},
+		{Name: "pids", Type: num},
+	}}
+	container := &graphql.Object{Name: "Container", Fields: []*graphql.Field{
+		{Name: "id", Type: graphql.NonNull(graphql.ID)},
+		{Name: "name", Type: str, Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return firstOf(source.(podman.Container).Names), nil
+		}},
+		{Name: "names", Type: strList},
+		{Name: "image", Type: str},
+		{Name: "imageId", Type: str},
+		{Name: "command", Type: strList},
+		{Name: "state", Type: str, Description: "created, running, paused, exited, ..."},
+		{Name: "status", Type: str},
+		{Name: "created", Type: graphql.String, Description: "RFC 3339 time"},
+		{Name: "labels", Type: listOf(label), Resolve: labels(func(source interface{}) map[string]string {
+			return source.(podman.Container).Labels
+		})},
+		{Name: "ports", Type: listOf(port)},
+		{Name: "stats", Type: graphql.ObjectOf(containerStats), Description: "Resource usage, null if not running",
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				loader := ctx.Value(graphQLLoaderKey{}).(*graphQLLoader)
+				return loader.containerStats(ctx)[source.(podman.Container).ID], nil
+			}},
+	}}
+	image := &graphql.Object{Name: "Image", Fields: []*graphql.Field{
+		{Name: "id", Type: graphql.NonNull(graphql.ID)},
+		{Name: "repoTags", Type: strList},
+		{Name: "repoDigests", Type: strList},
+		{Name: "created", Type: str, Description: "RFC 3339 time", Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return time.Unix(source.(graphQLImage).Created, 0), nil
+		}},
+		{Name: "size", Type: num, Description: "Bytes"},
+		{Name: "inUse", Type: graphql.NonNull(graphql.Boolean), Description: "Used by a container"},
+		{Name: "labels", Type: listOf(label), Resolve: labels(func(source interface{}) map[string]string {
+			return source.(graphQLImage).Labels
+		})},
+	}}
+	podContainer := &graphql.Object{Name: "PodContainer", Fields: []*graphql.Field{
+		{Name: "id", Type: graphql.NonNull(graphql.ID)},
+		{Name: "name", Type: str, Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return source.(podman.PodContainer).Names, nil
+		}},
+		{Name: "status", Type: str},
+	}}
+	pod := &graphql.Object{Name: "Pod", Fields: []*graphql.Field{
+		{Name: "id", Type: graphql.NonNull(graphql.ID)},
+		{Name: "name", Type: str},
+		{Name: "status", Type: str},
+		{Name: "created", Type: graphql.String, Description: "RFC 3339 time"},
+		{Name: "infraId", Type: graphql.String},
+		{Name: "labels", Type: listOf(label), Resolve: labels(func(source interface{}) map[string]string {
+			return source.(podman.Pod).Labels
+		})},
+		{Name: "containers", Type: listOf(podContainer)},
+	}}
+	temperature := &graphql.Object{Name: "Temperature", Fields: []*graphql.Field{
+		{Name: "label", Type: str},
+		{Name: "temp", Type: num, Description: "Degrees Celsius"},
+		{Name: "history", Type: graphql.ListOf(num), Description: "Recent samples, oldest first"},
+	}}
+	storageTemp := &graphql.Object{Name: "StorageTemp", Fields: []*graphql.Field{
+		{Name: "device", Type: str},
+		{Name: "sensors", Type: listOf(temperature)},
+	}}
+	deviceMapping := &graphql.Object{Name: "DeviceMapping", Fields: []*graphql.Field{
+		{Name: "kernel", Type: str, Description: "Kernel name, e.g. dm-0"},
+		{Name: "name", Type: str},
+		{Name: "type", Type: str, Description: "lvm, luks, crypt or the UUID prefix of other targets"},
+		{Name: "vg", Type: str},
+		{Name: "lv", Type: str},
+		{Name: "backing", Type: graphql.ListOf(str), Description: "Underlying devices"},
+	}}
+	disk := &graphql.Object{Name: "Disk", Fields: []*graphql.Field{
+		{Name: "device", Type: str},
+		{Name: "mountPoint", Type: str},
+		{Name: "total", Type: num, Description: "Bytes"},
+		{Name: "free", Type: num, Description: "Bytes"},
+		{Name: "used", Type: num, Description: "Bytes"},
+		{Name: "readBytesPerSec", Type: num, Description: "Bytes read per second"},
+		{Name: "writeBytesPerSec", Type: num, Description: "Bytes written per second"},
+		{Name: "utilization", Type: num, Description: "Percent of the time with I/O in flight"},
+		{Name: "mapping", Type: graphql.ObjectOf(deviceMapping), Description: "LVM or LUKS device, null for plain partitions"},
+		{Name: "network", Type: graphql.String, Description: "Filesystem type of NFS and CIFS mounts"},
+		{Name: "stale", Type: graphql.Boolean, Description: "Network mount not responding"},
+	}}
+	poolDevice := &graphql.Object{Name: "PoolDevice", Fields: []*graphql.Field{
+		{Name: "name", Type: str},
+		{Name: "state", Type: str, Description: "ZFS only"},
+		{Name: "readErrors", Type: num},
+		{Name: "writeErrors", Type: num},
+		{Name: "checksumErrors", Type: num},
+	}}
+	pool := &graphql.Object{Name: "StoragePool", Fields: []*graphql.Field{
+		{Name: "name", Type: str},
+		{Name: "type", Type: str, Description: "zfs or btrfs"},
+		{Name: "health", Type: str, Description: "ZFS only"},
+		{Name: "mountPoint", Type: str},
+		{Name: "total", Type: num, Description: "Bytes"},
+		{Name: "used", Type: num, Description: "Bytes"},
+		{Name: "free", Type: num, Description: "Bytes"},
+		{Name: "devices", Type: listOf(poolDevice)},
+	}}
+	boolean := graphql.NonNull(graphql.Boolean)
+	throttling := &graphql.Object{Name: "Throttling", Fields: []*graphql.Field{
+		{Name: "raw", Type: str, Description: "get_throttled value in hex"},
+		{Name: "underVoltage", Type: boolean},
+		{Name: "frequencyCapped", Type: boolean},
+		{Name: "throttled", Type: boolean},
+		{Name: "softTempLimit", Type: boolean},
+		{Name: "underVoltageOccurred", Type: boolean, Description: "Since boot"},
+		{Name: "frequencyCappedOccurred", Type: boolean, Description: "Since boot"},
+		{Name: "throttledOccurred", Type: boolean, Description: "Since boot"},
+		{Name: "softTempLimitOccurred", Type: boolean, Description: "Since boot"},
+	}}
+	raidMember := &graphql.Object{Name: "RaidMember", Fields: []*graphql.Field{
+		{Name: "name", Type: str},
+		{Name: "state", Type: str, Description: "active, faulty, spare, journal or replacement"},
+		{Name: "writeMostly", Type: boolean},
+	}}
+	raidSync := &graphql.Object{Name: "RaidSync", Fields: []*graphql.Field{
+		{Name: "action", Type: str, Description: "resync, recovery, reshape, check or repair"},
+		{Name: "pending", Type: boolean},
+		{Name: "progress", Type: num, Description: "Percent"},
+		{Name: "remaining", Type: num, Description: "Estimate in seconds"},
+		{Name: "speed", Type: num, Description: "Bytes per second"},
+	}}
+	raidArray := &graphql.Object{Name: "RaidArray", Fields: []*graphql.Field{
+		{Name: "name", Type: str},
+		{Name: "level", Type: str},
+		{Name: "state", Type: str, Description: "active or inactive"},
+		{Name: "readOnly", Type: boolean},
+		{Name: "size", Type: num, Description: "Bytes"},
+		{Name: "devices", Type: num, Description: "Devices the array should have"},
+		{Name: "active", Type: num, Description: "Devices in sync"},
+		{Name: "status", Type: str, Description: "Per slot, U in sync or _ missing"},
+		{Name: "degraded", Type: boolean},
+		{Name: "members", Type: listOf(raidMember)},
+		{Name: "sync", Type: graphql.ObjectOf(raidSync), Description: "null while idle"},
+	}}
+	host := &graphql.Object{Name: "Host", Fields: []*graphql.Field{
+		{Name: "cpuUsage", Type: num, Description: "CPU usage in percent"},
+		{Name: "memTotal", Type: num, Description: "Bytes"},
+		{Name: "memFree", Type: num, Description: "Available memory in bytes"},
+		{Name: "uptime", Type: num, Description: "Seconds"},
+		{Name: "disks", Type: listOf(disk)},
+		{Name: "temperatures", Type: listOf(temperature), Description: "From the temperature plugin, empty if it is disabled"},
+		{Name: "storageTemps", Type: listOf(storageTemp)},
+		{Name: "pools", Type: listOf(pool), Description: "ZFS pools and btrfs filesystems"},
+		{Name: "raid", Type: listOf(raidArray), Description: "md software RAID arrays"},
+		{Name: "throttling", Type: graphql.ObjectOf(throttling), Description: "Raspberry Pi under-voltage and throttling flags, null on other hosts"},
+	}}
+	event := &graphql.Object{Name: "Event", Fields: []*graphql.Field{
+		{Name: "id", Type: graphql.NonNull(graphql.ID)},
+		{Name: "type", Type: str},
+		{Name: "timestamp", Type: str, Description: "RFC 3339 time"},
+		{Name: "username", Type: str},
+		{Name: "ip", Type: str},
+		{Name: "success", Type: graphql.NonNull(graphql.Boolean)},
+		{Name: "details", Type: str},
+		{Name: "category", Type: str},
+		{Name: "severity", Type: str},
+	}}
+	engineEvent := &graphql.Object{Name: "EngineEvent", Fields: []*graphql.Field{
+		{Name: "type", Type: str, Description: "container, image, pod, volume, ..."},
+		{Name: "action", Type: str, Description: "start, died, pull, ..."},
+		{Name: "id", Type: str, Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return source.(podman.EngineEvent).Actor.ID, nil
+		}},
+		{Name: "name", Type: graphql.String, Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			if name, ok := source.(podman.EngineEvent).Actor.Attributes["name"]; ok {
+				return name, nil
+			}
+			return nil, nil
+		}},
+		{Name: "attributes", Type: listOf(label), Resolve: labels(func(source interface{}) map[string]string {
+			return source.(podman.EngineEvent).Actor.Attributes
+		})},
+		{Name: "time", Type: str, Description: "RFC 3339 time", Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return time.Unix(source.(podman.EngineEvent).Time, 0), nil
+		}},
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.Field{
+		{Name: "containers", Type: listOf(container), Args: listArgs("States, e.g. running or exited"), Resolve: h.resolveContainers},
+		{Name: "container", Type: graphql.ObjectOf(container), Description: "Container by ID, ID prefix or name",
+			Args: []*graphql.Argument{{Name: "id", Type: graphql.NonNull(graphql.ID)}}, Resolve: h.resolveContainer},
+		{Name: "images", Type: listOf(image), Args: listArgs("used or unused"), Resolve: h.resolveImages},
+		{Name: "pods", Type: listOf(pod), Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			return h.client.ListPods(ctx)
+		}},
+		{Name: "host", Type: graphql.NonNull(graphql.ObjectOf(host)), Description: "Host resource usage",
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return h.hostStats(), nil
+			}},
+		{Name: "events", Type: listOf(event), Description: "Audit events, newest first",
+			Args: append([]*graphql.Argument{{Name: "limit", Type: graphql.Int, Default: 50}}, eventArgs...), Resolve: h.resolveEvents},
+	}}
+	subscription := &graphql.Object{Name: "Subscription", Fields: []*graphql.Field{
+		{Name: "events", Type: graphql.NonNull(graphql.ObjectOf(event)), Description: "New audit events",
+			Args: eventArgs, Subscribe: h.subscribeEvents},
+		{Name: "engineEvents", Type: graphql.NonNull(graphql.ObjectOf(engineEvent)), Description: "Podman engine events",
+			Args: []*graphql.Argument{{Name: "type", Type: graphql.String}, {Name: "action", Type: graphql.String}}, Subscribe: h.subscribeEngineEvents},
+		{Name: "host", Type: graphql.NonNull(graphql.ObjectOf(host)), Description: "Host resource usage every interval seconds",
+			Args: []*graphql.Argument{{Name: "interval", Type: graphql.Int, Default: 5}}, Subscribe: h.subscribeHost},
+	}}
+	return &graphql.Schema{Query: query, Subscription: subscription}
+}
+
+func (h *GraphQLHandler) resolveContainers(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	query, err := graphQLListQuery(args, slices.Sorted(maps.Keys(graphQLContainerSort)))
+	if err != nil {
+		return nil, err
+	}
+	containers, err := h.client.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]podman.Container, 0, len(containers))
+	for _, c := range containers {
+		if query.matchStatus(c.State) && query.matchLabels(c.Labels) &&
+			query.matchSearch(append([]string{c.ID, c.Image}, c.Names...)...) {
+			result = append(result, c)
+		}
+	}
+	sortList(result, query, graphQLContainerSort)
+	return window(result, args)
+}
+
+func (h *GraphQLHandler) resolveContainer(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	id := args["id"].(string)
+	containers, err := h.client.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		if c.ID == id || slices.Contains(c.Names, id) || (len(id) >= 12 && strings.HasPrefix(c.ID, id)) {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (h *GraphQLHandler) resolveImages(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	query, err := graphQLListQuery(args, slices.Sorted(maps.Keys(graphQLImageSort)))
+	if err != nil {
+		return nil, err
+	}
+	images, err := h.client.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers, _ := h.client.ListContainers(ctx)
+	used := make(map[string]bool)
+	for _, c := range containers {
+		used[c.ImageID] = true
+	}
+
+	result := make([]graphQLImage, 0, len(images))
+	for _, img := range images {
+		status := "unused"
+		if used[img.ID] {
+			status = "used"
+		}
+		if query.matchStatus(status) && query.matchLabels(img.Labels) &&
+			query.matchSearch(append([]string{img.ID}, img.RepoTags...)...) {
+			result = append(result, graphQLImage{Image: img, InUse: used[img.ID]})
+		}
+	}
+	sortList(result, query, graphQLImageSort)
+	return window(result, args)
+}
+
+func (h *GraphQLHandler) resolveEvents(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+	q, err := eventsQuery(args)
+	if err != nil {
+		return nil, err
+	}
+	q.Limit, _ = args["limit"].(int)
+	if q.Limit < 1 || q.Limit > maxListLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+	}
+	list, _ := h.store.Query(q)
+	return list, nil
+}
+
+func (h *GraphQLHandler) subscribeEvents(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error) {
+	q, err := eventsQuery(args)
+	if err != nil {
+		return nil, err
+	}
+	ch, unsubscribe := h.store.Subscribe(streamBuffer)
+	return forward(ctx, ch, unsubscribe, func(e events.Event) (interface{}, bool) {
+		return e, q.Match(e)
+	}), nil
+}
+
+func (h *GraphQLHandler) subscribeEngineEvents(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error) {
+	eventType, _ := args["type"].(string)
+	action, _ := args["action"].(string)
+	ch, unsubscribe := h.engine.subscribe()
+	return forward(ctx, ch, unsubscribe, func(e podman.EngineEvent) (interface{}, bool) {
+		return e, (eventType == "" || e.Type == eventType) && (action == "" || e.Action == action)
+	}), nil
+}
+
+func (h *GraphQLHandler) subscribeHost(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error) {
+	interval, _ := args["interval"].(int)
+	if interval < 1 || interval > 3600 {
+		return nil, fmt.Errorf("interval must be between 1 and 3600 seconds")
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	ticks := make(chan time.Time, 1)
+	ticks <- time.Now() // first result right away
+	go func() {
+		defer close(ticks)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-ticker.C:
+				select {
+				case ticks <- t:
+				default: // previous result still pending
+				}
+			}
+		}
+	}()
+	return forward(ctx, ticks, ticker.Stop, func(time.Time) (interface{}, bool) {
+		return h.hostStats(), true
+	}), nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// healthCheckTimeout bounds each dependency check of the probes
+const healthCheckTimeout = 2 * time.Second
+
+// Probe check results. Probes are unauthenticated, so they report only
+// these values, never versions, names or error messages.
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+// probeResponse is the body of /healthz and /readyz
+type probeResponse struct {
+	Status string            `json:"status"`           // ok, degraded or fail
+	Checks map[string]string `json:"checks,omitempty"` // component -> ok or fail
+}
+
+// Healthz is the liveness probe: it fails only when PodmanView itself is
+// broken (the database does not respond), not when Podman is unavailable,
+// so restarting the service would not help
+func (s *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	checks := make(map[string]string)
+	if s.storage != nil {
+		checks["storage"] = checkResult(s.checkStorage(r.Context()))
+	}
+	writeProbe(w, checks, nil)
+}
+
+// Readyz is the readiness probe: PodmanView can serve requests when Podman
+// and the database respond and the server is not shutting down. Enabled
+// plugins that are not running degrade the status without failing it.
+func (s *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := make(map[string]string)
+	if s.podmanClient != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		checks["podman"] = checkResult(s.podmanClient.Ping(ctx))
+		cancel()
+	}
+	if s.storage != nil {
+		checks["storage"] = checkResult(s.checkStorage(r.Context()))
+		if s.pluginRegistry != nil && !s.config.DemoMode() {
+			checks["plugins"] = checkResult(s.checkPlugins())
+		}
+	}
+	if s.drainer.isClosed() {
+		checks["server"] = checkFail
+	}
+	writeProbe(w, checks, map[string]bool{"plugins": true})
+}
+
+// Live reports whether PodmanView itself works, like Healthz. Used for the
+// systemd watchdog.
+func (s *Server) Live(ctx context.Context) error {
+	if s.storage == nil {
+		return nil
+	}
+	return s.checkStorage(ctx)
+}
+
+// checkStorage reads from the database. Bolt waits for a locked database
+// without a timeout, so the read runs in the background.
+func (s *Server) checkStorage(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := s.storage.ListEnabledPlugins()
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkPlugins fails when a plugin enabled in storage is not running
+func (s *Server) checkPlugins() error {
+	enabled, err := s.storage.ListEnabledPlugins()
+	if err != nil {
+		return err
+	}
+	for _, name := range enabled {
+		if _, registered := s.pluginRegistry.Get(name); registered && !s.pluginRegistry.IsRunning(name) {
+			return errors.New("plugin " + name + " is not running")
+		}
+	}
+	return nil
+}
+
+// checkResult converts a check error to a probe check result
+func checkResult(err error) string {
+	if err != nil {
+		return checkFail
+	}
+	return checkOK
+}
+
+// writeProbe answers 200 when all checks pass, 200 "degraded" when only
+// optional checks fail and 503 otherwise. Responses are never cached.
+func writeProbe(w http.ResponseWriter, checks map[string]string, optional map[string]bool) {
+	resp := probeResponse{Status: checkOK, Checks: checks}
+	status := http.StatusOK
+	for name, check := range checks {
+		if check == checkOK {
+			continue
+		}
+		if !optional[name] {
+			resp.Status = checkFail
+			status = http.StatusServiceUnavailable
+		} else if resp.Status == checkOK {
+			resp.Status = "degraded"
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, resp)
+}
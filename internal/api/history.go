@@ -1,13 +1,41 @@
 package api
 
 import (
+	"bytes"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"podmanview/internal/auth"
 	"podmanview/internal/storage"
 )
 
+const (
+	// terminalHistorySize is the number of commands sent to a terminal on connect
+	terminalHistorySize = 50
+
+	// maxHistoryEntries is the number of commands kept in storage
+	maxHistoryEntries = 5000
+
+	// maxHistoryPage limits the page size of GET /api/history
+	maxHistoryPage = 500
+
+	// exitStatusPrefix starts the escape sequence printed by the shell before
+	// each prompt (see promptCommandEnv): ESC ] 6973 ; <status> ; <directory> BEL
+	exitStatusPrefix = "\x1b]6973;"
+
+	// maxExitStatusLen limits the digits of a status before a sequence is treated as output
+	maxExitStatusLen = 8
+	// maxStatusSequenceLen limits a whole sequence, with the working directory
+	maxStatusSequenceLen = maxExitStatusLen + 1 + 4096
+)
+
+// promptCommandEnv makes bash report the exit status of each command and its
+// working directory to the terminal handler
+const promptCommandEnv = `PROMPT_COMMAND=printf '\033]6973;%d;%s\007' $? "$PWD"`
+
 // HistoryHandler handles command history operations
 type HistoryHandler struct {
 	storage storage.Storage
@@ -21,41 +49,213 @@ func NewHistoryHandler(store storage.Storage) *HistoryHandler {
 	}
 }
 
-// loadHistory returns command history array (last 50 commands)
-func (h *HistoryHandler) loadHistory() []string {
+// List handles GET /api/history
+// Query parameters: q (words that must all appear in the command), user,
+// target ("host" or container ID), offset and limit. Newest commands first.
+func (h *HistoryHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	query := storage.HistoryQuery{
+		Search: r.URL.Query().Get("q"),
+		User:   r.URL.Query().Get("user"),
+		Target: r.URL.Query().Get("target"),
+		Limit:  50,
+	}
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
+			query.Offset = parsed
+		}
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= maxHistoryPage {
+			query.Limit = parsed
+		}
+	}
+
+	h.mu.RLock()
+	page, err := h.storage.SearchCommandHistory(query)
+	h.mu.RUnlock()
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": page.Entries,
+		"total":   page.Total,
+		"offset":  query.Offset,
+		"limit":   query.Limit,
+	})
+}
+
+// loadHistory returns the last commands run on a target, oldest first
+func (h *HistoryHandler) loadHistory(target string) []string {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	entries, err := h.storage.GetCommandHistory(50)
+	page, err := h.storage.SearchCommandHistory(storage.HistoryQuery{Target: target, Limit: terminalHistorySize})
 	if err != nil {
 		return []string{}
 	}
 
-	commands := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		commands = append(commands, entry.Command)
+	commands := make([]string, len(page.Entries))
+	for i, entry := range page.Entries {
+		commands[len(commands)-1-i] = entry.Command
 	}
 
 	return commands
 }
 
 // saveCommand saves a command to history (called from WebSocket)
-func (h *HistoryHandler) saveCommand(command string) error {
+// and returns the ID of its history entry, 0 if nothing was saved
+func (h *HistoryHandler) saveCommand(username, target, command string) (int64, error) {
 	command = strings.TrimSpace(command)
 	if command == "" {
-		return nil
+		return 0, nil
 	}
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	// Save to storage (duplicate check is handled inside)
-	if err := h.storage.SaveCommandHistory(command, time.Now()); err != nil {
-		return err
+	entry := &storage.CommandHistoryEntry{
+		Command:   command,
+		Timestamp: time.Now(),
+		User:      username,
+		Target:    target,
+	}
+	if err := h.storage.AddCommandHistory(entry); err != nil {
+		return 0, err
+	}
+
+	// Keep only the last commands (trim if needed)
+	go h.storage.TrimCommandHistory(maxHistoryEntries)
+
+	return entry.ID, nil
+}
+
+// setExitCode records the exit status of a saved command
+func (h *HistoryHandler) setExitCode(id int64, code int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.storage.SetCommandExitCode(id, code)
+}
+
+// commandTracker saves the commands of a terminal session and records
+// the exit status reported by the shell for the last saved command, and
+// the working directory of the shell
+type commandTracker struct {
+	history *HistoryHandler
+	user    string
+	target  string
+
+	mu      sync.Mutex
+	pending int64  // command awaiting its exit status, 0 = none
+	partial []byte // incomplete exit status sequence from the previous output
+	dir     string // working directory at the last prompt, "" until reported
+}
+
+// newCommandTracker creates a tracker for a terminal session
+func newCommandTracker(history *HistoryHandler, user, target string) *commandTracker {
+	return &commandTracker{history: history, user: user, target: target}
+}
+
+// save saves a command entered in the terminal
+func (t *commandTracker) save(command string) {
+	id, err := t.history.saveCommand(t.user, t.target, command)
+	if err != nil || id == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	t.pending = id
+	t.mu.Unlock()
+}
+
+// workDir returns the working directory of the shell at its last prompt,
+// "" if the shell doesn't report it (shells other than bash)
+func (t *commandTracker) workDir() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dir
+}
+
+// output strips exit status sequences from terminal output and records
+// the status for the pending command. Sequences split between reads are
+// held back until complete.
+func (t *commandTracker) output(data []byte) []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buf := data
+	if len(t.partial) > 0 {
+		buf = append(t.partial, data...)
+		t.partial = nil
 	}
 
-	// Keep only last 500 commands (trim if needed)
-	go h.storage.TrimCommandHistory(500)
+	prefix := []byte(exitStatusPrefix)
+	out := make([]byte, 0, len(buf))
+	for {
+		i := bytes.Index(buf, prefix)
+		if i < 0 {
+			keep := partialPrefixLen(buf, prefix)
+			out = append(out, buf[:len(buf)-keep]...)
+			t.partial = append([]byte(nil), buf[len(buf)-keep:]...)
+			return out
+		}
+		out = append(out, buf[:i]...)
+
+		rest := buf[i+len(prefix):]
+		end := bytes.IndexByte(rest, '\a')
+		if end < 0 && len(rest) <= maxStatusSequenceLen {
+			t.partial = append([]byte(nil), buf[i:]...)
+			return out
+		}
 
-	return nil
+		code, dir, ok := parseExitStatus(rest, end)
+		if !ok {
+			// Not a status sequence, pass the prefix through
+			out = append(out, prefix...)
+			buf = rest
+			continue
+		}
+
+		if dir != "" {
+			t.dir = dir
+		}
+		if t.pending != 0 {
+			t.history.setExitCode(t.pending, code)
+			t.pending = 0
+		}
+		buf = rest[end+1:]
+	}
+}
+
+// parseExitStatus parses the status digits and the working directory
+// before the BEL at end
+func parseExitStatus(rest []byte, end int) (int, string, bool) {
+	if end < 1 || end > maxStatusSequenceLen {
+		return 0, "", false
+	}
+	status, dir, _ := bytes.Cut(rest[:end], []byte(";"))
+	if len(status) < 1 || len(status) > maxExitStatusLen {
+		return 0, "", false
+	}
+	code, err := strconv.Atoi(string(status))
+	return code, string(dir), err == nil
+}
+
+// partialPrefixLen returns the length of the longest start of prefix that buf ends with
+func partialPrefixLen(buf, prefix []byte) int {
+	for n := len(prefix) - 1; n > 0; n-- {
+		if bytes.HasSuffix(buf, prefix[:n]) {
+			return n
+		}
+	}
+	return 0
 }
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ImageLayers is the build history of an image with the size each step
+// added, to see what makes an image large
+type ImageLayers struct {
+	ID      string       `json:"id"`
+	Size    int64        `json:"size"`    // bytes, sum of the layers
+	Layers  int          `json:"layers"`  // steps that added a layer
+	History []ImageLayer `json:"history"` // newest first, as podman history
+}
+
+// ImageLayer is a step of the build history
+type ImageLayer struct {
+	ID        string   `json:"id,omitempty"` // empty for steps of base images pulled from a registry
+	Created   int64    `json:"created"`      // Unix time
+	Command   string   `json:"command"`      // Dockerfile instruction, e.g. RUN apk add curl
+	CreatedBy string   `json:"createdBy"`    // as recorded by the builder
+	Size      int64    `json:"size"`         // bytes
+	Percent   float64  `json:"percent"`      // of the image size
+	Empty     bool     `json:"empty"`        // metadata only (ENV, CMD...), no layer
+	Tags      []string `json:"tags,omitempty"`
+	Comment   string   `json:"comment,omitempty"`
+}
+
+// Layers handles GET /api/images/{id}/layers
+func (h *ImageHandler) Layers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	info, err := h.client.InspectImage(r.Context(), id)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	history, err := h.client.ImageHistory(r.Context(), id)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	result := ImageLayers{ID: info.ID, History: make([]ImageLayer, 0, len(history))}
+	for _, step := range history {
+		result.Size += step.Size
+		if step.Size > 0 {
+			result.Layers++
+		}
+	}
+	for _, step := range history {
+		layer := ImageLayer{
+			Created:   step.Created,
+			Command:   historyCommand(step.CreatedBy),
+			CreatedBy: step.CreatedBy,
+			Size:      step.Size,
+			Empty:     step.Size == 0,
+			Tags:      step.Tags,
+			Comment:   step.Comment,
+		}
+		if step.ID != "<missing>" {
+			layer.ID = step.ID
+		}
+		if result.Size > 0 {
+			layer.Percent = math.Round(float64(step.Size)*1000/float64(result.Size)) / 10
+		}
+		result.History = append(result.History, layer)
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// historyCommand turns the CreatedBy of a history step into the
+// Dockerfile instruction:
+//
+//	/bin/sh -c #(nop)  CMD ["sh"]         -> CMD ["sh"]
+//	/bin/sh -c apk add curl               -> RUN apk add curl
+//	|1 VERSION=1.2 /bin/sh -c make        -> RUN make (build arguments)
+//	RUN /bin/sh -c make # buildkit        -> RUN make
+func historyCommand(createdBy string) string {
+	command := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(createdBy), "# buildkit"))
+
+	// Build arguments: |<count> followed by count name=value pairs
+	if rest, ok := strings.CutPrefix(command, "|"); ok {
+		count, args, _ := strings.Cut(rest, " ")
+		if n, err := strconv.Atoi(count); err == nil {
+			for range n {
+				_, args, _ = strings.Cut(strings.TrimLeft(args, " "), " ")
+			}
+			command = strings.TrimSpace(args)
+		}
+	}
+
+	run, isRun := strings.CutPrefix(command, "RUN ")
+	if isRun {
+		command = run
+	}
+	for _, shell := range []string{"/bin/sh -c ", "/bin/bash -c ", "sh -c "} {
+		if rest, ok := strings.CutPrefix(command, shell); ok {
+			if rest, ok := strings.CutPrefix(rest, "#(nop)"); ok {
+				return strings.TrimSpace(rest)
+			}
+			return "RUN " + strings.TrimSpace(rest)
+		}
+	}
+	if isRun {
+		return "RUN " + command
+	}
+	return command
+}
@@ -1,8 +1,16 @@
 package api
 
 import (
+	"cmp"
 	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 
@@ -31,11 +39,26 @@ type ImageWithUsage struct {
 	InUse    bool     `json:"InUse"`
 }
 
+// imageSortFields are the sort fields of the image list
+var imageSortFields = map[string]func(a, b ImageWithUsage) int{
+	"name":    func(a, b ImageWithUsage) int { return compareFold(firstOf(a.RepoTags), firstOf(b.RepoTags)) },
+	"created": func(a, b ImageWithUsage) int { return cmp.Compare(a.Created, b.Created) },
+	"size":    func(a, b ImageWithUsage) int { return cmp.Compare(a.Size, b.Size) },
+}
+
 // List handles GET /api/images
+// GET /api/images?page=1&limit=50&sort=-size&status=unused&label=maintainer&q=alpine
+// Status is "used" or "unused" by containers; see parseListQuery.
 func (h *ImageHandler) List(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r, slices.Sorted(maps.Keys(imageSortFields))...)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
 	images, err := h.client.ListImages(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -49,18 +72,27 @@ func (h *ImageHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build response with usage info
-	result := make([]ImageWithUsage, len(images))
-	for i, img := range images {
-		result[i] = ImageWithUsage{
+	result := make([]ImageWithUsage, 0, len(images))
+	for _, img := range images {
+		status := "unused"
+		if usedImageIDs[img.ID] {
+			status = "used"
+		}
+		if !query.matchStatus(status) || !query.matchLabels(img.Labels) ||
+			!query.matchSearch(append([]string{img.ID}, img.RepoTags...)...) {
+			continue
+		}
+		result = append(result, ImageWithUsage{
 			ID:       img.ID,
 			RepoTags: img.RepoTags,
 			Created:  img.Created,
 			Size:     img.Size,
 			InUse:    usedImageIDs[img.ID],
-		}
+		})
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	sortList(result, query, imageSortFields)
+	writeList(w, r, result, query)
 }
 
 // Inspect handles GET /api/images/{id}
@@ -69,7 +101,7 @@ func (h *ImageHandler) Inspect(w http.ResponseWriter, r *http.Request) {
 
 	info, err := h.client.InspectImage(r.Context(), id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -79,34 +111,69 @@ func (h *ImageHandler) Inspect(w http.ResponseWriter, r *http.Request) {
 // PullRequest represents image pull request
 type PullRequest struct {
 	Reference string `json:"reference"`
+	Platform  string `json:"platform,omitempty"` // os/architecture[/variant], the host's if empty
+}
+
+// platformRe matches platforms such as linux/amd64 and linux/arm/v7
+var platformRe = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// validPlatform reports whether a platform to pull is empty or
+// os/architecture[/variant]
+func validPlatform(platform string) bool {
+	return platform == "" || platformRe.MatchString(platform)
+}
+
+// imagePlatform returns the os/architecture of an image, empty if unknown
+func imagePlatform(image *podman.ImageInspect) string {
+	if image.Os == "" || image.Architecture == "" {
+		return ""
+	}
+	return image.Os + "/" + image.Architecture
+}
+
+// foreignPlatform returns the platform of an image if it is not the one of
+// the host, e.g. an arm64 image run under emulation on amd64
+func foreignPlatform(image *podman.ImageInspect) string {
+	if platform := imagePlatform(image); platform != runtime.GOOS+"/"+runtime.GOARCH {
+		return platform
+	}
+	return ""
 }
 
 // Pull handles POST /api/images/pull
 func (h *ImageHandler) Pull(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	var req PullRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Reference == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Reference is required"})
+		writeError(w, r, http.StatusBadRequest, "Reference is required")
+		return
+	}
+	if !validPlatform(req.Platform) {
+		writeError(w, r, http.StatusBadRequest, "Invalid platform")
 		return
 	}
 
-	if err := h.client.PullImage(r.Context(), req.Reference); err != nil {
-		h.eventStore.Add(events.EventImagePull, user.Username, getClientIP(r), false, req.Reference)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	details := req.Reference
+	if req.Platform != "" {
+		details += " (" + req.Platform + ")"
+	}
+	if err := h.client.PullImagePlatform(r.Context(), req.Reference, req.Platform); err != nil {
+		h.eventStore.Add(events.EventImagePull, user.Username, getClientIP(r), false, details)
+		writeErr(w, r, err, "")
 		return
 	}
 
-	h.eventStore.Add(events.EventImagePull, user.Username, getClientIP(r), true, req.Reference)
+	h.eventStore.Add(events.EventImagePull, user.Username, getClientIP(r), true, details)
 	writeJSON(w, http.StatusOK, map[string]string{"status": "pulled"})
 }
 
@@ -114,7 +181,7 @@ func (h *ImageHandler) Pull(w http.ResponseWriter, r *http.Request) {
 func (h *ImageHandler) Remove(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -123,10 +190,97 @@ func (h *ImageHandler) Remove(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.client.RemoveImage(r.Context(), id, force); err != nil {
 		h.eventStore.Add(events.EventImageRemove, user.Username, getClientIP(r), false, shortID(id))
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
 	h.eventStore.Add(events.EventImageRemove, user.Username, getClientIP(r), true, shortID(id))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
 }
+
+// maxImageUpload limits the size of an uploaded image archive
+const maxImageUpload = 32 << 30 // 32 GB
+
+// Export handles GET /api/images/{id}/export
+// Streams the image as a docker-archive tar for "podman load" or Import.
+func (h *ImageHandler) Export(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	info, err := h.client.InspectImage(r.Context(), id)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	archive, err := h.client.ExportImage(r.Context(), id)
+	if err != nil {
+		h.eventStore.Add(events.EventImageExport, user.Username, getClientIP(r), false, shortID(info.ID))
+		writeErr(w, r, err, "")
+		return
+	}
+	defer archive.Close()
+
+	name := shortID(info.ID)
+	if len(info.RepoTags) > 0 {
+		// docker.io/library/nginx:1.27 -> nginx_1.27
+		tag := info.RepoTags[0]
+		name = tag[strings.LastIndex(tag, "/")+1:]
+	}
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.tar\"", sanitizeFilename(name)))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, so a failed copy can only be logged
+	if _, err := io.Copy(w, archive); err != nil {
+		h.eventStore.Add(events.EventImageExport, user.Username, getClientIP(r), false, shortID(info.ID))
+		return
+	}
+	h.eventStore.Add(events.EventImageExport, user.Username, getClientIP(r), true, firstOf(info.RepoTags))
+}
+
+// Import handles POST /api/images/import
+// Loads the images of a tar archive (docker-archive or oci-archive, as
+// written by Export or "podman save"). The archive is the request body or
+// the "file" field of a multipart form, and is streamed to Podman.
+func (h *ImageHandler) Import(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUpload)
+	archive := io.Reader(r.Body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid form data")
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "Archive too large or missing file field")
+				return
+			}
+			if part.FormName() == "file" {
+				archive = part
+				break
+			}
+		}
+	}
+
+	names, err := h.client.LoadImage(r.Context(), archive)
+	if err != nil {
+		h.eventStore.Add(events.EventImageImport, user.Username, getClientIP(r), false, err.Error())
+		writeErr(w, r, err, "Failed to load image")
+		return
+	}
+
+	h.eventStore.Add(events.EventImageImport, user.Username, getClientIP(r), true, strings.Join(names, ", "))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "loaded", "images": names})
+}
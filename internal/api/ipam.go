@@ -0,0 +1,273 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"podmanview/internal/podman"
+)
+
+// Subnet states
+const (
+	subnetOK        = "ok"
+	subnetWarning   = "warning"   // nearly exhausted
+	subnetExhausted = "exhausted" // no free address left
+)
+
+// subnetWarningPercent is the share of allocated addresses at which a
+// subnet is nearly exhausted
+const subnetWarningPercent = 90
+
+// maxCountedHostBits limits the subnets whose addresses are counted. Larger
+// subnets, e.g. IPv6 /64, can't run out.
+const maxCountedHostBits = 32
+
+// NetworkHandler handles Podman network endpoints
+type NetworkHandler struct {
+	client *podman.Client
+}
+
+// NewNetworkHandler creates new network handler
+func NewNetworkHandler(client *podman.Client) *NetworkHandler {
+	return &NetworkHandler{client: client}
+}
+
+// IPAllocation is an address of a container in a network
+type IPAllocation struct {
+	IP          string `json:"ip"`
+	Container   string `json:"container"`
+	ContainerID string `json:"containerId"`
+	Pod         string `json:"pod,omitempty"`
+	MAC         string `json:"mac,omitempty"`
+}
+
+// SubnetUsage is the address usage of a subnet. Capacity is the number of
+// addresses containers can get, without the gateway; it is null for
+// subnets too large to run out.
+type SubnetUsage struct {
+	Subnet      string   `json:"subnet"`
+	Gateway     string   `json:"gateway,omitempty"`
+	RangeStart  string   `json:"rangeStart,omitempty"`
+	RangeEnd    string   `json:"rangeEnd,omitempty"`
+	Capacity    *int64   `json:"capacity"`
+	Allocated   int      `json:"allocated"`
+	Free        *int64   `json:"free"`
+	UsedPercent *float64 `json:"usedPercent"`
+	Status      string   `json:"status"`             // ok, warning or exhausted
+	Overlaps    []string `json:"overlaps,omitempty"` // other networks with an overlapping subnet
+}
+
+// NetworkIPAM is the address management of a network
+type NetworkIPAM struct {
+	Name        string         `json:"name"`
+	Driver      string         `json:"driver"`
+	Internal    bool           `json:"internal"`
+	Subnets     []SubnetUsage  `json:"subnets"`
+	Allocations []IPAllocation `json:"allocations"` // sorted by address
+}
+
+// IPAMOverview is the address management of all networks. Warnings name
+// the subnets that are nearly or fully exhausted or overlap.
+type IPAMOverview struct {
+	Networks []NetworkIPAM `json:"networks"`
+	Warnings []string      `json:"warnings"`
+}
+
+// IPAM handles GET /api/networks/ipam
+// Lists the subnets of each network with the addresses allocated to
+// running containers, and warns about subnets running out of addresses.
+func (h *NetworkHandler) IPAM(w http.ResponseWriter, r *http.Request) {
+	networks, err := h.client.ListNetworks(r.Context())
+	if err != nil {
+		writeErr(w, r, err, "Failed to list networks")
+		return
+	}
+	containers, err := h.client.ListContainers(r.Context())
+	if err != nil {
+		writeErr(w, r, err, "Failed to list containers")
+		return
+	}
+
+	// Only running containers hold addresses; pod members share the
+	// address of their infra container
+	allocations := make(map[string][]IPAllocation)
+	for _, c := range containers {
+		if len(c.Networks) == 0 || (c.State != "running" && c.State != "paused") {
+			continue
+		}
+		info, err := h.client.InspectContainer(r.Context(), c.ID)
+		if err != nil {
+			continue // removed meanwhile
+		}
+		for name, n := range info.NetworkSettings.Networks {
+			for _, ip := range []string{n.IPAddress, n.GlobalIPv6Address} {
+				if ip == "" {
+					continue
+				}
+				allocations[name] = append(allocations[name], IPAllocation{
+					IP:          ip,
+					Container:   strings.TrimPrefix(firstOf(c.Names), "/"),
+					ContainerID: c.ID,
+					Pod:         c.PodName,
+					MAC:         n.MacAddress,
+				})
+			}
+		}
+	}
+
+	overview := IPAMOverview{Networks: make([]NetworkIPAM, 0, len(networks)), Warnings: []string{}}
+	for _, n := range networks {
+		network := NetworkIPAM{
+			Name:        n.Name,
+			Driver:      n.Driver,
+			Internal:    n.Internal,
+			Subnets:     make([]SubnetUsage, 0, len(n.Subnets)),
+			Allocations: allocations[n.Name],
+		}
+		if network.Allocations == nil {
+			network.Allocations = []IPAllocation{}
+		}
+		sort.Slice(network.Allocations, func(i, j int) bool {
+			return compareIPs(network.Allocations[i].IP, network.Allocations[j].IP) < 0
+		})
+		for _, s := range n.Subnets {
+			usage := subnetUsage(s, network.Allocations)
+			for _, other := range networks {
+				if other.Name != n.Name && overlapsAny(s.Subnet, other.Subnets) {
+					usage.Overlaps = append(usage.Overlaps, other.Name)
+				}
+			}
+			switch usage.Status {
+			case subnetExhausted:
+				overview.Warnings = append(overview.Warnings, fmt.Sprintf("%s %s: no free addresses left", n.Name, s.Subnet))
+			case subnetWarning:
+				overview.Warnings = append(overview.Warnings, fmt.Sprintf("%s %s: %.0f%% of addresses allocated", n.Name, s.Subnet, *usage.UsedPercent))
+			}
+			if len(usage.Overlaps) > 0 {
+				overview.Warnings = append(overview.Warnings, fmt.Sprintf("%s %s: overlaps %s", n.Name, s.Subnet, strings.Join(usage.Overlaps, ", ")))
+			}
+			network.Subnets = append(network.Subnets, usage)
+		}
+		overview.Networks = append(overview.Networks, network)
+	}
+	sort.Slice(overview.Networks, func(i, j int) bool { return overview.Networks[i].Name < overview.Networks[j].Name })
+
+	writeJSON(w, http.StatusOK, overview)
+}
+
+// subnetUsage counts the allocated and free addresses of a subnet
+func subnetUsage(s podman.Subnet, allocations []IPAllocation) SubnetUsage {
+	usage := SubnetUsage{Subnet: s.Subnet, Gateway: s.Gateway, Status: subnetOK}
+	if s.LeaseRange != nil {
+		usage.RangeStart, usage.RangeEnd = s.LeaseRange.StartIP, s.LeaseRange.EndIP
+	}
+	_, ipnet, err := net.ParseCIDR(s.Subnet)
+	if err != nil {
+		return usage
+	}
+	for _, a := range allocations {
+		if ip := net.ParseIP(a.IP); ip != nil && ipnet.Contains(ip) {
+			usage.Allocated++
+		}
+	}
+
+	first, last := hostRange(ipnet)
+	if ip := net.ParseIP(usage.RangeStart); ip != nil && ipnet.Contains(ip) {
+		first = ip
+	}
+	if ip := net.ParseIP(usage.RangeEnd); ip != nil && ipnet.Contains(ip) {
+		last = ip
+	}
+	ones, bits := ipnet.Mask.Size()
+	if bits-ones > maxCountedHostBits || first == nil || compareIPs(first.String(), last.String()) > 0 {
+		return usage
+	}
+	capacity := new(big.Int).Sub(ipToInt(last), ipToInt(first)).Int64() + 1
+	if gateway := net.ParseIP(s.Gateway); gateway != nil && compareIPs(first.String(), s.Gateway) <= 0 && compareIPs(s.Gateway, last.String()) <= 0 {
+		capacity-- // the gateway is in the range
+	}
+	free := max(capacity-int64(usage.Allocated), 0)
+	usage.Capacity, usage.Free = &capacity, &free
+
+	percent := 100.0
+	if capacity > 0 {
+		percent = math.Round(float64(usage.Allocated)/float64(capacity)*10000) / 100
+	}
+	usage.UsedPercent = &percent
+	switch {
+	case free == 0:
+		usage.Status = subnetExhausted
+	case percent >= subnetWarningPercent:
+		usage.Status = subnetWarning
+	}
+	return usage
+}
+
+// hostRange returns the first and last host address of a subnet. IPv4
+// subnets up to /30 lose the network and broadcast address, IPv6 subnets
+// the subnet-router anycast address.
+func hostRange(ipnet *net.IPNet) (net.IP, net.IP) {
+	ones, bits := ipnet.Mask.Size()
+	first := ipToInt(ipnet.IP)
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	last := new(big.Int).Add(first, size)
+	last.Sub(last, big.NewInt(1))
+	switch {
+	case bits == 32 && bits-ones >= 2:
+		first.Add(first, big.NewInt(1))
+		last.Sub(last, big.NewInt(1))
+	case bits == 128 && bits-ones >= 1:
+		first.Add(first, big.NewInt(1))
+	}
+	return intToIP(first, bits), intToIP(last, bits)
+}
+
+// overlapsAny reports whether subnet overlaps one of subnets
+func overlapsAny(subnet string, subnets []podman.Subnet) bool {
+	_, a, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return false
+	}
+	for _, s := range subnets {
+		if _, b, err := net.ParseCIDR(s.Subnet); err == nil && (a.Contains(b.IP) || b.Contains(a.IP)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareIPs orders addresses numerically, IPv4 first; invalid
+// addresses are compared as text
+func compareIPs(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return strings.Compare(a, b)
+	}
+	if v4A, v4B := ipA.To4() != nil, ipB.To4() != nil; v4A != v4B {
+		if v4A {
+			return -1
+		}
+		return 1
+	}
+	return slices.Compare(ipA.To16(), ipB.To16())
+}
+
+// ipToInt returns an address as an integer
+func ipToInt(ip net.IP) *big.Int {
+	if v4 := ip.To4(); v4 != nil {
+		return new(big.Int).SetBytes(v4)
+	}
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+// intToIP returns an integer as an address with bits bits
+func intToIP(n *big.Int, bits int) net.IP {
+	b := n.FillBytes(make([]byte, bits/8))
+	return net.IP(b)
+}
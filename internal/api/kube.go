@@ -0,0 +1,117 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+// maxKubeManifest limits the size of an uploaded Kubernetes manifest
+const maxKubeManifest = 4 << 20 // 4 MB
+
+// KubeHandler handles Kubernetes YAML endpoints (podman generate kube and
+// podman play kube)
+type KubeHandler struct {
+	client     *podman.Client
+	eventStore *events.Store
+}
+
+// NewKubeHandler creates new Kubernetes YAML handler
+func NewKubeHandler(client *podman.Client, eventStore *events.Store) *KubeHandler {
+	return &KubeHandler{client: client, eventStore: eventStore}
+}
+
+// Generate handles GET /api/kube?names=web,db&service=true
+// Returns Kubernetes YAML for the named containers and pods, with a Service
+// for their published ports if service is true.
+func (h *KubeHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	for _, name := range strings.Split(r.URL.Query().Get("names"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		writeError(w, r, http.StatusBadRequest, "Names are required")
+		return
+	}
+
+	manifest, err := h.client.GenerateKube(r.Context(), names, r.URL.Query().Get("service") == "true")
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	filename := sanitizeFilename(names[0])
+	if len(names) > 1 {
+		filename = "podmanview"
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.yaml"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(manifest)
+}
+
+// Play handles POST /api/kube/play?start=true&replace=false
+// Creates the pods, containers and volumes of a Kubernetes YAML manifest,
+// sent as the request body or the "file" field of a multipart form. Pods
+// are started unless start is false; replace removes existing pods and
+// containers of the same name first.
+func (h *KubeHandler) Play(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxKubeManifest)
+	var manifest []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		file, _, ferr := r.FormFile("file")
+		if ferr != nil {
+			writeError(w, r, http.StatusBadRequest, "Manifest too large or missing file field")
+			return
+		}
+		defer file.Close()
+		manifest, err = io.ReadAll(file)
+	} else {
+		manifest, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		writeErr(w, r, err, "Failed to read manifest")
+		return
+	}
+	if strings.TrimSpace(string(manifest)) == "" {
+		writeError(w, r, http.StatusBadRequest, "Manifest is required")
+		return
+	}
+
+	report, err := h.client.PlayKube(r.Context(), manifest, podman.PlayKubeOptions{
+		Start:   r.URL.Query().Get("start") != "false",
+		Replace: r.URL.Query().Get("replace") == "true",
+	})
+	if err != nil {
+		h.eventStore.Add(events.EventKubePlay, user.Username, getClientIP(r), false, err.Error())
+		writeErr(w, r, err, "Failed to deploy manifest")
+		return
+	}
+
+	var pods []string
+	success := true
+	for _, pod := range report.Pods {
+		pods = append(pods, shortID(pod.ID))
+		if len(pod.ContainerErrors) > 0 {
+			success = false
+		}
+	}
+	h.eventStore.Add(events.EventKubePlay, user.Username, getClientIP(r), success, "pods: "+strings.Join(pods, ", "))
+	if report.Pods == nil {
+		report.Pods = []podman.PlayKubePod{}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+	"podmanview/web/templates"
+)
+
+const (
+	// launcherBucket is the storage namespace of the launcher settings,
+	// kept under launcherSettingsKey. The access key is encrypted at rest.
+	launcherBucket      = "launcher"
+	launcherSettingsKey = "settings"
+
+	// defaultLauncherTitle is the heading of the launcher page
+	defaultLauncherTitle = "Home"
+	// maxLauncherLinks limits the curated links
+	maxLauncherLinks = 100
+	// maxLauncherText limits titles, names and groups
+	maxLauncherText = 64
+
+	// labelHomepageGroup groups containers on the launcher, as on Homepage
+	labelHomepageGroup = "homepage.group"
+)
+
+var (
+	errLauncherDisabled = apierror.New(http.StatusNotFound, "Launcher is disabled")
+	errLauncherKey      = apierror.New(http.StatusUnauthorized, "Invalid launcher key")
+	errLauncherText     = apierror.New(http.StatusBadRequest, fmt.Sprintf("Titles, names and groups can be up to %d characters", maxLauncherText))
+	errLauncherLinks    = apierror.New(http.StatusBadRequest, fmt.Sprintf("The launcher can have up to %d links", maxLauncherLinks))
+	errLauncherURL      = apierror.New(http.StatusBadRequest, "Links need a name and an http or https URL")
+)
+
+// LauncherLink is a link of the launcher page. With a container, the
+// link shows whether it is running, and a missing name, icon or
+// description is taken from the app of the container.
+type LauncherLink struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Icon        string `json:"icon,omitempty"`
+	Description string `json:"description,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Container   string `json:"container,omitempty"` // container name
+}
+
+// LauncherSettings configure the launcher page. It is off by default;
+// with RequireKey, visitors need the access key, e.g. from a bookmark
+// with ?key=.
+type LauncherSettings struct {
+	Enabled    bool           `json:"enabled"`
+	Title      string         `json:"title"`
+	RequireKey bool           `json:"requireKey"`
+	Key        string         `json:"key,omitempty"` // generated, read-only
+	Labels     bool           `json:"labels"`        // add containers with a homepage.href label
+	Links      []LauncherLink `json:"links"`
+	UpdatedAt  time.Time      `json:"updatedAt,omitempty"`
+}
+
+// LauncherEntry is a link as shown on the launcher page
+type LauncherEntry struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Icon        string `json:"icon,omitempty"`
+	Description string `json:"description,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Running     *bool  `json:"running,omitempty"` // null for links without a container
+}
+
+// LauncherHandler serves the launcher, a public homepage with links to the
+// web UIs of selected containers
+type LauncherHandler struct {
+	client     *podman.Client
+	storage    storage.Storage
+	eventStore *events.Store
+
+	// mu serializes settings changes
+	mu sync.Mutex
+}
+
+// NewLauncherHandler creates new launcher handler
+func NewLauncherHandler(client *podman.Client, store storage.Storage, eventStore *events.Store) *LauncherHandler {
+	return &LauncherHandler{client: client, storage: store, eventStore: eventStore}
+}
+
+// settings returns the stored settings, the defaults if there are none
+func (h *LauncherHandler) settings() (*LauncherSettings, error) {
+	settings := &LauncherSettings{Title: defaultLauncherTitle, Labels: true, Links: []LauncherLink{}}
+	if h.storage == nil {
+		return settings, nil
+	}
+	if err := h.storage.GetJSON(launcherBucket, launcherSettingsKey, settings); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// authorize returns the settings if the launcher is enabled and the
+// request has the access key when one is required
+func (h *LauncherHandler) authorize(r *http.Request) (*LauncherSettings, error) {
+	settings, err := h.settings()
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, errLauncherDisabled
+	}
+	if settings.RequireKey {
+		key := r.URL.Query().Get("key")
+		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(settings.Key)) != 1 {
+			return nil, errLauncherKey
+		}
+	}
+	return settings, nil
+}
+
+// Page handles GET /launcher
+// Serves the launcher page, which loads its links from GET /api/launcher
+func (h *LauncherHandler) Page(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.authorize(r); err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+		html := strings.ReplaceAll(string(templates.LauncherHTML), "{{BASE_PATH}}", basePath)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Referrer-Policy", "no-referrer") // keep the key out of Referer headers
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(html))
+	}
+}
+
+// Links handles GET /api/launcher (public)
+// Returns the title and links of the launcher, sorted by group and name
+func (h *LauncherHandler) Links(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.authorize(r)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	containers, err := h.client.ListContainers(r.Context())
+	if err != nil {
+		writeErr(w, r, err, "Failed to list containers")
+		return
+	}
+	byName := make(map[string]podman.Container, len(containers))
+	for _, c := range containers {
+		byName[strings.TrimPrefix(firstOf(c.Names), "/")] = c
+	}
+
+	entries := []LauncherEntry{}
+	linked := make(map[string]bool)
+	for _, link := range settings.Links {
+		entry := LauncherEntry{Name: link.Name, URL: link.URL, Icon: iconURL(link.Icon), Description: link.Description, Group: link.Group}
+		if c, ok := byName[link.Container]; ok && link.Container != "" {
+			linked[link.Container] = true
+			running := c.State == "running"
+			entry.Running = &running
+			if app := resolveAppInfo(c.Image, c.Labels); app != nil {
+				entry.Icon = firstNonEmpty(entry.Icon, app.Icon)
+				entry.Description = firstNonEmpty(entry.Description, app.Description)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if settings.Labels {
+		for name, c := range byName {
+			href := strings.TrimSpace(c.Labels[labelHomepageHref])
+			if linked[name] || !validLinkURL(href) {
+				continue
+			}
+			app := resolveAppInfo(c.Image, c.Labels)
+			running := c.State == "running"
+			entries = append(entries, LauncherEntry{
+				Name:        app.Name,
+				URL:         href,
+				Icon:        app.Icon,
+				Description: app.Description,
+				Group:       strings.TrimSpace(c.Labels[labelHomepageGroup]),
+				Running:     &running,
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Group != entries[j].Group {
+			return entries[i].Group < entries[j].Group
+		}
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"title": settings.Title, "links": entries})
+}
+
+// GetSettings handles GET /api/launcher/settings (admin)
+func (h *LauncherHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	settings, err := h.settings()
+	if err != nil {
+		writeErr(w, r, err, "Failed to read launcher settings")
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /api/launcher/settings (admin)
+// The body replaces the settings; the access key is generated the first
+// time it is required and kept afterwards.
+func (h *LauncherHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	var settings LauncherSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := validateLauncher(&settings); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	current, err := h.settings()
+	if err != nil {
+		writeErr(w, r, err, "Failed to read launcher settings")
+		return
+	}
+	settings.Key = current.Key
+	h.save(w, r, &settings, "launcher settings")
+}
+
+// RotateKey handles POST /api/launcher/settings/key (admin)
+// Replaces the access key, so links with the old key stop working
+func (h *LauncherHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	settings, err := h.settings()
+	if err != nil {
+		writeErr(w, r, err, "Failed to read launcher settings")
+		return
+	}
+	settings.Key = ""
+	h.save(w, r, settings, "launcher key rotated")
+}
+
+// save stores the settings, generating the access key when it is required
+// and missing. Call with h.mu held.
+func (h *LauncherHandler) save(w http.ResponseWriter, r *http.Request, settings *LauncherSettings, details string) {
+	user := auth.GetUserFromContext(r.Context())
+	if settings.RequireKey && settings.Key == "" {
+		key, err := randomHex(16)
+		if err != nil {
+			writeErr(w, r, err, "Failed to generate launcher key")
+			return
+		}
+		settings.Key = key
+	}
+	settings.UpdatedAt = time.Now()
+	if err := h.storage.SetJSON(launcherBucket, launcherSettingsKey, settings); err != nil {
+		h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), false, details)
+		writeErr(w, r, err, "Failed to save launcher settings")
+		return
+	}
+	h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), true, details)
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// validateLauncher checks and normalizes launcher settings
+func validateLauncher(settings *LauncherSettings) error {
+	settings.Title = strings.TrimSpace(settings.Title)
+	if settings.Title == "" {
+		settings.Title = defaultLauncherTitle
+	}
+	if len(settings.Title) > maxLauncherText {
+		return errLauncherText
+	}
+	if len(settings.Links) > maxLauncherLinks {
+		return errLauncherLinks
+	}
+	if settings.Links == nil {
+		settings.Links = []LauncherLink{}
+	}
+	for i := range settings.Links {
+		link := &settings.Links[i]
+		link.Name = strings.TrimSpace(link.Name)
+		link.Group = strings.TrimSpace(link.Group)
+		link.URL = strings.TrimSpace(link.URL)
+		link.Container = strings.TrimPrefix(strings.TrimSpace(link.Container), "/")
+		if link.Name == "" || !validLinkURL(link.URL) {
+			return errLauncherURL
+		}
+		if len(link.Name) > maxLauncherText || len(link.Group) > maxLauncherText {
+			return errLauncherText
+		}
+	}
+	return nil
+}
+
+// validLinkURL reports whether s is an absolute http or https URL
+func validLinkURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmanview/internal/apierror"
+)
+
+// List pagination limits
+const (
+	defaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+// listQuery holds the server-side pagination, sorting and filtering
+// parameters of the container and image lists:
+//
+//	?page=2&limit=50&sort=-created&status=running,paused&label=app=web,tier!=db&q=nginx
+type listQuery struct {
+	page   int // 0 if not paginated
+	limit  int
+	sort   string
+	desc   bool
+	status []string
+	labels []labelRequirement
+	search string
+}
+
+// labelRequirement is a term of a label selector: key, !key, key=value or key!=value
+type labelRequirement struct {
+	key   string
+	value string
+	op    string // "exists", "!exists", "=", "!="
+}
+
+// parseListQuery parses the list query parameters of r.
+// sortFields are the accepted sort fields (prefixed with "-" for descending).
+func parseListQuery(r *http.Request, sortFields ...string) (listQuery, error) {
+	params := r.URL.Query()
+	q := listQuery{search: strings.ToLower(strings.TrimSpace(params.Get("q")))}
+
+	if params.Has("page") || params.Has("limit") {
+		q.page, q.limit = 1, defaultListLimit
+		if v := params.Get("page"); v != "" {
+			page, err := strconv.Atoi(v)
+			if err != nil || page < 1 {
+				return q, apierror.New(http.StatusBadRequest, "Invalid page, expected a number from 1")
+			}
+			q.page = page
+		}
+		if v := params.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxListLimit {
+				return q, apierror.New(http.StatusBadRequest, "Invalid limit, expected 1 to "+strconv.Itoa(maxListLimit))
+			}
+			q.limit = limit
+		}
+	}
+
+	if v := params.Get("sort"); v != "" {
+		q.sort, q.desc = strings.TrimPrefix(v, "-"), strings.HasPrefix(v, "-")
+		if !slices.Contains(sortFields, q.sort) {
+			return q, apierror.New(http.StatusBadRequest, "Invalid sort field, expected one of: "+strings.Join(sortFields, ", ")).
+				WithDetails(map[string]interface{}{"sortFields": sortFields})
+		}
+	}
+
+	for _, v := range params["status"] {
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				q.status = append(q.status, strings.ToLower(status))
+			}
+		}
+	}
+
+	for _, v := range params["label"] {
+		for _, term := range strings.Split(v, ",") {
+			if term = strings.TrimSpace(term); term == "" {
+				continue
+			}
+			req, ok := parseLabelRequirement(term)
+			if !ok {
+				return q, apierror.New(http.StatusBadRequest, "Invalid label selector: "+term)
+			}
+			q.labels = append(q.labels, req)
+		}
+	}
+
+	return q, nil
+}
+
+// parseLabelRequirement parses a label selector term
+func parseLabelRequirement(term string) (labelRequirement, bool) {
+	var req labelRequirement
+	switch {
+	case strings.Contains(term, "!="):
+		key, value, _ := strings.Cut(term, "!=")
+		req = labelRequirement{key: key, value: value, op: "!="}
+	case strings.Contains(term, "="):
+		key, value, _ := strings.Cut(term, "=")
+		req = labelRequirement{key: key, value: value, op: "="}
+	case strings.HasPrefix(term, "!"):
+		req = labelRequirement{key: term[1:], op: "!exists"}
+	default:
+		req = labelRequirement{key: term, op: "exists"}
+	}
+	req.key = strings.TrimSpace(req.key)
+	req.value = strings.TrimSpace(req.value)
+	return req, req.key != ""
+}
+
+// matches reports whether labels satisfy the requirement
+func (l labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[l.key]
+	switch l.op {
+	case "exists":
+		return ok
+	case "!exists":
+		return !ok
+	case "=":
+		return ok && value == l.value
+	default: // "!="
+		return !ok || value != l.value
+	}
+}
+
+// matchStatus reports whether status is one of the requested statuses
+func (q listQuery) matchStatus(status string) bool {
+	return len(q.status) == 0 || slices.Contains(q.status, strings.ToLower(status))
+}
+
+// matchLabels reports whether labels satisfy all label requirements
+func (q listQuery) matchLabels(labels map[string]string) bool {
+	for _, req := range q.labels {
+		if !req.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSearch reports whether any of values contains the search text (case-insensitive)
+func (q listQuery) matchSearch(values ...string) bool {
+	if q.search == "" {
+		return true
+	}
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), q.search) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortList sorts items by the requested field using the compare function of
+// that field. Items keep the Podman order if no sort field is requested.
+func sortList[T any](items []T, q listQuery, fields map[string]func(a, b T) int) {
+	compare := fields[q.sort]
+	if compare == nil {
+		return
+	}
+	slices.SortStableFunc(items, func(a, b T) int {
+		if q.desc {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
+
+// compareFold compares strings case-insensitively
+func compareFold(a, b string) int {
+	return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// firstOf returns the first value (container name, image tag) or ""
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// writeList writes a filtered and sorted list. Without page and limit the
+// list is written as a JSON array; with them, the requested page is written as
+// {"items": [...], "total": 120, "page": 2, "limit": 50}.
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T, q listQuery) {
+	if q.page == 0 {
+		writeJSONConditional(w, r, items, time.Time{})
+		return
+	}
+
+	start := min((q.page-1)*q.limit, len(items))
+	end := min(start+q.limit, len(items))
+	writeJSONConditional(w, r, map[string]interface{}{
+		"items": items[start:end],
+		"total": len(items),
+		"page":  q.page,
+		"limit": q.limit,
+	}, time.Time{})
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/logger"
+)
+
+// LogsHandler serves the application's own logs
+type LogsHandler struct {
+	logger  *logger.Logger
+	drainer *drainer // closes live tails on shutdown
+}
+
+// NewLogsHandler creates new logs handler
+func NewLogsHandler(appLogger *logger.Logger, drainer *drainer) *LogsHandler {
+	return &LogsHandler{logger: appLogger, drainer: drainer}
+}
+
+// admin checks admin access and that logs are available
+func (h *LogsHandler) admin(w http.ResponseWriter, r *http.Request) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return false
+	}
+	if h.logger == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Logs not available")
+		return false
+	}
+	return true
+}
+
+// parseQuery reads the level, from, to and q filter parameters
+func (h *LogsHandler) parseQuery(w http.ResponseWriter, r *http.Request) (logger.Query, bool) {
+	params := r.URL.Query()
+	q := logger.Query{Level: logger.LevelDebug, Search: params.Get("q")}
+
+	if v := params.Get("level"); v != "" {
+		level, err := logger.ParseLevel(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid level, expected debug, info, warn or error")
+			return q, false
+		}
+		q.Level = level
+	}
+	for param, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := params.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "Invalid "+param+" time, expected RFC 3339")
+				return q, false
+			}
+			*t = parsed
+		}
+	}
+	return q, true
+}
+
+// List handles GET /api/system/logs
+// GET /api/system/logs?level=warn&from=...&to=...&q=webhook&limit=100&cursor=100
+// Returns entries of app.log and its rotated files, newest first, and
+// nextCursor to fetch the following (older) page, 0 on the last page.
+func (h *LogsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.admin(w, r) {
+		return
+	}
+	q, ok := h.parseQuery(w, r)
+	if !ok {
+		return
+	}
+
+	q.Limit = 100 // default
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 1000 {
+			q.Limit = l
+		}
+	}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		cursor, err := strconv.Atoi(v)
+		if err != nil || cursor < 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		q.Skip = cursor
+	}
+
+	entries, next, err := h.logger.Entries(q)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, "Failed to read logs: "+err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []logger.Entry{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":    entries,
+		"nextCursor": next,
+		"level":      logger.LevelName(h.logger.Level()),
+	})
+}
+
+// Stream handles GET /api/system/logs/stream
+// Server-Sent Events: "log" events with new entries matching the
+// level and q parameters. Only entries at the current log level are written.
+func (h *LogsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	if !h.admin(w, r) {
+		return
+	}
+	q, ok := h.parseQuery(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	entries, unsubscribe := h.logger.Subscribe(streamBuffer)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-closing:
+			return
+		case entry := <-entries:
+			if !q.Match(entry) {
+				continue
+			}
+			writeSSE(w, "log", "", entry)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
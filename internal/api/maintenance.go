@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+)
+
+// maintenanceAllowedPaths are mutating endpoints that stay available in maintenance mode
+var maintenanceAllowedPaths = map[string]bool{
+	"/api/auth/login":         true,
+	"/api/auth/logout":        true,
+	"/api/system/maintenance": true,
+	"/api/graphql":            true, // queries only, no mutations
+}
+
+// MaintenanceHandler handles read-only maintenance mode
+type MaintenanceHandler struct {
+	config     *config.Config
+	eventStore *events.Store
+}
+
+// NewMaintenanceHandler creates new maintenance handler
+func NewMaintenanceHandler(cfg *config.Config, eventStore *events.Store) *MaintenanceHandler {
+	return &MaintenanceHandler{config: cfg, eventStore: eventStore}
+}
+
+// Middleware rejects mutating requests with 503 while maintenance mode is active.
+// GET requests (stats, logs, lists) keep working, except interactive terminals
+// and attach sessions.
+func (h *MaintenanceHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.config.MaintenanceMode() && isMutatingRequest(r) {
+			writeError(w, r, http.StatusServiceUnavailable, "Server is in read-only maintenance mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isMutatingRequest reports whether the request can change host or container state
+func isMutatingRequest(r *http.Request) bool {
+	if maintenanceAllowedPaths[r.URL.Path] {
+		return false
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		// Terminals and attach sessions are GET (WebSocket upgrade) but
+		// take input
+		return strings.HasSuffix(r.URL.Path, "/terminal") || strings.HasSuffix(r.URL.Path, "/attach")
+	default:
+		return true
+	}
+}
+
+// Status handles GET /api/system/maintenance
+func (h *MaintenanceHandler) Status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": h.config.MaintenanceMode()})
+}
+
+// Toggle handles POST /api/system/maintenance
+func (h *MaintenanceHandler) Toggle(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	details := "disabled"
+	if req.Enabled {
+		details = "enabled"
+	}
+
+	if err := h.config.SetMaintenanceMode(req.Enabled); err != nil {
+		h.eventStore.Add(events.EventMaintenance, user.Username, getClientIP(r), false, details)
+		writeError(w, r, http.StatusInternalServerError, "Failed to save configuration: "+err.Error())
+		return
+	}
+
+	h.eventStore.Add(events.EventMaintenance, user.Username, getClientIP(r), true, details)
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": req.Enabled})
+}
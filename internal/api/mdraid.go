@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mdstatPath lists the Linux software RAID (md) arrays
+const mdstatPath = "/proc/mdstat"
+
+// RAID member states
+const (
+	RaidMemberActive      = "active"
+	RaidMemberFaulty      = "faulty"
+	RaidMemberSpare       = "spare"
+	RaidMemberJournal     = "journal"
+	RaidMemberReplacement = "replacement"
+)
+
+// RaidArray is an md software RAID array from /proc/mdstat
+type RaidArray struct {
+	Name     string       `json:"name"`  // md0
+	Level    string       `json:"level"` // raid1, raid5...; empty while inactive
+	State    string       `json:"state"` // active or inactive
+	ReadOnly bool         `json:"readOnly"`
+	Size     uint64       `json:"size"`             // bytes
+	Devices  int          `json:"devices"`          // devices the array should have, 0 without redundancy (raid0, linear)
+	Active   int          `json:"active"`           // devices in sync
+	Status   string       `json:"status,omitempty"` // per slot, U in sync or _ missing, e.g. U_
+	Degraded bool         `json:"degraded"`
+	Members  []RaidMember `json:"members"`
+	Sync     *RaidSync    `json:"sync,omitempty"` // null while idle
+}
+
+// RaidMember is a device of an array
+type RaidMember struct {
+	Name        string `json:"name"`  // sda1
+	State       string `json:"state"` // active, faulty, spare, journal or replacement
+	WriteMostly bool   `json:"writeMostly,omitempty"`
+}
+
+// RaidSync is a running or queued resync, recovery, reshape, check or
+// repair of an array
+type RaidSync struct {
+	Action    string  `json:"action"`              // resync, recovery, reshape, check or repair
+	Pending   bool    `json:"pending"`             // waiting for another array on the same devices
+	Progress  float64 `json:"progress"`            // percent
+	Remaining int64   `json:"remaining,omitempty"` // estimate in seconds
+	Speed     uint64  `json:"speed,omitempty"`     // bytes per second
+}
+
+var (
+	// mdDeviceRe matches a member in the array line: sdb1[1](F)
+	mdDeviceRe = regexp.MustCompile(`^([^\[\s]+)\[\d+\]((?:\([A-Z]\))*)$`)
+	// mdStatusRe matches the device counts and slots of the status line: [2/1] [U_]
+	mdStatusRe = regexp.MustCompile(`\[(\d+)/(\d+)\]\s+\[([U_]+)\]`)
+	// mdSyncRe matches a progress line: recovery =  8.5% (...) finish=152.1min speed=195840K/sec
+	mdSyncRe = regexp.MustCompile(`(resync|recovery|reshape|check|repair)\s*=\s*([\d.]+)%`)
+	// mdPendingRe matches a queued sync: resync=DELAYED or resync=PENDING
+	mdPendingRe   = regexp.MustCompile(`(resync|recovery|reshape|check|repair)\s*=\s*(DELAYED|PENDING)`)
+	mdFinishRe    = regexp.MustCompile(`finish=([\d.]+)min`)
+	mdSpeedRe     = regexp.MustCompile(`speed=(\d+)K/sec`)
+	mdBlocksRe    = regexp.MustCompile(`^(\d+) blocks`)
+	mdPersonality = map[string]bool{"linear": true, "multipath": true, "faulty": true}
+)
+
+// ParseMdstat parses the arrays of /proc/mdstat
+func ParseMdstat(data string) []RaidArray {
+	arrays := []RaidArray{}
+	var array *RaidArray
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		// Array line: md0 : active raid1 sdb1[1] sda1[0]
+		if name, rest, ok := strings.Cut(line, " : "); ok && strings.HasPrefix(name, "md") {
+			arrays = append(arrays, parseMdArray(strings.TrimSpace(name), strings.Fields(rest)))
+			array = &arrays[len(arrays)-1]
+			continue
+		}
+		if array == nil || (line[0] != ' ' && line[0] != '\t') {
+			array = nil // Personalities and unused devices
+			continue
+		}
+
+		if m := mdBlocksRe.FindStringSubmatch(trimmed); m != nil {
+			blocks, _ := strconv.ParseUint(m[1], 10, 64)
+			array.Size = blocks * 1024
+		}
+		if m := mdStatusRe.FindStringSubmatch(trimmed); m != nil {
+			array.Devices, _ = strconv.Atoi(m[1])
+			array.Active, _ = strconv.Atoi(m[2])
+			array.Status = m[3]
+			array.Degraded = array.Active < array.Devices
+		}
+		if m := mdSyncRe.FindStringSubmatch(trimmed); m != nil {
+			array.Sync = &RaidSync{Action: m[1]}
+			array.Sync.Progress, _ = strconv.ParseFloat(m[2], 64)
+			if m := mdFinishRe.FindStringSubmatch(trimmed); m != nil {
+				minutes, _ := strconv.ParseFloat(m[1], 64)
+				array.Sync.Remaining = int64(minutes * 60)
+			}
+			if m := mdSpeedRe.FindStringSubmatch(trimmed); m != nil {
+				speed, _ := strconv.ParseUint(m[1], 10, 64)
+				array.Sync.Speed = speed * 1024
+			}
+		} else if m := mdPendingRe.FindStringSubmatch(trimmed); m != nil {
+			array.Sync = &RaidSync{Action: m[1], Pending: true}
+		}
+	}
+	return arrays
+}
+
+// parseMdArray parses the fields after the name of an array line: the
+// state, the personality and the members
+func parseMdArray(name string, fields []string) RaidArray {
+	array := RaidArray{Name: name, Members: []RaidMember{}}
+	for i, field := range fields {
+		switch {
+		case i == 0:
+			array.State = field
+		case strings.HasPrefix(field, "(") && strings.HasSuffix(field, ")"):
+			array.ReadOnly = strings.Contains(field, "read-only")
+		case strings.HasPrefix(field, "raid") || mdPersonality[field]:
+			array.Level = field
+		default:
+			if m := mdDeviceRe.FindStringSubmatch(field); m != nil {
+				array.Members = append(array.Members, parseMdMember(m[1], m[2]))
+			}
+		}
+	}
+	return array
+}
+
+// parseMdMember sets the state of a member from its flags: (F)aulty,
+// (S)pare, (J)ournal, (R)eplacement and (W)rite-mostly
+func parseMdMember(name, flags string) RaidMember {
+	member := RaidMember{Name: name, State: RaidMemberActive, WriteMostly: strings.Contains(flags, "(W)")}
+	switch {
+	case strings.Contains(flags, "(F)"):
+		member.State = RaidMemberFaulty
+	case strings.Contains(flags, "(S)"):
+		member.State = RaidMemberSpare
+	case strings.Contains(flags, "(J)"):
+		member.State = RaidMemberJournal
+	case strings.Contains(flags, "(R)"):
+		member.State = RaidMemberReplacement
+	}
+	return member
+}
+
+// getRaidArrays returns the md arrays of the host, nil without md support
+func getRaidArrays() []RaidArray {
+	data, err := os.ReadFile(mdstatPath)
+	if err != nil {
+		return nil
+	}
+	arrays := ParseMdstat(string(data))
+	if len(arrays) == 0 {
+		return nil
+	}
+	return arrays
+}
+
+// raidDegradedDetails describes a degraded array for a raid_degraded event
+func raidDegradedDetails(array RaidArray) string {
+	details := fmt.Sprintf("%s (%s) is degraded, %d of %d devices in sync", array.Name, array.Level, array.Active, array.Devices)
+	var faulty []string
+	for _, member := range array.Members {
+		if member.State == RaidMemberFaulty {
+			faulty = append(faulty, member.Name)
+		}
+	}
+	if len(faulty) > 0 {
+		details += ", faulty: " + strings.Join(faulty, ", ")
+	}
+	if array.Sync != nil && array.Sync.Action == "recovery" && !array.Sync.Pending {
+		details += fmt.Sprintf(", recovering (%.1f%%)", array.Sync.Progress)
+	}
+	return details
+}
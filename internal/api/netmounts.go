@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// netMountTimeout is how long a network mount gets to answer statfs
+// before it is reported as stale
+const netMountTimeout = 2 * time.Second
+
+// networkFSTypes are the filesystem types listed with network mounts
+var networkFSTypes = map[string]bool{
+	"nfs":   true,
+	"nfs4":  true,
+	"cifs":  true,
+	"smb3":  true,
+	"smbfs": true,
+}
+
+// ErrStaleMount is returned by MountStatter for a mount that did not
+// answer in time
+var ErrStaleMount = errors.New("mount not responding")
+
+// StatfsFunc reads filesystem statistics, syscall.Statfs on the host
+type StatfsFunc func(path string, stat *syscall.Statfs_t) error
+
+// MountStatter runs statfs with a timeout. statfs on a hung NFS mount
+// blocks in the kernel and can't be cancelled, so the call is left
+// running in the background and the mount is reported as stale without
+// another call until it returns.
+type MountStatter struct {
+	statfs  StatfsFunc
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]bool // paths with a statfs still running
+}
+
+// NewMountStatter creates a statter giving each call timeout to answer
+func NewMountStatter(statfs StatfsFunc, timeout time.Duration) *MountStatter {
+	return &MountStatter{statfs: statfs, timeout: timeout, pending: make(map[string]bool)}
+}
+
+// Statfs returns the statistics of the filesystem at path, ErrStaleMount
+// if it didn't answer within the timeout or hasn't answered a previous call
+func (m *MountStatter) Statfs(path string) (syscall.Statfs_t, error) {
+	m.mu.Lock()
+	if m.pending[path] {
+		m.mu.Unlock()
+		return syscall.Statfs_t{}, ErrStaleMount
+	}
+	m.pending[path] = true
+	m.mu.Unlock()
+
+	type result struct {
+		stat syscall.Statfs_t
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		r.err = m.statfs(path, &r.stat)
+		m.mu.Lock()
+		delete(m.pending, path)
+		m.mu.Unlock()
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r.stat, r.err
+	case <-time.After(m.timeout):
+		return syscall.Statfs_t{}, ErrStaleMount
+	}
+}
+
+// hostNetMounts checks the network mounts of the host
+var hostNetMounts = NewMountStatter(syscall.Statfs, netMountTimeout)
+
+// ParseNetworkMounts returns the NFS and CIFS mounts of /proc/mounts with
+// the server path as device (server:/export, //server/share) and the
+// filesystem type in Network
+func ParseNetworkMounts(data string) []DiskInfo {
+	var mounts []DiskInfo
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !networkFSTypes[fields[2]] || seen[fields[1]] {
+			continue
+		}
+		seen[fields[1]] = true
+		mounts = append(mounts, DiskInfo{Device: fields[0], MountPoint: fields[1], Network: fields[2]})
+	}
+	return mounts
+}
+
+// getNetworkMounts returns the usage of the network mounts of the host,
+// stale ones without usage. Mounts are checked in parallel, so a hung
+// server costs one timeout however many of its mounts there are.
+func getNetworkMounts() []DiskInfo {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+
+	mounts := ParseNetworkMounts(string(data))
+	ok := make([]bool, len(mounts))
+	var wg sync.WaitGroup
+	for i := range mounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := &mounts[i]
+			stat, err := hostNetMounts.Statfs(m.MountPoint)
+			switch {
+			case errors.Is(err, ErrStaleMount):
+				m.Stale, ok[i] = true, true
+			case err == nil:
+				m.Total = stat.Blocks * uint64(stat.Bsize)
+				m.Free = stat.Bavail * uint64(stat.Bsize) // available to non-root users, as local disks
+				m.Used = m.Total - stat.Bfree*uint64(stat.Bsize)
+				ok[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	var result []DiskInfo
+	for i, m := range mounts {
+		if ok[i] {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// GetHostStatsWithNetworkMounts returns the host stats with the network
+// mounts at the end of the disk list
+func GetHostStatsWithNetworkMounts() *HostStats {
+	stats := GetHostStats()
+	stats.Disks = append(stats.Disks, getNetworkMounts()...)
+	return stats
+}
@@ -0,0 +1,50 @@
+//go:build linux
+// +build linux
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// inNetNS runs fn on a thread in the network namespace of process pid.
+// Sockets fn creates stay in that namespace after it returns. Entering
+// another namespace needs CAP_SYS_ADMIN, e.g. running as root.
+func inNetNS(pid int, fn func() error) error {
+	target, err := os.Open("/proc/" + strconv.Itoa(pid) + "/ns/net")
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	runtime.LockOSThread()
+	self, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer self.Close()
+
+	if err := setns(target); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter the network namespace: %w", err)
+	}
+	fnErr := fn()
+	if err := setns(self); err != nil {
+		// The thread stays locked, so it exits with the goroutine instead
+		// of running other goroutines in the wrong namespace
+		return fmt.Errorf("failed to leave the network namespace: %w", err)
+	}
+	runtime.UnlockOSThread()
+	return fnErr
+}
+
+// setns moves the current thread to the network namespace of f
+func setns(f *os.File) error {
+	return unix.Setns(int(f.Fd()), unix.CLONE_NEWNET)
+}
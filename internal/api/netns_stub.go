@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package api
+
+import "errors"
+
+// inNetNS is not supported outside Linux
+func inNetNS(pid int, fn func() error) error {
+	return errors.New("network namespaces are only supported on Linux")
+}
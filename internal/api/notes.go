@@ -0,0 +1,370 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+const (
+	// notesBucket is the storage namespace of notes, stored as
+	// "<kind>/<name>" with all their versions. Notes may say where
+	// credentials are kept, so the bucket is encrypted at rest.
+	notesBucket = "notes"
+
+	// maxNoteSize limits the markdown content of a note
+	maxNoteSize = 64 << 10
+	// maxNoteVersions limits the versions kept of a note, oldest go first
+	maxNoteVersions = 50
+)
+
+// Note kinds: what a note is attached to
+const (
+	NoteContainer = "container" // by container name
+	NoteStack     = "stack"     // by compose project name
+	NoteVolume    = "volume"    // by volume name
+)
+
+var (
+	errNoteKind     = apierror.New(http.StatusBadRequest, "Invalid note kind, use container, stack or volume")
+	errNoteNotFound = apierror.New(http.StatusNotFound, "Note not found")
+	errNoteTooLarge = apierror.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("Notes can be up to %d KB", maxNoteSize>>10))
+	errNoteConflict = apierror.New(http.StatusConflict, "The note was changed since it was loaded, reload it and try again")
+	errNoteVersion  = apierror.New(http.StatusNotFound, "Note version not found")
+	errStackUnknown = apierror.New(http.StatusNotFound, "Stack not found")
+)
+
+// NoteVersion is a saved version of a note
+type NoteVersion struct {
+	Version   int       `json:"version"`
+	Content   string    `json:"content,omitempty"`
+	Author    string    `json:"author"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Note is the current version of the markdown note of a container, stack
+// or volume
+type Note struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	NoteVersion
+}
+
+// storedNote is a note with its versions, oldest first
+type storedNote struct {
+	Versions []NoteVersion `json:"versions"`
+}
+
+// current returns the latest version
+func (n *storedNote) current() NoteVersion {
+	return n.Versions[len(n.Versions)-1]
+}
+
+// NoteHandler stores markdown notes of containers, stacks and volumes
+type NoteHandler struct {
+	client     *podman.Client
+	storage    storage.Storage
+	eventStore *events.Store
+
+	// mu serializes updates so version checks and saves don't interleave
+	mu sync.Mutex
+}
+
+// NewNoteHandler creates new note handler
+func NewNoteHandler(client *podman.Client, store storage.Storage, eventStore *events.Store) *NoteHandler {
+	return &NoteHandler{client: client, storage: store, eventStore: eventStore}
+}
+
+// available checks that storage is available
+func (h *NoteHandler) available(w http.ResponseWriter, r *http.Request) bool {
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return false
+	}
+	return true
+}
+
+// target returns the kind and name of the note of a request. Containers
+// given by ID are resolved to their name, so notes are kept when a
+// container is recreated. With exists, the container, stack or volume
+// must exist; otherwise notes of removed ones can still be read.
+func (h *NoteHandler) target(r *http.Request, exists bool) (string, string, error) {
+	kind, name := chi.URLParam(r, "kind"), chi.URLParam(r, "name")
+	if name == "" || strings.Contains(name, "/") {
+		return "", "", errNoteNotFound
+	}
+
+	var err error
+	switch kind {
+	case NoteContainer:
+		var info *podman.ContainerInspect
+		if info, err = h.client.InspectContainer(r.Context(), name); err == nil {
+			name = strings.TrimPrefix(info.Name, "/")
+		}
+	case NoteVolume:
+		_, err = h.client.InspectVolume(r.Context(), name)
+	case NoteStack:
+		if exists {
+			err = h.stackExists(r, name)
+		}
+	default:
+		return "", "", errNoteKind
+	}
+	if err != nil && (exists || apierror.Status(err) != http.StatusNotFound) {
+		return "", "", err
+	}
+	return kind, name, nil
+}
+
+// stackExists checks that a container belongs to the compose project
+func (h *NoteHandler) stackExists(r *http.Request, project string) error {
+	containers, err := h.client.ListContainers(r.Context())
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		if composeProject(c) == project {
+			return nil
+		}
+	}
+	return errStackUnknown
+}
+
+// load returns a stored note
+func (h *NoteHandler) load(kind, name string) (*storedNote, error) {
+	var note storedNote
+	if err := h.storage.GetJSON(notesBucket, kind+"/"+name, &note); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errNoteNotFound
+		}
+		return nil, err
+	}
+	if len(note.Versions) == 0 {
+		return nil, errNoteNotFound
+	}
+	return &note, nil
+}
+
+// List handles GET /api/notes
+// Returns the current versions of notes without their content, optionally
+// only of one kind (?kind=container)
+func (h *NoteHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	prefix := ""
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		if kind != NoteContainer && kind != NoteStack && kind != NoteVolume {
+			writeErr(w, r, errNoteKind, "")
+			return
+		}
+		prefix = kind + "/"
+	}
+
+	data, err := h.storage.ListPrefix(notesBucket, prefix)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read notes")
+		return
+	}
+	notes := make([]Note, 0, len(data))
+	for key, value := range data {
+		var stored storedNote
+		kind, name, ok := strings.Cut(key, "/")
+		if !ok || json.Unmarshal(value, &stored) != nil || len(stored.Versions) == 0 {
+			continue
+		}
+		current := stored.current()
+		current.Content = ""
+		notes = append(notes, Note{Kind: kind, Name: name, NoteVersion: current})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Kind != notes[j].Kind {
+			return notes[i].Kind < notes[j].Kind
+		}
+		return notes[i].Name < notes[j].Name
+	})
+	writeJSON(w, http.StatusOK, notes)
+}
+
+// Get handles GET /api/notes/{kind}/{name}
+func (h *NoteHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	kind, name, err := h.target(r, false)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	note, err := h.load(kind, name)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read note")
+		return
+	}
+	writeJSON(w, http.StatusOK, Note{Kind: kind, Name: name, NoteVersion: note.current()})
+}
+
+// Update handles PUT /api/notes/{kind}/{name}
+// The body {"content": "...", "version": 3} saves a new version of the
+// note. With a version, saving fails with 409 if the note was changed
+// since that version was loaded.
+func (h *NoteHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+		Version int    `json:"version"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxNoteSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Content) > maxNoteSize {
+		writeErr(w, r, errNoteTooLarge, "")
+		return
+	}
+	kind, name, err := h.target(r, true)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	note, err := h.load(kind, name)
+	switch {
+	case errors.Is(err, errNoteNotFound):
+		note = &storedNote{}
+	case err != nil:
+		writeErr(w, r, err, "Failed to read note")
+		return
+	}
+	latest := 0
+	if len(note.Versions) > 0 {
+		latest = note.current().Version
+	}
+	if req.Version != 0 && req.Version != latest {
+		writeErr(w, r, errNoteConflict, "")
+		return
+	}
+
+	version := NoteVersion{Version: latest + 1, Content: req.Content, Author: user.Username, UpdatedAt: time.Now().UTC()}
+	note.Versions = append(note.Versions, version)
+	if len(note.Versions) > maxNoteVersions {
+		note.Versions = note.Versions[len(note.Versions)-maxNoteVersions:]
+	}
+	if err := h.storage.SetJSON(notesBucket, kind+"/"+name, note); err != nil {
+		writeErr(w, r, err, "Failed to save note")
+		return
+	}
+
+	h.eventStore.Add(events.EventNoteUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("%s %s: version %d", kind, name, version.Version))
+	writeJSON(w, http.StatusOK, Note{Kind: kind, Name: name, NoteVersion: version})
+}
+
+// Delete handles DELETE /api/notes/{kind}/{name}
+// Removes the note with all its versions
+func (h *NoteHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	kind, name, err := h.target(r, false)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.storage.Delete(notesBucket, kind+"/"+name); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			err = errNoteNotFound
+		}
+		writeErr(w, r, err, "Failed to remove note")
+		return
+	}
+
+	h.eventStore.Add(events.EventNoteUpdate, user.Username, getClientIP(r), true, kind+" "+name+": removed")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+// Versions handles GET /api/notes/{kind}/{name}/versions
+// Returns the versions of a note without their content, newest first
+func (h *NoteHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	kind, name, err := h.target(r, false)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	note, err := h.load(kind, name)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read note")
+		return
+	}
+	versions := make([]NoteVersion, 0, len(note.Versions))
+	for i := len(note.Versions) - 1; i >= 0; i-- {
+		version := note.Versions[i]
+		version.Content = ""
+		versions = append(versions, version)
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// Version handles GET /api/notes/{kind}/{name}/versions/{version}
+func (h *NoteHandler) Version(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	number, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		writeErr(w, r, errNoteVersion, "")
+		return
+	}
+	kind, name, err := h.target(r, false)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	note, err := h.load(kind, name)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read note")
+		return
+	}
+	for _, version := range note.Versions {
+		if version.Version == number {
+			writeJSON(w, http.StatusOK, Note{Kind: kind, Name: name, NoteVersion: version})
+			return
+		}
+	}
+	writeErr(w, r, errNoteVersion, "")
+}
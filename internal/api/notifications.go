@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/notify"
+)
+
+// NotificationsHandler handles notification channel configuration
+type NotificationsHandler struct {
+	manager    *notify.Manager
+	eventStore *events.Store
+}
+
+// NewNotificationsHandler creates new notifications handler
+func NewNotificationsHandler(manager *notify.Manager, eventStore *events.Store) *NotificationsHandler {
+	return &NotificationsHandler{manager: manager, eventStore: eventStore}
+}
+
+// channelRequest is the body of create and update requests.
+// Omitted fields keep their current value, the type can't be changed.
+type channelRequest struct {
+	Name     *string           `json:"name"`
+	Type     string            `json:"type"`
+	Enabled  *bool             `json:"enabled"`
+	Events   *[]string         `json:"events"`
+	Settings map[string]string `json:"settings"` // merged into the current settings
+}
+
+// apply copies the fields set in the request to ch
+func (req *channelRequest) apply(ch *notify.Channel) {
+	if req.Name != nil {
+		ch.Name = *req.Name
+	}
+	if req.Enabled != nil {
+		ch.Enabled = *req.Enabled
+	}
+	if req.Events != nil {
+		ch.Events = *req.Events
+	}
+	if ch.Settings == nil {
+		ch.Settings = make(map[string]string)
+	}
+	for key, value := range req.Settings {
+		ch.Settings[key] = value
+	}
+}
+
+// channelView is a channel as returned by the API, with secrets masked
+type channelView struct {
+	notify.Channel
+	LastDelivery *notify.DeliveryStatus `json:"lastDelivery"`
+}
+
+// view converts a channel for a response
+func (h *NotificationsHandler) view(ch notify.Channel) channelView {
+	return channelView{Channel: ch.Masked(), LastDelivery: h.manager.Status(ch.ID)}
+}
+
+// authorize checks admin access and that notifications are available
+func (h *NotificationsHandler) authorize(w http.ResponseWriter, r *http.Request) (*auth.User, bool) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return nil, false
+	}
+	if h.manager == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return nil, false
+	}
+	return user, true
+}
+
+// Types handles GET /api/notifications/types
+// Returns the supported channel types with their settings and the default events.
+func (h *NotificationsHandler) Types(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"types":         notify.Types(),
+		"defaultEvents": notify.DefaultEvents,
+	})
+}
+
+// List handles GET /api/notifications/channels
+func (h *NotificationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+
+	list := h.manager.List()
+	views := make([]channelView, len(list))
+	for i, ch := range list {
+		views[i] = h.view(ch)
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// Create handles POST /api/notifications/channels
+func (h *NotificationsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	var req channelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ch := notify.Channel{Type: req.Type, Enabled: true}
+	req.apply(&ch)
+	if err := h.manager.Save(&ch); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventNotifyUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("created %s channel %s", ch.Type, ch.Name))
+	writeJSON(w, http.StatusCreated, h.view(ch))
+}
+
+// Update handles PUT /api/notifications/channels/{id}
+func (h *NotificationsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	ch, err := h.manager.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	var req channelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Type != "" && req.Type != ch.Type {
+		writeError(w, r, http.StatusBadRequest, "Channel type can't be changed")
+		return
+	}
+
+	req.apply(ch)
+	if err := h.manager.Save(ch); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventNotifyUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("updated %s channel %s", ch.Type, ch.Name))
+	writeJSON(w, http.StatusOK, h.view(*ch))
+}
+
+// Delete handles DELETE /api/notifications/channels/{id}
+func (h *NotificationsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	ch, err := h.manager.Get(chi.URLParam(r, "id"))
+	if err == nil {
+		err = h.manager.Delete(ch.ID)
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventNotifyUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("deleted %s channel %s", ch.Type, ch.Name))
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// Test handles POST /api/notifications/channels/{id}/test
+func (h *NotificationsHandler) Test(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.Test(r.Context(), chi.URLParam(r, "id"), user.Username); err != nil {
+		if errors.Is(err, notify.ErrNotFound) {
+			h.writeError(w, r, err)
+			return
+		}
+		writeError(w, r, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"sent": true})
+}
+
+// writeError writes a notification manager error
+func (h *NotificationsHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, notify.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, notify.ErrInvalid):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	default:
+		writeErr(w, r, err, "")
+	}
+}
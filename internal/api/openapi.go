@@ -0,0 +1,438 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/web/templates"
+)
+
+// routeSummaries describes the core API routes in the OpenAPI document.
+// Routes missing here are documented with the name of their handler.
+var routeSummaries = map[string]string{
+	"GET /api/health":       "Health check",
+	"GET /api/openapi.json": "OpenAPI document",
+	"GET /api/docs":         "Swagger UI",
+
+	"POST /api/auth/login":       "Log in with username and password",
+	"GET /api/auth/jwks":         "Public JWT signing keys (JWKS)",
+	"POST /api/auth/logout":      "Log out",
+	"GET /api/auth/me":           "Current user",
+	"GET /api/auth/ws-token":     "One-time token for WebSocket connections",
+	"GET /api/auth/keys":         "JWT signing keys (admin)",
+	"POST /api/auth/keys/rotate": "Rotate the JWT signing key (admin)",
+
+	"GET /api/events":          "Event log, with filters and pagination",
+	"GET /api/events/types":    "Event type catalog",
+	"GET /api/events/stream":   "Live event log and Podman events (SSE)",
+	"GET /api/events/activity": "Activity summary of each user (admin)",
+
+	"GET /api/webhooks":            "List webhooks",
+	"POST /api/webhooks":           "Create a webhook (admin)",
+	"PUT /api/webhooks/{id}":       "Update a webhook (admin)",
+	"DELETE /api/webhooks/{id}":    "Delete a webhook (admin)",
+	"POST /api/webhooks/{id}/test": "Send a test delivery (admin)",
+
+	"GET /api/notifications/types":               "Notification channel types",
+	"GET /api/notifications/channels":            "List notification channels",
+	"POST /api/notifications/channels":           "Create a notification channel (admin)",
+	"PUT /api/notifications/channels/{id}":       "Update a notification channel (admin)",
+	"DELETE /api/notifications/channels/{id}":    "Delete a notification channel (admin)",
+	"POST /api/notifications/channels/{id}/test": "Send a test notification (admin)",
+
+	"GET /api/alerts":               "Alert rule states",
+	"GET /api/alerts/rules":         "List alert rules",
+	"POST /api/alerts/rules":        "Create an alert rule (admin)",
+	"PUT /api/alerts/rules/{id}":    "Update an alert rule (admin)",
+	"DELETE /api/alerts/rules/{id}": "Delete an alert rule (admin)",
+
+	"GET /api/dashboards":            "Dashboard layouts of the user",
+	"POST /api/dashboards":           "Create a dashboard layout",
+	"GET /api/dashboards/default":    "Dashboard layout for new users",
+	"PUT /api/dashboards/default":    "Set the dashboard layout for new users (admin)",
+	"DELETE /api/dashboards/default": "Reset the dashboard layout for new users (admin)",
+	"GET /api/dashboards/{id}":       "Get a dashboard layout",
+	"PUT /api/dashboards/{id}":       "Update a dashboard layout",
+	"DELETE /api/dashboards/{id}":    "Delete a dashboard layout",
+
+	"GET /api/launcher":               "Launcher title and links (public, or with ?key=)",
+	"GET /api/launcher/settings":      "Launcher settings (admin)",
+	"PUT /api/launcher/settings":      "Update launcher settings (admin)",
+	"POST /api/launcher/settings/key": "Rotate the launcher access key (admin)",
+
+	"POST /api/containers/{id}/share": "Create a read-only share link of a container (admin)",
+	"GET /api/shares":                 "List share links (admin)",
+	"DELETE /api/shares/{id}":         "Revoke a share link (admin)",
+	"GET /api/share/{token}":          "Status and stats of a shared container (public, signed token)",
+	"GET /api/share/{token}/logs":     "Logs of a shared container (public, signed token)",
+
+	"GET /api/notes":                                  "List notes of containers, stacks and volumes",
+	"GET /api/notes/{kind}/{name}":                    "Get a note",
+	"PUT /api/notes/{kind}/{name}":                    "Save a new version of a note (admin)",
+	"DELETE /api/notes/{kind}/{name}":                 "Delete a note with its versions (admin)",
+	"GET /api/notes/{kind}/{name}/versions":           "Versions of a note",
+	"GET /api/notes/{kind}/{name}/versions/{version}": "Get a version of a note",
+
+	"GET /api/stacks/env":                                 "Environment files of stacks, secrets masked",
+	"GET /api/stacks/{stack}/env":                         "Environment file of a stack, secrets masked",
+	"PUT /api/stacks/{stack}/env":                         "Replace the environment file of a stack (admin)",
+	"DELETE /api/stacks/{stack}/env":                      "Delete the environment file of a stack (admin)",
+	"POST /api/stacks/{stack}/services/{service}/restart": "Restart the replicas of a service one at a time (admin)",
+	"POST /api/stacks/{stack}/services/{service}/scale":   "Create or remove replicas of a service (admin)",
+
+	"GET /api/system/self": "Internal metrics of PodmanView (admin)",
+	"GET /api/metrics":     "Internal metrics in the Prometheus text format (admin)",
+
+	"GET /api/history": "Search terminal command history (admin)",
+
+	"GET /api/containers":               "List containers",
+	"POST /api/containers":              "Create a container",
+	"GET /api/containers/{id}":          "Inspect a container",
+	"GET /api/containers/{id}/logs":     "Container logs",
+	"GET /api/containers/{id}/network":  "Interfaces, routes and listening sockets of a container",
+	"POST /api/containers/{id}/start":   "Start a container",
+	"POST /api/containers/{id}/stop":    "Stop a container",
+	"POST /api/containers/{id}/restart": "Restart a container",
+	"DELETE /api/containers/{id}":       "Remove a container",
+	"GET /api/containers/{id}/terminal": "Container terminal (WebSocket)",
+	"GET /api/containers/{id}/attach":   "Attach to the main process of a container (WebSocket, admin)",
+	"GET /api/terminal":                 "Host terminal (WebSocket, admin)",
+	"GET /api/terminal/policy":          "Terminal command policies (admin)",
+	"PUT /api/terminal/policy":          "Update terminal command policies (admin)",
+	"POST /api/terminal/policy/check":   "Check a command against a terminal policy (admin)",
+
+	"GET /api/containers/tags":             "Container tags with their containers",
+	"GET /api/containers/favorites":        "Favorite containers of the user",
+	"GET /api/containers/{id}/tags":        "Tags of a container and whether it is a favorite",
+	"PUT /api/containers/{id}/tags":        "Replace the tags of a container (admin)",
+	"PUT /api/containers/{id}/favorite":    "Add a container to the favorites of the user",
+	"DELETE /api/containers/{id}/favorite": "Remove a container from the favorites of the user",
+
+	"GET /api/images":         "List images",
+	"GET /api/images/{id}":    "Inspect an image",
+	"POST /api/images/pull":   "Pull an image",
+	"DELETE /api/images/{id}": "Remove an image",
+
+	"GET /api/images/{id}/layers": "Layers of an image with their sizes and build commands",
+	"POST /api/images/build":      "Build an image from a Git repository, streaming the output (admin)",
+
+	"GET /api/builds":                 "Saved builds with their last run (admin)",
+	"POST /api/builds":                "Save a build (admin)",
+	"GET /api/builds/{id}":            "Get a saved build (admin)",
+	"PUT /api/builds/{id}":            "Update a saved build (admin)",
+	"DELETE /api/builds/{id}":         "Delete a saved build and its history (admin)",
+	"POST /api/builds/{id}/run":       "Run a saved build in the background (admin)",
+	"GET /api/builds/{id}/runs":       "Build history of a saved build (admin)",
+	"GET /api/builds/{id}/runs/{run}": "A run of a saved build with its log (admin)",
+
+	"GET /api/registry":        "Configured registries",
+	"GET /api/registry/search": "Search a registry for images (admin)",
+	"GET /api/registry/tags":   "Tags of an image on a registry with digests and platforms (admin)",
+
+	"GET /api/registry/credentials":                   "Registry logins, tokens masked (admin)",
+	"PUT /api/registry/credentials/{registry}":        "Save the login of a registry, checking provider tokens (admin)",
+	"DELETE /api/registry/credentials/{registry}":     "Remove the login of a registry (admin)",
+	"POST /api/registry/credentials/{registry}/token": "Create a Gitea token for a registry (admin)",
+
+	"GET /api/networks/ipam":    "Subnets with allocated and free addresses per network",
+	"GET /api/tools/dns":        "Query DNS records from the host or a container (admin)",
+	"GET /api/tools/port":       "Check a TCP port from the host or a container (admin)",
+	"GET /api/tools/traceroute": "Trace the route to a host from the host or a container (admin)",
+
+	"GET /api/system/dashboard":              "Dashboard data",
+	"GET /api/system/info":                   "System info",
+	"GET /api/system/df":                     "Disk usage",
+	"POST /api/system/reboot":                "Reboot the host (admin)",
+	"POST /api/system/shutdown":              "Shut down the host (admin)",
+	"GET /api/system/maintenance":            "Maintenance mode status",
+	"POST /api/system/maintenance":           "Toggle read-only maintenance mode (admin)",
+	"GET /api/system/backup":                 "Download a backup of the application data (admin)",
+	"POST /api/system/backup/restore":        "Restore a backup (admin)",
+	"GET /api/system/storage":                "Storage statistics (admin)",
+	"POST /api/system/storage/maintenance":   "Run storage maintenance (admin)",
+	"GET /api/system/jobs":                   "Scheduled jobs and their maintenance windows (admin)",
+	"PUT /api/system/jobs/{job}":             "Configure a scheduled job (admin)",
+	"POST /api/system/jobs/{job}/run":        "Run a scheduled job now (admin)",
+	"GET /api/volumes/backups":               "Snapshots of named volumes (admin)",
+	"POST /api/volumes/backups/{id}/restore": "Restore a volume snapshot (admin, confirmed)",
+	"DELETE /api/volumes/backups/{id}":       "Delete a volume snapshot (admin)",
+	"GET /api/system/logs":                   "Application log entries (admin)",
+	"GET /api/system/logs/stream":            "Live tail of the application log (SSE, admin)",
+	"GET /api/system/diagnostics":            "Download a diagnostics bundle for bug reports (admin)",
+	"GET /api/system/version":                "Version",
+	"GET /api/system/update/check":           "Check for updates",
+	"GET /api/system/update/status":          "Update status",
+	"POST /api/system/update":                "Install an update (admin)",
+
+	"GET /api/config":                       "Effective configuration with value sources (admin)",
+	"GET /api/settings":                     "Editable settings (admin)",
+	"PATCH /api/settings":                   "Update settings (admin)",
+	"GET /api/config/backups":               "List config versions (admin)",
+	"POST /api/config/backups/{id}/restore": "Restore a config version (admin)",
+
+	"GET /api/files/browse":   "List a directory (admin)",
+	"GET /api/files/download": "Download a file (admin)",
+	"GET /api/files/stream":   "Stream a file with range requests (admin)",
+	"POST /api/files/upload":  "Upload files (admin)",
+	"DELETE /api/files":       "Delete a file or directory (admin)",
+	"POST /api/files/mkdir":   "Create a directory (admin)",
+	"POST /api/files/create":  "Create a file (admin)",
+	"POST /api/files/rename":  "Rename a file (admin)",
+	"GET /api/files/read":     "Read a text file (admin)",
+	"POST /api/files/write":   "Write a text file (admin)",
+
+	"GET /api/graphql":        "Run a GraphQL query (stream with Accept: text/event-stream)",
+	"POST /api/graphql":       "Run a GraphQL query or subscription",
+	"GET /api/graphql/schema": "GraphQL schema (SDL)",
+
+	"GET /api/plugins":                "List plugins",
+	"GET /api/plugins/{name}":         "Plugin details",
+	"GET /api/plugins/{name}/html":    "Plugin page HTML",
+	"POST /api/plugins/{name}/toggle": "Enable or disable a plugin (admin)",
+}
+
+// publicRoutes are served without authentication
+var publicRoutes = map[string]bool{
+	"GET /api/health":             true,
+	"POST /api/auth/login":        true,
+	"GET /api/auth/jwks":          true,
+	"GET /api/launcher":           true,
+	"GET /api/share/{token}":      true,
+	"GET /api/share/{token}/logs": true,
+}
+
+// routeQueryParams are the documented query parameters of routes
+var routeQueryParams = map[string][]string{
+	"GET /api/containers":             {"page", "limit", "sort", "status", "label", "q", "tag", "favorite"},
+	"GET /api/images":                 {"page", "limit", "sort", "status", "label", "q"},
+	"GET /api/notes":                  {"kind"},
+	"GET /api/launcher":               {"key"},
+	"GET /api/share/{token}/logs":     {"tail"},
+	"GET /api/containers/{id}/attach": {"detachKeys"},
+}
+
+// openAPIOperation is an operation of the OpenAPI document
+type openAPIOperation struct {
+	Tags        []string                   `json:"tags"`
+	Summary     string                     `json:"summary"`
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody map[string]interface{}     `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    *[]map[string][]string     `json:"security,omitempty"` // empty for public routes
+}
+
+// openAPIParameter is a path or query parameter
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+// openAPIResponse is a response of an operation
+type openAPIResponse struct {
+	Description string                 `json:"description"`
+	Content     map[string]interface{} `json:"content,omitempty"`
+}
+
+// pathParamPattern matches chi path parameters ({id}, {id:[0-9]+})
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// OpenAPI handles GET /api/openapi.json
+// Returns an OpenAPI 3 document of all registered API routes, including plugin routes.
+func (s *Server) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.openAPIDocument())
+}
+
+// APIDocs handles GET /api/docs
+// Serves Swagger UI for the OpenAPI document.
+func (s *Server) APIDocs(w http.ResponseWriter, r *http.Request) {
+	html := strings.ReplaceAll(string(templates.DocsHTML), "{{BASE_PATH}}", s.config.BasePath())
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
+}
+
+// openAPIDocument builds the OpenAPI document from the routes of the router
+func (s *Server) openAPIDocument() map[string]interface{} {
+	// Plugin routes are tagged with their plugin and described by the plugin
+	type pluginRoute struct {
+		plugin  string
+		summary string
+		auth    bool
+	}
+	pluginRoutes := make(map[string]pluginRoute)
+	for _, plugin := range s.plugins {
+		for _, route := range plugin.Routes() {
+			summary := route.Summary
+			if summary == "" {
+				summary = handlerSummary(route.Handler)
+			}
+			pluginRoutes[route.Method+" "+route.Path] = pluginRoute{plugin: plugin.Name(), summary: summary, auth: route.RequireAuth}
+		}
+	}
+
+	paths := make(map[string]map[string]openAPIOperation)
+	chi.Walk(s.router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, "/api/") || strings.HasSuffix(route, "/*") {
+			return nil // static files, the web UI and the API 404 handler
+		}
+		route = strings.TrimSuffix(route, "/")
+		key := method + " " + route
+
+		op := openAPIOperation{
+			Tags:        []string{strings.TrimSuffix(strings.SplitN(strings.TrimPrefix(route, "/api/"), "/", 2)[0], ".json")},
+			Summary:     routeSummaries[key],
+			OperationID: operationID(method, route),
+			Responses: map[string]openAPIResponse{
+				"200":     {Description: "Success"},
+				"default": {Description: "Error", Content: jsonContent("#/components/schemas/Error")},
+			},
+		}
+		public := publicRoutes[key] || s.config.NoAuth()
+		if p, ok := pluginRoutes[key]; ok {
+			op.Tags = []string{"plugin: " + p.plugin}
+			op.Summary = p.summary
+			public = !p.auth || s.config.NoAuth()
+		}
+		if op.Summary == "" {
+			op.Summary = key
+		}
+		if public {
+			op.Security = &[]map[string][]string{}
+		} else {
+			op.Responses["401"] = openAPIResponse{Description: "Not authenticated", Content: jsonContent("#/components/schemas/Error")}
+		}
+
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route, -1) {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name: match[1], In: "path", Required: true, Schema: map[string]string{"type": "string"},
+			})
+		}
+		for _, name := range routeQueryParams[key] {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name: name, In: "query", Schema: map[string]string{"type": "string"},
+			})
+		}
+		switch method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			op.RequestBody = map[string]interface{}{
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]string{"type": "object"}},
+				},
+			}
+		}
+
+		path := apiVersionPrefix + strings.TrimPrefix(pathParamPattern.ReplaceAllString(route, "{$1}"), "/api")
+		if paths[path] == nil {
+			paths[path] = make(map[string]openAPIOperation)
+		}
+		paths[path][strings.ToLower(method)] = op
+		return nil
+	})
+
+	server := s.config.BasePath()
+	if server == "" {
+		server = "/"
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "PodmanView API",
+			"description": "Podman web management. Requests are authenticated with the session cookie set by POST /api/auth/login (or a client certificate in mtls mode).",
+			"version":     s.version,
+		},
+		"servers":  []map[string]string{{"url": server}},
+		"paths":    paths,
+		"security": []map[string][]string{{"cookieAuth": {}}},
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"cookieAuth": map[string]string{"type": "apiKey", "in": "cookie", "name": auth.CookieName},
+			},
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"code", "message"},
+					"properties": map[string]interface{}{
+						"code":      map[string]string{"type": "string", "example": apierror.CodeNotFound},
+						"message":   map[string]string{"type": "string"},
+						"details":   map[string]string{"description": "Additional error data, depends on the error"},
+						"requestId": map[string]string{"type": "string", "description": "ID of the request in the access log (X-Request-Id)"},
+						"error":     map[string]interface{}{"type": "string", "deprecated": true, "description": "Same as message"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// jsonContent returns a JSON response body with the referenced schema
+func jsonContent(ref string) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": map[string]string{"$ref": ref}},
+	}
+}
+
+// operationID derives a unique operation ID from the method and path:
+// GET /api/containers/{id}/logs -> getContainersIdLogs
+func operationID(method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(route, "/api/"), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// handlerSummary describes a route by the name of its handler:
+// (*Plugin).handleGetBlocks -> "Get blocks"
+func handlerSummary(handler http.HandlerFunc) string {
+	if handler == nil {
+		return ""
+	}
+	fn := runtime.FuncForPC(reflect.ValueOf(handler).Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	name = name[strings.LastIndex(name, ".")+1:]
+	name = strings.TrimPrefix(name, "handle")
+	if name == "" || strings.HasPrefix(name, "func") {
+		return "" // anonymous function
+	}
+
+	// Split camel case words, keeping acronyms: ToggleMQTT -> Toggle MQTT,
+	// ToggleLEDs -> Toggle LEDs
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerBefore := unicode.IsLower(runes[i-1])
+		lowerAfter := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		plural := lowerAfter && runes[i+1] == 's' && (i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+		if unicode.IsUpper(runes[i]) && (lowerBefore || lowerAfter && !plural) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	for i := 1; i < len(words); i++ {
+		if w := []rune(words[i]); len(w) < 2 || !unicode.IsUpper(w[1]) {
+			words[i] = strings.ToLower(words[i]) // not an acronym
+		}
+	}
+	summary := []rune(strings.Join(words, " "))
+	summary[0] = unicode.ToUpper(summary[0])
+	return string(summary)
+}
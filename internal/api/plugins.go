@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"podmanview/internal/i18n"
 	"podmanview/internal/storage"
 )
 
@@ -47,7 +48,7 @@ func (h *PluginHandler) Get(w http.ResponseWriter, r *http.Request) {
 	pluginName := chi.URLParam(r, "name")
 
 	if h.server.plugins == nil {
-		http.Error(w, "Plugin not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Plugin not found")
 		return
 	}
 
@@ -72,7 +73,7 @@ func (h *PluginHandler) Get(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	http.Error(w, "Plugin not found", http.StatusNotFound)
+	writeError(w, r, http.StatusNotFound, "Plugin not found")
 }
 
 // GetHTML returns the HTML interface for a specific plugin
@@ -80,7 +81,7 @@ func (h *PluginHandler) GetHTML(w http.ResponseWriter, r *http.Request) {
 	pluginName := chi.URLParam(r, "name")
 
 	if h.server.plugins == nil {
-		http.Error(w, "Plugin not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Plugin not found")
 		return
 	}
 
@@ -88,23 +89,26 @@ func (h *PluginHandler) GetHTML(w http.ResponseWriter, r *http.Request) {
 		if plugin.Name() == pluginName {
 			html, err := plugin.GetHTML()
 			if err != nil {
-				http.Error(w, "Failed to get plugin HTML: "+err.Error(), http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, "Failed to get plugin HTML: "+err.Error())
 				return
 			}
 
 			if html == "" {
-				http.Error(w, "Plugin has no HTML interface", http.StatusNotFound)
+				writeError(w, r, http.StatusNotFound, "Plugin has no HTML interface")
 				return
 			}
 
+			lang := i18n.FromRequest(r)
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Content-Language", lang)
+			w.Header().Add("Vary", "Accept-Language")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(html))
+			w.Write([]byte(i18n.TranslateHTML(lang, html)))
 			return
 		}
 	}
 
-	http.Error(w, "Plugin not found", http.StatusNotFound)
+	writeError(w, r, http.StatusNotFound, "Plugin not found")
 }
 
 // Toggle enables or disables a plugin
@@ -117,13 +121,13 @@ func (h *PluginHandler) Toggle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Check if storage is available
 	if h.server.storage == nil {
-		http.Error(w, "Storage not available", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Storage not available")
 		return
 	}
 
@@ -135,7 +139,7 @@ func (h *PluginHandler) Toggle(w http.ResponseWriter, r *http.Request) {
 			Name:    pluginName,
 		}
 	} else if err != nil {
-		http.Error(w, "Failed to get plugin config: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to get plugin config: "+err.Error())
 		return
 	} else {
 		pluginConfig.Enabled = req.Enabled
@@ -143,7 +147,7 @@ func (h *PluginHandler) Toggle(w http.ResponseWriter, r *http.Request) {
 
 	// Save to storage
 	if err := h.server.storage.SetPluginConfig(pluginName, pluginConfig); err != nil {
-		http.Error(w, "Failed to save plugin config: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to save plugin config: "+err.Error())
 		return
 	}
 
@@ -158,7 +162,7 @@ func (h *PluginHandler) Toggle(w http.ResponseWriter, r *http.Request) {
 			err = h.server.pluginRegistry.DisablePlugin(ctx, pluginName)
 		}
 		if err != nil {
-			http.Error(w, "Failed to toggle plugin: "+err.Error(), http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Failed to toggle plugin: "+err.Error())
 			return
 		}
 	} else {
@@ -0,0 +1,460 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// poolCommandTimeout bounds each zpool, zfs or btrfs command
+	poolCommandTimeout = 5 * time.Second
+	// poolsCacheTTL limits how often the pool tools are run, as the
+	// dashboard polls every few seconds and alerts check disks every minute
+	poolsCacheTTL = 30 * time.Second
+	// zpoolTimeLayout is the time format of zpool status and btrfs scrub status
+	zpoolTimeLayout = "Mon Jan _2 15:04:05 2006"
+)
+
+// Pool types
+const (
+	PoolZFS   = "zfs"
+	PoolBtrfs = "btrfs"
+)
+
+// Scrub states
+const (
+	ScrubNone     = "none"
+	ScrubRunning  = "running"
+	ScrubFinished = "finished"
+	ScrubCanceled = "canceled"
+)
+
+// StoragePool is a ZFS pool or btrfs filesystem. Their space is shared by
+// datasets and subvolumes and can span several devices, so the usage of
+// a single mount point (statfs) is misleading; pools report it for the
+// whole pool instead.
+type StoragePool struct {
+	Name       string       `json:"name"`             // zpool name or btrfs device
+	Type       string       `json:"type"`             // zfs or btrfs
+	Health     string       `json:"health,omitempty"` // zfs: ONLINE, DEGRADED, FAULTED...
+	MountPoint string       `json:"mountPoint,omitempty"`
+	Total      uint64       `json:"total"` // bytes usable, after redundancy for btrfs
+	Used       uint64       `json:"used"`
+	Free       uint64       `json:"free"`
+	Devices    []PoolDevice `json:"devices"`
+	Scrub      *ScrubStatus `json:"scrub,omitempty"` // null when unknown
+	Volumes    []PoolVolume `json:"volumes"`         // datasets or mounted subvolumes
+}
+
+// PoolDevice is a device (or ZFS vdev) of a pool with its error counters
+type PoolDevice struct {
+	Name           string `json:"name"`
+	State          string `json:"state,omitempty"` // zfs only
+	ReadErrors     uint64 `json:"readErrors"`
+	WriteErrors    uint64 `json:"writeErrors"`
+	ChecksumErrors uint64 `json:"checksumErrors"` // checksum, corruption and generation errors
+}
+
+// ScrubStatus is the state of the last or current scrub of a pool
+type ScrubStatus struct {
+	State    string     `json:"state"`              // none, running, finished or canceled
+	Progress float64    `json:"progress,omitempty"` // percent, zfs while running
+	Errors   uint64     `json:"errors"`
+	Time     *time.Time `json:"time,omitempty"` // when it started (running) or ended
+}
+
+// PoolVolume is a ZFS dataset or a mounted btrfs subvolume
+type PoolVolume struct {
+	Name       string `json:"name"`
+	MountPoint string `json:"mountPoint,omitempty"`
+	Used       uint64 `json:"used,omitempty"` // zfs only
+	Available  uint64 `json:"available,omitempty"`
+}
+
+// poolsCache remembers the pools between dashboard polls
+var poolsCache struct {
+	sync.Mutex
+	pools  []StoragePool
+	readAt time.Time
+}
+
+// getStoragePools returns the ZFS pools and btrfs filesystems of the host,
+// cached for poolsCacheTTL
+func getStoragePools() []StoragePool {
+	c := &poolsCache
+	c.Lock()
+	defer c.Unlock()
+	if !c.readAt.IsZero() && time.Since(c.readAt) < poolsCacheTTL {
+		return c.pools
+	}
+	pools := append(getZFSPools(), getBtrfsPools()...)
+	c.pools, c.readAt = pools, time.Now()
+	return pools
+}
+
+// poolCommand runs a pool tool and returns its output, or "" and false if
+// the tool is missing or fails
+func poolCommand(name string, args ...string) (string, bool) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), poolCommandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
+
+// getZFSPools returns the ZFS pools with their datasets and status
+func getZFSPools() []StoragePool {
+	out, ok := poolCommand("zpool", "list", "-Hp", "-o", "name,size,alloc,free,health")
+	if !ok {
+		return nil
+	}
+	pools := ParseZpoolList(out)
+	var volumes []PoolVolume
+	if out, ok := poolCommand("zfs", "list", "-Hp", "-t", "filesystem", "-o", "name,used,avail,mountpoint"); ok {
+		volumes = ParseZFSList(out)
+	}
+	for i := range pools {
+		pool := &pools[i]
+		if out, ok := poolCommand("zpool", "status", "-p", pool.Name); ok {
+			ParseZpoolStatus(out, pool)
+		}
+		for _, v := range volumes {
+			if v.Name == pool.Name || strings.HasPrefix(v.Name, pool.Name+"/") {
+				pool.Volumes = append(pool.Volumes, v)
+				if v.Name == pool.Name {
+					pool.MountPoint = v.MountPoint
+				}
+			}
+		}
+	}
+	return pools
+}
+
+// ParseZpoolList parses "zpool list -Hp -o name,size,alloc,free,health"
+func ParseZpoolList(out string) []StoragePool {
+	pools := []StoragePool{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 {
+			continue
+		}
+		size, _ := strconv.ParseUint(fields[1], 10, 64)
+		alloc, _ := strconv.ParseUint(fields[2], 10, 64)
+		free, _ := strconv.ParseUint(fields[3], 10, 64)
+		pools = append(pools, StoragePool{
+			Name:    fields[0],
+			Type:    PoolZFS,
+			Health:  fields[4],
+			Total:   size,
+			Used:    alloc,
+			Free:    free,
+			Devices: []PoolDevice{},
+			Volumes: []PoolVolume{},
+		})
+	}
+	return pools
+}
+
+// ParseZFSList parses "zfs list -Hp -o name,used,avail,mountpoint"
+func ParseZFSList(out string) []PoolVolume {
+	volumes := []PoolVolume{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			continue
+		}
+		used, _ := strconv.ParseUint(fields[1], 10, 64)
+		avail, _ := strconv.ParseUint(fields[2], 10, 64)
+		volume := PoolVolume{Name: fields[0], Used: used, Available: avail}
+		if strings.HasPrefix(fields[3], "/") {
+			volume.MountPoint = fields[3] // not "none" or "legacy"
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// ParseZpoolStatus adds the scrub status and the devices with their error
+// counters from "zpool status -p" to pool
+func ParseZpoolStatus(out string, pool *StoragePool) {
+	lines := strings.Split(out, "\n")
+	inConfig := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "scan:"):
+			pool.Scrub = parseZFSScan(strings.TrimSpace(strings.TrimPrefix(trimmed, "scan:")), lines[i+1:])
+		case strings.HasPrefix(trimmed, "NAME") && strings.Contains(trimmed, "CKSUM"):
+			inConfig = true
+		case inConfig && trimmed == "":
+			inConfig = false
+		case inConfig:
+			fields := strings.Fields(trimmed)
+			if len(fields) < 5 || fields[0] == pool.Name {
+				continue
+			}
+			device := PoolDevice{Name: fields[0], State: fields[1]}
+			device.ReadErrors, _ = strconv.ParseUint(fields[2], 10, 64)
+			device.WriteErrors, _ = strconv.ParseUint(fields[3], 10, 64)
+			device.ChecksumErrors, _ = strconv.ParseUint(fields[4], 10, 64)
+			pool.Devices = append(pool.Devices, device)
+		}
+	}
+}
+
+// parseZFSScan parses the scan line of zpool status and, for a running
+// scrub, the progress in the following lines. Returns nil for resilvers.
+func parseZFSScan(scan string, next []string) *ScrubStatus {
+	switch {
+	case scan == "none requested":
+		return &ScrubStatus{State: ScrubNone}
+	case strings.HasPrefix(scan, "scrub repaired"):
+		// scrub repaired 0B in 00:00:01 with 0 errors on Sun Oct 13 00:24:02 2024
+		status := &ScrubStatus{State: ScrubFinished}
+		if _, rest, ok := strings.Cut(scan, " with "); ok {
+			count, _, _ := strings.Cut(rest, " ")
+			status.Errors, _ = strconv.ParseUint(count, 10, 64)
+		}
+		status.Time = parseScanTime(scan, " on ")
+		return status
+	case strings.HasPrefix(scan, "scrub in progress"):
+		status := &ScrubStatus{State: ScrubRunning, Time: parseScanTime(scan, " since ")}
+		for _, line := range next {
+			if before, _, ok := strings.Cut(line, "% done"); ok {
+				fields := strings.Fields(before)
+				if len(fields) > 0 {
+					status.Progress, _ = strconv.ParseFloat(fields[len(fields)-1], 64)
+				}
+				break
+			}
+		}
+		return status
+	case strings.HasPrefix(scan, "scrub canceled"):
+		return &ScrubStatus{State: ScrubCanceled, Time: parseScanTime(scan, " on ")}
+	}
+	return nil
+}
+
+// parseScanTime parses the time after sep at the end of a scan line
+func parseScanTime(scan, sep string) *time.Time {
+	i := strings.LastIndex(scan, sep)
+	if i < 0 {
+		return nil
+	}
+	t, err := time.ParseInLocation(zpoolTimeLayout, strings.TrimSpace(scan[i+len(sep):]), time.Local)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// getBtrfsPools returns the mounted btrfs filesystems, one per device,
+// with their mounted subvolumes
+func getBtrfsPools() []StoragePool {
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var pools []StoragePool
+	index := make(map[string]int) // device -> pools index
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] != PoolBtrfs {
+			continue
+		}
+		device, mountPoint := fields[0], fields[1]
+		subvol := "/"
+		for _, option := range strings.Split(fields[3], ",") {
+			if v, ok := strings.CutPrefix(option, "subvol="); ok {
+				subvol = v
+			}
+		}
+
+		i, ok := index[device]
+		if !ok {
+			i = len(pools)
+			index[device] = i
+			pools = append(pools, StoragePool{
+				Name:       strings.TrimPrefix(device, "/dev/"),
+				Type:       PoolBtrfs,
+				MountPoint: mountPoint,
+				Devices:    []PoolDevice{},
+				Volumes:    []PoolVolume{},
+			})
+		}
+		pools[i].Volumes = append(pools[i].Volumes, PoolVolume{Name: subvol, MountPoint: mountPoint})
+	}
+
+	for i := range pools {
+		pool := &pools[i]
+		if out, ok := poolCommand("btrfs", "filesystem", "usage", "-b", pool.MountPoint); ok {
+			pool.Total, pool.Used, pool.Free = ParseBtrfsUsage(out)
+		} else {
+			// Without the btrfs tool, statfs of one mount point is the best guess
+			pool.Total, pool.Free = getDiskUsage(pool.MountPoint)
+			pool.Used = pool.Total - pool.Free
+		}
+		if out, ok := poolCommand("btrfs", "device", "stats", pool.MountPoint); ok {
+			pool.Devices = ParseBtrfsDeviceStats(out)
+		}
+		if out, ok := poolCommand("btrfs", "scrub", "status", pool.MountPoint); ok {
+			pool.Scrub = ParseBtrfsScrubStatus(out)
+		}
+	}
+	return pools
+}
+
+// ParseBtrfsUsage parses the overall usage of "btrfs filesystem usage -b"
+// and returns the usable size, used and free space in bytes. Raw device
+// sizes are divided by the data ratio (2 for RAID1).
+func ParseBtrfsUsage(out string) (total, used, free uint64) {
+	values := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) > 0 {
+			values[strings.TrimSpace(key)] = fields[0]
+		}
+	}
+	ratio, err := strconv.ParseFloat(values["Data ratio"], 64)
+	if err != nil || ratio <= 0 {
+		ratio = 1
+	}
+	size, _ := strconv.ParseUint(values["Device size"], 10, 64)
+	rawUsed, _ := strconv.ParseUint(values["Used"], 10, 64)
+	free, _ = strconv.ParseUint(values["Free (estimated)"], 10, 64)
+	return uint64(float64(size) / ratio), uint64(float64(rawUsed) / ratio), free
+}
+
+// ParseBtrfsDeviceStats parses "btrfs device stats": lines like
+// "[/dev/sda].write_io_errs 0", several per device
+func ParseBtrfsDeviceStats(out string) []PoolDevice {
+	devices := []PoolDevice{}
+	index := make(map[string]int)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[0], "[") {
+			continue
+		}
+		name, counter, ok := strings.Cut(strings.TrimPrefix(fields[0], "["), "].")
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(devices)
+			index[name] = i
+			devices = append(devices, PoolDevice{Name: strings.TrimPrefix(name, "/dev/")})
+		}
+		switch counter {
+		case "read_io_errs":
+			devices[i].ReadErrors += value
+		case "write_io_errs", "flush_io_errs":
+			devices[i].WriteErrors += value
+		case "corruption_errs", "generation_errs":
+			devices[i].ChecksumErrors += value
+		}
+	}
+	return devices
+}
+
+// ParseBtrfsScrubStatus parses "btrfs scrub status"
+func ParseBtrfsScrubStatus(out string) *ScrubStatus {
+	if strings.Contains(out, "no stats available") {
+		return &ScrubStatus{State: ScrubNone}
+	}
+	status := &ScrubStatus{}
+	var started *time.Time
+	var duration time.Duration
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Scrub started":
+			if t, err := time.ParseInLocation(zpoolTimeLayout, value, time.Local); err == nil {
+				started = &t
+			}
+		case "Status":
+			switch value {
+			case "running":
+				status.State = ScrubRunning
+			case "finished":
+				status.State = ScrubFinished
+			case "aborted", "interrupted", "canceled":
+				status.State = ScrubCanceled
+			}
+		case "Duration":
+			duration = parseClockDuration(value)
+		case "Error summary":
+			// "no errors found" or e.g. "csum=2 verify=1"
+			for _, field := range strings.Fields(value) {
+				if _, count, ok := strings.Cut(field, "="); ok {
+					n, _ := strconv.ParseUint(count, 10, 64)
+					status.Errors += n
+				}
+			}
+		}
+	}
+	if status.State == "" {
+		return nil
+	}
+	if started != nil {
+		t := *started
+		if status.State != ScrubRunning {
+			t = t.Add(duration)
+		}
+		status.Time = &t
+	}
+	return status
+}
+
+// parseClockDuration parses a h:mm:ss duration, 0 if it is invalid
+func parseClockDuration(value string) time.Duration {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0
+		}
+		d += time.Duration(n) * unit
+	}
+	return d
+}
+
+// poolDisks returns the pools as disks for the disk usage alerts
+func poolDisks() []DiskInfo {
+	var disks []DiskInfo
+	for _, pool := range getStoragePools() {
+		disks = append(disks, DiskInfo{Device: pool.Name, MountPoint: pool.MountPoint, Total: pool.Total, Free: pool.Free, Used: pool.Used})
+	}
+	return disks
+}
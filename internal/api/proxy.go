@@ -0,0 +1,166 @@
+package api
+
+import (
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/podman"
+)
+
+// proxySandbox is the Content-Security-Policy of proxied responses. Pages of
+// container apps get an opaque origin, so their scripts can't call the
+// PodmanView API with the session of the user.
+const proxySandbox = "sandbox allow-scripts allow-forms allow-popups allow-modals allow-downloads"
+
+// ProxyHandler forwards HTTP traffic to ports of containers, so web UIs
+// without published ports can be reached through PodmanView
+type ProxyHandler struct {
+	client *podman.Client
+	config *config.Config
+}
+
+// NewProxyHandler creates new container proxy handler
+func NewProxyHandler(client *podman.Client, cfg *config.Config) *ProxyHandler {
+	return &ProxyHandler{client: client, config: cfg}
+}
+
+// Redirect handles /proxy/{container}/{port}
+// Redirects to the trailing slash so relative links of the app resolve
+// below the proxy path.
+func (h *ProxyHandler) Redirect(w http.ResponseWriter, r *http.Request) {
+	target := h.config.BasePath() + r.URL.Path + "/"
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
+
+// Serve handles /proxy/{container}/{port}/*
+// Forwards the request, including WebSocket upgrades, to the port of the
+// container. The PodmanView session is not passed on.
+func (h *ProxyHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	name := chi.URLParam(r, "container")
+	port, err := strconv.Atoi(chi.URLParam(r, "port"))
+	if err != nil || port < 1 || port > 65535 {
+		writeError(w, r, http.StatusBadRequest, "Invalid port")
+		return
+	}
+
+	info, err := h.client.InspectContainer(r.Context(), name)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if !info.State.Running {
+		writeError(w, r, http.StatusConflict, "Container is not running")
+		return
+	}
+	target := proxyTarget(info, port)
+	if target == "" {
+		writeError(w, r, http.StatusBadGateway, "Container has no reachable address")
+		return
+	}
+
+	// Path of the proxy as seen by the browser
+	prefix := h.config.BasePath() + "/proxy/" + name + "/" + strconv.Itoa(port)
+	rest := "/" + chi.URLParam(r, "*")
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = "http"
+			pr.Out.URL.Host = target
+			pr.Out.Host = target
+			// The wildcard is escaped if the request path is
+			if r.URL.RawPath != "" {
+				pr.Out.URL.RawPath = rest
+				pr.Out.URL.Path, _ = url.PathUnescape(rest)
+			} else {
+				pr.Out.URL.Path, pr.Out.URL.RawPath = rest, ""
+			}
+			pr.SetXForwarded()
+			pr.Out.Header.Set("X-Forwarded-Prefix", prefix)
+
+			pr.Out.Header.Del("Authorization")
+			cookies := pr.Out.Cookies()
+			pr.Out.Header.Del("Cookie")
+			for _, c := range cookies {
+				if c.Name != auth.CookieName {
+					pr.Out.AddCookie(c)
+				}
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// Keep redirects of the app below the proxy path
+			if location := resp.Header.Get("Location"); location != "" {
+				if u, err := url.Parse(location); err == nil {
+					switch {
+					case u.Host == target:
+						resp.Header.Set("Location", prefix+u.RequestURI())
+					case u.Host == "" && strings.HasPrefix(u.Path, "/"):
+						resp.Header.Set("Location", prefix+location)
+					}
+				}
+			}
+
+			// Apps can't replace the PodmanView session
+			setCookies := resp.Header.Values("Set-Cookie")
+			resp.Header.Del("Set-Cookie")
+			for _, line := range setCookies {
+				if c, err := http.ParseSetCookie(line); err == nil && c.Name == auth.CookieName {
+					continue
+				}
+				resp.Header.Add("Set-Cookie", line)
+			}
+
+			resp.Header.Add("Content-Security-Policy", proxySandbox)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			writeErr(w, r, apierror.Wrap(err, http.StatusBadGateway, "Failed to reach container: "+err.Error()), "")
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// proxyTarget returns the address the port of a running container is
+// reached at: the published host port, which also works for rootless
+// containers, the host network, or the container IP
+func proxyTarget(info *podman.ContainerInspect, port int) string {
+	for _, binding := range info.HostConfig.PortBindings[strconv.Itoa(port)+"/tcp"] {
+		if binding.HostPort == "" {
+			continue
+		}
+		host := binding.HostIP
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "127.0.0.1"
+		}
+		return net.JoinHostPort(host, binding.HostPort)
+	}
+
+	if info.HostConfig.NetworkMode == "host" {
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	}
+	for _, network := range slices.Sorted(maps.Keys(info.NetworkSettings.Networks)) {
+		if ip := info.NetworkSettings.Networks[network].IPAddress; ip != "" {
+			return net.JoinHostPort(ip, strconv.Itoa(port))
+		}
+	}
+	return ""
+}
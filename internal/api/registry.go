@@ -0,0 +1,272 @@
+package api
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/registry"
+	"podmanview/internal/storage"
+)
+
+const (
+	// defaultRegistryLimit and maxRegistryLimit bound the search results
+	// and the tags inspected per request
+	defaultRegistryLimit = 25
+	maxRegistryLimit     = 100
+
+	// maxConcurrentInspects limits the manifests read at once per request
+	maxConcurrentInspects = 4
+)
+
+// RegistryHandler searches the configured registries and lists the tags of
+// their images, for the pull dialog, and keeps the registry credentials
+type RegistryHandler struct {
+	client     *podman.Client
+	config     *config.Config
+	registries *registry.Client
+	storage    storage.Storage
+	eventStore *events.Store
+}
+
+// NewRegistryHandler creates a registry handler for the registries of cfg.
+// The stored credentials sign in to the registries.
+func NewRegistryHandler(client *podman.Client, cfg *config.Config, store storage.Storage, eventStore *events.Store) *RegistryHandler {
+	h := &RegistryHandler{client: client, config: cfg, registries: registry.NewClient(cfg.Registries()), storage: store, eventStore: eventStore}
+	h.registries.SetCredentials(h.login)
+	return h
+}
+
+// RegistrySearchResult is an image found by Search
+type RegistrySearchResult struct {
+	Name        string `json:"name"` // full name, e.g. docker.io/library/nginx
+	Description string `json:"description"`
+	Stars       int    `json:"stars"`
+	Official    bool   `json:"official"`
+}
+
+// RegistryTag is a tag of an image with its digest and platforms
+type RegistryTag struct {
+	Name      string   `json:"name"`
+	Digest    string   `json:"digest,omitempty"`
+	Platforms []string `json:"platforms,omitempty"` // os/architecture[/variant]
+	Error     string   `json:"error,omitempty"`     // the manifest could not be read
+}
+
+// List handles GET /api/registry
+// Returns the names of the configured registries, the first is the default.
+func (h *RegistryHandler) List(w http.ResponseWriter, r *http.Request) {
+	names := []string{}
+	for _, entry := range h.config.Registries() {
+		name, _ := registry.Endpoint(entry)
+		names = append(names, name)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"registries": names})
+}
+
+// Search handles GET /api/registry/search?q=nginx&registry=quay.io&limit=25
+// Searches a configured registry through Podman, the first by default.
+func (h *RegistryHandler) Search(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, r, http.StatusBadRequest, "Search term is required")
+		return
+	}
+	name, endpoint, ok := h.registry(r.URL.Query().Get("registry"))
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, "Registry is not configured")
+		return
+	}
+	limit, ok := registryLimit(r)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	found, err := h.client.SearchImages(r.Context(), name+"/"+query, limit, !strings.HasPrefix(endpoint, "http://"))
+	if err != nil {
+		writeErr(w, r, err, "Failed to search registry")
+		return
+	}
+	results := make([]RegistrySearchResult, 0, len(found))
+	for _, image := range found {
+		results = append(results, RegistrySearchResult{
+			Name:        image.Name,
+			Description: image.Description,
+			Stars:       image.Stars,
+			Official:    image.Official != "",
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"registry": name, "results": results})
+}
+
+// Tags handles GET /api/registry/tags?image=quay.io/prometheus/node-exporter&limit=25
+// Lists the tags of an image on a configured registry, newest versions
+// first, with the digest and platforms of the first limit tags.
+func (h *RegistryHandler) Tags(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	image := strings.TrimSpace(r.URL.Query().Get("image"))
+	if image == "" {
+		writeError(w, r, http.StatusBadRequest, "Image is required")
+		return
+	}
+	limit, ok := registryLimit(r)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	ref := registry.ParseReference(image)
+	ref.Tag = ""
+
+	names, err := h.registries.Tags(r.Context(), ref)
+	if err != nil {
+		writeRegistryErr(w, r, err)
+		return
+	}
+	slices.SortFunc(names, compareTags)
+	total := len(names)
+	names = names[:min(limit, total)]
+
+	tags := make([]RegistryTag, len(names))
+	slots := make(chan struct{}, maxConcurrentInspects)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			tag := ref
+			tag.Tag = name
+			tags[i] = RegistryTag{Name: name}
+			manifest, err := h.registries.Inspect(r.Context(), tag)
+			if err != nil {
+				tags[i].Error = err.Error()
+				return
+			}
+			tags[i].Digest, tags[i].Platforms = manifest.Digest, manifest.Platforms
+		}()
+	}
+	wg.Wait()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"image": ref.String(), "total": total, "tags": tags})
+}
+
+// registry returns the name and endpoint of a configured registry, the
+// first one if name is empty
+func (h *RegistryHandler) registry(name string) (string, string, bool) {
+	for _, entry := range h.config.Registries() {
+		registryName, endpoint := registry.Endpoint(entry)
+		if name == "" || name == registryName {
+			return registryName, endpoint, true
+		}
+	}
+	return "", "", false
+}
+
+// registryLimit parses the limit query parameter
+func registryLimit(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultRegistryLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxRegistryLimit {
+		return 0, false
+	}
+	return limit, true
+}
+
+// writeRegistryErr writes the response of a registry error
+func writeRegistryErr(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, registry.ErrNotConfigured):
+		writeError(w, r, http.StatusBadRequest, "Registry is not configured")
+	case errors.Is(err, registry.ErrNotFound), errors.Is(err, registry.ErrUnauthorized):
+		// Docker Hub answers unauthorized for repositories that don't exist
+		writeError(w, r, http.StatusNotFound, "Image not found or private")
+	default:
+		writeErr(w, r, err, "Failed to read registry")
+	}
+}
+
+// compareTags orders tags for browsing: latest first, then versions
+// newest first (1.10 before 1.9), then other tags
+func compareTags(a, b string) int {
+	if a == "latest" || b == "latest" {
+		return cmp.Compare(boolInt(b == "latest"), boolInt(a == "latest"))
+	}
+	aVersion, bVersion := isVersionTag(a), isVersionTag(b)
+	if aVersion != bVersion {
+		return cmp.Compare(boolInt(bVersion), boolInt(aVersion))
+	}
+	if aVersion {
+		a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	}
+	return -naturalCompare(a, b)
+}
+
+// isVersionTag reports whether a tag starts with a version number (1.2,
+// v1.2-alpine)
+func isVersionTag(tag string) bool {
+	tag = strings.TrimPrefix(tag, "v")
+	return tag != "" && unicode.IsDigit(rune(tag[0]))
+}
+
+// naturalCompare compares strings with runs of digits compared as numbers
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits != "" && bDigits != "" {
+			aNum, bNum := strings.TrimLeft(aDigits, "0"), strings.TrimLeft(bDigits, "0")
+			if c := cmp.Or(cmp.Compare(len(aNum), len(bNum)), strings.Compare(aNum, bNum)); c != 0 {
+				return c
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// leadingDigits returns the digits at the start of s
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// boolInt is 1 for true
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
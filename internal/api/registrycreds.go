@@ -0,0 +1,432 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/notify"
+	"podmanview/internal/registry"
+	"podmanview/internal/storage"
+)
+
+const (
+	// registryBucket stores the registry credentials by registry name; it
+	// is encrypted at rest (storage.DefaultSensitiveBuckets)
+	registryBucket = "registry"
+
+	// tokenExpiryWarning is how long before a token expires it is warned of
+	tokenExpiryWarning = 7 * 24 * time.Hour
+
+	// tokenCheckInterval is how often token expiry is checked
+	tokenCheckInterval = time.Hour
+)
+
+// Expiry warning states of a credential
+const (
+	tokenWarnedExpiring = "expiring"
+	tokenWarnedExpired  = "expired"
+)
+
+// registryNameRe matches registry names: a host with an optional port
+var registryNameRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]{1,5})?$`)
+
+var (
+	errCredentialNotFound = apierror.New(http.StatusNotFound, "Registry credential not found")
+	errRegistryName       = apierror.New(http.StatusBadRequest, "Invalid registry name")
+	errCredentialProvider = apierror.New(http.StatusBadRequest, "Provider must be generic, github or gitea")
+	errCredentialAPIURL   = apierror.New(http.StatusBadRequest, "API URL must be an http:// or https:// URL")
+	errCredentialLogin    = apierror.New(http.StatusBadRequest, "Username and token are required")
+	errGiteaLogin         = apierror.New(http.StatusBadRequest, "Username and password of the Gitea account are required")
+)
+
+// RegistryCredential is the login of a registry, used to pull its images
+// and to browse its tags. The token is only returned as notify.MaskedValue.
+type RegistryCredential struct {
+	Registry  string     `json:"registry"`         // e.g. ghcr.io or localhost:5000
+	Provider  string     `json:"provider"`         // generic, github or gitea
+	APIURL    string     `json:"apiUrl,omitempty"` // API of the provider, its default if empty
+	Username  string     `json:"username"`
+	Token     string     `json:"token"`               // token or password
+	TokenName string     `json:"tokenName,omitempty"` // name of a token created on Gitea, to replace it
+	Scopes    []string   `json:"scopes,omitempty"`    // as reported by GitHub
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // reported by GitHub or set for other tokens
+	CheckedAt *time.Time `json:"checkedAt,omitempty"` // last check with the provider
+	Warned    string     `json:"warned,omitempty"`    // expiry warning raised: expiring or expired
+	UpdatedAt time.Time  `json:"updatedAt"`
+
+	// NewTokenURL is the page where tokens for the registry are created,
+	// set in responses
+	NewTokenURL string `json:"newTokenUrl,omitempty"`
+}
+
+// api returns the API URL of the provider of the credential
+func (c *RegistryCredential) api() string {
+	if c.APIURL != "" {
+		return c.APIURL
+	}
+	return registry.ProviderAPI(c.Provider, c.Registry)
+}
+
+// masked returns the credential for responses
+func (c RegistryCredential) masked() RegistryCredential {
+	if c.Token != "" {
+		c.Token = notify.MaskedValue
+	}
+	c.NewTokenURL = registry.NewTokenURL(c.Provider, c.api())
+	return c
+}
+
+// check asks the provider about the token and records its account, scopes
+// and expiry. Tokens of generic registries keep the expiry set by users.
+func (c *RegistryCredential) check(ctx context.Context) error {
+	if c.Provider == registry.ProviderGeneric {
+		return nil
+	}
+	info, err := registry.CheckToken(ctx, c.Provider, c.api(), c.Token)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	c.CheckedAt = &now
+	c.Scopes = info.Scopes
+	if c.Username == "" {
+		c.Username = info.Login
+	}
+	if c.Provider == registry.ProviderGitHub {
+		c.ExpiresAt = info.Expires
+	}
+	return nil
+}
+
+// credential returns the stored credential of a registry
+func (h *RegistryHandler) credential(name string) (*RegistryCredential, error) {
+	var cred RegistryCredential
+	if err := h.storage.GetJSON(registryBucket, name, &cred); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errCredentialNotFound
+		}
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// credentials returns the stored credentials by registry name
+func (h *RegistryHandler) credentials() ([]RegistryCredential, error) {
+	data, err := h.storage.List(registryBucket)
+	if err != nil {
+		return nil, err
+	}
+	creds := make([]RegistryCredential, 0, len(data))
+	for _, value := range data {
+		var cred RegistryCredential
+		if json.Unmarshal(value, &cred) == nil {
+			creds = append(creds, cred)
+		}
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Registry < creds[j].Registry })
+	return creds, nil
+}
+
+// login returns the stored login of a registry, for the registry client
+func (h *RegistryHandler) login(name string) (username, password string, ok bool) {
+	if h.storage == nil {
+		return "", "", false
+	}
+	cred, err := h.credential(name)
+	if err != nil || cred.Token == "" {
+		return "", "", false
+	}
+	return cred.Username, cred.Token, true
+}
+
+// pullLogin returns the stored login of the registry of an image
+// reference, for Podman pulls
+func (h *RegistryHandler) pullLogin(reference string) (username, password string, ok bool) {
+	return h.login(registry.ParseReference(reference).Registry)
+}
+
+// credentialsAvailable checks for an admin user and storage
+func (h *RegistryHandler) credentialsAvailable(w http.ResponseWriter, r *http.Request) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return false
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return false
+	}
+	return true
+}
+
+// ListCredentials handles GET /api/registry/credentials
+// Returns the registry credentials with their tokens masked
+func (h *RegistryHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	if !h.credentialsAvailable(w, r) {
+		return
+	}
+	creds, err := h.credentials()
+	if err != nil {
+		writeErr(w, r, err, "Failed to read registry credentials")
+		return
+	}
+	for i := range creds {
+		creds[i] = creds[i].masked()
+	}
+	writeJSON(w, http.StatusOK, creds)
+}
+
+// SetCredential handles PUT /api/registry/credentials/{registry}
+// Saves the login of a registry. Tokens of GitHub and Gitea are checked
+// with the provider first; a token of notify.MaskedValue or empty keeps
+// the stored one.
+func (h *RegistryHandler) SetCredential(w http.ResponseWriter, r *http.Request) {
+	if !h.credentialsAvailable(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	name := chi.URLParam(r, "registry")
+	if !registryNameRe.MatchString(name) {
+		writeErr(w, r, errRegistryName, "")
+		return
+	}
+
+	var cred RegistryCredential
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&cred); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	cred.Registry = name
+	cred.TokenName, cred.Scopes, cred.CheckedAt, cred.NewTokenURL = "", nil, nil, ""
+	cred.Username = strings.TrimSpace(cred.Username)
+	cred.Token = strings.TrimSpace(cred.Token)
+	if cred.Provider == "" {
+		cred.Provider = registry.DetectProvider(name)
+	}
+	switch cred.Provider {
+	case registry.ProviderGeneric, registry.ProviderGitHub, registry.ProviderGitea:
+	default:
+		writeErr(w, r, errCredentialProvider, "")
+		return
+	}
+	if cred.APIURL != "" {
+		if u, err := url.Parse(cred.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeErr(w, r, errCredentialAPIURL, "")
+			return
+		}
+	}
+
+	existing, err := h.credential(name)
+	if err != nil && !errors.Is(err, errCredentialNotFound) {
+		writeErr(w, r, err, "Failed to read registry credentials")
+		return
+	}
+	// A token of the user replaces the one created on Gitea
+	if cred.Token == "" || cred.Token == notify.MaskedValue {
+		cred.Token = ""
+		if existing != nil {
+			cred.Token, cred.TokenName = existing.Token, existing.TokenName
+		}
+	}
+	if cred.Token == "" || (cred.Username == "" && cred.Provider == registry.ProviderGeneric) {
+		writeErr(w, r, errCredentialLogin, "")
+		return
+	}
+
+	if err := cred.check(r.Context()); err != nil {
+		h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), false, name+": "+err.Error())
+		writeError(w, r, http.StatusBadRequest, "Token check failed: "+err.Error())
+		return
+	}
+	cred.Warned = ""
+	cred.UpdatedAt = time.Now()
+	if err := h.storage.SetJSON(registryBucket, name, cred); err != nil {
+		h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), false, name)
+		writeErr(w, r, err, "Failed to save registry credential")
+		return
+	}
+	h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), true, name)
+	writeJSON(w, http.StatusOK, cred.masked())
+}
+
+// DeleteCredential handles DELETE /api/registry/credentials/{registry}
+// Tokens created on Gitea are not revoked, as that needs the password.
+func (h *RegistryHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	if !h.credentialsAvailable(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	name := chi.URLParam(r, "registry")
+	if _, err := h.credential(name); err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if err := h.storage.Delete(registryBucket, name); err != nil {
+		h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), false, "delete "+name)
+		writeErr(w, r, err, "Failed to delete registry credential")
+		return
+	}
+	h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), true, "delete "+name)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted", "registry": name})
+}
+
+// GiteaTokenRequest signs in to Gitea to create a token
+type GiteaTokenRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"` // not stored
+	APIURL   string `json:"apiUrl,omitempty"`
+}
+
+// CreateToken handles POST /api/registry/credentials/{registry}/token
+// Creates a new token on Gitea with the password of the account and saves
+// it; the token PodmanView created before is deleted. GitHub tokens can't
+// be created through its API, the error links to the page that creates one.
+func (h *RegistryHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	if !h.credentialsAvailable(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	name := chi.URLParam(r, "registry")
+	if !registryNameRe.MatchString(name) {
+		writeErr(w, r, errRegistryName, "")
+		return
+	}
+
+	var req GiteaTokenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	cred, err := h.credential(name)
+	if errors.Is(err, errCredentialNotFound) {
+		cred, err = &RegistryCredential{Registry: name, Provider: registry.DetectProvider(name)}, nil
+		if cred.Provider == registry.ProviderGeneric {
+			cred.Provider = registry.ProviderGitea // self-hosted
+		}
+	}
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if cred.Provider != registry.ProviderGitea {
+		message := "Tokens of this registry can't be created through its API"
+		if link := registry.NewTokenURL(cred.Provider, cred.api()); link != "" {
+			message += ": " + link
+		}
+		writeError(w, r, http.StatusBadRequest, message)
+		return
+	}
+	if req.APIURL != "" {
+		if u, err := url.Parse(req.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeErr(w, r, errCredentialAPIURL, "")
+			return
+		}
+		cred.APIURL = req.APIURL
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || req.Password == "" {
+		writeErr(w, r, errGiteaLogin, "")
+		return
+	}
+
+	suffix, err := randomHex(4)
+	if err != nil {
+		writeErr(w, r, err, "Failed to create token")
+		return
+	}
+	tokenName := "podmanview-" + suffix
+	token, err := registry.CreateGiteaToken(r.Context(), cred.api(), req.Username, req.Password, tokenName)
+	if err != nil {
+		h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), false, name+": "+err.Error())
+		writeError(w, r, http.StatusBadRequest, "Token creation failed: "+err.Error())
+		return
+	}
+	previous := cred.TokenName
+	now := time.Now()
+	cred.Username, cred.Token, cred.TokenName = req.Username, token, tokenName
+	cred.Scopes, cred.ExpiresAt, cred.CheckedAt, cred.Warned = registry.GiteaScopes, nil, &now, ""
+	cred.UpdatedAt = now
+	if err := h.storage.SetJSON(registryBucket, name, cred); err != nil {
+		// Don't leave a token behind that nobody knows
+		registry.DeleteGiteaToken(r.Context(), cred.api(), req.Username, req.Password, tokenName)
+		h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), false, name)
+		writeErr(w, r, err, "Failed to save registry credential")
+		return
+	}
+	details := name + ": created token " + tokenName
+	if previous != "" {
+		if err := registry.DeleteGiteaToken(r.Context(), cred.api(), req.Username, req.Password, previous); err != nil {
+			details += ", " + previous + " not deleted: " + err.Error()
+		} else {
+			details += ", deleted " + previous
+		}
+	}
+	h.eventStore.Add(events.EventRegistryAuth, user.Username, getClientIP(r), true, details)
+	writeJSON(w, http.StatusOK, cred.masked())
+}
+
+// CheckTokenExpiry raises a registry_token_expiring event for tokens that
+// expire within tokenExpiryWarning and again when they expired, once each
+// until the token is replaced
+func (h *RegistryHandler) CheckTokenExpiry(now time.Time) {
+	if h.storage == nil {
+		return
+	}
+	creds, err := h.credentials()
+	if err != nil {
+		return
+	}
+	for _, cred := range creds {
+		if cred.ExpiresAt == nil {
+			continue
+		}
+		var state, details string
+		switch left := cred.ExpiresAt.Sub(now); {
+		case left <= 0:
+			state = tokenWarnedExpired
+			details = fmt.Sprintf("%s: token of %s expired on %s", cred.Registry, cred.Username, cred.ExpiresAt.Format("2006-01-02"))
+		case left <= tokenExpiryWarning:
+			state = tokenWarnedExpiring
+			details = fmt.Sprintf("%s: token of %s expires in %d days, on %s", cred.Registry, cred.Username, int(left.Hours()+23)/24, cred.ExpiresAt.Format("2006-01-02"))
+		default:
+			continue
+		}
+		if cred.Warned == state {
+			continue
+		}
+		if link := registry.NewTokenURL(cred.Provider, cred.api()); link != "" {
+			details += ", create a new one at " + link
+		}
+		h.eventStore.Add(events.EventRegistryToken, "system", "", false, details)
+		cred.Warned = state
+		h.storage.SetJSON(registryBucket, cred.Registry, cred)
+	}
+}
+
+// watchTokenExpiry checks token expiry every tokenCheckInterval until ctx
+// is cancelled
+func (h *RegistryHandler) watchTokenExpiry(ctx context.Context) {
+	ticker := time.NewTicker(tokenCheckInterval)
+	defer ticker.Stop()
+	for {
+		h.CheckTokenExpiry(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/logger"
+)
+
+// requestUserKey is the context key of the *string that logRequestUser
+// fills in for the access log
+type requestUserKey struct{}
+
+// requestLog returns base with the request ID and the authenticated user of r
+func requestLog(r *http.Request, base *logger.Logger) *logger.Logger {
+	args := []any{logger.KeyRequestID, middleware.GetReqID(r.Context())}
+	if user := auth.GetUserFromContext(r.Context()); user != nil {
+		args = append(args, logger.KeyUser, user.Username)
+	}
+	return base.With(args...)
+}
+
+// accessLog logs every request with its request ID (see middleware.RequestID),
+// at debug level and 5xx responses as warnings. The request ID is returned
+// in the X-Request-Id header.
+func (s *Server) accessLog(next http.Handler) http.Handler {
+	httpLogger := s.logger.Module("http")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+
+		var username string
+		ctx := context.WithValue(r.Context(), requestUserKey{}, &username)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK // hijacked or nothing written
+		}
+		args := []any{
+			logger.KeyRequestID, middleware.GetReqID(ctx),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start).Round(time.Millisecond),
+			"ip", getClientIP(r),
+		}
+		if username != "" {
+			args = append(args, logger.KeyUser, username)
+		}
+
+		if status >= http.StatusInternalServerError {
+			httpLogger.Warn("Request failed", args...)
+		} else {
+			httpLogger.Debug("Request", args...)
+		}
+	})
+}
+
+// recoverer turns handler panics into a 500 API error with the request ID,
+// logging the panic and its stack
+func (s *Server) recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				panic(rvr) // aborted response, handled by net/http
+			}
+			requestLog(r, s.logger.Module("http")).Error("Handler panic",
+				"panic", fmt.Sprint(rvr), "stack", string(debug.Stack()))
+			if r.Header.Get("Connection") != "Upgrade" {
+				writeError(w, r, http.StatusInternalServerError, "Internal server error")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logRequestUser records the authenticated user for the access log.
+// Used after the auth middleware.
+func (s *Server) logRequestUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, ok := r.Context().Value(requestUserKey{}).(*string); ok {
+			if user := auth.GetUserFromContext(r.Context()); user != nil {
+				*username = user.Username
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+var errServiceNotFound = apierror.New(http.StatusNotFound, "Service not found")
+
+// RollingRestart handles POST /api/stacks/{stack}/services/{service}/restart?timeout=120
+// Restarts the running replicas of a compose service one at a time, in
+// replica order. After each restart the replica must be running, and
+// healthy if it has a health check, within timeout seconds before the next
+// one restarts, so the first replica is a canary: if it doesn't come back,
+// the others keep running untouched. Stopped replicas are skipped.
+func (h *ContainerHandler) RollingRestart(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	timeout := defaultStackTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid timeout")
+			return
+		}
+		timeout = min(time.Duration(seconds)*time.Second, maxStackTimeout)
+	}
+
+	replicas, err := h.serviceReplicas(r, chi.URLParam(r, "stack"), chi.URLParam(r, "service"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	results := make([]StackResult, len(replicas))
+	for i, c := range replicas {
+		results[i] = StackResult{Name: firstOf(c.Names), ID: c.ID, Status: "pending"}
+	}
+	fail := func(i int, err *apierror.Error) {
+		results[i].Status = "failed"
+		writeErr(w, r, err.WithDetails(map[string]interface{}{"replicas": results}), "")
+	}
+
+	for i, c := range replicas {
+		if c.State != "running" {
+			results[i].Status = "skipped"
+			continue
+		}
+		if err := h.client.RestartContainer(r.Context(), c.ID); err != nil {
+			h.eventStore.Add(events.EventContainerRestart, user.Username, getClientIP(r), false, shortID(c.ID))
+			fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to restart container: "+results[i].Name+": "+err.Error()))
+			return
+		}
+		h.eventStore.Add(events.EventContainerRestart, user.Username, getClientIP(r), true, shortID(c.ID))
+		if err := h.waitReady(r.Context(), c.ID, false, timeout); err != nil {
+			fail(i, err)
+			return
+		}
+		results[i].Status = "restarted"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"replicas": results})
+}
+
+// serviceReplicas returns the containers of a compose service, ordered by
+// their replica number, then name
+func (h *ContainerHandler) serviceReplicas(r *http.Request, stack, service string) ([]podman.Container, error) {
+	containers, err := h.client.ListContainers(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	var replicas []podman.Container
+	for _, c := range containers {
+		if composeProject(c) == stack && c.Labels[labelComposeService] == service {
+			replicas = append(replicas, c)
+		}
+	}
+	if len(replicas) == 0 {
+		return nil, errServiceNotFound
+	}
+
+	slices.SortStableFunc(replicas, func(a, b podman.Container) int {
+		if d := replicaNumber(a) - replicaNumber(b); d != 0 {
+			return d
+		}
+		return strings.Compare(firstOf(a.Names), firstOf(b.Names))
+	})
+	return replicas, nil
+}
+
+// replicaNumber returns the replica number of a container of a compose
+// service: its container-number label, else the number ending its name
+// ("shop_web_2"), else 1
+func replicaNumber(c podman.Container) int {
+	if n, err := strconv.Atoi(c.Labels[labelComposeNumber]); err == nil {
+		return n
+	}
+	name := firstOf(c.Names)
+	if i := strings.LastIndexAny(name, "_-"); i >= 0 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 1
+}
@@ -1,25 +1,35 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"podmanview/internal/alerts"
+	"podmanview/internal/apierror"
 	"podmanview/internal/auth"
 	"podmanview/internal/config"
 	"podmanview/internal/events"
+	"podmanview/internal/idle"
 	"podmanview/internal/logger"
+	"podmanview/internal/notify"
 	"podmanview/internal/plugins"
 	"podmanview/internal/podman"
 	"podmanview/internal/storage"
 	"podmanview/internal/updater"
+	"podmanview/internal/webhooks"
 	"podmanview/web/templates"
 )
 
+// eventPruneInterval is how often expired events are removed and archived
+const eventPruneInterval = time.Minute
+
 // Server represents the API server
 type Server struct {
 	router         *chi.Mux
@@ -35,6 +45,19 @@ type Server struct {
 	plugins        []plugins.Plugin
 	pluginRegistry *plugins.Registry
 	storage        storage.Storage
+	backupHandler  *BackupHandler
+	scheduler      *SchedulerHandler
+	registry       *RegistryHandler
+	engineEvents   *engineEventHub
+	webhooks       *webhooks.Manager
+	notifications  *notify.Manager
+	alerts         *alerts.Engine
+	crashes        *crashTracker
+	uptime         *uptimeTracker
+	tags           *containerTags
+	drainer        *drainer      // event streams and terminals closed on shutdown
+	idle           *idle.Monitor // UI client activity, nil when power saving is off
+	metrics        *selfMetrics  // internal metrics of the server
 	version        string
 	staticVersion  string
 	logger         *logger.Logger
@@ -42,22 +65,51 @@ type Server struct {
 
 // NewServer creates new API server without plugins
 func NewServer(podmanClient *podman.Client, cfg *config.Config, version, staticVersion string, appLogger *logger.Logger) *Server {
-	return NewServerWithPlugins(podmanClient, cfg, version, staticVersion, nil, nil, nil, appLogger)
+	return NewServerWithPlugins(podmanClient, cfg, version, staticVersion, nil, nil, nil, NewEventStore(cfg, appLogger), appLogger)
+}
+
+// NewEventStore creates the event store with the retention and archive
+// settings from config. It is shared by the API server and plugins.
+func NewEventStore(cfg *config.Config, appLogger *logger.Logger) *events.Store {
+	eventStore := events.NewStore(cfg.EventsMax())
+	eventStore.SetRetention(cfg.EventsMax(), cfg.EventsMaxAge())
+	if dir := cfg.EventsArchiveDir(); dir != "" {
+		archiver, err := events.NewFileArchiver(dir)
+		if err != nil {
+			if appLogger != nil {
+				appLogger.Warn("Events will not be archived", logger.KeyModule, "events", logger.KeyError, err)
+			}
+		} else {
+			eventStore.SetArchiver(archiver)
+		}
+	}
+	return eventStore
 }
 
 // NewServerWithPlugins creates new API server with plugins
-func NewServerWithPlugins(podmanClient *podman.Client, cfg *config.Config, version, staticVersion string, pluginList []plugins.Plugin, registry *plugins.Registry, pluginStorage storage.Storage, appLogger *logger.Logger) *Server {
+func NewServerWithPlugins(podmanClient *podman.Client, cfg *config.Config, version, staticVersion string, pluginList []plugins.Plugin, registry *plugins.Registry, pluginStorage storage.Storage, eventStore *events.Store, appLogger *logger.Logger) *Server {
 	pamAuth := auth.NewPAMAuth()
-	jwtManager := auth.NewJWTManager(cfg.JWTSecret(), cfg.JWTExpiration())
+	jwtManager, err := auth.NewJWTManagerWithAlgorithm(cfg.JWTSecret(), cfg.JWTExpiration(), cfg.JWTAlgorithm())
+	if err != nil {
+		if appLogger != nil {
+			appLogger.Warn("Falling back to HS256", logger.KeyModule, "auth", logger.KeyError, err)
+		}
+		jwtManager = auth.NewJWTManager(cfg.JWTSecret(), cfg.JWTExpiration())
+	}
+	if err := loadJWTKeys(jwtManager, pluginStorage); err != nil && appLogger != nil {
+		appLogger.Warn("Failed to load JWT signing keys", logger.KeyModule, "auth", logger.KeyError, err)
+	}
 	authMw := auth.NewMiddleware(jwtManager)
+	if cfg.AuthMode() == config.AuthModeMTLS {
+		authMw.SetCertAuth(auth.NewCertAuth(pamAuth))
+	}
 	wsTokenStore := auth.NewWSTokenStore()
-	eventStore := events.NewStore(100) // Keep last 100 events in memory
 
 	// Get working directory for updater
 	workDir, err := os.Getwd()
 	if err != nil {
 		if appLogger != nil {
-			appLogger.Printf("Warning: failed to get working directory: %v", err)
+			appLogger.Warn("Failed to get working directory", logger.KeyModule, "updater", logger.KeyError, err)
 		}
 		workDir = "."
 	}
@@ -66,15 +118,33 @@ func NewServerWithPlugins(podmanClient *podman.Client, cfg *config.Config, versi
 	upd, err := updater.New(version, workDir)
 	if err != nil {
 		if appLogger != nil {
-			appLogger.Printf("Warning: failed to create updater: %v", err)
+			appLogger.Warn("Failed to create updater", logger.KeyModule, "updater", logger.KeyError, err)
 		}
 	} else if appLogger != nil {
-		upd.SetLogger(appLogger)
+		upd.SetLogger(appLogger.Module("updater"))
 	}
 
 	// Create history handler (store history in database)
 	historyHandler := NewHistoryHandler(pluginStorage)
 
+	// Load registered webhooks
+	var webhookManager *webhooks.Manager
+	if pluginStorage != nil {
+		webhookManager, err = webhooks.NewManager(pluginStorage, appLogger.Module("webhooks"))
+		if err != nil && appLogger != nil {
+			appLogger.Warn("Webhooks disabled", logger.KeyModule, "webhooks", logger.KeyError, err)
+		}
+	}
+
+	// Load notification channels
+	var notifyManager *notify.Manager
+	if pluginStorage != nil {
+		notifyManager, err = notify.NewManager(pluginStorage, appLogger.Module("notify"))
+		if err != nil && appLogger != nil {
+			appLogger.Warn("Notifications disabled", logger.KeyModule, "notify", logger.KeyError, err)
+		}
+	}
+
 	s := &Server{
 		router:         chi.NewRouter(),
 		podmanClient:   podmanClient,
@@ -89,11 +159,28 @@ func NewServerWithPlugins(podmanClient *podman.Client, cfg *config.Config, versi
 		plugins:        pluginList,
 		pluginRegistry: registry,
 		storage:        pluginStorage,
+		engineEvents:   newEngineEventHub(podmanClient, appLogger.Module("podman")),
+		webhooks:       webhookManager,
+		notifications:  notifyManager,
+		drainer:        newDrainer(),
+		metrics:        newSelfMetrics(),
 		version:        version,
 		staticVersion:  staticVersion,
 		logger:         appLogger,
 	}
 
+	s.crashes = newCrashTracker(podmanClient, pluginStorage, eventStore, appLogger.Module("podman"))
+	s.uptime = newUptimeTracker(pluginStorage, appLogger.Module("podman"))
+	s.tags = newContainerTags(pluginStorage, appLogger.Module("podman"))
+
+	// Load alert rules (actions use the managers above)
+	if pluginStorage != nil {
+		s.alerts, err = alerts.NewEngine(pluginStorage, eventStore, alertMetrics, &alertActions{server: s}, appLogger.Module("alerts"))
+		if err != nil && appLogger != nil {
+			appLogger.Warn("Alert rules disabled", logger.KeyModule, "alerts", logger.KeyError, err)
+		}
+	}
+
 	s.setupRoutes()
 	return s
 }
@@ -103,50 +190,232 @@ func (s *Server) setupRoutes() {
 	r := s.router
 
 	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(middleware.RequestID)
+	r.Use(clientIP(s.config.TrustedProxies()))
+	r.Use(s.accessLog)
+	r.Use(s.metrics.countRequests)
+	r.Use(s.recoverer)
 	r.Use(middleware.Compress(5))
+	r.Use(s.apiVersion)
+	if cors := newCORS(s.config.CORSOrigins(), s.config.CORSMethods(), s.config.CORSHeaders()); cors != nil {
+		r.Use(cors.Middleware)
+	}
+	if s.config.DemoMode() {
+		r.Use(demoMiddleware)
+	}
+
+	maintenanceHandler := NewMaintenanceHandler(s.config, s.eventStore)
+	r.Use(maintenanceHandler.Middleware)
 
 	// Create handlers
-	authHandler := NewAuthHandler(s.pamAuth, s.jwtManager, s.wsTokenStore, s.eventStore)
-	containerHandler := NewContainerHandler(s.podmanClient, s.eventStore)
+	authHandler := NewAuthHandler(s.pamAuth, s.jwtManager, s.wsTokenStore, s.eventStore, s.storage)
+	backupHandler := NewBackupHandler(s.storage, s.config, s.eventStore, s.logger.Module("storage"))
+	backupHandler.metrics = s.metrics
+	s.backupHandler = backupHandler
+	schedulerHandler := NewSchedulerHandler(s.podmanClient, s.storage, backupHandler, s.eventStore, s.logger.Module("scheduler"))
+	schedulerHandler.metrics = s.metrics
+	s.scheduler = schedulerHandler
+	confirms := newConfirmStore() // tokens of destructive operations
+	volumeHandler := NewVolumeHandler(s.podmanClient, s.storage, schedulerHandler, s.eventStore, confirms)
+	trashHandler := NewTrashHandler(s.podmanClient, s.storage, s.eventStore)
+	stackEnvHandler := NewStackEnvHandler(s.storage, s.config, s.eventStore)
+	containerHandler := NewContainerHandler(s.podmanClient, s.eventStore, s.drainer, trashHandler, volumeHandler, s.uptime, s.tags, stackEnvHandler)
+	crashHandler := NewCrashHandler(s.podmanClient, s.crashes)
+	tagHandler := NewTagHandler(s.podmanClient, s.tags, s.eventStore)
 	imageHandler := NewImageHandler(s.podmanClient, s.eventStore)
-	systemHandler := NewSystemHandler(s.podmanClient, s.eventStore, s.pluginRegistry)
-	terminalHandler := NewTerminalHandler(s.podmanClient, s.wsTokenStore, s.eventStore, s.historyHandler, s.logger)
-	eventsHandler := NewEventsHandler(s.eventStore)
-	updateHandler := NewUpdateHandler(s.updater, s.eventStore, s.logger)
-	fileManagerHandler := NewFileManagerHandler(s.eventStore, "", s.logger) // Empty baseDir means use home dir
+	registryHandler := NewRegistryHandler(s.podmanClient, s.config, s.storage, s.eventStore)
+	if s.podmanClient != nil {
+		s.podmanClient.SetRegistryAuth(registryHandler.pullLogin)
+	}
+	s.registry = registryHandler
+	buildHandler := NewBuildHandler(s.podmanClient, s.storage, s.eventStore, s.drainer)
+	schedulerHandler.builds = buildHandler
+	kubeHandler := NewKubeHandler(s.podmanClient, s.eventStore)
+	toolsHandler := NewToolsHandler(s.podmanClient)
+	networkHandler := NewNetworkHandler(s.podmanClient)
+	envHandler := NewEnvHandler(s.podmanClient, s.config, s.eventStore, volumeHandler)
+	proxyHandler := NewProxyHandler(s.podmanClient, s.config)
+	systemHandler := NewSystemHandler(s.podmanClient, s.eventStore, s.pluginRegistry, confirms)
+	terminalPolicyHandler := NewTerminalPolicyHandler(s.storage, s.eventStore)
+	terminalHandler := NewTerminalHandler(s.podmanClient, s.storage, s.wsTokenStore, s.eventStore, s.historyHandler, s.drainer, s.logger.Module("terminal"))
+	eventsHandler := NewEventsHandler(s.eventStore, s.engineEvents, s.drainer)
+	activityHandler := NewActivityHandler(s.podmanClient, s.config, s.eventStore)
+	updateHandler := NewUpdateHandler(s.updater, s.eventStore, s.logger.Module("updater"))
+	fileManagerHandler := NewFileManagerHandler(s.eventStore, "", s.logger.Module("files")) // Empty baseDir means use home dir
 	pluginHandler := NewPluginHandler(s)
+	configHandler := NewConfigHandler(s.config, s.eventStore, s.logger)
+	webhooksHandler := NewWebhooksHandler(s.webhooks, s.eventStore)
+	notificationsHandler := NewNotificationsHandler(s.notifications, s.eventStore)
+	alertsHandler := NewAlertsHandler(s.alerts, s.eventStore)
+	dashboardHandler := NewDashboardHandler(s.storage, s, s.eventStore)
+	noteHandler := NewNoteHandler(s.podmanClient, s.storage, s.eventStore)
+	launcherHandler := NewLauncherHandler(s.podmanClient, s.storage, s.eventStore)
+	shareHandler := NewShareHandler(s.podmanClient, s.storage, s.config, s.eventStore)
+	logsHandler := NewLogsHandler(s.logger, s.drainer)
+	if s.config.DemoMode() {
+		systemHandler.readHostStats = demoHostStats
+	} else if s.config.NetworkMounts() {
+		systemHandler.readHostStats = GetHostStatsWithNetworkMounts
+	}
 
 	// Health check (no auth required)
 	r.Get("/api/health", s.Health)
 
+	// Liveness and readiness probes for monitors (no auth required)
+	r.Get("/healthz", s.Healthz)
+	r.Head("/healthz", s.Healthz)
+	r.Get("/readyz", s.Readyz)
+	r.Head("/readyz", s.Readyz)
+
 	// Public routes
-	r.Post("/api/auth/login", authHandler.Login)
+	if s.config.AuthMode() == config.AuthModeMTLS {
+		r.Post("/api/auth/login", authHandler.LoginDisabled)
+	} else {
+		r.Post("/api/auth/login", authHandler.Login)
+	}
+	r.Get("/api/auth/jwks", authHandler.JWKS)
+
+	// Launcher page, public or with its own access key
+	r.Get("/launcher", launcherHandler.Page(s.config.BasePath()))
+	r.Get("/api/launcher", launcherHandler.Links)
+
+	// Shared container pages, with a signed token
+	r.Get("/shared/{token}", shareHandler.Page(s.config.BasePath()))
+	r.Get("/api/share/{token}", shareHandler.Container)
+	r.Get("/api/share/{token}/logs", shareHandler.Logs)
 
 	// Protected API routes
 	r.Group(func(r chi.Router) {
 		// Apply auth middleware only if NoAuth is false
-		if !s.config.NoAuth() {
+		if s.config.DemoMode() {
+			r.Use(demoAuthMiddleware)
+		} else if !s.config.NoAuth() {
 			r.Use(s.authMw.RequireAuth)
 		} else {
 			// In no-auth mode, inject a fake admin user
 			r.Use(s.fakeAuthMiddleware)
 		}
+		r.Use(s.logRequestUser)
+		r.Use(s.trackActivity)
+
+		// API documentation
+		r.Get("/api/openapi.json", s.OpenAPI)
+		r.Get("/api/docs", s.APIDocs)
 
 		// Auth
 		r.Post("/api/auth/logout", authHandler.Logout)
 		r.Get("/api/auth/me", authHandler.Me)
 		r.Get("/api/auth/ws-token", authHandler.WSToken)
+		r.Get("/api/auth/keys", authHandler.Keys)
+		r.Post("/api/auth/keys/rotate", authHandler.RotateKey)
 
 		// Events
 		r.Get("/api/events", eventsHandler.List)
+		r.Get("/api/events/types", eventsHandler.Types)
+		r.Get("/api/events/stream", eventsHandler.Stream)
+		r.Get("/api/events/activity", activityHandler.Activity)
+
+		// GraphQL (optional)
+		if s.config.GraphQLEnabled() {
+			graphQLHandler := NewGraphQLHandler(s.podmanClient, s.eventStore, s.engineEvents, s.pluginRegistry, s.drainer)
+			if s.config.DemoMode() {
+				graphQLHandler.readHostStats = demoHostStats
+			} else if s.config.NetworkMounts() {
+				graphQLHandler.readHostStats = GetHostStatsWithNetworkMounts
+			}
+			r.Get("/api/graphql", graphQLHandler.Query)
+			r.Post("/api/graphql", graphQLHandler.Query)
+			r.Get("/api/graphql/schema", graphQLHandler.Schema)
+		}
+
+		// Webhooks
+		r.Get("/api/webhooks", webhooksHandler.List)
+		r.Post("/api/webhooks", webhooksHandler.Create)
+		r.Put("/api/webhooks/{id}", webhooksHandler.Update)
+		r.Delete("/api/webhooks/{id}", webhooksHandler.Delete)
+		r.Post("/api/webhooks/{id}/test", webhooksHandler.Test)
+
+		// Notifications
+		r.Get("/api/notifications/types", notificationsHandler.Types)
+		r.Get("/api/notifications/channels", notificationsHandler.List)
+		r.Post("/api/notifications/channels", notificationsHandler.Create)
+		r.Put("/api/notifications/channels/{id}", notificationsHandler.Update)
+		r.Delete("/api/notifications/channels/{id}", notificationsHandler.Delete)
+		r.Post("/api/notifications/channels/{id}/test", notificationsHandler.Test)
+
+		// Alert rules
+		r.Get("/api/alerts", alertsHandler.Status)
+		r.Get("/api/alerts/rules", alertsHandler.List)
+		r.Post("/api/alerts/rules", alertsHandler.Create)
+		r.Put("/api/alerts/rules/{id}", alertsHandler.Update)
+		r.Delete("/api/alerts/rules/{id}", alertsHandler.Delete)
+
+		// Dashboard layouts
+		r.Get("/api/dashboards", dashboardHandler.List)
+		r.Post("/api/dashboards", dashboardHandler.Create)
+		r.Get("/api/dashboards/default", dashboardHandler.GetDefault)
+		r.Put("/api/dashboards/default", dashboardHandler.SetDefault)
+		r.Delete("/api/dashboards/default", dashboardHandler.ResetDefault)
+		r.Get("/api/dashboards/{id}", dashboardHandler.Get)
+		r.Put("/api/dashboards/{id}", dashboardHandler.Update)
+		r.Delete("/api/dashboards/{id}", dashboardHandler.Delete)
+
+		// Launcher settings
+		r.Get("/api/launcher/settings", launcherHandler.GetSettings)
+		r.Put("/api/launcher/settings", launcherHandler.UpdateSettings)
+		r.Post("/api/launcher/settings/key", launcherHandler.RotateKey)
+
+		// Share links
+		r.Get("/api/shares", shareHandler.List)
+		r.Delete("/api/shares/{id}", shareHandler.Revoke)
+
+		// Notes of containers, stacks and volumes
+		r.Get("/api/notes", noteHandler.List)
+		r.Get("/api/notes/{kind}/{name}", noteHandler.Get)
+		r.Put("/api/notes/{kind}/{name}", noteHandler.Update)
+		r.Delete("/api/notes/{kind}/{name}", noteHandler.Delete)
+		r.Get("/api/notes/{kind}/{name}/versions", noteHandler.Versions)
+		r.Get("/api/notes/{kind}/{name}/versions/{version}", noteHandler.Version)
+
+		// Command history
+		r.Get("/api/history", s.historyHandler.List)
 
 		// Containers
 		r.Get("/api/containers", containerHandler.List)
 		r.Post("/api/containers", containerHandler.Create)
+		r.Get("/api/containers/graph", containerHandler.Graph)
+		r.Get("/api/containers/security", containerHandler.Security)
+		r.Post("/api/containers/import", containerHandler.Import)
+		r.Post("/api/containers/stack", containerHandler.CreateStack)
+		r.Post("/api/containers/stack/redeploy", containerHandler.RedeployStack)
+		r.Get("/api/stacks/env", stackEnvHandler.List)
+		r.Get("/api/stacks/{stack}/env", stackEnvHandler.Get)
+		r.Put("/api/stacks/{stack}/env", stackEnvHandler.Update)
+		r.Delete("/api/stacks/{stack}/env", stackEnvHandler.Delete)
+		r.Post("/api/stacks/{stack}/services/{service}/restart", containerHandler.RollingRestart)
+		r.Post("/api/stacks/{stack}/services/{service}/scale", containerHandler.Scale)
+		r.Get("/api/containers/logs/stream", containerHandler.StreamLogs)
+		r.Get("/api/containers/unstable", crashHandler.Unstable)
+		r.Get("/api/containers/tags", tagHandler.List)
+		r.Get("/api/containers/favorites", tagHandler.Favorites)
+		r.Get("/api/containers/trash", trashHandler.List)
+		r.Post("/api/containers/trash/{id}/restore", trashHandler.Restore)
+		r.Delete("/api/containers/trash/{id}", trashHandler.Delete)
 		r.Get("/api/containers/{id}", containerHandler.Inspect)
+		r.Get("/api/containers/{id}/export", containerHandler.Export)
+		r.Get("/api/containers/{id}/diff", containerHandler.Diff)
+		r.Get("/api/containers/{id}/network", containerHandler.Network)
+		r.Get("/api/containers/{id}/exits", crashHandler.Exits)
+		r.Get("/api/containers/{id}/tags", tagHandler.Get)
+		r.Put("/api/containers/{id}/tags", tagHandler.Update)
+		r.Post("/api/containers/{id}/share", shareHandler.Create)
+		r.Put("/api/containers/{id}/favorite", tagHandler.AddFavorite)
+		r.Delete("/api/containers/{id}/favorite", tagHandler.RemoveFavorite)
+		r.Get("/api/containers/{id}/env", envHandler.Get)
+		r.Put("/api/containers/{id}/env", envHandler.Update)
 		r.Get("/api/containers/{id}/logs", containerHandler.Logs)
+		r.Get("/api/containers/{id}/logs/download", containerHandler.DownloadLogs)
+		r.Get("/api/containers/{id}/logs/search", containerHandler.SearchLogs)
 		r.Post("/api/containers/{id}/start", containerHandler.Start)
 		r.Post("/api/containers/{id}/stop", containerHandler.Stop)
 		r.Post("/api/containers/{id}/restart", containerHandler.Restart)
@@ -154,20 +423,91 @@ func (s *Server) setupRoutes() {
 
 		// Terminal (WebSocket) - history is sent via WebSocket
 		r.Get("/api/containers/{id}/terminal", terminalHandler.Connect)
+		r.Get("/api/containers/{id}/attach", terminalHandler.Attach)
 		r.Get("/api/terminal", terminalHandler.HostTerminal)
+		r.Get("/api/terminal/policy", terminalPolicyHandler.Get)
+		r.Put("/api/terminal/policy", terminalPolicyHandler.Update)
+		r.Post("/api/terminal/policy/check", terminalPolicyHandler.Check)
+
+		// Proxy to container ports (any method, WebSocket upgrades)
+		r.HandleFunc("/proxy/{container}/{port}", proxyHandler.Redirect)
+		r.HandleFunc("/proxy/{container}/{port}/*", proxyHandler.Serve)
 
 		// Images
 		r.Get("/api/images", imageHandler.List)
 		r.Get("/api/images/{id}", imageHandler.Inspect)
+		r.Get("/api/images/{id}/export", imageHandler.Export)
+		r.Get("/api/images/{id}/layers", imageHandler.Layers)
 		r.Post("/api/images/pull", imageHandler.Pull)
+		r.Post("/api/images/import", imageHandler.Import)
+		r.Post("/api/images/build", buildHandler.Build)
+		r.Get("/api/builds", buildHandler.ListDefinitions)
+		r.Post("/api/builds", buildHandler.CreateDefinition)
+		r.Get("/api/builds/{id}", buildHandler.GetDefinition)
+		r.Put("/api/builds/{id}", buildHandler.UpdateDefinition)
+		r.Delete("/api/builds/{id}", buildHandler.DeleteDefinition)
+		r.Post("/api/builds/{id}/run", buildHandler.RunDefinition)
+		r.Get("/api/builds/{id}/runs", buildHandler.Runs)
+		r.Get("/api/builds/{id}/runs/{run}", buildHandler.GetRun)
 		r.Delete("/api/images/{id}", imageHandler.Remove)
 
+		// Registries
+		r.Get("/api/registry", registryHandler.List)
+		r.Get("/api/registry/search", registryHandler.Search)
+		r.Get("/api/registry/tags", registryHandler.Tags)
+		r.Get("/api/registry/credentials", registryHandler.ListCredentials)
+		r.Put("/api/registry/credentials/{registry}", registryHandler.SetCredential)
+		r.Delete("/api/registry/credentials/{registry}", registryHandler.DeleteCredential)
+		r.Post("/api/registry/credentials/{registry}/token", registryHandler.CreateToken)
+
+		// Volumes
+		r.Get("/api/volumes/backups", volumeHandler.ListBackups)
+		r.Post("/api/volumes/backups/{id}/restore", volumeHandler.RestoreBackup)
+		r.Delete("/api/volumes/backups/{id}", volumeHandler.DeleteBackup)
+		r.Delete("/api/volumes/{name}", volumeHandler.Remove)
+
+		// Networks
+		r.Get("/api/networks/ipam", networkHandler.IPAM)
+
+		// Kubernetes YAML
+		r.Get("/api/kube", kubeHandler.Generate)
+		r.Post("/api/kube/play", kubeHandler.Play)
+
+		// Network debug tools
+		r.Get("/api/tools/dns", toolsHandler.DNS)
+		r.Get("/api/tools/port", toolsHandler.Port)
+		r.Get("/api/tools/traceroute", toolsHandler.Traceroute)
+
 		// System
 		r.Get("/api/system/dashboard", systemHandler.Dashboard)
 		r.Get("/api/system/info", systemHandler.Info)
 		r.Get("/api/system/df", systemHandler.DiskUsage)
+		r.Get("/api/system/df/containers", systemHandler.ContainerDiskUsage)
+		r.Get("/api/system/allocations", systemHandler.Allocations)
+		r.Post("/api/system/prune", systemHandler.Prune)
 		r.Post("/api/system/reboot", systemHandler.Reboot)
 		r.Post("/api/system/shutdown", systemHandler.Shutdown)
+		r.Get("/api/system/maintenance", maintenanceHandler.Status)
+		r.Post("/api/system/maintenance", maintenanceHandler.Toggle)
+		r.Get("/api/system/backup", backupHandler.Download)
+		r.Post("/api/system/backup/restore", backupHandler.Restore)
+		r.Get("/api/system/storage", backupHandler.Stats)
+		r.Post("/api/system/storage/maintenance", backupHandler.Maintenance)
+		r.Get("/api/system/jobs", schedulerHandler.List)
+		r.Put("/api/system/jobs/{job}", schedulerHandler.Update)
+		r.Post("/api/system/jobs/{job}/run", schedulerHandler.RunNow)
+		r.Get("/api/system/logs", logsHandler.List)
+		r.Get("/api/system/logs/stream", logsHandler.Stream)
+		r.Get("/api/system/diagnostics", s.Diagnostics)
+		r.Get("/api/system/self", s.Self)
+		r.Get("/api/metrics", s.Metrics)
+
+		// Configuration
+		r.Get("/api/config", configHandler.Effective)
+		r.Get("/api/settings", configHandler.GetSettings)
+		r.Patch("/api/settings", configHandler.UpdateSettings)
+		r.Get("/api/config/backups", configHandler.ListBackups)
+		r.Post("/api/config/backups/{id}/restore", configHandler.RestoreBackup)
 
 		// Updates
 		r.Get("/api/system/version", updateHandler.Version)
@@ -197,8 +537,16 @@ func (s *Server) setupRoutes() {
 	// Register plugin routes
 	s.registerPluginRoutes(r)
 
-	// Static files and SPA
-	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
+	// Unknown API paths and methods get an API error instead of the SPA
+	r.Get("/api/*", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusNotFound, "API endpoint not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+	})
+
+	// Static files (embedded) and SPA
+	r.Handle("/static/*", s.staticHandler())
 
 	// Serve index.html for all other routes (SPA)
 	r.Get("/*", s.serveIndex)
@@ -213,7 +561,7 @@ func (s *Server) pluginEnabledMiddleware(pluginName string, next http.HandlerFun
 				return
 			}
 		}
-		http.Error(w, "Plugin not enabled", http.StatusServiceUnavailable)
+		writeError(w, r, http.StatusServiceUnavailable, "Plugin not enabled")
 	}
 }
 
@@ -230,13 +578,16 @@ func (s *Server) registerPluginRoutes(r chi.Router) {
 		}
 
 		for _, route := range routes {
-			handler := s.pluginEnabledMiddleware(plugin.Name(), route.Handler)
+			handler := s.pluginEnabledMiddleware(plugin.Name(), s.metrics.timePlugin(plugin.Name(), route.Handler))
 
 			if route.RequireAuth && !s.config.NoAuth() {
 				authHandler := handler
 				handler = func(w http.ResponseWriter, req *http.Request) {
 					s.authMw.RequireAuth(http.HandlerFunc(authHandler)).ServeHTTP(w, req)
 				}
+			} else if route.RequireAuth {
+				// Same fake admin as the protected API routes
+				handler = s.fakeAuthMiddleware(handler).ServeHTTP
 			}
 
 			switch route.Method {
@@ -258,8 +609,30 @@ func (s *Server) registerPluginRoutes(r chi.Router) {
 // Health returns the health status of the server
 func (s *Server) Health(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status":  "ok",
-		"version": s.version,
+		"status":      "ok",
+		"version":     s.version,
+		"maintenance": s.config.MaintenanceMode(),
+		"demo":        s.config.DemoMode(),
+		"idle":        s.idle.Idle(),
+	})
+}
+
+// SetIdleMonitor enables power saving: while no client is connected,
+// host sampling slows down and maintenance jobs wait. Call before the
+// background jobs are started.
+func (s *Server) SetIdleMonitor(m *idle.Monitor) {
+	s.idle = m
+	if s.alerts != nil {
+		s.alerts.SetIdleMonitor(m)
+	}
+}
+
+// trackActivity records requests of clients for the idle monitor
+func (s *Server) trackActivity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.idle.Begin()
+		defer s.idle.End()
+		next.ServeHTTP(w, r)
 	})
 }
 
@@ -269,6 +642,7 @@ func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
 	html := string(templates.IndexHTML)
 	html = strings.ReplaceAll(html, "{{VERSION}}", s.version)
 	html = strings.ReplaceAll(html, "{{STATIC_VERSION}}", s.staticVersion)
+	html = strings.ReplaceAll(html, "{{BASE_PATH}}", s.config.BasePath())
 
 	// Set content type and write response
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -276,9 +650,95 @@ func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
-// Router returns the chi router
+// Router returns the chi router.
+// With a base path configured, all routes are served under it and the prefix
+// is stripped before routing, so handlers always see root-relative paths.
 func (s *Server) Router() *chi.Mux {
-	return s.router
+	basePath := s.config.BasePath()
+	if basePath == "" {
+		return s.router
+	}
+
+	root := chi.NewRouter()
+	root.Handle(basePath+"/*", http.StripPrefix(basePath, s.router))
+	root.Get(basePath, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, basePath+"/", http.StatusMovedPermanently)
+	})
+	root.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, basePath+"/", http.StatusFound)
+	})
+	return root
+}
+
+// StartStorageMaintenance periodically checks and compacts the storage
+// in the background until ctx is cancelled. Does nothing if interval is 0.
+func (s *Server) StartStorageMaintenance(ctx context.Context, interval time.Duration) {
+	if s.storage == nil || interval <= 0 {
+		return
+	}
+	go s.backupHandler.ScheduleMaintenance(ctx, interval, s.idle)
+}
+
+// StartScheduler runs the scheduled jobs (auto-update, prune, backup) in
+// their maintenance windows until ctx is cancelled. Does nothing without
+// storage or in demo mode.
+func (s *Server) StartScheduler(ctx context.Context) {
+	if s.storage == nil || s.config.DemoMode() {
+		return
+	}
+	go s.scheduler.Run(ctx)
+}
+
+// StartEventPruning periodically removes expired events and archives removed
+// events in the background until ctx is cancelled. While idle, pruning
+// waits until a client connects.
+func (s *Server) StartEventPruning(ctx context.Context) {
+	go s.idle.RunPeriodic(ctx, eventPruneInterval, s.PruneEvents)
+}
+
+// StartEngineEvents follows the Podman engine event stream in the background
+// until ctx is cancelled and forwards it to clients of /api/events/stream
+func (s *Server) StartEngineEvents(ctx context.Context) {
+	go s.engineEvents.run(ctx)
+}
+
+// StartWebhooks delivers new events to registered webhooks in the
+// background until ctx is cancelled
+func (s *Server) StartWebhooks(ctx context.Context) {
+	if s.webhooks == nil {
+		return
+	}
+	go s.webhooks.Run(ctx, s.eventStore)
+}
+
+// StartNotifications sends alert-class events to the configured
+// notification channels in the background until ctx is cancelled
+func (s *Server) StartNotifications(ctx context.Context) {
+	if s.notifications == nil {
+		return
+	}
+	go s.notifications.Run(ctx, s.eventStore)
+}
+
+// CloseEvents archives the events still in memory on shutdown
+func (s *Server) CloseEvents() {
+	if err := s.eventStore.Close(); err != nil {
+		s.logger.Error("Failed to archive events", logger.KeyModule, "events", logger.KeyError, err)
+	}
+}
+
+// PruneEvents removes expired events and archives removed events
+func (s *Server) PruneEvents() {
+	s.metrics.timeJob(jobEventPruning, func() bool {
+		expired, err := s.eventStore.Prune()
+		if err != nil {
+			s.logger.Error("Failed to archive events", logger.KeyModule, "events", logger.KeyError, err)
+		}
+		if expired > 0 {
+			s.logger.Debug("Removed expired events", logger.KeyModule, "events", "count", expired)
+		}
+		return err == nil
+	})
 }
 
 // writeJSON writes JSON response
@@ -288,6 +748,17 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
+// writeError writes an error response in the API error format (see apierror.Response)
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	apierror.Write(w, r, status, message)
+}
+
+// writeErr writes an error response for err, with the status mapped from
+// the error (see apierror.Status) and prefix before its message
+func writeErr(w http.ResponseWriter, r *http.Request, err error, prefix string) {
+	apierror.WriteErr(w, r, err, prefix)
+}
+
 // fakeAuthMiddleware injects a fake admin user for no-auth mode
 func (s *Server) fakeAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
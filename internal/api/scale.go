@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+// maxReplicas limits the replicas of a scaled service
+const maxReplicas = 20
+
+// Port modes of new replicas
+const (
+	ScalePortsOffset = "offset" // published ports of the first replica, shifted by the replica number
+	ScalePortsNone   = "none"   // no published ports, e.g. behind a reverse proxy
+)
+
+// ScaleRequest represents the request body for scaling a compose service
+type ScaleRequest struct {
+	Replicas int    `json:"replicas"`
+	Ports    string `json:"ports"` // offset (default) or none
+	// PortStep is the host port offset between consecutive replicas, 1 by
+	// default
+	PortStep int `json:"portStep"`
+}
+
+var (
+	errScaleReplicas = apierror.New(http.StatusBadRequest, "Replicas must be between 1 and "+strconv.Itoa(maxReplicas))
+	errScalePorts    = apierror.New(http.StatusBadRequest, "Invalid ports, use offset or none")
+	errScaleStep     = apierror.New(http.StatusBadRequest, "Invalid port step")
+)
+
+// Scale handles POST /api/stacks/{stack}/services/{service}/scale
+// Creates or removes replicas of a compose service until it has the
+// requested number. New replicas are created from the spec of the first
+// replica, named after it with the next replica numbers ("shop_web_3"),
+// and started if it is running. Their published host ports are those of
+// the first replica shifted by portStep per replica number, or left out
+// with ports=none. Scaling down stops and removes the replicas with the
+// highest numbers.
+func (h *ContainerHandler) Scale(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req ScaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	switch {
+	case req.Replicas < 1 || req.Replicas > maxReplicas:
+		writeErr(w, r, errScaleReplicas, "")
+		return
+	case req.Ports != "" && req.Ports != ScalePortsOffset && req.Ports != ScalePortsNone:
+		writeErr(w, r, errScalePorts, "")
+		return
+	case req.PortStep < 0 || req.PortStep > 1000:
+		writeErr(w, r, errScaleStep, "")
+		return
+	}
+	if req.PortStep == 0 {
+		req.PortStep = 1
+	}
+
+	replicas, err := h.serviceReplicas(r, chi.URLParam(r, "stack"), chi.URLParam(r, "service"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	results := make([]StackResult, 0, max(len(replicas), req.Replicas))
+	for _, c := range replicas {
+		results = append(results, StackResult{Name: firstOf(c.Names), ID: c.ID, Status: "unchanged"})
+	}
+	fail := func(i int, err *apierror.Error) {
+		results[i].Status = "failed"
+		writeErr(w, r, err.WithDetails(map[string]interface{}{"replicas": results}), "")
+	}
+
+	// Scale down from the last replica
+	for i := len(replicas) - 1; i >= req.Replicas; i-- {
+		c := replicas[i]
+		if c.State == "running" {
+			if err := h.client.StopContainer(r.Context(), c.ID); err != nil {
+				h.eventStore.Add(events.EventContainerStop, user.Username, getClientIP(r), false, shortID(c.ID))
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to stop container: "+results[i].Name+": "+err.Error()))
+				return
+			}
+			h.eventStore.Add(events.EventContainerStop, user.Username, getClientIP(r), true, shortID(c.ID))
+		}
+		if err := h.client.RemoveContainer(r.Context(), c.ID, false); err != nil {
+			h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), false, shortID(c.ID))
+			fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to remove container: "+results[i].Name+": "+err.Error()))
+			return
+		}
+		h.eventStore.Add(events.EventContainerRemove, user.Username, getClientIP(r), true, shortID(c.ID))
+		results[i].Status = "removed"
+	}
+
+	if req.Replicas > len(replicas) {
+		first := replicas[0]
+		info, err := h.client.InspectContainer(r.Context(), first.ID)
+		if err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+		image, err := h.client.InspectImage(r.Context(), info.Image)
+		if err != nil {
+			image = &podman.ImageInspect{}
+		}
+		template := buildContainerSpec(info, image)
+		firstNumber := replicaNumber(first)
+		next := replicaNumber(replicas[len(replicas)-1])
+
+		for len(results) < req.Replicas {
+			next++
+			spec := *template
+			spec.Name = replicaName(template.Name, firstNumber, next)
+			spec.Hostname = ""
+			spec.Labels = maps.Clone(template.Labels)
+			if spec.Labels == nil {
+				spec.Labels = make(map[string]string)
+			}
+			spec.Labels[labelComposeNumber] = strconv.Itoa(next)
+			spec.Ports = nil
+			if req.Ports != ScalePortsNone {
+				for _, p := range template.Ports {
+					if p.HostPort > 0 {
+						p.HostPort += (next - firstNumber) * req.PortStep
+					}
+					spec.Ports = append(spec.Ports, p)
+				}
+			}
+
+			i := len(results)
+			results = append(results, StackResult{Name: spec.Name, Status: "pending"})
+			config, err := spec.createConfig()
+			if err != nil {
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to create container: "+spec.Name+": "+err.Error()))
+				return
+			}
+			result, err := h.client.CreateContainer(r.Context(), config)
+			if err != nil {
+				h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), false, spec.Name)
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to create container: "+spec.Name+": "+err.Error()))
+				return
+			}
+			h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), true, shortID(result.ID))
+			results[i].ID = result.ID
+			results[i].Status = "created"
+
+			if first.State != "running" {
+				continue
+			}
+			if err := h.client.StartContainer(r.Context(), result.ID); err != nil {
+				h.eventStore.Add(events.EventContainerStart, user.Username, getClientIP(r), false, shortID(result.ID))
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to start container: "+spec.Name+": "+err.Error()))
+				return
+			}
+			h.eventStore.Add(events.EventContainerStart, user.Username, getClientIP(r), true, shortID(result.ID))
+			results[i].Status = "started"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"replicas": results})
+}
+
+// replicaName returns the name of replica n of a service whose replica
+// number is named name: "shop_web_1" becomes "shop_web_3", and names
+// without the number get it appended ("web" becomes "web_3")
+func replicaName(name string, number, n int) string {
+	for _, sep := range []string{"_", "-"} {
+		if base, ok := strings.CutSuffix(name, sep+strconv.Itoa(number)); ok {
+			return base + sep + strconv.Itoa(n)
+		}
+	}
+	return name + "_" + strconv.Itoa(n)
+}
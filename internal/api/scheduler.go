@@ -0,0 +1,657 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// Scheduled jobs
+const (
+	JobAutoUpdate = "auto-update" // pull new images of labeled containers and recreate them
+	JobPrune      = "prune"       // remove stopped containers and dangling images
+	JobBackup     = "backup"      // write a storage backup archive to a directory
+	JobBuilds     = "builds"      // run saved builds that are scheduled or whose base image changed
+)
+
+// scheduledJobs lists the jobs in display order
+var scheduledJobs = []string{JobAutoUpdate, JobPrune, JobBackup, JobBuilds}
+
+const (
+	// schedulerBucket stores the settings and last run of each job
+	schedulerBucket = "scheduler"
+
+	// schedulerInterval is how often due jobs are checked
+	schedulerInterval = time.Minute
+
+	// autoUpdateLabel marks containers updated by the auto-update job, like
+	// for podman auto-update; only the "registry" policy is supported
+	autoUpdateLabel = "io.containers.autoupdate"
+
+	// defaultBackupKeep is how many archives the backup job keeps
+	defaultBackupKeep = 7
+)
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// MaintenanceWindow is a weekly time range in the local time of the server.
+// A window whose end is not after its start runs past midnight.
+type MaintenanceWindow struct {
+	Days  []string `json:"days,omitempty"` // "mon" ... "sun" the window starts on, every day if empty
+	Start string   `json:"start"`          // "02:00"
+	End   string   `json:"end"`            // "05:00"
+}
+
+// JobSettings configures a scheduled job
+type JobSettings struct {
+	Enabled       bool                `json:"enabled"`
+	IntervalHours int                 `json:"intervalHours"`
+	Windows       []MaintenanceWindow `json:"windows"`        // runs any time without windows
+	Dir           string              `json:"dir,omitempty"`  // backup: directory of the archives
+	Keep          int                 `json:"keep,omitempty"` // backup: number of archives kept
+}
+
+// jobState is the stored state of a job
+type jobState struct {
+	Settings   JobSettings `json:"settings"`
+	LastRun    *time.Time  `json:"lastRun,omitempty"`
+	LastResult string      `json:"lastResult,omitempty"`
+	LastError  string      `json:"lastError,omitempty"`
+}
+
+// JobStatus is the state of a scheduled job
+type JobStatus struct {
+	Name       string      `json:"name"`
+	Settings   JobSettings `json:"settings"`
+	LastRun    *time.Time  `json:"lastRun,omitempty"`
+	LastResult string      `json:"lastResult,omitempty"`
+	LastError  string      `json:"lastError,omitempty"`
+	Running    bool        `json:"running"`
+	Deferred   bool        `json:"deferred"`             // due, but outside its maintenance windows
+	NextWindow *time.Time  `json:"nextWindow,omitempty"` // start of the next window, if outside one
+}
+
+// SchedulerHandler runs the auto-update, prune and backup jobs every
+// interval, within their maintenance windows. Jobs that become due outside
+// their windows are deferred to the start of the next window.
+type SchedulerHandler struct {
+	client     *podman.Client
+	storage    storage.Storage
+	backup     *BackupHandler
+	eventStore *events.Store
+	logger     *logger.Logger
+	metrics    *selfMetrics  // times job runs, nil outside a server
+	builds     *BuildHandler // runs saved builds, nil outside a server
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewSchedulerHandler creates new scheduler handler
+func NewSchedulerHandler(client *podman.Client, store storage.Storage, backup *BackupHandler, eventStore *events.Store, appLogger *logger.Logger) *SchedulerHandler {
+	return &SchedulerHandler{
+		client:     client,
+		storage:    store,
+		backup:     backup,
+		eventStore: eventStore,
+		logger:     appLogger,
+		running:    make(map[string]bool),
+	}
+}
+
+// parseClock parses "15:04" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validate checks the days and times of the window
+func (mw MaintenanceWindow) validate() error {
+	for _, day := range mw.Days {
+		if !slices.Contains(weekdays, strings.ToLower(day)) {
+			return fmt.Errorf("invalid day %q", day)
+		}
+	}
+	if _, err := parseClock(mw.Start); err != nil {
+		return fmt.Errorf("invalid start %q", mw.Start)
+	}
+	if _, err := parseClock(mw.End); err != nil {
+		return fmt.Errorf("invalid end %q", mw.End)
+	}
+	return nil
+}
+
+// startsOn reports whether the window starts on the weekday
+func (mw MaintenanceWindow) startsOn(day time.Weekday) bool {
+	if len(mw.Days) == 0 {
+		return true
+	}
+	for _, d := range mw.Days {
+		if strings.EqualFold(d, weekdays[day]) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether t is within the window
+func (mw MaintenanceWindow) contains(t time.Time) bool {
+	start, _ := parseClock(mw.Start)
+	end, _ := parseClock(mw.End)
+	minute := t.Hour()*60 + t.Minute()
+	if end > start {
+		return mw.startsOn(t.Weekday()) && minute >= start && minute < end
+	}
+	// Past midnight: the part of today's window, or the rest of yesterday's
+	return (mw.startsOn(t.Weekday()) && minute >= start) ||
+		(mw.startsOn(t.AddDate(0, 0, -1).Weekday()) && minute < end)
+}
+
+// next returns the next start of the window after t
+func (mw MaintenanceWindow) next(t time.Time) time.Time {
+	start, _ := parseClock(mw.Start)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for d := 0; d <= 7; d++ {
+		day := midnight.AddDate(0, 0, d)
+		candidate := day.Add(time.Duration(start) * time.Minute)
+		if candidate.After(t) && mw.startsOn(day.Weekday()) {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+// inWindow reports whether t is within one of the windows, returning the
+// start of the next window if not
+func inWindow(windows []MaintenanceWindow, t time.Time) (bool, time.Time) {
+	if len(windows) == 0 {
+		return true, time.Time{}
+	}
+	var next time.Time
+	for _, mw := range windows {
+		if mw.contains(t) {
+			return true, time.Time{}
+		}
+		if n := mw.next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return false, next
+}
+
+// defaultJobSettings are the settings of a job that was never configured
+func defaultJobSettings(job string) JobSettings {
+	settings := JobSettings{IntervalHours: 24, Windows: []MaintenanceWindow{}}
+	if job == JobBackup {
+		settings.Keep = defaultBackupKeep
+	}
+	return settings
+}
+
+// state returns the stored state of a job
+func (h *SchedulerHandler) state(job string) (*jobState, error) {
+	state := &jobState{Settings: defaultJobSettings(job)}
+	if err := h.storage.GetJSON(schedulerBucket, job, state); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return state, nil
+}
+
+// status returns the status of a job at now
+func (h *SchedulerHandler) status(job string, now time.Time) (*JobStatus, error) {
+	state, err := h.state(job)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	running := h.running[job]
+	h.mu.Unlock()
+
+	status := &JobStatus{
+		Name:       job,
+		Settings:   state.Settings,
+		LastRun:    state.LastRun,
+		LastResult: state.LastResult,
+		LastError:  state.LastError,
+		Running:    running,
+	}
+	if open, next := inWindow(state.Settings.Windows, now); !open {
+		status.NextWindow = &next
+		status.Deferred = state.Settings.Enabled && state.due(now)
+	}
+	return status, nil
+}
+
+// backupDir returns the directory of the backup job, which also holds the
+// snapshots of volumes
+func (h *SchedulerHandler) backupDir() (string, error) {
+	if h.storage == nil {
+		return "", apierror.New(http.StatusServiceUnavailable, "Storage not available")
+	}
+	state, err := h.state(JobBackup)
+	if err != nil {
+		return "", err
+	}
+	if state.Settings.Dir == "" {
+		return "", apierror.New(http.StatusBadRequest, "Backup directory not configured")
+	}
+	return state.Settings.Dir, nil
+}
+
+// due reports whether the interval of the job has passed at now
+func (s *jobState) due(now time.Time) bool {
+	interval := time.Duration(s.Settings.IntervalHours) * time.Hour
+	return s.LastRun == nil || !now.Before(s.LastRun.Add(interval))
+}
+
+// RunDue runs the enabled jobs whose interval has passed if now is within
+// their maintenance windows; other due jobs stay deferred
+func (h *SchedulerHandler) RunDue(ctx context.Context, now time.Time) {
+	for _, job := range scheduledJobs {
+		state, err := h.state(job)
+		if err != nil {
+			h.logger.Error("Failed to read job state", "job", job, logger.KeyError, err)
+			continue
+		}
+		if !state.Settings.Enabled || !state.due(now) {
+			continue
+		}
+		if open, next := inWindow(state.Settings.Windows, now); !open {
+			h.logger.Debug("Job deferred to maintenance window", "job", job, "next", next)
+			continue
+		}
+		h.run(ctx, job, now)
+	}
+}
+
+// Run checks for due jobs every minute until ctx is cancelled
+func (h *SchedulerHandler) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.RunDue(ctx, now)
+		}
+	}
+}
+
+// run runs a job and stores its result, or returns an error if it is
+// already running
+func (h *SchedulerHandler) run(ctx context.Context, job string, now time.Time) (*jobState, error) {
+	h.mu.Lock()
+	if h.running[job] {
+		h.mu.Unlock()
+		return nil, apierror.New(http.StatusConflict, "Job is already running")
+	}
+	h.running[job] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.running, job)
+		h.mu.Unlock()
+	}()
+
+	state, err := h.state(job)
+	if err != nil {
+		return nil, err
+	}
+
+	var result string
+	h.metrics.timeJob(jobScheduledPrefix+job, func() bool {
+		switch job {
+		case JobAutoUpdate:
+			result, err = h.autoUpdate(ctx)
+		case JobPrune:
+			result, err = h.prune(ctx)
+		case JobBackup:
+			result, err = h.writeBackup(state.Settings, now)
+		case JobBuilds:
+			if h.builds == nil {
+				err = errors.New("builds not available")
+			} else {
+				result, err = h.builds.runScheduled(ctx)
+			}
+		}
+		return err == nil
+	})
+
+	state.LastRun = &now
+	state.LastResult = result
+	state.LastError = ""
+	if err != nil {
+		state.LastError = err.Error()
+		h.logger.Error("Scheduled job failed", "job", job, logger.KeyError, err)
+	} else {
+		h.logger.Info("Scheduled job finished", "job", job, "result", result)
+	}
+	if serr := h.storage.SetJSON(schedulerBucket, job, state); serr != nil {
+		h.logger.Error("Failed to save job state", "job", job, logger.KeyError, serr)
+	}
+	return state, nil
+}
+
+// prune removes stopped containers and dangling images
+func (h *SchedulerHandler) prune(ctx context.Context) (string, error) {
+	report, err := h.client.SystemPrune(ctx, false, false)
+	if err != nil {
+		h.eventStore.Add(events.EventSystemPrune, "system", "", false, err.Error())
+		return "", err
+	}
+	result := fmt.Sprintf("%d containers, %d images, %d bytes reclaimed", len(report.ContainerPruneReports), len(report.ImagePruneReports), report.ReclaimedSpace)
+	h.eventStore.Add(events.EventSystemPrune, "system", "", true, result)
+	return result, nil
+}
+
+// writeBackup writes a backup archive to the directory of the job and
+// removes the oldest archives over its limit
+func (h *SchedulerHandler) writeBackup(settings JobSettings, now time.Time) (string, error) {
+	if err := os.MkdirAll(settings.Dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(settings.Dir, backupFilename(now))
+	tmp, err := os.CreateTemp(settings.Dir, ".podmanview-backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = h.backup.writeArchive(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		h.eventStore.Add(events.EventStorageBackup, "system", "", false, err.Error())
+		return "", err
+	}
+	h.eventStore.Add(events.EventStorageBackup, "system", "", true, path)
+
+	archives, _ := filepath.Glob(filepath.Join(settings.Dir, "podmanview-backup-*.tar.gz"))
+	sort.Strings(archives) // timestamped names sort by age
+	keep := settings.Keep
+	if keep <= 0 {
+		keep = defaultBackupKeep
+	}
+	for len(archives) > keep {
+		os.Remove(archives[0])
+		archives = archives[1:]
+	}
+	return path, nil
+}
+
+// autoUpdate pulls the images of containers labeled with
+// io.containers.autoupdate=registry and recreates those whose image changed
+// from their spec (see buildContainerSpec), restarting them if they were
+// running. A container that can't be created again is restored.
+func (h *SchedulerHandler) autoUpdate(ctx context.Context) (string, error) {
+	containers, err := h.client.ListContainers(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var updated, failed []string
+	for _, c := range containers {
+		if c.Labels[autoUpdateLabel] != "registry" {
+			continue
+		}
+		name := firstOf(c.Names)
+		changed, err := h.updateContainer(ctx, c.ID)
+		if err != nil {
+			failed = append(failed, name)
+			h.eventStore.Add(events.EventContainerUpdate, "system", "", false, name+": "+err.Error())
+			continue
+		}
+		if changed {
+			updated = append(updated, name)
+			h.eventStore.Add(events.EventContainerUpdate, "system", "", true, name)
+		}
+	}
+
+	result := fmt.Sprintf("%d containers updated", len(updated))
+	if len(updated) > 0 {
+		result += ": " + strings.Join(updated, ", ")
+	}
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to update %s", strings.Join(failed, ", "))
+	}
+	return result, nil
+}
+
+// updateContainer pulls the image of a container and recreates it if the
+// image changed
+func (h *SchedulerHandler) updateContainer(ctx context.Context, id string) (bool, error) {
+	info, err := h.client.InspectContainer(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	current, err := h.client.InspectImage(ctx, info.Image)
+	if err != nil {
+		current = &podman.ImageInspect{}
+	}
+	// Stay on the platform of the image, an arm64 image run under
+	// emulation is not replaced by the amd64 one
+	if err := h.client.PullImagePlatform(ctx, info.ImageName, imagePlatform(current)); err != nil {
+		return false, err
+	}
+	latest, err := h.client.InspectImage(ctx, info.ImageName)
+	if err != nil {
+		return false, err
+	}
+	if latest.ID == info.Image {
+		return false, nil
+	}
+	return true, recreateContainer(ctx, h.client, info, current)
+}
+
+// recreateContainer creates a container again from its spec, which refers
+// to the image by name, so the container runs the image now tagged with
+// that name; it is started if it was running. current is the image the
+// container runs. A container that can't be created again is put back on
+// its old image.
+func recreateContainer(ctx context.Context, client *podman.Client, info *podman.ContainerInspect, current *podman.ImageInspect) error {
+	config, err := buildContainerSpec(info, current).createConfig()
+	if err != nil {
+		return err
+	}
+	return replaceContainer(ctx, client, info, current, config)
+}
+
+// replaceContainer removes a container and creates config in its place,
+// started if the container was running. A container that can't be created
+// is put back as it was, on its old image.
+func replaceContainer(ctx context.Context, client *podman.Client, info *podman.ContainerInspect, current *podman.ImageInspect, config *podman.ContainerCreateConfig) error {
+	running := info.State.Running
+	if running {
+		if err := client.StopContainer(ctx, info.ID); err != nil {
+			return err
+		}
+	}
+	if err := client.RemoveContainer(ctx, info.ID, false); err != nil {
+		if running {
+			client.StartContainer(ctx, info.ID)
+		}
+		return err
+	}
+
+	result, err := client.CreateContainer(ctx, config)
+	if err != nil {
+		// Put the container back on its old image
+		if old, serr := buildContainerSpec(info, current).createConfig(); serr == nil {
+			old.Image = info.Image
+			if restored, rerr := client.CreateContainer(ctx, old); rerr == nil && running {
+				client.StartContainer(ctx, restored.ID)
+			}
+		}
+		return err
+	}
+	if running {
+		return client.StartContainer(ctx, result.ID)
+	}
+	return nil
+}
+
+// List handles GET /api/system/jobs
+// Returns the settings and status of the scheduled jobs
+func (h *SchedulerHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	now := time.Now()
+	jobs := make([]*JobStatus, 0, len(scheduledJobs))
+	for _, job := range scheduledJobs {
+		status, err := h.status(job, now)
+		if err != nil {
+			writeErr(w, r, err, "Failed to read jobs")
+			return
+		}
+		jobs = append(jobs, status)
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// jobName returns the job of the request, or writes 404
+func jobName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	job := chi.URLParam(r, "job")
+	if !slices.Contains(scheduledJobs, job) {
+		writeError(w, r, http.StatusNotFound, "Job not found")
+		return "", false
+	}
+	return job, true
+}
+
+// Update handles PUT /api/system/jobs/{job}
+// Replaces the settings of a job: {"enabled": true, "intervalHours": 24,
+// "windows": [{"days": ["sat", "sun"], "start": "02:00", "end": "05:00"}]}
+func (h *SchedulerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+	job, ok := jobName(w, r)
+	if !ok {
+		return
+	}
+
+	var settings JobSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if settings.IntervalHours < 1 {
+		writeError(w, r, http.StatusBadRequest, "Interval must be at least 1 hour")
+		return
+	}
+	for _, mw := range settings.Windows {
+		if err := mw.validate(); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid maintenance window: "+err.Error())
+			return
+		}
+	}
+	if settings.Windows == nil {
+		settings.Windows = []MaintenanceWindow{}
+	}
+	if job == JobBackup {
+		if !filepath.IsAbs(settings.Dir) {
+			writeError(w, r, http.StatusBadRequest, "Backup directory must be an absolute path")
+			return
+		}
+		if settings.Keep <= 0 {
+			settings.Keep = defaultBackupKeep
+		}
+	} else {
+		settings.Dir, settings.Keep = "", 0
+	}
+
+	state, err := h.state(job)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read jobs")
+		return
+	}
+	state.Settings = settings
+	if err := h.storage.SetJSON(schedulerBucket, job, state); err != nil {
+		h.eventStore.Add(events.EventScheduleUpdate, user.Username, getClientIP(r), false, job)
+		writeErr(w, r, err, "Failed to save job")
+		return
+	}
+	h.eventStore.Add(events.EventScheduleUpdate, user.Username, getClientIP(r), true, job)
+
+	status, err := h.status(job, time.Now())
+	if err != nil {
+		writeErr(w, r, err, "Failed to read jobs")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// RunNow handles POST /api/system/jobs/{job}/run
+// Runs a job immediately, regardless of its maintenance windows
+func (h *SchedulerHandler) RunNow(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+	job, ok := jobName(w, r)
+	if !ok {
+		return
+	}
+	if state, err := h.state(job); err == nil && job == JobBackup && state.Settings.Dir == "" {
+		writeError(w, r, http.StatusBadRequest, "Backup directory must be an absolute path")
+		return
+	}
+
+	state, err := h.run(r.Context(), job, time.Now())
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":       job,
+		"lastRun":    state.LastRun,
+		"lastResult": state.LastResult,
+		"lastError":  state.LastError,
+	})
+}
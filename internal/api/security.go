@@ -0,0 +1,157 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"podmanview/internal/podman"
+)
+
+// Finding severities and the score each one deducts from 100
+const (
+	severityCritical = "critical"
+	severityHigh     = "high"
+	severityMedium   = "medium"
+	severityLow      = "low"
+)
+
+var severityPenalty = map[string]int{
+	severityCritical: 40,
+	severityHigh:     25,
+	severityMedium:   15,
+	severityLow:      5,
+}
+
+// dangerousCapabilities give a container control over the host
+var dangerousCapabilities = []string{"ALL", "SYS_ADMIN", "SYS_MODULE", "SYS_PTRACE", "SYS_RAWIO", "NET_ADMIN", "DAC_READ_SEARCH", "BPF"}
+
+// SecurityFinding is a failed best-practice check of a container
+type SecurityFinding struct {
+	Check       string `json:"check"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// ContainerSecurity is the security report of a container. Score is 100
+// minus the penalties of its findings, at least 0.
+type ContainerSecurity struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Image    string            `json:"image"`
+	Score    int               `json:"score"`
+	Findings []SecurityFinding `json:"findings"`
+}
+
+// SecurityReport is the security posture of all containers. Score is the
+// average score of the containers, 100 without containers.
+type SecurityReport struct {
+	Score      int                 `json:"score"`
+	Containers []ContainerSecurity `json:"containers"`
+}
+
+// Security handles GET /api/containers/security. Containers are evaluated
+// against best practices and listed with the lowest score first.
+func (h *ContainerHandler) Security(w http.ResponseWriter, r *http.Request) {
+	containers, err := h.client.ListContainers(r.Context())
+	if err != nil {
+		writeErr(w, r, err, "Failed to list containers")
+		return
+	}
+
+	report := SecurityReport{Score: 100, Containers: []ContainerSecurity{}}
+	total := 0
+	for _, c := range containers {
+		if c.IsInfra {
+			continue
+		}
+		info, err := h.client.InspectContainer(r.Context(), c.ID)
+		if err != nil {
+			continue // removed meanwhile
+		}
+		result := evaluateContainerSecurity(c, info)
+		total += result.Score
+		report.Containers = append(report.Containers, result)
+	}
+	if len(report.Containers) > 0 {
+		report.Score = int(math.Round(float64(total) / float64(len(report.Containers))))
+	}
+	slices.SortStableFunc(report.Containers, func(a, b ContainerSecurity) int { return a.Score - b.Score })
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// evaluateContainerSecurity checks a container against best practices
+func evaluateContainerSecurity(c podman.Container, info *podman.ContainerInspect) ContainerSecurity {
+	image := c.Image
+	if image == "" {
+		image = info.ImageName
+	}
+	result := ContainerSecurity{
+		ID:       c.ID,
+		Name:     strings.TrimPrefix(firstOf(c.Names), "/"),
+		Image:    image,
+		Findings: []SecurityFinding{},
+	}
+	add := func(check, severity, message, remediation string) {
+		result.Findings = append(result.Findings, SecurityFinding{check, severity, message, remediation})
+	}
+
+	host := info.HostConfig
+	if host.Privileged {
+		add("privileged", severityCritical,
+			"Runs in privileged mode with all capabilities and access to host devices",
+			"Remove --privileged and add only the capabilities the container needs with --cap-add")
+	}
+	if len(host.CapAdd) > 0 {
+		severity := severityMedium
+		for _, capability := range host.CapAdd {
+			if slices.Contains(dangerousCapabilities, strings.TrimPrefix(strings.ToUpper(capability), "CAP_")) {
+				severity = severityHigh
+			}
+		}
+		add("capabilities", severity,
+			"Adds capabilities: "+strings.Join(host.CapAdd, ", "),
+			"Drop capabilities the container does not need; prefer narrow ones over SYS_ADMIN or NET_ADMIN")
+	}
+	if host.NetworkMode == "host" {
+		add("host_network", severityHigh,
+			"Shares the network namespace of the host",
+			"Use a bridge network and publish only the needed ports with -p")
+	}
+	if host.Memory <= 0 {
+		add("memory_limit", severityMedium,
+			"Has no memory limit and can exhaust the memory of the host",
+			"Set a limit with --memory, e.g. --memory 512m")
+	}
+	if isLatestImage(image) {
+		add("latest_tag", severityLow,
+			"Uses the :latest tag, so the running version is unknown and changes on pull",
+			"Pin a version tag or digest, e.g. nginx:1.27")
+	}
+	if !host.ReadonlyRootfs {
+		add("writable_rootfs", severityLow,
+			"Root filesystem is writable",
+			"Run with --read-only and mount writable paths as volumes or with --tmpfs")
+	}
+
+	result.Score = 100
+	for _, f := range result.Findings {
+		result.Score -= severityPenalty[f.Severity]
+	}
+	result.Score = max(result.Score, 0)
+	return result
+}
+
+// isLatestImage reports whether an image reference uses the latest tag,
+// explicitly or by having no tag and no digest
+func isLatestImage(image string) bool {
+	if image == "" || strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, ok := strings.Cut(name, ":")
+	return !ok || tag == "latest"
+}
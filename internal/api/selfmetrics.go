@@ -0,0 +1,391 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/storage"
+)
+
+// Background jobs timed by selfMetrics
+const (
+	jobEventPruning    = "event_pruning"
+	jobStorageMaintain = "storage_maintenance"
+	jobDiskUsageCheck  = "disk_usage_check"
+	jobScheduledPrefix = "scheduled_" // + name of the scheduled job
+)
+
+// selfMetricsNamespace prefixes the names of the Prometheus metrics
+const selfMetricsNamespace = "podmanview"
+
+// latencyBuckets are the upper bounds of the request latency histogram, in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// timing accumulates the durations of requests, plugin handlers or jobs
+type timing struct {
+	count   uint64
+	errors  uint64
+	total   time.Duration
+	max     time.Duration
+	last    time.Duration
+	lastAt  time.Time
+	buckets []uint64 // cumulative counts per latencyBuckets, only for routes
+}
+
+func (t *timing) observe(d time.Duration, failed bool) {
+	t.count++
+	if failed {
+		t.errors++
+	}
+	t.total += d
+	if d > t.max {
+		t.max = d
+	}
+	t.last = d
+	t.lastAt = time.Now()
+	if t.buckets != nil {
+		for i, le := range latencyBuckets {
+			if d.Seconds() <= le {
+				t.buckets[i]++
+			}
+		}
+	}
+}
+
+// TimingStats summarizes the durations of an API route, plugin handler or
+// background job
+type TimingStats struct {
+	Name    string     `json:"name"`             // route pattern, plugin or job
+	Method  string     `json:"method,omitempty"` // routes only
+	Count   uint64     `json:"count"`
+	Errors  uint64     `json:"errors"` // 5xx responses or failed jobs
+	AvgMs   float64    `json:"avgMs"`
+	MaxMs   float64    `json:"maxMs"`
+	LastMs  float64    `json:"lastMs"`
+	LastRun *time.Time `json:"lastRun,omitempty"` // jobs only
+}
+
+// SelfStats are the internal metrics of PodmanView returned by
+// GET /api/system/self
+type SelfStats struct {
+	Version   string    `json:"version"`
+	StartedAt time.Time `json:"startedAt"`
+	Uptime    int64     `json:"uptime"` // seconds
+	Runtime   struct {
+		Goroutines int    `json:"goroutines"`
+		HeapBytes  uint64 `json:"heapBytes"`
+		SysBytes   uint64 `json:"sysBytes"`
+		GCRuns     uint32 `json:"gcRuns"`
+	} `json:"runtime"`
+	Requests    uint64 `json:"requests"`
+	Connections struct {
+		WebSockets int    `json:"websockets"`
+		Streams    int    `json:"streams"` // server-sent event streams
+		Total      uint64 `json:"total"`   // since start
+	} `json:"connections"`
+	Storage *storage.Stats `json:"storage"` // null without storage
+	Routes  []TimingStats  `json:"routes"`
+	Plugins []TimingStats  `json:"plugins"`
+	Jobs    []TimingStats  `json:"jobs"`
+}
+
+// routeKey identifies a route in selfMetrics
+type routeKey struct {
+	method, pattern string
+}
+
+// selfMetrics collects the internal metrics of the server: API requests
+// by route, plugin handler durations and background job runtimes.
+// Connection counts come from the drainer and storage sizes from the
+// storage at read time. Handlers created without a server have a nil
+// selfMetrics, which times nothing.
+type selfMetrics struct {
+	started time.Time
+
+	mu      sync.Mutex
+	routes  map[routeKey]*timing
+	plugins map[string]*timing
+	jobs    map[string]*timing
+}
+
+func newSelfMetrics() *selfMetrics {
+	return &selfMetrics{
+		started: time.Now(),
+		routes:  make(map[routeKey]*timing),
+		plugins: make(map[string]*timing),
+		jobs:    make(map[string]*timing),
+	}
+}
+
+// observe records a duration under name, creating its timing on first use
+func (m *selfMetrics) observe(set map[string]*timing, name string, d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := set[name]
+	if !ok {
+		t = &timing{}
+		set[name] = t
+	}
+	t.observe(d, failed)
+}
+
+// timeJob runs a background job and records its runtime. job reports
+// whether it succeeded.
+func (m *selfMetrics) timeJob(name string, job func() bool) {
+	if m == nil {
+		job()
+		return
+	}
+	start := time.Now()
+	ok := job()
+	m.observe(m.jobs, name, time.Since(start), !ok)
+}
+
+// timePlugin wraps the handler of a plugin route to record its durations
+func (m *selfMetrics) timePlugin(plugin string, next http.HandlerFunc) http.HandlerFunc {
+	if m == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next(ww, r)
+		m.observe(m.plugins, plugin, time.Since(start), ww.Status() >= http.StatusInternalServerError)
+	}
+}
+
+// countRequests records the count, status and latency of every request by
+// its route pattern, so paths with IDs are counted under one route
+func (m *selfMetrics) countRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		pattern := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			pattern = rctx.RoutePattern()
+		}
+		key := routeKey{method: r.Method, pattern: pattern}
+		d := time.Since(start)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		t, ok := m.routes[key]
+		if !ok {
+			t = &timing{buckets: make([]uint64, len(latencyBuckets))}
+			m.routes[key] = t
+		}
+		t.observe(d, ww.Status() >= http.StatusInternalServerError)
+	})
+}
+
+// timingStats converts timings to stats sorted by name
+func timingStats(set map[string]*timing, withLastRun bool) []TimingStats {
+	stats := make([]TimingStats, 0, len(set))
+	for name, t := range set {
+		s := t.stats(name)
+		if withLastRun {
+			lastAt := t.lastAt
+			s.LastRun = &lastAt
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+func (t *timing) stats(name string) TimingStats {
+	s := TimingStats{Name: name, Count: t.count, Errors: t.errors, MaxMs: millis(t.max), LastMs: millis(t.last)}
+	if t.count > 0 {
+		s.AvgMs = millis(t.total / time.Duration(t.count))
+	}
+	return s
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// selfStats returns the current internal metrics of the server
+func (s *Server) selfStats() *SelfStats {
+	m := s.metrics
+	stats := &SelfStats{Version: s.version, StartedAt: m.started, Uptime: int64(time.Since(m.started).Seconds())}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats.Runtime.Goroutines = runtime.NumGoroutine()
+	stats.Runtime.HeapBytes = mem.HeapAlloc
+	stats.Runtime.SysBytes = mem.Sys
+	stats.Runtime.GCRuns = mem.NumGC
+
+	stats.Connections.Streams, stats.Connections.WebSockets, stats.Connections.Total = s.drainer.counts()
+
+	if s.storage != nil {
+		if st, err := s.storage.Stats(); err == nil {
+			stats.Storage = st
+		} else {
+			s.logger.Warn("Failed to read storage stats", "error", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats.Routes = make([]TimingStats, 0, len(m.routes))
+	for key, t := range m.routes {
+		route := t.stats(key.pattern)
+		route.Method = key.method
+		stats.Routes = append(stats.Routes, route)
+		stats.Requests += t.count
+	}
+	sort.Slice(stats.Routes, func(i, j int) bool {
+		if stats.Routes[i].Count != stats.Routes[j].Count {
+			return stats.Routes[i].Count > stats.Routes[j].Count
+		}
+		return stats.Routes[i].Name+stats.Routes[i].Method < stats.Routes[j].Name+stats.Routes[j].Method
+	})
+	stats.Plugins = timingStats(m.plugins, false)
+	stats.Jobs = timingStats(m.jobs, true)
+	return stats
+}
+
+// Self handles GET /api/system/self (admin)
+// Returns the internal metrics of PodmanView: requests by route, open
+// WebSocket and event stream connections, storage sizes, plugin handler
+// durations and background job runtimes.
+func (s *Server) Self(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.selfStats())
+}
+
+// Metrics handles GET /api/metrics (admin)
+// Returns the internal metrics in the Prometheus text format
+func (s *Server) Metrics(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	stats := s.selfStats()
+	var b bytes.Buffer
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", selfMetricsNamespace, name, help, selfMetricsNamespace, name, kind)
+	}
+	sample := func(name, labels string, value interface{}) {
+		if labels != "" {
+			labels = "{" + labels + "}"
+		}
+		fmt.Fprintf(&b, "%s_%s%s %v\n", selfMetricsNamespace, name, labels, value)
+	}
+
+	metric("build_info", "gauge", "Version of PodmanView.")
+	sample("build_info", "version="+promLabel(stats.Version), 1)
+	metric("uptime_seconds", "gauge", "Seconds since the server started.")
+	sample("uptime_seconds", "", stats.Uptime)
+	metric("goroutines", "gauge", "Number of goroutines.")
+	sample("goroutines", "", stats.Runtime.Goroutines)
+	metric("heap_bytes", "gauge", "Bytes of allocated heap objects.")
+	sample("heap_bytes", "", stats.Runtime.HeapBytes)
+
+	metric("websocket_connections", "gauge", "Open WebSocket connections (terminals).")
+	sample("websocket_connections", "", stats.Connections.WebSockets)
+	metric("stream_connections", "gauge", "Open server-sent event streams.")
+	sample("stream_connections", "", stats.Connections.Streams)
+	metric("connections_total", "counter", "Long-lived connections opened since start.")
+	sample("connections_total", "", stats.Connections.Total)
+
+	if stats.Storage != nil {
+		metric("storage_file_bytes", "gauge", "Size of the database file.")
+		sample("storage_file_bytes", "", stats.Storage.FileSize)
+		metric("storage_bucket_bytes", "gauge", "Bytes used by the keys and values of a bucket.")
+		for _, bucket := range stats.Storage.Buckets {
+			sample("storage_bucket_bytes", "bucket="+promLabel(bucket.Name), bucket.Size)
+		}
+		metric("storage_bucket_keys", "gauge", "Keys in a bucket.")
+		for _, bucket := range stats.Storage.Buckets {
+			sample("storage_bucket_keys", "bucket="+promLabel(bucket.Name), bucket.Keys)
+		}
+	}
+
+	s.metrics.mu.Lock()
+	keys := make([]routeKey, 0, len(s.metrics.routes))
+	for key := range s.metrics.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].pattern+keys[i].method < keys[j].pattern+keys[j].method
+	})
+	metric("http_requests_total", "counter", "API requests by route.")
+	for _, key := range keys {
+		sample("http_requests_total", routeLabels(key), s.metrics.routes[key].count)
+	}
+	metric("http_request_errors_total", "counter", "API requests by route answered with a 5xx status.")
+	for _, key := range keys {
+		sample("http_request_errors_total", routeLabels(key), s.metrics.routes[key].errors)
+	}
+	metric("http_request_duration_seconds", "histogram", "API request latencies by route.")
+	for _, key := range keys {
+		t := s.metrics.routes[key]
+		labels := routeLabels(key)
+		for i, le := range latencyBuckets {
+			sample("http_request_duration_seconds_bucket", labels+fmt.Sprintf(`,le="%g"`, le), t.buckets[i])
+		}
+		sample("http_request_duration_seconds_bucket", labels+`,le="+Inf"`, t.count)
+		sample("http_request_duration_seconds_sum", labels, t.total.Seconds())
+		sample("http_request_duration_seconds_count", labels, t.count)
+	}
+	metric("plugin_handler_duration_seconds", "summary", "Durations of plugin route handlers.")
+	for _, p := range stats.Plugins {
+		t := s.metrics.plugins[p.Name]
+		sample("plugin_handler_duration_seconds_sum", "plugin="+promLabel(p.Name), t.total.Seconds())
+		sample("plugin_handler_duration_seconds_count", "plugin="+promLabel(p.Name), t.count)
+	}
+	s.metrics.mu.Unlock()
+
+	metric("job_runs_total", "counter", "Runs of background jobs.")
+	for _, job := range stats.Jobs {
+		sample("job_runs_total", "job="+promLabel(job.Name), job.Count)
+	}
+	metric("job_failures_total", "counter", "Failed runs of background jobs.")
+	for _, job := range stats.Jobs {
+		sample("job_failures_total", "job="+promLabel(job.Name), job.Errors)
+	}
+	metric("job_last_duration_seconds", "gauge", "Runtime of the last run of a background job.")
+	for _, job := range stats.Jobs {
+		sample("job_last_duration_seconds", "job="+promLabel(job.Name), job.LastMs/1000)
+	}
+	metric("job_last_run_timestamp_seconds", "gauge", "Unix time of the last run of a background job.")
+	for _, job := range stats.Jobs {
+		sample("job_last_run_timestamp_seconds", "job="+promLabel(job.Name), job.LastRun.Unix())
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+// routeLabels returns the Prometheus labels of a route
+func routeLabels(key routeKey) string {
+	return "method=" + promLabel(key.method) + ",route=" + promLabel(key.pattern)
+}
+
+// promLabel quotes a Prometheus label value
+func promLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,370 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+	"podmanview/web/templates"
+)
+
+const (
+	// shareBucket is the storage namespace of share links, under
+	// shareLinkPrefix and their ID, and of the key signing them, under
+	// shareKeyName. It is encrypted at rest (storage.DefaultSensitiveBuckets).
+	shareBucket     = "shares"
+	shareLinkPrefix = "link/"
+	shareKeyName    = "key"
+
+	defaultShareExpiry = 24 * time.Hour
+	maxShareExpiry     = 30 * 24 * time.Hour
+
+	// Log lines shown on shared pages
+	defaultShareLogLines = 100
+	maxShareLogLines     = 500
+)
+
+var (
+	errShareNotFound = apierror.New(http.StatusNotFound, "Share link not found or expired")
+	errShareExpiry   = apierror.New(http.StatusBadRequest, "Share links can expire in 1 to "+strconv.Itoa(int(maxShareExpiry.Hours()))+" hours")
+	errShareLogs     = apierror.New(http.StatusForbidden, "Logs are not shared")
+)
+
+// ShareLink grants anonymous read-only access to the status, stats and,
+// with Logs, the logs of a container until it expires. The container is
+// found by name, so the link keeps working when it is recreated.
+type ShareLink struct {
+	ID        string    `json:"id"`
+	Container string    `json:"container"` // container name
+	Logs      bool      `json:"logs"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// URL is the path of the shared page, with the signed token. It is only
+	// returned when the link is created.
+	URL string `json:"url,omitempty"`
+}
+
+// ShareRequest represents the request body for sharing a container
+type ShareRequest struct {
+	ExpiresIn int  `json:"expiresIn"` // hours, 24 by default
+	Logs      bool `json:"logs"`
+}
+
+// SharedContainer is a container as shown on its shared page
+type SharedContainer struct {
+	Name      string                 `json:"name"`
+	Image     string                 `json:"image"`
+	State     string                 `json:"state"`
+	Health    string                 `json:"health,omitempty"`
+	StartedAt string                 `json:"startedAt,omitempty"`
+	ExitCode  int                    `json:"exitCode"`
+	Stats     *podman.ContainerStats `json:"stats"` // null when not running
+	Logs      bool                   `json:"logs"`
+	ExpiresAt time.Time              `json:"expiresAt"`
+}
+
+// ShareHandler manages share links and serves the shared pages
+type ShareHandler struct {
+	client     *podman.Client
+	storage    storage.Storage
+	config     *config.Config
+	eventStore *events.Store
+
+	// mu serializes the creation of the signing key
+	mu sync.Mutex
+}
+
+// NewShareHandler creates new share link handler
+func NewShareHandler(client *podman.Client, store storage.Storage, cfg *config.Config, eventStore *events.Store) *ShareHandler {
+	return &ShareHandler{client: client, storage: store, config: cfg, eventStore: eventStore}
+}
+
+// key returns the key signing share links, generated the first time
+func (h *ShareHandler) key() ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key, err := h.storage.GetString(shareBucket, shareKeyName)
+	if errors.Is(err, storage.ErrNotFound) {
+		if key, err = randomHex(32); err == nil {
+			err = h.storage.SetString(shareBucket, shareKeyName, key)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(key), nil
+}
+
+// sign returns the signature of a link, binding its ID to the container,
+// the logs permission and the expiry
+func (h *ShareHandler) sign(link *ShareLink) (string, error) {
+	key, err := h.key()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(link.ID + "\n" + link.Container + "\n" + strconv.FormatBool(link.Logs) + "\n" + strconv.FormatInt(link.ExpiresAt.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// resolve returns the link of a token ("<id>.<signature>") if it is
+// valid, not expired and not revoked
+func (h *ShareHandler) resolve(token string) (*ShareLink, error) {
+	if h.storage == nil {
+		return nil, apierror.New(http.StatusServiceUnavailable, "Storage not available")
+	}
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok || id == "" {
+		return nil, errShareNotFound
+	}
+	var link ShareLink
+	if err := h.storage.GetJSON(shareBucket, shareLinkPrefix+id, &link); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errShareNotFound
+		}
+		return nil, err
+	}
+	want, err := h.sign(&link)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(want)) || !time.Now().Before(link.ExpiresAt) {
+		return nil, errShareNotFound
+	}
+	return &link, nil
+}
+
+// Create handles POST /api/containers/{id}/share (admin)
+// Creates a link to a read-only page with the status, stats and, with
+// logs, the logs of the container, valid for expiresIn hours
+func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	var req ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	expiry := defaultShareExpiry
+	if req.ExpiresIn != 0 {
+		expiry = time.Duration(req.ExpiresIn) * time.Hour
+		if req.ExpiresIn < 0 || expiry > maxShareExpiry {
+			writeErr(w, r, errShareExpiry, "")
+			return
+		}
+	}
+
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		writeErr(w, r, err, "Failed to create share link")
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	link := &ShareLink{
+		ID:        id,
+		Container: strings.TrimPrefix(info.Name, "/"),
+		Logs:      req.Logs,
+		CreatedAt: now,
+		CreatedBy: user.Username,
+		ExpiresAt: now.Add(expiry),
+	}
+	signature, err := h.sign(link)
+	if err != nil {
+		writeErr(w, r, err, "Failed to create share link")
+		return
+	}
+	details := link.Container + ": link " + id + " created, expires " + link.ExpiresAt.Format(time.RFC3339)
+	if link.Logs {
+		details += ", with logs"
+	}
+	if err := h.storage.SetJSONWithTTL(shareBucket, shareLinkPrefix+id, link, expiry); err != nil {
+		h.eventStore.Add(events.EventShareUpdate, user.Username, getClientIP(r), false, link.Container)
+		writeErr(w, r, err, "Failed to save share link")
+		return
+	}
+
+	h.eventStore.Add(events.EventShareUpdate, user.Username, getClientIP(r), true, details)
+	link.URL = h.config.BasePath() + "/shared/" + id + "." + signature
+	writeJSON(w, http.StatusCreated, link)
+}
+
+// List handles GET /api/shares (admin)
+// Returns the share links that haven't expired, newest first
+func (h *ShareHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	links := []ShareLink{}
+	if h.storage != nil {
+		data, err := h.storage.ListPrefix(shareBucket, shareLinkPrefix)
+		if err != nil {
+			writeErr(w, r, err, "Failed to read share links")
+			return
+		}
+		now := time.Now()
+		for _, value := range data {
+			var link ShareLink
+			if json.Unmarshal(value, &link) == nil && now.Before(link.ExpiresAt) {
+				links = append(links, link)
+			}
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].CreatedAt.After(links[j].CreatedAt)
+		}
+		return links[i].ID < links[j].ID
+	})
+	writeJSON(w, http.StatusOK, links)
+}
+
+// Revoke handles DELETE /api/shares/{id} (admin)
+func (h *ShareHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	var link ShareLink
+	if err := h.storage.GetJSON(shareBucket, shareLinkPrefix+id, &link); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			err = errShareNotFound
+		}
+		writeErr(w, r, err, "Failed to read share link")
+		return
+	}
+	if err := h.storage.Delete(shareBucket, shareLinkPrefix+id); err != nil {
+		writeErr(w, r, err, "Failed to revoke share link")
+		return
+	}
+
+	h.eventStore.Add(events.EventShareUpdate, user.Username, getClientIP(r), true, link.Container+": link "+id+" revoked")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// Page handles GET /shared/{token} (public)
+// Serves the shared page, which loads the container from
+// GET /api/share/{token}
+func (h *ShareHandler) Page(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.resolve(chi.URLParam(r, "token")); err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+		html := strings.ReplaceAll(string(templates.ShareHTML), "{{BASE_PATH}}", basePath)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer") // keep the token out of Referer headers
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(html))
+	}
+}
+
+// Container handles GET /api/share/{token} (public)
+// Returns the status and stats of the shared container
+func (h *ShareHandler) Container(w http.ResponseWriter, r *http.Request) {
+	link, err := h.resolve(chi.URLParam(r, "token"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	info, err := h.client.InspectContainer(r.Context(), link.Container)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	result := SharedContainer{
+		Name:      link.Container,
+		Image:     info.ImageName,
+		State:     info.State.Status,
+		StartedAt: info.State.StartedAt,
+		ExitCode:  info.State.ExitCode,
+		Logs:      link.Logs,
+		ExpiresAt: link.ExpiresAt,
+	}
+	if info.State.Health != nil {
+		result.Health = info.State.Health.Status
+	}
+	if info.State.Running {
+		stats, _ := h.client.GetContainersStats(r.Context())
+		for i := range stats {
+			if stats[i].ContainerID == info.ID {
+				result.Stats = &stats[i]
+				break
+			}
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Logs handles GET /api/share/{token}/logs?tail=100 (public)
+// Returns the last log lines of the shared container if the link shares
+// its logs
+func (h *ShareHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	link, err := h.resolve(chi.URLParam(r, "token"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if !link.Logs {
+		writeErr(w, r, errShareLogs, "")
+		return
+	}
+
+	tail := defaultShareLogLines
+	if t := r.URL.Query().Get("tail"); t != "" {
+		if parsed, err := strconv.Atoi(t); err == nil && parsed > 0 {
+			tail = min(parsed, maxShareLogLines)
+		}
+	}
+	lines, err := containerLogLines(r.Context(), h.client, link.Container, tail)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, LogsResponse{Lines: lines})
+}
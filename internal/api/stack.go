@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+const (
+	// defaultStackTimeout is how long a container waits for its
+	// requirements to be ready when the request doesn't set a timeout
+	defaultStackTimeout = 120 * time.Second
+	// maxStackTimeout limits the timeout of a stack request
+	maxStackTimeout = 600 * time.Second
+	// stackPollInterval is how often requirements are inspected while waiting
+	stackPollInterval = 250 * time.Millisecond
+)
+
+// StackRequest represents the request body for creating a stack of
+// containers. The containers are created and started in dependency order,
+// whatever their order in the request.
+type StackRequest struct {
+	// Name is the stack, whose environment file (see StackEnvHandler) is
+	// set in every container; variables of a container take precedence
+	Name       string           `json:"name"`
+	Containers []StackContainer `json:"containers"`
+	Start      bool             `json:"start"`
+	// Timeout is how long each container waits for its requirements, in
+	// seconds
+	Timeout int `json:"timeout"`
+}
+
+// StackContainer is a container of a stack. Requires names containers that
+// must be ready before this one starts: running (and healthy, if they have
+// a health check), or exited with code 0 for init containers. Requirements
+// outside the stack are left to Podman, which starts them first. After only
+// orders the start, without waiting. The start field is ignored; the
+// stack's applies.
+type StackContainer struct {
+	CreateContainerRequest
+	After string `json:"after"`
+	// Init marks a container that runs to completion before the containers
+	// requiring it start, e.g. database migrations
+	Init bool `json:"init"`
+}
+
+// StackResult is the outcome for a container of a stack
+type StackResult struct {
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"` // pending, created, started or failed; restarted or skipped in rolling restarts; unchanged or removed when scaling
+}
+
+// CreateStack handles POST /api/containers/stack
+// On failure the containers created so far are kept and listed in the
+// details of the error.
+func (h *ContainerHandler) CreateStack(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req StackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	order, err := stackOrder(req.Containers)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	env, err := h.stackEnv.env(req.Name)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read environment file")
+		return
+	}
+
+	timeout := req.waitTimeout()
+	results := make([]StackResult, len(order))
+	byName := make(map[string]*StackContainer, len(order))
+	for i, c := range order {
+		results[i] = StackResult{Name: c.Name, Status: "pending"}
+		byName[c.Name] = c
+	}
+	fail := func(i int, err *apierror.Error) {
+		results[i].Status = "failed"
+		writeErr(w, r, err.WithDetails(map[string]interface{}{"containers": results}), "")
+	}
+
+	for i, c := range order {
+		result, err := h.client.CreateContainer(r.Context(), c.stackConfig(byName, env))
+		if err != nil {
+			h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), false, c.Name)
+			fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to create container: "+c.Name+": "+err.Error()))
+			return
+		}
+		h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), true, shortID(result.ID))
+		results[i].ID = result.ID
+		results[i].Status = "created"
+	}
+
+	if req.Start {
+		for i, c := range order {
+			for _, name := range parseNameList(c.Requires) {
+				if dep, ok := byName[name]; ok {
+					if err := h.waitReady(r.Context(), dep.Name, dep.Init, timeout); err != nil {
+						fail(i, err)
+						return
+					}
+				}
+			}
+			if err := h.client.StartContainer(r.Context(), results[i].ID); err != nil {
+				h.eventStore.Add(events.EventContainerStart, user.Username, getClientIP(r), false, shortID(results[i].ID))
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to start container: "+c.Name+": "+err.Error()))
+				return
+			}
+			h.eventStore.Add(events.EventContainerStart, user.Username, getClientIP(r), true, shortID(results[i].ID))
+			results[i].Status = "started"
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"containers": results})
+}
+
+// waitTimeout returns how long a container waits for its requirements
+func (req *StackRequest) waitTimeout() time.Duration {
+	if req.Timeout > 0 {
+		return min(time.Duration(req.Timeout)*time.Second, maxStackTimeout)
+	}
+	return defaultStackTimeout
+}
+
+// stackConfig builds the Podman create config of a container of a stack
+// whose containers are byName, with the variables of its environment file
+func (c *StackContainer) stackConfig(byName map[string]*StackContainer, env map[string]string) *podman.ContainerCreateConfig {
+	config := c.createConfig()
+	if len(env) > 0 {
+		merged := maps.Clone(env)
+		maps.Copy(merged, config.Env)
+		config.Env = merged
+	}
+	// Podman only starts a container whose dependencies are running, so
+	// init containers, which exit, are waited for here instead
+	config.Dependencies = slices.DeleteFunc(config.Dependencies, func(name string) bool {
+		dep, ok := byName[name]
+		return ok && dep.Init
+	})
+	return config
+}
+
+// waitReady waits until a started container is ready for the containers
+// requiring it: running and healthy, or exited with code 0 for init
+// containers
+func (h *ContainerHandler) waitReady(ctx context.Context, name string, init bool, timeout time.Duration) *apierror.Error {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := h.client.InspectContainer(ctx, name)
+		if err != nil {
+			return apierror.Wrap(err, apierror.Status(err), "Failed to inspect container: "+name+": "+err.Error())
+		}
+
+		state := info.State
+		running := state.Running || state.Status == "running"
+		switch {
+		case init && !running && state.ExitCode == 0:
+			return nil
+		case init && !running:
+			return apierror.New(http.StatusFailedDependency, fmt.Sprintf("Init container failed: %s exited with code %d", name, state.ExitCode)).
+				WithCode("dependency_failed")
+		case init:
+			// Still running
+		case !running:
+			return apierror.New(http.StatusFailedDependency, "Required container is not running: "+name).
+				WithCode("dependency_failed")
+		case state.Health == nil || state.Health.Status == "" || state.Health.Status == "healthy":
+			return nil
+		case state.Health.Status == "unhealthy":
+			return apierror.New(http.StatusFailedDependency, "Required container is unhealthy: "+name).
+				WithCode("dependency_failed")
+		}
+
+		if time.Now().After(deadline) {
+			return apierror.New(http.StatusGatewayTimeout, "Timed out waiting for container: "+name)
+		}
+		select {
+		case <-ctx.Done():
+			return apierror.Wrap(ctx.Err(), apierror.Status(ctx.Err()), "Request canceled")
+		case <-time.After(stackPollInterval):
+		}
+	}
+}
+
+// stackOrder validates the containers of a stack and sorts them so every
+// container comes after the stack containers it requires or follows. The
+// request order is kept where dependencies allow it.
+func stackOrder(containers []StackContainer) ([]*StackContainer, error) {
+	if len(containers) == 0 {
+		return nil, apierror.New(http.StatusBadRequest, "Containers are required")
+	}
+
+	index := make(map[string]int, len(containers))
+	for i, c := range containers {
+		switch {
+		case c.Image == "":
+			return nil, apierror.New(http.StatusBadRequest, "Image is required")
+		case c.Name == "":
+			return nil, apierror.New(http.StatusBadRequest, "Name is required")
+		}
+		if _, ok := index[c.Name]; ok {
+			return nil, apierror.New(http.StatusBadRequest, "Duplicate container name: "+c.Name)
+		}
+		index[c.Name] = i
+	}
+
+	// deps[i] are the stack containers that must start before containers[i]
+	deps := make([][]int, len(containers))
+	for i, c := range containers {
+		for _, name := range parseNameList(c.Requires) {
+			if j, ok := index[name]; ok {
+				deps[i] = append(deps[i], j)
+			}
+		}
+		for _, name := range parseNameList(c.After) {
+			j, ok := index[name]
+			if !ok {
+				return nil, apierror.New(http.StatusBadRequest, "Unknown container in after: "+name)
+			}
+			deps[i] = append(deps[i], j)
+		}
+	}
+
+	order := make([]*StackContainer, 0, len(containers))
+	done := make([]bool, len(containers))
+	for len(order) < len(containers) {
+		progress := false
+		for i := range containers {
+			if done[i] || slices.ContainsFunc(deps[i], func(j int) bool { return !done[j] }) {
+				continue
+			}
+			done[i] = true
+			order = append(order, &containers[i])
+			progress = true
+		}
+		if !progress {
+			var cycle []string
+			for i, c := range containers {
+				if !done[i] {
+					cycle = append(cycle, c.Name)
+				}
+			}
+			return nil, apierror.New(http.StatusBadRequest, "Dependency cycle between containers: "+strings.Join(cycle, ", "))
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/storage"
+)
+
+const (
+	// stackEnvBucket is the storage namespace of the environment files of
+	// stacks, by stack name. They hold passwords and tokens, so the bucket
+	// is encrypted at rest (storage.DefaultSensitiveBuckets).
+	stackEnvBucket = "stackenv"
+
+	// maxStackEnvSize limits the content of an environment file
+	maxStackEnvSize = 64 << 10
+)
+
+// stackNameRe matches the names of stacks
+var stackNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+var (
+	errStackName        = apierror.New(http.StatusBadRequest, "Invalid stack name, use up to 64 letters, digits, '.', '_' and '-'")
+	errStackEnvNotFound = apierror.New(http.StatusNotFound, "Environment file not found")
+	errStackEnvTooLarge = apierror.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("Environment files can be up to %d KB", maxStackEnvSize>>10))
+)
+
+// StackEnvFile is the environment file of a stack. Values of secret
+// variables (PODMANVIEW_SECRET_ENV) are masked, in Vars and in Content.
+type StackEnvFile struct {
+	Stack string   `json:"stack"`
+	Vars  []EnvVar `json:"vars"`
+	// Content is the file in .env format, to edit and save back
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UpdatedBy string    `json:"updatedBy"`
+}
+
+// storedStackEnv is an environment file as stored
+type storedStackEnv struct {
+	Env       map[string]string `json:"env"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	UpdatedBy string            `json:"updatedBy"`
+}
+
+// StackEnvHandler stores the environment files of stacks, whose variables
+// are set in every container of the stack when it is deployed
+type StackEnvHandler struct {
+	storage    storage.Storage
+	config     *config.Config
+	eventStore *events.Store
+
+	// mu serializes updates, which keep masked values
+	mu sync.Mutex
+}
+
+// NewStackEnvHandler creates new stack environment file handler
+func NewStackEnvHandler(store storage.Storage, cfg *config.Config, eventStore *events.Store) *StackEnvHandler {
+	return &StackEnvHandler{storage: store, config: cfg, eventStore: eventStore}
+}
+
+// available checks that storage is available
+func (h *StackEnvHandler) available(w http.ResponseWriter, r *http.Request) bool {
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return false
+	}
+	return true
+}
+
+// stackName returns the stack of the request, or writes 400
+func stackName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := chi.URLParam(r, "stack")
+	if !stackNameRe.MatchString(name) {
+		writeErr(w, r, errStackName, "")
+		return "", false
+	}
+	return name, true
+}
+
+// load returns the stored environment file of a stack
+func (h *StackEnvHandler) load(stack string) (*storedStackEnv, error) {
+	var file storedStackEnv
+	if err := h.storage.GetJSON(stackEnvBucket, stack, &file); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errStackEnvNotFound
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+// env returns the variables to set in the containers of a stack: none
+// without a stack name or environment file
+func (h *StackEnvHandler) env(stack string) (map[string]string, error) {
+	if stack == "" {
+		return nil, nil
+	}
+	if !stackNameRe.MatchString(stack) {
+		return nil, errStackName
+	}
+	if h.storage == nil {
+		return nil, apierror.New(http.StatusServiceUnavailable, "Storage not available")
+	}
+	file, err := h.load(stack)
+	if errors.Is(err, errStackEnvNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file.Env, nil
+}
+
+// masked returns the environment file of a stack for the API
+func (h *StackEnvHandler) masked(stack string, file *storedStackEnv) StackEnvFile {
+	patterns := h.config.SecretEnvPatterns()
+	result := StackEnvFile{Stack: stack, Vars: make([]EnvVar, 0, len(file.Env)), UpdatedAt: file.UpdatedAt, UpdatedBy: file.UpdatedBy}
+	var content strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(file.Env)) {
+		v := EnvVar{Name: name, Value: file.Env[name], Secret: isSecretEnv(name, patterns)}
+		if v.Secret {
+			v.Value = envSecretMask
+		}
+		result.Vars = append(result.Vars, v)
+		content.WriteString(name + "=" + quoteEnvValue(v.Value) + "\n")
+	}
+	result.Content = content.String()
+	return result
+}
+
+// quoteEnvValue quotes a value of a .env file when it isn't read back as
+// is without quotes
+func quoteEnvValue(value string) string {
+	if value == strings.TrimSpace(value) && !strings.ContainsAny(value, "\"'#\\\n\r\t") {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// List handles GET /api/stacks/env
+// Returns the environment files of all stacks, secret values masked
+func (h *StackEnvHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	data, err := h.storage.ListPrefix(stackEnvBucket, "")
+	if err != nil {
+		writeErr(w, r, err, "Failed to read environment files")
+		return
+	}
+	files := make([]StackEnvFile, 0, len(data))
+	for stack, value := range data {
+		var file storedStackEnv
+		if json.Unmarshal(value, &file) != nil {
+			continue
+		}
+		files = append(files, h.masked(stack, &file))
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Stack < files[j].Stack })
+	writeJSON(w, http.StatusOK, files)
+}
+
+// Get handles GET /api/stacks/{stack}/env
+func (h *StackEnvHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	stack, ok := stackName(w, r)
+	if !ok {
+		return
+	}
+	file, err := h.load(stack)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read environment file")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.masked(stack, file))
+}
+
+// Update handles PUT /api/stacks/{stack}/env
+// The body {"content": "DB_PASSWORD=secret\n..."} replaces the environment
+// file of the stack. A masked value keeps the stored one. The containers
+// get the new values when the stack is deployed again.
+func (h *StackEnvHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	stack, ok := stackName(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxStackEnvSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Content) > maxStackEnvSize {
+		writeErr(w, r, errStackEnvTooLarge, "")
+		return
+	}
+	env, err := config.ParseEnvFile(strings.NewReader(req.Content))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid environment file")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	current := make(map[string]string)
+	if file, err := h.load(stack); err == nil {
+		current = file.Env
+	} else if !errors.Is(err, errStackEnvNotFound) {
+		writeErr(w, r, err, "Failed to read environment file")
+		return
+	}
+	for name, value := range env {
+		if value != envSecretMask {
+			continue
+		}
+		old, ok := current[name]
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, "Masked value for a new variable: "+name)
+			return
+		}
+		env[name] = old
+	}
+
+	file := &storedStackEnv{Env: env, UpdatedAt: time.Now().UTC(), UpdatedBy: user.Username}
+	if err := h.storage.SetJSON(stackEnvBucket, stack, file); err != nil {
+		h.eventStore.Add(events.EventStackEnv, user.Username, getClientIP(r), false, stack)
+		writeErr(w, r, err, "Failed to save environment file")
+		return
+	}
+
+	h.eventStore.Add(events.EventStackEnv, user.Username, getClientIP(r), true, stack+": "+h.envChanges(current, env))
+	writeJSON(w, http.StatusOK, h.masked(stack, file))
+}
+
+// envChanges describes the changes of an environment file for the event
+// log, secret values masked
+func (h *StackEnvHandler) envChanges(before, after map[string]string) string {
+	patterns := h.config.SecretEnvPatterns()
+	var changes []string
+	for _, name := range slices.Sorted(maps.Keys(after)) {
+		if old, ok := before[name]; ok && old == after[name] {
+			continue
+		}
+		value := after[name]
+		if isSecretEnv(name, patterns) {
+			value = envSecretMask
+		}
+		changes = append(changes, name+"="+value)
+	}
+	for _, name := range slices.Sorted(maps.Keys(before)) {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, name+" removed")
+		}
+	}
+	if len(changes) == 0 {
+		return "unchanged"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// Delete handles DELETE /api/stacks/{stack}/env
+func (h *StackEnvHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w, r) {
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	stack, ok := stackName(w, r)
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := h.load(stack); err != nil {
+		writeErr(w, r, err, "Failed to read environment file")
+		return
+	}
+	if err := h.storage.Delete(stackEnvBucket, stack); err != nil {
+		writeErr(w, r, err, "Failed to remove environment file")
+		return
+	}
+
+	h.eventStore.Add(events.EventStackEnv, user.Username, getClientIP(r), true, stack+": removed")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
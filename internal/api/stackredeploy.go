@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+// Actions of a container on stack redeploy
+const (
+	StackActionCreate   = "create"   // no container has its name
+	StackActionRecreate = "recreate" // its image, environment or ports changed
+	StackActionKeep     = "keep"     // nothing changed
+)
+
+// StackChange is the planned change of a container of a stack on redeploy,
+// and its outcome
+type StackChange struct {
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+	Action string `json:"action"`
+	// Changes says what differs, e.g. "env DEBUG changed"; values of
+	// environment variables are left out
+	Changes []string `json:"changes,omitempty"`
+	Status  string   `json:"status"` // pending, unchanged, created, recreated, started or failed
+}
+
+// RedeployStack handles POST /api/containers/stack/redeploy
+// The body is the stack as for CreateStack. Each container is compared with
+// the container of the same name on its image digest, environment and
+// ports; only the changed ones are recreated, and missing ones created.
+// Images are pulled to compare their digest. With dryRun=true only the plan
+// is returned.
+func (h *ContainerHandler) RedeployStack(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req StackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	order, err := stackOrder(req.Containers)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	env, err := h.stackEnv.env(req.Name)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read environment file")
+		return
+	}
+
+	plan := make([]StackChange, len(order))
+	infos := make([]*podman.ContainerInspect, len(order))
+	images := make([]*podman.ImageInspect, len(order))
+	byName := make(map[string]*StackContainer, len(order))
+	for i, c := range order {
+		plan[i], infos[i], images[i], err = h.planStackContainer(r.Context(), c, env)
+		if err != nil {
+			writeErr(w, r, err, "Failed to plan container: "+c.Name)
+			return
+		}
+		byName[c.Name] = c
+	}
+
+	if r.URL.Query().Get("dryRun") == "true" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"containers": plan})
+		return
+	}
+
+	fail := func(i int, err *apierror.Error) {
+		plan[i].Status = "failed"
+		writeErr(w, r, err.WithDetails(map[string]interface{}{"containers": plan}), "")
+	}
+
+	timeout := req.waitTimeout()
+	for i, c := range order {
+		switch plan[i].Action {
+		case StackActionKeep:
+			plan[i].Status = "unchanged"
+
+		case StackActionRecreate:
+			// Recreated containers keep their running state
+			if err := replaceContainer(r.Context(), h.client, infos[i], images[i], c.stackConfig(byName, env)); err != nil {
+				h.eventStore.Add(events.EventContainerUpdate, user.Username, getClientIP(r), false, c.Name+": "+err.Error())
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to recreate container: "+c.Name+": "+err.Error()))
+				return
+			}
+			h.eventStore.Add(events.EventContainerUpdate, user.Username, getClientIP(r), true, c.Name)
+			plan[i].Status = "recreated"
+			if info, err := h.client.InspectContainer(r.Context(), c.Name); err == nil {
+				plan[i].ID = info.ID
+			}
+
+		case StackActionCreate:
+			result, err := h.client.CreateContainer(r.Context(), c.stackConfig(byName, env))
+			if err != nil {
+				h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), false, c.Name)
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to create container: "+c.Name+": "+err.Error()))
+				return
+			}
+			h.eventStore.Add(events.EventContainerCreate, user.Username, getClientIP(r), true, shortID(result.ID))
+			plan[i].ID = result.ID
+			plan[i].Status = "created"
+			if !req.Start {
+				continue
+			}
+			for _, name := range parseNameList(c.Requires) {
+				if dep, ok := byName[name]; ok {
+					if err := h.waitReady(r.Context(), dep.Name, dep.Init, timeout); err != nil {
+						fail(i, err)
+						return
+					}
+				}
+			}
+			if err := h.client.StartContainer(r.Context(), result.ID); err != nil {
+				h.eventStore.Add(events.EventContainerStart, user.Username, getClientIP(r), false, shortID(result.ID))
+				fail(i, apierror.Wrap(err, apierror.Status(err), "Failed to start container: "+c.Name+": "+err.Error()))
+				return
+			}
+			h.eventStore.Add(events.EventContainerStart, user.Username, getClientIP(r), true, shortID(result.ID))
+			plan[i].Status = "started"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"containers": plan})
+}
+
+// planStackContainer compares a container of a stack, with the variables of
+// the environment file of the stack, with the container of the same name,
+// which is returned with its image when it exists
+func (h *ContainerHandler) planStackContainer(ctx context.Context, c *StackContainer, env map[string]string) (StackChange, *podman.ContainerInspect, *podman.ImageInspect, error) {
+	change := StackChange{Name: c.Name, Action: StackActionCreate, Status: "pending"}
+	info, err := h.client.InspectContainer(ctx, c.Name)
+	if apierror.Status(err) == http.StatusNotFound {
+		return change, nil, nil, nil
+	}
+	if err != nil {
+		return change, nil, nil, err
+	}
+	change.ID = info.ID
+
+	current, err := h.client.InspectImage(ctx, info.Image)
+	if err != nil {
+		current = &podman.ImageInspect{}
+	}
+	// Stay on the platform of the image, as auto-update does. Images that
+	// can't be pulled, e.g. built locally, are compared as they are.
+	pullErr := h.client.PullImagePlatform(ctx, c.Image, imagePlatform(current))
+	latest, err := h.client.InspectImage(ctx, c.Image)
+	if err != nil {
+		if pullErr != nil {
+			err = pullErr
+		}
+		return change, nil, nil, err
+	}
+
+	if strings.TrimPrefix(latest.ID, "sha256:") != strings.TrimPrefix(info.Image, "sha256:") {
+		if info.ImageName != c.Image {
+			change.Changes = append(change.Changes, "image "+info.ImageName+" → "+c.Image)
+		} else {
+			change.Changes = append(change.Changes, "image digest "+shortID(strings.TrimPrefix(info.Image, "sha256:"))+" → "+shortID(strings.TrimPrefix(latest.ID, "sha256:")))
+		}
+	}
+	spec := buildContainerSpec(info, current)
+	config := c.stackConfig(nil, env)
+	change.Changes = append(change.Changes, envChanges(parseEnvList(info.Config.Env), spec.Env, config.Env)...)
+	if before, after := specPorts(spec.Ports), mappingPorts(config.PortMappings); !slices.Equal(before, after) {
+		change.Changes = append(change.Changes, "ports "+portList(before)+" → "+portList(after))
+	}
+
+	change.Action = StackActionKeep
+	if len(change.Changes) > 0 {
+		change.Action = StackActionRecreate
+	}
+	return change, info, current, nil
+}
+
+// envChanges lists the variables of want that differ from the environment
+// of a container, and the variables set on the container (not inherited
+// from its image) that want drops
+func envChanges(env, set, want map[string]string) []string {
+	var changes []string
+	for _, name := range slices.Sorted(maps.Keys(want)) {
+		value, ok := env[name]
+		switch {
+		case !ok:
+			changes = append(changes, "env "+name+" added")
+		case value != want[name]:
+			changes = append(changes, "env "+name+" changed")
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(set)) {
+		if _, ok := want[name]; !ok {
+			changes = append(changes, "env "+name+" removed")
+		}
+	}
+	return changes
+}
+
+// specPorts returns the published ports of a spec as sorted
+// host:container/protocol entries
+func specPorts(ports []SpecPort) []string {
+	list := make([]string, 0, len(ports))
+	for _, p := range ports {
+		list = append(list, portEntry(p.HostPort, p.ContainerPort, p.Protocol))
+	}
+	slices.Sort(list)
+	return list
+}
+
+// mappingPorts returns port mappings as sorted host:container/protocol
+// entries
+func mappingPorts(mappings []podman.PortMapping) []string {
+	list := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		list = append(list, portEntry(m.HostPort, m.ContainerPort, m.Protocol))
+	}
+	slices.Sort(list)
+	return list
+}
+
+// portEntry formats a published port; the protocol defaults to tcp
+func portEntry(hostPort, containerPort int, protocol string) string {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	return fmt.Sprintf("%d:%d/%s", hostPort, containerPort, protocol)
+}
+
+// portList joins port entries for a change, "none" if there are none
+func portList(ports []string) string {
+	if len(ports) == 0 {
+		return "none"
+	}
+	return strings.Join(ports, ", ")
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/web/static"
+)
+
+// staticHandler serves the web assets embedded in the binary.
+// Requests with the current ?v= version are cached for a year; others
+// (scripts loaded without a version, the service worker) are revalidated
+// with a content ETag on every use.
+func (s *Server) staticHandler() http.Handler {
+	etags := make(map[string]string)
+	fs.WalkDir(static.Files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := static.Files.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		etags[path] = `W/"` + hex.EncodeToString(sum[:8]) + `"`
+		return nil
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "*")
+		etag, ok := etags[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		f, err := static.Files.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+
+		// The service worker must be revalidated so updates reach clients
+		if v := r.URL.Query().Get("v"); v != "" && v == s.staticVersion && name != "sw.js" {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, name, time.Time{}, f.(io.ReadSeeker))
+	})
+}
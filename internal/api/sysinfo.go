@@ -2,24 +2,27 @@ package api
 
 import (
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
-	"sync"
 	"syscall"
-	"time"
 )
 
 // HostStats represents CPU, memory, temperature, uptime and disk info
 type HostStats struct {
 	CPUUsage     float64       `json:"cpuUsage"`
+	CPUs         int           `json:"cpus"`
 	MemTotal     uint64        `json:"memTotal"`               // bytes
 	MemFree      uint64        `json:"memFree"`                // bytes (MemAvailable from /proc/meminfo)
 	Temperatures []Temperature `json:"temperatures"`           // CPU/SoC temperatures
 	StorageTemps []StorageTemp `json:"storageTemps,omitempty"` // NVMe/Storage temperatures grouped by device
 	Uptime       int64         `json:"uptime"`                 // seconds
-	DiskTotal    uint64        `json:"diskTotal"`              // bytes (deprecated, kept for compatibility)
-	DiskFree     uint64        `json:"diskFree"`               // bytes (deprecated, kept for compatibility)
+	DiskTotal    uint64        `json:"diskTotal,omitempty"`    // bytes (deprecated, unversioned API only)
+	DiskFree     uint64        `json:"diskFree,omitempty"`     // bytes (deprecated, unversioned API only)
 	Disks        []DiskInfo    `json:"disks,omitempty"`        // All disks info
+	Throttling   *Throttling   `json:"throttling,omitempty"`   // Raspberry Pi only
+	Pools        []StoragePool `json:"pools,omitempty"`        // ZFS pools and btrfs filesystems
+	Raid         []RaidArray   `json:"raid,omitempty"`         // md software RAID arrays
 }
 
 // DiskInfo represents disk usage information
@@ -29,6 +32,22 @@ type DiskInfo struct {
 	Total      uint64 `json:"total"`      // Total size in bytes
 	Free       uint64 `json:"free"`       // Free space in bytes
 	Used       uint64 `json:"used"`       // Used space in bytes
+	DiskIO            // Throughput since the previous sample
+
+	// LVM or LUKS device (Device is then vg/lv or the mapping name)
+	Mapping *DeviceMapping `json:"mapping,omitempty"`
+
+	// NFS or CIFS mount (Device is then the server path), see PODMANVIEW_NETWORK_MOUNTS
+	Network string `json:"network,omitempty"` // filesystem type
+	Stale   bool   `json:"stale,omitempty"`   // not responding, no usage
+}
+
+// kernelName is the name of the disk in /proc/diskstats
+func (d DiskInfo) kernelName() string {
+	if d.Mapping != nil {
+		return d.Mapping.Kernel
+	}
+	return d.Device
 }
 
 // StorageTemp represents storage device temperatures grouped by device
@@ -39,8 +58,9 @@ type StorageTemp struct {
 
 // Temperature represents a temperature sensor reading
 type Temperature struct {
-	Label string  `json:"label"`
-	Temp  float64 `json:"temp"`
+	Label   string    `json:"label"`
+	Temp    float64   `json:"temp"`
+	History []float64 `json:"history,omitempty"` // recent samples, oldest first, for sparklines
 }
 
 // GetHostStats reads CPU usage, memory, uptime and disk info from /sys and /proc
@@ -53,7 +73,8 @@ func GetHostStats() *HostStats {
 	}
 
 	// Get CPU usage
-	stats.CPUUsage = getCPUUsage()
+	stats.CPUUsage = hostCPU.Usage()
+	stats.CPUs = runtime.NumCPU()
 
 	// Get memory info
 	stats.MemTotal, stats.MemFree = getMemoryInfo()
@@ -65,8 +86,21 @@ func GetHostStats() *HostStats {
 	// Get uptime
 	stats.Uptime = getUptime()
 
-	// Get all disks usage
+	// Get all disks usage with their I/O throughput
 	stats.Disks = getAllDisksUsage()
+	diskIO := hostDiskIO.Usage()
+	for i := range stats.Disks {
+		stats.Disks[i].DiskIO = diskIO[stats.Disks[i].kernelName()]
+	}
+
+	// Get ZFS pools and btrfs filesystems, which are not in Disks
+	stats.Pools = getStoragePools()
+
+	// Get md software RAID arrays
+	stats.Raid = getRaidArrays()
+
+	// Get under-voltage and throttling flags (Raspberry Pi)
+	stats.Throttling = getThrottling()
 
 	// Keep backward compatibility - use root disk for DiskTotal/DiskFree
 	stats.DiskTotal, stats.DiskFree = getDiskUsage("/")
@@ -138,60 +172,6 @@ func getUptime() int64 {
 	return int64(uptime)
 }
 
-// CPU stats for delta calculation
-var (
-	cpuMu        sync.Mutex
-	prevTotal    int64
-	prevIdle     int64
-	prevTime     time.Time
-	lastCPUUsage float64
-)
-
-// getCPUUsage calculates real CPU usage from /proc/stat
-// Returns percentage (0-100)
-func getCPUUsage() float64 {
-	total, idle := readCPUStat()
-	if total == 0 {
-		return lastCPUUsage
-	}
-
-	cpuMu.Lock()
-	defer cpuMu.Unlock()
-
-	now := time.Now()
-
-	// Need previous reading to calculate delta
-	if prevTime.IsZero() {
-		prevTotal = total
-		prevIdle = idle
-		prevTime = now
-		return 0
-	}
-
-	// Calculate delta since last reading
-	totalDelta := total - prevTotal
-	idleDelta := idle - prevIdle
-
-	// Store current values for next call
-	prevTotal = total
-	prevIdle = idle
-	prevTime = now
-
-	if totalDelta <= 0 {
-		return lastCPUUsage
-	}
-
-	// CPU usage = (total - idle) / total * 100
-	lastCPUUsage = float64(totalDelta-idleDelta) / float64(totalDelta) * 100
-	if lastCPUUsage < 0 {
-		lastCPUUsage = 0
-	} else if lastCPUUsage > 100 {
-		lastCPUUsage = 100
-	}
-
-	return lastCPUUsage
-}
-
 // readCPUStat reads CPU times from /proc/stat
 func readCPUStat() (total, idle int64) {
 	data, err := os.ReadFile("/proc/stat")
@@ -252,6 +232,11 @@ func getAllDisksUsage() []DiskInfo {
 			continue
 		}
 
+		// Pools report btrfs usage, statfs of a subvolume is misleading
+		if len(fields) > 2 && fields[2] == PoolBtrfs {
+			continue
+		}
+
 		// Skip pseudo filesystems
 		if strings.HasPrefix(device, "/dev/loop") {
 			continue
@@ -281,6 +266,13 @@ func getAllDisksUsage() []DiskInfo {
 			}
 		}
 
+		// Device-mapper (LVM, LUKS): name it after the logical volume or
+		// the encrypted mapping instead of dm-0
+		mapping := ResolveDeviceMapper(sysBlockPath, device)
+		if mapping != nil {
+			baseDevice = mapping.DisplayName()
+		}
+
 		// Skip if we already have this device (use first mount point)
 		if seen[baseDevice] {
 			continue
@@ -309,6 +301,7 @@ func getAllDisksUsage() []DiskInfo {
 			Total:      total,
 			Free:       avail, // Show available space (what user can actually use)
 			Used:       used,
+			Mapping:    mapping,
 		})
 	}
 
@@ -2,8 +2,12 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,14 +38,18 @@ type SystemHandler struct {
 	client         *podman.Client
 	eventStore     *events.Store
 	pluginRegistry *plugins.Registry
+	confirms       *confirmStore
+	readHostStats  func() *HostStats // GetHostStats, mock stats in demo mode
 }
 
 // NewSystemHandler creates new system handler
-func NewSystemHandler(client *podman.Client, eventStore *events.Store, pluginRegistry *plugins.Registry) *SystemHandler {
+func NewSystemHandler(client *podman.Client, eventStore *events.Store, pluginRegistry *plugins.Registry, confirms *confirmStore) *SystemHandler {
 	return &SystemHandler{
 		client:         client,
 		eventStore:     eventStore,
 		pluginRegistry: pluginRegistry,
+		confirms:       confirms,
+		readHostStats:  GetHostStats,
 	}
 }
 
@@ -87,7 +95,7 @@ func (h *SystemHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	// Get cached or fresh system info (static data, cache for 5 minutes)
 	sysInfo := h.getCachedSystemInfo(ctx)
 	if sysInfo == nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get system info"})
+		writeError(w, r, http.StatusInternalServerError, "Failed to get system info")
 		return
 	}
 
@@ -97,26 +105,19 @@ func (h *SystemHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	// Only containers need fresh data (state changes frequently)
 	containers, err := h.client.ListContainers(ctx)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
 	// Get host stats (reads /proc, /sys)
-	hostStats := GetHostStats()
-
-	// Check if temperature plugin is enabled and get temperature data from it
-	if h.pluginRegistry != nil {
-		if tempPlugin, ok := h.pluginRegistry.Get("temperature"); ok && tempPlugin.IsEnabled() {
-			// Type assert to *temperature.TemperaturePlugin
-			if plugin, ok := tempPlugin.(*temperature.TemperaturePlugin); ok {
-				tempData := plugin.GetTemperatureData()
-				// Convert plugin temperature data to API temperature data
-				hostStats.Temperatures = convertTemperatures(tempData.Temperatures)
-				hostStats.StorageTemps = convertStorageTemps(tempData.StorageTemps)
-			}
-		}
+	hostStats := h.readHostStats()
+	if requestAPIVersion(r) != "" {
+		// Deprecated root disk fields are only served by the unversioned API, use disks
+		hostStats.DiskTotal, hostStats.DiskFree = 0, 0
 	}
 
+	addPluginTemperatures(h.pluginRegistry, hostStats)
+
 	containerCounts := ContainerCounts{Total: len(containers)}
 	for _, c := range containers {
 		if c.State == "running" {
@@ -227,7 +228,7 @@ func (h *SystemHandler) getCachedResourceCounts(ctx context.Context) (int, int,
 func (h *SystemHandler) Info(w http.ResponseWriter, r *http.Request) {
 	info, err := h.client.GetSystemInfo(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
@@ -238,18 +239,116 @@ func (h *SystemHandler) Info(w http.ResponseWriter, r *http.Request) {
 func (h *SystemHandler) DiskUsage(w http.ResponseWriter, r *http.Request) {
 	df, err := h.client.GetSystemDF(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeErr(w, r, err, "")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, df)
 }
 
+// ContainerDisk is the disk space used by a container
+type ContainerDisk struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Writable  int64  `json:"writable"`  // bytes of the writable layer
+	LogDriver string `json:"logDriver"` // k8s-file, journald or none
+	LogSize   int64  `json:"logSize"`   // bytes of the log file, 0 if the log is not a file
+	Total     int64  `json:"total"`
+}
+
+// ContainerDiskUsage handles GET /api/system/df/containers
+// Returns the writable layer and log file size of every container, the
+// largest first, and the disk of the Podman storage.
+func (h *SystemHandler) ContainerDiskUsage(w http.ResponseWriter, r *http.Request) {
+	df, err := h.client.GetSystemDF(r.Context())
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	list := []ContainerDisk{}
+	var writable, logs int64
+	for _, c := range df.Containers {
+		usage := ContainerDisk{ID: c.ContainerID, Name: c.Names, Status: c.Status, Writable: c.RWSize}
+		if info, err := h.client.InspectContainer(r.Context(), c.ContainerID); err == nil {
+			usage.Name = strings.TrimPrefix(info.Name, "/")
+			usage.LogDriver = info.HostConfig.LogConfig.Type
+			if path := info.HostConfig.LogConfig.Path; path != "" {
+				if stat, err := os.Stat(path); err == nil {
+					usage.LogSize = stat.Size()
+				}
+			}
+		}
+		usage.Total = usage.Writable + usage.LogSize
+		writable += usage.Writable
+		logs += usage.LogSize
+		list = append(list, usage)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Total > list[j].Total })
+
+	result := map[string]interface{}{
+		"writable":   writable,
+		"logs":       logs,
+		"containers": list,
+	}
+	if info, err := h.client.GetSystemInfo(r.Context()); err == nil && info.Store.GraphRoot != "" {
+		result["graphRoot"] = info.Store.GraphRoot
+		if disk := diskOfPath(h.readHostStats().Disks, info.Store.GraphRoot); disk != nil {
+			result["disk"] = disk
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// diskOfPath returns the disk whose mount point holds path, nil if unknown
+func diskOfPath(disks []DiskInfo, path string) *DiskInfo {
+	var found *DiskInfo
+	for i, disk := range disks {
+		mount := strings.TrimSuffix(disk.MountPoint, "/")
+		if path != mount && !strings.HasPrefix(path, mount+"/") {
+			continue
+		}
+		if found == nil || len(disk.MountPoint) > len(found.MountPoint) {
+			found = &disks[i]
+		}
+	}
+	return found
+}
+
+// PowerImpact is what a reboot or shutdown of the host interrupts
+type PowerImpact struct {
+	RunningContainers []string `json:"runningContainers"`
+}
+
+// powerImpact lists the running containers, which are stopped by a reboot
+// or shutdown
+func (h *SystemHandler) powerImpact(r *http.Request) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		containers, err := h.client.ListContainers(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		impact := &PowerImpact{RunningContainers: []string{}}
+		for _, c := range containers {
+			if c.State == "running" && !c.IsInfra {
+				impact.RunningContainers = append(impact.RunningContainers, firstOf(c.Names))
+			}
+		}
+		sort.Strings(impact.RunningContainers)
+		return impact, nil
+	}
+}
+
 // Reboot handles POST /api/system/reboot
+// Needs a confirmation token (see Confirmation)
 func (h *SystemHandler) Reboot(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if !h.confirms.confirmed(w, r, "reboot", "", h.powerImpact(r)) {
 		return
 	}
 
@@ -266,10 +365,14 @@ func (h *SystemHandler) Reboot(w http.ResponseWriter, r *http.Request) {
 }
 
 // Shutdown handles POST /api/system/shutdown
+// Needs a confirmation token (see Confirmation)
 func (h *SystemHandler) Shutdown(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if !h.confirms.confirmed(w, r, "shutdown", "", h.powerImpact(r)) {
 		return
 	}
 
@@ -285,13 +388,109 @@ func (h *SystemHandler) Shutdown(w http.ResponseWriter, r *http.Request) {
 	}()
 }
 
+// PruneImpact is what a system prune removes
+type PruneImpact struct {
+	Containers  []string `json:"containers"` // stopped containers
+	Images      int      `json:"images"`
+	Volumes     []string `json:"volumes"`     // unused volumes, with volumes=true
+	Reclaimable int64    `json:"reclaimable"` // bytes, estimated since images share layers
+}
+
+// Prune handles POST /api/system/prune?all=true&volumes=true
+// Removes stopped containers and dangling images, all unused images with
+// all=true and unused volumes with volumes=true. Needs a confirmation token
+// (see Confirmation), whose impact is estimated from the disk usage.
+func (h *SystemHandler) Prune(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	all := r.URL.Query().Get("all") == "true"
+	volumes := r.URL.Query().Get("volumes") == "true"
+	target := fmt.Sprintf("all=%t,volumes=%t", all, volumes)
+	impact := func() (interface{}, error) {
+		df, err := h.client.GetSystemDF(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		return pruneImpact(df, all, volumes), nil
+	}
+	if !h.confirms.confirmed(w, r, "prune", target, impact) {
+		return
+	}
+
+	report, err := h.client.SystemPrune(r.Context(), all, volumes)
+	if err != nil {
+		h.eventStore.Add(events.EventSystemPrune, user.Username, getClientIP(r), false, target)
+		writeErr(w, r, err, "Failed to prune")
+		return
+	}
+	details := fmt.Sprintf("%d containers, %d images, %d volumes", len(report.ContainerPruneReports), len(report.ImagePruneReports), len(report.VolumePruneReports))
+	h.eventStore.Add(events.EventSystemPrune, user.Username, getClientIP(r), true, details)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"containers":     len(report.ContainerPruneReports),
+		"images":         len(report.ImagePruneReports),
+		"volumes":        len(report.VolumePruneReports),
+		"reclaimedSpace": report.ReclaimedSpace,
+	})
+}
+
+// pruneImpact estimates from the disk usage what a prune removes
+func pruneImpact(df *podman.SystemDF, all, volumes bool) *PruneImpact {
+	impact := &PruneImpact{Containers: []string{}, Volumes: []string{}}
+	for _, c := range df.Containers {
+		if c.Status != "running" && c.Status != "paused" {
+			impact.Containers = append(impact.Containers, c.Names)
+			impact.Reclaimable += c.RWSize
+		}
+	}
+	for _, img := range df.Images {
+		if img.Containers == 0 && (all || img.Repository == "<none>") {
+			impact.Images++
+			impact.Reclaimable += img.Size
+		}
+	}
+	if volumes {
+		for _, v := range df.Volumes {
+			if v.Links == 0 {
+				impact.Volumes = append(impact.Volumes, v.VolumeName)
+				impact.Reclaimable += v.Size
+			}
+		}
+	}
+	sort.Strings(impact.Containers)
+	sort.Strings(impact.Volumes)
+	return impact
+}
+
+// addPluginTemperatures adds the temperature data of the temperature plugin
+// to stats if the plugin is enabled
+func addPluginTemperatures(registry *plugins.Registry, stats *HostStats) {
+	if registry == nil {
+		return
+	}
+	if tempPlugin, ok := registry.Get("temperature"); ok && tempPlugin.IsEnabled() {
+		// Type assert to *temperature.TemperaturePlugin
+		if plugin, ok := tempPlugin.(*temperature.TemperaturePlugin); ok {
+			tempData := plugin.GetTemperatureData()
+			// Convert plugin temperature data to API temperature data
+			stats.Temperatures = convertTemperatures(tempData.Temperatures)
+			stats.StorageTemps = convertStorageTemps(tempData.StorageTemps)
+		}
+	}
+}
+
 // convertTemperatures converts plugin temperature data to API temperature data
 func convertTemperatures(pluginTemps []temperature.Temperature) []Temperature {
 	result := make([]Temperature, len(pluginTemps))
 	for i, t := range pluginTemps {
 		result[i] = Temperature{
-			Label: t.Label,
-			Temp:  t.Temp,
+			Label:   t.Label,
+			Temp:    t.Temp,
+			History: t.History,
 		}
 	}
 	return result
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+const (
+	// tagsBucket is the storage namespace of container tags, by container
+	// name. favoritesBucket holds the favorite containers of each user.
+	tagsBucket      = "tags"
+	favoritesBucket = "favorites"
+
+	// maxContainerTags limits the tags of a container
+	maxContainerTags = 20
+)
+
+// tagPattern matches valid tags: lowercase letters, digits, ".", "_" and "-"
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,31}$`)
+
+var (
+	errInvalidTag  = apierror.New(http.StatusBadRequest, "Invalid tag, use up to 32 lowercase letters, digits, '.', '_' and '-'")
+	errTooManyTags = apierror.New(http.StatusBadRequest, fmt.Sprintf("A container can have up to %d tags", maxContainerTags))
+)
+
+// TagGroup is a tag with the containers that have it
+type TagGroup struct {
+	Tag        string   `json:"tag"`
+	Containers []string `json:"containers"`
+}
+
+// containerTags keeps user-defined tags of containers and the favorite
+// containers of users. Both are by container name, so they are kept when
+// a container is recreated, and are independent of Podman labels.
+type containerTags struct {
+	storage storage.Storage // nil keeps tags in memory only
+
+	mu        sync.Mutex
+	tags      map[string][]string // by container name, sorted
+	favorites map[string][]string // container names by username, sorted
+}
+
+// newContainerTags creates the tag store and loads the stored tags
+func newContainerTags(store storage.Storage, appLogger *logger.Logger) *containerTags {
+	t := &containerTags{storage: store, tags: make(map[string][]string), favorites: make(map[string][]string)}
+	if store == nil {
+		return t
+	}
+
+	for bucket, target := range map[string]map[string][]string{tagsBucket: t.tags, favoritesBucket: t.favorites} {
+		data, err := store.List(bucket)
+		if err != nil {
+			appLogger.Warn("Failed to load container tags", logger.KeyError, err)
+			continue
+		}
+		for key, value := range data {
+			var list []string
+			if err := json.Unmarshal(value, &list); err != nil {
+				appLogger.Warn("Failed to load container tags", "key", key, logger.KeyError, err)
+				continue
+			}
+			target[key] = list
+		}
+	}
+	return t
+}
+
+// get returns the tags of a container
+func (t *containerTags) get(name string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.tags[name])
+}
+
+// set replaces the tags of a container, returning the normalized tags
+func (t *containerTags) set(name string, tags []string) ([]string, error) {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagPattern.MatchString(tag) {
+			return nil, errInvalidTag
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxContainerTags {
+		return nil, errTooManyTags
+	}
+	sort.Strings(normalized)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.store(tagsBucket, name, normalized); err != nil {
+		return nil, err
+	}
+	if len(normalized) == 0 {
+		delete(t.tags, name)
+	} else {
+		t.tags[name] = normalized
+	}
+	return normalized, nil
+}
+
+// groups returns the tags in use with their containers
+func (t *containerTags) groups() []TagGroup {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	byTag := make(map[string][]string)
+	for name, tags := range t.tags {
+		for _, tag := range tags {
+			byTag[tag] = append(byTag[tag], name)
+		}
+	}
+	groups := make([]TagGroup, 0, len(byTag))
+	for tag, names := range byTag {
+		sort.Strings(names)
+		groups = append(groups, TagGroup{Tag: tag, Containers: names})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Tag < groups[j].Tag })
+	return groups
+}
+
+// isFavorite reports whether a container is a favorite of a user
+func (t *containerTags) isFavorite(username, name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Contains(t.favorites[username], name)
+}
+
+// userFavorites returns the favorite containers of a user
+func (t *containerTags) userFavorites(username string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if list := t.favorites[username]; list != nil {
+		return slices.Clone(list)
+	}
+	return []string{}
+}
+
+// setFavorite adds or removes a favorite container of a user
+func (t *containerTags) setFavorite(username, name string, favorite bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := slices.Clone(t.favorites[username])
+	i, found := slices.BinarySearch(list, name)
+	switch {
+	case favorite && !found:
+		list = slices.Insert(list, i, name)
+	case !favorite && found:
+		list = slices.Delete(list, i, i+1)
+	default:
+		return nil
+	}
+	if err := t.store(favoritesBucket, username, list); err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		delete(t.favorites, username)
+	} else {
+		t.favorites[username] = list
+	}
+	return nil
+}
+
+// store saves a list, removing the key when it is empty. Call with t.mu
+// held.
+func (t *containerTags) store(bucket, key string, list []string) error {
+	if t.storage == nil {
+		return nil
+	}
+	if len(list) == 0 {
+		if err := t.storage.Delete(bucket, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		return nil
+	}
+	return t.storage.SetJSON(bucket, key, list)
+}
+
+// TagHandler handles container tags and favorites
+type TagHandler struct {
+	client     *podman.Client
+	tags       *containerTags
+	eventStore *events.Store
+}
+
+// NewTagHandler creates new tag handler
+func NewTagHandler(client *podman.Client, tags *containerTags, eventStore *events.Store) *TagHandler {
+	return &TagHandler{client: client, tags: tags, eventStore: eventStore}
+}
+
+// containerName returns the name of a container given by name or ID
+func (h *TagHandler) containerName(r *http.Request) (string, error) {
+	info, err := h.client.InspectContainer(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(info.Name, "/"), nil
+}
+
+// List handles GET /api/containers/tags
+// Returns the tags in use with their containers
+func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.tags.groups())
+}
+
+// Get handles GET /api/containers/{id}/tags
+func (h *TagHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name, err := h.containerName(r)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	tags := h.tags.get(name)
+	if tags == nil {
+		tags = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"container": name,
+		"tags":      tags,
+		"favorite":  h.tags.isFavorite(user.Username, name),
+	})
+}
+
+// Update handles PUT /api/containers/{id}/tags
+// The body {"tags": ["media", "public"]} replaces the tags of the container
+func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name, err := h.containerName(r)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	tags, err := h.tags.set(name, req.Tags)
+	if err != nil {
+		writeErr(w, r, err, "Failed to save tags")
+		return
+	}
+
+	h.eventStore.Add(events.EventContainerTags, user.Username, getClientIP(r), true, name+": "+strings.Join(tags, ", "))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"container": name, "tags": tags})
+}
+
+// Favorites handles GET /api/containers/favorites
+// Returns the favorite containers of the user
+func (h *TagHandler) Favorites(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	writeJSON(w, http.StatusOK, h.tags.userFavorites(user.Username))
+}
+
+// AddFavorite handles PUT /api/containers/{id}/favorite
+func (h *TagHandler) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, true)
+}
+
+// RemoveFavorite handles DELETE /api/containers/{id}/favorite
+func (h *TagHandler) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, false)
+}
+
+// setFavorite marks a container as a favorite of the user or not
+func (h *TagHandler) setFavorite(w http.ResponseWriter, r *http.Request, favorite bool) {
+	user := auth.GetUserFromContext(r.Context())
+	name, err := h.containerName(r)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if err := h.tags.setFavorite(user.Username, name, favorite); err != nil {
+		writeErr(w, r, err, "Failed to save favorites")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"container": name, "favorite": favorite})
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+// maxTransferSize limits the files moved in and out of terminal sessions
+const maxTransferSize = 4 << 20
+
+var (
+	errTransferAdmin  = apierror.New(http.StatusForbidden, "File transfer requires admin access")
+	errTransferPath   = apierror.New(http.StatusBadRequest, "File path required")
+	errTransferData   = apierror.New(http.StatusBadRequest, "Invalid file data, expected base64")
+	errTransferSize   = apierror.New(http.StatusRequestEntityTooLarge, "Files of up to 4 MB can be transferred")
+	errTransferExists = apierror.New(http.StatusConflict, "File already exists")
+	errTransferFile   = apierror.New(http.StatusBadRequest, "Not a regular file")
+	errTransferPolicy = apierror.New(http.StatusForbidden, "Uploads are not available under a terminal command policy")
+)
+
+// TransferMessage is the reply of a terminal session to an upload or
+// download message
+type TransferMessage struct {
+	Type  string `json:"type"`           // always "transfer"
+	Op    string `json:"op"`             // "upload" or "download"
+	Path  string `json:"path"`           // absolute path of the file
+	Size  int64  `json:"size"`           // bytes
+	Data  string `json:"data,omitempty"` // base64 content of a downloaded file
+	Error string `json:"error,omitempty"`
+}
+
+// fileTransfer moves files in and out of the target of a terminal session:
+// the host, or a container through the archive API of Podman, which needs
+// no tools in the container. Relative paths are in the working directory
+// of the shell.
+type fileTransfer struct {
+	client      *podman.Client
+	containerID string // "" for the host
+	tracker     *commandTracker
+	policy      *TerminalPolicy // command policy of the session
+}
+
+// resolve returns the absolute path of a file
+func (t *fileTransfer) resolve(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", errTransferPath
+	}
+	if t.containerID == "" {
+		if filepath.IsAbs(name) {
+			return filepath.Clean(name), nil
+		}
+		dir := t.tracker.workDir()
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				home = "/"
+			}
+			dir = home
+		}
+		return filepath.Join(dir, name), nil
+	}
+
+	if path.IsAbs(name) {
+		return path.Clean(name), nil
+	}
+	dir := t.tracker.workDir()
+	if dir == "" {
+		// sh doesn't report its directory, use the one it started in
+		info, err := t.client.InspectContainer(ctx, t.containerID)
+		if err != nil {
+			return "", err
+		}
+		dir = firstNonEmpty(info.Config.WorkingDir, "/")
+	}
+	return path.Join(dir, name), nil
+}
+
+// upload writes a file, refusing to replace an existing one unless
+// overwrite is set. A replaced file keeps its mode.
+func (t *fileTransfer) upload(ctx context.Context, name string, data []byte, overwrite bool) (string, error) {
+	target, err := t.resolve(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	mode := os.FileMode(0644)
+	if t.containerID == "" {
+		if info, err := os.Stat(target); err == nil {
+			if !info.Mode().IsRegular() {
+				return target, errTransferFile
+			}
+			if !overwrite {
+				return target, errTransferExists
+			}
+			mode = info.Mode().Perm()
+		}
+		return target, os.WriteFile(target, data, mode)
+	}
+
+	if stat, err := t.client.StatContainerPath(ctx, t.containerID, target); err == nil {
+		if stat.Mode.IsDir() {
+			return target, errTransferFile
+		}
+		if !overwrite {
+			return target, errTransferExists
+		}
+		mode = stat.Mode.Perm()
+	}
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	header := &tar.Header{
+		Name:    path.Base(target),
+		Mode:    int64(mode),
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return target, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return target, err
+	}
+	if err := tw.Close(); err != nil {
+		return target, err
+	}
+	return target, t.client.CopyToContainer(ctx, t.containerID, path.Dir(target), &archive)
+}
+
+// download reads a file
+func (t *fileTransfer) download(ctx context.Context, name string) (string, []byte, error) {
+	target, err := t.resolve(ctx, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if t.containerID == "" {
+		info, err := os.Stat(target)
+		if err != nil {
+			return target, nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return target, nil, errTransferFile
+		}
+		if info.Size() > maxTransferSize {
+			return target, nil, errTransferSize
+		}
+		data, err := os.ReadFile(target)
+		return target, data, err
+	}
+
+	archive, err := t.client.CopyFromContainer(ctx, t.containerID, target)
+	if err != nil {
+		return target, nil, err
+	}
+	defer archive.Close()
+	tr := tar.NewReader(archive)
+	header, err := tr.Next()
+	if err != nil {
+		return target, nil, err
+	}
+	if header.Typeflag != tar.TypeReg {
+		return target, nil, errTransferFile
+	}
+	if header.Size > maxTransferSize {
+		return target, nil, errTransferSize
+	}
+	data, err := io.ReadAll(io.LimitReader(tr, maxTransferSize))
+	return target, data, err
+}
+
+// transfer handles an upload or download message of a terminal session and
+// returns the reply. Transfers need admin access, also in sessions of
+// read-only users limited by a command policy. Uploads could bring in
+// commands the policy doesn't see, so sessions with a policy can't upload.
+func (h *TerminalHandler) transfer(r *http.Request, user *auth.User, files *fileTransfer, target string, msg ExecMessage) []byte {
+	reply := TransferMessage{Type: "transfer", Op: msg.Type}
+	var err error
+	switch {
+	case !user.IsAdmin():
+		err = errTransferAdmin
+	case msg.Type == "upload" && files.policy != nil && files.policy.Mode != PolicyOff:
+		err = errTransferPolicy
+	case msg.Type == "upload":
+		var data []byte
+		if len(msg.Data) > base64.StdEncoding.EncodedLen(maxTransferSize) {
+			err = errTransferSize
+		} else if data, err = base64.StdEncoding.DecodeString(msg.Data); err != nil {
+			err = errTransferData
+		} else {
+			reply.Path, err = files.upload(r.Context(), msg.Path, data, msg.Overwrite)
+			reply.Size = int64(len(data))
+		}
+	default:
+		var data []byte
+		reply.Path, data, err = files.download(r.Context(), msg.Path)
+		reply.Size = int64(len(data))
+		reply.Data = base64.StdEncoding.EncodeToString(data)
+	}
+
+	details := fmt.Sprintf("%s: %s %s (%d bytes)", target, msg.Type, firstNonEmpty(reply.Path, msg.Path), reply.Size)
+	if err != nil {
+		reply.Error = transferError(err)
+		reply.Size, reply.Data = 0, ""
+		details = fmt.Sprintf("%s: %s %s: %s", target, msg.Type, firstNonEmpty(reply.Path, msg.Path), reply.Error)
+	}
+	if !errors.Is(err, errTransferExists) {
+		h.eventStore.Add(events.EventTerminalTransfer, user.Username, getClientIP(r), err == nil, details)
+	}
+	data, _ := json.Marshal(reply)
+	return data
+}
+
+// transferError returns the message of a failed transfer
+func transferError(err error) string {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
+	}
+	var podmanErr *podman.APIError
+	if errors.Is(err, os.ErrNotExist) || errors.As(err, &podmanErr) && podmanErr.StatusCode == http.StatusNotFound {
+		return "No such file or directory"
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"sync"
 	"time"
 
 	"github.com/creack/pty"
@@ -20,6 +21,7 @@ import (
 	"podmanview/internal/events"
 	"podmanview/internal/logger"
 	"podmanview/internal/podman"
+	"podmanview/internal/storage"
 )
 
 const (
@@ -35,20 +37,24 @@ const (
 // TerminalHandler handles terminal WebSocket connections
 type TerminalHandler struct {
 	client         *podman.Client
+	storage        storage.Storage // terminal command policies
 	wsTokenStore   *auth.WSTokenStore
 	eventStore     *events.Store
 	historyHandler *HistoryHandler
+	drainer        *drainer // closes terminals on shutdown
 	upgrader       websocket.Upgrader
 	logger         *logger.Logger
 }
 
 // NewTerminalHandler creates new terminal handler
-func NewTerminalHandler(client *podman.Client, wsTokenStore *auth.WSTokenStore, eventStore *events.Store, historyHandler *HistoryHandler, logger *logger.Logger) *TerminalHandler {
+func NewTerminalHandler(client *podman.Client, store storage.Storage, wsTokenStore *auth.WSTokenStore, eventStore *events.Store, historyHandler *HistoryHandler, drainer *drainer, logger *logger.Logger) *TerminalHandler {
 	h := &TerminalHandler{
 		client:         client,
+		storage:        store,
 		wsTokenStore:   wsTokenStore,
 		eventStore:     eventStore,
 		historyHandler: historyHandler,
+		drainer:        drainer,
 		logger:         logger,
 	}
 
@@ -67,44 +73,87 @@ func (h *TerminalHandler) checkOrigin(r *http.Request) bool {
 	// Get token from query parameter
 	token := r.URL.Query().Get("ws_token")
 	if token == "" {
-		h.logger.Printf("WebSocket rejected: missing ws_token")
+		requestLog(r, h.logger).Warn("WebSocket rejected: missing ws_token")
 		return false
 	}
 
 	// Validate token (allows reconnection within grace period)
-	username, valid := h.wsTokenStore.Validate(token)
+	username, valid := h.wsTokenStore.Validate(token, clientFingerprint(r))
 	if !valid {
-		h.logger.Printf("WebSocket rejected: invalid or expired ws_token (token may have exceeded max uses or grace period)")
+		requestLog(r, h.logger).Warn("WebSocket rejected: invalid or expired ws_token (token may have exceeded max uses, grace period, or was issued to another client)")
 		return false
 	}
 
-	h.logger.Printf("WebSocket connection authorized for user: %s (reconnection supported for %v)", username, auth.WSTokenGracePeriod)
+	requestLog(r, h.logger).Info("WebSocket connection authorized", logger.KeyUser, username, "grace_period", auth.WSTokenGracePeriod)
 	return true
 }
 
 // ExecMessage represents a WebSocket message
 type ExecMessage struct {
-	Type    string `json:"type"` // "stdin", "resize", "save_command"
-	Data    string `json:"data,omitempty"`
-	Command string `json:"command,omitempty"`
-	Cols    int    `json:"cols,omitempty"`
-	Rows    int    `json:"rows,omitempty"`
+	Type      string `json:"type"` // "stdin", "resize", "save_command", "upload", "download"
+	Data      string `json:"data,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Cols      int    `json:"cols,omitempty"`
+	Rows      int    `json:"rows,omitempty"`
+	Path      string `json:"path,omitempty"`      // file to upload or download, relative to the working directory of the shell
+	Overwrite bool   `json:"overwrite,omitempty"` // replace an existing file on upload
+}
+
+// terminalSocket is a terminal WebSocket, written to by the output, echo
+// and ping goroutines of a session
+type terminalSocket struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+// WriteMessage serializes writes to the connection
+func (s *terminalSocket) WriteMessage(messageType int, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Conn.WriteMessage(messageType, data)
+}
+
+// commandFilter returns the filter enforcing a policy in a session, logging
+// refused commands
+func (h *TerminalHandler) commandFilter(r *http.Request, user *auth.User, policy *TerminalPolicy, target string) *commandFilter {
+	ip := getClientIP(r)
+	return newCommandFilter(policy, func(line, reason string) {
+		if len(line) > 200 {
+			line = line[:200] + "..."
+		}
+		h.eventStore.Add(events.EventTerminalDenied, user.Username, ip, false, target+": "+line)
+		requestLog(r, h.logger).Warn("Terminal command denied", logger.KeyUser, user.Username, "target", target, "reason", reason)
+	})
 }
 
 // HostTerminal handles WebSocket connection for host terminal
 func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	policy, err := terminalPolicy(h.storage, user)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read terminal policy")
+		return
+	}
+
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
 		return
 	}
+	defer done()
+
+	reqLog := requestLog(r, h.logger)
 
 	// Upgrade HTTP to WebSocket
-	ws, err := h.upgrader.Upgrade(w, r, nil)
+	wsConn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		h.logger.Printf("WebSocket upgrade failed: %v", err)
+		reqLog.Error("WebSocket upgrade failed", logger.KeyError, err)
 		return
 	}
+	ws := &terminalSocket{Conn: wsConn}
 	defer ws.Close()
 
 	// Setup pong handler to keep connection alive
@@ -118,7 +167,7 @@ func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 	h.eventStore.Add(events.EventTerminalHost, user.Username, getClientIP(r), true, "")
 
 	// Send command history as first message
-	history := h.historyHandler.loadHistory()
+	history := h.historyHandler.loadHistory(storage.HostTarget)
 	if len(history) > 0 {
 		historyMsg := map[string]interface{}{
 			"type":     "history",
@@ -131,12 +180,15 @@ func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 
 	// Start shell process (use bash for better readline support)
 	cmd := exec.Command("/bin/bash")
-	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color", promptCommandEnv)
+	tracker := newCommandTracker(h.historyHandler, user.Username, storage.HostTarget)
+	filter := h.commandFilter(r, user, policy, storage.HostTarget)
+	files := &fileTransfer{tracker: tracker, policy: policy}
 
 	// Get PTY
 	ptmx, err := pty.Start(cmd)
 	if err != nil {
-		h.logger.Printf("Failed to start PTY: %v", err)
+		reqLog.Error("Failed to start PTY", logger.KeyError, err)
 		ws.WriteMessage(websocket.TextMessage, []byte("Failed to start shell: "+err.Error()))
 		return
 	}
@@ -147,6 +199,7 @@ func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
+	go closeWebSocketOnDrain(ctx, ws.Conn, closing)
 
 	// Ping ticker to keep connection alive
 	ticker := time.NewTicker(pingInterval)
@@ -161,7 +214,7 @@ func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 			case <-ticker.C:
 				ws.SetWriteDeadline(time.Now().Add(writeWait))
 				if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
-					h.logger.Printf("Failed to send ping: %v", err)
+					reqLog.Debug("Failed to send ping", logger.KeyError, err)
 					return
 				}
 			}
@@ -181,8 +234,8 @@ func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 					cancel()
 					return
 				}
-				if n > 0 {
-					if err := ws.WriteMessage(websocket.TextMessage, buf[:n]); err != nil {
+				if out := tracker.output(buf[:n]); len(out) > 0 {
+					if err := ws.WriteMessage(websocket.TextMessage, out); err != nil {
 						cancel()
 						return
 					}
@@ -206,13 +259,16 @@ func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 			var msg ExecMessage
 			if err := json.Unmarshal(message, &msg); err != nil {
 				// Treat as raw stdin
-				ptmx.Write(message)
-				continue
+				msg = ExecMessage{Type: "stdin", Data: string(message)}
 			}
 
 			switch msg.Type {
 			case "stdin":
-				ptmx.Write([]byte(msg.Data))
+				forward, echo := filter.input([]byte(msg.Data))
+				if len(echo) > 0 {
+					ws.WriteMessage(websocket.TextMessage, echo)
+				}
+				ptmx.Write(forward)
 			case "resize":
 				if msg.Cols > 0 && msg.Rows > 0 {
 					pty.Setsize(ptmx, &pty.Winsize{
@@ -223,31 +279,50 @@ func (h *TerminalHandler) HostTerminal(w http.ResponseWriter, r *http.Request) {
 			case "save_command":
 				// Save command to history
 				if msg.Command != "" {
-					h.historyHandler.saveCommand(msg.Command)
+					tracker.save(msg.Command)
 				}
+			case "upload", "download":
+				ws.WriteMessage(websocket.TextMessage, h.transfer(r, user, files, storage.HostTarget, msg))
 			}
 		}
 	}
 }
 
 // Connect handles WebSocket connection for container terminal
+// Read-only users can connect when their terminal policy is an allow list.
 func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
-	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+	if user == nil {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	policy, err := terminalPolicy(h.storage, user)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read terminal policy")
+		return
+	}
+	if policy == nil {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
+	closing, done, ok := h.drainer.track(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
 	containerID := chi.URLParam(r, "id")
+	reqLog := requestLog(r, h.logger).With("container", shortID(containerID))
 
 	// Create exec instance with TERM environment variable for proper terminal support
 	// Try to use bash if available (better readline support), otherwise fallback to sh
-	env := []string{"TERM=xterm-256color"}
+	env := []string{"TERM=xterm-256color", promptCommandEnv}
 	cmd := []string{"/bin/sh", "-c", "command -v bash >/dev/null 2>&1 && exec bash || exec sh"}
 	execResp, err := h.client.CreateExecWithEnv(r.Context(), containerID, cmd, env)
 	if err != nil {
-		h.logger.Printf("Failed to create exec: %v", err)
-		http.Error(w, "Failed to create exec: "+err.Error(), http.StatusInternalServerError)
+		reqLog.Error("Failed to create exec", logger.KeyError, err)
+		writeErr(w, r, err, "Failed to create exec")
 		return
 	}
 
@@ -255,8 +330,8 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	socketPath := h.client.GetSocketPath()
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
-		h.logger.Printf("Failed to connect to socket: %v", err)
-		http.Error(w, "Failed to connect to Podman", http.StatusInternalServerError)
+		reqLog.Error("Failed to connect to socket", logger.KeyError, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to connect to Podman")
 		return
 	}
 
@@ -274,8 +349,8 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	_, err = conn.Write([]byte(httpReq))
 	if err != nil {
 		conn.Close()
-		h.logger.Printf("Failed to send exec start: %v", err)
-		http.Error(w, "Failed to start exec", http.StatusInternalServerError)
+		reqLog.Error("Failed to send exec start", logger.KeyError, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to start exec")
 		return
 	}
 
@@ -284,28 +359,29 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	resp, err := http.ReadResponse(reader, nil)
 	if err != nil {
 		conn.Close()
-		h.logger.Printf("Failed to read response: %v", err)
-		http.Error(w, "Failed to start exec", http.StatusInternalServerError)
+		reqLog.Error("Failed to read exec start response", logger.KeyError, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to start exec")
 		return
 	}
 
-	h.logger.Printf("Exec start response: %d %s", resp.StatusCode, resp.Status)
+	reqLog.Debug("Exec start response", "status", resp.Status)
 
 	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
 		conn.Close()
 		body, _ := io.ReadAll(resp.Body)
-		h.logger.Printf("Exec start failed: %d %s", resp.StatusCode, string(body))
-		http.Error(w, "Exec start failed", http.StatusInternalServerError)
+		reqLog.Error("Exec start failed", "status", resp.Status, "body", string(body))
+		writeError(w, r, http.StatusInternalServerError, "Exec start failed")
 		return
 	}
 
 	// Upgrade HTTP to WebSocket
-	ws, err := h.upgrader.Upgrade(w, r, nil)
+	wsConn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		conn.Close()
-		h.logger.Printf("WebSocket upgrade failed: %v", err)
+		reqLog.Error("WebSocket upgrade failed", logger.KeyError, err)
 		return
 	}
+	ws := &terminalSocket{Conn: wsConn}
 
 	// Setup pong handler to keep connection alive
 	ws.SetReadDeadline(time.Now().Add(pongWait))
@@ -317,9 +393,14 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	// Log terminal connection
 	h.eventStore.Add(events.EventTerminalContainer, user.Username, getClientIP(r), true, shortID(containerID))
 
+	tracker := newCommandTracker(h.historyHandler, user.Username, containerID)
+	filter := h.commandFilter(r, user, policy, shortID(containerID))
+	files := &fileTransfer{client: h.client, containerID: containerID, tracker: tracker, policy: policy}
+
 	// Start proxying
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
+	go closeWebSocketOnDrain(ctx, ws.Conn, closing)
 
 	// Ping ticker to keep connection alive
 	ticker := time.NewTicker(pingInterval)
@@ -334,7 +415,7 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 			case <-ticker.C:
 				ws.SetWriteDeadline(time.Now().Add(writeWait))
 				if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
-					h.logger.Printf("Container terminal: Failed to send ping: %v", err)
+					reqLog.Debug("Failed to send ping", logger.KeyError, err)
 					return
 				}
 			}
@@ -357,13 +438,13 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 						continue
 					}
 					if err != io.EOF {
-						h.logger.Printf("Read from container error: %v", err)
+						reqLog.Warn("Read from container failed", logger.KeyError, err)
 					}
 					return
 				}
-				if n > 0 {
-					if err := ws.WriteMessage(websocket.TextMessage, buf[:n]); err != nil {
-						h.logger.Printf("WebSocket write error: %v", err)
+				if out := tracker.output(buf[:n]); len(out) > 0 {
+					if err := ws.WriteMessage(websocket.TextMessage, out); err != nil {
+						reqLog.Warn("WebSocket write failed", logger.KeyError, err)
 						return
 					}
 				}
@@ -382,7 +463,7 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 			_, message, err := ws.ReadMessage()
 			if err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					h.logger.Printf("WebSocket read error: %v", err)
+					reqLog.Warn("WebSocket read failed", logger.KeyError, err)
 				}
 				ws.Close()
 				conn.Close()
@@ -393,19 +474,20 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 			var msg ExecMessage
 			if err := json.Unmarshal(message, &msg); err != nil {
 				// Treat as raw stdin
-				if _, err := conn.Write(message); err != nil {
-					h.logger.Printf("Container write error: %v", err)
-					ws.Close()
-					conn.Close()
-					return
-				}
-				continue
+				msg = ExecMessage{Type: "stdin", Data: string(message)}
 			}
 
 			switch msg.Type {
 			case "stdin":
-				if _, err := conn.Write([]byte(msg.Data)); err != nil {
-					h.logger.Printf("Container write error: %v", err)
+				forward, echo := filter.input([]byte(msg.Data))
+				if len(echo) > 0 {
+					ws.WriteMessage(websocket.TextMessage, echo)
+				}
+				if len(forward) == 0 {
+					continue
+				}
+				if _, err := conn.Write(forward); err != nil {
+					reqLog.Warn("Container write failed", logger.KeyError, err)
 					ws.Close()
 					conn.Close()
 					return
@@ -413,6 +495,13 @@ func (h *TerminalHandler) Connect(w http.ResponseWriter, r *http.Request) {
 			case "resize":
 				// Resize is more complex with Podman, skip for now
 				// Would need to send resize request to exec instance
+			case "save_command":
+				// Save command to history (shared with the browser's local history)
+				if msg.Command != "" {
+					tracker.save(msg.Command)
+				}
+			case "upload", "download":
+				ws.WriteMessage(websocket.TextMessage, h.transfer(r, user, files, shortID(containerID), msg))
 			}
 		}
 	}
@@ -0,0 +1,554 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/storage"
+)
+
+const (
+	// terminalPolicyBucket is the storage namespace of the terminal command
+	// policies, kept under terminalPolicyKey
+	terminalPolicyBucket = "terminal"
+	terminalPolicyKey    = "policy"
+
+	// maxPolicyRules limits the rules of a policy
+	maxPolicyRules = 200
+	// maxPolicyRule limits the length of a rule
+	maxPolicyRule = 256
+	// maxCommandLine limits a line typed in a terminal with a policy
+	maxCommandLine = 4096
+)
+
+// Terminal policy modes
+const (
+	PolicyOff   = "off"   // commands are not checked
+	PolicyDeny  = "deny"  // commands matching a rule are refused
+	PolicyAllow = "allow" // only commands matching a rule run
+)
+
+var (
+	errPolicyMode     = apierror.New(http.StatusBadRequest, "Invalid mode, use off, deny or allow")
+	errPolicyReadOnly = apierror.New(http.StatusBadRequest, "Read-only users can only have an allow list")
+	errPolicyRules    = apierror.New(http.StatusBadRequest, fmt.Sprintf("Policies can have up to %d rules of up to %d characters", maxPolicyRules, maxPolicyRule))
+)
+
+// TerminalPolicy restricts the commands of a role in terminals. Rules are
+// commands with their first arguments ("rm -rf", "git push"); words may
+// have * and ? wildcards ("rm -*r*"). A rule matches commands starting
+// with its words, programs matched by name ("/bin/rm" is "rm").
+type TerminalPolicy struct {
+	Mode  string   `json:"mode"` // off, deny or allow
+	Rules []string `json:"rules"`
+}
+
+// TerminalPolicies are the command policies of terminals by role. With an
+// allow list, read-only users can open container terminals too.
+type TerminalPolicies struct {
+	Admin     TerminalPolicy `json:"admin"`
+	ReadOnly  TerminalPolicy `json:"readonly"`
+	UpdatedAt time.Time      `json:"updatedAt,omitempty"`
+	UpdatedBy string         `json:"updatedBy,omitempty"`
+}
+
+// loadTerminalPolicies returns the stored policies, no restrictions (and no
+// terminals for read-only users) if there are none
+func loadTerminalPolicies(store storage.Storage) (*TerminalPolicies, error) {
+	policies := &TerminalPolicies{
+		Admin:    TerminalPolicy{Mode: PolicyOff, Rules: []string{}},
+		ReadOnly: TerminalPolicy{Mode: PolicyOff, Rules: []string{}},
+	}
+	if store == nil {
+		return policies, nil
+	}
+	if err := store.GetJSON(terminalPolicyBucket, terminalPolicyKey, policies); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// terminalPolicy returns the policy of a user, nil if the user can't open
+// container terminals
+func terminalPolicy(store storage.Storage, user *auth.User) (*TerminalPolicy, error) {
+	policies, err := loadTerminalPolicies(store)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin() {
+		return &policies.Admin, nil
+	}
+	if policies.ReadOnly.Mode == PolicyAllow {
+		return &policies.ReadOnly, nil
+	}
+	return nil, nil
+}
+
+// validate checks and normalizes a policy
+func (p *TerminalPolicy) validate() error {
+	if p.Mode == "" {
+		p.Mode = PolicyOff
+	}
+	if p.Mode != PolicyOff && p.Mode != PolicyDeny && p.Mode != PolicyAllow {
+		return errPolicyMode
+	}
+	if len(p.Rules) > maxPolicyRules {
+		return errPolicyRules
+	}
+	rules := make([]string, 0, len(p.Rules))
+	for _, rule := range p.Rules {
+		rule = strings.Join(strings.Fields(rule), " ")
+		if rule == "" {
+			continue
+		}
+		if len(rule) > maxPolicyRule {
+			return errPolicyRules
+		}
+		for _, word := range strings.Fields(rule) {
+			if _, err := path.Match(word, ""); err != nil {
+				return apierror.New(http.StatusBadRequest, "Invalid rule: "+rule)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	p.Rules = rules
+	return nil
+}
+
+// check returns why a command line is refused, "" if it may run.
+// Every command of the line is checked, including those in pipes and
+// lists. With an allow list, lines whose commands can't be told before
+// they run are refused: command substitutions, subshells, variables and
+// wildcards in command names, history expansion, and output redirections,
+// which would write files.
+func (p *TerminalPolicy) check(line string) string {
+	if p == nil || p.Mode == PolicyOff || strings.TrimSpace(line) == "" {
+		return ""
+	}
+	parsed, err := parseCommandLine(line)
+	if err != nil {
+		return err.Error()
+	}
+	allow := p.Mode == PolicyAllow
+	if allow {
+		switch {
+		case parsed.substitution:
+			return "subshells and command substitution are not allowed"
+		case parsed.redirect:
+			return "output redirection is not allowed"
+		case parsed.history:
+			return "history expansion is not allowed"
+		}
+	}
+
+	for _, words := range parsed.commands {
+		if !allow {
+			// The options of wrappers can't be told from their arguments
+			// ("sudo -u root rm"), so any word after one may start the command
+			words, wrapped := skipCommandPrefix(words)
+			for i := range words {
+				if i > 0 && !wrapped {
+					break
+				}
+				if p.match(words[i:]) {
+					return strings.Join(append([]string{path.Base(words[i])}, words[i+1:]...), " ") + ": command denied"
+				}
+			}
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		name := words[0]
+		if strings.Contains(name, "=") {
+			return "variable assignments are not allowed"
+		}
+		if strings.ContainsAny(name, "$*?[{}()") {
+			return name + ": dynamic command names are not allowed"
+		}
+		if !p.match(words) {
+			return path.Base(name) + ": command not allowed"
+		}
+	}
+	return ""
+}
+
+// match reports whether a rule matches a command, comparing programs by
+// name
+func (p *TerminalPolicy) match(words []string) bool {
+	for _, rule := range p.Rules {
+		patterns := strings.Fields(rule)
+		if len(patterns) == 0 || len(patterns) > len(words) {
+			continue
+		}
+		matched := true
+		for i, pattern := range patterns {
+			word := words[i]
+			if i == 0 {
+				pattern, word = path.Base(pattern), path.Base(word)
+			}
+			if ok, _ := path.Match(pattern, word); !ok && pattern != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// commandPrefixes are words that run the command following them, skipped
+// to find the commands of deny lists ("sudo rm" is checked as "rm")
+var commandPrefixes = map[string]bool{
+	"!": true, "{": true, "}": true, "(": true, ")": true,
+	"if": true, "then": true, "else": true, "elif": true, "do": true, "while": true, "until": true,
+	"time": true, "command": true, "builtin": true, "exec": true, "nohup": true, "nice": true,
+	"sudo": true, "doas": true, "env": true, "xargs": true, "timeout": true, "busybox": true,
+}
+
+// skipCommandPrefix returns a command without the variable assignments
+// and wrappers before it, and whether there were wrappers
+func skipCommandPrefix(words []string) ([]string, bool) {
+	wrapped := false
+	for len(words) > 0 {
+		word := words[0]
+		switch {
+		case commandPrefixes[path.Base(word)]:
+			wrapped = true
+		case strings.Contains(word, "=") && !strings.HasPrefix(word, "="):
+		case wrapped && (strings.HasPrefix(word, "-") || strings.Trim(word, "0123456789.smhd") == ""):
+			// Options and durations of wrappers ("timeout 5s", "nice -n 10")
+		default:
+			return words, wrapped
+		}
+		words = words[1:]
+	}
+	return words, wrapped
+}
+
+// parsedCommandLine is a shell command line split into simple commands
+type parsedCommandLine struct {
+	commands     [][]string // words of each command, quotes removed
+	substitution bool       // $(...), `...`, <(...) or a subshell
+	redirect     bool       // output redirection to a file
+	history      bool       // ! or ^ history expansion
+}
+
+// parseCommandLine splits a line like the shell would into commands
+// separated by ;, &, | and newlines, removing quotes and backslashes.
+// Unterminated quotes and trailing backslashes are errors: the shell would
+// read the next line as part of the command.
+func parseCommandLine(line string) (*parsedCommandLine, error) {
+	parsed := &parsedCommandLine{history: strings.HasPrefix(strings.TrimSpace(line), "^")}
+	var words []string
+	var word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			parsed.commands = append(parsed.commands, words)
+			words = nil
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '\\':
+			if i+1 == len(line) {
+				return nil, errors.New("unterminated line")
+			}
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		case '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				switch {
+				case line[i] == '\\' && i+1 < len(line):
+					i++
+				case line[i] == '`', line[i] == '$' && i+1 < len(line) && line[i+1] == '(':
+					parsed.substitution = true
+				case line[i] == '!':
+					parsed.history = true
+				}
+				word.WriteByte(line[i])
+			}
+			if i == len(line) {
+				return nil, errors.New("unterminated quote")
+			}
+			inWord = true
+		case ' ', '\t':
+			endWord()
+		case ';', '&', '|', '\n', '\r':
+			endCommand()
+		case '(', ')':
+			// Subshells, and process substitutions after < or >
+			parsed.substitution = true
+			endCommand()
+		case '`':
+			parsed.substitution = true
+			endCommand()
+		case '>', '<':
+			endWord()
+			switch {
+			case i+1 < len(line) && line[i+1] == '(':
+				parsed.substitution = true
+			case i+1 < len(line) && line[i+1] == '&':
+				// Duplicated descriptors (2>&1) write no file
+				i++
+			case c == '>':
+				parsed.redirect = true
+			}
+		case '!':
+			parsed.history = true
+			word.WriteByte(c)
+			inWord = true
+		case '$':
+			if i+1 < len(line) && line[i+1] == '(' {
+				parsed.substitution = true
+			}
+			word.WriteByte(c)
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return parsed, nil
+}
+
+// commandFilter enforces a terminal policy on the input of a session.
+// Input is edited in a line buffer, echoed to the terminal, and only
+// forwarded to the shell when Enter is pressed and the line passes the
+// policy, so nothing reaches the shell unchecked. Line editing is limited
+// to backspace and Ctrl+U; Ctrl+C, Ctrl+D on an empty line, Ctrl+Z and
+// Ctrl+\ are passed through.
+type commandFilter struct {
+	policy *TerminalPolicy
+	denied func(line, reason string) // called for refused lines
+
+	line   []byte
+	escape int // state of a skipped escape sequence: 1 after ESC, 2 in a CSI or SS3 sequence
+}
+
+// newCommandFilter returns the filter of a policy, nil if nothing is
+// checked
+func newCommandFilter(policy *TerminalPolicy, denied func(line, reason string)) *commandFilter {
+	if policy == nil || policy.Mode == PolicyOff {
+		return nil
+	}
+	return &commandFilter{policy: policy, denied: denied}
+}
+
+// input processes terminal input and returns what to send to the shell and
+// what to echo to the terminal. Without a filter, input goes to the shell
+// as is.
+func (f *commandFilter) input(data []byte) (forward, echo []byte) {
+	if f == nil {
+		return data, nil
+	}
+	for _, c := range data {
+		switch {
+		case f.escape == 1:
+			// Arrow keys and other sequences are ignored
+			f.escape = 0
+			if c == '[' || c == 'O' {
+				f.escape = 2
+			}
+		case f.escape == 2:
+			if c >= 0x40 && c <= 0x7e {
+				f.escape = 0
+			}
+		case c == 0x1b:
+			f.escape = 1
+		case c == '\r' || c == '\n':
+			line := string(f.line)
+			if reason := f.policy.check(line); reason != "" {
+				// A new prompt follows the message
+				f.line = f.line[:0]
+				echo = append(echo, "\r\n\x1b[31mCommand not allowed: "+reason+"\x1b[0m"...)
+				if f.denied != nil {
+					f.denied(line, reason)
+				}
+				forward = append(forward, '\r')
+				continue
+			}
+			// The shell echoes the line it gets
+			echo = append(echo, f.erase()...)
+			forward = append(forward, line+"\r"...)
+		case c == 0x7f || c == 0x08:
+			if len(f.line) > 0 {
+				_, size := utf8.DecodeLastRune(f.line)
+				f.line = f.line[:len(f.line)-size]
+				echo = append(echo, "\b \b"...)
+			}
+		case c == 0x15:
+			echo = append(echo, f.erase()...)
+		case c == 0x03 || c == 0x1a || c == 0x1c:
+			echo = append(echo, f.erase()...)
+			forward = append(forward, c)
+		case c == 0x04:
+			if len(f.line) == 0 {
+				forward = append(forward, c)
+			}
+		case c < 0x20:
+			// Tab completion and other control keys are not available
+		case len(f.line) < maxCommandLine:
+			f.line = append(f.line, c)
+			echo = append(echo, c)
+		}
+	}
+	return forward, echo
+}
+
+// erase clears the line buffer and returns the output erasing its echo
+func (f *commandFilter) erase() []byte {
+	n := utf8.RuneCount(f.line)
+	f.line = f.line[:0]
+	return []byte(strings.Repeat("\b \b", n))
+}
+
+// TerminalPolicyHandler manages the terminal command policies
+type TerminalPolicyHandler struct {
+	storage    storage.Storage
+	eventStore *events.Store
+
+	// mu serializes updates
+	mu sync.Mutex
+}
+
+// NewTerminalPolicyHandler creates new terminal policy handler
+func NewTerminalPolicyHandler(store storage.Storage, eventStore *events.Store) *TerminalPolicyHandler {
+	return &TerminalPolicyHandler{storage: store, eventStore: eventStore}
+}
+
+// Get handles GET /api/terminal/policy (admin)
+func (h *TerminalPolicyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	policies, err := loadTerminalPolicies(h.storage)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read terminal policy")
+		return
+	}
+	writeJSON(w, http.StatusOK, policies)
+}
+
+// Update handles PUT /api/terminal/policy (admin)
+// The body replaces the policies. They apply to terminals opened
+// afterwards.
+func (h *TerminalPolicyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	if h.storage == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return
+	}
+
+	var policies TerminalPolicies
+	if err := json.NewDecoder(r.Body).Decode(&policies); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	for _, p := range []*TerminalPolicy{&policies.Admin, &policies.ReadOnly} {
+		if err := p.validate(); err != nil {
+			writeErr(w, r, err, "")
+			return
+		}
+	}
+	if policies.ReadOnly.Mode == PolicyDeny {
+		writeErr(w, r, errPolicyReadOnly, "")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	policies.UpdatedAt = time.Now().UTC()
+	policies.UpdatedBy = user.Username
+	details := fmt.Sprintf("terminal policy: admin %s (%d rules), readonly %s (%d rules)",
+		policies.Admin.Mode, len(policies.Admin.Rules), policies.ReadOnly.Mode, len(policies.ReadOnly.Rules))
+	if err := h.storage.SetJSON(terminalPolicyBucket, terminalPolicyKey, &policies); err != nil {
+		h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), false, "terminal policy")
+		writeErr(w, r, err, "Failed to save terminal policy")
+		return
+	}
+	h.eventStore.Add(events.EventSettingsUpdate, user.Username, getClientIP(r), true, details)
+	writeJSON(w, http.StatusOK, policies)
+}
+
+// Check handles POST /api/terminal/policy/check (admin)
+// Tells whether the policy of a role ("admin" or "readonly") lets a
+// command line run: {"role": "readonly", "command": "cat /etc/hosts"}
+func (h *TerminalPolicyHandler) Check(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req struct {
+		Role    auth.Role `json:"role"`
+		Command string    `json:"command"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	policies, err := loadTerminalPolicies(h.storage)
+	if err != nil {
+		writeErr(w, r, err, "Failed to read terminal policy")
+		return
+	}
+	var policy *TerminalPolicy
+	switch req.Role {
+	case auth.RoleAdmin:
+		policy = &policies.Admin
+	case auth.RoleReadOnly:
+		if policies.ReadOnly.Mode != PolicyAllow {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"allowed": false, "reason": "read-only users have no terminal access"})
+			return
+		}
+		policy = &policies.ReadOnly
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid role, use admin or readonly")
+		return
+	}
+
+	reason := policy.check(req.Command)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"allowed": reason == "", "reason": reason})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Raspberry Pi firmware throttling state, from the kernel (newer kernels)
+// or vcgencmd
+const (
+	throttledSysfsPath = "/sys/devices/platform/soc/soc:firmware/get_throttled"
+	vcgencmdTimeout    = 2 * time.Second
+	// throttledCacheTTL limits how often vcgencmd is run, as it is slower
+	// than reading sysfs and the dashboard polls every few seconds
+	throttledCacheTTL = 10 * time.Second
+)
+
+// Bits of the get_throttled value: the low bits are the current state,
+// the high bits whether it has happened since boot
+const (
+	throttledUnderVoltage    = 1 << 0
+	throttledFrequencyCapped = 1 << 1
+	throttledThrottled       = 1 << 2
+	throttledSoftTempLimit   = 1 << 3
+	throttledOccurredShift   = 16
+)
+
+// Throttling reports the Raspberry Pi firmware throttling flags. Under-
+// voltage (a weak power supply) and throttling explain slowness that
+// temperatures alone don't.
+type Throttling struct {
+	Raw                     string `json:"raw"` // get_throttled value in hex, e.g. 0x50005
+	UnderVoltage            bool   `json:"underVoltage"`
+	FrequencyCapped         bool   `json:"frequencyCapped"`
+	Throttled               bool   `json:"throttled"`
+	SoftTempLimit           bool   `json:"softTempLimit"`
+	UnderVoltageOccurred    bool   `json:"underVoltageOccurred"` // since boot
+	FrequencyCappedOccurred bool   `json:"frequencyCappedOccurred"`
+	ThrottledOccurred       bool   `json:"throttledOccurred"`
+	SoftTempLimitOccurred   bool   `json:"softTempLimitOccurred"`
+}
+
+// ParseThrottled parses a get_throttled value: the output of
+// "vcgencmd get_throttled" (throttled=0x50005) or the sysfs file (50005)
+func ParseThrottled(value string) (*Throttling, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "throttled=")
+	value = strings.TrimPrefix(strings.ToLower(value), "0x")
+	bits, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid get_throttled value %q", value)
+	}
+	flag := func(bit uint64) bool { return bits&bit != 0 }
+	return &Throttling{
+		Raw:                     fmt.Sprintf("0x%x", bits),
+		UnderVoltage:            flag(throttledUnderVoltage),
+		FrequencyCapped:         flag(throttledFrequencyCapped),
+		Throttled:               flag(throttledThrottled),
+		SoftTempLimit:           flag(throttledSoftTempLimit),
+		UnderVoltageOccurred:    flag(throttledUnderVoltage << throttledOccurredShift),
+		FrequencyCappedOccurred: flag(throttledFrequencyCapped << throttledOccurredShift),
+		ThrottledOccurred:       flag(throttledThrottled << throttledOccurredShift),
+		SoftTempLimitOccurred:   flag(throttledSoftTempLimit << throttledOccurredShift),
+	}, nil
+}
+
+// throttledCache remembers the last vcgencmd result and whether vcgencmd
+// exists at all
+var throttledCache struct {
+	sync.Mutex
+	checked     bool // looked for vcgencmd
+	vcgencmd    string
+	value       *Throttling
+	readAt      time.Time
+	unsupported bool
+}
+
+// getThrottling returns the throttling flags, or nil on hosts other than
+// a Raspberry Pi
+func getThrottling() *Throttling {
+	if data, err := os.ReadFile(throttledSysfsPath); err == nil {
+		if t, err := ParseThrottled(string(data)); err == nil {
+			return t
+		}
+	}
+
+	c := &throttledCache
+	c.Lock()
+	defer c.Unlock()
+	if !c.checked {
+		c.checked = true
+		c.vcgencmd, _ = exec.LookPath("vcgencmd")
+	}
+	if c.vcgencmd == "" || c.unsupported {
+		return nil
+	}
+	if c.value != nil && time.Since(c.readAt) < throttledCacheTTL {
+		return c.value
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vcgencmdTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, c.vcgencmd, "get_throttled").Output()
+	if err != nil {
+		return c.value
+	}
+	t, err := ParseThrottled(string(out))
+	if err != nil {
+		// vcgencmd without the firmware interface, e.g. in a container
+		c.unsupported = true
+		return nil
+	}
+	c.value, c.readAt = t, time.Now()
+	return t
+}
@@ -0,0 +1,501 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/podman"
+)
+
+const (
+	// defaultToolTimeout and maxToolTimeout limit DNS queries and port
+	// checks, in seconds
+	defaultToolTimeout = 5
+	maxToolTimeout     = 30
+
+	// defaultMaxHops and maxMaxHops limit traceroute
+	defaultMaxHops = 30
+	maxMaxHops     = 64
+
+	// tracerouteTimeout limits a whole traceroute run
+	tracerouteTimeout = 2 * time.Minute
+)
+
+// toolHostPattern is a host name or IP address. A leading '-' would be
+// read as an option by traceroute.
+var toolHostPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._:-]{0,252}$`)
+
+// dnsTypes are the record types of DNS queries
+var dnsTypes = map[string]bool{"A": true, "AAAA": true, "CNAME": true, "MX": true, "NS": true, "TXT": true, "SRV": true, "PTR": true}
+
+var (
+	errToolHost       = apierror.New(http.StatusBadRequest, "Invalid host")
+	errToolPort       = apierror.New(http.StatusBadRequest, "Invalid port")
+	errToolTimeout    = apierror.New(http.StatusBadRequest, "Invalid timeout parameter")
+	errDNSType        = apierror.New(http.StatusBadRequest, "Invalid record type, use A, AAAA, CNAME, MX, NS, TXT, SRV or PTR")
+	errDNSServer      = apierror.New(http.StatusBadRequest, "Invalid DNS server, use an IP address with an optional port")
+	errNoNameserver   = apierror.New(http.StatusUnprocessableEntity, "Container has no nameserver in resolv.conf")
+	errToolNotRunning = apierror.New(http.StatusConflict, "Container is not running")
+	errMaxHops        = apierror.New(http.StatusBadRequest, "Invalid maxHops parameter")
+	errNoTraceroute   = apierror.New(http.StatusNotImplemented, "traceroute is not installed on the host")
+)
+
+// ToolsHandler handles network debug tools, run from the host or from the
+// network namespace of a container
+type ToolsHandler struct {
+	client *podman.Client
+}
+
+// NewToolsHandler creates new network tools handler
+func NewToolsHandler(client *podman.Client) *ToolsHandler {
+	return &ToolsHandler{client: client}
+}
+
+// DNSResult is the answer to a DNS query. A failed lookup, e.g. an
+// unknown name, is a result with an error.
+type DNSResult struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Server     string   `json:"server,omitempty"` // empty for the resolver of the host
+	Container  string   `json:"container,omitempty"`
+	Records    []string `json:"records"`
+	DurationMs float64  `json:"durationMs"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// PortResult is the result of a TCP connection attempt
+type PortResult struct {
+	Host       string  `json:"host"`
+	Port       int     `json:"port"`
+	Container  string  `json:"container,omitempty"`
+	Address    string  `json:"address,omitempty"` // the address connected to
+	Open       bool    `json:"open"`
+	DurationMs float64 `json:"durationMs"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// TracerouteHop is a hop of a traceroute. Address and RTTMs are empty for
+// hops that didn't answer.
+type TracerouteHop struct {
+	Hop     int      `json:"hop"`
+	Address string   `json:"address,omitempty"`
+	RTTMs   *float64 `json:"rttMs"`
+}
+
+// TracerouteResult is the route to a host
+type TracerouteResult struct {
+	Host      string          `json:"host"`
+	Container string          `json:"container,omitempty"`
+	Hops      []TracerouteHop `json:"hops"`
+	Output    string          `json:"output"` // traceroute output
+	Error     string          `json:"error,omitempty"`
+}
+
+// netScope is where a tool runs: on the host, or in the network namespace
+// of a running container
+type netScope struct {
+	container string
+	pid       int // 0 for the host
+}
+
+// DNS handles GET /api/tools/dns?name=example.com&type=A&server=1.1.1.1&container=web
+// Queries DNS records. Without server, the host resolver is used, or the
+// first nameserver of the container.
+func (h *ToolsHandler) DNS(w http.ResponseWriter, r *http.Request) {
+	if !auth.GetUserFromContext(r.Context()).IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	q := r.URL.Query()
+	name := strings.TrimSuffix(q.Get("name"), ".")
+	if !toolHostPattern.MatchString(name) {
+		writeErr(w, r, errToolHost, "")
+		return
+	}
+	recordType := strings.ToUpper(q.Get("type"))
+	if recordType == "" {
+		recordType = "A"
+	}
+	if !dnsTypes[recordType] {
+		writeErr(w, r, errDNSType, "")
+		return
+	}
+	server := ""
+	if v := q.Get("server"); v != "" {
+		var ok bool
+		if server, ok = dnsServerAddress(v); !ok {
+			writeErr(w, r, errDNSServer, "")
+			return
+		}
+	}
+	timeout, err := toolTimeout(q.Get("timeout"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	scope, err := h.scope(r.Context(), q.Get("container"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	resolver, server, err := scope.resolver(server)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	result := DNSResult{Name: name, Type: recordType, Server: server, Container: scope.container, Records: []string{}}
+	start := time.Now()
+	records, err := lookup(ctx, resolver, name, recordType)
+	result.DurationMs = durationMs(time.Since(start))
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Records = records
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Port handles GET /api/tools/port?host=db&port=5432&container=web&timeout=5
+// Opens a TCP connection to check whether a port is reachable.
+func (h *ToolsHandler) Port(w http.ResponseWriter, r *http.Request) {
+	if !auth.GetUserFromContext(r.Context()).IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	q := r.URL.Query()
+	host := q.Get("host")
+	if !toolHostPattern.MatchString(host) {
+		writeErr(w, r, errToolHost, "")
+		return
+	}
+	port, err := strconv.Atoi(q.Get("port"))
+	if err != nil || port < 1 || port > 65535 {
+		writeErr(w, r, errToolPort, "")
+		return
+	}
+	timeout, err := toolTimeout(q.Get("timeout"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	scope, err := h.scope(r.Context(), q.Get("container"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	result := PortResult{Host: host, Port: port, Container: scope.container}
+	start := time.Now()
+	ip, err := scope.lookupIP(ctx, host)
+	if err == nil {
+		result.Address = net.JoinHostPort(ip, strconv.Itoa(port))
+		var conn net.Conn
+		if conn, err = scope.dial(ctx, "tcp", result.Address); err == nil {
+			conn.Close()
+			result.Open = true
+		}
+	}
+	result.DurationMs = durationMs(time.Since(start))
+	if err != nil {
+		result.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Traceroute handles GET /api/tools/traceroute?host=example.com&container=web&maxHops=30
+// Runs traceroute, which must be installed on the host. In a container it
+// runs in the network namespace of the container.
+func (h *ToolsHandler) Traceroute(w http.ResponseWriter, r *http.Request) {
+	if !auth.GetUserFromContext(r.Context()).IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+	q := r.URL.Query()
+	host := q.Get("host")
+	if !toolHostPattern.MatchString(host) {
+		writeErr(w, r, errToolHost, "")
+		return
+	}
+	maxHops := defaultMaxHops
+	if v := q.Get("maxHops"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxMaxHops {
+			writeErr(w, r, errMaxHops, "")
+			return
+		}
+		maxHops = n
+	}
+	path, err := exec.LookPath("traceroute")
+	if err != nil {
+		writeErr(w, r, errNoTraceroute, "")
+		return
+	}
+	scope, err := h.scope(r.Context(), q.Get("container"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tracerouteTimeout)
+	defer cancel()
+	// Numeric output, one probe per hop, wait up to 2 seconds for it
+	cmd := exec.CommandContext(ctx, path, "-n", "-q", "1", "-w", "2", "-m", strconv.Itoa(maxHops), host)
+	var output strings.Builder
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if scope.pid == 0 {
+		err = cmd.Start()
+	} else {
+		// The child inherits the namespace of the thread starting it
+		err = inNetNS(scope.pid, cmd.Start)
+	}
+	if err != nil {
+		writeErr(w, r, err, "Failed to run traceroute")
+		return
+	}
+	err = cmd.Wait()
+
+	result := TracerouteResult{Host: host, Container: scope.container, Hops: parseTraceroute(output.String()), Output: output.String()}
+	if err != nil {
+		result.Error = "traceroute failed"
+		if ctx.Err() != nil {
+			result.Error = "traceroute timed out"
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// scope returns where tools run: on the host if container is empty, or in
+// the network namespace of the container, which must be running
+func (h *ToolsHandler) scope(ctx context.Context, container string) (*netScope, error) {
+	if container == "" {
+		return &netScope{}, nil
+	}
+	info, err := h.client.InspectContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+	if !info.State.Running || info.State.Pid <= 0 {
+		return nil, errToolNotRunning
+	}
+	return &netScope{container: strings.TrimPrefix(info.Name, "/"), pid: info.State.Pid}, nil
+}
+
+// dial connects to address, from the network namespace of the scope. The
+// address must be numeric, the host resolver doesn't run in the namespace.
+func (s *netScope) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	if s.pid == 0 {
+		return dialer.DialContext(ctx, network, address)
+	}
+	var conn net.Conn
+	err := inNetNS(s.pid, func() (err error) {
+		conn, err = dialer.DialContext(ctx, network, address)
+		return err
+	})
+	return conn, err
+}
+
+// resolver returns a resolver querying server, which is host:port or
+// empty. In a container an empty server is its first nameserver. The
+// server used is returned.
+func (s *netScope) resolver(server string) (*net.Resolver, string, error) {
+	if server == "" && s.pid == 0 {
+		return net.DefaultResolver, "", nil
+	}
+	if server == "" {
+		nameservers, err := readNameservers("/proc/" + strconv.Itoa(s.pid) + "/root/etc/resolv.conf")
+		if err != nil {
+			return nil, "", err
+		}
+		if len(nameservers) == 0 {
+			return nil, "", errNoNameserver
+		}
+		server = net.JoinHostPort(nameservers[0], "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return s.dial(ctx, network, server)
+		},
+	}, server, nil
+}
+
+// lookupIP resolves host to an IP address, preferring IPv4
+func (s *netScope) lookupIP(ctx context.Context, host string) (string, error) {
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	resolver, _, err := s.resolver("")
+	if err != nil {
+		return "", err
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP.String(), nil
+		}
+	}
+	return addrs[0].IP.String(), nil
+}
+
+// lookup queries the records of a type
+func lookup(ctx context.Context, resolver *net.Resolver, name, recordType string) ([]string, error) {
+	var records []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, cname)
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			records = append(records, strconv.Itoa(int(mx.Pref))+" "+mx.Host)
+		}
+	case "NS":
+		nss, err := resolver.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			records = append(records, ns.Host)
+		}
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, txts...)
+	case "SRV":
+		_, srvs, err := resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range srvs {
+			records = append(records, strconv.Itoa(int(srv.Priority))+" "+strconv.Itoa(int(srv.Weight))+" "+strconv.Itoa(int(srv.Port))+" "+srv.Target)
+		}
+	case "PTR":
+		names, err := resolver.LookupAddr(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, names...)
+	}
+	if records == nil {
+		records = []string{}
+	}
+	return records, nil
+}
+
+// dnsServerAddress returns a DNS server as ip:port, port 53 by default
+func dnsServerAddress(server string) (string, bool) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = strings.Trim(server, "[]"), "53"
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 || net.ParseIP(host) == nil {
+		return "", false
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// readNameservers returns the nameservers of a resolv.conf file
+func readNameservers(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var nameservers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			// Drop the zone of link-local IPv6 addresses
+			if ip, _, _ := strings.Cut(fields[1], "%"); net.ParseIP(ip) != nil {
+				nameservers = append(nameservers, ip)
+			}
+		}
+	}
+	return nameservers, scanner.Err()
+}
+
+// toolTimeout parses the timeout parameter in seconds
+func toolTimeout(v string) (time.Duration, error) {
+	if v == "" {
+		return defaultToolTimeout * time.Second, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxToolTimeout {
+		return 0, errToolTimeout
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// parseTraceroute reads the hops of numeric traceroute output with one
+// probe per hop, e.g. " 2  192.168.1.1  0.512 ms" or " 3  *"
+func parseTraceroute(output string) []TracerouteHop {
+	hops := []TracerouteHop{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue // the header or an error
+		}
+		hop := TracerouteHop{Hop: n}
+		if fields[1] != "*" {
+			hop.Address = fields[1]
+			if len(fields) >= 4 && fields[3] == "ms" {
+				if rtt, err := strconv.ParseFloat(fields[2], 64); err == nil {
+					hop.RTTMs = &rtt
+				}
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// durationMs returns d in milliseconds, to the microsecond
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
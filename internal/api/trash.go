@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// trashBucket is the storage namespace of removed containers. It is a
+// sensitive bucket, since specs carry the environment of the container.
+const trashBucket = "trash"
+
+// trashRetention is how long removed containers can be restored
+const trashRetention = 7 * 24 * time.Hour
+
+// TrashEntry is the spec of a removed container, kept to restore it
+type TrashEntry struct {
+	ID          string         `json:"id"` // short ID of the removed container
+	ContainerID string         `json:"containerId"`
+	Name        string         `json:"name"`
+	Image       string         `json:"image"`
+	Spec        *ContainerSpec `json:"spec"`
+	Volumes     []string       `json:"volumes"` // named volumes, kept by the removal
+	WasRunning  bool           `json:"wasRunning"`
+	RemovedBy   string         `json:"removedBy"`
+	RemovedAt   time.Time      `json:"removedAt"`
+	ExpiresAt   time.Time      `json:"expiresAt"`
+}
+
+// TrashHandler keeps the specs of removed containers and restores them
+type TrashHandler struct {
+	client     *podman.Client
+	storage    storage.Storage
+	eventStore *events.Store
+}
+
+// NewTrashHandler creates new trash handler
+func NewTrashHandler(client *podman.Client, store storage.Storage, eventStore *events.Store) *TrashHandler {
+	return &TrashHandler{client: client, storage: store, eventStore: eventStore}
+}
+
+// keep saves the spec of a container before it is removed
+func (h *TrashHandler) keep(ctx context.Context, id, username string) (*TrashEntry, error) {
+	if h.storage == nil {
+		return nil, apierror.New(http.StatusServiceUnavailable, "Storage not available")
+	}
+	info, err := h.client.InspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	image, err := h.client.InspectImage(ctx, info.Image)
+	if err != nil {
+		image = &podman.ImageInspect{}
+	}
+
+	spec := buildContainerSpec(info, image)
+	now := time.Now()
+	entry := &TrashEntry{
+		ID:          shortID(info.ID),
+		ContainerID: info.ID,
+		Name:        spec.Name,
+		Image:       spec.Image,
+		Spec:        spec,
+		Volumes:     []string{},
+		WasRunning:  info.State.Running,
+		RemovedBy:   username,
+		RemovedAt:   now,
+		ExpiresAt:   now.Add(trashRetention),
+	}
+	for _, m := range spec.Mounts {
+		if m.Type == "volume" {
+			entry.Volumes = append(entry.Volumes, m.Source)
+		}
+	}
+	if err := h.storage.SetJSONWithTTL(trashBucket, entry.ID, entry, trashRetention); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// discard drops an entry, e.g. when the removal failed
+func (h *TrashHandler) discard(id string) {
+	h.storage.Delete(trashBucket, id)
+}
+
+// get returns an entry of the trash
+func (h *TrashHandler) get(id string) (*TrashEntry, error) {
+	if h.storage == nil {
+		return nil, apierror.New(http.StatusServiceUnavailable, "Storage not available")
+	}
+	var entry TrashEntry
+	if err := h.storage.GetJSON(trashBucket, id, &entry); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, apierror.New(http.StatusNotFound, "Container not found in trash")
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// List handles GET /api/containers/trash
+// Returns the removed containers that can still be restored, newest first
+func (h *TrashHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	entries := []TrashEntry{}
+	if h.storage != nil {
+		data, err := h.storage.List(trashBucket)
+		if err != nil {
+			writeErr(w, r, err, "Failed to read trash")
+			return
+		}
+		for _, value := range data {
+			var entry TrashEntry
+			if json.Unmarshal(value, &entry) == nil {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].RemovedAt.After(entries[j].RemovedAt)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"containers":    entries,
+		"retentionDays": int(trashRetention.Hours() / 24),
+	})
+}
+
+// Restore handles POST /api/containers/trash/{id}/restore?name=&start=true
+// Creates the container again from its spec, under its old name unless
+// name is given, and starts it if start=true. The image is pulled if it was
+// removed meanwhile. Named volumes that no longer exist are created empty
+// by Podman and listed as missingVolumes.
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	entry, err := h.get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	spec := *entry.Spec
+	if name := r.URL.Query().Get("name"); name != "" {
+		spec.Name = name
+	}
+	config, err := spec.createConfig()
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+
+	missing := []string{}
+	if len(entry.Volumes) > 0 {
+		if volumes, err := h.client.ListVolumes(r.Context()); err == nil {
+			existing := make(map[string]bool, len(volumes))
+			for _, v := range volumes {
+				existing[v.Name] = true
+			}
+			for _, name := range entry.Volumes {
+				if !existing[name] {
+					missing = append(missing, name)
+				}
+			}
+		}
+	}
+
+	if _, err := h.client.InspectImage(r.Context(), spec.Image); err != nil {
+		if err := h.client.PullImagePlatform(r.Context(), spec.Image, spec.Platform); err != nil {
+			h.eventStore.Add(events.EventContainerRestore, user.Username, getClientIP(r), false, spec.Name)
+			writeErr(w, r, err, "Failed to pull image")
+			return
+		}
+	}
+
+	result, err := h.client.CreateContainer(r.Context(), config)
+	if err != nil {
+		h.eventStore.Add(events.EventContainerRestore, user.Username, getClientIP(r), false, spec.Name)
+		writeErr(w, r, err, "Failed to restore container")
+		return
+	}
+	h.discard(entry.ID)
+	h.eventStore.Add(events.EventContainerRestore, user.Username, getClientIP(r), true, spec.Name+" ("+shortID(result.ID)+")")
+
+	response := map[string]interface{}{
+		"id":             result.ID,
+		"name":           spec.Name,
+		"status":         "created",
+		"missingVolumes": missing,
+	}
+	if r.URL.Query().Get("start") == "true" {
+		if err := h.client.StartContainer(r.Context(), result.ID); err != nil {
+			response["warning"] = "Container restored but failed to start: " + err.Error()
+		} else {
+			response["status"] = "started"
+		}
+	}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// Delete handles DELETE /api/containers/trash/{id}
+// Drops a removed container for good
+func (h *TrashHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	entry, err := h.get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if err := h.storage.Delete(trashBucket, entry.ID); err != nil {
+		writeErr(w, r, err, "Failed to delete from trash")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted", "name": entry.Name})
+}
@@ -36,12 +36,12 @@ func NewUpdateHandler(u *updater.Updater, eventStore *events.Store, logger *logg
 // Check handles GET /api/system/update/check
 func (h *UpdateHandler) Check(w http.ResponseWriter, r *http.Request) {
 	if h.updater == nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Updater not available"})
+		writeError(w, r, http.StatusInternalServerError, "Updater not available")
 		return
 	}
 	result, err := h.updater.CheckUpdate(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
@@ -69,7 +69,7 @@ func (h *UpdateHandler) Status(w http.ResponseWriter, r *http.Request) {
 func (h *UpdateHandler) Perform(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		writeError(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -77,7 +77,7 @@ func (h *UpdateHandler) Perform(w http.ResponseWriter, r *http.Request) {
 	h.updateMu.Lock()
 	if h.updating {
 		h.updateMu.Unlock()
-		writeJSON(w, http.StatusConflict, map[string]string{"error": "Update already in progress"})
+		writeError(w, r, http.StatusConflict, "Update already in progress")
 		return
 	}
 	h.updating = true
@@ -87,6 +87,7 @@ func (h *UpdateHandler) Perform(w http.ResponseWriter, r *http.Request) {
 	clientIP := getClientIP(r)
 
 	// Run update in background
+	reqLog := requestLog(r, h.logger)
 	go func() {
 		defer func() {
 			h.updateMu.Lock()
@@ -98,12 +99,12 @@ func (h *UpdateHandler) Perform(w http.ResponseWriter, r *http.Request) {
 			h.updateMu.Lock()
 			h.updateStatus = &p
 			h.updateMu.Unlock()
-			h.logger.Printf("Update progress: %s (%d%%)", p.Stage, p.Percent)
+			reqLog.Info("Update progress", "stage", p.Stage, "percent", p.Percent)
 		})
 
 		if err != nil {
 			h.eventStore.Add(events.EventSystemUpdate, user.Username, clientIP, false, err.Error())
-			h.logger.Printf("Update failed: %v", err)
+			reqLog.Error("Update failed", "error", err)
 
 			h.updateMu.Lock()
 			h.updateStatus = &updater.UpdateProgress{
@@ -116,15 +117,15 @@ func (h *UpdateHandler) Perform(w http.ResponseWriter, r *http.Request) {
 		}
 
 		h.eventStore.Add(events.EventSystemUpdate, user.Username, clientIP, true, "")
-		h.logger.Println("Update completed successfully")
+		reqLog.Info("Update completed successfully")
 
 		// Wait a moment for clients to receive status
 		time.Sleep(2 * time.Second)
 
 		// Restart service
-		h.logger.Println("Restarting service...")
+		reqLog.Info("Restarting service...")
 		if err := updater.RestartService(); err != nil {
-			h.logger.Printf("Failed to restart service: %v", err)
+			reqLog.Error("Failed to restart service", "error", err)
 		}
 	}()
 
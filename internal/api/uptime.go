@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"podmanview/internal/logger"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// uptimeBucket is the storage namespace of container state histories
+const uptimeBucket = "uptime"
+
+const (
+	// uptimeRetention is the longest uptime period, how long state
+	// changes are kept
+	uptimeRetention = 30 * 24 * time.Hour
+
+	// maxStateChanges limits the state changes kept per container
+	maxStateChanges = 2000
+)
+
+// StateChange is a container going up (start) or down (stop, exit, pause)
+type StateChange struct {
+	Time time.Time `json:"time"`
+	Up   bool      `json:"up"`
+}
+
+// ContainerUptime is the share of time a container was running, in percent
+// over the last 24 hours, 7 days and 30 days. Time before the first
+// recorded state is left out; a period without any is null.
+type ContainerUptime struct {
+	Day   *float64 `json:"day"`
+	Week  *float64 `json:"week"`
+	Month *float64 `json:"month"`
+}
+
+// upActions and downActions are the Podman container events changing
+// whether a container serves
+var (
+	upActions   = map[string]bool{"start": true, "restart": true, "unpause": true}
+	downActions = map[string]bool{"died": true, "stop": true, "pause": true, "remove": true}
+)
+
+// uptimeTracker records when containers go up and down, by name, to
+// compute their uptime
+type uptimeTracker struct {
+	storage storage.Storage // nil keeps the history in memory only
+	logger  *logger.Logger
+
+	mu      sync.Mutex
+	changes map[string][]StateChange // by container name, oldest first
+}
+
+// newUptimeTracker creates an uptime tracker and loads the stored histories
+func newUptimeTracker(store storage.Storage, appLogger *logger.Logger) *uptimeTracker {
+	t := &uptimeTracker{storage: store, logger: appLogger, changes: make(map[string][]StateChange)}
+	if store == nil {
+		return t
+	}
+
+	data, err := store.List(uptimeBucket)
+	if err != nil {
+		appLogger.Warn("Failed to load container uptime", logger.KeyError, err)
+		return t
+	}
+	for name, value := range data {
+		var changes []StateChange
+		if err := json.Unmarshal(value, &changes); err != nil {
+			appLogger.Warn("Failed to load container uptime", "container", name, logger.KeyError, err)
+			continue
+		}
+		t.changes[name] = changes
+	}
+	return t
+}
+
+// observe records a state change from a Podman container event
+func (t *uptimeTracker) observe(event podman.EngineEvent) {
+	if event.Type != "container" || (!upActions[event.Action] && !downActions[event.Action]) {
+		return
+	}
+	name := event.Actor.Attributes["name"]
+	if name == "" {
+		name = shortID(event.Actor.ID)
+	}
+	at := time.Now()
+	if event.TimeNano > 0 {
+		at = time.Unix(0, event.TimeNano)
+	}
+	t.record(name, StateChange{Time: at, Up: upActions[event.Action]})
+}
+
+// reconcile records the current state of containers whose last recorded
+// state differs, e.g. after changes missed while PodmanView was not running
+func (t *uptimeTracker) reconcile(containers []podman.Container) {
+	now := time.Now()
+	for _, c := range containers {
+		if c.IsInfra {
+			continue
+		}
+		name := firstOf(c.Names)
+		up := c.State == "running"
+		at := c.ExitedAt
+		if up {
+			at = c.StartedAt
+		}
+
+		t.mu.Lock()
+		changes := t.changes[name]
+		t.mu.Unlock()
+		if len(changes) > 0 && changes[len(changes)-1].Up == up {
+			continue
+		}
+		if len(changes) == 0 && !up && at <= 0 {
+			continue // never started
+		}
+		change := StateChange{Time: now, Up: up}
+		if at > 0 && time.Unix(at, 0).Before(now) && (len(changes) == 0 || time.Unix(at, 0).After(changes[len(changes)-1].Time)) {
+			change.Time = time.Unix(at, 0)
+		}
+		t.record(name, change)
+	}
+}
+
+// record adds a state change in time order unless the state before it is
+// the same. Events can arrive after a change taken from reconcile.
+func (t *uptimeTracker) record(name string, change StateChange) {
+	t.mu.Lock()
+	changes := t.changes[name]
+	i := sort.Search(len(changes), func(i int) bool { return changes[i].Time.After(change.Time) })
+	if i > 0 && changes[i-1].Up == change.Up {
+		t.mu.Unlock()
+		return
+	}
+	// Readers use the slice without the lock, so it is copied
+	changes = slices.Insert(slices.Clone(changes), i, change)
+	if i+1 < len(changes) && changes[i+1].Up == change.Up {
+		changes = slices.Delete(changes, i+1, i+2) // now repeats the state
+	}
+	changes = pruneStateChanges(changes, time.Now())
+	t.changes[name] = changes
+	t.mu.Unlock()
+
+	if t.storage != nil {
+		if err := t.storage.SetJSON(uptimeBucket, name, changes); err != nil {
+			t.logger.Warn("Failed to save container uptime", "container", name, logger.KeyError, err)
+		}
+	}
+}
+
+// pruneStateChanges drops changes older than the retention and over the
+// limit, keeping the last older one: it is the state at the period start
+func pruneStateChanges(changes []StateChange, now time.Time) []StateChange {
+	start := 0
+	for start+1 < len(changes) && now.Sub(changes[start+1].Time) > uptimeRetention {
+		start++
+	}
+	start = max(start, len(changes)-maxStateChanges)
+	return changes[start:]
+}
+
+// uptime returns the uptime of a container at now
+func (t *uptimeTracker) uptime(name string, now time.Time) *ContainerUptime {
+	t.mu.Lock()
+	changes := t.changes[name]
+	t.mu.Unlock()
+	if len(changes) == 0 {
+		return nil
+	}
+	return &ContainerUptime{
+		Day:   uptimePercent(changes, now.Add(-24*time.Hour), now),
+		Week:  uptimePercent(changes, now.Add(-7*24*time.Hour), now),
+		Month: uptimePercent(changes, now.Add(-uptimeRetention), now),
+	}
+}
+
+// uptimePercent returns the share of the recorded time between since and
+// now the container was up, rounded to hundredths of a percent
+func uptimePercent(changes []StateChange, since, now time.Time) *float64 {
+	var up, recorded time.Duration
+	for i, change := range changes {
+		start, end := change.Time, now
+		if i+1 < len(changes) {
+			end = changes[i+1].Time
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if !end.After(start) {
+			continue
+		}
+		recorded += end.Sub(start)
+		if change.Up {
+			up += end.Sub(start)
+		}
+	}
+	if recorded == 0 {
+		return nil
+	}
+	percent := math.Round(float64(up)/float64(recorded)*10000) / 100
+	return &percent
+}
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"strings"
+
+	"podmanview/internal/auth"
 )
 
 // shortID returns first 12 characters of an ID (safe for short IDs)
@@ -13,27 +18,92 @@ func shortID(id string) string {
 	return id
 }
 
-// getClientIP extracts client IP from request, considering reverse proxy headers
+// formatBytes formats bytes as human-readable string
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// clientIPKey is the context key of the client IP, set by clientIP
+type clientIPKey struct{}
+
+// clientIP resolves the client IP of each request. X-Real-IP and
+// X-Forwarded-For are set by clients as they like, so they are only
+// honoured from the trusted proxies, and from unix sockets, which only a
+// local proxy can reach. Of X-Forwarded-For, the last address not of a
+// trusted proxy is used, since earlier ones can be sent by the client.
+func clientIP(trusted []string) func(http.Handler) http.Handler {
+	var nets []*net.IPNet
+	for _, proxy := range trusted {
+		if _, n, err := net.ParseCIDR(proxy); err == nil {
+			nets = append(nets, n)
+		} else if ip := net.ParseIP(proxy); ip != nil {
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		}
+	}
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		for _, n := range nets {
+			if ip != nil && n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if net.ParseIP(ip) == nil || isTrusted(ip) {
+				if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+					ip = realIP
+				} else if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+					hops := strings.Split(xff, ",")
+					for i := len(hops) - 1; i >= 0; i-- {
+						hop := strings.TrimSpace(hops[i])
+						if net.ParseIP(hop) == nil {
+							break
+						}
+						ip = hop
+						if !isTrusted(hop) {
+							break
+						}
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+// getClientIP returns the client IP of a request, see clientIP
 func getClientIP(r *http.Request) string {
-	// Check X-Real-IP first (set by nginx)
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
 		return ip
 	}
+	return remoteIP(r)
+}
 
-	// Check X-Forwarded-For (can contain multiple IPs)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP (original client)
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
-		}
-		return strings.TrimSpace(xff)
+// remoteIP returns the address of the peer without the port
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
 	}
+	return r.RemoteAddr
+}
 
-	// Fall back to RemoteAddr
-	// Remove port if present
-	addr := r.RemoteAddr
-	if idx := strings.LastIndex(addr, ":"); idx != -1 {
-		return addr[:idx]
+// clientFingerprint returns the client identity used to bind WebSocket tokens
+func clientFingerprint(r *http.Request) auth.ClientFingerprint {
+	return auth.ClientFingerprint{
+		IP:        getClientIP(r),
+		UserAgent: r.UserAgent(),
 	}
-	return addr
 }
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIVersion is the current API version. Routes are registered as
+// /api/..., served as /api/v1/... and, deprecated, at their unversioned path.
+const APIVersion = "v1"
+
+// apiVersionPrefix is the path prefix of the current API version
+const apiVersionPrefix = "/api/" + APIVersion
+
+// legacyAPI announces the deprecation of the unversioned /api paths
+var legacyAPI = deprecation{
+	Since:  time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+	Sunset: time.Date(2027, 10, 16, 0, 0, 0, 0, time.UTC),
+}
+
+// deprecation describes a deprecated API path, announced with the
+// Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers
+type deprecation struct {
+	Since  time.Time // deprecated since
+	Sunset time.Time // removed after, zero = not scheduled
+}
+
+// apiVersionKey is the context key of the API version of a request
+type apiVersionKey struct{}
+
+// apiVersion routes /api/v1/... requests to the /api/... routes and marks
+// requests to unversioned API paths as deprecated, pointing to the
+// versioned path. Must run before middleware that checks the path.
+func (s *Server) apiVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == apiVersionPrefix || strings.HasPrefix(path, apiVersionPrefix+"/"):
+			u := *r.URL
+			u.Path = "/api" + strings.TrimPrefix(path, apiVersionPrefix)
+			// Routes match the escaped path when there is one, so that
+			// escaped slashes stay in their path parameter
+			if strings.HasPrefix(u.RawPath, apiVersionPrefix) {
+				u.RawPath = "/api" + strings.TrimPrefix(u.RawPath, apiVersionPrefix)
+			} else {
+				u.RawPath = ""
+			}
+			r = r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, APIVersion))
+			r.URL = &u
+		case strings.HasPrefix(path, "/api/"):
+			successor := s.config.BasePath() + apiVersionPrefix + strings.TrimPrefix(path, "/api")
+			setDeprecation(w.Header(), legacyAPI, successor)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestAPIVersion returns the API version of a request, empty for
+// unversioned (deprecated) paths. Handlers use it to drop deprecated
+// response fields from versioned responses.
+func requestAPIVersion(r *http.Request) string {
+	version, _ := r.Context().Value(apiVersionKey{}).(string)
+	return version
+}
+
+// setDeprecation writes the deprecation headers, with successor as the
+// URL that replaces the deprecated one
+func setDeprecation(h http.Header, d deprecation, successor string) {
+	h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if successor != "" {
+		h.Add("Link", "<"+successor+`>; rel="successor-version"`)
+	}
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// volumeBackupBucket stores the records of volume snapshots
+const volumeBackupBucket = "volume_backups"
+
+// VolumeBackup is a tar snapshot of a named volume, taken before the
+// container using it was removed or recreated
+type VolumeBackup struct {
+	ID          string    `json:"id"`
+	Volume      string    `json:"volume"`
+	Container   string    `json:"container"`
+	Reason      string    `json:"reason"` // remove, recreate or auto-update
+	Path        string    `json:"path"`   // .tar.gz archive in the backup directory
+	Size        int64     `json:"size"`   // bytes of the archive
+	CreatedBy   string    `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	RestorePath string    `json:"restorePath"` // API path restoring the snapshot
+}
+
+// snapshotDir returns the directory of volume snapshots, in the directory
+// of the backup job. Actions check it before they stop a container.
+func (h *VolumeHandler) snapshotDir() (string, error) {
+	dir, err := h.scheduler.backupDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "volumes"), nil
+}
+
+// snapshot writes a snapshot of each named volume of a container to the
+// snapshot directory and records it. Either all volumes are saved or none.
+func (h *VolumeHandler) snapshot(ctx context.Context, info *podman.ContainerInspect, reason, username string) ([]VolumeBackup, error) {
+	dir, err := h.snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	backups := []VolumeBackup{}
+	for _, m := range info.Mounts {
+		if m.Type != "volume" || m.Name == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			h.discard(backups)
+			return nil, err
+		}
+		id := m.Name + "-" + now.Format("20060102-150405.000000")
+		backup := VolumeBackup{
+			ID:          id,
+			Volume:      m.Name,
+			Container:   strings.TrimPrefix(info.Name, "/"),
+			Reason:      reason,
+			Path:        filepath.Join(dir, id+".tar.gz"),
+			CreatedBy:   username,
+			CreatedAt:   now,
+			RestorePath: "/api/volumes/backups/" + id + "/restore",
+		}
+		backup.Size, err = writeVolumeArchive(backup.Path, m.Source)
+		if err == nil {
+			err = h.storage.SetJSON(volumeBackupBucket, id, backup)
+		}
+		if err != nil {
+			os.Remove(backup.Path)
+			h.discard(backups)
+			return nil, fmt.Errorf("snapshot of volume %s: %w", m.Name, err)
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+// discard removes snapshots, e.g. when the action they were taken for failed
+func (h *VolumeHandler) discard(backups []VolumeBackup) {
+	for _, backup := range backups {
+		os.Remove(backup.Path)
+		h.storage.Delete(volumeBackupBucket, backup.ID)
+	}
+}
+
+// writeVolumeArchive writes the files of dir to a .tar.gz archive at path
+// and returns its size
+func writeVolumeArchive(path, dir string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err // sockets and other special files
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+// restoreVolumeArchive replaces the files of dir with those of the archive
+func restoreVolumeArchive(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	root := filepath.Clean(dir)
+	dirModes := map[string]os.FileMode{} // set last, so read-only directories can be filled
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			for target, mode := range dirModes {
+				os.Chmod(target, mode)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(root, header.Name)
+		if !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid file path: %s", header.Name)
+		}
+		// Symlinks of the archive must not redirect later entries
+		if parent, err := filepath.EvalSymlinks(filepath.Dir(target)); err != nil || (parent != root && !strings.HasPrefix(parent, root+string(os.PathSeparator))) {
+			return fmt.Errorf("invalid file path: %s", header.Name)
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.Mkdir(target, 0700)
+			dirModes[target] = mode
+		case tar.TypeReg:
+			var out *os.File
+			if out, err = os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode); err == nil {
+				_, err = io.Copy(out, tr)
+				if cerr := out.Close(); err == nil {
+					err = cerr
+				}
+			}
+		case tar.TypeSymlink:
+			err = os.Symlink(header.Linkname, target)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Ownership matters to the processes of the container; it can only
+		// be kept when running as root
+		os.Lchown(target, header.Uid, header.Gid)
+	}
+}
+
+// getSnapshot returns the record of a snapshot
+func (h *VolumeHandler) getSnapshot(id string) (*VolumeBackup, error) {
+	if h.storage == nil {
+		return nil, apierror.New(http.StatusServiceUnavailable, "Storage not available")
+	}
+	var backup VolumeBackup
+	if err := h.storage.GetJSON(volumeBackupBucket, id, &backup); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, apierror.New(http.StatusNotFound, "Volume backup not found")
+		}
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// ListBackups handles GET /api/volumes/backups
+// Returns the volume snapshots, newest first
+func (h *VolumeHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	backups := []VolumeBackup{}
+	if h.storage != nil {
+		data, err := h.storage.List(volumeBackupBucket)
+		if err != nil {
+			writeErr(w, r, err, "Failed to read volume backups")
+			return
+		}
+		for _, value := range data {
+			var backup VolumeBackup
+			if json.Unmarshal(value, &backup) == nil {
+				backups = append(backups, backup)
+			}
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	writeJSON(w, http.StatusOK, backups)
+}
+
+// RestoreBackup handles POST /api/volumes/backups/{id}/restore?volume=
+// Replaces the data of the volume (the snapshotted one unless volume is
+// given) with the snapshot, creating the volume if it no longer exists.
+// Needs a confirmation token (see Confirmation). Volumes used by a running
+// container can't be restored.
+func (h *VolumeHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	backup, err := h.getSnapshot(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	name := backup.Volume
+	if v := r.URL.Query().Get("volume"); v != "" {
+		name = v
+	}
+
+	impact := func() (interface{}, error) {
+		impact, err := h.volumeImpact(r, name)
+		if apierror.Status(err) == http.StatusNotFound {
+			return &VolumeImpact{UsedBy: []string{}}, nil // created by the restore
+		}
+		return impact, err
+	}
+	if !h.confirms.confirmed(w, r, "volume_restore", backup.ID+":"+name, impact) {
+		return
+	}
+
+	running, err := h.volumeUsers(r.Context(), name, true)
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if len(running) > 0 {
+		writeError(w, r, http.StatusConflict, "Volume is used by a running container: "+strings.Join(running, ", "))
+		return
+	}
+
+	volume, err := h.client.InspectVolume(r.Context(), name)
+	if apierror.Status(err) == http.StatusNotFound {
+		if err = h.client.CreateVolume(r.Context(), name); err == nil {
+			volume, err = h.client.InspectVolume(r.Context(), name)
+		}
+	}
+	if err == nil {
+		err = restoreVolumeArchive(backup.Path, volume.Mountpoint)
+	}
+	if err != nil {
+		h.eventStore.Add(events.EventVolumeRestore, user.Username, getClientIP(r), false, name)
+		writeErr(w, r, err, "Failed to restore volume")
+		return
+	}
+	h.eventStore.Add(events.EventVolumeRestore, user.Username, getClientIP(r), true, name+" ("+backup.ID+")")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "restored", "volume": name})
+}
+
+// DeleteBackup handles DELETE /api/volumes/backups/{id}
+// Removes a snapshot and its archive
+func (h *VolumeHandler) DeleteBackup(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	backup, err := h.getSnapshot(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErr(w, r, err, "")
+		return
+	}
+	if err := os.Remove(backup.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		writeErr(w, r, err, "Failed to delete volume backup")
+		return
+	}
+	if err := h.storage.Delete(volumeBackupBucket, backup.ID); err != nil {
+		writeErr(w, r, err, "Failed to delete volume backup")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted", "id": backup.ID})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// VolumeHandler handles volume endpoints and the snapshots of volumes
+type VolumeHandler struct {
+	client     *podman.Client
+	storage    storage.Storage
+	scheduler  *SchedulerHandler // directory of the backup job
+	eventStore *events.Store
+	confirms   *confirmStore
+}
+
+// NewVolumeHandler creates new volume handler
+func NewVolumeHandler(client *podman.Client, store storage.Storage, scheduler *SchedulerHandler, eventStore *events.Store, confirms *confirmStore) *VolumeHandler {
+	return &VolumeHandler{client: client, storage: store, scheduler: scheduler, eventStore: eventStore, confirms: confirms}
+}
+
+// VolumeImpact is what the removal of a volume deletes
+type VolumeImpact struct {
+	Size   int64    `json:"size"`   // bytes of data in the volume
+	UsedBy []string `json:"usedBy"` // containers mounting it, which block the removal
+}
+
+// volumeImpact returns the size of a volume and the containers using it
+func (h *VolumeHandler) volumeImpact(r *http.Request, name string) (interface{}, error) {
+	volumes, err := h.client.ListVolumes(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, v := range volumes {
+		found = found || v.Name == name
+	}
+	if !found {
+		return nil, apierror.New(http.StatusNotFound, "Volume not found")
+	}
+
+	impact := &VolumeImpact{UsedBy: []string{}}
+	if df, err := h.client.GetSystemDF(r.Context()); err == nil {
+		for _, v := range df.Volumes {
+			if v.VolumeName == name {
+				impact.Size = v.Size
+			}
+		}
+	}
+	if impact.UsedBy, err = h.volumeUsers(r.Context(), name, false); err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// volumeUsers returns the names of the containers mounting a volume, only
+// the running ones with running
+func (h *VolumeHandler) volumeUsers(ctx context.Context, name string, running bool) ([]string, error) {
+	containers, err := h.client.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := []string{}
+	// Volume names are only reported by inspect
+	for _, c := range containers {
+		if c.IsInfra || (running && c.State != "running") {
+			continue
+		}
+		info, err := h.client.InspectContainer(ctx, c.ID)
+		if err != nil {
+			continue // removed meanwhile
+		}
+		for _, m := range info.Mounts {
+			if m.Type == "volume" && m.Name == name {
+				users = append(users, firstOf(c.Names))
+				break
+			}
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// Remove handles DELETE /api/volumes/{name}
+// Removes a volume with its data. Needs a confirmation token (see
+// Confirmation); volumes used by a container can't be removed.
+func (h *VolumeHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	impact := func() (interface{}, error) { return h.volumeImpact(r, name) }
+	if !h.confirms.confirmed(w, r, "volume_remove", name, impact) {
+		return
+	}
+
+	if err := h.client.RemoveVolume(r.Context(), name, false); err != nil {
+		h.eventStore.Add(events.EventVolumeRemove, user.Username, getClientIP(r), false, name)
+		writeErr(w, r, err, "Failed to remove volume")
+		return
+	}
+	h.eventStore.Add(events.EventVolumeRemove, user.Username, getClientIP(r), true, name)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed", "name": name})
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"podmanview/internal/auth"
+	"podmanview/internal/events"
+	"podmanview/internal/webhooks"
+)
+
+// WebhooksHandler handles webhook registration
+type WebhooksHandler struct {
+	manager    *webhooks.Manager
+	eventStore *events.Store
+}
+
+// NewWebhooksHandler creates new webhooks handler
+func NewWebhooksHandler(manager *webhooks.Manager, eventStore *events.Store) *WebhooksHandler {
+	return &WebhooksHandler{manager: manager, eventStore: eventStore}
+}
+
+// webhookRequest is the body of create and update requests.
+// Omitted fields keep their current (or default) value.
+type webhookRequest struct {
+	Name       *string   `json:"name"`
+	URL        *string   `json:"url"`
+	Events     *[]string `json:"events"`
+	Secret     *string   `json:"secret"` // "" removes the secret
+	Enabled    *bool     `json:"enabled"`
+	MaxRetries *int      `json:"maxRetries"`
+	RetryDelay *int      `json:"retryDelay"`
+}
+
+// apply copies the fields set in the request to hook
+func (req *webhookRequest) apply(hook *webhooks.Webhook) {
+	if req.Name != nil {
+		hook.Name = *req.Name
+	}
+	if req.URL != nil {
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		hook.Events = *req.Events
+	}
+	if req.Secret != nil {
+		hook.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if req.MaxRetries != nil {
+		hook.MaxRetries = *req.MaxRetries
+	}
+	if req.RetryDelay != nil {
+		hook.RetryDelay = *req.RetryDelay
+	}
+}
+
+// webhookView is a webhook as returned by the API, without its secret
+type webhookView struct {
+	webhooks.Webhook
+	HasSecret    bool                     `json:"hasSecret"`
+	LastDelivery *webhooks.DeliveryStatus `json:"lastDelivery"`
+}
+
+// view converts a webhook for a response
+func (h *WebhooksHandler) view(hook webhooks.Webhook) webhookView {
+	v := webhookView{
+		Webhook:      hook,
+		HasSecret:    hook.Secret != "",
+		LastDelivery: h.manager.Status(hook.ID),
+	}
+	v.Secret = ""
+	return v
+}
+
+// authorize checks admin access and that webhooks are available
+func (h *WebhooksHandler) authorize(w http.ResponseWriter, r *http.Request) (*auth.User, bool) {
+	user := auth.GetUserFromContext(r.Context())
+	if !user.IsAdmin() {
+		writeError(w, r, http.StatusForbidden, "Admin access required")
+		return nil, false
+	}
+	if h.manager == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Storage not available")
+		return nil, false
+	}
+	return user, true
+}
+
+// List handles GET /api/webhooks
+func (h *WebhooksHandler) List(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorize(w, r); !ok {
+		return
+	}
+
+	list := h.manager.List()
+	views := make([]webhookView, len(list))
+	for i, hook := range list {
+		views[i] = h.view(hook)
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// Create handles POST /api/webhooks
+func (h *WebhooksHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	hook := webhooks.Webhook{Enabled: true, MaxRetries: webhooks.DefaultMaxRetries}
+	req.apply(&hook)
+	if err := h.manager.Save(&hook); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventWebhookUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("created %s (%s)", hook.Name, hook.URL))
+	writeJSON(w, http.StatusCreated, h.view(hook))
+}
+
+// Update handles PUT /api/webhooks/{id}
+func (h *WebhooksHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	hook, err := h.manager.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.apply(hook)
+	if err := h.manager.Save(hook); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventWebhookUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("updated %s (%s)", hook.Name, hook.URL))
+	writeJSON(w, http.StatusOK, h.view(*hook))
+}
+
+// Delete handles DELETE /api/webhooks/{id}
+func (h *WebhooksHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	hook, err := h.manager.Get(chi.URLParam(r, "id"))
+	if err == nil {
+		err = h.manager.Delete(hook.ID)
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.eventStore.Add(events.EventWebhookUpdate, user.Username, getClientIP(r), true, fmt.Sprintf("deleted %s (%s)", hook.Name, hook.URL))
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// Test handles POST /api/webhooks/{id}/test
+// Sends a "webhook_test" event once and returns the delivery result.
+func (h *WebhooksHandler) Test(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.manager.Test(r.Context(), chi.URLParam(r, "id"), user.Username)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// writeError writes a webhook manager error
+func (h *WebhooksHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, webhooks.ErrInvalid):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	default:
+		writeErr(w, r, err, "")
+	}
+}
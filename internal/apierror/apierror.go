@@ -0,0 +1,206 @@
+// Package apierror writes API error responses in a single JSON format and
+// maps Go errors to HTTP status codes. It is shared by the API handlers,
+// the auth middleware and plugins.
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"podmanview/internal/i18n"
+)
+
+// Error codes, one per status class handled by the API
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal"
+	CodeNotImplemented   = "not_implemented"
+	CodeBadGateway       = "bad_gateway"
+	CodeUnavailable      = "unavailable"
+	CodeTimeout          = "timeout"
+)
+
+// Response is the JSON body of every API error:
+//
+//	{"code": "not_found", "message": "Container not found", "requestId": "host/abc-000001", "error": "Container not found"}
+//
+// Error repeats Message for clients of the previous {"error": "..."} format.
+type Response struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+	Error     string      `json:"error"`
+}
+
+// Error is an error with the status, code and message of its API response.
+// The wrapped error is logged by callers but never sent to the client.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+	Err     error
+}
+
+// New creates an error with the default code of the status
+func New(status int, message string) *Error {
+	return &Error{Status: status, Code: CodeFor(status), Message: message}
+}
+
+// Wrap creates an error for err with the given status and client message
+func Wrap(err error, status int, message string) *Error {
+	return &Error{Status: status, Code: CodeFor(status), Message: message, Err: err}
+}
+
+// WithCode sets a specific error code (e.g. "container_running")
+func (e *Error) WithCode(code string) *Error {
+	e.Code = code
+	return e
+}
+
+// WithDetails adds details to the response (field errors, limits, ...)
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus returns the status of the response
+func (e *Error) HTTPStatus() int {
+	return e.Status
+}
+
+// statusError is implemented by errors that know their HTTP status
+// (*Error, podman.APIError)
+type statusError interface {
+	HTTPStatus() int
+}
+
+// Status maps an error to an HTTP status: errors with an HTTPStatus method,
+// then standard errors (not exist - 404, exist - 409, permission - 403,
+// timeout - 504, body too large - 413), otherwise 500
+func Status(err error) int {
+	var se statusError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, &se):
+		return se.HTTPStatus()
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrExist):
+		return http.StatusConflict
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CodeFor returns the default error code of a status
+func CodeFor(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// Write writes an error response with the default code of the status
+func Write(w http.ResponseWriter, r *http.Request, status int, message string) {
+	WriteError(w, r, New(status, message))
+}
+
+// WriteErr writes an error response for err. *Error values are written as is;
+// other errors get the status from Status and their text as the message,
+// prefixed with prefix ("Failed to start container") if not empty.
+func WriteErr(w http.ResponseWriter, r *http.Request, err error, prefix string) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		message := err.Error()
+		if prefix != "" {
+			message = prefix + ": " + message
+		}
+		apiErr = Wrap(err, Status(err), message)
+	}
+	WriteError(w, r, apiErr)
+}
+
+// WriteError writes the response of an *Error. The message is translated
+// into the language negotiated from the Accept-Language header of r; the
+// code is not, so clients should match on it.
+func WriteError(w http.ResponseWriter, r *http.Request, e *Error) {
+	lang := i18n.FromRequest(r)
+	message := i18n.T(lang, e.Message)
+	resp := Response{
+		Code:    e.Code,
+		Message: message,
+		Details: e.Details,
+		Error:   message,
+	}
+	if resp.Code == "" {
+		resp.Code = CodeFor(e.Status)
+	}
+	if r != nil {
+		resp.RequestID = middleware.GetReqID(r.Context())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(resp)
+}
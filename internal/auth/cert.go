@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+)
+
+var (
+	ErrNoClientCert = errors.New("no verified client certificate")
+	ErrEmptyCertCN  = errors.New("client certificate has empty common name")
+)
+
+// CertAuth authenticates users by TLS client certificate.
+// The certificate Common Name is mapped to a local system account,
+// and the role is determined the same way as for PAM logins.
+type CertAuth struct {
+	pamAuth *PAMAuth
+}
+
+// NewCertAuth creates new client certificate authenticator
+func NewCertAuth(pamAuth *PAMAuth) *CertAuth {
+	return &CertAuth{pamAuth: pamAuth}
+}
+
+// Authenticate returns the user mapped from the request's verified client certificate
+func (c *CertAuth) Authenticate(r *http.Request) (*User, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoClientCert
+	}
+
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if cn == "" {
+		return nil, ErrEmptyCertCN
+	}
+
+	u, err := user.Lookup(cn)
+	if err != nil {
+		return nil, fmt.Errorf("no local account for certificate CN %q: %w", cn, err)
+	}
+
+	return &User{
+		Username: cn,
+		UID:      u.Uid,
+		Role:     c.pamAuth.determineRole(cn),
+	}, nil
+}
+
+// LoadClientCAPool reads PEM-encoded CA certificates used to verify client certificates
+func LoadClientCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificates found in %s", path)
+	}
+
+	return pool, nil
+}
@@ -1,15 +1,46 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("token expired")
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrExpiredToken     = errors.New("token expired")
+	ErrUnsupportedAlg   = errors.New("unsupported JWT algorithm")
+	ErrNoSigningKey     = errors.New("no signing key available")
+	ErrKeyMaterialEmpty = errors.New("key material is empty")
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const (
+	// DefaultKeyID is the key ID of the HMAC key derived from the configured JWT secret.
+	// Tokens issued before key rotation was introduced carry no kid and map to this key.
+	DefaultKeyID = "default"
+
+	// maxSigningKeys is how many keys are kept for validation after rotation
+	maxSigningKeys = 3
+
+	rsaKeyBits = 2048
 )
 
 // Claims represents JWT claims
@@ -20,19 +51,88 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// JWTManager handles JWT token operations
+// signingKey is a single key in the JWT key ring
+type signingKey struct {
+	id        string
+	algorithm string
+	createdAt time.Time
+	secret    []byte           // HS256
+	private   crypto.Signer    // RS256, EdDSA
+	public    crypto.PublicKey // RS256, EdDSA
+}
+
+// StoredKey is the persisted form of a signing key.
+// Material is the raw HMAC secret or a PKCS#8 DER private key.
+// The default key is stored without material - it is always derived from the configured secret.
+type StoredKey struct {
+	ID        string    `json:"id"`
+	Algorithm string    `json:"algorithm"`
+	CreatedAt time.Time `json:"createdAt"`
+	Material  []byte    `json:"material,omitempty"`
+}
+
+// KeyInfo describes a signing key without exposing its material
+type KeyInfo struct {
+	ID        string    `json:"id"`
+	Algorithm string    `json:"algorithm"`
+	CreatedAt time.Time `json:"createdAt"`
+	Active    bool      `json:"active"` // used for signing new tokens
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWTManager handles JWT token operations.
+// It keeps a ring of signing keys: the newest key signs new tokens,
+// older keys remain valid for verification until they are rotated out.
 type JWTManager struct {
-	secretKey     []byte
+	mu            sync.RWMutex
+	algorithm     string
+	secret        []byte
+	keys          []*signingKey // newest first
 	tokenDuration time.Duration
 }
 
-// NewJWTManager creates new JWT manager.
+// NewJWTManager creates new JWT manager with HS256 signing.
 // Secret key must be provided (generated by config package).
 func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
+	m, _ := NewJWTManagerWithAlgorithm(secretKey, tokenDuration, AlgHS256)
+	return m
+}
+
+// NewJWTManagerWithAlgorithm creates new JWT manager signing with the given algorithm.
+// The key ring initially contains only the HMAC key derived from the secret;
+// call LoadKeys and EnsureSigningKey to restore persisted keys and create an
+// asymmetric key when needed.
+func NewJWTManagerWithAlgorithm(secretKey string, tokenDuration time.Duration, algorithm string) (*JWTManager, error) {
+	if !isSupportedAlgorithm(algorithm) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, algorithm)
+	}
+
 	return &JWTManager{
-		secretKey:     []byte(secretKey),
+		algorithm: algorithm,
+		secret:    []byte(secretKey),
+		keys: []*signingKey{{
+			id:        DefaultKeyID,
+			algorithm: AlgHS256,
+			secret:    []byte(secretKey),
+		}},
 		tokenDuration: tokenDuration,
-	}
+	}, nil
+}
+
+// Algorithm returns the algorithm used for signing new tokens
+func (m *JWTManager) Algorithm() string {
+	return m.algorithm
 }
 
 // GenerateToken creates new JWT token for user with default duration
@@ -53,17 +153,42 @@ func (m *JWTManager) GenerateTokenWithDuration(user *User, duration time.Duratio
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.secretKey)
+	m.mu.RLock()
+	if len(m.keys) == 0 {
+		m.mu.RUnlock()
+		return "", ErrNoSigningKey
+	}
+	key := m.keys[0]
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(signingMethod(key.algorithm), claims)
+	if key.id != DefaultKeyID {
+		token.Header["kid"] = key.id
+	}
+
+	if key.algorithm == AlgHS256 {
+		return token.SignedString(key.secret)
+	}
+	return token.SignedString(key.private)
 }
 
 // ValidateToken validates JWT token and returns claims
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = DefaultKeyID
+		}
+
+		key := m.findKey(kid)
+		if key == nil || token.Method.Alg() != key.algorithm {
 			return nil, ErrInvalidToken
 		}
-		return m.secretKey, nil
+
+		if key.algorithm == AlgHS256 {
+			return key.secret, nil
+		}
+		return key.public, nil
 	})
 
 	if err != nil {
@@ -80,3 +205,288 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// findKey returns the key with the given ID or nil
+func (m *JWTManager) findKey(id string) *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, k := range m.keys {
+		if k.id == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// LoadKeys replaces the key ring with persisted keys (newest first).
+// Does nothing if stored is empty.
+func (m *JWTManager) LoadKeys(stored []StoredKey) error {
+	if len(stored) == 0 {
+		return nil
+	}
+
+	keys := make([]*signingKey, 0, len(stored))
+	for _, sk := range stored {
+		key, err := m.keyFromStored(sk)
+		if err != nil {
+			return fmt.Errorf("failed to load key %s: %w", sk.ID, err)
+		}
+		keys = append(keys, key)
+	}
+
+	m.mu.Lock()
+	m.keys = withoutSecretKey(keys)
+	m.mu.Unlock()
+
+	return nil
+}
+
+// ExportKeys returns the key ring in persistable form (newest first)
+func (m *JWTManager) ExportKeys() ([]StoredKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]StoredKey, 0, len(m.keys))
+	for _, k := range m.keys {
+		sk := StoredKey{
+			ID:        k.id,
+			Algorithm: k.algorithm,
+			CreatedAt: k.createdAt,
+		}
+
+		switch {
+		case k.id == DefaultKeyID:
+			// Derived from the configured secret, never persisted
+		case k.algorithm == AlgHS256:
+			sk.Material = k.secret
+		default:
+			der, err := x509.MarshalPKCS8PrivateKey(k.private)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal key %s: %w", k.id, err)
+			}
+			sk.Material = der
+		}
+
+		result = append(result, sk)
+	}
+
+	return result, nil
+}
+
+// EnsureSigningKey makes sure the active key matches the configured algorithm,
+// generating a new key if needed. Returns true if the key ring changed.
+func (m *JWTManager) EnsureSigningKey() (bool, error) {
+	m.mu.RLock()
+	ok := len(m.keys) > 0 && m.keys[0].algorithm == m.algorithm
+	m.mu.RUnlock()
+
+	if ok {
+		return false, nil
+	}
+
+	if _, err := m.Rotate(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Rotate generates a new signing key for the configured algorithm and makes it active.
+// Older keys stay valid for verification; keys beyond the ring size are dropped,
+// and so is the key derived from the secret once an asymmetric key is active.
+func (m *JWTManager) Rotate() (*KeyInfo, error) {
+	key, err := generateSigningKey(m.algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.keys = withoutSecretKey(append([]*signingKey{key}, m.keys...))
+	if len(m.keys) > maxSigningKeys {
+		m.keys = m.keys[:maxSigningKeys]
+	}
+	m.mu.Unlock()
+
+	return &KeyInfo{
+		ID:        key.id,
+		Algorithm: key.algorithm,
+		CreatedAt: key.createdAt,
+		Active:    true,
+	}, nil
+}
+
+// Keys returns information about all keys in the ring
+func (m *JWTManager) Keys() []KeyInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]KeyInfo, len(m.keys))
+	for i, k := range m.keys {
+		result[i] = KeyInfo{
+			ID:        k.id,
+			Algorithm: k.algorithm,
+			CreatedAt: k.createdAt,
+			Active:    i == 0,
+		}
+	}
+	return result
+}
+
+// PublicKeys returns asymmetric verification keys as a JWK set.
+// HMAC keys are never exposed.
+func (m *JWTManager) PublicKeys() []JWK {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := []JWK{}
+	for _, k := range m.keys {
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			result = append(result, JWK{
+				KeyType:   "RSA",
+				KeyID:     k.id,
+				Algorithm: k.algorithm,
+				Use:       "sig",
+				N:         base64URL(pub.N.Bytes()),
+				E:         base64URL(bigEndianBytes(pub.E)),
+			})
+		case ed25519.PublicKey:
+			result = append(result, JWK{
+				KeyType:   "OKP",
+				KeyID:     k.id,
+				Algorithm: k.algorithm,
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64URL(pub),
+			})
+		}
+	}
+	return result
+}
+
+// withoutSecretKey drops the key derived from the configured secret when an
+// asymmetric key is active (first). Anyone who knows the secret could sign
+// tokens with it, which switching to RS256 or EdDSA should rule out, so its
+// tokens stop being valid then.
+func withoutSecretKey(keys []*signingKey) []*signingKey {
+	if len(keys) == 0 || keys[0].algorithm == AlgHS256 {
+		return keys
+	}
+	result := keys[:0:0]
+	for _, k := range keys {
+		if k.id != DefaultKeyID {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// keyFromStored restores a signing key from its persisted form
+func (m *JWTManager) keyFromStored(sk StoredKey) (*signingKey, error) {
+	key := &signingKey{
+		id:        sk.ID,
+		algorithm: sk.Algorithm,
+		createdAt: sk.CreatedAt,
+	}
+
+	if sk.ID == DefaultKeyID {
+		key.algorithm = AlgHS256
+		key.secret = m.secret
+		return key, nil
+	}
+
+	if len(sk.Material) == 0 {
+		return nil, ErrKeyMaterialEmpty
+	}
+
+	switch sk.Algorithm {
+	case AlgHS256:
+		key.secret = sk.Material
+	case AlgRS256, AlgEdDSA:
+		parsed, err := x509.ParsePKCS8PrivateKey(sk.Material)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key %s is not a signing key", sk.ID)
+		}
+		key.private = signer
+		key.public = signer.Public()
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, sk.Algorithm)
+	}
+
+	return key, nil
+}
+
+// generateSigningKey creates a new random key for the algorithm
+func generateSigningKey(algorithm string) (*signingKey, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		id:        hex.EncodeToString(idBytes),
+		algorithm: algorithm,
+		createdAt: time.Now(),
+	}
+
+	switch algorithm {
+	case AlgHS256:
+		key.secret = make([]byte, 32)
+		if _, err := rand.Read(key.secret); err != nil {
+			return nil, err
+		}
+	case AlgRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		key.private = priv
+		key.public = &priv.PublicKey
+	case AlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.private = priv
+		key.public = pub
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, algorithm)
+	}
+
+	return key, nil
+}
+
+// signingMethod maps algorithm name to jwt signing method
+func signingMethod(algorithm string) jwt.SigningMethod {
+	switch algorithm {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// isSupportedAlgorithm checks if algorithm can be used for signing
+func isSupportedAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case AlgHS256, AlgRS256, AlgEdDSA:
+		return true
+	}
+	return false
+}
+
+// base64URL encodes bytes as unpadded base64url (JWK encoding)
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// bigEndianBytes returns the minimal big-endian encoding of an integer
+func bigEndianBytes(v int) []byte {
+	return big.NewInt(int64(v)).Bytes()
+}
@@ -3,6 +3,8 @@ package auth
 import (
 	"context"
 	"net/http"
+
+	"podmanview/internal/apierror"
 )
 
 type contextKey string
@@ -15,6 +17,7 @@ const (
 // Middleware handles authentication for protected routes
 type Middleware struct {
 	jwtManager *JWTManager
+	certAuth   *CertAuth // when set, client certificates replace JWT cookies
 }
 
 // NewMiddleware creates new auth middleware
@@ -22,13 +25,29 @@ func NewMiddleware(jwtManager *JWTManager) *Middleware {
 	return &Middleware{jwtManager: jwtManager}
 }
 
+// SetCertAuth switches the middleware to client certificate authentication
+func (m *Middleware) SetCertAuth(certAuth *CertAuth) {
+	m.certAuth = certAuth
+}
+
 // RequireAuth middleware checks for valid JWT token
+// (or a verified client certificate when certificate auth is enabled)
 func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.certAuth != nil {
+			user, err := m.certAuth.Authenticate(r)
+			if err != nil {
+				apierror.Write(w, r, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(SetUserContext(r.Context(), user)))
+			return
+		}
+
 		// Try to get token from cookie
 		cookie, err := r.Cookie(CookieName)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
@@ -43,7 +62,7 @@ func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 				MaxAge:   -1,
 				HttpOnly: true,
 			})
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
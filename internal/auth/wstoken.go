@@ -7,18 +7,27 @@ import (
 	"time"
 )
 
+// ClientFingerprint identifies the client a WebSocket token was issued to
+type ClientFingerprint struct {
+	IP        string
+	UserAgent string
+}
+
 // WSTokenStore manages WebSocket CSRF tokens
-// Tokens are one-time use and expire after a short TTL
+// Tokens are one-time use and expire after a short TTL.
+// Each token is bound to the client fingerprint it was generated for,
+// so a leaked token cannot be replayed from another client during the grace period.
 type WSTokenStore struct {
 	mu     sync.RWMutex
 	tokens map[string]*wsTokenEntry
 }
 
 type wsTokenEntry struct {
-	username  string
-	createdAt time.Time
-	usedAt    *time.Time // Time of first use
-	useCount  int        // Number of times token was used
+	username    string
+	fingerprint ClientFingerprint
+	createdAt   time.Time
+	usedAt      *time.Time // Time of first use
+	useCount    int        // Number of times token was used
 }
 
 const (
@@ -42,8 +51,8 @@ func NewWSTokenStore() *WSTokenStore {
 	return store
 }
 
-// Generate creates a new one-time token for a user
-func (s *WSTokenStore) Generate(username string) (string, error) {
+// Generate creates a new one-time token for a user bound to the client fingerprint
+func (s *WSTokenStore) Generate(username string, fingerprint ClientFingerprint) (string, error) {
 	bytes := make([]byte, WSTokenLength)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
@@ -52,8 +61,9 @@ func (s *WSTokenStore) Generate(username string) (string, error) {
 
 	s.mu.Lock()
 	s.tokens[token] = &wsTokenEntry{
-		username:  username,
-		createdAt: time.Now(),
+		username:    username,
+		fingerprint: fingerprint,
+		createdAt:   time.Now(),
 	}
 	s.mu.Unlock()
 
@@ -62,8 +72,9 @@ func (s *WSTokenStore) Generate(username string) (string, error) {
 
 // Validate checks if a token is valid and marks it as used
 // Allows multiple uses within grace period for reconnection scenarios
+// Tokens presented by a client with a different fingerprint are rejected and revoked
 // Returns the username associated with the token, or empty string if invalid
-func (s *WSTokenStore) Validate(token string) (string, bool) {
+func (s *WSTokenStore) Validate(token string, fingerprint ClientFingerprint) (string, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -72,6 +83,12 @@ func (s *WSTokenStore) Validate(token string) (string, bool) {
 		return "", false
 	}
 
+	// Token relayed from another client - revoke it entirely
+	if entry.fingerprint != fingerprint {
+		delete(s.tokens, token)
+		return "", false
+	}
+
 	now := time.Now()
 	username := entry.username
 
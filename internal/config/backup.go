@@ -0,0 +1,308 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupDirName is the directory next to the config file that holds config versions.
+const backupDirName = "config-backups"
+
+// settingsSnapshotFile is the name of the storage snapshot inside a backup.
+const settingsSnapshotFile = "settings.json"
+
+// backupIDFormat names backups by creation time (UTC), so names sort chronologically.
+const backupIDFormat = "20060102-150405.000"
+
+// ErrBackupNotFound is returned when a config backup doesn't exist.
+var ErrBackupNotFound = errors.New("config backup not found")
+
+// SettingsSnapshotter exports and restores settings kept outside the config file
+// (plugin settings in the database), so they are versioned together with it.
+type SettingsSnapshotter interface {
+	ExportSettings() ([]byte, error)
+	ImportSettings(data []byte) error
+}
+
+// Backup describes a saved config version.
+type Backup struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`     // config file size in bytes
+	Settings  bool      `json:"settings"` // includes a plugin settings snapshot
+}
+
+// SetSettingsSnapshotter sets the source of plugin settings included in backups.
+func (c *Config) SetSettingsSnapshotter(s SettingsSnapshotter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshotter = s
+}
+
+// ConfigBackups returns how many config versions are kept (0 disables backups).
+func (c *Config) ConfigBackups() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.configBackups
+}
+
+// backupDir returns the directory holding config versions.
+func (c *Config) backupDir() string {
+	return filepath.Join(filepath.Dir(c.filePath), backupDirName)
+}
+
+// backupCurrent copies the config file as it is on disk (and a snapshot of
+// plugin settings) into a new timestamped backup, then prunes old backups.
+// Nothing is written if the file doesn't exist yet or matches the latest backup.
+func (c *Config) backupCurrent() error {
+	c.mu.RLock()
+	keep := c.configBackups
+	snapshotter := c.snapshotter
+	dir := c.backupDir()
+	name := filepath.Base(c.filePath)
+	filePath := c.filePath
+	c.mu.RUnlock()
+
+	if keep <= 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config for backup: %w", err)
+	}
+
+	var settings []byte
+	if snapshotter != nil {
+		if settings, err = snapshotter.ExportSettings(); err != nil {
+			return fmt.Errorf("failed to export settings for backup: %w", err)
+		}
+	}
+
+	ids, err := listBackupIDs(dir)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 && backupMatches(filepath.Join(dir, ids[0]), name, data, settings) {
+		return nil
+	}
+
+	backupPath, err := createBackupDir(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(backupPath, name), data, 0600); err != nil {
+		return fmt.Errorf("failed to write config backup: %w", err)
+	}
+	if settings != nil {
+		if err := os.WriteFile(filepath.Join(backupPath, settingsSnapshotFile), settings, 0600); err != nil {
+			return fmt.Errorf("failed to write settings backup: %w", err)
+		}
+	}
+
+	return pruneBackups(dir, keep)
+}
+
+// createBackupDir creates a new, uniquely named backup directory.
+func createBackupDir(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Saves within the same millisecond get the next free name
+	for t := time.Now().UTC(); ; t = t.Add(time.Millisecond) {
+		path := filepath.Join(dir, t.Format(backupIDFormat))
+		err := os.Mkdir(path, 0700)
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create backup directory: %w", err)
+		}
+	}
+}
+
+// backupMatches reports whether the backup holds the same config and settings.
+func backupMatches(backupPath, name string, data, settings []byte) bool {
+	prev, err := os.ReadFile(filepath.Join(backupPath, name))
+	if err != nil || !bytes.Equal(prev, data) {
+		return false
+	}
+	prevSettings, err := os.ReadFile(filepath.Join(backupPath, settingsSnapshotFile))
+	if err != nil {
+		return settings == nil
+	}
+	return bytes.Equal(prevSettings, settings)
+}
+
+// listBackupIDs returns backup IDs in dir, newest first.
+func listBackupIDs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupIDFormat, entry.Name()); err != nil {
+			continue
+		}
+		ids = append(ids, entry.Name())
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// pruneBackups removes all but the newest keep backups.
+func pruneBackups(dir string, keep int) error {
+	ids, err := listBackupIDs(dir)
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(ids); i++ {
+		if err := os.RemoveAll(filepath.Join(dir, ids[i])); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// Backups returns saved config versions, newest first.
+func (c *Config) Backups() ([]Backup, error) {
+	c.mu.RLock()
+	dir := c.backupDir()
+	name := filepath.Base(c.filePath)
+	c.mu.RUnlock()
+
+	ids, err := listBackupIDs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]Backup, 0, len(ids))
+	for _, id := range ids {
+		info, err := os.Stat(filepath.Join(dir, id, name))
+		if err != nil {
+			// Backup of a config file in another format
+			continue
+		}
+		createdAt, _ := time.Parse(backupIDFormat, id)
+		_, err = os.Stat(filepath.Join(dir, id, settingsSnapshotFile))
+
+		backups = append(backups, Backup{
+			ID:        id,
+			CreatedAt: createdAt,
+			Size:      info.Size(),
+			Settings:  err == nil,
+		})
+	}
+
+	return backups, nil
+}
+
+// RestoreBackup replaces the current configuration (and plugin settings, if
+// the backup has them) with a saved version. The current state is backed up
+// first, so a restore can itself be undone. Environment variable and flag
+// overrides stay in effect. Most settings take effect after a restart.
+func (c *Config) RestoreBackup(id string) error {
+	if _, err := time.Parse(backupIDFormat, id); err != nil || strings.ContainsAny(id, `/\`) {
+		return ErrBackupNotFound
+	}
+
+	c.mu.RLock()
+	backupPath := filepath.Join(c.backupDir(), id)
+	filePath := c.filePath
+	snapshotter := c.snapshotter
+	c.mu.RUnlock()
+
+	data, err := os.ReadFile(filepath.Join(backupPath, filepath.Base(filePath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrBackupNotFound
+		}
+		return err
+	}
+
+	settings, err := os.ReadFile(filepath.Join(backupPath, settingsSnapshotFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Load the backup into a separate config so the current one is untouched on error
+	restored := &Config{
+		filePath: filePath,
+		fileKeys: make(map[string]bool),
+	}
+	restored.setDefaults()
+	if err := restored.loadFromData(data); err != nil {
+		return fmt.Errorf("failed to parse backup: %w", err)
+	}
+	if err := restored.initSecrets(); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	current := c.toMap()
+	for key, o := range c.overrides {
+		restored.applyOverrides(map[string]string{key: current[key]}, o.source)
+	}
+	c.mu.RUnlock()
+
+	if err := restored.validate(); err != nil {
+		return fmt.Errorf("invalid configuration in backup: %w", err)
+	}
+
+	if err := c.backupCurrent(); err != nil {
+		return err
+	}
+
+	if settings != nil && snapshotter != nil {
+		if err := snapshotter.ImportSettings(settings); err != nil {
+			return fmt.Errorf("failed to restore settings: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.setDefaults()
+	c.applyValues(restored.toMap())
+	c.fileKeys = restored.fileKeys
+	c.overrides = restored.overrides
+	c.cipher = restored.cipher
+	c.plugins = restored.plugins
+	c.notifications = restored.notifications
+	c.dirty = true
+	c.mu.Unlock()
+
+	return c.write()
+}
+
+// loadFromData parses config file contents in the format of c.filePath.
+func (c *Config) loadFromData(data []byte) error {
+	if isYAMLPath(c.filePath) {
+		return c.applyYAML(data)
+	}
+
+	values, err := ParseEnvFile(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	c.applyFileValues(values)
+	return nil
+}
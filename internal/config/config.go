@@ -6,11 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"podmanview/internal/secrets"
 )
 
 // Environment variable names
@@ -18,22 +21,101 @@ const (
 	EnvAddr          = "PODMANVIEW_ADDR"
 	EnvJWTSecret     = "PODMANVIEW_JWT_SECRET"
 	EnvJWTExpiration = "PODMANVIEW_JWT_EXPIRATION"
+	EnvJWTAlgorithm  = "PODMANVIEW_JWT_ALGORITHM"
 	EnvNoAuth        = "PODMANVIEW_NO_AUTH"
 	EnvSocket        = "PODMANVIEW_SOCKET"
+	EnvSecretEnv     = "PODMANVIEW_SECRET_ENV"
+	EnvRegistries    = "PODMANVIEW_REGISTRIES"
 	EnvLogDir        = "PODMANVIEW_LOG_DIR"
 	EnvLogMaxSize    = "PODMANVIEW_LOG_MAX_SIZE"
 	EnvLogMaxBackups = "PODMANVIEW_LOG_MAX_BACKUPS"
+	EnvLogLevel      = "PODMANVIEW_LOG_LEVEL"
+	EnvLogMaxAge     = "PODMANVIEW_LOG_MAX_AGE"
+	EnvLogMaxTotal   = "PODMANVIEW_LOG_MAX_TOTAL"
+	EnvLogCompress   = "PODMANVIEW_LOG_COMPRESS"
+	EnvLogConsole    = "PODMANVIEW_LOG_CONSOLE"
+	EnvLogFileFormat = "PODMANVIEW_LOG_FILE_FORMAT"
+	EnvLogSystem     = "PODMANVIEW_LOG_SYSTEM"
+	EnvLogSyslogAddr = "PODMANVIEW_LOG_SYSLOG_ADDR"
+	EnvSecretKey     = "PODMANVIEW_SECRET_KEY_SOURCE"
+	EnvSecretKeyFile = "PODMANVIEW_SECRET_KEY_FILE"
+	EnvSecretKeyData = "PODMANVIEW_SECRET_KEY"
+	EnvBasePath      = "PODMANVIEW_BASE_PATH"
+	EnvShutdownWait  = "PODMANVIEW_SHUTDOWN_TIMEOUT"
+	EnvGraphQL       = "PODMANVIEW_GRAPHQL"
+	EnvMDNS          = "PODMANVIEW_MDNS"
+	EnvMDNSName      = "PODMANVIEW_MDNS_NAME"
+	EnvIdleAfter     = "PODMANVIEW_IDLE_AFTER"
+	EnvNetMounts     = "PODMANVIEW_NETWORK_MOUNTS"
+	EnvCORSOrigins   = "PODMANVIEW_CORS_ORIGINS"
+	EnvCORSMethods   = "PODMANVIEW_CORS_METHODS"
+	EnvCORSHeaders   = "PODMANVIEW_CORS_HEADERS"
+	EnvTrustedProxy  = "PODMANVIEW_TRUSTED_PROXIES"
+	EnvAuthMode      = "PODMANVIEW_AUTH_MODE"
+	EnvTLSCert       = "PODMANVIEW_TLS_CERT"
+	EnvTLSKey        = "PODMANVIEW_TLS_KEY"
+	EnvTLSClientCA   = "PODMANVIEW_TLS_CLIENT_CA"
+	EnvMaintenance   = "PODMANVIEW_MAINTENANCE"
+	EnvDemo          = "PODMANVIEW_DEMO"
+	EnvConfigBackups = "PODMANVIEW_CONFIG_BACKUPS"
+	EnvStorage       = "PODMANVIEW_STORAGE"
+	EnvStorageMaint  = "PODMANVIEW_STORAGE_MAINTENANCE"
+	EnvEventsMax     = "PODMANVIEW_EVENTS_MAX"
+	EnvEventsMaxAge  = "PODMANVIEW_EVENTS_MAX_AGE"
+	EnvEventsArchive = "PODMANVIEW_EVENTS_ARCHIVE"
+	EnvGeoIPURL      = "PODMANVIEW_GEOIP_URL"
+)
+
+// UnixSocketPrefix marks a server address as a unix socket path (unix:///run/podmanview.sock)
+const UnixSocketPrefix = "unix://"
+
+// Authentication modes
+const (
+	AuthModePassword = "password" // PAM username/password login with JWT cookie
+	AuthModeMTLS     = "mtls"     // TLS client certificate, CN mapped to a local user
 )
 
 // Default values
 const (
 	DefaultAddr          = ":80"
 	DefaultJWTExpiration = 24 * time.Hour
+	DefaultJWTAlgorithm  = "HS256"
 	DefaultNoAuth        = false
 	DefaultSocket        = "" // auto-detect
+	DefaultSecretEnv     = "*PASSWORD*,*PASSWD*,*SECRET*,*TOKEN*,*KEY*,*CREDENTIAL*"
+	DefaultRegistries    = "docker.io,quay.io,ghcr.io"
 	DefaultLogDir        = "./logs"
 	DefaultLogMaxSize    = 10 // MB
 	DefaultLogMaxBackups = 3
+	DefaultLogLevel      = "info"
+	DefaultLogMaxAge     = 0   // days, 0 = no limit
+	DefaultLogMaxTotal   = 100 // MB of rotated files per log, 0 = no limit
+	DefaultLogCompress   = true
+	DefaultLogConsole    = "text"
+	DefaultLogFileFormat = "text"
+	DefaultLogSystem     = "" // disabled
+	DefaultSecretKey     = SecretKeyNone
+	DefaultBasePath      = "" // served at the root
+	DefaultShutdownWait  = 10 * time.Second
+	DefaultGraphQL       = false
+	DefaultMDNS          = false
+	DefaultMDNSName      = "PodmanView"
+	DefaultIdleAfter     = time.Duration(0)
+	DefaultNetMounts     = false
+	DefaultCORSOrigins   = "" // disabled
+	DefaultCORSMethods   = "GET,POST,PUT,PATCH,DELETE"
+	DefaultCORSHeaders   = "Content-Type,If-None-Match,If-Modified-Since,Last-Event-ID,X-Confirm-Token"
+	DefaultTrustedProxy  = "" // forwarding headers are ignored
+	DefaultAuthMode      = AuthModePassword
+	DefaultMaintenance   = false
+	DefaultDemo          = false
+	DefaultConfigBackups = 10
+	DefaultStorage       = "bolt"
+	DefaultStorageMaint  = 24 * time.Hour
+	DefaultEventsMax     = 100
+	DefaultEventsMaxAge  = 0  // days, 0 = no limit
+	DefaultEventsArchive = "" // disabled
+	DefaultGeoIPURL      = "" // disabled
 )
 
 // Config holds all application configuration.
@@ -44,27 +126,99 @@ type Config struct {
 	dirty    bool // tracks if config was modified
 
 	// Server settings
-	addr string
+	addr     string
+	basePath string        // URL prefix for subpath deployments, e.g. /podmanview
+	shutdown time.Duration // how long requests and connections are drained on shutdown
+	graphql  bool          // serve /api/graphql
+	mdns     bool          // announce the web UI on the local network
+	mdnsName string        // friendly name in the announcement, also the .local host name
+
+	// Power saving after this long without UI clients, 0 = disabled
+	idleAfter time.Duration
+
+	// Include NFS and CIFS mounts in the disk list
+	netMounts bool
+
+	// Cross-origin requests (comma-separated lists)
+	corsOrigins string // allowed origins, empty disables CORS
+	corsMethods string
+	corsHeaders string
+
+	// Reverse proxies whose X-Real-IP and X-Forwarded-For headers are
+	// trusted (comma-separated IPs and CIDRs)
+	trustedProxies string
 
 	// Security settings
 	jwtSecret     string
 	jwtExpiration time.Duration
+	jwtAlgorithm  string
 	noAuth        bool
+	maintenance   bool // read-only maintenance mode
+	demo          bool // public demo with mock data, no login and no changes
+
+	// Secrets at rest
+	secretKeySource string
+	secretKeyFile   string // key file for SecretKeyFile
+	secretKeyData   string // encoded key for SecretKeyConfig
+	cipher          *secrets.Cipher
 
 	// Podman settings
 	socketPath string
+	secretEnv  string // comma-separated name patterns of masked container env vars
+	registries string // comma-separated registries of the registry browser
+
+	// Storage settings
+	storageBackend     string        // bolt or sqlite
+	storageMaintenance time.Duration // integrity check and compaction interval, 0 = disabled
+
+	// Event log retention
+	eventsMax     int           // events kept in memory
+	eventsMaxAge  time.Duration // 0 = no limit
+	eventsArchive string        // directory for removed events, empty = discard
+	geoIPURL      string        // location lookup of login IPs, {ip} replaced, empty = disabled
 
 	// Logging settings
 	logDir        string
 	logMaxSize    int // MB
 	logMaxBackups int
+	logLevel      string
+	logMaxAge     time.Duration // 0 = no limit
+	logMaxTotal   int           // MB, 0 = no limit
+	logCompress   bool
+	logConsole    string // console format: text, json or none
+	logFileFormat string // app.log/error.log format: text or json
+	logSystem     string // journald, syslog or empty
+	logSyslogAddr string // empty = local syslog daemon
+
+	// TLS settings
+	authMode    string
+	tlsCert     string
+	tlsKey      string
+	tlsClientCA string
+
+	// Config versioning, see backup.go
+	configBackups int                 // number of versions to keep, 0 = disabled
+	snapshotter   SettingsSnapshotter // plugin settings included in backups
+
+	// Value sources, see Settings()
+	fileKeys  map[string]bool     // keys set in the config file
+	overrides map[string]override // runtime-only values from env or flags
+
+	// Free-form sections (config.yaml only)
+	plugins       map[string]map[string]interface{}
+	notifications map[string]map[string]interface{}
 }
 
-// Load loads configuration from .env file or creates it with defaults.
+// Load loads configuration from .env or config.yaml file or creates it with defaults.
+// The format is chosen by file extension (.yaml/.yml for YAML, anything else is .env).
 // This is the main entry point for configuration initialization.
+//
+// Precedence (highest first): command-line flags (see ApplyOverrides),
+// PODMANVIEW_* environment variables, config file, built-in defaults.
 func Load(filePath string) (*Config, error) {
 	cfg := &Config{
 		filePath: filePath,
+		fileKeys: make(map[string]bool),
 	}
 
 	// Set defaults first
@@ -86,9 +240,18 @@ func Load(filePath string) (*Config, error) {
 			return nil, fmt.Errorf("failed to generate JWT secret: %w", err)
 		}
 		cfg.jwtSecret = secret
+		cfg.fileKeys[EnvJWTSecret] = true
 		cfg.dirty = true
 	}
 
+	// Environment variables override file values
+	cfg.applyEnvOverrides()
+
+	// Decrypt secrets stored encrypted in the file
+	if err := cfg.initSecrets(); err != nil {
+		return nil, err
+	}
+
 	// Validate configuration
 	if err := cfg.validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -107,17 +270,59 @@ func Load(filePath string) (*Config, error) {
 // setDefaults initializes all fields with default values.
 func (c *Config) setDefaults() {
 	c.addr = DefaultAddr
+	c.basePath = DefaultBasePath
+	c.shutdown = DefaultShutdownWait
+	c.graphql = DefaultGraphQL
+	c.mdns = DefaultMDNS
+	c.mdnsName = DefaultMDNSName
+	c.netMounts = DefaultNetMounts
+	c.idleAfter = DefaultIdleAfter
+	c.corsOrigins = DefaultCORSOrigins
+	c.corsMethods = DefaultCORSMethods
+	c.corsHeaders = DefaultCORSHeaders
+	c.trustedProxies = DefaultTrustedProxy
 	c.jwtSecret = ""
 	c.jwtExpiration = DefaultJWTExpiration
+	c.jwtAlgorithm = DefaultJWTAlgorithm
 	c.noAuth = DefaultNoAuth
+	c.maintenance = DefaultMaintenance
+	c.demo = DefaultDemo
 	c.socketPath = DefaultSocket
+	c.secretEnv = DefaultSecretEnv
+	c.registries = DefaultRegistries
+	c.storageBackend = DefaultStorage
+	c.storageMaintenance = DefaultStorageMaint
+	c.eventsMax = DefaultEventsMax
+	c.eventsMaxAge = DefaultEventsMaxAge * 24 * time.Hour
+	c.eventsArchive = DefaultEventsArchive
+	c.geoIPURL = DefaultGeoIPURL
 	c.logDir = DefaultLogDir
 	c.logMaxSize = DefaultLogMaxSize
 	c.logMaxBackups = DefaultLogMaxBackups
+	c.logLevel = DefaultLogLevel
+	c.logMaxAge = DefaultLogMaxAge * 24 * time.Hour
+	c.logMaxTotal = DefaultLogMaxTotal
+	c.logCompress = DefaultLogCompress
+	c.logConsole = DefaultLogConsole
+	c.logFileFormat = DefaultLogFileFormat
+	c.logSystem = DefaultLogSystem
+	c.logSyslogAddr = ""
+	c.secretKeySource = DefaultSecretKey
+	c.secretKeyFile = ""
+	c.secretKeyData = ""
+	c.authMode = DefaultAuthMode
+	c.tlsCert = ""
+	c.tlsKey = ""
+	c.tlsClientCA = ""
+	c.configBackups = DefaultConfigBackups
 }
 
-// loadFromFile reads configuration from .env file.
+// loadFromFile reads configuration from .env or YAML file.
 func (c *Config) loadFromFile() error {
+	if isYAMLPath(c.filePath) {
+		return c.loadFromYAML()
+	}
+
 	file, err := os.Open(c.filePath)
 	if err != nil {
 		return err
@@ -129,16 +334,75 @@ func (c *Config) loadFromFile() error {
 		return err
 	}
 
-	c.applyValues(values)
+	c.applyFileValues(values)
 	return nil
 }
 
+// applyFileValues applies values read from the config file
+// and records which keys the file sets.
+func (c *Config) applyFileValues(values map[string]string) {
+	for key, v := range values {
+		if v != "" {
+			c.fileKeys[key] = true
+		}
+	}
+	c.applyValues(values)
+}
+
 // applyValues applies parsed key-value pairs to config.
 func (c *Config) applyValues(values map[string]string) {
 	if v, ok := values[EnvAddr]; ok && v != "" {
 		c.addr = v
 	}
 
+	if v, ok := values[EnvBasePath]; ok {
+		c.basePath = normalizeBasePath(v)
+	}
+
+	if v, ok := values[EnvShutdownWait]; ok && v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+			c.shutdown = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if v, ok := values[EnvGraphQL]; ok {
+		c.graphql = parseBool(v)
+	}
+
+	if v, ok := values[EnvMDNS]; ok {
+		c.mdns = parseBool(v)
+	}
+
+	if v, ok := values[EnvMDNSName]; ok && strings.TrimSpace(v) != "" {
+		c.mdnsName = strings.TrimSpace(v)
+	}
+
+	if v, ok := values[EnvNetMounts]; ok {
+		c.netMounts = parseBool(v)
+	}
+
+	if v, ok := values[EnvIdleAfter]; ok && v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes >= 0 {
+			c.idleAfter = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if v, ok := values[EnvCORSOrigins]; ok {
+		c.corsOrigins = strings.Join(splitList(v), ",")
+	}
+
+	if v, ok := values[EnvCORSMethods]; ok && v != "" {
+		c.corsMethods = strings.ToUpper(strings.Join(splitList(v), ","))
+	}
+
+	if v, ok := values[EnvCORSHeaders]; ok && v != "" {
+		c.corsHeaders = strings.Join(splitList(v), ",")
+	}
+
+	if v, ok := values[EnvTrustedProxy]; ok {
+		c.trustedProxies = strings.Join(splitList(v), ",")
+	}
+
 	if v, ok := values[EnvJWTSecret]; ok && v != "" {
 		c.jwtSecret = v
 	}
@@ -149,13 +413,58 @@ func (c *Config) applyValues(values map[string]string) {
 		}
 	}
 
+	if v, ok := values[EnvJWTAlgorithm]; ok && v != "" {
+		c.jwtAlgorithm = strings.TrimSpace(v)
+	}
+
 	if v, ok := values[EnvNoAuth]; ok {
 		c.noAuth = parseBool(v)
 	}
 
+	if v, ok := values[EnvMaintenance]; ok {
+		c.maintenance = parseBool(v)
+	}
+
+	if v, ok := values[EnvDemo]; ok {
+		c.demo = parseBool(v)
+	}
+
 	if v, ok := values[EnvSocket]; ok {
 		c.socketPath = v
 	}
+	if v, ok := values[EnvSecretEnv]; ok && v != "" {
+		c.secretEnv = strings.ToUpper(strings.Join(splitList(v), ","))
+	}
+
+	if v, ok := values[EnvRegistries]; ok && v != "" {
+		c.registries = strings.Join(splitList(v), ",")
+	}
+
+	if v, ok := values[EnvStorage]; ok && v != "" {
+		c.storageBackend = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := values[EnvStorageMaint]; ok && v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours >= 0 {
+			c.storageMaintenance = time.Duration(hours) * time.Hour
+		}
+	}
+
+	if v, ok := values[EnvEventsMax]; ok && v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			c.eventsMax = n
+		}
+	}
+	if v, ok := values[EnvEventsMaxAge]; ok && v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days >= 0 {
+			c.eventsMaxAge = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	if v, ok := values[EnvEventsArchive]; ok {
+		c.eventsArchive = v
+	}
+	if v, ok := values[EnvGeoIPURL]; ok {
+		c.geoIPURL = v
+	}
 
 	if v, ok := values[EnvLogDir]; ok && v != "" {
 		c.logDir = v
@@ -170,6 +479,63 @@ func (c *Config) applyValues(values map[string]string) {
 			c.logMaxBackups = backups
 		}
 	}
+	if v, ok := values[EnvLogLevel]; ok && v != "" {
+		c.logLevel = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := values[EnvLogMaxAge]; ok && v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days >= 0 {
+			c.logMaxAge = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	if v, ok := values[EnvLogMaxTotal]; ok && v != "" {
+		if size, err := strconv.Atoi(v); err == nil && size >= 0 {
+			c.logMaxTotal = size
+		}
+	}
+	if v, ok := values[EnvLogCompress]; ok && v != "" {
+		c.logCompress = parseBool(v)
+	}
+	if v, ok := values[EnvLogConsole]; ok && v != "" {
+		c.logConsole = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := values[EnvLogFileFormat]; ok && v != "" {
+		c.logFileFormat = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := values[EnvLogSystem]; ok {
+		c.logSystem = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := values[EnvLogSyslogAddr]; ok {
+		c.logSyslogAddr = strings.TrimSpace(v)
+	}
+
+	if v, ok := values[EnvSecretKey]; ok && v != "" {
+		c.secretKeySource = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := values[EnvSecretKeyFile]; ok {
+		c.secretKeyFile = v
+	}
+	if v, ok := values[EnvSecretKeyData]; ok {
+		c.secretKeyData = strings.TrimSpace(v)
+	}
+
+	if v, ok := values[EnvAuthMode]; ok && v != "" {
+		c.authMode = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := values[EnvTLSCert]; ok {
+		c.tlsCert = v
+	}
+	if v, ok := values[EnvTLSKey]; ok {
+		c.tlsKey = v
+	}
+	if v, ok := values[EnvTLSClientCA]; ok {
+		c.tlsClientCA = v
+	}
+
+	if v, ok := values[EnvConfigBackups]; ok && v != "" {
+		if keep, err := strconv.Atoi(v); err == nil && keep >= 0 {
+			c.configBackups = keep
+		}
+	}
 }
 
 // validate checks if configuration is valid.
@@ -179,22 +545,41 @@ func (c *Config) validate() error {
 		return errors.New("server address cannot be empty")
 	}
 
-	// Check if address format is valid
-	host, port, err := net.SplitHostPort(c.addr)
-	if err != nil {
-		// Try with default host
-		if _, err := strconv.Atoi(strings.TrimPrefix(c.addr, ":")); err != nil {
-			return fmt.Errorf("invalid server address format: %s", c.addr)
+	// Check if each listener address is valid
+	listeners := parseListeners(c.addr, c.tlsCert != "")
+	if len(listeners) == 0 {
+		return errors.New("server address cannot be empty")
+	}
+	for _, l := range listeners {
+		if err := validateListenAddr(l.Addr); err != nil {
+			return err
 		}
-	} else {
-		if port == "" {
-			return errors.New("port cannot be empty")
+		if l.TLS && c.tlsCert == "" {
+			return fmt.Errorf("https listener %s requires TLS certificate and key", l.Addr)
 		}
-		portNum, err := strconv.Atoi(port)
-		if err != nil || portNum < 1 || portNum > 65535 {
-			return fmt.Errorf("invalid port number: %s", port)
+	}
+
+	// Validate base path
+	if strings.ContainsAny(c.basePath, " ?#\\\"'<>") {
+		return fmt.Errorf("invalid base path: %s", c.basePath)
+	}
+
+	// Validate CORS origins: scheme://host[:port] or *
+	for _, origin := range splitList(c.corsOrigins) {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("invalid CORS origin: %s (expected scheme://host[:port] or *)", origin)
+		}
+	}
+
+	// Validate trusted proxies: IPs or CIDRs
+	for _, proxy := range splitList(c.trustedProxies) {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy: %s (expected an IP or CIDR)", proxy)
 		}
-		_ = host // host can be empty (bind to all interfaces)
 	}
 
 	// Validate JWT expiration
@@ -205,6 +590,37 @@ func (c *Config) validate() error {
 		return errors.New("JWT expiration cannot exceed 1 year")
 	}
 
+	// Validate JWT algorithm
+	switch c.jwtAlgorithm {
+	case "HS256", "RS256", "EdDSA":
+	default:
+		return fmt.Errorf("invalid JWT algorithm: %s (expected HS256, RS256 or EdDSA)", c.jwtAlgorithm)
+	}
+
+	// Validate log level
+	switch c.logLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid log level: %s (expected debug, info, warn or error)", c.logLevel)
+	}
+
+	// Validate log sinks
+	switch c.logConsole {
+	case "text", "json", "none":
+	default:
+		return fmt.Errorf("invalid console log format: %s (expected text, json or none)", c.logConsole)
+	}
+	switch c.logFileFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid log file format: %s (expected text or json)", c.logFileFormat)
+	}
+	switch c.logSystem {
+	case "", "journald", "syslog":
+	default:
+		return fmt.Errorf("invalid system log: %s (expected journald or syslog)", c.logSystem)
+	}
+
 	// Validate socket path if specified
 	if c.socketPath != "" {
 		// Just check it's not obviously invalid
@@ -213,17 +629,101 @@ func (c *Config) validate() error {
 		}
 	}
 
+	// Validate storage backend
+	switch c.storageBackend {
+	case "bolt", "sqlite":
+	default:
+		return fmt.Errorf("invalid storage backend: %s (expected bolt or sqlite)", c.storageBackend)
+	}
+
+	// Validate TLS settings: certificate and key must be set together
+	if (c.tlsCert == "") != (c.tlsKey == "") {
+		return errors.New("TLS certificate and key must both be set")
+	}
+
+	// Validate authentication mode
+	switch c.authMode {
+	case AuthModePassword:
+	case AuthModeMTLS:
+		if c.tlsCert == "" {
+			return errors.New("mtls auth mode requires TLS certificate and key")
+		}
+		if c.tlsClientCA == "" {
+			return errors.New("mtls auth mode requires a client CA file")
+		}
+		for _, l := range parseListeners(c.addr, true) {
+			if !l.TLS {
+				return fmt.Errorf("mtls auth mode requires https listeners, got %s", l.Addr)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid auth mode: %s (expected %s or %s)", c.authMode, AuthModePassword, AuthModeMTLS)
+	}
+
 	return nil
 }
 
-// Save writes current configuration to .env file.
+// validateListenAddr checks a single listener address (host:port, :port or unix socket path).
+func validateListenAddr(addr string) error {
+	if addr == "" {
+		return errors.New("server address cannot be empty")
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if path, ok := strings.CutPrefix(addr, UnixSocketPrefix); ok {
+		if path == "" {
+			return errors.New("unix socket path cannot be empty")
+		}
+	} else if err != nil {
+		// Try with default host
+		if _, err := strconv.Atoi(strings.TrimPrefix(addr, ":")); err != nil {
+			return fmt.Errorf("invalid server address format: %s", addr)
+		}
+	} else {
+		if port == "" {
+			return errors.New("port cannot be empty")
+		}
+		portNum, err := strconv.Atoi(port)
+		if err != nil || portNum < 1 || portNum > 65535 {
+			return fmt.Errorf("invalid port number: %s", port)
+		}
+		_ = host // host can be empty (bind to all interfaces)
+	}
+
+	return nil
+}
+
+// Save writes current configuration to .env or YAML file.
+// The previous file is kept as a backup, see Backups.
 func (c *Config) Save() error {
+	if err := c.backupCurrent(); err != nil {
+		return err
+	}
+	return c.write()
+}
+
+// write writes current configuration to the config file without a backup.
+func (c *Config) write() error {
 	c.mu.RLock()
 	values := c.toMap()
+	for key, o := range c.overrides {
+		values[key] = o.fileValue
+	}
+	err := c.encryptSecrets(values)
 	filePath := c.filePath
+	plugins := c.plugins
+	notifications := c.notifications
 	c.mu.RUnlock()
 
-	if err := WriteEnvFile(filePath, values); err != nil {
+	if err != nil {
+		return err
+	}
+	if isYAMLPath(filePath) {
+		err = WriteYAMLFile(filePath, values, plugins, notifications)
+	} else {
+		err = WriteEnvFile(filePath, values)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -238,25 +738,146 @@ func (c *Config) Save() error {
 func (c *Config) toMap() map[string]string {
 	return map[string]string{
 		EnvAddr:          c.addr,
+		EnvBasePath:      c.basePath,
+		EnvShutdownWait:  strconv.Itoa(int(c.shutdown.Seconds())),
+		EnvGraphQL:       strconv.FormatBool(c.graphql),
+		EnvMDNS:          strconv.FormatBool(c.mdns),
+		EnvMDNSName:      c.mdnsName,
+		EnvIdleAfter:     strconv.Itoa(int(c.idleAfter.Minutes())),
+		EnvNetMounts:     strconv.FormatBool(c.netMounts),
+		EnvCORSOrigins:   c.corsOrigins,
+		EnvCORSMethods:   c.corsMethods,
+		EnvCORSHeaders:   c.corsHeaders,
+		EnvTrustedProxy:  c.trustedProxies,
 		EnvJWTSecret:     c.jwtSecret,
 		EnvJWTExpiration: strconv.Itoa(int(c.jwtExpiration.Seconds())),
+		EnvJWTAlgorithm:  c.jwtAlgorithm,
 		EnvNoAuth:        strconv.FormatBool(c.noAuth),
+		EnvMaintenance:   strconv.FormatBool(c.maintenance),
+		EnvDemo:          strconv.FormatBool(c.demo),
 		EnvSocket:        c.socketPath,
+		EnvSecretEnv:     c.secretEnv,
+		EnvRegistries:    c.registries,
+		EnvStorage:       c.storageBackend,
+		EnvStorageMaint:  strconv.Itoa(int(c.storageMaintenance.Hours())),
+		EnvEventsMax:     strconv.Itoa(c.eventsMax),
+		EnvEventsMaxAge:  strconv.Itoa(int(c.eventsMaxAge.Hours() / 24)),
+		EnvEventsArchive: c.eventsArchive,
+		EnvGeoIPURL:      c.geoIPURL,
 		EnvLogDir:        c.logDir,
 		EnvLogMaxSize:    strconv.Itoa(c.logMaxSize),
 		EnvLogMaxBackups: strconv.Itoa(c.logMaxBackups),
+		EnvLogLevel:      c.logLevel,
+		EnvLogMaxAge:     strconv.Itoa(int(c.logMaxAge.Hours() / 24)),
+		EnvLogMaxTotal:   strconv.Itoa(c.logMaxTotal),
+		EnvLogCompress:   strconv.FormatBool(c.logCompress),
+		EnvLogConsole:    c.logConsole,
+		EnvLogFileFormat: c.logFileFormat,
+		EnvLogSystem:     c.logSystem,
+		EnvLogSyslogAddr: c.logSyslogAddr,
+		EnvSecretKey:     c.secretKeySource,
+		EnvSecretKeyFile: c.secretKeyFile,
+		EnvSecretKeyData: c.secretKeyData,
+		EnvAuthMode:      c.authMode,
+		EnvTLSCert:       c.tlsCert,
+		EnvTLSKey:        c.tlsKey,
+		EnvTLSClientCA:   c.tlsClientCA,
+		EnvConfigBackups: strconv.Itoa(c.configBackups),
 	}
 }
 
 // Getters (thread-safe)
 
-// Addr returns the server address.
+// Addr returns the raw server address setting (may list several listeners, see Listeners).
 func (c *Config) Addr() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.addr
 }
 
+// BasePath returns the URL prefix the app is served under ("" for root, otherwise "/path").
+func (c *Config) BasePath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.basePath
+}
+
+// ShutdownTimeout returns how long in-flight requests, event streams and
+// terminals are drained on shutdown before they are closed.
+func (c *Config) ShutdownTimeout() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.shutdown
+}
+
+// GraphQLEnabled returns whether the /api/graphql endpoint is served.
+func (c *Config) GraphQLEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.graphql
+}
+
+// MDNSEnabled returns whether the web UI is announced over mDNS.
+func (c *Config) MDNSEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mdns
+}
+
+// MDNSName returns the friendly name the web UI is announced under; its
+// host label form is the .local host name.
+func (c *Config) MDNSName() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mdnsName
+}
+
+// NetworkMounts returns whether NFS and CIFS mounts are included in the
+// disk list.
+func (c *Config) NetworkMounts() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.netMounts
+}
+
+// IdleAfter returns how long without UI clients before power saving starts
+// (0 = never).
+func (c *Config) IdleAfter() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.idleAfter
+}
+
+// CORSOrigins returns the origins allowed to call the API from other sites
+// ("*" for any), empty when CORS is disabled.
+func (c *Config) CORSOrigins() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return splitList(c.corsOrigins)
+}
+
+// CORSMethods returns the methods allowed in cross-origin requests.
+func (c *Config) CORSMethods() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return splitList(c.corsMethods)
+}
+
+// TrustedProxies returns the IPs and CIDRs of reverse proxies whose
+// forwarding headers give the client IP, empty when they are ignored.
+func (c *Config) TrustedProxies() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return splitList(c.trustedProxies)
+}
+
+// CORSHeaders returns the request headers allowed in cross-origin requests.
+func (c *Config) CORSHeaders() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return splitList(c.corsHeaders)
+}
+
 // JWTSecret returns the JWT secret key.
 func (c *Config) JWTSecret() string {
 	c.mu.RLock()
@@ -271,6 +892,13 @@ func (c *Config) JWTExpiration() time.Duration {
 	return c.jwtExpiration
 }
 
+// JWTAlgorithm returns the JWT signing algorithm (HS256, RS256 or EdDSA).
+func (c *Config) JWTAlgorithm() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.jwtAlgorithm
+}
+
 // NoAuth returns whether authentication is disabled.
 func (c *Config) NoAuth() bool {
 	c.mu.RLock()
@@ -278,6 +906,53 @@ func (c *Config) NoAuth() bool {
 	return c.noAuth
 }
 
+// MaintenanceMode returns whether read-only maintenance mode is active.
+func (c *Config) MaintenanceMode() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.maintenance
+}
+
+// DemoMode returns whether PodmanView runs as a public demo: mock data
+// instead of Podman and the host, no login, and all changes rejected.
+func (c *Config) DemoMode() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.demo
+}
+
+// SetMaintenanceMode enables or disables read-only maintenance mode
+// and persists the change to the config file.
+func (c *Config) SetMaintenanceMode(enabled bool) error {
+	c.mu.Lock()
+	c.maintenance = enabled
+	c.clearOverride(EnvMaintenance)
+	c.dirty = true
+	c.mu.Unlock()
+
+	return c.Save()
+}
+
+// Update applies key-value pairs (same keys as the config file), validates
+// the result and persists it. On validation error nothing is changed.
+func (c *Config) Update(values map[string]string) error {
+	c.mu.Lock()
+	previous := c.toMap()
+	c.applyValues(values)
+	if err := c.validate(); err != nil {
+		c.applyValues(previous)
+		c.mu.Unlock()
+		return err
+	}
+	for key := range values {
+		c.clearOverride(key)
+	}
+	c.dirty = true
+	c.mu.Unlock()
+
+	return c.Save()
+}
+
 // SocketPath returns the Podman socket path.
 func (c *Config) SocketPath() string {
 	c.mu.RLock()
@@ -285,6 +960,66 @@ func (c *Config) SocketPath() string {
 	return c.socketPath
 }
 
+// SecretEnvPatterns returns the upper-case name patterns of container
+// environment variables whose values are masked, e.g. *PASSWORD*.
+func (c *Config) SecretEnvPatterns() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return splitList(c.secretEnv)
+}
+
+// Registries returns the registries the registry browser may search, e.g.
+// docker.io or http://localhost:5000 for a registry without TLS.
+func (c *Config) Registries() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return splitList(c.registries)
+}
+
+// StorageBackend returns the application storage backend (bolt or sqlite).
+func (c *Config) StorageBackend() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.storageBackend
+}
+
+// StorageMaintenanceInterval returns how often storage is checked and compacted (0 = never).
+func (c *Config) StorageMaintenanceInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.storageMaintenance
+}
+
+// EventsMax returns the maximum number of events kept in memory.
+func (c *Config) EventsMax() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.eventsMax
+}
+
+// EventsMaxAge returns the maximum age of events (0 = no limit).
+func (c *Config) EventsMaxAge() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.eventsMaxAge
+}
+
+// EventsArchiveDir returns the directory events are archived to when removed
+// by retention (empty = removed events are discarded).
+func (c *Config) EventsArchiveDir() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.eventsArchive
+}
+
+// GeoIPURL returns the URL of the location lookup of login IPs, with {ip}
+// in place of the address (empty = only private addresses are located).
+func (c *Config) GeoIPURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.geoIPURL
+}
+
 // LogDir returns the log directory path.
 func (c *Config) LogDir() string {
 	c.mu.RLock()
@@ -306,8 +1041,133 @@ func (c *Config) LogMaxBackups() int {
 	return c.logMaxBackups
 }
 
+// LogLevel returns the log level (debug, info, warn or error).
+func (c *Config) LogLevel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logLevel
+}
+
+// LogMaxAge returns how long rotated logs are kept (0 = no limit).
+func (c *Config) LogMaxAge() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logMaxAge
+}
+
+// LogMaxTotal returns the maximum total size of rotated logs in MB (0 = no limit).
+func (c *Config) LogMaxTotal() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logMaxTotal
+}
+
+// LogCompress returns true if rotated logs are gzip compressed.
+func (c *Config) LogCompress() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logCompress
+}
+
+// LogConsole returns the console log format (text, json or none).
+func (c *Config) LogConsole() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logConsole
+}
+
+// LogFileFormat returns the format of app.log and error.log (text or json).
+func (c *Config) LogFileFormat() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logFileFormat
+}
+
+// LogSystem returns the system log (journald or syslog), empty if disabled.
+func (c *Config) LogSystem() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logSystem
+}
+
+// LogSyslogAddr returns the syslog server address, empty for the local daemon.
+func (c *Config) LogSyslogAddr() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logSyslogAddr
+}
+
+// AuthMode returns the authentication mode (password or mtls).
+func (c *Config) AuthMode() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.authMode
+}
+
+// TLSEnabled returns whether a server certificate is configured.
+// Individual listeners may still serve plain HTTP, see Listeners.
+func (c *Config) TLSEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tlsCert != "" && c.tlsKey != ""
+}
+
+// TLSCertFile returns the path to the server TLS certificate.
+func (c *Config) TLSCertFile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tlsCert
+}
+
+// TLSKeyFile returns the path to the server TLS private key.
+func (c *Config) TLSKeyFile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tlsKey
+}
+
+// TLSClientCAFile returns the path to the CA bundle used to verify client certificates.
+func (c *Config) TLSClientCAFile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tlsClientCA
+}
+
+// FilePath returns the path of the loaded config file.
+func (c *Config) FilePath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.filePath
+}
+
+// PluginSettings returns the plugins.<name> section of config.yaml, or nil if not set.
+func (c *Config) PluginSettings(name string) map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return copySection(c.plugins[name])
+}
+
+// NotificationSettings returns the notifications.<channel> section of config.yaml, or nil if not set.
+func (c *Config) NotificationSettings(channel string) map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return copySection(c.notifications[channel])
+}
+
 // Helper functions
 
+// copySection returns a shallow copy of a free-form config section.
+func copySection(section map[string]interface{}) map[string]interface{} {
+	if section == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(section))
+	for k, v := range section {
+		result[k] = v
+	}
+	return result
+}
+
 // generateSecureSecret generates a cryptographically secure random hex string.
 func generateSecureSecret(length int) (string, error) {
 	bytes := make([]byte, length)
@@ -317,6 +1177,27 @@ func generateSecureSecret(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// normalizeBasePath returns the base path with a leading slash and no trailing slash.
+// "" and "/" both mean the root.
+func normalizeBasePath(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), "/")
+	if s == "" {
+		return ""
+	}
+	return "/" + s
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // parseBool parses a boolean string value.
 // Accepts: true, false, 1, 0, yes, no (case-insensitive)
 func parseBool(s string) bool {
@@ -340,7 +1221,7 @@ func (c *Config) String() string {
 	}
 
 	return fmt.Sprintf(
-		"Config{Addr: %q, JWTSecret: %s, JWTExpiration: %v, NoAuth: %v, SocketPath: %q}",
-		c.addr, secretDisplay, c.jwtExpiration, c.noAuth, c.socketPath,
+		"Config{Addr: %q, JWTSecret: %s, JWTExpiration: %v, NoAuth: %v, Maintenance: %v, SocketPath: %q, Storage: %s, AuthMode: %q, TLS: %v}",
+		c.addr, secretDisplay, c.jwtExpiration, c.noAuth, c.maintenance, c.socketPath, c.storageBackend, c.authMode, c.tlsCert != "",
 	)
 }
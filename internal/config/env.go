@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"sort"
+)
+
+// Setting sources, in increasing order of precedence
+const (
+	SourceDefault = "default" // built-in default value
+	SourceFile    = "file"    // .env or config.yaml
+	SourceEnv     = "env"     // process environment variable
+	SourceFlag    = "flag"    // command-line flag
+)
+
+// secretKeys are settings whose values are never exposed through Settings().
+var secretKeys = map[string]bool{
+	EnvJWTSecret:     true,
+	EnvSecretKeyData: true,
+}
+
+// Setting describes the effective value of a single configuration key.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// override records a runtime-only value that hides the file value.
+type override struct {
+	fileValue string // value to keep when saving the file
+	source    string // SourceEnv or SourceFlag
+}
+
+// applyEnvOverrides applies PODMANVIEW_* process environment variables on top
+// of file-based config.
+func (c *Config) applyEnvOverrides() {
+	values := make(map[string]string)
+	for key := range c.toMap() {
+		if v, ok := os.LookupEnv(key); ok {
+			values[key] = v
+		}
+	}
+	c.applyOverrides(values, SourceEnv)
+}
+
+// applyOverrides applies values at runtime only; they are never written back
+// to the config file. Caller must hold c.mu or own c exclusively.
+func (c *Config) applyOverrides(values map[string]string, source string) {
+	if len(values) == 0 {
+		return
+	}
+
+	fileValues := c.toMap()
+	c.applyValues(values)
+
+	if c.overrides == nil {
+		c.overrides = make(map[string]override, len(values))
+	}
+	for key := range values {
+		// Keep the original file value if the key is already overridden
+		fileValue := fileValues[key]
+		if prev, ok := c.overrides[key]; ok {
+			fileValue = prev.fileValue
+		}
+		c.overrides[key] = override{fileValue: fileValue, source: source}
+	}
+}
+
+// ApplyOverrides applies runtime-only values (e.g. command-line flags) using
+// the same keys as the config file and re-validates the configuration.
+// Overrides take precedence over the file and environment variables.
+func (c *Config) ApplyOverrides(values map[string]string, source string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.applyOverrides(values, source)
+	return c.validate()
+}
+
+// clearOverride drops the override for key, so a runtime change
+// made through a setter is persisted. Caller must hold c.mu.
+func (c *Config) clearOverride(key string) {
+	delete(c.overrides, key)
+}
+
+// Settings returns the effective value and source of every setting.
+// Secret values are masked.
+func (c *Config) Settings() []Setting {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	values := c.toMap()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		value := values[key]
+		if secretKeys[key] && value != "" {
+			value = "[set]"
+		}
+
+		source := SourceDefault
+		if o, ok := c.overrides[key]; ok {
+			source = o.source
+		} else if c.fileKeys[key] {
+			source = SourceFile
+		}
+
+		result = append(result, Setting{Key: key, Value: value, Source: source})
+	}
+
+	return result
+}
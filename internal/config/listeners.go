@@ -0,0 +1,49 @@
+package config
+
+import "strings"
+
+// Listener scheme prefixes for PODMANVIEW_ADDR entries
+const (
+	httpPrefix  = "http://"
+	httpsPrefix = "https://"
+)
+
+// Listener is a single address the server listens on.
+type Listener struct {
+	Addr string // host:port, :port or unix:///path
+	TLS  bool   // serve HTTPS using the configured certificate
+}
+
+// parseListeners splits a comma-separated PODMANVIEW_ADDR value into listeners.
+// Entries may be prefixed with http:// or https://. Without a prefix, TCP
+// listeners use HTTPS when a certificate is configured; unix sockets are plain HTTP.
+func parseListeners(addr string, tlsDefault bool) []Listener {
+	var listeners []Listener
+
+	for _, part := range strings.Split(addr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(part, httpsPrefix):
+			listeners = append(listeners, Listener{Addr: strings.TrimPrefix(part, httpsPrefix), TLS: true})
+		case strings.HasPrefix(part, httpPrefix):
+			listeners = append(listeners, Listener{Addr: strings.TrimPrefix(part, httpPrefix)})
+		case strings.HasPrefix(part, UnixSocketPrefix):
+			listeners = append(listeners, Listener{Addr: part})
+		default:
+			listeners = append(listeners, Listener{Addr: part, TLS: tlsDefault})
+		}
+	}
+
+	return listeners
+}
+
+// Listeners returns all configured listen addresses.
+func (c *Config) Listeners() []Listener {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return parseListeners(c.addr, c.tlsCert != "")
+}
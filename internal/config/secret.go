@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"podmanview/internal/secrets"
+)
+
+// Secret key sources for encrypting secrets at rest
+const (
+	SecretKeyNone       = "none"       // secrets are stored in plaintext
+	SecretKeyMachine    = "machine"    // key derived from /etc/machine-id
+	SecretKeyPassphrase = "passphrase" // key derived from PODMANVIEW_SECRET_PASSPHRASE
+	SecretKeyFile       = "file"       // key read from PODMANVIEW_SECRET_KEY_FILE
+	SecretKeyConfig     = "key"        // key set in PODMANVIEW_SECRET_KEY
+)
+
+// EnvSecretPassphrase is read from the process environment only and is never saved.
+const EnvSecretPassphrase = "PODMANVIEW_SECRET_PASSPHRASE"
+
+// encryptedKeys are settings written encrypted to the config file when a key is configured.
+// The secret key itself is masked in Settings() but can't be encrypted with itself.
+var encryptedKeys = map[string]bool{
+	EnvJWTSecret: true,
+}
+
+// initSecrets creates the cipher for the configured key source
+// and decrypts secret values loaded from the file.
+func (c *Config) initSecrets() error {
+	switch c.secretKeySource {
+	case SecretKeyNone:
+		c.cipher = nil
+	case SecretKeyMachine:
+		cph, err := secrets.FromMachineID()
+		if err != nil {
+			return fmt.Errorf("failed to derive secret key: %w", err)
+		}
+		c.cipher = cph
+	case SecretKeyPassphrase:
+		cph, err := secrets.FromPassphrase(os.Getenv(EnvSecretPassphrase))
+		if err != nil {
+			return fmt.Errorf("failed to derive secret key (set %s): %w", EnvSecretPassphrase, err)
+		}
+		c.cipher = cph
+	case SecretKeyFile:
+		if c.secretKeyFile == "" {
+			return fmt.Errorf("secret key source %s requires %s", SecretKeyFile, EnvSecretKeyFile)
+		}
+		cph, err := secrets.FromKeyFile(c.secretKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read secret key file: %w", err)
+		}
+		c.cipher = cph
+	case SecretKeyConfig:
+		cph, err := secrets.FromKey(c.secretKeyData)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvSecretKeyData, err)
+		}
+		c.cipher = cph
+	default:
+		return fmt.Errorf("invalid secret key source: %s (expected %s, %s, %s, %s or %s)",
+			c.secretKeySource, SecretKeyNone, SecretKeyMachine, SecretKeyPassphrase, SecretKeyFile, SecretKeyConfig)
+	}
+
+	if secrets.IsEncrypted(c.jwtSecret) {
+		secret, err := c.cipher.Decrypt(c.jwtSecret)
+		if err != nil {
+			return fmt.Errorf("JWT secret: %w", err)
+		}
+		c.jwtSecret = secret
+	} else if c.cipher != nil && c.fileKeys[EnvJWTSecret] {
+		// Plaintext secret in file - rewrite it encrypted
+		c.dirty = true
+	}
+
+	return nil
+}
+
+// encryptSecrets encrypts secret values before they are written to the file.
+func (c *Config) encryptSecrets(values map[string]string) error {
+	if c.cipher == nil {
+		return nil
+	}
+
+	for key := range encryptedKeys {
+		v, ok := values[key]
+		if !ok || v == "" || strings.HasPrefix(v, secrets.Prefix) {
+			continue
+		}
+		enc, err := c.cipher.Encrypt(v)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		values[key] = enc
+	}
+
+	return nil
+}
+
+// SecretCipher returns the cipher used for secrets at rest, or nil if encryption is disabled.
+// The same cipher is used for secrets kept in the application database.
+func (c *Config) SecretCipher() *secrets.Cipher {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cipher
+}
@@ -25,6 +25,17 @@ var envTemplate = []envEntry{
 	{"", "# ==================="},
 	{"", ""},
 	{"PODMANVIEW_ADDR", "# Server address (host:port)"},
+	{"PODMANVIEW_BASE_PATH", "# URL prefix when served behind a path-based reverse proxy (e.g. /podmanview)"},
+	{"PODMANVIEW_SHUTDOWN_TIMEOUT", "# Seconds to drain requests, event streams and terminals on shutdown"},
+	{"PODMANVIEW_GRAPHQL", "# Serve the GraphQL API at /api/graphql (true/false)"},
+	{"PODMANVIEW_MDNS", "# Announce the web UI on the local network over mDNS/zeroconf (true/false)"},
+	{"PODMANVIEW_MDNS_NAME", "# Name shown in service browsers, also the host name (PodmanView -> podmanview.local)"},
+	{"PODMANVIEW_IDLE_AFTER", "# Minutes without UI clients before sampling slows down and maintenance jobs wait (0 = never)"},
+	{"PODMANVIEW_NETWORK_MOUNTS", "# Show NFS and CIFS mounts in the disk list, unresponsive ones as stale (true/false)"},
+	{"PODMANVIEW_CORS_ORIGINS", "# Origins allowed to call the API from other sites, comma-separated (e.g. http://localhost:5173), empty disables CORS"},
+	{"PODMANVIEW_CORS_METHODS", "# Methods allowed in cross-origin requests"},
+	{"PODMANVIEW_CORS_HEADERS", "# Request headers allowed in cross-origin requests"},
+	{"PODMANVIEW_TRUSTED_PROXIES", "# Reverse proxies whose X-Real-IP and X-Forwarded-For give the client IP, comma-separated IPs or CIDRs (e.g. 127.0.0.1)"},
 	{"", ""},
 	{"", "# ==================="},
 	{"", "# Security Settings"},
@@ -32,13 +43,68 @@ var envTemplate = []envEntry{
 	{"", ""},
 	{"PODMANVIEW_JWT_SECRET", "# JWT secret key (auto-generated, do not share!)"},
 	{"PODMANVIEW_JWT_EXPIRATION", "# JWT token expiration in seconds (default: 24 hours)"},
+	{"PODMANVIEW_JWT_ALGORITHM", "# JWT signing algorithm: HS256, RS256 or EdDSA (asymmetric keys are published at /api/auth/jwks)"},
 	{"PODMANVIEW_NO_AUTH", "# Disable authentication (true/false, for development only!)"},
+	{"PODMANVIEW_MAINTENANCE", "# Read-only maintenance mode (true/false): mutating API calls return 503"},
+	{"PODMANVIEW_DEMO", "# Public demo mode (true/false): mock data, no login, all changes rejected"},
+	{"PODMANVIEW_AUTH_MODE", "# Authentication mode: password (PAM login) or mtls (client certificates)"},
+	{"PODMANVIEW_SECRET_KEY_SOURCE", "# Encrypt secrets at rest: none, machine (/etc/machine-id), passphrase (PODMANVIEW_SECRET_PASSPHRASE env var), file or key"},
+	{"PODMANVIEW_SECRET_KEY_FILE", "# Key file for the file source: 32 raw bytes or a hex/base64 key (openssl rand -hex 32 > key)"},
+	{"PODMANVIEW_SECRET_KEY", "# Hex or base64 32-byte key for the key source (protects the database, not this file)"},
+	{"", ""},
+	{"", "# ==================="},
+	{"", "# TLS Settings"},
+	{"", "# ==================="},
+	{"", ""},
+	{"PODMANVIEW_TLS_CERT", "# Server certificate file (PEM). Leave empty to serve plain HTTP"},
+	{"PODMANVIEW_TLS_KEY", "# Server private key file (PEM)"},
+	{"PODMANVIEW_TLS_CLIENT_CA", "# CA bundle for verifying client certificates (required for mtls mode)"},
 	{"", ""},
 	{"", "# ==================="},
 	{"", "# Podman Settings"},
 	{"", "# ==================="},
 	{"", ""},
 	{"PODMANVIEW_SOCKET", "# Podman socket path (leave empty for auto-detection)"},
+	{"PODMANVIEW_SECRET_ENV", "# Container environment variable names masked in the env editor, comma-separated patterns (* matches any text)"},
+	{"PODMANVIEW_REGISTRIES", "# Registries the image browser can search, comma-separated (http://host:port for a registry without TLS)"},
+	{"", ""},
+	{"", "# ==================="},
+	{"", "# Storage Settings"},
+	{"", "# ==================="},
+	{"", ""},
+	{"PODMANVIEW_STORAGE", "# Application storage backend: bolt (podmanview.db) or sqlite (podmanview.sqlite)"},
+	{"PODMANVIEW_STORAGE_MAINTENANCE", "# Hours between storage integrity checks and compaction (0 disables)"},
+	{"", ""},
+	{"", "# ==================="},
+	{"", "# Event Log Settings"},
+	{"", "# ==================="},
+	{"", ""},
+	{"PODMANVIEW_EVENTS_MAX", "# Number of events kept in memory"},
+	{"PODMANVIEW_EVENTS_MAX_AGE", "# Days to keep events (0 = no limit)"},
+	{"PODMANVIEW_EVENTS_ARCHIVE", "# Directory for compressed JSONL archives of removed events (empty = discard)"},
+	{"PODMANVIEW_GEOIP_URL", "# Location lookup of login IPs for user activity, {ip} is replaced (empty = disabled)"},
+	{"", ""},
+	{"", "# ==================="},
+	{"", "# Logging Settings"},
+	{"", "# ==================="},
+	{"", ""},
+	{"PODMANVIEW_LOG_DIR", "# Log directory"},
+	{"PODMANVIEW_LOG_MAX_SIZE", "# Max log file size in MB before rotation"},
+	{"PODMANVIEW_LOG_MAX_BACKUPS", "# Number of rotated log backups to keep"},
+	{"PODMANVIEW_LOG_LEVEL", "# Log level: debug, info, warn or error"},
+	{"PODMANVIEW_LOG_MAX_AGE", "# Days to keep rotated logs, logs are also rotated daily (0 = no limit)"},
+	{"PODMANVIEW_LOG_MAX_TOTAL", "# Max total size of rotated logs in MB per log file (0 = no limit)"},
+	{"PODMANVIEW_LOG_COMPRESS", "# Gzip rotated logs (true/false)"},
+	{"PODMANVIEW_LOG_CONSOLE", "# Console log format: text, json or none"},
+	{"PODMANVIEW_LOG_FILE_FORMAT", "# app.log/error.log format: text or json"},
+	{"PODMANVIEW_LOG_SYSTEM", "# Also log to the system log: journald or syslog (empty = disabled)"},
+	{"PODMANVIEW_LOG_SYSLOG_ADDR", "# Syslog server, e.g. udp://192.168.1.10:514 (empty = local daemon)"},
+	{"", ""},
+	{"", "# ==================="},
+	{"", "# Backup Settings"},
+	{"", "# ==================="},
+	{"", ""},
+	{"PODMANVIEW_CONFIG_BACKUPS", "# Number of config versions to keep in config-backups/ (0 disables backups)"},
 }
 
 // WriteEnvFile writes configuration to .env file with comments.
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlHeader is written at the top of generated config.yaml files.
+const yamlHeader = `# PodmanView Configuration
+# Generated automatically. Comments are not preserved on save.
+`
+
+// yamlFile is the structured layout of config.yaml.
+type yamlFile struct {
+	Server struct {
+		Addr        string   `yaml:"addr"`
+		BasePath    string   `yaml:"base_path"`
+		Socket      string   `yaml:"socket"`
+		SecretEnv   []string `yaml:"secret_env"` // masked container env var names
+		Registries  []string `yaml:"registries"` // searched by the registry browser
+		Maintenance bool     `yaml:"maintenance"`
+		Demo        bool     `yaml:"demo"`
+		Shutdown    int      `yaml:"shutdown_timeout"` // seconds
+		GraphQL     bool     `yaml:"graphql"`
+		IdleAfter   int      `yaml:"idle_after"`      // minutes without UI clients, 0 disables
+		NetMounts   bool     `yaml:"network_mounts"`  // NFS and CIFS mounts in the disk list
+		Proxies     []string `yaml:"trusted_proxies"` // IPs and CIDRs whose forwarding headers are trusted
+		MDNS        struct {
+			Enabled bool   `yaml:"enabled"`
+			Name    string `yaml:"name"` // friendly name, also the .local host name
+		} `yaml:"mdns"`
+		CORS struct {
+			Origins []string `yaml:"origins"` // empty disables CORS
+			Methods []string `yaml:"methods"`
+			Headers []string `yaml:"headers"`
+		} `yaml:"cors"`
+		TLS struct {
+			Cert     string `yaml:"cert"`
+			Key      string `yaml:"key"`
+			ClientCA string `yaml:"client_ca"`
+		} `yaml:"tls"`
+	} `yaml:"server"`
+
+	Auth struct {
+		Mode            string `yaml:"mode"`
+		NoAuth          bool   `yaml:"no_auth"`
+		SecretKeySource string `yaml:"secret_key_source"`
+		SecretKeyFile   string `yaml:"secret_key_file"`
+		SecretKey       string `yaml:"secret_key"`
+		JWT             struct {
+			Secret     string `yaml:"secret"`
+			Expiration int    `yaml:"expiration"` // seconds
+			Algorithm  string `yaml:"algorithm"`
+		} `yaml:"jwt"`
+	} `yaml:"auth"`
+
+	Storage struct {
+		Backend     string `yaml:"backend"`
+		Maintenance *int   `yaml:"maintenance"` // hours, 0 disables
+	} `yaml:"storage"`
+
+	Events struct {
+		Max     int    `yaml:"max"`
+		MaxAge  int    `yaml:"max_age"` // days, 0 = no limit
+		Archive string `yaml:"archive"` // directory, empty disables archiving
+		GeoIP   string `yaml:"geoip"`   // location lookup URL of login IPs, {ip} replaced
+	} `yaml:"events"`
+
+	Logging struct {
+		Dir        string `yaml:"dir"`
+		MaxSize    int    `yaml:"max_size"`    // MB
+		MaxBackups *int   `yaml:"max_backups"` // pointer: 0 is a valid value
+		Level      string `yaml:"level"`
+		MaxAge     int    `yaml:"max_age"`     // days, 0 = no limit
+		MaxTotal   *int   `yaml:"max_total"`   // MB, pointer: 0 is a valid value
+		Compress   *bool  `yaml:"compress"`    // pointer: defaults to true
+		Console    string `yaml:"console"`     // text, json or none
+		FileFormat string `yaml:"file_format"` // text or json
+		System     string `yaml:"system"`      // journald, syslog or empty
+		SyslogAddr string `yaml:"syslog_addr"` // udp://host:514, empty = local daemon
+	} `yaml:"logging"`
+
+	Backup struct {
+		Keep *int `yaml:"keep"` // config versions to keep, 0 disables backups
+	} `yaml:"backup"`
+
+	// Free-form sections, kept as-is for plugins and notification channels
+	Plugins       map[string]map[string]interface{} `yaml:"plugins,omitempty"`
+	Notifications map[string]map[string]interface{} `yaml:"notifications,omitempty"`
+}
+
+// isYAMLPath reports whether the config file should be read as YAML.
+func isYAMLPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// loadFromYAML reads configuration from a YAML file.
+// A missing or empty file is treated as a request to convert: settings are
+// imported from the .env file next to it and written back as YAML on save.
+func (c *Config) loadFromYAML() error {
+	data, err := os.ReadFile(c.filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if err := c.importEnvFile(); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		c.dirty = true
+		return nil
+	}
+
+	return c.applyYAML(data)
+}
+
+// applyYAML parses YAML config contents and applies them.
+func (c *Config) applyYAML(data []byte) error {
+	var f yamlFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", c.filePath, err)
+	}
+
+	c.applyFileValues(f.values())
+	c.plugins = f.Plugins
+	c.notifications = f.Notifications
+	return nil
+}
+
+// importEnvFile loads settings from the .env file in the same directory
+// as the YAML config, so existing installations keep their settings.
+func (c *Config) importEnvFile() error {
+	envPath := filepath.Join(filepath.Dir(c.filePath), ".env")
+
+	file, err := os.Open(envPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	values, err := ParseEnvFile(file)
+	if err != nil {
+		return err
+	}
+
+	c.applyFileValues(values)
+	return nil
+}
+
+// values flattens the YAML layout into the same key-value form as .env,
+// so both formats share defaults, parsing and validation.
+func (f *yamlFile) values() map[string]string {
+	values := map[string]string{
+		EnvAddr:          f.Server.Addr,
+		EnvBasePath:      f.Server.BasePath,
+		EnvSocket:        f.Server.Socket,
+		EnvSecretEnv:     strings.Join(f.Server.SecretEnv, ","),
+		EnvRegistries:    strings.Join(f.Server.Registries, ","),
+		EnvMaintenance:   strconv.FormatBool(f.Server.Maintenance),
+		EnvDemo:          strconv.FormatBool(f.Server.Demo),
+		EnvShutdownWait:  strconv.Itoa(f.Server.Shutdown),
+		EnvGraphQL:       strconv.FormatBool(f.Server.GraphQL),
+		EnvMDNS:          strconv.FormatBool(f.Server.MDNS.Enabled),
+		EnvMDNSName:      f.Server.MDNS.Name,
+		EnvIdleAfter:     strconv.Itoa(f.Server.IdleAfter),
+		EnvNetMounts:     strconv.FormatBool(f.Server.NetMounts),
+		EnvCORSOrigins:   strings.Join(f.Server.CORS.Origins, ","),
+		EnvCORSMethods:   strings.Join(f.Server.CORS.Methods, ","),
+		EnvCORSHeaders:   strings.Join(f.Server.CORS.Headers, ","),
+		EnvTrustedProxy:  strings.Join(f.Server.Proxies, ","),
+		EnvTLSCert:       f.Server.TLS.Cert,
+		EnvTLSKey:        f.Server.TLS.Key,
+		EnvTLSClientCA:   f.Server.TLS.ClientCA,
+		EnvAuthMode:      f.Auth.Mode,
+		EnvNoAuth:        strconv.FormatBool(f.Auth.NoAuth),
+		EnvSecretKey:     f.Auth.SecretKeySource,
+		EnvSecretKeyFile: f.Auth.SecretKeyFile,
+		EnvSecretKeyData: f.Auth.SecretKey,
+		EnvJWTSecret:     f.Auth.JWT.Secret,
+		EnvJWTAlgorithm:  f.Auth.JWT.Algorithm,
+		EnvStorage:       f.Storage.Backend,
+		EnvEventsArchive: f.Events.Archive,
+		EnvGeoIPURL:      f.Events.GeoIP,
+		EnvLogDir:        f.Logging.Dir,
+		EnvLogLevel:      f.Logging.Level,
+		EnvLogConsole:    f.Logging.Console,
+		EnvLogFileFormat: f.Logging.FileFormat,
+		EnvLogSystem:     f.Logging.System,
+		EnvLogSyslogAddr: f.Logging.SyslogAddr,
+	}
+
+	// Zero means "not set" for numeric values
+	if f.Auth.JWT.Expiration > 0 {
+		values[EnvJWTExpiration] = strconv.Itoa(f.Auth.JWT.Expiration)
+	}
+	if f.Events.Max > 0 {
+		values[EnvEventsMax] = strconv.Itoa(f.Events.Max)
+	}
+	if f.Events.MaxAge > 0 {
+		values[EnvEventsMaxAge] = strconv.Itoa(f.Events.MaxAge)
+	}
+	if f.Logging.MaxSize > 0 {
+		values[EnvLogMaxSize] = strconv.Itoa(f.Logging.MaxSize)
+	}
+	if f.Logging.MaxBackups != nil {
+		values[EnvLogMaxBackups] = strconv.Itoa(*f.Logging.MaxBackups)
+	}
+	if f.Logging.MaxAge > 0 {
+		values[EnvLogMaxAge] = strconv.Itoa(f.Logging.MaxAge)
+	}
+	if f.Logging.MaxTotal != nil {
+		values[EnvLogMaxTotal] = strconv.Itoa(*f.Logging.MaxTotal)
+	}
+	if f.Logging.Compress != nil {
+		values[EnvLogCompress] = strconv.FormatBool(*f.Logging.Compress)
+	}
+	if f.Storage.Maintenance != nil {
+		values[EnvStorageMaint] = strconv.Itoa(*f.Storage.Maintenance)
+	}
+	if f.Backup.Keep != nil {
+		values[EnvConfigBackups] = strconv.Itoa(*f.Backup.Keep)
+	}
+
+	return values
+}
+
+// WriteYAMLFile writes configuration to a YAML file.
+// Uses atomic write (write to temp file, then rename).
+func WriteYAMLFile(filePath string, values map[string]string, plugins, notifications map[string]map[string]interface{}) error {
+	var f yamlFile
+
+	f.Server.Addr = values[EnvAddr]
+	f.Server.BasePath = values[EnvBasePath]
+	f.Server.Socket = values[EnvSocket]
+	f.Server.SecretEnv = splitList(values[EnvSecretEnv])
+	f.Server.Registries = splitList(values[EnvRegistries])
+	f.Server.Maintenance = parseBool(values[EnvMaintenance])
+	f.Server.Demo = parseBool(values[EnvDemo])
+	f.Server.Shutdown, _ = strconv.Atoi(values[EnvShutdownWait])
+	f.Server.GraphQL = parseBool(values[EnvGraphQL])
+	f.Server.MDNS.Enabled = parseBool(values[EnvMDNS])
+	f.Server.MDNS.Name = values[EnvMDNSName]
+	f.Server.IdleAfter, _ = strconv.Atoi(values[EnvIdleAfter])
+	f.Server.NetMounts = parseBool(values[EnvNetMounts])
+	f.Server.CORS.Origins = splitList(values[EnvCORSOrigins])
+	f.Server.CORS.Methods = splitList(values[EnvCORSMethods])
+	f.Server.CORS.Headers = splitList(values[EnvCORSHeaders])
+	f.Server.Proxies = splitList(values[EnvTrustedProxy])
+	f.Server.TLS.Cert = values[EnvTLSCert]
+	f.Server.TLS.Key = values[EnvTLSKey]
+	f.Server.TLS.ClientCA = values[EnvTLSClientCA]
+
+	f.Auth.Mode = values[EnvAuthMode]
+	f.Auth.NoAuth = parseBool(values[EnvNoAuth])
+	f.Auth.SecretKeySource = values[EnvSecretKey]
+	f.Auth.SecretKeyFile = values[EnvSecretKeyFile]
+	f.Auth.SecretKey = values[EnvSecretKeyData]
+	f.Auth.JWT.Secret = values[EnvJWTSecret]
+	f.Auth.JWT.Expiration, _ = strconv.Atoi(values[EnvJWTExpiration])
+	f.Auth.JWT.Algorithm = values[EnvJWTAlgorithm]
+
+	f.Storage.Backend = values[EnvStorage]
+	if hours, err := strconv.Atoi(values[EnvStorageMaint]); err == nil {
+		f.Storage.Maintenance = &hours
+	}
+
+	f.Events.Max, _ = strconv.Atoi(values[EnvEventsMax])
+	f.Events.MaxAge, _ = strconv.Atoi(values[EnvEventsMaxAge])
+	f.Events.Archive = values[EnvEventsArchive]
+	f.Events.GeoIP = values[EnvGeoIPURL]
+
+	f.Logging.Dir = values[EnvLogDir]
+	f.Logging.MaxSize, _ = strconv.Atoi(values[EnvLogMaxSize])
+	f.Logging.Level = values[EnvLogLevel]
+	f.Logging.Console = values[EnvLogConsole]
+	f.Logging.FileFormat = values[EnvLogFileFormat]
+	f.Logging.System = values[EnvLogSystem]
+	f.Logging.SyslogAddr = values[EnvLogSyslogAddr]
+	if backups, err := strconv.Atoi(values[EnvLogMaxBackups]); err == nil {
+		f.Logging.MaxBackups = &backups
+	}
+	f.Logging.MaxAge, _ = strconv.Atoi(values[EnvLogMaxAge])
+	if total, err := strconv.Atoi(values[EnvLogMaxTotal]); err == nil {
+		f.Logging.MaxTotal = &total
+	}
+	if v := values[EnvLogCompress]; v != "" {
+		compress := parseBool(v)
+		f.Logging.Compress = &compress
+	}
+	if keep, err := strconv.Atoi(values[EnvConfigBackups]); err == nil {
+		f.Backup.Keep = &keep
+	}
+
+	f.Plugins = plugins
+	f.Notifications = notifications
+
+	var buf bytes.Buffer
+	buf.WriteString(yamlHeader)
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&f); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return atomicWrite(filePath, buf.String())
+}
@@ -0,0 +1,621 @@
+// Package demo provides the anonymized mock data served in demo mode: a
+// fake Podman API with a fixed set of containers, images and pods, and
+// sample events. Nothing is read from the host.
+package demo
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"podmanview/internal/events"
+)
+
+// Hostname is the host name reported in demo mode
+const Hostname = "demo-host"
+
+// mockContainer is a container of the mock Podman API
+type mockContainer struct {
+	name    string
+	image   string
+	command []string
+	state   string
+	pod     string
+	network []string
+	host    mockHostConfig
+	ports   [][2]int // host, container
+	labels  map[string]string
+	env     []string
+	mounts  [][2]string // source, destination
+	age     time.Duration
+	cpu     float64 // average CPU usage in percent (running only)
+	memory  uint64  // bytes (running only)
+	logs    []string
+}
+
+// started is when the demo instance started; container ages are relative to it
+var started = time.Now().Truncate(time.Minute)
+
+var containers = []mockContainer{
+	{
+		name: "web", image: "docker.io/library/nginx:1.27", command: []string{"nginx", "-g", "daemon off;"}, state: "running",
+		ports: [][2]int{{8080, 80}}, network: []string{"podman", "shop"},
+		labels: map[string]string{"app": "web", "tier": "front", "com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.depends_on": "api:service_started:false"},
+		env:    []string{"NGINX_PORT=80"}, mounts: [][2]string{{"/srv/web/html", "/usr/share/nginx/html"}},
+		age: 72 * time.Hour, cpu: 1.5, memory: 12 << 20,
+		logs: []string{
+			"/docker-entrypoint.sh: Configuration complete; ready for start up",
+			`192.0.2.10 - - "GET / HTTP/1.1" 200 615 "-" "Mozilla/5.0"`,
+			`192.0.2.10 - - "GET /favicon.ico HTTP/1.1" 404 153 "-" "Mozilla/5.0"`,
+			`198.51.100.7 - - "GET /api/status HTTP/1.1" 200 42 "-" "curl/8.5.0"`,
+		},
+	},
+	{
+		name: "api", image: "ghcr.io/example/api:2.3.1", command: []string{"/app/server"}, state: "running", pod: "shop", network: []string{"shop"},
+		labels: map[string]string{"app": "api", "tier": "back", "com.docker.compose.project": "shop", "com.docker.compose.service": "api", "com.docker.compose.depends_on": "db:service_healthy:false"},
+		env:    []string{"DATABASE_URL=postgres://db:5432/shop", "LOG_LEVEL=info"},
+		host:   mockHostConfig{memory: 256 << 20, cpus: 0.5, readOnly: true},
+		age:    48 * time.Hour, cpu: 6, memory: 64 << 20,
+		logs: []string{
+			"level=info msg=\"Starting server\" addr=:3000",
+			"level=info msg=\"Connected to database\"",
+			"level=info msg=\"GET /orders\" status=200 duration=12ms",
+			"level=warn msg=\"Slow query\" duration=840ms",
+		},
+	},
+	{
+		name: "db", image: "docker.io/library/postgres:16", command: []string{"postgres"}, state: "running", pod: "shop", network: []string{"shop"},
+		labels: map[string]string{"app": "db", "tier": "back", "com.docker.compose.project": "shop", "com.docker.compose.service": "db"},
+		env:    []string{"POSTGRES_DB=shop", "POSTGRES_PASSWORD=********"}, mounts: [][2]string{{"pgdata", "/var/lib/postgresql/data"}},
+		host: mockHostConfig{memory: 1 << 30, cpus: 2},
+		age:  48 * time.Hour, cpu: 3, memory: 180 << 20,
+		logs: []string{
+			"LOG:  database system is ready to accept connections",
+			"LOG:  checkpoint starting: time",
+			"LOG:  checkpoint complete: wrote 42 buffers (0.3%)",
+		},
+	},
+	{
+		name: "cache", image: "docker.io/library/redis:7-alpine", command: []string{"redis-server"}, state: "running",
+		ports: [][2]int{{6379, 6379}}, labels: map[string]string{"app": "cache"},
+		age: 24 * time.Hour, cpu: 0.5, memory: 8 << 20,
+		logs: []string{
+			"* Ready to accept connections tcp",
+			"* 100 changes in 300 seconds. Saving...",
+			"* Background saving terminated with success",
+		},
+	},
+	{
+		name: "homeassistant", image: "ghcr.io/home-assistant/home-assistant:stable", command: []string{"/init"}, state: "running",
+		ports: [][2]int{{8123, 8123}}, labels: map[string]string{"app": "homeassistant"},
+		mounts: [][2]string{{"/srv/homeassistant", "/config"}},
+		host:   mockHostConfig{privileged: true, network: "host"},
+		age:    240 * time.Hour, cpu: 9, memory: 310 << 20,
+		logs: []string{
+			"INFO (MainThread) [homeassistant.bootstrap] Home Assistant initialized in 12.4s",
+			"INFO (MainThread) [homeassistant.core] Starting Home Assistant",
+		},
+	},
+	{
+		name: "backup", image: "docker.io/restic/restic:0.17.0", command: []string{"restic", "backup", "/data"}, state: "exited",
+		labels: map[string]string{"app": "backup", "schedule": "nightly"},
+		host:   mockHostConfig{capAdd: []string{"CAP_DAC_READ_SEARCH"}, memory: 512 << 20},
+		age:    6 * time.Hour,
+		logs: []string{
+			"Files:          12 new,   340 changed, 18214 unmodified",
+			"snapshot 5f3c2a1b saved",
+		},
+	},
+	{
+		name: "grafana", image: "docker.io/grafana/grafana:11.2.0", command: []string{"/run.sh"}, state: "exited",
+		ports: [][2]int{{3000, 3000}}, labels: map[string]string{"app": "grafana", "tier": "front"},
+		age: 500 * time.Hour,
+		logs: []string{
+			"logger=server level=info msg=\"Shutdown started\" reason=\"System signal: terminated\"",
+		},
+	},
+}
+
+// mockHostConfig is the security-relevant host configuration and the
+// resource limits of a container
+type mockHostConfig struct {
+	privileged bool
+	capAdd     []string
+	network    string
+	memory     int64   // limit in bytes
+	cpus       float64 // limit in CPUs
+	readOnly   bool
+}
+
+// mockPod is a pod of the mock Podman API
+type mockPod struct {
+	name string
+	age  time.Duration
+}
+
+var pods = []mockPod{{name: "shop", age: 48 * time.Hour}}
+
+// mockID returns a stable 64 hex digit ID for a name
+func mockID(kind, name string) string {
+	sum := sha256.Sum256([]byte(kind + "/" + name))
+	return hex.EncodeToString(sum[:])
+}
+
+// imageID returns the ID of an image reference
+func imageID(image string) string {
+	return mockID("image", image)
+}
+
+// wave returns a value varying smoothly over time around avg, different
+// for each seed, so stats look alive
+func wave(avg float64, seed string) float64 {
+	phase := float64(mockID("wave", seed)[0]) / 16
+	t := float64(time.Now().Unix()) / 60
+	return math.Max(0, avg*(1+0.4*math.Sin(t+phase)+0.15*math.Sin(3.7*t+phase)))
+}
+
+// Podman returns a handler serving the part of the Podman (libpod) API
+// used by PodmanView, with mock data. Changes are rejected with 403.
+func Podman() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/info", serveInfo)
+	mux.HandleFunc("GET /v4.0.0/libpod/system/df", serveDF)
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", serveContainers)
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/stats", serveStats)
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", serveInspectContainer)
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/logs", serveLogs)
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/changes", serveChanges)
+	mux.HandleFunc("GET /v4.0.0/containers/{id}/archive", serveArchiveStat)
+	mux.HandleFunc("GET /v4.0.0/libpod/images/json", serveImages)
+	mux.HandleFunc("GET /v4.0.0/libpod/images/{id}/json", serveInspectImage)
+	mux.HandleFunc("GET /v4.0.0/libpod/pods/json", servePods)
+	mux.HandleFunc("GET /v4.0.0/libpod/volumes/json", serveVolumes)
+	mux.HandleFunc("GET /v4.0.0/libpod/networks/json", serveNetworks)
+	mux.HandleFunc("GET /v4.0.0/libpod/events", serveEvents)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusForbidden, map[string]string{"cause": "demo mode", "message": "Changes are disabled in demo mode"})
+			return
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"cause": "no such object", "message": "Not found"})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// findContainer looks up a container by name, ID or ID prefix
+func findContainer(id string) (mockContainer, bool) {
+	for _, c := range containers {
+		if cid := mockID("container", c.name); c.name == id || (len(id) >= 3 && strings.HasPrefix(cid, id)) {
+			return c, true
+		}
+	}
+	return mockContainer{}, false
+}
+
+func serveInfo(w http.ResponseWriter, r *http.Request) {
+	info := map[string]interface{}{
+		"host":    map[string]string{"arch": "arm64", "hostname": Hostname, "kernel": "6.6.31-demo"},
+		"store":   map[string]string{"graphRoot": "/srv/containers/storage"},
+		"version": map[string]string{"Version": "5.2.2"},
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func serveDF(w http.ResponseWriter, r *http.Request) {
+	type containerDF struct {
+		ContainerID string
+		Names       string
+		Status      string
+		Size        int64
+		RWSize      int64
+	}
+	type imageDF struct {
+		ImageID string
+		Size    int64
+	}
+	type volumeDF struct {
+		VolumeName string
+		Size       int64
+	}
+	df := struct {
+		Containers []containerDF
+		Images     []imageDF
+		Volumes    []volumeDF
+	}{}
+	for i, c := range containers {
+		df.Containers = append(df.Containers, containerDF{ContainerID: mockID("container", c.name), Names: c.name, Status: c.state, Size: int64(i+1) << 20, RWSize: int64(i+1) << 18})
+	}
+	for _, img := range images() {
+		df.Images = append(df.Images, imageDF{ImageID: img.id, Size: img.size})
+	}
+	df.Volumes = []volumeDF{{VolumeName: "pgdata", Size: 420 << 20}}
+	writeJSON(w, http.StatusOK, df)
+}
+
+func serveContainers(w http.ResponseWriter, r *http.Request) {
+	list := make([]map[string]interface{}, 0, len(containers))
+	for _, c := range containers {
+		var ports []map[string]interface{}
+		for _, p := range c.ports {
+			ports = append(ports, map[string]interface{}{"IP": "", "PublicPort": p[0], "PrivatePort": p[1], "Type": "tcp"})
+		}
+		entry := map[string]interface{}{
+			"Id":       mockID("container", c.name),
+			"Names":    []string{c.name},
+			"Image":    c.image,
+			"ImageID":  imageID(c.image),
+			"Command":  c.command,
+			"State":    c.state,
+			"Status":   containerStatus(c),
+			"Ports":    ports,
+			"Labels":   c.labels,
+			"Created":  started.Add(-c.age).UTC().Format(time.RFC3339),
+			"Networks": containerNetworks(c),
+		}
+		if c.pod != "" {
+			entry["Pod"] = mockID("pod", c.pod)
+			entry["PodName"] = c.pod
+		}
+		list = append(list, entry)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// containerNetworks returns the networks of a container, the default
+// podman network if none are set
+func containerNetworks(c mockContainer) []string {
+	if len(c.network) == 0 {
+		return []string{"podman"}
+	}
+	return c.network
+}
+
+// containerStatus returns the human-readable status, e.g. "Up 3 days"
+func containerStatus(c mockContainer) string {
+	if c.state == "running" {
+		return "Up " + humanDuration(c.age)
+	}
+	return "Exited (0) " + humanDuration(c.age/4) + " ago"
+}
+
+func humanDuration(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%d hours", int(d.Hours()))
+}
+
+func serveStats(w http.ResponseWriter, r *http.Request) {
+	type stats struct {
+		ContainerID string
+		Name        string
+		CPU         float64
+		MemUsage    uint64
+		MemLimit    uint64
+		MemPerc     float64
+		NetInput    uint64
+		NetOutput   uint64
+		BlockInput  uint64
+		BlockOutput uint64
+		PIDs        uint64
+	}
+	const memLimit = 4 << 30
+	var result struct{ Stats []stats }
+	for _, c := range containers {
+		if c.state != "running" {
+			continue
+		}
+		mem := uint64(wave(float64(c.memory), c.name+"mem")*0.1 + float64(c.memory)*0.9)
+		uptime := uint64(time.Since(started.Add(-c.age)).Seconds())
+		result.Stats = append(result.Stats, stats{
+			ContainerID: mockID("container", c.name),
+			Name:        c.name,
+			CPU:         math.Round(wave(c.cpu, c.name)*100) / 100,
+			MemUsage:    mem,
+			MemLimit:    memLimit,
+			MemPerc:     math.Round(float64(mem)/memLimit*10000) / 100,
+			NetInput:    uptime * 1200,
+			NetOutput:   uptime * 800,
+			BlockInput:  c.memory,
+			BlockOutput: c.memory / 4,
+			PIDs:        uint64(len(c.name) + 2),
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func serveInspectContainer(w http.ResponseWriter, r *http.Request) {
+	c, ok := findContainer(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"cause": "no such container", "message": "no container with name or ID " + r.PathValue("id") + " found"})
+		return
+	}
+	created := started.Add(-c.age).UTC()
+	state := map[string]interface{}{
+		"Status":    c.state,
+		"Running":   c.state == "running",
+		"Paused":    false,
+		"StartedAt": created.Format(time.RFC3339Nano),
+	}
+	if c.state != "running" {
+		state["FinishedAt"] = created.Add(c.age * 3 / 4).Format(time.RFC3339Nano)
+	}
+	var mounts []map[string]string
+	for _, m := range c.mounts {
+		mount := map[string]string{"Type": "bind", "Source": m[0], "Destination": m[1]}
+		if !strings.HasPrefix(m[0], "/") {
+			mount["Type"] = "volume"
+			mount["Name"] = m[0]
+			mount["Source"] = "/var/lib/containers/storage/volumes/" + m[0] + "/_data"
+		}
+		mounts = append(mounts, mount)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Id":        mockID("container", c.name),
+		"Name":      c.name,
+		"Created":   created.Format(time.RFC3339Nano),
+		"State":     state,
+		"Image":     imageID(c.image),
+		"ImageName": c.image,
+		"Config": map[string]interface{}{
+			"Hostname": mockID("container", c.name)[:12],
+			"Env":      append([]string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"}, c.env...),
+			"Cmd":      c.command,
+			"Labels":   c.labels,
+		},
+		"HostConfig": map[string]interface{}{
+			"Privileged":     c.host.privileged,
+			"CapAdd":         c.host.capAdd,
+			"NetworkMode":    cmp.Or(c.host.network, "bridge"),
+			"Memory":         c.host.memory,
+			"NanoCpus":       int64(c.host.cpus * 1e9),
+			"ReadonlyRootfs": c.host.readOnly,
+		},
+		"Mounts": mounts,
+	})
+}
+
+func serveLogs(w http.ResponseWriter, r *http.Request) {
+	c, ok := findContainer(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"cause": "no such container", "message": "no container with name or ID " + r.PathValue("id") + " found"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	timestamps := r.URL.Query().Get("timestamps") == "true"
+	for i, line := range c.logs {
+		if timestamps {
+			// One line a minute, the last one when the demo started
+			line = started.Add(time.Duration(i-len(c.logs)+1)*time.Minute).UTC().Format(time.RFC3339Nano) + " " + line
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// mockChanges are the filesystem changes of every mock container; sizes
+// are derived from the path
+var mockChanges = []struct {
+	path string
+	kind int
+	dir  bool
+}{
+	{"/etc", 0, true},
+	{"/etc/motd", 2, false},
+	{"/tmp", 0, true},
+	{"/tmp/cache.db", 1, false},
+	{"/var", 0, true},
+	{"/var/log", 0, true},
+	{"/var/log/app.log", 1, false},
+}
+
+func serveChanges(w http.ResponseWriter, r *http.Request) {
+	if _, ok := findContainer(r.PathValue("id")); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"cause": "no such container", "message": "no container with name or ID " + r.PathValue("id") + " found"})
+		return
+	}
+	list := make([]map[string]interface{}, 0, len(mockChanges))
+	for _, c := range mockChanges {
+		list = append(list, map[string]interface{}{"Path": c.path, "Kind": c.kind})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// serveArchiveStat answers HEAD requests for a path with its stat header
+func serveArchiveStat(w http.ResponseWriter, r *http.Request) {
+	c, ok := findContainer(r.PathValue("id"))
+	path := r.URL.Query().Get("path")
+	for _, change := range mockChanges {
+		if !ok || change.path != path || change.kind == 2 {
+			continue
+		}
+		stat := map[string]interface{}{"name": path[strings.LastIndex(path, "/")+1:], "size": 4096, "mode": 0644}
+		if change.dir {
+			stat["mode"] = uint32(os.ModeDir | 0755)
+		} else {
+			sum := sha256.Sum256([]byte(c.name + path))
+			stat["size"] = int(sum[0])<<12 | int(sum[1])<<4
+		}
+		data, _ := json.Marshal(stat)
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(data))
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// mockImage is an image of the mock Podman API
+type mockImage struct {
+	id     string
+	ref    string
+	size   int64
+	age    time.Duration
+	labels map[string]string
+}
+
+// images returns the images of the containers and an unused one
+func images() []mockImage {
+	seen := make(map[string]bool)
+	var result []mockImage
+	add := func(ref string, age time.Duration) {
+		if seen[ref] {
+			return
+		}
+		seen[ref] = true
+		result = append(result, mockImage{
+			id:     imageID(ref),
+			ref:    ref,
+			size:   int64(20+len(ref)*9) << 20,
+			age:    age,
+			labels: map[string]string{"org.opencontainers.image.source": "https://" + strings.SplitN(ref, ":", 2)[0]},
+		})
+	}
+	for _, c := range containers {
+		add(c.image, c.age+240*time.Hour)
+	}
+	add("docker.io/library/alpine:3.20", 900*time.Hour)
+	return result
+}
+
+func serveImages(w http.ResponseWriter, r *http.Request) {
+	var list []map[string]interface{}
+	for _, img := range images() {
+		list = append(list, map[string]interface{}{
+			"Id":          img.id,
+			"RepoTags":    []string{img.ref},
+			"RepoDigests": []string{strings.SplitN(img.ref, ":", 2)[0] + "@sha256:" + mockID("digest", img.ref)},
+			"Created":     started.Add(-img.age).Unix(),
+			"Size":        img.size,
+			"VirtualSize": img.size,
+			"Labels":      img.labels,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func serveInspectImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	for _, img := range images() {
+		if img.ref == id || (len(id) >= 3 && strings.HasPrefix(img.id, strings.TrimPrefix(id, "sha256:"))) {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"Id":           img.id,
+				"RepoTags":     []string{img.ref},
+				"RepoDigests":  []string{strings.SplitN(img.ref, ":", 2)[0] + "@sha256:" + mockID("digest", img.ref)},
+				"Created":      started.Add(-img.age).UTC().Format(time.RFC3339Nano),
+				"Size":         img.size,
+				"Architecture": "arm64",
+				"Os":           "linux",
+				"Config": map[string]interface{}{
+					"Env":    []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"},
+					"Labels": img.labels,
+				},
+			})
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"cause": "image not known", "message": id + ": image not known"})
+}
+
+func servePods(w http.ResponseWriter, r *http.Request) {
+	var list []map[string]interface{}
+	for _, p := range pods {
+		var members []map[string]string
+		status := "Exited"
+		for _, c := range containers {
+			if c.pod != p.name {
+				continue
+			}
+			members = append(members, map[string]string{"Id": mockID("container", c.name), "Names": c.name, "Status": c.state})
+			if c.state == "running" {
+				status = "Running"
+			}
+		}
+		list = append(list, map[string]interface{}{
+			"Id":         mockID("pod", p.name),
+			"Name":       p.name,
+			"Status":     status,
+			"Created":    started.Add(-p.age).UTC().Format(time.RFC3339),
+			"InfraId":    mockID("infra", p.name),
+			"Labels":     map[string]string{},
+			"Containers": members,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func serveVolumes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, []map[string]interface{}{
+		{"Name": "pgdata", "Driver": "local", "Mountpoint": "/var/lib/containers/storage/volumes/pgdata/_data", "CreatedAt": started.Add(-48 * time.Hour).UTC().Format(time.RFC3339), "Labels": map[string]string{}},
+	})
+}
+
+func serveNetworks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, []map[string]interface{}{
+		{"name": "podman", "id": mockID("network", "podman"), "driver": "bridge", "subnets": []map[string]string{{"subnet": "10.88.0.0/16", "gateway": "10.88.0.1"}}},
+		{"name": "shop", "id": mockID("network", "shop"), "driver": "bridge", "subnets": []map[string]string{{"subnet": "10.89.0.0/24", "gateway": "10.89.0.1"}}},
+	})
+}
+
+// eventInterval is how often the mock event stream reports a health check
+const eventInterval = time.Minute
+
+// serveEvents streams a container health_status event per interval until the client disconnects
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	ticker := time.NewTicker(eventInterval)
+	defer ticker.Stop()
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case now := <-ticker.C:
+			event := map[string]interface{}{
+				"Type":   "container",
+				"Action": "health_status",
+				"Actor": map[string]interface{}{
+					"ID":         mockID("container", "api"),
+					"Attributes": map[string]string{"name": "api", "image": "ghcr.io/example/api:2.3.1", "health_status": "healthy"},
+				},
+				"time":     now.Unix(),
+				"timeNano": now.UnixNano(),
+			}
+			if err := enc.Encode(event); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// SeedEvents adds sample security and container events to the event log
+func SeedEvents(store *events.Store) {
+	store.Add(events.EventLogin, "demo", "192.0.2.10", true, "")
+	store.Add(events.EventContainerStart, "demo", "192.0.2.10", true, "web")
+	store.Add(events.EventImagePull, "demo", "192.0.2.10", true, "docker.io/library/redis:7-alpine")
+	store.Add(events.EventContainerStop, "demo", "192.0.2.10", true, "grafana")
+	store.Add(events.EventLoginFailed, "admin", "203.0.113.5", false, "invalid credentials")
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Archiver stores events removed from a Store by retention
+type Archiver interface {
+	Archive(events []Event) error
+}
+
+// FileArchiver appends events as gzip-compressed JSON lines to one file
+// per month (events-2006-01.jsonl.gz). Each call adds a gzip member to the
+// file, so the files can be read with zcat or any multistream gzip reader.
+type FileArchiver struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileArchiver creates an archiver writing to dir, creating it if needed
+func NewFileArchiver(dir string) (*FileArchiver, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create event archive directory: %w", err)
+	}
+	return &FileArchiver{dir: dir}, nil
+}
+
+// Archive appends events to the archive files of their month
+func (a *FileArchiver) Archive(events []Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	byFile := make(map[string][]Event)
+	var order []string
+	for _, e := range events {
+		name := fmt.Sprintf("events-%s.jsonl.gz", e.Timestamp.UTC().Format("2006-01"))
+		if _, ok := byFile[name]; !ok {
+			order = append(order, name)
+		}
+		byFile[name] = append(byFile[name], e)
+	}
+
+	for _, name := range order {
+		if err := a.appendFile(filepath.Join(a.dir, name), byFile[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendFile writes events as a new gzip member at the end of path
+func (a *FileArchiver) appendFile(path string, events []Event) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open event archive: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to write event archive: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write event archive: %w", err)
+	}
+	return f.Sync()
+}
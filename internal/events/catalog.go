@@ -0,0 +1,174 @@
+package events
+
+import "sort"
+
+// Severity is how important an event is
+type Severity string
+
+// Severities, from least to most important
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityError    Severity = "error"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities lists all severities, from least to most important
+var Severities = []Severity{SeverityInfo, SeverityWarning, SeverityError, SeverityCritical}
+
+// Level returns the rank of the severity (0 = info), -1 if it is unknown
+func (s Severity) Level() int {
+	for i, known := range Severities {
+		if s == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// Category groups related event types
+type Category string
+
+// Categories
+const (
+	CategoryAuth        Category = "auth"
+	CategoryTerminal    Category = "terminal"
+	CategoryContainer   Category = "container"
+	CategoryImage       Category = "image"
+	CategorySystem      Category = "system"
+	CategoryConfig      Category = "config"
+	CategoryStorage     Category = "storage"
+	CategoryIntegration Category = "integration"
+	CategoryAlert       Category = "alert"
+	CategoryFile        Category = "file"
+	CategoryOther       Category = "other"
+)
+
+// Categories lists all categories
+var Categories = []Category{
+	CategoryAuth, CategoryTerminal, CategoryContainer, CategoryImage, CategorySystem,
+	CategoryConfig, CategoryStorage, CategoryIntegration, CategoryAlert, CategoryFile,
+	CategoryOther,
+}
+
+// TypeInfo describes an event type
+type TypeInfo struct {
+	Type     EventType `json:"type"`
+	Label    string    `json:"label"`
+	Category Category  `json:"category"`
+	Severity Severity  `json:"severity"` // failed events of info types are SeverityError
+}
+
+// catalog lists all known event types
+var catalog = map[EventType]TypeInfo{
+	EventLogin:       {Label: "Login", Category: CategoryAuth, Severity: SeverityInfo},
+	EventLoginFailed: {Label: "Login Failed", Category: CategoryAuth, Severity: SeverityWarning},
+	EventLogout:      {Label: "Logout", Category: CategoryAuth, Severity: SeverityInfo},
+	EventKeyRotate:   {Label: "JWT Key Rotation", Category: CategoryAuth, Severity: SeverityWarning},
+
+	EventTerminalHost:      {Label: "Host Terminal", Category: CategoryTerminal, Severity: SeverityWarning},
+	EventTerminalContainer: {Label: "Container Terminal", Category: CategoryTerminal, Severity: SeverityInfo},
+	EventTerminalDenied:    {Label: "Terminal Command Denied", Category: CategoryTerminal, Severity: SeverityWarning},
+	EventTerminalTransfer:  {Label: "Terminal File Transfer", Category: CategoryTerminal, Severity: SeverityInfo},
+	EventTerminalAttach:    {Label: "Container Attach", Category: CategoryTerminal, Severity: SeverityInfo},
+
+	EventContainerStart:   {Label: "Container Start", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerStop:    {Label: "Container Stop", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerRestart: {Label: "Container Restart", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerRemove:  {Label: "Container Remove", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerCreate:  {Label: "Container Create", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerRestore: {Label: "Container Restore", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerEnv:     {Label: "Container Env Update", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerDied:    {Label: "Container Died", Category: CategoryContainer, Severity: SeverityError},
+	EventContainerCrash:   {Label: "Container Crash Loop", Category: CategoryContainer, Severity: SeverityCritical},
+	EventContainerUpdate:  {Label: "Container Auto-Update", Category: CategoryContainer, Severity: SeverityInfo},
+	EventContainerTags:    {Label: "Container Tags Update", Category: CategoryContainer, Severity: SeverityInfo},
+	EventNoteUpdate:       {Label: "Note Update", Category: CategoryContainer, Severity: SeverityInfo},
+	EventStackEnv:         {Label: "Stack Env Update", Category: CategoryContainer, Severity: SeverityInfo},
+
+	EventImagePull:     {Label: "Image Pull", Category: CategoryImage, Severity: SeverityInfo},
+	EventImageRemove:   {Label: "Image Remove", Category: CategoryImage, Severity: SeverityInfo},
+	EventImageExport:   {Label: "Image Export", Category: CategoryImage, Severity: SeverityInfo},
+	EventImageImport:   {Label: "Image Import", Category: CategoryImage, Severity: SeverityInfo},
+	EventImageBuild:    {Label: "Image Build", Category: CategoryImage, Severity: SeverityInfo},
+	EventBuildUpdate:   {Label: "Saved Build Update", Category: CategoryImage, Severity: SeverityInfo},
+	EventVolumeRemove:  {Label: "Volume Remove", Category: CategoryImage, Severity: SeverityWarning},
+	EventVolumeRestore: {Label: "Volume Restore", Category: CategoryImage, Severity: SeverityWarning},
+	EventKubePlay:      {Label: "Kube Play", Category: CategoryContainer, Severity: SeverityInfo},
+
+	EventSystemReboot:   {Label: "System Reboot", Category: CategorySystem, Severity: SeverityWarning},
+	EventSystemShutdown: {Label: "System Shutdown", Category: CategorySystem, Severity: SeverityWarning},
+	EventSystemUpdate:   {Label: "System Update", Category: CategorySystem, Severity: SeverityInfo},
+	EventSystemPrune:    {Label: "System Prune", Category: CategorySystem, Severity: SeverityWarning},
+	EventDiagnostics:    {Label: "Diagnostics Bundle", Category: CategorySystem, Severity: SeverityInfo},
+	EventMaintenance:    {Label: "Maintenance Mode", Category: CategorySystem, Severity: SeverityWarning},
+	EventDiskFull:       {Label: "Disk Full", Category: CategorySystem, Severity: SeverityCritical},
+	EventRaidDegraded:   {Label: "RAID Degraded", Category: CategorySystem, Severity: SeverityCritical},
+	EventTempThreshold:  {Label: "Temperature Threshold", Category: CategorySystem, Severity: SeverityWarning},
+	EventMonitorDown:    {Label: "Monitor Down", Category: CategorySystem, Severity: SeverityCritical},
+	EventMonitorUp:      {Label: "Monitor Up", Category: CategorySystem, Severity: SeverityInfo},
+
+	EventSettingsUpdate: {Label: "Settings Update", Category: CategoryConfig, Severity: SeverityInfo},
+	EventConfigRestore:  {Label: "Config Restore", Category: CategoryConfig, Severity: SeverityWarning},
+	EventScheduleUpdate: {Label: "Schedule Update", Category: CategoryConfig, Severity: SeverityInfo},
+	EventShareUpdate:    {Label: "Share Link Update", Category: CategoryConfig, Severity: SeverityInfo},
+
+	EventStorageBackup:  {Label: "Storage Backup", Category: CategoryStorage, Severity: SeverityInfo},
+	EventStorageRestore: {Label: "Storage Restore", Category: CategoryStorage, Severity: SeverityWarning},
+	EventStorageMaint:   {Label: "Storage Maintenance", Category: CategoryStorage, Severity: SeverityInfo},
+
+	EventWebhookUpdate: {Label: "Webhook Update", Category: CategoryIntegration, Severity: SeverityInfo},
+	EventNotifyUpdate:  {Label: "Notification Update", Category: CategoryIntegration, Severity: SeverityInfo},
+	EventRegistryAuth:  {Label: "Registry Credential Update", Category: CategoryIntegration, Severity: SeverityInfo},
+	EventRegistryToken: {Label: "Registry Token Expiring", Category: CategoryIntegration, Severity: SeverityWarning},
+
+	EventAlertFired:    {Label: "Alert Fired", Category: CategoryAlert, Severity: SeverityWarning},
+	EventAlertResolved: {Label: "Alert Resolved", Category: CategoryAlert, Severity: SeverityInfo},
+	EventAlertUpdate:   {Label: "Alert Rule Update", Category: CategoryAlert, Severity: SeverityInfo},
+
+	EventFileBrowse:   {Label: "File Browse", Category: CategoryFile, Severity: SeverityInfo},
+	EventFileDownload: {Label: "File Download", Category: CategoryFile, Severity: SeverityInfo},
+	EventFileUpload:   {Label: "File Upload", Category: CategoryFile, Severity: SeverityInfo},
+	EventFileDelete:   {Label: "File Delete", Category: CategoryFile, Severity: SeverityInfo},
+	EventFileMkdir:    {Label: "Create Folder", Category: CategoryFile, Severity: SeverityInfo},
+	EventFileRename:   {Label: "File Rename", Category: CategoryFile, Severity: SeverityInfo},
+	EventFileRead:     {Label: "File Read", Category: CategoryFile, Severity: SeverityInfo},
+	EventFileWrite:    {Label: "File Write", Category: CategoryFile, Severity: SeverityInfo},
+}
+
+// Types returns all known event types sorted by category and type
+func Types() []TypeInfo {
+	list := make([]TypeInfo, 0, len(catalog))
+	for t, info := range catalog {
+		info.Type = t
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Category != list[j].Category {
+			return list[i].Category < list[j].Category
+		}
+		return list[i].Type < list[j].Type
+	})
+	return list
+}
+
+// Lookup returns the catalog entry of an event type. Unknown types
+// are uncategorized info events labeled with the type.
+func Lookup(t EventType) (TypeInfo, bool) {
+	info, ok := catalog[t]
+	if !ok {
+		info = TypeInfo{Label: string(t), Category: CategoryOther, Severity: SeverityInfo}
+	}
+	info.Type = t
+	return info, ok
+}
+
+// classify returns the category and severity of an event. Failed events
+// of info types (e.g. a container that didn't start) are errors.
+func classify(t EventType, success bool) (Category, Severity) {
+	info, _ := Lookup(t)
+	if !success && info.Severity.Level() < SeverityWarning.Level() {
+		return info.Category, SeverityError
+	}
+	return info.Category, info.Severity
+}
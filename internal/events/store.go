@@ -1,6 +1,8 @@
 package events
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,10 +15,14 @@ const (
 	EventLogin       EventType = "login"
 	EventLoginFailed EventType = "login_failed"
 	EventLogout      EventType = "logout"
+	EventKeyRotate   EventType = "jwt_key_rotate"
 
 	// Terminal events
 	EventTerminalHost      EventType = "terminal_host"
 	EventTerminalContainer EventType = "terminal_container"
+	EventTerminalDenied    EventType = "terminal_command_denied"
+	EventTerminalTransfer  EventType = "terminal_file_transfer"
+	EventTerminalAttach    EventType = "terminal_attach"
 
 	// Container events
 	EventContainerStart   EventType = "container_start"
@@ -24,15 +30,52 @@ const (
 	EventContainerRestart EventType = "container_restart"
 	EventContainerRemove  EventType = "container_remove"
 	EventContainerCreate  EventType = "container_create"
+	EventContainerRestore EventType = "container_restore"
+	EventContainerEnv     EventType = "container_env_update"
+	EventContainerDied    EventType = "container_died"
+	EventContainerCrash   EventType = "container_crash_loop"
+	EventContainerUpdate  EventType = "container_auto_update"
+	EventContainerTags    EventType = "container_tags_update"
+	EventNoteUpdate       EventType = "note_update"
+	EventStackEnv         EventType = "stack_env_update"
 
 	// Image events
-	EventImagePull   EventType = "image_pull"
-	EventImageRemove EventType = "image_remove"
+	EventImagePull     EventType = "image_pull"
+	EventImageRemove   EventType = "image_remove"
+	EventImageExport   EventType = "image_export"
+	EventImageImport   EventType = "image_import"
+	EventImageBuild    EventType = "image_build"
+	EventBuildUpdate   EventType = "build_update"
+	EventVolumeRemove  EventType = "volume_remove"
+	EventVolumeRestore EventType = "volume_restore"
+	EventKubePlay      EventType = "kube_play"
 
 	// System events
 	EventSystemReboot   EventType = "system_reboot"
 	EventSystemShutdown EventType = "system_shutdown"
 	EventSystemUpdate   EventType = "system_update"
+	EventSystemPrune    EventType = "system_prune"
+	EventDiagnostics    EventType = "system_diagnostics"
+	EventScheduleUpdate EventType = "schedule_update"
+	EventMaintenance    EventType = "maintenance_mode"
+	EventSettingsUpdate EventType = "settings_update"
+	EventShareUpdate    EventType = "share_link_update"
+	EventConfigRestore  EventType = "config_restore"
+	EventStorageBackup  EventType = "storage_backup"
+	EventStorageRestore EventType = "storage_restore"
+	EventStorageMaint   EventType = "storage_maintenance"
+	EventWebhookUpdate  EventType = "webhook_update"
+	EventNotifyUpdate   EventType = "notification_update"
+	EventRegistryAuth   EventType = "registry_credential_update"
+	EventRegistryToken  EventType = "registry_token_expiring"
+	EventDiskFull       EventType = "disk_full"
+	EventRaidDegraded   EventType = "raid_degraded"
+	EventTempThreshold  EventType = "temperature_threshold"
+	EventMonitorDown    EventType = "monitor_down"
+	EventMonitorUp      EventType = "monitor_up"
+	EventAlertFired     EventType = "alert_fired"
+	EventAlertResolved  EventType = "alert_resolved"
+	EventAlertUpdate    EventType = "alert_rule_update"
 
 	// File manager events
 	EventFileBrowse   EventType = "file_browse"
@@ -54,14 +97,27 @@ type Event struct {
 	IP        string    `json:"ip"`
 	Success   bool      `json:"success"`
 	Details   string    `json:"details,omitempty"`
+	Category  Category  `json:"category"`
+	Severity  Severity  `json:"severity"`
 }
 
+// maxPendingArchive limits the removed events kept for archiving
+// while the archiver fails (oldest are dropped first)
+const maxPendingArchive = 10000
+
 // Store holds events in memory with a fixed capacity (ring buffer)
 type Store struct {
-	mu      sync.RWMutex
-	events  []Event
-	maxSize int
-	nextID  int64
+	mu       sync.RWMutex
+	events   []Event
+	maxSize  int
+	nextID   int64
+	modified time.Time // last time events were added or removed
+
+	maxAge   time.Duration // events older than this are removed by Prune, 0 = no limit
+	archiver Archiver      // receives removed events, nil = discard
+	pending  []Event       // removed events not archived yet
+
+	subscribers map[chan Event]struct{}
 }
 
 // NewStore creates a new event store with specified max capacity
@@ -72,8 +128,22 @@ func NewStore(maxSize int) *Store {
 	}
 }
 
-// Add adds a new event to the store
+// Add adds a new event to the store, with the category and severity
+// from the event type catalog
 func (s *Store) Add(eventType EventType, username, ip string, success bool, details string) {
+	category, severity := classify(eventType, success)
+	s.add(eventType, category, severity, username, ip, success, details)
+}
+
+// AddWithSeverity adds a new event with a severity that depends on the
+// event rather than its type, e.g. an alert with the severity of its rule
+func (s *Store) AddWithSeverity(eventType EventType, severity Severity, username, ip string, success bool, details string) {
+	category, _ := classify(eventType, success)
+	s.add(eventType, category, severity, username, ip, success, details)
+}
+
+// add adds a classified event to the store
+func (s *Store) add(eventType EventType, category Category, severity Severity, username, ip string, success bool, details string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -86,13 +156,131 @@ func (s *Store) Add(eventType EventType, username, ip string, success bool, deta
 		IP:        ip,
 		Success:   success,
 		Details:   details,
+		Category:  category,
+		Severity:  severity,
 	}
 
 	// Ring buffer: remove oldest if at max capacity
 	if len(s.events) >= s.maxSize {
-		s.events = s.events[1:]
+		s.remove(1)
 	}
 	s.events = append(s.events, event)
+	s.modified = event.Timestamp
+
+	// Notify subscribers without blocking, slow ones miss the event
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel that receives events as they are added and
+// a function to unsubscribe, which closes the channel. Events are dropped
+// for a subscriber whose buffer is full; it can catch up with GetSince.
+func (s *Store) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	s.mu.Lock()
+	if s.subscribers == nil {
+		s.subscribers = make(map[chan Event]struct{})
+	}
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subscribers, ch)
+			s.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// remove removes the n oldest events, keeping them for the archiver.
+// Must be called with the lock held.
+func (s *Store) remove(n int) {
+	if s.archiver != nil {
+		s.pending = append(s.pending, s.events[:n]...)
+	}
+	s.events = s.events[n:]
+	s.modified = time.Now()
+}
+
+// SetRetention changes the maximum number of events kept in memory and
+// the maximum age of events (0 = no limit). Excess events are removed
+// immediately, expired ones on the next Prune.
+func (s *Store) SetRetention(maxSize int, maxAge time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	s.maxSize = maxSize
+	s.maxAge = maxAge
+	if over := len(s.events) - maxSize; over > 0 {
+		s.remove(over)
+	}
+}
+
+// SetArchiver sets where events removed by retention are archived.
+// Without an archiver removed events are discarded.
+func (s *Store) SetArchiver(a Archiver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archiver = a
+}
+
+// Prune removes events older than the maximum age and archives all events
+// removed since the last call. Returns the number of expired events.
+// Events that fail to archive are retried on the next call.
+func (s *Store) Prune() (int, error) {
+	s.mu.Lock()
+	expired := 0
+	if s.maxAge > 0 {
+		cutoff := time.Now().Add(-s.maxAge)
+		for expired < len(s.events) && s.events[expired].Timestamp.Before(cutoff) {
+			expired++
+		}
+		if expired > 0 {
+			s.remove(expired)
+		}
+	}
+	archiver, batch := s.archiver, s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if archiver == nil || len(batch) == 0 {
+		return expired, nil
+	}
+
+	if err := archiver.Archive(batch); err != nil {
+		s.mu.Lock()
+		s.pending = append(batch, s.pending...)
+		if over := len(s.pending) - maxPendingArchive; over > 0 {
+			s.pending = s.pending[over:]
+		}
+		s.mu.Unlock()
+		return expired, err
+	}
+	return expired, nil
+}
+
+// Close archives all events still in memory, as events are not persisted
+// otherwise. Does nothing without an archiver.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.archiver != nil {
+		s.remove(len(s.events))
+	}
+	s.mu.Unlock()
+
+	_, err := s.Prune()
+	return err
 }
 
 // GetLast returns the last N events (newest first)
@@ -127,6 +315,116 @@ func (s *Store) GetSince(lastID int64) []Event {
 	return result
 }
 
+// Query selects events for Store.Query. Zero values match everything.
+type Query struct {
+	TypePrefix string    // e.g. "container_" for all container events
+	Username   string    // exact match
+	Success    *bool     // nil = both
+	Category   Category  // exact match
+	Severity   Severity  // events at least as severe
+	From       time.Time // events at or after
+	To         time.Time // events before
+	Search     string    // words that must all appear in details, case-insensitive
+	Before     int64     // cursor: only events with a lower ID, 0 = start at the newest
+	Limit      int
+}
+
+// matches reports whether an event matches the query filters
+func (q *Query) matches(e *Event, terms []string) bool {
+	if q.TypePrefix != "" && !strings.HasPrefix(string(e.Type), q.TypePrefix) {
+		return false
+	}
+	if q.Username != "" && e.Username != q.Username {
+		return false
+	}
+	if q.Success != nil && e.Success != *q.Success {
+		return false
+	}
+	if q.Category != "" && e.Category != q.Category {
+		return false
+	}
+	if q.Severity != "" && e.Severity.Level() < q.Severity.Level() {
+		return false
+	}
+	if !q.From.IsZero() && e.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !e.Timestamp.Before(q.To) {
+		return false
+	}
+	details := strings.ToLower(e.Details)
+	for _, term := range terms {
+		if !strings.Contains(details, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether an event matches the query filters.
+// Before and Limit are ignored.
+func (q *Query) Match(e Event) bool {
+	return q.matches(&e, strings.Fields(strings.ToLower(q.Search)))
+}
+
+// Query returns up to q.Limit matching events (newest first) and the cursor
+// to pass as q.Before for the next page, 0 if there are no more matches
+func (s *Store) Query(q Query) ([]Event, int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := strings.Fields(strings.ToLower(q.Search))
+	result := []Event{}
+	for i := len(s.events) - 1; i >= 0; i-- {
+		e := &s.events[i]
+		if q.Before > 0 && e.ID >= q.Before {
+			continue
+		}
+		if !q.matches(e, terms) {
+			continue
+		}
+		if len(result) == q.Limit {
+			// There is at least one more match
+			return result, result[len(result)-1].ID
+		}
+		result = append(result, *e)
+	}
+	return result, 0
+}
+
+// Restore replaces stored events, e.g. with events from a backup.
+// IDs keep increasing, so clients polling with GetSince only see newer events.
+func (s *Store) Restore(events []Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	if len(events) > s.maxSize {
+		events = events[len(events)-s.maxSize:]
+	}
+
+	// Events recorded before the type catalog have no category and severity
+	for i := range events {
+		if events[i].Severity == "" {
+			events[i].Category, events[i].Severity = classify(events[i].Type, events[i].Success)
+		}
+	}
+
+	s.events = append(make([]Event, 0, s.maxSize), events...)
+	s.modified = time.Now()
+	if n := len(events); n > 0 && events[n-1].ID > s.nextID {
+		s.nextID = events[n-1].ID
+	}
+}
+
+// Modified returns the last time events were added or removed,
+// zero if the store has not changed since it was created
+func (s *Store) Modified() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.modified
+}
+
 // LastID returns the ID of the most recent event
 func (s *Store) LastID() int64 {
 	s.mu.RLock()
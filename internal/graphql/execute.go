@@ -0,0 +1,730 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is omitted when the request
+// failed before execution (syntax or validation errors).
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error with the source locations and the result path
+// of the field it belongs to
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Location is a line and column of the request document (1-based)
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Execute runs a query operation. Subscription operations are rejected,
+// they are run with Subscribe.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	ex, op, resp := s.prepare(req)
+	if resp != nil {
+		return resp
+	}
+	if op.kind == "subscription" {
+		return errorResponse(ex.errorAt(op.pos, "Subscriptions must be requested as an event stream (Accept: text/event-stream)."))
+	}
+	data, _ := ex.executeSelections(ctx, s.Query, nil, op.selections, nil)
+	return &Response{Data: data, Errors: ex.errors}
+}
+
+// Subscribe runs a subscription operation and returns a channel with one
+// result per event, which is closed when ctx is done or the event source
+// ends. Query operations give a single result. Requests that cannot be
+// executed return the error response instead.
+func (s *Schema) Subscribe(ctx context.Context, req Request) (<-chan *Response, *Response) {
+	ex, op, resp := s.prepare(req)
+	if resp != nil {
+		return nil, resp
+	}
+
+	results := make(chan *Response, 1)
+	if op.kind != "subscription" {
+		data, _ := ex.executeSelections(ctx, s.Query, nil, op.selections, nil)
+		results <- &Response{Data: data, Errors: ex.errors}
+		close(results)
+		return results, nil
+	}
+
+	fields := ex.collectFields(s.Subscription, op.selections)
+	if len(fields) != 1 {
+		return nil, errorResponse(ex.errorAt(op.pos, "Subscription must select exactly one top level field."))
+	}
+	key, sels := fields[0].key, fields[0].fields
+	rootField := s.Subscription.field(sels[0].name)
+	if rootField == nil || rootField.Subscribe == nil {
+		return nil, errorResponse(ex.errorAt(sels[0].pos, fmt.Sprintf("Field %q is not a subscription.", sels[0].name)))
+	}
+	source, err := rootField.Subscribe(ctx, ex.args[sels[0]])
+	if err != nil {
+		return nil, errorResponse(&Error{Message: err.Error(), Locations: []Location{location(ex.src, sels[0].pos)}, Path: []interface{}{key}})
+	}
+
+	go func() {
+		defer close(results)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-source:
+				if !ok {
+					return
+				}
+				run := ex.fork()
+				value, ok := run.completeValue(ctx, rootField.Type, sels, event, []interface{}{key})
+				var data interface{}
+				if ok {
+					data = &orderedMap{keys: []string{key}, values: map[string]interface{}{key: value}}
+				}
+				select {
+				case results <- &Response{Data: data, Errors: run.errors}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return results, nil
+}
+
+// prepare parses and validates a request and selects its operation
+func (s *Schema) prepare(req Request) (*executor, *operation, *Response) {
+	ex := &executor{schema: s, src: req.Query, args: make(map[*field]map[string]interface{}), validated: make(map[string]bool)}
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, nil, errorResponse(&Error{Message: "Must provide a query string."})
+	}
+	doc, err := parse(req.Query)
+	if err != nil {
+		se := err.(*syntaxError)
+		return nil, nil, errorResponse(ex.errorAt(se.pos, se.msg))
+	}
+	ex.doc = doc
+
+	var op *operation
+	for _, o := range doc.operations {
+		if req.OperationName == "" && len(doc.operations) > 1 {
+			return nil, nil, errorResponse(&Error{Message: "Must provide operation name if query contains multiple operations."})
+		}
+		if req.OperationName == "" || o.name == req.OperationName {
+			op = o
+			break
+		}
+	}
+	if op == nil {
+		return nil, nil, errorResponse(&Error{Message: fmt.Sprintf("Unknown operation named %q.", req.OperationName)})
+	}
+
+	var root *Object
+	switch op.kind {
+	case "query":
+		root = s.Query
+	case "subscription":
+		root = s.Subscription
+	}
+	if root == nil {
+		return nil, nil, errorResponse(ex.errorAt(op.pos, fmt.Sprintf("Schema is not configured for %ss.", op.kind)))
+	}
+
+	ex.op = op
+	if err := ex.coerceVariables(op, req.Variables); err != nil {
+		return nil, nil, errorResponse(err)
+	}
+	ex.validateSelections(root, op.selections, map[string]bool{})
+	if len(ex.errors) > 0 {
+		return nil, nil, &Response{Errors: ex.errors}
+	}
+	return ex, op, nil
+}
+
+func errorResponse(err *Error) *Response {
+	return &Response{Errors: []*Error{err}}
+}
+
+// executor holds the state of one request
+type executor struct {
+	schema *Schema
+	src    string
+	doc    *document
+	op     *operation
+	vars   map[string]interface{}
+	args   map[*field]map[string]interface{} // coerced arguments, set by validation
+	errors []*Error
+
+	validated map[string]bool // fragments already validated
+}
+
+// fork returns an executor for another execution of the same request
+func (ex *executor) fork() *executor {
+	run := *ex
+	run.errors = nil
+	return &run
+}
+
+func (ex *executor) errorAt(pos int, message string) *Error {
+	return &Error{Message: message, Locations: []Location{location(ex.src, pos)}}
+}
+
+func (ex *executor) addError(pos int, message string, path []interface{}) {
+	err := ex.errorAt(pos, message)
+	if path != nil {
+		err.Path = append([]interface{}{}, path...)
+	}
+	ex.errors = append(ex.errors, err)
+}
+
+// coerceVariables checks the variable values against their definitions
+func (ex *executor) coerceVariables(op *operation, values map[string]interface{}) *Error {
+	ex.vars = make(map[string]interface{})
+	for _, def := range op.vars {
+		t, err := def.typ.schemaType()
+		if err != nil {
+			return ex.errorAt(op.pos, fmt.Sprintf("Variable \"$%s\": %v", def.name, err))
+		}
+		value, provided := values[def.name]
+		if !provided {
+			if def.hasDefault {
+				value, provided = def.def, true
+			} else if t.Kind == KindNonNull {
+				return ex.errorAt(op.pos, fmt.Sprintf("Variable \"$%s\" of required type %q was not provided.", def.name, t))
+			}
+		}
+		if !provided {
+			continue
+		}
+		coerced, err := coerceInput(t, value)
+		if err != nil {
+			return ex.errorAt(op.pos, fmt.Sprintf("Variable \"$%s\" got invalid value: %v", def.name, err))
+		}
+		ex.vars[def.name] = coerced
+	}
+	return nil
+}
+
+// schemaType maps a variable type to a schema input type
+func (t *typeRef) schemaType() (*Type, error) {
+	var result *Type
+	if t.elem != nil {
+		elem, err := t.elem.schemaType()
+		if err != nil {
+			return nil, err
+		}
+		result = ListOf(elem)
+	} else if result = scalars[t.name]; result == nil {
+		return nil, fmt.Errorf("unknown type %q", t.name)
+	}
+	if t.nonNull {
+		result = NonNull(result)
+	}
+	return result, nil
+}
+
+// validateSelections checks the selected fields and arguments of obj and
+// stores the coerced arguments of each field. Each fragment is validated
+// once, however often it is spread: its type condition fixes the object,
+// so the result is the same everywhere, and repeated nested spreads would
+// otherwise take exponential time.
+func (ex *executor) validateSelections(obj *Object, selections []*selection, fragments map[string]bool) {
+	for _, sel := range selections {
+		for _, d := range sel.directives {
+			if d.name != "skip" && d.name != "include" {
+				ex.addError(sel.pos, fmt.Sprintf("Unknown directive \"@%s\".", d.name), nil)
+			} else if _, err := ex.directiveCondition(d); err != nil {
+				ex.addError(sel.pos, err.Error(), nil)
+			}
+		}
+
+		switch {
+		case sel.spread != "":
+			frag := ex.doc.fragments[sel.spread]
+			switch {
+			case frag == nil:
+				ex.addError(sel.pos, fmt.Sprintf("Unknown fragment %q.", sel.spread), nil)
+			case fragments[sel.spread]:
+				ex.addError(sel.pos, fmt.Sprintf("Cannot spread fragment %q within itself.", sel.spread), nil)
+			case frag.typeCond != obj.Name:
+				ex.addError(sel.pos, fmt.Sprintf("Fragment %q cannot be spread here as objects of type %q can never be of type %q.", sel.spread, obj.Name, frag.typeCond), nil)
+			case !ex.validated[sel.spread]:
+				ex.validated[sel.spread] = true
+				fragments[sel.spread] = true
+				ex.validateSelections(obj, frag.selections, fragments)
+				delete(fragments, sel.spread)
+			}
+		case sel.inline != nil:
+			if sel.inline.typeCond != "" && sel.inline.typeCond != obj.Name {
+				ex.addError(sel.pos, fmt.Sprintf("Fragment cannot be spread here as objects of type %q can never be of type %q.", obj.Name, sel.inline.typeCond), nil)
+				continue
+			}
+			ex.validateSelections(obj, sel.inline.selections, fragments)
+		default:
+			ex.validateField(obj, sel.field, fragments)
+		}
+	}
+}
+
+func (ex *executor) validateField(obj *Object, f *field, fragments map[string]bool) {
+	if f.name == "__typename" {
+		if f.selections != nil {
+			ex.addError(f.pos, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.", nil)
+		}
+		return
+	}
+	def := obj.field(f.name)
+	if def == nil {
+		ex.addError(f.pos, fmt.Sprintf("Cannot query field %q on type %q.", f.name, obj.Name), nil)
+		return
+	}
+
+	args := make(map[string]interface{})
+	for _, arg := range f.args {
+		argDef := def.argument(arg.name)
+		if argDef == nil {
+			ex.addError(arg.pos, fmt.Sprintf("Unknown argument %q on field \"%s.%s\".", arg.name, obj.Name, f.name), nil)
+			continue
+		}
+		value, provided, err := ex.resolveLiteral(arg.value)
+		if err == nil && provided {
+			args[arg.name], err = coerceInput(argDef.Type, value)
+		}
+		if err != nil {
+			ex.addError(arg.pos, fmt.Sprintf("Argument %q has invalid value: %v", arg.name, err), nil)
+		}
+	}
+	for _, argDef := range def.Args {
+		if _, ok := args[argDef.Name]; ok {
+			continue
+		}
+		if argDef.Default != nil {
+			args[argDef.Name] = argDef.Default
+		} else if argDef.Type.Kind == KindNonNull {
+			ex.addError(f.pos, fmt.Sprintf("Field %q argument %q of type %q is required, but it was not provided.", f.name, argDef.Name, argDef.Type), nil)
+		}
+	}
+	ex.args[f] = args
+
+	named := def.Type.named()
+	switch {
+	case named.Kind == KindObject && f.selections == nil:
+		ex.addError(f.pos, fmt.Sprintf("Field %q of type %q must have a selection of subfields.", f.name, def.Type), nil)
+	case named.Kind == KindScalar && f.selections != nil:
+		ex.addError(f.pos, fmt.Sprintf("Field %q must not have a selection since type %q has no subfields.", f.name, def.Type), nil)
+	case named.Kind == KindObject:
+		ex.validateSelections(named.Object, f.selections, fragments)
+	}
+}
+
+// resolveLiteral replaces variables in a literal value. provided is false
+// for an unset variable, which counts as an omitted argument.
+func (ex *executor) resolveLiteral(value interface{}) (result interface{}, provided bool, err error) {
+	switch v := value.(type) {
+	case variable:
+		if !ex.op.defines(string(v)) {
+			return nil, false, fmt.Errorf("variable \"$%s\" is not defined", v)
+		}
+		result, provided = ex.vars[string(v)]
+		return result, provided, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			if list[i], _, err = ex.resolveLiteral(item); err != nil {
+				return nil, false, err
+			}
+		}
+		return list, true, nil
+	case enumValue:
+		return nil, false, fmt.Errorf("enum value %s is not supported, use a string", v)
+	case map[string]interface{}:
+		return nil, false, fmt.Errorf("input objects are not supported")
+	}
+	return value, true, nil
+}
+
+// directiveCondition evaluates @skip(if:) or @include(if:); true means included
+func (ex *executor) directiveCondition(d *argumentList) (bool, error) {
+	if len(d.args) != 1 || d.args[0].name != "if" {
+		return false, fmt.Errorf("Directive \"@%s\" requires the argument \"if\" of type \"Boolean!\".", d.name)
+	}
+	value, _, err := ex.resolveLiteral(d.args[0].value)
+	cond, ok := value.(bool)
+	if err != nil || !ok {
+		return false, fmt.Errorf("Directive \"@%s\" argument \"if\" must be a Boolean.", d.name)
+	}
+	return cond == (d.name == "include"), nil
+}
+
+// included reports whether the directives of a selection include it
+func (ex *executor) included(sel *selection) bool {
+	for _, d := range sel.directives {
+		if cond, _ := ex.directiveCondition(d); !cond {
+			return false
+		}
+	}
+	return true
+}
+
+// collectedField is a response key with the fields selected for it
+type collectedField struct {
+	key    string
+	fields []*field
+}
+
+// collectFields flattens fragments and groups the fields by response key.
+// A fragment spread again adds no fields, so it is collected once.
+func (ex *executor) collectFields(obj *Object, selections []*selection) []collectedField {
+	var result []collectedField
+	index := make(map[string]int)
+	visited := make(map[string]bool)
+	var collect func(selections []*selection)
+	collect = func(selections []*selection) {
+		for _, sel := range selections {
+			if !ex.included(sel) {
+				continue
+			}
+			switch {
+			case sel.spread != "":
+				if !visited[sel.spread] {
+					visited[sel.spread] = true
+					collect(ex.doc.fragments[sel.spread].selections)
+				}
+			case sel.inline != nil:
+				collect(sel.inline.selections)
+			default:
+				key := sel.field.responseKey()
+				if i, ok := index[key]; ok {
+					result[i].fields = append(result[i].fields, sel.field)
+				} else {
+					index[key] = len(result)
+					result = append(result, collectedField{key: key, fields: []*field{sel.field}})
+				}
+			}
+		}
+	}
+	collect(selections)
+	return result
+}
+
+// executeSelections resolves the selected fields of obj for source. ok is
+// false when a non-null field is null, which makes the object null.
+func (ex *executor) executeSelections(ctx context.Context, obj *Object, source interface{}, selections []*selection, path []interface{}) (*orderedMap, bool) {
+	result := &orderedMap{values: make(map[string]interface{})}
+	for _, cf := range ex.collectFields(obj, selections) {
+		fieldPath := append(path[:len(path):len(path)], cf.key)
+		value, ok := ex.executeField(ctx, obj, source, cf.fields, fieldPath)
+		if !ok {
+			return nil, false
+		}
+		result.keys = append(result.keys, cf.key)
+		result.values[cf.key] = value
+	}
+	return result, true
+}
+
+func (ex *executor) executeField(ctx context.Context, obj *Object, source interface{}, fields []*field, path []interface{}) (interface{}, bool) {
+	f := fields[0]
+	if f.name == "__typename" {
+		return obj.Name, true
+	}
+	def := obj.field(f.name)
+
+	var value interface{}
+	var err error
+	if def.Resolve != nil {
+		value, err = def.Resolve(ctx, source, ex.args[f])
+	} else {
+		value = defaultResolve(source, f.name)
+	}
+	if err != nil {
+		ex.addError(f.pos, err.Error(), path)
+		return nil, def.Type.Kind != KindNonNull
+	}
+	return ex.completeValue(ctx, def.Type, fields, value, path)
+}
+
+// completeValue converts a resolved value to the result of type t. ok is
+// false when the value is null for a non-null type; the null then
+// propagates to the nearest nullable parent.
+func (ex *executor) completeValue(ctx context.Context, t *Type, fields []*field, value interface{}, path []interface{}) (interface{}, bool) {
+	if t.Kind == KindNonNull {
+		result, ok := ex.completeNullable(ctx, t.OfType, fields, value, path)
+		if ok && result == nil {
+			ex.addError(fields[0].pos, fmt.Sprintf("Cannot return null for non-nullable field %q.", fields[0].name), path)
+		}
+		return result, ok && result != nil
+	}
+
+	result, ok := ex.completeNullable(ctx, t, fields, value, path)
+	if !ok {
+		return nil, true // null propagated to this nullable position
+	}
+	return result, true
+}
+
+func (ex *executor) completeNullable(ctx context.Context, t *Type, fields []*field, value interface{}, path []interface{}) (interface{}, bool) {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil, true
+		}
+		if t.Kind == KindObject && v.Kind() == reflect.Pointer {
+			break // objects keep their pointer as the source of their fields
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, true
+	}
+
+	switch t.Kind {
+	case KindList:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			ex.addError(fields[0].pos, fmt.Sprintf("Expected a list for field %q.", fields[0].name), path)
+			return nil, true
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			item, ok := ex.completeValue(ctx, t.OfType, fields, v.Index(i).Interface(), append(path[:len(path):len(path)], i))
+			if !ok {
+				return nil, false
+			}
+			list[i] = item
+		}
+		return list, true
+	case KindObject:
+		var selections []*selection
+		for _, f := range fields {
+			selections = append(selections, f.selections...)
+		}
+		obj, ok := ex.executeSelections(ctx, t.Object, v.Interface(), selections, path)
+		if !ok {
+			return nil, false
+		}
+		return obj, true
+	}
+
+	result, err := serializeScalar(t, v)
+	if err != nil {
+		ex.addError(fields[0].pos, err.Error(), path)
+		return nil, true
+	}
+	return result, true
+}
+
+// serializeScalar converts a Go value to the JSON value of a scalar
+func serializeScalar(t *Type, v reflect.Value) (interface{}, error) {
+	if tm, ok := v.Interface().(time.Time); ok && (t == String || t == ID) {
+		if tm.IsZero() {
+			return nil, nil
+		}
+		return tm.UTC().Format(time.RFC3339), nil
+	}
+	switch t {
+	case Int:
+		switch {
+		case v.CanInt():
+			return v.Int(), nil
+		case v.CanUint():
+			return v.Uint(), nil
+		case v.CanFloat() && v.Float() == math.Trunc(v.Float()):
+			return int64(v.Float()), nil
+		}
+	case Float:
+		switch {
+		case v.CanFloat():
+			return v.Float(), nil
+		case v.CanInt():
+			return float64(v.Int()), nil
+		case v.CanUint():
+			return float64(v.Uint()), nil
+		}
+	case String, ID:
+		switch {
+		case v.Kind() == reflect.String:
+			return v.String(), nil
+		case t == ID && v.CanInt():
+			return fmt.Sprint(v.Int()), nil
+		case t == ID && v.CanUint():
+			return fmt.Sprint(v.Uint()), nil
+		}
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+	case Boolean:
+		if v.Kind() == reflect.Bool {
+			return v.Bool(), nil
+		}
+	}
+	return nil, fmt.Errorf("%s cannot represent value: %v", t.Name, v.Interface())
+}
+
+// coerceInput converts an argument or variable value to type t
+func coerceInput(t *Type, value interface{}) (interface{}, error) {
+	if t.Kind == KindNonNull {
+		if value == nil {
+			return nil, fmt.Errorf("expected non-null %s", t)
+		}
+		return coerceInput(t.OfType, value)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	if t.Kind == KindList {
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value} // a single value is a list of one
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			coerced, err := coerceInput(t.OfType, item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	}
+
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			value = i
+		} else if f, err := n.Float64(); err == nil {
+			value = f
+		}
+	}
+	switch t {
+	case Int:
+		switch v := value.(type) {
+		case int64:
+			return int(v), nil
+		case int:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+				return int(v), nil
+			}
+		}
+	case Float:
+		switch v := value.(type) {
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case String:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case ID:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int64, int:
+			return fmt.Sprint(v), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return fmt.Sprint(int64(v)), nil
+			}
+		}
+	case Boolean:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, got %v", t, value)
+}
+
+// defaultResolve reads a field from a map or struct source
+func defaultResolve(source interface{}, name string) interface{} {
+	v := reflect.ValueOf(source)
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		if item := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())); item.IsValid() {
+			return item.Interface()
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if strings.EqualFold(jsonName, name) || (jsonName == "" && strings.EqualFold(sf.Name, name)) {
+				return v.Field(i).Interface()
+			}
+		}
+		// Fields of embedded structs
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Anonymous {
+				if value := defaultResolve(v.Field(i).Interface(), name); value != nil {
+					return value
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// orderedMap is a result object with its fields in selection order
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
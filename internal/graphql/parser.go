@@ -0,0 +1,609 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL request
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query, mutation or subscription of a document
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	vars       []*varDef
+	selections []*selection
+	pos        int
+}
+
+// defines reports whether the operation defines a variable
+func (op *operation) defines(name string) bool {
+	for _, def := range op.vars {
+		if def.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// varDef is a variable definition of an operation
+type varDef struct {
+	name       string
+	typ        *typeRef
+	def        interface{}
+	hasDefault bool
+}
+
+// typeRef is a type as written in a variable definition
+type typeRef struct {
+	name    string
+	elem    *typeRef // list element type
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// fragment is a named fragment or an inline fragment
+type fragment struct {
+	name       string
+	typeCond   string
+	selections []*selection
+	pos        int
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	field      *field
+	spread     string
+	inline     *fragment
+	directives []*argumentList
+	pos        int
+}
+
+// field is a selected field
+type field struct {
+	alias      string
+	name       string
+	args       []*argument
+	selections []*selection
+	pos        int
+}
+
+// responseKey is the key of the field in the result
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// argument is an argument of a field or directive
+type argument struct {
+	name  string
+	value interface{}
+	pos   int
+}
+
+// argumentList is a directive with its arguments
+type argumentList struct {
+	name string
+	args []*argument
+}
+
+// Literal values besides the JSON-like ones (int64, float64, string, bool,
+// nil, []interface{}, map[string]interface{})
+type (
+	variable  string // $name
+	enumValue string // an unquoted name
+)
+
+// Token kinds
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// parser is a recursive descent parser of GraphQL documents
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// syntaxError is a parse error at a position of the source
+type syntaxError struct {
+	msg string
+	pos int
+}
+
+func (e *syntaxError) Error() string {
+	return e.msg
+}
+
+// parse parses a request document
+func parse(src string) (doc *document, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(*syntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, se
+		}
+	}()
+
+	p := &parser{src: src}
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek(tokPunct, "{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", pos: p.tok.pos, selections: p.parseSelectionSet()})
+		case p.peek(tokName, "query"), p.peek(tokName, "mutation"), p.peek(tokName, "subscription"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case p.peek(tokName, "fragment"):
+			frag := p.parseFragment()
+			if _, exists := doc.fragments[frag.name]; exists {
+				p.fail(frag.pos, "There can be only one fragment named %q.", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		p.fail(0, "Document does not contain an operation.")
+	}
+	return doc, nil
+}
+
+// fail aborts parsing with a syntax error
+func (p *parser) fail(pos int, format string, args ...interface{}) {
+	panic(&syntaxError{msg: "Syntax Error: " + fmt.Sprintf(format, args...), pos: pos})
+}
+
+func (p *parser) unexpected() {
+	if p.tok.kind == tokEOF {
+		p.fail(p.tok.pos, "Unexpected <EOF>.")
+	}
+	p.fail(p.tok.pos, "Unexpected %q.", p.tok.value)
+}
+
+// peek reports whether the current token is of kind with value (any value if empty)
+func (p *parser) peek(kind int, value string) bool {
+	return p.tok.kind == kind && (value == "" || p.tok.value == value)
+}
+
+// skip consumes the current token if it matches
+func (p *parser) skip(kind int, value string) bool {
+	if p.peek(kind, value) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// expect consumes the current token, which must match
+func (p *parser) expect(kind int, value string) token {
+	if !p.peek(kind, value) {
+		p.unexpected()
+	}
+	tok := p.tok
+	p.next()
+	return tok
+}
+
+func (p *parser) parseOperation() *operation {
+	op := &operation{kind: p.tok.value, pos: p.tok.pos}
+	p.next()
+	if p.peek(tokName, "") {
+		op.name = p.tok.value
+		p.next()
+	}
+	if p.skip(tokPunct, "(") {
+		for !p.skip(tokPunct, ")") {
+			p.expect(tokPunct, "$")
+			v := &varDef{name: p.expect(tokName, "").value}
+			p.expect(tokPunct, ":")
+			v.typ = p.parseType()
+			if p.skip(tokPunct, "=") {
+				v.def, v.hasDefault = p.parseValue(true), true
+			}
+			op.vars = append(op.vars, v)
+		}
+	}
+	p.parseDirectives()
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+func (p *parser) parseFragment() *fragment {
+	pos := p.tok.pos
+	p.next()
+	frag := &fragment{name: p.expect(tokName, "").value, pos: pos}
+	if frag.name == "on" {
+		p.fail(pos, "Unexpected name \"on\".")
+	}
+	p.expect(tokName, "on")
+	frag.typeCond = p.expect(tokName, "").value
+	p.parseDirectives()
+	frag.selections = p.parseSelectionSet()
+	return frag
+}
+
+func (p *parser) parseType() *typeRef {
+	var t *typeRef
+	if p.skip(tokPunct, "[") {
+		t = &typeRef{elem: p.parseType()}
+		p.expect(tokPunct, "]")
+	} else {
+		t = &typeRef{name: p.expect(tokName, "").value}
+	}
+	t.nonNull = p.skip(tokPunct, "!")
+	return t
+}
+
+func (p *parser) parseSelectionSet() []*selection {
+	p.expect(tokPunct, "{")
+	var selections []*selection
+	for !p.skip(tokPunct, "}") {
+		selections = append(selections, p.parseSelection())
+	}
+	if len(selections) == 0 {
+		p.fail(p.tok.pos, "Expected a selection.")
+	}
+	return selections
+}
+
+func (p *parser) parseSelection() *selection {
+	sel := &selection{pos: p.tok.pos}
+	if p.skip(tokPunct, "...") {
+		switch {
+		case p.peek(tokName, "") && p.tok.value != "on":
+			sel.spread = p.tok.value
+			p.next()
+			sel.directives = p.parseDirectives()
+		default:
+			frag := &fragment{pos: sel.pos}
+			if p.skip(tokName, "on") {
+				frag.typeCond = p.expect(tokName, "").value
+			}
+			sel.directives = p.parseDirectives()
+			frag.selections = p.parseSelectionSet()
+			sel.inline = frag
+		}
+		return sel
+	}
+
+	f := &field{name: p.expect(tokName, "").value, pos: sel.pos}
+	if p.skip(tokPunct, ":") {
+		f.alias, f.name = f.name, p.expect(tokName, "").value
+	}
+	f.args = p.parseArguments(false)
+	sel.directives = p.parseDirectives()
+	if p.peek(tokPunct, "{") {
+		f.selections = p.parseSelectionSet()
+	}
+	sel.field = f
+	return sel
+}
+
+func (p *parser) parseArguments(constant bool) []*argument {
+	if !p.skip(tokPunct, "(") {
+		return nil
+	}
+	var args []*argument
+	for !p.skip(tokPunct, ")") {
+		arg := &argument{pos: p.tok.pos, name: p.expect(tokName, "").value}
+		p.expect(tokPunct, ":")
+		arg.value = p.parseValue(constant)
+		args = append(args, arg)
+	}
+	return args
+}
+
+func (p *parser) parseDirectives() []*argumentList {
+	var directives []*argumentList
+	for p.skip(tokPunct, "@") {
+		d := &argumentList{name: p.expect(tokName, "").value}
+		d.args = p.parseArguments(false)
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// parseValue parses a literal; constant values (defaults) cannot contain variables
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.unexpected()
+			}
+			p.next()
+			return variable(p.expect(tokName, "").value)
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.skip(tokPunct, "]") {
+				list = append(list, p.parseValue(constant))
+			}
+			return list
+		case "{":
+			p.next()
+			obj := map[string]interface{}{}
+			for !p.skip(tokPunct, "}") {
+				name := p.expect(tokName, "").value
+				p.expect(tokPunct, ":")
+				obj[name] = p.parseValue(constant)
+			}
+			return obj
+		}
+	case tokInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			p.fail(tok.pos, "Invalid number %s.", tok.value)
+		}
+		return n
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			p.fail(tok.pos, "Invalid number %s.", tok.value)
+		}
+		return f
+	case tokString:
+		p.next()
+		return tok.value
+	case tokName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.value)
+	}
+	p.unexpected()
+	return nil
+}
+
+// next reads the next token, skipping whitespace, commas and comments
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+		case strings.HasPrefix(p.src[p.pos:], "\uFEFF"): // byte order mark
+			p.pos += len("\uFEFF")
+		default:
+			p.tok = p.lex()
+			return
+		}
+	}
+	p.tok = token{kind: tokEOF, pos: p.pos}
+}
+
+func (p *parser) lex() token {
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		return token{kind: tokPunct, value: "...", pos: start}
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		p.pos++
+		return token{kind: tokPunct, value: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		return token{kind: tokName, value: p.src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return p.lexNumber()
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return p.lexBlockString()
+		}
+		return p.lexString()
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.fail(start, "Unexpected character %q.", r)
+	return token{}
+}
+
+func (p *parser) lexNumber() token {
+	start := p.pos
+	kind := tokInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		begin := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == begin {
+			p.fail(p.pos, "Invalid number, expected digit.")
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	return token{kind: kind, value: p.src[start:p.pos], pos: start}
+}
+
+func (p *parser) lexString() token {
+	start := p.pos
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			p.fail(start, "Unterminated string.")
+		}
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return token{kind: tokString, value: sb.String(), pos: start}
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				p.fail(start, "Unterminated string.")
+			}
+			esc := p.src[p.pos+1]
+			p.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				sb.WriteByte(esc)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.src) {
+					p.fail(p.pos, "Invalid Unicode escape sequence.")
+				}
+				n, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					p.fail(p.pos, "Invalid Unicode escape sequence.")
+				}
+				sb.WriteRune(rune(n))
+				p.pos += 4
+			default:
+				p.fail(p.pos-2, "Invalid character escape sequence: \\%c.", esc)
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// lexBlockString reads a """block string""". Common indentation is removed
+// and blank first and last lines are dropped.
+func (p *parser) lexBlockString() token {
+	start := p.pos
+	p.pos += 3
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			p.fail(start, "Unterminated string.")
+		}
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], `\"""`):
+			sb.WriteString(`"""`)
+			p.pos += 4
+		case strings.HasPrefix(p.src[p.pos:], `"""`):
+			p.pos += 3
+			return token{kind: tokString, value: blockStringValue(sb.String()), pos: start}
+		default:
+			sb.WriteByte(p.src[p.pos])
+			p.pos++
+		}
+	}
+}
+
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// location converts a byte offset of src to a line and column (1-based)
+func location(src string, pos int) Location {
+	loc := Location{Line: 1, Column: 1}
+	for i, r := range src {
+		if i >= pos {
+			break
+		}
+		if r == '\n' {
+			loc.Line++
+			loc.Column = 1
+		} else {
+			loc.Column++
+		}
+	}
+	return loc
+}
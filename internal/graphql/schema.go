@@ -0,0 +1,206 @@
+// Package graphql implements the part of GraphQL served by /api/graphql:
+// queries and subscriptions with field selection, aliases, arguments,
+// variables, fragments and the @skip/@include directives, executed against
+// a schema of Go resolvers. Mutations and introspection (except __typename)
+// are not supported; the schema is published in SDL form instead.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Kind is the kind of a Type
+type Kind int
+
+// Type kinds
+const (
+	KindScalar Kind = iota
+	KindObject
+	KindList
+	KindNonNull
+)
+
+// Type is a scalar, an object, or a list or non-null wrapper of a type
+type Type struct {
+	Kind   Kind
+	Name   string  // scalars
+	Object *Object // objects
+	OfType *Type   // lists and non-null types
+}
+
+// Built-in scalars
+var (
+	Int     = &Type{Kind: KindScalar, Name: "Int"}
+	Float   = &Type{Kind: KindScalar, Name: "Float"}
+	String  = &Type{Kind: KindScalar, Name: "String"}
+	Boolean = &Type{Kind: KindScalar, Name: "Boolean"}
+	ID      = &Type{Kind: KindScalar, Name: "ID"}
+)
+
+// scalars are the input types usable in variable definitions
+var scalars = map[string]*Type{"Int": Int, "Float": Float, "String": String, "Boolean": Boolean, "ID": ID}
+
+// ObjectOf returns the type of an object
+func ObjectOf(o *Object) *Type {
+	return &Type{Kind: KindObject, Object: o}
+}
+
+// ListOf returns a list of t
+func ListOf(t *Type) *Type {
+	return &Type{Kind: KindList, OfType: t}
+}
+
+// NonNull returns the non-null variant of t
+func NonNull(t *Type) *Type {
+	return &Type{Kind: KindNonNull, OfType: t}
+}
+
+// String returns the type in SDL notation, e.g. [Container!]!
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindObject:
+		return t.Object.Name
+	case KindList:
+		return "[" + t.OfType.String() + "]"
+	case KindNonNull:
+		return t.OfType.String() + "!"
+	}
+	return t.Name
+}
+
+// named returns the scalar or object type without list and non-null wrappers
+func (t *Type) named() *Type {
+	for t.Kind == KindList || t.Kind == KindNonNull {
+		t = t.OfType
+	}
+	return t
+}
+
+// Object is an object type
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+// field returns the field called name, or nil
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// ResolveFunc returns the value of a field of source (the value of the
+// parent object, nil for root fields) for the coerced arguments
+type ResolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// SubscribeFunc starts a subscription. Each value received from the channel
+// is the source of one result; the channel must be closed when ctx is done.
+type SubscribeFunc func(ctx context.Context, args map[string]interface{}) (<-chan interface{}, error)
+
+// Field is a field of an object type. Fields without Resolve read the
+// value from the source: a map key or a struct field with the same name
+// or JSON name (case-insensitive).
+type Field struct {
+	Name        string
+	Description string
+	Type        *Type
+	Args        []*Argument
+	Resolve     ResolveFunc
+	Subscribe   SubscribeFunc // subscription root fields only
+}
+
+// argument returns the argument called name, or nil
+func (f *Field) argument(name string) *Argument {
+	for _, a := range f.Args {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Argument is an argument of a field. Default is used when the argument
+// is omitted (nil for none).
+type Argument struct {
+	Name        string
+	Description string
+	Type        *Type
+	Default     interface{}
+}
+
+// Schema is a set of types with a query and an optional subscription root
+type Schema struct {
+	Query        *Object
+	Subscription *Object
+}
+
+// SDL returns the schema in the GraphQL schema definition language
+func (s *Schema) SDL() string {
+	var objects []*Object
+	seen := make(map[*Object]bool)
+	var walk func(o *Object)
+	walk = func(o *Object) {
+		if o == nil || seen[o] {
+			return
+		}
+		seen[o] = true
+		objects = append(objects, o)
+		for _, f := range o.Fields {
+			if t := f.Type.named(); t.Kind == KindObject {
+				walk(t.Object)
+			}
+		}
+	}
+	walk(s.Query)
+	walk(s.Subscription)
+	// Roots first, then the other types by name
+	sort.SliceStable(objects[1:], func(i, j int) bool {
+		a, b := objects[i+1], objects[j+1]
+		if (a == s.Subscription) != (b == s.Subscription) {
+			return a == s.Subscription
+		}
+		return a.Name < b.Name
+	})
+
+	var sb strings.Builder
+	for i, o := range objects {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		writeDescription(&sb, "", o.Description)
+		sb.WriteString("type " + o.Name + " {\n")
+		for _, f := range o.Fields {
+			writeDescription(&sb, "  ", f.Description)
+			sb.WriteString("  " + f.Name)
+			if len(f.Args) > 0 {
+				args := make([]string, len(f.Args))
+				for i, a := range f.Args {
+					args[i] = a.Name + ": " + a.Type.String()
+					if a.Default != nil {
+						def, _ := json.Marshal(a.Default)
+						args[i] += " = " + string(def)
+					}
+				}
+				sb.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			sb.WriteString(": " + f.Type.String() + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+func writeDescription(sb *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	quoted, _ := json.Marshal(description)
+	sb.WriteString(indent + string(quoted) + "\n")
+}
@@ -0,0 +1,177 @@
+// Package i18n translates user-facing API messages and plugin HTML.
+//
+// English is the source language: messages are written in English in the
+// code and looked up as keys in the bundle of the requested locale. Bundles
+// are embedded from locales/<lang>.json, one flat JSON object per locale
+// mapping English strings to their translation. Strings missing from a
+// bundle are returned unchanged.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the source language, used when no requested locale is available
+const Default = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// bundles maps a locale to its translations
+var bundles = loadBundles()
+
+func loadBundles() map[string]map[string]string {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic("i18n: " + err.Error())
+	}
+	result := make(map[string]map[string]string, len(files))
+	for _, f := range files {
+		data, err := localeFiles.ReadFile("locales/" + f.Name())
+		if err != nil {
+			panic("i18n: " + err.Error())
+		}
+		var bundle map[string]string
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			panic("i18n: invalid bundle " + f.Name() + ": " + err.Error())
+		}
+		result[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = bundle
+	}
+	return result
+}
+
+// Languages returns the available locales, Default first
+func Languages() []string {
+	langs := []string{Default}
+	for lang := range bundles {
+		if lang != Default {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// Negotiate picks the available locale that best matches an Accept-Language
+// header ("ru-RU,ru;q=0.9,en;q=0.8"). Region subtags fall back to the base
+// language; Default is returned if nothing matches.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{strings.ToLower(tag), q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.tag == Default || bundles[c.tag] != nil {
+			return c.tag
+		}
+		base, _, _ := strings.Cut(c.tag, "-")
+		if base == Default || bundles[base] != nil {
+			return base
+		}
+	}
+	return Default
+}
+
+// FromRequest returns the locale negotiated from the Accept-Language header
+// of r, Default for a nil request
+func FromRequest(r *http.Request) string {
+	if r == nil {
+		return Default
+	}
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// T translates message into lang. Messages with a dynamic detail after ": "
+// ("Failed to save keys: permission denied") are translated by their prefix.
+func T(lang, message string) string {
+	bundle := bundles[lang]
+	if bundle == nil {
+		return message
+	}
+	if translated, ok := bundle[message]; ok {
+		return translated
+	}
+	if prefix, detail, ok := strings.Cut(message, ": "); ok {
+		if translated, ok := bundle[prefix]; ok {
+			return translated + ": " + detail
+		}
+	}
+	return message
+}
+
+var (
+	// htmlSkipRe matches elements whose content is not user-visible text
+	htmlSkipRe = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>|<!--.*?-->`)
+	// htmlTextRe matches the text between two tags
+	htmlTextRe = regexp.MustCompile(`>([^<>]+)<`)
+	// htmlAttrRe matches attributes shown to the user
+	htmlAttrRe = regexp.MustCompile(`\b(title|placeholder|aria-label)="([^"]*)"`)
+)
+
+// TranslateHTML translates the text nodes and the title, placeholder and
+// aria-label attributes of an HTML fragment whose trimmed content is in the
+// bundle of lang. Scripts, styles and comments are left as they are.
+func TranslateHTML(lang, html string) string {
+	bundle := bundles[lang]
+	if len(bundle) == 0 {
+		return html
+	}
+
+	var b strings.Builder
+	b.Grow(len(html))
+	last := 0
+	for _, loc := range htmlSkipRe.FindAllStringIndex(html, -1) {
+		b.WriteString(translateMarkup(bundle, html[last:loc[0]]))
+		b.WriteString(html[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(translateMarkup(bundle, html[last:]))
+	return b.String()
+}
+
+// translateMarkup translates a fragment that contains no scripts or styles
+func translateMarkup(bundle map[string]string, markup string) string {
+	markup = htmlTextRe.ReplaceAllStringFunc(markup, func(m string) string {
+		text := m[1 : len(m)-1]
+		trimmed := strings.TrimSpace(text)
+		translated, ok := bundle[trimmed]
+		if trimmed == "" || !ok {
+			return m
+		}
+		start := strings.Index(text, trimmed)
+		return ">" + text[:start] + translated + text[start+len(trimmed):] + "<"
+	})
+	return htmlAttrRe.ReplaceAllStringFunc(markup, func(m string) string {
+		sub := htmlAttrRe.FindStringSubmatch(m)
+		translated, ok := bundle[sub[2]]
+		if !ok {
+			return m
+		}
+		return sub[1] + `="` + translated + `"`
+	})
+}
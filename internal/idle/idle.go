@@ -0,0 +1,137 @@
+// Package idle tracks whether any UI or API client is connected, so
+// background work can be slowed down on battery or solar powered hosts
+// while nobody is looking.
+package idle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Slowdown is the factor sampling intervals are stretched by while idle
+const Slowdown = 4
+
+// Monitor tracks client activity. The host is idle when no request has
+// been in flight for the configured period; long-lived requests such as
+// event streams and terminals keep it active while they are open.
+// A nil Monitor is never idle.
+type Monitor struct {
+	after time.Duration
+
+	mu     sync.Mutex
+	active int           // requests in flight
+	last   time.Time     // end of the last request
+	wake   chan struct{} // closed when a client connects while idle
+}
+
+// New creates a monitor that becomes idle after the given period
+// without clients. Zero or less disables idle mode.
+func New(after time.Duration) *Monitor {
+	if after <= 0 {
+		return nil
+	}
+	return &Monitor{after: after, last: time.Now(), wake: make(chan struct{})}
+}
+
+// Begin records the start of a client request
+func (m *Monitor) Begin() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.idleLocked(time.Now()) {
+		close(m.wake)
+		m.wake = make(chan struct{})
+	}
+	m.active++
+}
+
+// End records the end of a client request started with Begin
+func (m *Monitor) End() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active--
+	m.last = time.Now()
+}
+
+// Idle reports whether no client has been connected for the period
+func (m *Monitor) Idle() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.idleLocked(time.Now())
+}
+
+// idleLocked reports whether the monitor is idle at now. Call with m.mu
+// held.
+func (m *Monitor) idleLocked(now time.Time) bool {
+	return m.active == 0 && now.Sub(m.last) >= m.after
+}
+
+// Woken returns a channel that is closed when a client next connects
+// while idle. A nil Monitor returns a nil channel, which never fires.
+func (m *Monitor) Woken() <-chan struct{} {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.wake
+}
+
+// Interval returns a sampling interval, stretched by Slowdown while idle
+func (m *Monitor) Interval(d time.Duration) time.Duration {
+	if m.Idle() {
+		return d * Slowdown
+	}
+	return d
+}
+
+// Sleep waits for the sampling interval d, or d*Slowdown while idle,
+// ending early when a client connects. Returns false when ctx is
+// cancelled.
+func (m *Monitor) Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(m.Interval(d))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+	case <-m.Woken():
+	}
+	return true
+}
+
+// RunPeriodic runs a non-essential job every interval until ctx is
+// cancelled. Runs that fall due while idle are postponed until a client
+// connects.
+func (m *Monitor) RunPeriodic(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.Idle() {
+				pending = true
+				continue
+			}
+		case <-m.Woken():
+			if !pending {
+				continue
+			}
+		}
+		pending = false
+		job()
+	}
+}
@@ -0,0 +1,355 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLineSize - максимальная длина строки лога при чтении
+const maxLineSize = 1024 * 1024
+
+// Entry - запись лога для просмотра (см. Entries и Subscribe)
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"` // debug, info, warn или error
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Query - фильтр записей лога
+type Query struct {
+	Level  Level     // минимальный уровень
+	From   time.Time // не раньше, нулевое - без ограничения
+	To     time.Time // не позже, нулевое - без ограничения
+	Search string    // подстрока сообщения или значений полей без учета регистра
+	Skip   int       // пропустить столько подходящих записей (пагинация)
+	Limit  int
+}
+
+// Match проверяет, подходит ли запись под фильтр (кроме Skip и Limit)
+func (q Query) Match(e Entry) bool {
+	if level, err := ParseLevel(e.Level); err == nil && level < q.Level {
+		return false
+	}
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && e.Time.After(q.To) {
+		return false
+	}
+	if q.Search == "" {
+		return true
+	}
+	search := strings.ToLower(q.Search)
+	if strings.Contains(strings.ToLower(e.Message), search) {
+		return true
+	}
+	for _, v := range e.Fields {
+		if strings.Contains(strings.ToLower(v), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// Entries возвращает записи app.log и его ротированных копий, новые первыми.
+// next - значение Skip для следующей страницы, 0 если записей больше нет.
+func (l *Logger) Entries(q Query) (entries []Entry, next int, err error) {
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+
+	w := l.core.appWriter
+	paths := []string{w.filename}
+	for i := 1; ; i++ {
+		path := w.backupPath(i)
+		if path == "" {
+			break
+		}
+		paths = append(paths, path)
+	}
+
+	skipped := 0
+	for _, path := range paths {
+		fileEntries, err := readEntries(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // ротирован во время чтения
+			}
+			return nil, 0, err
+		}
+		for i := len(fileEntries) - 1; i >= 0; i-- {
+			e := fileEntries[i]
+			if !q.From.IsZero() && !e.Time.IsZero() && e.Time.Before(q.From) {
+				return entries, 0, nil // дальше только более старые записи
+			}
+			if !q.Match(e) {
+				continue
+			}
+			if skipped < q.Skip {
+				skipped++
+				continue
+			}
+			if len(entries) == q.Limit {
+				return entries, q.Skip + q.Limit, nil
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, 0, nil
+}
+
+// readEntries читает записи файла лога (сжатого, если имя оканчивается на .gz)
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.TrimSpace(line) != "" {
+			entries = append(entries, ParseEntry(line))
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// ParseEntry разбирает строку лога в формате text (key=value) или json.
+// Строки другого формата возвращаются целиком как сообщение уровня info.
+func ParseEntry(line string) Entry {
+	var fields map[string]string
+	if strings.HasPrefix(line, "{") {
+		fields = parseJSONFields(line)
+	} else if strings.HasPrefix(line, slog.TimeKey+"=") {
+		fields = parseTextFields(line)
+	}
+	if fields == nil {
+		return Entry{Level: "info", Message: line}
+	}
+
+	e := Entry{Level: "info", Message: fields[slog.MessageKey]}
+	if t, err := time.Parse(time.RFC3339Nano, fields[slog.TimeKey]); err == nil {
+		e.Time = t
+	}
+	if level, ok := fields[slog.LevelKey]; ok {
+		e.Level = entryLevel(level)
+	}
+	for _, key := range []string{slog.TimeKey, slog.LevelKey, slog.MessageKey} {
+		delete(fields, key)
+	}
+	if len(fields) > 0 {
+		e.Fields = fields
+	}
+	return e
+}
+
+// entryLevel приводит уровень slog ("WARN", "INFO+2") к имени LevelName
+func entryLevel(s string) string {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return "info"
+	}
+	return LevelName(level)
+}
+
+// parseJSONFields разбирает строку формата json, вложенные объекты
+// раскрываются в ключи через точку (source.file)
+func parseJSONFields(line string) map[string]string {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return nil
+	}
+	fields := make(map[string]string, len(obj))
+	flattenJSON(fields, "", obj)
+	return fields
+}
+
+func flattenJSON(fields map[string]string, prefix string, obj map[string]any) {
+	for k, v := range obj {
+		switch v := v.(type) {
+		case map[string]any:
+			flattenJSON(fields, prefix+k+".", v)
+		case string:
+			fields[prefix+k] = v
+		default:
+			data, _ := json.Marshal(v)
+			fields[prefix+k] = string(data)
+		}
+	}
+}
+
+// parseTextFields разбирает строку формата text: key=value через пробел,
+// значения с пробелами и спецсимволами в кавычках Go
+func parseTextFields(line string) map[string]string {
+	fields := make(map[string]string)
+	for line != "" {
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return nil
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil
+			}
+			value, _ = strconv.Unquote(quoted)
+			line = line[len(quoted):]
+		} else if end := strings.IndexByte(line, ' '); end >= 0 {
+			value = line[:end]
+			line = line[end:]
+		} else {
+			value, line = line, ""
+		}
+		fields[key] = value
+		line = strings.TrimPrefix(line, " ")
+	}
+	return fields
+}
+
+// tail рассылает записи подписчикам Subscribe
+type tail struct {
+	mu          sync.Mutex
+	subscribers map[chan Entry]struct{}
+}
+
+// Subscribe возвращает канал, получающий новые записи лога от текущего
+// уровня (медленные подписчики пропускают записи), и функцию отписки
+func (l *Logger) Subscribe(buffer int) (<-chan Entry, func()) {
+	t := &l.core.tail
+	ch := make(chan Entry, buffer)
+
+	t.mu.Lock()
+	if t.subscribers == nil {
+		t.subscribers = make(map[chan Entry]struct{})
+	}
+	t.subscribers[ch] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.subscribers, ch)
+			t.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// active проверяет, есть ли подписчики
+func (t *tail) active() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.subscribers) > 0
+}
+
+// publish отправляет запись всем подписчикам
+func (t *tail) publish(e Entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ch := range t.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// tailHandler - обработчик slog, передающий записи подписчикам
+type tailHandler struct {
+	tail   *tail
+	level  slog.Leveler
+	fields map[string]string // поля из With
+	prefix string            // префикс группы ("group.")
+}
+
+func (h *tailHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.tail.active()
+}
+
+func (h *tailHandler) Handle(_ context.Context, r slog.Record) error {
+	e := Entry{
+		Time:    r.Time,
+		Level:   LevelName(r.Level),
+		Message: r.Message,
+		Fields:  make(map[string]string, len(h.fields)+r.NumAttrs()),
+	}
+	for k, v := range h.fields {
+		e.Fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addEntryField(e.Fields, h.prefix, a)
+		return true
+	})
+	if len(e.Fields) == 0 {
+		e.Fields = nil
+	}
+	h.tail.publish(e)
+	return nil
+}
+
+func (h *tailHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.fields = make(map[string]string, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		next.fields[k] = v
+	}
+	for _, a := range attrs {
+		addEntryField(next.fields, h.prefix, a)
+	}
+	return &next
+}
+
+func (h *tailHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// addEntryField добавляет поле атрибута (группы раскрываются через точку)
+func addEntryField(fields map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addEntryField(fields, prefix, ga)
+		}
+		return
+	}
+	if !a.Equal(slog.Attr{}) {
+		fields[prefix+a.Key] = a.Value.String()
+	}
+}
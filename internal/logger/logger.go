@@ -1,102 +1,97 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"sync"
+	"runtime"
+	"strings"
+	"time"
 )
 
+// Level - уровень логирования
+type Level = slog.Level
+
 const (
-	defaultMaxSize    = 10 * 1024 * 1024 // 10 MB
-	defaultMaxBackups = 3
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
 )
 
-// rotatingWriter пишет в файл и автоматически ротирует при превышении maxSize
-type rotatingWriter struct {
-	filename   string
-	maxSize    int64
-	maxBackups int
-	size       int64
-	file       *os.File
-	mu         sync.Mutex
-}
-
-func newRotatingWriter(filename string, maxSize int64, maxBackups int) (*rotatingWriter, error) {
-	w := &rotatingWriter{
-		filename:   filename,
-		maxSize:    maxSize,
-		maxBackups: maxBackups,
-	}
-	info, err := os.Stat(filename)
-	if err == nil {
-		w.size = info.Size()
-	}
-	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, err
+// ParseLevel разбирает уровень логирования из строки (debug, info, warn, error)
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level: %s (expected debug, info, warn or error)", s)
 	}
-	w.file = f
-	return w, nil
 }
 
-func (w *rotatingWriter) Write(p []byte) (n int, err error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if w.size+int64(len(p)) > w.maxSize {
-		if err := w.rotate(); err != nil {
-			return 0, err
-		}
+// LevelName возвращает имя уровня в виде, принимаемом ParseLevel
+func LevelName(level Level) string {
+	switch {
+	case level <= LevelDebug:
+		return "debug"
+	case level <= LevelInfo:
+		return "info"
+	case level <= LevelWarn:
+		return "warn"
+	default:
+		return "error"
 	}
-
-	n, err = w.file.Write(p)
-	w.size += int64(n)
-	return n, err
-}
-
-func (w *rotatingWriter) Close() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	return w.file.Close()
 }
 
-func (w *rotatingWriter) rotate() error {
-	w.file.Close()
-
-	// Shift backups: .2 -> .3, .1 -> .2
-	for i := w.maxBackups - 1; i > 0; i-- {
-		oldPath := fmt.Sprintf("%s.%d", w.filename, i)
-		newPath := fmt.Sprintf("%s.%d", w.filename, i+1)
-		os.Remove(newPath) // Remove oldest if exists
-		os.Rename(oldPath, newPath)
-	}
-	os.Remove(w.filename + ".1")
-	os.Rename(w.filename, w.filename+".1")
+// Стандартные ключи полей, общие для всего приложения
+const (
+	KeyModule    = "module"     // подсистема или плагин
+	KeyUser      = "user"       // пользователь, выполнивший запрос
+	KeyRequestID = "request_id" // ID HTTP-запроса
+	KeyError     = "error"
+)
 
-	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return err
-	}
-	w.file = f
-	w.size = 0
-	return nil
+// Logger - структурированный логгер (slog) с записью в файлы и ротацией.
+// Все сообщения от текущего уровня пишутся в stdout, app.log и системный
+// журнал (см. Sinks), предупреждения и ошибки дополнительно в error.log
+// с указанием источника.
+type Logger struct {
+	handler slog.Handler
+	core    *core
 }
 
-// Logger - кастомный логгер с поддержкой записи в файлы и ротацией
-type Logger struct {
-	infoLogger  *log.Logger
-	errorLogger *log.Logger
+// core - общие для логгера и его потомков (With) уровень, файлы и журналы
+type core struct {
+	level       *slog.LevelVar
 	logDir      string
 	appWriter   *rotatingWriter
 	errorWriter *rotatingWriter
-	mu          sync.Mutex
+	closers     []io.Closer // соединения с системным журналом
+	tail        tail        // подписчики новых записей (Subscribe)
 }
 
 // New создает новый логгер с указанной директорией для логов
+// и текстовым выводом в консоль и файлы
 func New(logDir string, maxSizeMB, maxBackups int) (*Logger, error) {
+	return NewWithSinks(logDir, maxSizeMB, maxBackups, DefaultSinks)
+}
+
+// NewWithSinks создает новый логгер с указанной директорией для логов
+// и форматами вывода sinks
+func NewWithSinks(logDir string, maxSizeMB, maxBackups int, sinks Sinks) (*Logger, error) {
+	if err := sinks.validate(); err != nil {
+		return nil, err
+	}
 	if maxSizeMB <= 0 {
 		maxSizeMB = defaultMaxSize / (1024 * 1024)
 	}
@@ -130,34 +125,54 @@ func New(logDir string, maxSizeMB, maxBackups int) (*Logger, error) {
 		return nil, fmt.Errorf("failed to open error.log: %w", err)
 	}
 
-	// Создаем MultiWriter для дублирования вывода в консоль и файл
-	appOut := io.MultiWriter(os.Stdout, appWriter)
-	errorOut := io.MultiWriter(os.Stderr, errorWriter)
-
-	logger := &Logger{
-		infoLogger:  log.New(appOut, "", log.LstdFlags),
-		errorLogger: log.New(errorOut, "ERROR: ", log.LstdFlags|log.Lshortfile),
+	c := &core{
+		level:       new(slog.LevelVar),
 		logDir:      logDir,
 		appWriter:   appWriter,
 		errorWriter: errorWriter,
 	}
+	c.level.Set(LevelInfo)
+
+	// Вывод в консоль и app.log, ошибки - в error.log, плюс системный журнал
+	handler := fanoutHandler{
+		newFormatHandler(sinks.File, appWriter, &slog.HandlerOptions{Level: c.level}),
+		newFormatHandler(sinks.File, errorWriter, &slog.HandlerOptions{Level: LevelWarn, AddSource: true}),
+		&tailHandler{tail: &c.tail, level: c.level},
+	}
+	if sinks.Console != FormatNone {
+		handler = append(handler, newFormatHandler(sinks.Console, os.Stdout, &slog.HandlerOptions{Level: c.level}))
+	}
+	if sinks.System != "" {
+		systemHandler, closer, err := newSystemHandler(sinks, c.level)
+		if err != nil {
+			appWriter.Close()
+			errorWriter.Close()
+			return nil, fmt.Errorf("failed to connect to %s: %w", sinks.System, err)
+		}
+		handler = append(handler, systemHandler)
+		c.closers = append(c.closers, closer)
+	}
 
-	logger.Printf("Logger initialized (max size: %d MB, max backups: %d)", maxSizeMB, maxBackups)
+	logger := &Logger{handler: handler, core: c}
+	logger.Info("Logger initialized", "max_size_mb", maxSizeMB, "max_backups", maxBackups,
+		"console", sinks.Console, "file", sinks.File, "system", sinks.System)
 	return logger, nil
 }
 
-// Close закрывает файлы логов
+// Close закрывает файлы логов и соединения с системным журналом
 func (l *Logger) Close() error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
 	var errs []error
-	if err := l.appWriter.Close(); err != nil {
+	if err := l.core.appWriter.Close(); err != nil {
 		errs = append(errs, err)
 	}
-	if err := l.errorWriter.Close(); err != nil {
+	if err := l.core.errorWriter.Close(); err != nil {
 		errs = append(errs, err)
 	}
+	for _, closer := range l.core.closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 
 	if len(errs) > 0 {
 		return fmt.Errorf("errors closing log files: %v", errs)
@@ -165,73 +180,208 @@ func (l *Logger) Close() error {
 	return nil
 }
 
-// Printf пишет форматированное сообщение в app.log
+// SetRetention задает хранение ротированных файлов (для app.log и error.log
+// отдельно): maxAge - возраст (0 = без ограничения, иначе файлы также
+// ротируются раз в сутки), maxTotalMB - суммарный размер (0 = без ограничения),
+// compress - сжатие gzip
+func (l *Logger) SetRetention(maxAge time.Duration, maxTotalMB int, compress bool) {
+	maxTotal := int64(maxTotalMB) * 1024 * 1024
+	l.core.appWriter.setRetention(maxAge, maxTotal, compress)
+	l.core.errorWriter.setRetention(maxAge, maxTotal, compress)
+}
+
+// SetLevel устанавливает минимальный уровень сообщений, попадающих в лог.
+// Действует на логгер и все его потомки. Ошибки пишутся всегда.
+func (l *Logger) SetLevel(level Level) {
+	l.core.level.Set(min(level, LevelError))
+}
+
+// Level возвращает текущий уровень логирования
+func (l *Logger) Level() Level {
+	return l.core.level.Level()
+}
+
+// With возвращает логгер, добавляющий поля ключ-значение к каждому сообщению.
+// Для nil-логгера возвращает nil (сообщения nil-логгера отбрасываются).
+func (l *Logger) With(args ...any) *Logger {
+	if l == nil {
+		return nil
+	}
+	return &Logger{handler: slog.New(l.handler).With(args...).Handler(), core: l.core}
+}
+
+// Module возвращает логгер подсистемы (поле module)
+func (l *Logger) Module(name string) *Logger {
+	return l.With(KeyModule, name)
+}
+
+// Slog возвращает *slog.Logger для библиотек, принимающих slog
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(l.handler)
+}
+
+// log пишет сообщение, указывая источником вызывающего exported-метод
+func (l *Logger) log(level Level, msg string, args ...any) {
+	ctx := context.Background()
+	if l == nil || !l.handler.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // runtime.Callers, log, exported-метод
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.handler.Handle(ctx, r)
+}
+
+// Debug пишет отладочное сообщение с полями ключ-значение
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log(LevelDebug, msg, args...)
+}
+
+// Info пишет информационное сообщение с полями ключ-значение
+func (l *Logger) Info(msg string, args ...any) {
+	l.log(LevelInfo, msg, args...)
+}
+
+// Warn пишет предупреждение с полями ключ-значение
+func (l *Logger) Warn(msg string, args ...any) {
+	l.log(LevelWarn, msg, args...)
+}
+
+// Error пишет сообщение об ошибке с полями ключ-значение
+func (l *Logger) Error(msg string, args ...any) {
+	l.log(LevelError, msg, args...)
+}
+
+// Debugf пишет форматированное отладочное сообщение
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.log(LevelDebug, fmt.Sprintf(format, v...))
+}
+
+// Printf пишет форматированное информационное сообщение
 func (l *Logger) Printf(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.infoLogger.Printf(format, v...)
+	l.log(LevelInfo, fmt.Sprintf(format, v...))
 }
 
-// Print пишет сообщение в app.log
+// Print пишет информационное сообщение
 func (l *Logger) Print(v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.infoLogger.Print(v...)
+	l.log(LevelInfo, fmt.Sprint(v...))
 }
 
-// Println пишет сообщение с новой строкой в app.log
+// Println пишет информационное сообщение
 func (l *Logger) Println(v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.infoLogger.Println(v...)
+	l.log(LevelInfo, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
 }
 
-// Errorf пишет форматированное сообщение об ошибке в error.log
-func (l *Logger) Errorf(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.errorLogger.Printf(format, v...)
+// Warnf пишет форматированное предупреждение
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.log(LevelWarn, fmt.Sprintf(format, v...))
 }
 
-// Error пишет сообщение об ошибке в error.log
-func (l *Logger) Error(v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.errorLogger.Print(v...)
+// Errorf пишет форматированное сообщение об ошибке
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.log(LevelError, fmt.Sprintf(format, v...))
 }
 
-// Errorln пишет сообщение об ошибке с новой строкой в error.log
+// Errorln пишет сообщение об ошибке
 func (l *Logger) Errorln(v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.errorLogger.Println(v...)
+	l.log(LevelError, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
 }
 
-// Fatal пишет сообщение об ошибке в error.log и завершает программу
-func (l *Logger) Fatal(v ...interface{}) {
-	l.mu.Lock()
-	l.errorLogger.Print(v...)
-	l.mu.Unlock()
+// Fatalf пишет форматированное сообщение об ошибке и завершает программу
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.log(LevelError, fmt.Sprintf(format, v...))
 	os.Exit(1)
 }
 
-// Fatalf пишет форматированное сообщение об ошибке в error.log и завершает программу
-func (l *Logger) Fatalf(format string, v ...interface{}) {
-	l.mu.Lock()
-	l.errorLogger.Printf(format, v...)
-	l.mu.Unlock()
-	os.Exit(1)
+// Writer возвращает io.Writer, передающий строки стандартного log в логгер
+// (см. StdLogger)
+func (l *Logger) Writer() io.Writer {
+	return &stdWriter{logger: l}
 }
 
-// Fatalln пишет сообщение об ошибке с новой строкой в error.log и завершает программу
-func (l *Logger) Fatalln(v ...interface{}) {
-	l.mu.Lock()
-	l.errorLogger.Println(v...)
-	l.mu.Unlock()
-	os.Exit(1)
+// StdLogger возвращает *log.Logger для кода, использующего стандартный log
+// (плагины, net/http). Префикс "[name] " становится полем module, сообщения,
+// начинающиеся с "ERROR"/"Failed" и "Warning", получают уровни error и warn.
+func (l *Logger) StdLogger() *log.Logger {
+	return log.New(l.Writer(), "", 0)
 }
 
-// Writer возвращает io.Writer для записи в app.log
-func (l *Logger) Writer() io.Writer {
-	return l.infoLogger.Writer()
+// stdWriter разбирает строки стандартного log
+type stdWriter struct {
+	logger *Logger
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.write(line)
+	}
+	return len(p), nil
+}
+
+func (w *stdWriter) write(line string) {
+	l := w.logger
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "] "); end > 1 {
+			l = l.Module(line[1:end])
+			line = line[end+2:]
+		}
+	}
+
+	level := LevelInfo
+	switch {
+	case strings.HasPrefix(line, "ERROR"), strings.HasPrefix(line, "Failed"):
+		level = LevelError
+	case strings.HasPrefix(strings.ToLower(line), "warning"):
+		level = LevelWarn
+	}
+	for _, prefix := range []string{"ERROR: ", "Warning: ", "WARNING: "} {
+		line = strings.TrimPrefix(line, prefix)
+	}
+
+	ctx := context.Background()
+	if l.handler.Enabled(ctx, level) {
+		_ = l.handler.Handle(ctx, slog.NewRecord(time.Now(), level, line, 0))
+	}
+}
+
+// fanoutHandler передает сообщения нескольким обработчикам
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, handler := range h {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
 }
@@ -1,10 +1,16 @@
 package logger
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -103,3 +109,266 @@ func TestRotation(t *testing.T) {
 		t.Errorf("Expected test.log.4 to not exist (maxBackups=3)")
 	}
 }
+
+func TestRotationRetention(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.log")
+
+	w, err := newRotatingWriter(path, 100, 3)
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer: %v", err)
+	}
+	defer w.Close()
+	w.setRetention(0, 0, true)
+
+	data := []byte("this is a test log line that should trigger rotation when written multiple times\n")
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+
+	// Rotated files are compressed
+	for i := 1; i <= 3; i++ {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", path, i)); !os.IsNotExist(err) {
+			t.Errorf("Expected test.log.%d to be compressed", i)
+		}
+	}
+	f, err := os.Open(path + ".1.gz")
+	if err != nil {
+		t.Fatalf("Expected test.log.1.gz to exist: %v", err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read test.log.1.gz: %v", err)
+	}
+	content, _ := io.ReadAll(gz)
+	f.Close()
+	if string(content) != string(data) {
+		t.Errorf("Unexpected content of test.log.1.gz: %q", content)
+	}
+
+	// Backups older than maxAge are removed
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path+".3.gz", old, old); err != nil {
+		t.Fatalf("Failed to change mtime: %v", err)
+	}
+	w.setRetention(24*time.Hour, 0, true)
+	if _, err := os.Stat(path + ".3.gz"); !os.IsNotExist(err) {
+		t.Errorf("Expected test.log.3.gz to be removed (maxAge)")
+	}
+	if _, err := os.Stat(path + ".2.gz"); err != nil {
+		t.Errorf("Expected test.log.2.gz to be kept: %v", err)
+	}
+
+	// The newest backup is kept while it fits into maxTotal
+	info, _ := os.Stat(path + ".1.gz")
+	w.setRetention(0, info.Size(), true)
+	if _, err := os.Stat(path + ".1.gz"); err != nil {
+		t.Errorf("Expected test.log.1.gz to be kept: %v", err)
+	}
+	if _, err := os.Stat(path + ".2.gz"); !os.IsNotExist(err) {
+		t.Errorf("Expected test.log.2.gz to be removed (maxTotal)")
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tempDir := t.TempDir()
+
+	logger, err := New(tempDir, 10, 3)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Close()
+
+	appLogPath := filepath.Join(tempDir, "app.log")
+	before, _ := os.Stat(appLogPath)
+
+	// Debug is hidden at the default info level, info is hidden at error level
+	logger.Debugf("hidden debug")
+	logger.SetLevel(LevelError)
+	logger.Printf("hidden info")
+
+	after, _ := os.Stat(appLogPath)
+	if after.Size() != before.Size() {
+		t.Errorf("Expected no output below the log level, app.log grew by %d bytes", after.Size()-before.Size())
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown log level")
+	}
+}
+
+func TestStructured(t *testing.T) {
+	tempDir := t.TempDir()
+
+	logger, err := New(tempDir, 10, 3)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Close()
+
+	api := logger.Module("api").With(KeyRequestID, "req-1")
+	api.Info("Request handled", KeyUser, "alice")
+	api.Warn("Slow request", "duration", "2s")
+	logger.StdLogger().Printf("[temperature] Failed to read sensor: %v", os.ErrNotExist)
+
+	var nilLogger *Logger
+	nilLogger.Module("x").Info("discarded") // nil loggers discard
+
+	appLog, _ := os.ReadFile(filepath.Join(tempDir, "app.log"))
+	errorLog, _ := os.ReadFile(filepath.Join(tempDir, "error.log"))
+
+	for _, want := range []string{
+		`level=INFO msg="Request handled" module=api request_id=req-1 user=alice`,
+		`level=WARN msg="Slow request" module=api request_id=req-1 duration=2s`,
+		`level=ERROR msg="Failed to read sensor: file does not exist" module=temperature`,
+	} {
+		if !strings.Contains(string(appLog), want) {
+			t.Errorf("Expected app.log to contain %q, got:\n%s", want, appLog)
+		}
+	}
+
+	// Warnings and errors also go to error.log, with the caller
+	if strings.Contains(string(errorLog), "Request handled") {
+		t.Error("Expected info messages not to be in error.log")
+	}
+	if !strings.Contains(string(errorLog), "logger_test.go") || !strings.Contains(string(errorLog), "Slow request") {
+		t.Errorf("Expected the warning with its source in error.log, got:\n%s", errorLog)
+	}
+
+	if level, err := ParseLevel("warning"); err != nil || level != LevelWarn {
+		t.Errorf("Expected warning to parse as warn, got %v, %v", level, err)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if _, err := NewWithSinks(tempDir, 10, 3, Sinks{Console: "xml", File: FormatText}); err == nil {
+		t.Error("Expected an error for an unknown console format")
+	}
+
+	// journald: a datagram socket standing in for the journal
+	socket := filepath.Join(tempDir, "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets not available: %v", err)
+	}
+	defer conn.Close()
+	defer func(path string) { journalSocket = path }(journalSocket)
+	journalSocket = socket
+
+	logger, err := NewWithSinks(tempDir, 10, 3, Sinks{Console: FormatNone, File: FormatJSON, System: SystemJournald})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Close()
+
+	logger.Module("api").Warn("Slow request", "duration", "2s", "stack", "line 1\nline 2")
+
+	// app.log holds one JSON object per line
+	appLog, _ := os.ReadFile(filepath.Join(tempDir, "app.log"))
+	lines := strings.Split(strings.TrimSpace(string(appLog)), "\n")
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("Expected JSON lines in app.log, got:\n%s", appLog)
+	}
+	if entry["msg"] != "Slow request" || entry["level"] != "WARN" || entry["module"] != "api" || entry["duration"] != "2s" {
+		t.Errorf("Unexpected app.log entry: %v", entry)
+	}
+
+	// The first datagram is "Logger initialized", the second the warning
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var datagram string
+	for i := 0; i < 2; i++ {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Failed to read journal datagram: %v", err)
+		}
+		datagram = string(buf[:n])
+	}
+	for _, want := range []string{"MESSAGE=Slow request\n", "PRIORITY=4\n", "SYSLOG_IDENTIFIER=podmanview\n", "MODULE=api\n", "DURATION=2s\n", "CODE_FILE="} {
+		if !strings.Contains(datagram, want) {
+			t.Errorf("Expected journal entry to contain %q, got %q", want, datagram)
+		}
+	}
+	if !strings.Contains(datagram, "STACK\n\x0d\x00\x00\x00\x00\x00\x00\x00line 1\nline 2\n") {
+		t.Errorf("Expected multi-line field in binary form, got %q", datagram)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tempDir := t.TempDir()
+
+	logger, err := New(tempDir, 10, 3)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Close()
+	logger.SetRetention(0, 0, true)
+
+	entries, unsubscribe := logger.Subscribe(10)
+	defer unsubscribe()
+
+	api := logger.Module("api")
+	api.Info("Request handled", KeyUser, "alice")
+	api.Warn("Slow request", "path", "/api/containers json")
+
+	select {
+	case e := <-entries:
+		if e.Message != "Request handled" || e.Level != "info" || e.Fields[KeyModule] != "api" || e.Fields[KeyUser] != "alice" {
+			t.Errorf("Unexpected tailed entry: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a tailed entry")
+	}
+
+	// Older entries are read from compressed rotated files
+	if err := logger.core.appWriter.rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	api.Error("Request failed", KeyError, "timeout")
+
+	all, next, err := logger.Entries(Query{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to read entries: %v", err)
+	}
+	if next != 0 || len(all) != 4 {
+		t.Fatalf("Expected 4 entries on one page, got %d (next %d): %+v", len(all), next, all)
+	}
+	if all[0].Message != "Request failed" || all[3].Message != "Logger initialized" {
+		t.Errorf("Expected newest entries first, got %+v", all)
+	}
+	if all[1].Fields["path"] != "/api/containers json" {
+		t.Errorf("Expected quoted field values to be parsed, got %+v", all[1].Fields)
+	}
+
+	page, next, _ := logger.Entries(Query{Level: LevelWarn, Limit: 1})
+	if len(page) != 1 || page[0].Level != "error" || next != 1 {
+		t.Fatalf("Expected the error entry and a next page, got %+v (next %d)", page, next)
+	}
+	page, next, _ = logger.Entries(Query{Level: LevelWarn, Limit: 1, Skip: next})
+	if len(page) != 1 || page[0].Message != "Slow request" || next != 0 {
+		t.Errorf("Expected the warning on the last page, got %+v (next %d)", page, next)
+	}
+
+	found, _, _ := logger.Entries(Query{Search: "ALICE", Limit: 10})
+	if len(found) != 1 || found[0].Message != "Request handled" {
+		t.Errorf("Expected search to match field values, got %+v", found)
+	}
+	found, _, _ = logger.Entries(Query{From: time.Now().Add(time.Minute), Limit: 10})
+	if len(found) != 0 {
+		t.Errorf("Expected no entries after now, got %+v", found)
+	}
+
+	// JSON lines and foreign lines
+	e := ParseEntry(`{"time":"2026-01-01T12:00:00Z","level":"WARN","msg":"Slow","module":"api","status":502}`)
+	if e.Level != "warn" || e.Message != "Slow" || e.Fields["module"] != "api" || e.Fields["status"] != "502" || e.Time.IsZero() {
+		t.Errorf("Unexpected JSON entry: %+v", e)
+	}
+	if e := ParseEntry("2025/01/01 12:00:00 legacy line"); e.Message != "2025/01/01 12:00:00 legacy line" || e.Level != "info" {
+		t.Errorf("Unexpected legacy entry: %+v", e)
+	}
+}
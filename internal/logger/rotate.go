@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxSize    = 10 * 1024 * 1024 // 10 MB
+	defaultMaxBackups = 3
+
+	// rotateInterval - возраст текущего файла, после которого он ротируется,
+	// если задан maxAge (ежедневная ротация, чтобы старые записи удалялись)
+	rotateInterval = 24 * time.Hour
+)
+
+// rotatingWriter пишет в файл и автоматически ротирует при превышении maxSize
+// (и раз в сутки, если задан maxAge). Ротированные файлы: name.1 (новейший),
+// name.2, ..., со сжатием - name.1.gz, ...
+type rotatingWriter struct {
+	filename   string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration // возраст ротированных файлов, 0 = без ограничения
+	maxTotal   int64         // суммарный размер ротированных файлов, 0 = без ограничения
+	compress   bool          // сжимать ротированные файлы gzip
+	size       int64
+	opened     time.Time // начало записи в текущий файл
+	file       *os.File
+	mu         sync.Mutex
+}
+
+func newRotatingWriter(filename string, maxSize int64, maxBackups int) (*rotatingWriter, error) {
+	w := &rotatingWriter{
+		filename:   filename,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		opened:     time.Now(),
+	}
+	info, err := os.Stat(filename)
+	if err == nil {
+		w.size = info.Size()
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w.file = f
+	return w, nil
+}
+
+// setRetention задает ограничения хранения и удаляет лишние ротированные файлы
+func (w *rotatingWriter) setRetention(maxAge time.Duration, maxTotal int64, compress bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxAge = maxAge
+	w.maxTotal = maxTotal
+	w.compress = compress
+	w.cleanup()
+}
+
+func (w *rotatingWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	expired := w.maxAge > 0 && w.size > 0 && time.Since(w.opened) >= rotateInterval
+	if expired || w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// backupPath возвращает путь существующего ротированного файла номер i
+// (сжатого или нет), "" если его нет
+func (w *rotatingWriter) backupPath(i int) string {
+	for _, path := range []string{fmt.Sprintf("%s.%d", w.filename, i), fmt.Sprintf("%s.%d.gz", w.filename, i)} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func (w *rotatingWriter) rotate() error {
+	w.file.Close()
+
+	// Shift backups: .2 -> .3, .1 -> .2 (keeping the .gz extension)
+	if last := w.backupPath(w.maxBackups); last != "" {
+		os.Remove(last)
+	}
+	for i := w.maxBackups - 1; i > 0; i-- {
+		if oldPath := w.backupPath(i); oldPath != "" {
+			newPath := fmt.Sprintf("%s.%d", w.filename, i+1)
+			if strings.HasSuffix(oldPath, ".gz") {
+				newPath += ".gz"
+			}
+			os.Rename(oldPath, newPath)
+		}
+	}
+	if w.maxBackups > 0 {
+		os.Rename(w.filename, w.filename+".1")
+		if w.compress {
+			if err := compressFile(w.filename + ".1"); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to compress rotated log %s: %v\n", w.filename, err)
+			}
+		}
+	} else {
+		os.Remove(w.filename)
+	}
+
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.size = 0
+	w.opened = time.Now()
+
+	w.cleanup()
+	return nil
+}
+
+// cleanup удаляет ротированные файлы старше maxAge, затем самые старые,
+// пока их суммарный размер больше maxTotal
+func (w *rotatingWriter) cleanup() {
+	if w.maxAge <= 0 && w.maxTotal <= 0 {
+		return
+	}
+
+	type backup struct {
+		path  string
+		index int
+		info  os.FileInfo
+	}
+	matches, _ := filepath.Glob(w.filename + ".*")
+	var backups []backup
+	for _, path := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(path, w.filename+"."), ".gz")
+		index, err := strconv.Atoi(suffix)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			backups = append(backups, backup{path: path, index: index, info: info})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].index < backups[j].index })
+
+	var total int64
+	for _, b := range backups {
+		total += b.info.Size()
+		expired := w.maxAge > 0 && time.Since(b.info.ModTime()) > w.maxAge
+		if expired || (w.maxTotal > 0 && total > w.maxTotal) {
+			os.Remove(b.path)
+		}
+	}
+}
+
+// compressFile сжимает файл в path.gz и удаляет исходный
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	// Keep the modification time for maxAge
+	os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
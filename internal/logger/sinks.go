@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Форматы вывода
+const (
+	FormatText = "text" // key=value
+	FormatJSON = "json" // JSON lines для сборщиков логов
+	FormatNone = "none" // вывод отключен (только для консоли)
+)
+
+// Системные журналы
+const (
+	SystemJournald = "journald"
+	SystemSyslog   = "syslog"
+)
+
+// syslogIdentifier - имя приложения в системном журнале
+const syslogIdentifier = "podmanview"
+
+// journalSocket - сокет нативного протокола systemd-journald
+var journalSocket = "/run/systemd/journal/socket"
+
+// Sinks задает, куда и в каком формате пишутся логи
+type Sinks struct {
+	Console    string // формат вывода в stdout: text, json или none
+	File       string // формат app.log и error.log: text или json
+	System     string // системный журнал: journald, syslog или "" (отключен)
+	SyslogAddr string // адрес syslog ("udp://host:514"), пустой - локальный демон
+}
+
+// DefaultSinks - текстовый вывод в консоль и файлы
+var DefaultSinks = Sinks{Console: FormatText, File: FormatText}
+
+// validate проверяет форматы и журналы
+func (s Sinks) validate() error {
+	switch s.Console {
+	case FormatText, FormatJSON, FormatNone:
+	default:
+		return fmt.Errorf("unknown console log format: %s (expected text, json or none)", s.Console)
+	}
+	switch s.File {
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown file log format: %s (expected text or json)", s.File)
+	}
+	switch s.System {
+	case "", SystemJournald, SystemSyslog:
+	default:
+		return fmt.Errorf("unknown system log: %s (expected journald or syslog)", s.System)
+	}
+	return nil
+}
+
+// newFormatHandler создает обработчик, пишущий в w в формате format
+func newFormatHandler(format string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	if format == FormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// newSystemHandler подключается к системному журналу
+func newSystemHandler(sinks Sinks, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	if sinks.System == SystemSyslog {
+		return newSyslogHandler(sinks.SyslogAddr, level)
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &journaldHandler{conn: conn, level: level}, conn, nil
+}
+
+// priorityHandler передает сообщение одному из обработчиков по уровню:
+// debug, info, warn, error (для syslog, где уровень задается при записи)
+type priorityHandler struct {
+	level    slog.Leveler
+	handlers [4]slog.Handler
+}
+
+// priorityIndex возвращает номер обработчика для уровня
+func priorityIndex(level slog.Level) int {
+	switch {
+	case level < LevelInfo:
+		return 0
+	case level < LevelWarn:
+		return 1
+	case level < LevelError:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func (h *priorityHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *priorityHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handlers[priorityIndex(r.Level)].Handle(ctx, r)
+}
+
+func (h *priorityHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &priorityHandler{level: h.level}
+	for i, handler := range h.handlers {
+		next.handlers[i] = handler.WithAttrs(attrs)
+	}
+	return next
+}
+
+func (h *priorityHandler) WithGroup(name string) slog.Handler {
+	next := &priorityHandler{level: h.level}
+	for i, handler := range h.handlers {
+		next.handlers[i] = handler.WithGroup(name)
+	}
+	return next
+}
+
+// journaldField - поле записи journald
+type journaldField struct {
+	name  string
+	value string
+}
+
+// journaldHandler пишет сообщения в systemd-journald по нативному протоколу:
+// поля сообщения становятся полями журнала (module=http -> MODULE=http)
+type journaldHandler struct {
+	conn   *net.UnixConn
+	level  slog.Leveler
+	fields []journaldField // поля из With
+	prefix string          // префикс группы ("GROUP_")
+}
+
+func (h *journaldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *journaldHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "MESSAGE", r.Message)
+	writeJournaldField(&buf, "PRIORITY", strconv.Itoa(journaldPriority(r.Level)))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", syslogIdentifier)
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		writeJournaldField(&buf, "CODE_FILE", frame.File)
+		writeJournaldField(&buf, "CODE_LINE", strconv.Itoa(frame.Line))
+		writeJournaldField(&buf, "CODE_FUNC", frame.Function)
+	}
+	for _, f := range h.fields {
+		writeJournaldField(&buf, f.name, f.value)
+	}
+
+	var fields []journaldField
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendJournaldFields(fields, h.prefix, a)
+		return true
+	})
+	for _, f := range fields {
+		writeJournaldField(&buf, f.name, f.value)
+	}
+
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+func (h *journaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.fields = append([]journaldField(nil), h.fields...)
+	for _, a := range attrs {
+		next.fields = appendJournaldFields(next.fields, h.prefix, a)
+	}
+	return &next
+}
+
+func (h *journaldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + journaldFieldName(name) + "_"
+	return &next
+}
+
+// appendJournaldFields добавляет поля атрибута (группы раскрываются)
+func appendJournaldFields(fields []journaldField, prefix string, a slog.Attr) []journaldField {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += journaldFieldName(a.Key) + "_"
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendJournaldFields(fields, prefix, ga)
+		}
+		return fields
+	}
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	return append(fields, journaldField{name: prefix + journaldFieldName(a.Key), value: a.Value.String()})
+}
+
+// journaldFieldName приводит ключ к имени поля journald:
+// заглавные латинские буквы, цифры и "_", не начинается с "_" или цифры
+func journaldFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	result := strings.TrimLeft(string(name), "_")
+	if result == "" || result[0] >= '0' && result[0] <= '9' {
+		result = "F_" + result
+	}
+	return result
+}
+
+// writeJournaldField пишет поле; значения с переводом строки
+// пишутся в двоичном виде (имя, длина little-endian, значение)
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.Contains(value, "\n") {
+		buf.WriteByte('\n')
+		_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journaldPriority возвращает приоритет syslog для уровня
+func journaldPriority(level slog.Level) int {
+	return [...]int{7, 6, 4, 3}[priorityIndex(level)] // debug, info, warning, err
+}
@@ -0,0 +1,57 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"net/url"
+	"strings"
+)
+
+// newSyslogHandler подключается к syslog: addr - "udp://host:514",
+// "tcp://host:514" или пустой для локального демона. Поля сообщения
+// пишутся в тексте в виде key=value, время и уровень задает syslog.
+func newSyslogHandler(addr string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	var network, raddr string
+	if addr != "" {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" {
+			return nil, nil, fmt.Errorf("invalid syslog address: %s (expected udp://host:port or tcp://host:port)", addr)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, syslogIdentifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	handler := &priorityHandler{level: level}
+	for i, write := range []func(string) error{w.Debug, w.Info, w.Warning, w.Err} {
+		handler.handlers[i] = slog.NewTextHandler(syslogWriter(write), opts)
+	}
+	return handler, w, nil
+}
+
+// syslogWriter передает строку обработчика в syslog с заданным приоритетом
+type syslogWriter func(string) error
+
+func (f syslogWriter) Write(p []byte) (int, error) {
+	if err := f(strings.TrimSuffix(string(p), "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package logger
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// newSyslogHandler - syslog недоступен на этой платформе
+func newSyslogHandler(addr string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	return nil, nil, errors.New("syslog is not supported on this platform")
+}
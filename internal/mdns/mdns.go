@@ -0,0 +1,412 @@
+// Package mdns announces the web UI on the local network with multicast
+// DNS (RFC 6762) and DNS-SD (RFC 6763), so it can be opened as
+// <host>.local and shows up in service browsers without a DNS server.
+package mdns
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+
+	"podmanview/internal/logger"
+)
+
+const (
+	mdnsPort = 5353
+
+	// TTLs recommended by RFC 6762 section 10, in seconds
+	hostTTL    = 120  // records naming the host
+	serviceTTL = 4500 // other records
+
+	// maxPacket is the largest mDNS message, 9000 bytes including headers
+	maxPacket = 9000
+
+	// cacheFlush marks unique records in the class field, unicastResponse
+	// asks for a unicast answer in the class field of a question
+	cacheFlush      = 1 << 15
+	unicastResponse = 1 << 15
+)
+
+// servicesName enumerates the service types of a host (RFC 6763 section 9)
+const servicesName = "_services._dns-sd._udp.local."
+
+// DefaultHost is the host label used when a name has none
+const DefaultHost = "podmanview"
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+
+// Service is the announced web UI
+type Service struct {
+	Instance string   // friendly name shown by service browsers
+	Host     string   // host label, announced as <Host>.local
+	Port     int      // TCP port of the web UI
+	Path     string   // URL path of the web UI, announced in the TXT record
+	TLS      bool     // announce _https._tcp instead of _http._tcp
+	Addrs    []net.IP // announced addresses, nil for those of the interface
+}
+
+// serviceType returns the DNS-SD service type
+func (s Service) serviceType() string {
+	if s.TLS {
+		return "_https._tcp.local."
+	}
+	return "_http._tcp.local."
+}
+
+// instanceName returns the DNS-SD service instance name. Dots would split
+// the instance label, so they are dropped, and labels are limited to 63
+// bytes.
+func (s Service) instanceName() string {
+	instance := strings.TrimSpace(strings.ReplaceAll(s.Instance, ".", ""))
+	for len(instance) > 63 {
+		_, size := utf8.DecodeLastRuneInString(instance)
+		instance = instance[:len(instance)-size]
+	}
+	if instance == "" {
+		instance = DefaultHost
+	}
+	return instance + "." + s.serviceType()
+}
+
+// hostName returns the fully qualified host name
+func (s Service) hostName() string {
+	return s.Host + ".local."
+}
+
+// HostLabel derives a host label from a friendly name, e.g.
+// "My Server" becomes "my-server"
+func HostLabel(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	label := b.String()
+	if len(label) > 63 {
+		label = strings.TrimRight(label[:63], "-")
+	}
+	if label == "" {
+		return DefaultHost
+	}
+	return label
+}
+
+// Responder answers mDNS queries for a service. It doesn't probe for
+// name conflicts; pick a unique name when several instances share a
+// network.
+type Responder struct {
+	service Service
+	conn    *ipv4.PacketConn
+	ifaces  []net.Interface // interfaces the multicast group was joined on
+	logger  *logger.Logger
+	wg      sync.WaitGroup
+}
+
+// NewResponder creates a responder for a service without network access,
+// see Start
+func NewResponder(service Service) *Responder {
+	return &Responder{service: service}
+}
+
+// Start joins the mDNS group on all multicast interfaces, announces the
+// service and answers queries until Close. The port is shared with other
+// responders such as Avahi.
+func Start(service Service, appLogger *logger.Logger) (*Responder, error) {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return nil, err
+	}
+	r := NewResponder(service)
+	r.logger = appLogger
+	r.conn = ipv4.NewPacketConn(conn)
+	if err := r.conn.SetControlMessage(ipv4.FlagInterface, true); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r.conn.SetMulticastLoopback(true)
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		// ListenMulticastUDP already joined the default interface
+		r.conn.JoinGroup(&iface, mdnsGroup)
+		r.ifaces = append(r.ifaces, iface)
+	}
+	if len(r.ifaces) == 0 {
+		conn.Close()
+		return nil, errors.New("no multicast network interface")
+	}
+
+	r.wg.Add(2)
+	go r.serve()
+	go func() {
+		defer r.wg.Done()
+		// Unsolicited announcements are repeated after a second
+		// (RFC 6762 section 8.3)
+		for i := 0; i < 2; i++ {
+			if i > 0 {
+				time.Sleep(time.Second)
+			}
+			if !r.announce(false) {
+				return
+			}
+		}
+	}()
+	return r, nil
+}
+
+// Close says goodbye, so browsers drop the service at once, and stops
+// answering queries
+func (r *Responder) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	r.announce(true)
+	err := r.conn.Close()
+	r.wg.Wait()
+	return err
+}
+
+// serve answers queries until the connection is closed
+func (r *Responder) serve() {
+	defer r.wg.Done()
+	buf := make([]byte, maxPacket)
+	for {
+		n, cm, src, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				r.logger.Warn("mDNS read failed", logger.KeyError, err)
+			}
+			return
+		}
+		ifIndex := 0
+		if cm != nil {
+			ifIndex = cm.IfIndex
+		}
+		addrs := r.service.Addrs
+		if addrs == nil {
+			addrs = interfaceAddrs(ifIndex)
+		}
+		udpSrc, _ := src.(*net.UDPAddr)
+		legacy := udpSrc != nil && udpSrc.Port != mdnsPort
+		reply, unicast, err := r.Reply(buf[:n], addrs, legacy)
+		if err != nil || reply == nil {
+			continue
+		}
+		dst := net.Addr(mdnsGroup)
+		if unicast {
+			dst = src
+		}
+		if _, err := r.conn.WriteTo(reply, &ipv4.ControlMessage{IfIndex: ifIndex}, dst); err != nil {
+			r.logger.Debug("mDNS reply failed", logger.KeyError, err)
+		}
+	}
+}
+
+// announce sends all records on every interface, with a zero TTL for a
+// goodbye. It returns false once the connection is closed.
+func (r *Responder) announce(goodbye bool) bool {
+	for _, iface := range r.ifaces {
+		addrs := r.service.Addrs
+		if addrs == nil {
+			addrs = interfaceAddrs(iface.Index)
+		}
+		msg, err := r.Announcement(addrs, goodbye)
+		if err != nil {
+			r.logger.Warn("Failed to build mDNS announcement", logger.KeyError, err)
+			return false
+		}
+		if _, err := r.conn.WriteTo(msg, &ipv4.ControlMessage{IfIndex: iface.Index}, mdnsGroup); err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return false
+			}
+			r.logger.Debug("mDNS announcement failed", "interface", iface.Name, logger.KeyError, err)
+		}
+	}
+	return true
+}
+
+// Announcement builds an unsolicited response with all records of the
+// service, with a zero TTL for a goodbye
+func (r *Responder) Announcement(addrs []net.IP, goodbye bool) ([]byte, error) {
+	b := newBuilder(dnsmessage.Header{Response: true, Authoritative: true})
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	rs := r.records(addrs)
+	for _, rr := range []recordSet{rs.services, rs.ptr, rs.srv, rs.txt, rs.addrs} {
+		if goodbye {
+			rr = rr.withTTL(0)
+		}
+		if err := rr.add(&b); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// Reply builds the answer to a query packet, with addrs as the host
+// addresses; legacy queries come from a plain resolver, not from port
+// 5353. It returns nil if no question is about the service, and whether
+// the answer goes to the sender instead of the group.
+func (r *Responder) Reply(query []byte, addrs []net.IP, legacy bool) ([]byte, bool, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return nil, false, err
+	}
+	if header.Response || header.OpCode != 0 || header.RCode != dnsmessage.RCodeSuccess {
+		return nil, false, nil
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, false, err
+	}
+
+	rs := r.records(addrs)
+	var answers, additionals []recordSet
+	unicast := true
+	for _, q := range questions {
+		if q.Class&unicastResponse == 0 {
+			unicast = false
+		}
+		name := strings.ToLower(q.Name.String())
+		wants := func(t dnsmessage.Type) bool { return q.Type == t || q.Type == dnsmessage.TypeALL }
+		switch name {
+		case servicesName:
+			if wants(dnsmessage.TypePTR) {
+				answers = append(answers, rs.services)
+			}
+		case r.service.serviceType():
+			if wants(dnsmessage.TypePTR) {
+				answers = append(answers, rs.ptr)
+				additionals = append(additionals, rs.srv, rs.txt, rs.addrs)
+			}
+		case strings.ToLower(r.service.instanceName()):
+			if wants(dnsmessage.TypeSRV) {
+				answers = append(answers, rs.srv)
+				additionals = append(additionals, rs.addrs)
+			}
+			if wants(dnsmessage.TypeTXT) {
+				answers = append(answers, rs.txt)
+			}
+		case strings.ToLower(r.service.hostName()):
+			// The other address family goes along (RFC 6762 section 6.2)
+			for _, t := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+				if wants(t) {
+					answers = append(answers, rs.addrs.ofType(t))
+					additionals = append(additionals, rs.addrs)
+				}
+			}
+		}
+	}
+	if len(slices.Concat(answers...)) == 0 {
+		return nil, false, nil
+	}
+
+	// Legacy resolvers expect a conventional DNS answer: same ID, the
+	// questions echoed and short TTLs (RFC 6762 section 6.7)
+	h := dnsmessage.Header{Response: true, Authoritative: true}
+	if legacy {
+		h.ID = header.ID
+	}
+	b := newBuilder(h)
+	if legacy {
+		if err := b.StartQuestions(); err != nil {
+			return nil, false, err
+		}
+		for _, q := range questions {
+			q.Class &^= unicastResponse
+			if err := b.Question(q); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, false, err
+	}
+	added := make(map[string]bool)
+	for _, rr := range answers {
+		if err := rr.addOnce(&b, added, legacy); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, false, err
+	}
+	for _, rr := range additionals {
+		if err := rr.addOnce(&b, added, legacy); err != nil {
+			return nil, false, err
+		}
+	}
+	reply, err := b.Finish()
+	return reply, unicast || legacy, err
+}
+
+// newBuilder returns a message builder with name compression
+func newBuilder(h dnsmessage.Header) dnsmessage.Builder {
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), h)
+	b.EnableCompression()
+	return b
+}
+
+// interfaceAddrs returns the addresses of an interface, or of all up
+// interfaces except loopback for index 0. Link-local IPv6 addresses are
+// left out, they don't work without a zone.
+func interfaceAddrs(index int) []net.IP {
+	var ifaces []net.Interface
+	if index > 0 {
+		iface, err := net.InterfaceByIndex(index)
+		if err != nil {
+			return nil
+		}
+		ifaces = []net.Interface{*iface}
+	} else {
+		all, err := net.Interfaces()
+		if err != nil {
+			return nil
+		}
+		for _, iface := range all {
+			if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 {
+				ifaces = append(ifaces, iface)
+			}
+		}
+	}
+
+	var ips []net.IP
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if ok && !ipnet.IP.IsLinkLocalUnicast() {
+				ips = append(ips, ipnet.IP)
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,142 @@
+package mdns
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// legacyTTL caps the TTLs of answers to legacy resolvers (RFC 6762 section 6.7)
+const legacyTTL = 10
+
+// recordSet is a set of resource records of one name and type
+type recordSet []dnsmessage.Resource
+
+// serviceRecords are the records of a service
+type serviceRecords struct {
+	services recordSet // _services._dns-sd._udp PTR service type
+	ptr      recordSet // service type PTR instance
+	srv      recordSet // instance SRV host and port
+	txt      recordSet // instance TXT path
+	addrs    recordSet // host A and AAAA
+}
+
+// records returns the records of the service with addrs as the host
+// addresses
+func (r *Responder) records(addrs []net.IP) serviceRecords {
+	s := r.service
+	serviceType := dnsmessage.MustNewName(s.serviceType())
+	instance := dnsmessage.MustNewName(s.instanceName())
+	host := dnsmessage.MustNewName(s.hostName())
+	header := func(name dnsmessage.Name, t dnsmessage.Type, ttl uint32, unique bool) dnsmessage.ResourceHeader {
+		class := dnsmessage.ClassINET
+		if unique {
+			class |= cacheFlush
+		}
+		return dnsmessage.ResourceHeader{Name: name, Type: t, Class: class, TTL: ttl}
+	}
+
+	path := s.Path
+	if path == "" {
+		path = "/"
+	}
+	rs := serviceRecords{
+		services: recordSet{{
+			Header: header(dnsmessage.MustNewName(servicesName), dnsmessage.TypePTR, serviceTTL, false),
+			Body:   &dnsmessage.PTRResource{PTR: serviceType},
+		}},
+		ptr: recordSet{{
+			Header: header(serviceType, dnsmessage.TypePTR, serviceTTL, false),
+			Body:   &dnsmessage.PTRResource{PTR: instance},
+		}},
+		srv: recordSet{{
+			Header: header(instance, dnsmessage.TypeSRV, hostTTL, true),
+			Body:   &dnsmessage.SRVResource{Port: uint16(s.Port), Target: host},
+		}},
+		txt: recordSet{{
+			Header: header(instance, dnsmessage.TypeTXT, serviceTTL, true),
+			Body:   &dnsmessage.TXTResource{TXT: []string{"path=" + path}},
+		}},
+	}
+	for _, ip := range addrs {
+		if v4 := ip.To4(); v4 != nil {
+			rs.addrs = append(rs.addrs, dnsmessage.Resource{
+				Header: header(host, dnsmessage.TypeA, hostTTL, true),
+				Body:   &dnsmessage.AResource{A: [4]byte(v4)},
+			})
+		} else if v6 := ip.To16(); v6 != nil {
+			rs.addrs = append(rs.addrs, dnsmessage.Resource{
+				Header: header(host, dnsmessage.TypeAAAA, hostTTL, true),
+				Body:   &dnsmessage.AAAAResource{AAAA: [16]byte(v6)},
+			})
+		}
+	}
+	return rs
+}
+
+// withTTL returns a copy of the records with another TTL
+func (rs recordSet) withTTL(ttl uint32) recordSet {
+	result := make(recordSet, len(rs))
+	for i, rr := range rs {
+		rr.Header.TTL = ttl
+		result[i] = rr
+	}
+	return result
+}
+
+// ofType returns the records of type t
+func (rs recordSet) ofType(t dnsmessage.Type) recordSet {
+	var result recordSet
+	for _, rr := range rs {
+		if rr.Header.Type == t {
+			result = append(result, rr)
+		}
+	}
+	return result
+}
+
+// add adds the records to the current section of a message
+func (rs recordSet) add(b *dnsmessage.Builder) error {
+	for _, rr := range rs {
+		var err error
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			err = b.PTRResource(rr.Header, *body)
+		case *dnsmessage.SRVResource:
+			err = b.SRVResource(rr.Header, *body)
+		case *dnsmessage.TXTResource:
+			err = b.TXTResource(rr.Header, *body)
+		case *dnsmessage.AResource:
+			err = b.AResource(rr.Header, *body)
+		case *dnsmessage.AAAAResource:
+			err = b.AAAAResource(rr.Header, *body)
+		default:
+			err = fmt.Errorf("unsupported record type %s", rr.Header.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addOnce adds the records not in added yet. Answers to legacy resolvers
+// have short TTLs and no cache-flush bit.
+func (rs recordSet) addOnce(b *dnsmessage.Builder, added map[string]bool, legacy bool) error {
+	for _, rr := range rs {
+		key := rr.Header.Name.String() + " " + rr.Header.Type.String() + " " + rr.Body.GoString()
+		if added[key] {
+			continue
+		}
+		added[key] = true
+		if legacy {
+			rr.Header.Class &^= cacheFlush
+			rr.Header.TTL = min(rr.Header.TTL, legacyTTL)
+		}
+		if err := (recordSet{rr}).add(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
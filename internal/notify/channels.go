@@ -0,0 +1,289 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"podmanview/internal/events"
+)
+
+const (
+	// sendTimeout limits sending a single notification
+	sendTimeout = 15 * time.Second
+
+	// subscribeBuffer is the number of events queued for notification
+	subscribeBuffer = 256
+
+	// EventTest is the type of the event sent by Test
+	EventTest events.EventType = "notification_test"
+)
+
+// Channel types
+const (
+	TypeTelegram = "telegram"
+	TypeDiscord  = "discord"
+	TypeSlack    = "slack"
+	TypeEmail    = "email"
+	TypeGotify   = "gotify"
+	TypeNtfy     = "ntfy"
+	TypePushover = "pushover"
+)
+
+// Setting describes a channel setting
+type Setting struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Required bool   `json:"required"`
+	Secret   bool   `json:"secret"` // masked in API responses
+}
+
+// TypeInfo describes a channel type and its settings
+type TypeInfo struct {
+	Type     string    `json:"type"`
+	Label    string    `json:"label"`
+	Settings []Setting `json:"settings"`
+
+	send func(ctx context.Context, settings map[string]string, msg Message) error
+}
+
+// channelTypes are the supported channel types by name
+var channelTypes = map[string]*TypeInfo{
+	TypeTelegram: {
+		Label: "Telegram bot",
+		Settings: []Setting{
+			{Key: "token", Label: "Bot token", Required: true, Secret: true},
+			{Key: "chatId", Label: "Chat ID", Required: true},
+		},
+		send: sendTelegram,
+	},
+	TypeDiscord: {
+		Label: "Discord webhook",
+		Settings: []Setting{
+			{Key: "url", Label: "Webhook URL", Required: true, Secret: true},
+		},
+		send: sendDiscord,
+	},
+	TypeSlack: {
+		Label: "Slack webhook",
+		Settings: []Setting{
+			{Key: "url", Label: "Webhook URL", Required: true, Secret: true},
+		},
+		send: sendSlack,
+	},
+	TypeEmail: {
+		Label: "Email (SMTP)",
+		Settings: []Setting{
+			{Key: "host", Label: "SMTP host", Required: true},
+			{Key: "port", Label: "SMTP port (default 587)"},
+			{Key: "username", Label: "Username"},
+			{Key: "password", Label: "Password", Secret: true},
+			{Key: "from", Label: "From address", Required: true},
+			{Key: "to", Label: "To addresses (comma-separated)", Required: true},
+		},
+		send: sendEmail,
+	},
+	TypeGotify: {
+		Label: "Gotify",
+		Settings: []Setting{
+			{Key: "url", Label: "Server URL", Required: true},
+			{Key: "token", Label: "Application token", Required: true, Secret: true},
+			{Key: "priority", Label: "Priority (default 5)"},
+		},
+		send: sendGotify,
+	},
+	TypeNtfy: {
+		Label: "ntfy",
+		Settings: []Setting{
+			{Key: "url", Label: "Topic URL (e.g. https://ntfy.sh/mytopic)", Required: true},
+			{Key: "token", Label: "Access token", Secret: true},
+			{Key: "priority", Label: "Priority (1-5)"},
+		},
+		send: sendNtfy,
+	},
+	TypePushover: {
+		Label: "Pushover",
+		Settings: []Setting{
+			{Key: "token", Label: "Application token", Required: true, Secret: true},
+			{Key: "user", Label: "User key", Required: true, Secret: true},
+		},
+		send: sendPushover,
+	},
+}
+
+// Types returns the supported channel types, sorted by name
+func Types() []TypeInfo {
+	list := make([]TypeInfo, 0, len(channelTypes))
+	for name, info := range channelTypes {
+		t := *info
+		t.Type = name
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return list
+}
+
+// isSecretSetting reports whether a setting of a channel type is masked
+func isSecretSetting(channelType, key string) bool {
+	info, ok := channelTypes[channelType]
+	if !ok {
+		return true
+	}
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s.Secret
+		}
+	}
+	return true
+}
+
+// httpClient sends notifications over HTTP
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// postJSON posts a JSON body and checks the response status
+func postJSON(ctx context.Context, endpoint string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(req)
+}
+
+// doRequest sends a request and returns an error for a non-2xx response
+func doRequest(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sendTelegram sends a message with the Telegram Bot API
+func sendTelegram(ctx context.Context, s map[string]string, msg Message) error {
+	endpoint := "https://api.telegram.org/bot" + s["token"] + "/sendMessage"
+	return postJSON(ctx, endpoint, map[string]string{
+		"chat_id": s["chatId"],
+		"text":    msg.Title + "\n\n" + msg.Body,
+	})
+}
+
+// sendDiscord posts a message to a Discord webhook
+func sendDiscord(ctx context.Context, s map[string]string, msg Message) error {
+	return postJSON(ctx, s["url"], map[string]string{
+		"content": "**" + msg.Title + "**\n" + msg.Body,
+	})
+}
+
+// sendSlack posts a message to a Slack incoming webhook
+func sendSlack(ctx context.Context, s map[string]string, msg Message) error {
+	return postJSON(ctx, s["url"], map[string]string{
+		"text": "*" + msg.Title + "*\n" + msg.Body,
+	})
+}
+
+// sendGotify posts a message to a Gotify server
+func sendGotify(ctx context.Context, s map[string]string, msg Message) error {
+	priority := 5
+	if p := s["priority"]; p != "" {
+		fmt.Sscanf(p, "%d", &priority)
+	}
+	endpoint := strings.TrimRight(s["url"], "/") + "/message?token=" + url.QueryEscape(s["token"])
+	return postJSON(ctx, endpoint, map[string]interface{}{
+		"title":    msg.Title,
+		"message":  msg.Body,
+		"priority": priority,
+	})
+}
+
+// sendNtfy publishes a message to an ntfy topic
+func sendNtfy(ctx context.Context, s map[string]string, msg Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s["url"], strings.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", msg.Title)
+	if p := s["priority"]; p != "" {
+		req.Header.Set("Priority", p)
+	}
+	if token := s["token"]; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return doRequest(req)
+}
+
+// sendPushover sends a message with the Pushover API
+func sendPushover(ctx context.Context, s map[string]string, msg Message) error {
+	form := url.Values{
+		"token":   {s["token"]},
+		"user":    {s["user"]},
+		"title":   {msg.Title},
+		"message": {msg.Body},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.pushover.net/1/messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doRequest(req)
+}
+
+// sendEmail sends a message over SMTP (STARTTLS when the server supports it)
+func sendEmail(ctx context.Context, s map[string]string, msg Message) error {
+	port := s["port"]
+	if port == "" {
+		port = "587"
+	}
+	addr := net.JoinHostPort(s["host"], port)
+
+	var to []string
+	for _, rcpt := range strings.Split(s["to"], ",") {
+		if rcpt = strings.TrimSpace(rcpt); rcpt != "" {
+			to = append(to, rcpt)
+		}
+	}
+
+	var auth smtp.Auth
+	if s["username"] != "" {
+		auth = smtp.PlainAuth("", s["username"], s["password"], s["host"])
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", s["from"])
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Title)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	// smtp.SendMail has no context, run it so a hung server doesn't block the caller
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, s["from"], to, body.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
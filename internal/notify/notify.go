@@ -0,0 +1,414 @@
+// Package notify sends alert-class events to notification channels
+// (Telegram, Discord, Slack, email, Gotify, ntfy, Pushover)
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+	"podmanview/internal/storage"
+)
+
+// bucket is the storage namespace of channels (encrypted at rest, see storage.DefaultSensitiveBuckets)
+const bucket = "notifications"
+
+// MaskedValue replaces secret settings in API responses.
+// Saving it back keeps the stored value.
+const MaskedValue = "[set]"
+
+// DefaultEvents are the alert-class event types routed to a channel
+// that doesn't select its own
+var DefaultEvents = []string{
+	string(events.EventContainerDied),
+	string(events.EventContainerCrash),
+	string(events.EventTempThreshold),
+	string(events.EventDiskFull),
+	string(events.EventRaidDegraded),
+	string(events.EventMonitorDown),
+	string(events.EventLoginFailed),
+	string(events.EventRegistryToken),
+}
+
+var (
+	// ErrNotFound is returned for an unknown channel ID
+	ErrNotFound = errors.New("notification channel not found")
+
+	// ErrInvalid is returned by Validate for an invalid channel
+	ErrInvalid = errors.New("invalid notification channel")
+)
+
+// Message is a notification sent to a channel
+type Message struct {
+	Title string
+	Body  string
+	Event events.Event
+}
+
+// Channel is a configured notification destination
+type Channel struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"` // see Types
+	Enabled  bool              `json:"enabled"`
+	Events   []string          `json:"events"` // event type prefixes, empty = DefaultEvents
+	Settings map[string]string `json:"settings"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Matches reports whether the channel receives events of type t
+func (c *Channel) Matches(t events.EventType) bool {
+	filters := c.Events
+	if len(filters) == 0 {
+		filters = DefaultEvents
+	}
+	for _, prefix := range filters {
+		if strings.HasPrefix(string(t), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the channel type and its required settings
+func (c *Channel) Validate() error {
+	def, ok := channelTypes[c.Type]
+	if !ok {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalid, c.Type)
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		c.Name = def.Label
+	}
+	if c.Settings == nil {
+		c.Settings = make(map[string]string)
+	}
+
+	known := make(map[string]bool)
+	for _, s := range def.Settings {
+		known[s.Key] = true
+		c.Settings[s.Key] = strings.TrimSpace(c.Settings[s.Key])
+		if s.Required && c.Settings[s.Key] == "" {
+			return fmt.Errorf("%w: %s requires %s", ErrInvalid, def.Label, s.Key)
+		}
+	}
+	for key := range c.Settings {
+		if !known[key] {
+			delete(c.Settings, key)
+		}
+	}
+
+	filters := c.Events[:0]
+	for _, prefix := range c.Events {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			filters = append(filters, prefix)
+		}
+	}
+	c.Events = filters
+	return nil
+}
+
+// Masked returns a copy of the channel with secret settings replaced by MaskedValue
+func (c Channel) Masked() Channel {
+	settings := make(map[string]string, len(c.Settings))
+	for key, value := range c.Settings {
+		if value != "" && isSecretSetting(c.Type, key) {
+			value = MaskedValue
+		}
+		settings[key] = value
+	}
+	c.Settings = settings
+	return c
+}
+
+// DeliveryStatus is the result of the last notification sent to a channel
+type DeliveryStatus struct {
+	Time      time.Time        `json:"time"`
+	EventType events.EventType `json:"eventType"`
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// Manager stores notification channels and routes events to them
+type Manager struct {
+	storage  storage.Storage
+	logger   *logger.Logger
+	hostname string
+
+	mu       sync.RWMutex
+	channels map[string]*Channel
+	status   map[string]*DeliveryStatus
+}
+
+// NewManager creates a notification manager and loads the configured channels
+func NewManager(store storage.Storage, appLogger *logger.Logger) (*Manager, error) {
+	hostname, _ := os.Hostname()
+	m := &Manager{
+		storage:  store,
+		logger:   appLogger,
+		hostname: hostname,
+		channels: make(map[string]*Channel),
+		status:   make(map[string]*DeliveryStatus),
+	}
+
+	data, err := store.List(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification channels: %w", err)
+	}
+	for id, value := range data {
+		var ch Channel
+		if err := json.Unmarshal(value, &ch); err != nil {
+			return nil, fmt.Errorf("failed to load notification channel %s: %w", id, err)
+		}
+		m.channels[id] = &ch
+	}
+
+	return m, nil
+}
+
+// List returns all channels, oldest first
+func (m *Manager) List() []Channel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Channel, 0, len(m.channels))
+	for _, ch := range m.channels {
+		list = append(list, *ch)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Get returns a channel by ID
+func (m *Manager) Get(id string) (*Channel, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ch, ok := m.channels[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *ch
+	copied.Settings = make(map[string]string, len(ch.Settings))
+	for key, value := range ch.Settings {
+		copied.Settings[key] = value
+	}
+	return &copied, nil
+}
+
+// Save validates and stores a channel. A channel without ID is created.
+// Settings set to MaskedValue keep their stored value.
+func (m *Manager) Save(ch *Channel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ch.ID != "" {
+		existing, ok := m.channels[ch.ID]
+		if !ok {
+			return ErrNotFound
+		}
+		for key, value := range ch.Settings {
+			if value == MaskedValue {
+				ch.Settings[key] = existing.Settings[key]
+			}
+		}
+	}
+	if err := ch.Validate(); err != nil {
+		return err
+	}
+
+	if ch.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		ch.ID = id
+		ch.CreatedAt = time.Now()
+	}
+
+	if err := m.storage.SetJSON(bucket, ch.ID, ch); err != nil {
+		return fmt.Errorf("failed to save notification channel: %w", err)
+	}
+	copied := *ch
+	m.channels[ch.ID] = &copied
+	return nil
+}
+
+// Delete removes a channel
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.channels[id]; !ok {
+		return ErrNotFound
+	}
+	if err := m.storage.Delete(bucket, id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+	delete(m.channels, id)
+	delete(m.status, id)
+	return nil
+}
+
+// Status returns the result of the last notification sent to a channel, nil if none
+func (m *Manager) Status(id string) *DeliveryStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status[id]
+}
+
+// Run sends events added to store to the matching channels until ctx is cancelled
+func (m *Manager) Run(ctx context.Context, store *events.Store) {
+	sub, unsubscribe := store.Subscribe(subscribeBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub:
+			for _, ch := range m.matching(event.Type) {
+				go func(ch Channel) {
+					if err := m.send(ctx, &ch, event); err != nil && ctx.Err() == nil {
+						m.logger.Warn("Failed to send notification", "channel", ch.Name, "event_type", event.Type, "event_id", event.ID, "error", err)
+					}
+				}(ch)
+			}
+		}
+	}
+}
+
+// SendTo sends an event to a channel regardless of its event filter.
+// An empty id sends to all enabled channels.
+func (m *Manager) SendTo(ctx context.Context, id string, event events.Event) error {
+	if id != "" {
+		ch, err := m.Get(id)
+		if err != nil {
+			return err
+		}
+		return m.send(ctx, ch, event)
+	}
+
+	var errs []error
+	for _, ch := range m.List() {
+		if ch.Enabled {
+			if err := m.send(ctx, &ch, event); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", ch.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Test sends a test notification to a channel
+func (m *Manager) Test(ctx context.Context, id, username string) error {
+	ch, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, ch, events.Event{
+		Type:      EventTest,
+		Timestamp: time.Now(),
+		Username:  username,
+		Success:   true,
+		Details:   "Test notification from PodmanView",
+	})
+}
+
+// send formats an event and sends it to a channel, recording the result
+func (m *Manager) send(ctx context.Context, ch *Channel, event events.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var err error
+	if info, ok := channelTypes[ch.Type]; ok {
+		err = info.send(ctx, ch.Settings, m.format(event))
+	} else {
+		err = fmt.Errorf("%w: unknown type %q", ErrInvalid, ch.Type)
+	}
+
+	status := &DeliveryStatus{Time: time.Now(), EventType: event.Type, Success: err == nil}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	m.mu.Lock()
+	if _, ok := m.channels[ch.ID]; ok {
+		m.status[ch.ID] = status
+	}
+	m.mu.Unlock()
+
+	return err
+}
+
+// format builds the notification text for an event
+func (m *Manager) format(event events.Event) Message {
+	title := "PodmanView: " + titleCase(string(event.Type))
+	if m.hostname != "" {
+		title += " on " + m.hostname
+	}
+
+	var body strings.Builder
+	if event.Details != "" {
+		body.WriteString(event.Details)
+		body.WriteString("\n")
+	}
+	if event.Severity != "" {
+		fmt.Fprintf(&body, "Severity: %s\n", event.Severity)
+	}
+	if event.Username != "" {
+		fmt.Fprintf(&body, "User: %s\n", event.Username)
+	}
+	if event.IP != "" {
+		fmt.Fprintf(&body, "IP: %s\n", event.IP)
+	}
+	fmt.Fprintf(&body, "Time: %s", event.Timestamp.Format(time.RFC1123))
+
+	return Message{Title: title, Body: body.String(), Event: event}
+}
+
+// matching returns the enabled channels that receive events of type t
+func (m *Manager) matching(t events.EventType) []Channel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var list []Channel
+	for _, ch := range m.channels {
+		if ch.Enabled && ch.Matches(t) {
+			list = append(list, *ch)
+		}
+	}
+	return list
+}
+
+// titleCase turns an event type like "container_died" into "Container Died"
+func titleCase(s string) string {
+	words := strings.Split(s, "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// newID generates a random channel ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
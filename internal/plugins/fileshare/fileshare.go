@@ -0,0 +1,208 @@
+// Package fileshare provides a plugin serving host directories as read-only file listings
+package fileshare
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/plugins"
+	"podmanview/internal/storage"
+)
+
+//go:embed index.html
+var htmlContent []byte
+
+// sharesKey is the storage key of the share list. Passwords are stored
+// encrypted, so the list is saved as a secret.
+const sharesKey = "shares"
+
+// shareNamePattern restricts share names to what reads well in a URL
+var shareNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+var (
+	errInvalidName  = apierror.New(http.StatusBadRequest, "Invalid share name, use lowercase letters, digits, '.', '_' and '-'")
+	errRelativePath = apierror.New(http.StatusBadRequest, "Path must be absolute")
+	errNotDirectory = apierror.New(http.StatusBadRequest, "Path is not a directory")
+	errShareExists  = apierror.New(http.StatusConflict, "Share already exists")
+	errShareMissing = apierror.New(http.StatusNotFound, "Share not found")
+)
+
+// Share is a host directory served under /share/{name}/
+type Share struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Password  string    `json:"password,omitempty"` // HTTP Basic auth password, empty for public shares
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// FileSharePlugin serves selected host directories read-only over HTTP
+type FileSharePlugin struct {
+	*plugins.BasePlugin
+
+	mu     sync.RWMutex
+	shares map[string]*Share
+}
+
+// New creates a new FileSharePlugin instance
+func New() *FileSharePlugin {
+	return &FileSharePlugin{
+		BasePlugin: plugins.NewBasePlugin(
+			"fileshare",
+			"File Sharing — serve host directories as read-only file listings",
+			"1.0.0",
+			htmlContent,
+		),
+		shares: make(map[string]*Share),
+	}
+}
+
+// Init initializes the plugin
+func (p *FileSharePlugin) Init(ctx context.Context, deps *plugins.PluginDependencies) error {
+	p.SetDependencies(deps)
+
+	if err := p.loadShares(); err != nil {
+		p.Logger().Printf("[%s] Warning: failed to load shares: %v", p.Name(), err)
+	}
+
+	p.Logger().Printf("[%s] Plugin initialized, %d shares loaded", p.Name(), len(p.shares))
+	return nil
+}
+
+// Start starts the plugin
+func (p *FileSharePlugin) Start(ctx context.Context) error {
+	p.Logger().Printf("[%s] Plugin started", p.Name())
+	return nil
+}
+
+// Stop stops the plugin
+func (p *FileSharePlugin) Stop(ctx context.Context) error {
+	p.Logger().Printf("[%s] Plugin stopped", p.Name())
+	return nil
+}
+
+// Routes returns the plugin's HTTP routes
+func (p *FileSharePlugin) Routes() []plugins.Route {
+	return []plugins.Route{
+		// Share management (admin)
+		{Method: "GET", Path: "/api/plugins/fileshare/shares", Handler: p.handleListShares, RequireAuth: true, Summary: "List file shares"},
+		{Method: "POST", Path: "/api/plugins/fileshare/shares", Handler: p.handleCreateShare, RequireAuth: true, Summary: "Share a host directory"},
+		{Method: "DELETE", Path: "/api/plugins/fileshare/shares/{name}", Handler: p.handleDeleteShare, RequireAuth: true, Summary: "Remove a file share"},
+		// Shared files, protected by the share password instead of a login
+		{Method: "GET", Path: "/share/{name}", Handler: p.handleServeShare, Summary: "Browse a file share"},
+		{Method: "GET", Path: "/share/{name}/*", Handler: p.handleServeShare, Summary: "Download from a file share"},
+	}
+}
+
+// IsEnabled checks if the plugin is enabled
+func (p *FileSharePlugin) IsEnabled() bool {
+	if p.Deps() == nil || p.Deps().Storage == nil {
+		return false
+	}
+	enabled, err := p.Deps().Storage.IsPluginEnabled(p.Name())
+	if err != nil {
+		return false
+	}
+	return enabled
+}
+
+// loadShares reads the share list from storage
+func (p *FileSharePlugin) loadShares() error {
+	var list []*Share
+	if err := p.Deps().Storage.GetSecretJSON(p.Name(), sharesKey, &list); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, share := range list {
+		p.shares[share.Name] = share
+	}
+	return nil
+}
+
+// saveShares writes the share list to storage. Call with p.mu held.
+func (p *FileSharePlugin) saveShares() error {
+	return p.Deps().Storage.SetSecretJSON(p.Name(), sharesKey, p.sortedShares())
+}
+
+// sortedShares returns the shares ordered by name. Call with p.mu held.
+func (p *FileSharePlugin) sortedShares() []*Share {
+	list := make([]*Share, 0, len(p.shares))
+	for _, share := range p.shares {
+		list = append(list, share)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// getShare returns a share by name
+func (p *FileSharePlugin) getShare(name string) (*Share, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	share, ok := p.shares[name]
+	return share, ok
+}
+
+// addShare validates and stores a new share. The path must be an existing
+// directory; it is stored resolved, so a moved symlink doesn't change the share.
+func (p *FileSharePlugin) addShare(share *Share) error {
+	if !shareNamePattern.MatchString(share.Name) {
+		return errInvalidName
+	}
+	if !filepath.IsAbs(share.Path) {
+		return errRelativePath
+	}
+	path, err := filepath.EvalSymlinks(filepath.Clean(share.Path))
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errNotDirectory
+	}
+	share.Path = path
+	share.CreatedAt = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.shares[share.Name]; exists {
+		return errShareExists
+	}
+	p.shares[share.Name] = share
+	if err := p.saveShares(); err != nil {
+		delete(p.shares, share.Name)
+		return err
+	}
+	return nil
+}
+
+// removeShare deletes a share
+func (p *FileSharePlugin) removeShare(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	share, exists := p.shares[name]
+	if !exists {
+		return errShareMissing
+	}
+	delete(p.shares, name)
+	if err := p.saveShares(); err != nil {
+		p.shares[name] = share
+		return err
+	}
+	return nil
+}
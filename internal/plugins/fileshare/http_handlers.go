@@ -0,0 +1,152 @@
+package fileshare
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/plugins"
+)
+
+// shareSandbox is the Content-Security-Policy of shared files. HTML files
+// of a share get an opaque origin and no scripts, so they can't call the
+// PodmanView API with the session of the visitor.
+const shareSandbox = "sandbox"
+
+// ShareInfo is a share as listed to admins, without the password
+type ShareInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	URL       string    `json:"url"`       // relative to the PodmanView base path
+	Protected bool      `json:"protected"` // a password is required
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateShareRequest is the body for creating a share
+type CreateShareRequest struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Password string `json:"password"` // optional
+}
+
+// requireAdmin writes 403 unless the user is an admin
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
+		return false
+	}
+	return true
+}
+
+// handleListShares returns all shares
+func (p *FileSharePlugin) handleListShares(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	p.mu.RLock()
+	shares := p.sortedShares()
+	p.mu.RUnlock()
+
+	result := make([]ShareInfo, 0, len(shares))
+	for _, share := range shares {
+		result = append(result, share.info())
+	}
+	plugins.WriteJSON(w, http.StatusOK, result)
+}
+
+// handleCreateShare shares a host directory
+func (p *FileSharePlugin) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var req CreateShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	share := &Share{Name: req.Name, Path: req.Path, Password: req.Password}
+	if err := p.addShare(share); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to create share")
+		return
+	}
+
+	p.Logger().Printf("[%s] Shared %s as %s", p.Name(), share.Path, share.Name)
+	plugins.WriteJSON(w, http.StatusCreated, share.info())
+}
+
+// handleDeleteShare removes a share. The directory itself is kept.
+func (p *FileSharePlugin) handleDeleteShare(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if err := p.removeShare(name); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to remove share")
+		return
+	}
+
+	p.Logger().Printf("[%s] Removed share %s", p.Name(), name)
+	plugins.WriteJSON(w, http.StatusOK, map[string]string{"status": "Share removed"})
+}
+
+// handleServeShare serves a directory listing or file of a share. Files are
+// opened through os.Root, so neither ".." nor symlinks reach outside the
+// shared directory. Protected shares ask for the password with HTTP Basic
+// auth; any user name is accepted.
+func (p *FileSharePlugin) handleServeShare(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	share, ok := p.getShare(name)
+	if !ok {
+		apierror.Write(w, r, http.StatusNotFound, "Share not found")
+		return
+	}
+
+	if share.Password != "" {
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(share.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+share.Name+`", charset="UTF-8"`)
+			apierror.Write(w, r, http.StatusUnauthorized, "Password required")
+			return
+		}
+	}
+
+	prefix := "/share/" + name
+	if r.URL.Path == prefix {
+		// Relative, so the redirect keeps the base path
+		w.Header().Set("Location", name+"/")
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+
+	root, err := os.OpenRoot(share.Path)
+	if err != nil {
+		apierror.WriteErr(w, r, err, "Failed to open share")
+		return
+	}
+	defer root.Close()
+
+	w.Header().Set("Content-Security-Policy", shareSandbox)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.StripPrefix(prefix, http.FileServerFS(root.FS())).ServeHTTP(w, r)
+}
+
+// info returns the share as listed to admins
+func (s *Share) info() ShareInfo {
+	return ShareInfo{
+		Name:      s.Name,
+		Path:      s.Path,
+		URL:       "/share/" + s.Name + "/",
+		Protected: s.Password != "",
+		CreatedAt: s.CreatedAt,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"podmanview/internal/apierror"
 	"podmanview/internal/plugins"
 )
 
@@ -38,16 +39,14 @@ func (p *LEDPlugin) handleGetStatus(w http.ResponseWriter, r *http.Request) {
 func (p *LEDPlugin) handleToggleLEDs(w http.ResponseWriter, r *http.Request) {
 	var req ToggleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Check if LEDs are available
 	state := p.GetState()
 	if state.TotalLEDs == 0 {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "No LEDs available. This plugin requires a Linux system with accessible LEDs in /sys/class/leds",
-		})
+		apierror.Write(w, r, http.StatusBadRequest, "No LEDs available. This plugin requires a Linux system with accessible LEDs in /sys/class/leds")
 		return
 	}
 
@@ -55,7 +54,7 @@ func (p *LEDPlugin) handleToggleLEDs(w http.ResponseWriter, r *http.Request) {
 		if p.Logger() != nil {
 			p.Logger().Printf("[%s] Failed to toggle LEDs: %v", p.Name(), err)
 		}
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to toggle LEDs: " + err.Error()})
+		apierror.Write(w, r, http.StatusInternalServerError, "Failed to toggle LEDs: "+err.Error())
 		return
 	}
 
@@ -80,7 +79,7 @@ func (p *LEDPlugin) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 func (p *LEDPlugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 	var settings Settings
 	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -88,7 +87,7 @@ func (p *LEDPlugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request)
 		if p.Logger() != nil {
 			p.Logger().Printf("[%s] Failed to update settings: %v", p.Name(), err)
 		}
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update settings"})
+		apierror.Write(w, r, http.StatusInternalServerError, "Failed to update settings")
 		return
 	}
 
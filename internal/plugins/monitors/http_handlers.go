@@ -0,0 +1,224 @@
+package monitors
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/plugins"
+)
+
+// MonitorInfo is a monitor with its current status
+type MonitorInfo struct {
+	Monitor
+	Status    string  `json:"status"` // up, down, pending or paused
+	LastCheck *Result `json:"lastCheck,omitempty"`
+	// Uptime is the share of successful checks in the last 24 hours, in
+	// percent, and AvgResponseMs their mean response time
+	Uptime        *float64 `json:"uptime"`
+	AvgResponseMs *float64 `json:"avgResponseMs"`
+}
+
+// MonitorRequest is the body for creating or updating a monitor
+type MonitorRequest struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Target          string `json:"target"`
+	IntervalSeconds int    `json:"intervalSeconds"`
+	TimeoutSeconds  int    `json:"timeoutSeconds"`
+	ExpectedStatus  int    `json:"expectedStatus"`
+	SkipTLSVerify   bool   `json:"skipTlsVerify"`
+	Enabled         *bool  `json:"enabled"` // default true
+}
+
+// requireAdmin writes 403 unless the user is an admin
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
+		return false
+	}
+	return true
+}
+
+// handleListMonitors returns all monitors with their status
+func (p *MonitorsPlugin) handleListMonitors(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	p.mu.Lock()
+	monitors := p.sortedMonitors()
+	result := make([]MonitorInfo, 0, len(monitors))
+	for _, m := range monitors {
+		result = append(result, p.states[m.ID].info(*m, now))
+	}
+	p.mu.Unlock()
+
+	plugins.WriteJSON(w, http.StatusOK, result)
+}
+
+// handleCreateMonitor adds a monitor
+func (p *MonitorsPlugin) handleCreateMonitor(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	m, ok := decodeMonitor(w, r)
+	if !ok {
+		return
+	}
+	if err := p.addMonitor(m); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to create monitor")
+		return
+	}
+
+	p.Logger().Printf("[%s] Added monitor %s (%s %s)", p.Name(), m.Name, m.Type, m.Target)
+	plugins.WriteJSON(w, http.StatusCreated, (&monitorState{}).info(*m, time.Now()))
+}
+
+// handleUpdateMonitor replaces the settings of a monitor
+func (p *MonitorsPlugin) handleUpdateMonitor(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	m, ok := decodeMonitor(w, r)
+	if !ok {
+		return
+	}
+	if err := p.updateMonitor(chi.URLParam(r, "id"), m); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to update monitor")
+		return
+	}
+
+	p.Logger().Printf("[%s] Updated monitor %s", p.Name(), m.Name)
+	p.mu.Lock()
+	info := p.states[m.ID].info(*m, time.Now())
+	p.mu.Unlock()
+	plugins.WriteJSON(w, http.StatusOK, info)
+}
+
+// handleDeleteMonitor removes a monitor and its results
+func (p *MonitorsPlugin) handleDeleteMonitor(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := p.removeMonitor(id); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to remove monitor")
+		return
+	}
+
+	p.Logger().Printf("[%s] Removed monitor %s", p.Name(), id)
+	plugins.WriteJSON(w, http.StatusOK, map[string]string{"status": "Monitor removed"})
+}
+
+// handleHistory returns the check results of a monitor, oldest first.
+// ?limit=N returns the last N.
+func (p *MonitorsPlugin) handleHistory(w http.ResponseWriter, r *http.Request) {
+	limit := maxResults
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			apierror.Write(w, r, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		limit = n
+	}
+
+	p.mu.Lock()
+	state, exists := p.states[chi.URLParam(r, "id")]
+	var results []Result
+	if exists {
+		results = state.results
+	}
+	p.mu.Unlock()
+	if !exists {
+		apierror.WriteError(w, r, errMonitorMissing)
+		return
+	}
+
+	if len(results) > limit {
+		results = results[len(results)-limit:]
+	}
+	if results == nil {
+		results = []Result{}
+	}
+	plugins.WriteJSON(w, http.StatusOK, results)
+}
+
+// handleCheckNow checks a monitor right away and returns the result
+func (p *MonitorsPlugin) handleCheckNow(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	result, err := p.checkNow(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.WriteErr(w, r, err, "Failed to check monitor")
+		return
+	}
+	plugins.WriteJSON(w, http.StatusOK, result)
+}
+
+// decodeMonitor reads a MonitorRequest body, writing 400 if it is invalid
+func decodeMonitor(w http.ResponseWriter, r *http.Request) (*Monitor, bool) {
+	var req MonitorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+	return &Monitor{
+		Name:            req.Name,
+		Type:            req.Type,
+		Target:          req.Target,
+		IntervalSeconds: req.IntervalSeconds,
+		TimeoutSeconds:  req.TimeoutSeconds,
+		ExpectedStatus:  req.ExpectedStatus,
+		SkipTLSVerify:   req.SkipTLSVerify,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+	}, true
+}
+
+// info returns the monitor with its status at now. Call with p.mu held.
+func (s *monitorState) info(m Monitor, now time.Time) MonitorInfo {
+	info := MonitorInfo{Monitor: m, Status: "pending"}
+	switch {
+	case !m.Enabled:
+		info.Status = "paused"
+	case s.down:
+		info.Status = "down"
+	case s.failures < len(s.results):
+		info.Status = "up" // a single failure is not down yet
+	}
+	if len(s.results) > 0 {
+		last := s.results[len(s.results)-1]
+		info.LastCheck = &last
+	}
+
+	var checks, up int
+	var responseMs float64
+	for _, result := range s.results {
+		if now.Sub(result.Time) > 24*time.Hour {
+			continue
+		}
+		checks++
+		if result.Up {
+			up++
+			responseMs += result.ResponseMs
+		}
+	}
+	if checks > 0 {
+		uptime := math.Round(float64(up)/float64(checks)*10000) / 100
+		info.Uptime = &uptime
+	}
+	if up > 0 {
+		avg := math.Round(responseMs/float64(up)*100) / 100
+		info.AvgResponseMs = &avg
+	}
+	return info
+}
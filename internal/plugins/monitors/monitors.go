@@ -0,0 +1,543 @@
+// Package monitors provides a plugin probing HTTP, TCP and ping targets and
+// alerting when they go down
+package monitors
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/events"
+	"podmanview/internal/plugins"
+	"podmanview/internal/storage"
+)
+
+//go:embed index.html
+var htmlContent []byte
+
+// Monitor types
+const (
+	TypeHTTP = "http"
+	TypeTCP  = "tcp"
+	TypePing = "ping"
+)
+
+const (
+	// monitorsKey is the storage key of the monitor list
+	monitorsKey = "monitors"
+
+	// resultsPrefix is the storage key prefix of the results of a monitor
+	resultsPrefix = "results/"
+
+	// maxResults limits the results kept per monitor, a day at the
+	// default interval
+	maxResults = 1440
+
+	// downAfter is the number of failed checks in a row after which a
+	// monitor is down, so a single lost packet doesn't alert
+	downAfter = 2
+
+	// tickInterval is how often due monitors are looked for
+	tickInterval = 5 * time.Second
+
+	defaultInterval = 60
+	minInterval     = 10
+	defaultTimeout  = 10
+	maxTimeout      = 60
+)
+
+// hostPattern is a host name or IP address for ping. A leading '-' would
+// be read as an option.
+var hostPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._:-]{0,252}$`)
+
+var (
+	errInvalidName     = apierror.New(http.StatusBadRequest, "Name is required")
+	errInvalidType     = apierror.New(http.StatusBadRequest, "Invalid monitor type, use http, tcp or ping")
+	errInvalidURL      = apierror.New(http.StatusBadRequest, "Invalid URL, use http:// or https://")
+	errInvalidAddress  = apierror.New(http.StatusBadRequest, "Invalid address, use host:port")
+	errInvalidHost     = apierror.New(http.StatusBadRequest, "Invalid host")
+	errInvalidInterval = apierror.New(http.StatusBadRequest, fmt.Sprintf("Interval must be at least %d seconds", minInterval))
+	errInvalidTimeout  = apierror.New(http.StatusBadRequest, fmt.Sprintf("Timeout must be between 1 and %d seconds", maxTimeout))
+	errInvalidStatus   = apierror.New(http.StatusBadRequest, "Invalid expected status")
+	errMonitorMissing  = apierror.New(http.StatusNotFound, "Monitor not found")
+)
+
+// Monitor is a target checked periodically
+type Monitor struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // http, tcp or ping
+	// Target is a URL for http, host:port for tcp and a host for ping
+	Target          string    `json:"target"`
+	IntervalSeconds int       `json:"intervalSeconds"`
+	TimeoutSeconds  int       `json:"timeoutSeconds"`
+	ExpectedStatus  int       `json:"expectedStatus,omitempty"` // http only, 0 accepts 2xx and 3xx
+	SkipTLSVerify   bool      `json:"skipTlsVerify,omitempty"`  // http only, for self-signed certificates
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Time       time.Time `json:"time"`
+	Up         bool      `json:"up"`
+	ResponseMs float64   `json:"responseMs"`
+	Status     int       `json:"status,omitempty"` // HTTP status
+	Error      string    `json:"error,omitempty"`
+}
+
+// monitorState is the check state of a monitor
+type monitorState struct {
+	results  []Result // oldest first
+	failures int      // failed checks in a row
+	down     bool     // a monitor_down event was raised
+	downAt   time.Time
+	lastRun  time.Time
+	running  bool
+}
+
+// MonitorsPlugin probes user-defined targets and raises events when they
+// go down and up again
+type MonitorsPlugin struct {
+	*plugins.BasePlugin
+
+	mu       sync.Mutex
+	monitors map[string]*Monitor
+	states   map[string]*monitorState
+}
+
+// New creates a new MonitorsPlugin instance
+func New() *MonitorsPlugin {
+	return &MonitorsPlugin{
+		BasePlugin: plugins.NewBasePlugin(
+			"monitors",
+			"Endpoint Monitors — probe HTTP, TCP and ping targets and alert when they go down",
+			"1.0.0",
+			htmlContent,
+		),
+		monitors: make(map[string]*Monitor),
+		states:   make(map[string]*monitorState),
+	}
+}
+
+// Init initializes the plugin
+func (p *MonitorsPlugin) Init(ctx context.Context, deps *plugins.PluginDependencies) error {
+	p.SetDependencies(deps)
+
+	if err := p.loadMonitors(); err != nil {
+		p.Logger().Printf("[%s] Warning: failed to load monitors: %v", p.Name(), err)
+	}
+
+	p.Logger().Printf("[%s] Plugin initialized, %d monitors loaded", p.Name(), len(p.monitors))
+	return nil
+}
+
+// Start starts the plugin
+func (p *MonitorsPlugin) Start(ctx context.Context) error {
+	p.Logger().Printf("[%s] Plugin started", p.Name())
+	return nil
+}
+
+// Stop stops the plugin
+func (p *MonitorsPlugin) Stop(ctx context.Context) error {
+	p.Logger().Printf("[%s] Plugin stopped", p.Name())
+	return nil
+}
+
+// Routes returns the plugin's HTTP routes
+func (p *MonitorsPlugin) Routes() []plugins.Route {
+	return []plugins.Route{
+		{Method: "GET", Path: "/api/plugins/monitors/monitors", Handler: p.handleListMonitors, RequireAuth: true, Summary: "List endpoint monitors with their status"},
+		{Method: "POST", Path: "/api/plugins/monitors/monitors", Handler: p.handleCreateMonitor, RequireAuth: true, Summary: "Create an endpoint monitor"},
+		{Method: "PUT", Path: "/api/plugins/monitors/monitors/{id}", Handler: p.handleUpdateMonitor, RequireAuth: true, Summary: "Update an endpoint monitor"},
+		{Method: "DELETE", Path: "/api/plugins/monitors/monitors/{id}", Handler: p.handleDeleteMonitor, RequireAuth: true, Summary: "Remove an endpoint monitor"},
+		{Method: "GET", Path: "/api/plugins/monitors/monitors/{id}/history", Handler: p.handleHistory, RequireAuth: true, Summary: "Get the check results of a monitor"},
+		{Method: "POST", Path: "/api/plugins/monitors/monitors/{id}/check", Handler: p.handleCheckNow, RequireAuth: true, Summary: "Check a monitor now"},
+	}
+}
+
+// IsEnabled checks if the plugin is enabled
+func (p *MonitorsPlugin) IsEnabled() bool {
+	if p.Deps() == nil || p.Deps().Storage == nil {
+		return false
+	}
+	enabled, err := p.Deps().Storage.IsPluginEnabled(p.Name())
+	if err != nil {
+		return false
+	}
+	return enabled
+}
+
+// StartBackgroundTasks starts checking the monitors that are due
+func (p *MonitorsPlugin) StartBackgroundTasks(ctx context.Context) error {
+	go plugins.RunPeriodic(ctx, tickInterval, p.Logger(), p.Name(), func(ctx context.Context) error {
+		p.checkDue(ctx, time.Now())
+		return nil
+	})
+	return nil
+}
+
+// loadMonitors reads the monitors and their results from storage
+func (p *MonitorsPlugin) loadMonitors() error {
+	st := p.Deps().Storage
+	var list []*Monitor
+	if err := st.GetJSON(p.Name(), monitorsKey, &list); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range list {
+		p.monitors[m.ID] = m
+		state := &monitorState{}
+		if err := st.GetJSON(p.Name(), resultsPrefix+m.ID, &state.results); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			p.Logger().Printf("[%s] Warning: failed to load results of %s: %v", p.Name(), m.Name, err)
+		}
+		// Continue where the results stopped, so a restart doesn't alert again
+		for i := len(state.results) - 1; i >= 0 && !state.results[i].Up; i-- {
+			state.failures++
+		}
+		if n := len(state.results); n > 0 {
+			state.lastRun = state.results[n-1].Time
+			if state.failures >= downAfter {
+				state.down = true
+				state.downAt = state.results[n-state.failures].Time
+			}
+		}
+		p.states[m.ID] = state
+	}
+	return nil
+}
+
+// saveMonitors writes the monitor list to storage. Call with p.mu held.
+func (p *MonitorsPlugin) saveMonitors() error {
+	return p.Deps().Storage.SetJSON(p.Name(), monitorsKey, p.sortedMonitors())
+}
+
+// sortedMonitors returns the monitors ordered by name. Call with p.mu held.
+func (p *MonitorsPlugin) sortedMonitors() []*Monitor {
+	list := make([]*Monitor, 0, len(p.monitors))
+	for _, m := range p.monitors {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// validate checks a monitor and fills in defaults
+func (m *Monitor) validate() error {
+	if m.Name == "" {
+		return errInvalidName
+	}
+	switch m.Type {
+	case TypeHTTP:
+		u, err := url.Parse(m.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errInvalidURL
+		}
+	case TypeTCP:
+		host, port, err := net.SplitHostPort(m.Target)
+		if err != nil || host == "" {
+			return errInvalidAddress
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return errInvalidAddress
+		}
+	case TypePing:
+		if !hostPattern.MatchString(m.Target) {
+			return errInvalidHost
+		}
+	default:
+		return errInvalidType
+	}
+	if m.IntervalSeconds == 0 {
+		m.IntervalSeconds = defaultInterval
+	}
+	if m.IntervalSeconds < minInterval {
+		return errInvalidInterval
+	}
+	if m.TimeoutSeconds == 0 {
+		m.TimeoutSeconds = defaultTimeout
+	}
+	if m.TimeoutSeconds < 1 || m.TimeoutSeconds > maxTimeout {
+		return errInvalidTimeout
+	}
+	if m.ExpectedStatus != 0 && (m.ExpectedStatus < 100 || m.ExpectedStatus > 599) {
+		return errInvalidStatus
+	}
+	return nil
+}
+
+// addMonitor validates and stores a new monitor
+func (p *MonitorsPlugin) addMonitor(m *Monitor) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	m.ID = id
+	m.CreatedAt = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.monitors[m.ID] = m
+	if err := p.saveMonitors(); err != nil {
+		delete(p.monitors, m.ID)
+		return err
+	}
+	p.states[m.ID] = &monitorState{}
+	return nil
+}
+
+// updateMonitor replaces the settings of a monitor. The results are kept,
+// the failure count starts over.
+func (p *MonitorsPlugin) updateMonitor(id string, m *Monitor) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old, exists := p.monitors[id]
+	if !exists {
+		return errMonitorMissing
+	}
+	m.ID = id
+	m.CreatedAt = old.CreatedAt
+	p.monitors[id] = m
+	if err := p.saveMonitors(); err != nil {
+		p.monitors[id] = old
+		return err
+	}
+	state := p.states[id]
+	state.failures = 0
+	state.down = false
+	state.lastRun = time.Time{}
+	return nil
+}
+
+// removeMonitor deletes a monitor and its results
+func (p *MonitorsPlugin) removeMonitor(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, exists := p.monitors[id]
+	if !exists {
+		return errMonitorMissing
+	}
+	delete(p.monitors, id)
+	if err := p.saveMonitors(); err != nil {
+		p.monitors[id] = m
+		return err
+	}
+	delete(p.states, id)
+	if err := p.Deps().Storage.Delete(p.Name(), resultsPrefix+id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		p.Logger().Printf("[%s] Warning: failed to delete results of %s: %v", p.Name(), m.Name, err)
+	}
+	return nil
+}
+
+// checkDue starts checks of the enabled monitors whose interval passed
+func (p *MonitorsPlugin) checkDue(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	var due []Monitor
+	for id, m := range p.monitors {
+		state := p.states[id]
+		if !m.Enabled || state.running || now.Sub(state.lastRun) < time.Duration(m.IntervalSeconds)*time.Second {
+			continue
+		}
+		state.running = true
+		state.lastRun = now
+		due = append(due, *m)
+	}
+	p.mu.Unlock()
+
+	for _, m := range due {
+		go func() {
+			p.record(m, check(ctx, m))
+			p.mu.Lock()
+			if state := p.states[m.ID]; state != nil {
+				state.running = false
+			}
+			p.mu.Unlock()
+		}()
+	}
+}
+
+// checkNow checks a monitor right away, enabled or not
+func (p *MonitorsPlugin) checkNow(ctx context.Context, id string) (Result, error) {
+	p.mu.Lock()
+	m, exists := p.monitors[id]
+	var monitor Monitor
+	if exists {
+		monitor = *m
+		p.states[id].lastRun = time.Now()
+	}
+	p.mu.Unlock()
+	if !exists {
+		return Result{}, errMonitorMissing
+	}
+
+	result := check(ctx, monitor)
+	p.record(monitor, result)
+	return result, nil
+}
+
+// record adds a check result and raises monitor_down after downAfter
+// failures in a row, and monitor_up when a down monitor recovers
+func (p *MonitorsPlugin) record(m Monitor, result Result) {
+	p.mu.Lock()
+	state, exists := p.states[m.ID]
+	if !exists {
+		p.mu.Unlock()
+		return // removed during the check
+	}
+	// Readers use the slice without the lock, so it is copied
+	results := append(make([]Result, 0, len(state.results)+1), state.results...)
+	results = append(results, result)
+	if len(results) > maxResults {
+		results = results[len(results)-maxResults:]
+	}
+	state.results = results
+
+	var event events.EventType
+	var details string
+	if result.Up {
+		if state.down {
+			event = events.EventMonitorUp
+			details = fmt.Sprintf("%s (%s) is up again after %s", m.Name, m.Target, result.Time.Sub(state.downAt).Round(time.Second))
+		}
+		state.failures = 0
+		state.down = false
+	} else {
+		state.failures++
+		if state.failures >= downAfter && !state.down {
+			state.down = true
+			state.downAt = result.Time
+			event = events.EventMonitorDown
+			details = fmt.Sprintf("%s (%s) is down: %s", m.Name, m.Target, result.Error)
+		}
+	}
+	p.mu.Unlock()
+
+	if err := p.Deps().Storage.SetJSON(p.Name(), resultsPrefix+m.ID, results); err != nil {
+		p.Logger().Printf("[%s] Warning: failed to save results of %s: %v", p.Name(), m.Name, err)
+	}
+	if event != "" {
+		p.Logger().Printf("[%s] %s", p.Name(), details)
+		if deps := p.Deps(); deps.EventStore != nil {
+			deps.EventStore.Add(event, "system", "", event == events.EventMonitorUp, details)
+		}
+	}
+}
+
+// check probes the target of a monitor
+func check(ctx context.Context, m Monitor) Result {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(m.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	result := Result{Time: time.Now()}
+	var err error
+	switch m.Type {
+	case TypeHTTP:
+		result.Status, err = checkHTTP(ctx, m)
+	case TypeTCP:
+		var conn net.Conn
+		var dialer net.Dialer
+		if conn, err = dialer.DialContext(ctx, "tcp", m.Target); err == nil {
+			conn.Close()
+		}
+	case TypePing:
+		err = checkPing(ctx, m)
+	}
+	result.ResponseMs = float64(time.Since(result.Time).Microseconds()) / 1000
+	result.Up = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// checkHTTP requests the URL of a monitor. Redirects are followed; the
+// final status must match the expected status, or be 2xx or 3xx.
+func checkHTTP(ctx context.Context, m Monitor) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.Target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "PodmanView-Monitor/1.0")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	if m.SkipTLSVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case m.ExpectedStatus != 0 && resp.StatusCode != m.ExpectedStatus:
+		return resp.StatusCode, fmt.Errorf("status %d, expected %d", resp.StatusCode, m.ExpectedStatus)
+	case m.ExpectedStatus == 0 && resp.StatusCode >= 400:
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// checkPing sends one ICMP echo request with the ping command
+func checkPing(ctx context.Context, m Monitor) error {
+	cmd := exec.CommandContext(ctx, "ping", "-c", "1", "-W", strconv.Itoa(m.TimeoutSeconds), m.Target)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return errors.New("timeout")
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return errors.New("no reply")
+		}
+		if len(out) > 0 {
+			return fmt.Errorf("%v: %s", err, lastLine(out))
+		}
+		return err
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of command output
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// newID generates a random monitor ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/websocket"
+	"podmanview/internal/apierror"
 	"podmanview/internal/auth"
 	"podmanview/internal/plugins"
 )
@@ -48,18 +49,18 @@ type ContainerInfo struct {
 func (h *Handlers) ListContainers(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		plugins.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	if h.sessions.podman == nil {
-		plugins.WriteJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Podman client not available"})
+		apierror.Write(w, r, http.StatusServiceUnavailable, "Podman client not available")
 		return
 	}
 
 	containers, err := h.sessions.podman.ListContainers(r.Context())
 	if err != nil {
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		apierror.WriteErr(w, r, err, "")
 		return
 	}
 
@@ -98,7 +99,7 @@ func (h *Handlers) ListContainers(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) ContainerSessions(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		plugins.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -111,7 +112,7 @@ func (h *Handlers) ContainerSessions(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) ListSessions(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		plugins.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
@@ -130,13 +131,13 @@ type CreateSessionRequest struct {
 func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		plugins.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	var req CreateSessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -173,7 +174,7 @@ func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 	pp, err := h.sessions.Create(meta)
 	if err != nil {
 		h.logf("Failed to create session: %v", err)
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		apierror.WriteErr(w, r, err, "")
 		return
 	}
 
@@ -184,13 +185,13 @@ func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		plugins.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	if err := h.sessions.Kill(id); err != nil {
-		plugins.WriteJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		apierror.Write(w, r, http.StatusNotFound, err.Error())
 		return
 	}
 
@@ -201,20 +202,20 @@ func (h *Handlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) CompactSession(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		plugins.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	pp := h.sessions.Get(id)
 	if pp == nil {
-		plugins.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Session not found"})
+		apierror.Write(w, r, http.StatusNotFound, "Session not found")
 		return
 	}
 
 	cmd := map[string]string{"type": "compact"}
 	if err := h.sessions.SendCommand(id, cmd); err != nil {
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		apierror.WriteErr(w, r, err, "")
 		return
 	}
 
@@ -225,20 +226,20 @@ func (h *Handlers) CompactSession(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) ExportSession(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		plugins.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Admin access required"})
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	pp := h.sessions.Get(id)
 	if pp == nil {
-		plugins.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Session not found"})
+		apierror.Write(w, r, http.StatusNotFound, "Session not found")
 		return
 	}
 
 	cmd := map[string]string{"type": "export_html"}
 	if err := h.sessions.SendCommand(id, cmd); err != nil {
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		apierror.WriteErr(w, r, err, "")
 		return
 	}
 
@@ -249,14 +250,14 @@ func (h *Handlers) ExportSession(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) WSProxy(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if !user.IsAdmin() {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
 		return
 	}
 
 	id := chi.URLParam(r, "id")
 	pp := h.sessions.Get(id)
 	if pp == nil {
-		http.Error(w, "Session not found", http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, "Session not found")
 		return
 	}
 
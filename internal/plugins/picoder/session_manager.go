@@ -278,15 +278,12 @@ func (sm *SessionManager) LoadAll() error {
 	if sm.storage == nil {
 		return nil
 	}
-	data, err := sm.storage.List(storagePluginName)
+	data, err := sm.storage.ListPrefix(storagePluginName, "session:")
 	if err != nil {
 		return err
 	}
 
 	for key, value := range data {
-		if !strings.HasPrefix(key, "session:") {
-			continue
-		}
 		var meta SessionMetadata
 		if err := json.Unmarshal(value, &meta); err != nil {
 			sm.logf("Warning: corrupt session metadata %s: %v", key, err)
@@ -372,11 +369,7 @@ func (sm *SessionManager) saveMetadata(meta *SessionMetadata) error {
 	if sm.storage == nil {
 		return nil
 	}
-	data, err := json.Marshal(meta)
-	if err != nil {
-		return err
-	}
-	return sm.storage.Set(storagePluginName, "session:"+meta.ID, data)
+	return sm.storage.SetJSON(storagePluginName, "session:"+meta.ID, meta)
 }
 
 func (sm *SessionManager) logf(format string, v ...interface{}) {
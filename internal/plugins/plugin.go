@@ -9,6 +9,7 @@ import (
 
 	"podmanview/internal/config"
 	"podmanview/internal/events"
+	"podmanview/internal/idle"
 	"podmanview/internal/podman"
 	"podmanview/internal/storage"
 )
@@ -74,6 +75,10 @@ type PluginDependencies struct {
 
 	// Storage is the storage for plugin configurations and data
 	Storage storage.Storage
+
+	// Idle tracks UI clients, so sampling can slow down while nobody is
+	// connected (nil when power saving is off)
+	Idle *idle.Monitor
 }
 
 // Route represents a plugin's HTTP route
@@ -90,6 +95,10 @@ type Route struct {
 
 	// RequireAuth indicates whether authentication is required for this route
 	RequireAuth bool
+
+	// Summary describes the route in the OpenAPI document (/api/openapi.json)
+	// Optional, defaults to the handler name (handleGetStatus -> "Get status")
+	Summary string
 }
 
 // BasePlugin is a base structure that plugins can embed
@@ -161,6 +170,23 @@ func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
+// RunSampler is RunPeriodic for tasks that sample the host: while idle the
+// interval is stretched by idle.Slowdown, and sampling resumes at once when
+// a client connects. A nil monitor samples at the interval.
+func RunSampler(ctx context.Context, interval time.Duration, idleMonitor *idle.Monitor, logger *log.Logger, pluginName string, task func(context.Context) error) {
+	for {
+		if err := task(ctx); err != nil && logger != nil {
+			logger.Printf("[%s] Failed to run background task: %v", pluginName, err)
+		}
+		if !idleMonitor.Sleep(ctx, interval) {
+			if logger != nil {
+				logger.Printf("[%s] Background task stopped", pluginName)
+			}
+			return
+		}
+	}
+}
+
 // RunPeriodic runs a function periodically until the context is cancelled
 // This is a helper for plugins that need to run background tasks
 // Usage example:
@@ -176,7 +202,7 @@ func RunPeriodic(ctx context.Context, interval time.Duration, logger *log.Logger
 	// Run task immediately on start
 	if err := task(ctx); err != nil {
 		if logger != nil {
-			logger.Printf("[%s] Background task error: %v", pluginName, err)
+			logger.Printf("[%s] Failed to run background task: %v", pluginName, err)
 		}
 	}
 
@@ -191,7 +217,7 @@ func RunPeriodic(ctx context.Context, interval time.Duration, logger *log.Logger
 		case <-ticker.C:
 			if err := task(ctx); err != nil {
 				if logger != nil {
-					logger.Printf("[%s] Background task error: %v", pluginName, err)
+					logger.Printf("[%s] Failed to run background task: %v", pluginName, err)
 				}
 			}
 		}
@@ -0,0 +1,340 @@
+package portmap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"podmanview/internal/apierror"
+	"podmanview/internal/auth"
+	"podmanview/internal/plugins"
+)
+
+// GatewayInfo is the router found for port mapping. Error says why none
+// was found.
+type GatewayInfo struct {
+	Method     string `json:"method,omitempty"`  // natpmp or upnp
+	Address    string `json:"address,omitempty"` // NAT-PMP address or UPnP control URL
+	ExternalIP string `json:"externalIp,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MappingInfo is a requested mapping with its state on the router
+type MappingInfo struct {
+	Mapping
+	Active bool   `json:"active"`
+	Method string `json:"method,omitempty"`
+	// GrantedPort is the external port the router mapped, which NAT-PMP
+	// routers may choose differently
+	GrantedPort int        `json:"grantedPort,omitempty"`
+	RenewedAt   *time.Time `json:"renewedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"` // null for permanent mappings
+	Error       string     `json:"error,omitempty"`
+}
+
+// PublishedPort is a container port published on the host
+type PublishedPort struct {
+	ContainerID   string `json:"containerId"`
+	Container     string `json:"container"`
+	Protocol      string `json:"protocol"`
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	HostIP        string `json:"hostIp,omitempty"`
+	Mapped        bool   `json:"mapped"` // forwarded by a mapping
+}
+
+// MappingRequest is the body for creating a mapping
+type MappingRequest struct {
+	ContainerID  string `json:"containerId"`
+	Protocol     string `json:"protocol"` // default tcp
+	HostPort     int    `json:"hostPort"`
+	ExternalPort int    `json:"externalPort"` // default HostPort
+	Description  string `json:"description"`
+}
+
+// requireAdmin writes 403 unless the user is an admin
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		apierror.Write(w, r, http.StatusForbidden, "Admin access required")
+		return false
+	}
+	return true
+}
+
+// handleGateway finds the router and asks it for the external address.
+// ?refresh=true discovers it again.
+func (p *PortMapPlugin) handleGateway(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("refresh") == "true" {
+		p.gwMu.Lock()
+		p.gw = nil
+		p.gwMu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	var info GatewayInfo
+	err := p.withGateway(ctx, func(gw gateway) error {
+		info.Method, info.Address = gw.Method(), gw.Address()
+		var err error
+		info.ExternalIP, err = gw.ExternalIP(ctx)
+		return err
+	})
+	if err != nil {
+		info.Error = err.Error()
+	}
+	plugins.WriteJSON(w, http.StatusOK, info)
+}
+
+// handlePorts lists the published ports of all containers
+func (p *PortMapPlugin) handlePorts(w http.ResponseWriter, r *http.Request) {
+	containers, err := p.Deps().PodmanClient.ListContainers(r.Context())
+	if err != nil {
+		apierror.WriteErr(w, r, err, "Failed to list containers")
+		return
+	}
+
+	p.mu.Lock()
+	mapped := make(map[string]bool)
+	for _, m := range p.mappings {
+		mapped[m.Protocol+"/"+strconv.Itoa(m.HostPort)] = true
+	}
+	p.mu.Unlock()
+
+	result := []PublishedPort{}
+	seen := make(map[string]bool)
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		for _, port := range c.Ports {
+			if port.PublicPort == 0 {
+				continue
+			}
+			protocol := strings.ToLower(port.Type)
+			key := c.ID + protocol + strconv.Itoa(port.PublicPort)
+			if seen[key] {
+				continue // published on IPv4 and IPv6
+			}
+			seen[key] = true
+			result = append(result, PublishedPort{
+				ContainerID:   c.ID,
+				Container:     name,
+				Protocol:      protocol,
+				HostPort:      port.PublicPort,
+				ContainerPort: port.PrivatePort,
+				HostIP:        port.IP,
+				Mapped:        mapped[protocol+"/"+strconv.Itoa(port.PublicPort)],
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].HostPort != result[j].HostPort {
+			return result[i].HostPort < result[j].HostPort
+		}
+		return result[i].Protocol < result[j].Protocol
+	})
+	plugins.WriteJSON(w, http.StatusOK, result)
+}
+
+// handleListMappings returns the requested mappings with their state
+func (p *PortMapPlugin) handleListMappings(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	mappings := p.sortedMappings()
+	result := make([]MappingInfo, 0, len(mappings))
+	for _, m := range mappings {
+		result = append(result, p.states[m.ID].info(*m))
+	}
+	p.mu.Unlock()
+
+	plugins.WriteJSON(w, http.StatusOK, result)
+}
+
+// handleCreateMapping forwards a published port of a container on the
+// router
+func (p *PortMapPlugin) handleCreateMapping(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var req MappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Protocol == "" {
+		req.Protocol = ProtocolTCP
+	}
+
+	// The port must be published by the container, on an address the
+	// router can reach
+	info, err := p.Deps().PodmanClient.InspectContainer(r.Context(), req.ContainerID)
+	if err != nil {
+		apierror.WriteErr(w, r, err, "")
+		return
+	}
+	m := &Mapping{
+		ContainerID:  info.ID,
+		Container:    strings.TrimPrefix(info.Name, "/"),
+		Protocol:     strings.ToLower(req.Protocol),
+		HostPort:     req.HostPort,
+		ExternalPort: req.ExternalPort,
+		Description:  req.Description,
+	}
+	published, localOnly := false, false
+	for spec, bindings := range info.HostConfig.PortBindings {
+		port, protocol, _ := strings.Cut(spec, "/")
+		if protocol == "" {
+			protocol = ProtocolTCP
+		}
+		for _, b := range bindings {
+			if protocol != m.Protocol || b.HostPort != strconv.Itoa(m.HostPort) {
+				continue
+			}
+			if ip := net.ParseIP(b.HostIP); ip != nil && ip.IsLoopback() {
+				localOnly = true
+				continue
+			}
+			published = true
+			m.ContainerPort, _ = strconv.Atoi(port)
+		}
+	}
+	switch {
+	case published:
+	case localOnly:
+		apierror.WriteErr(w, r, errLocalhostOnly, "")
+		return
+	default:
+		apierror.WriteErr(w, r, errNotPublished, "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	if err := p.addMapping(ctx, m); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to create mapping")
+		return
+	}
+
+	p.Logger().Printf("[%s] Mapped external %s port %d to %s port %d", p.Name(), m.Protocol, m.ExternalPort, m.Container, m.HostPort)
+	p.mu.Lock()
+	result := p.states[m.ID].info(*m)
+	p.mu.Unlock()
+	plugins.WriteJSON(w, http.StatusCreated, result)
+}
+
+// handleDeleteMapping removes a mapping from the router
+func (p *PortMapPlugin) handleDeleteMapping(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	id := chi.URLParam(r, "id")
+	if err := p.removeMapping(ctx, id); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to remove mapping")
+		return
+	}
+
+	p.Logger().Printf("[%s] Removed mapping %s", p.Name(), id)
+	plugins.WriteJSON(w, http.StatusOK, map[string]string{"status": "Mapping removed"})
+}
+
+// handleRouterMappings lists all mappings of the router, including those
+// of other hosts and applications. Only UPnP routers can list them.
+func (p *PortMapPlugin) handleRouterMappings(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	var mappings []RouterMapping
+	var localIP string
+	err := p.withGateway(ctx, func(gw gateway) error {
+		if u, ok := gw.(*upnpGateway); ok {
+			localIP = u.localIP
+		}
+		var err error
+		mappings, err = gw.ListMappings(ctx)
+		if errors.Is(err, errListUnsupported) {
+			// The router is fine, keep it
+			return nil
+		}
+		return err
+	})
+	if err == nil && mappings == nil {
+		err = errListUnsupported
+	}
+	if err != nil {
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) {
+			err = apierror.Wrap(err, http.StatusBadGateway, "Failed to list router mappings: "+err.Error())
+		}
+		apierror.WriteErr(w, r, err, "")
+		return
+	}
+
+	p.mu.Lock()
+	for i := range mappings {
+		for id, m := range p.mappings {
+			state := p.states[id]
+			if state.active && m.Protocol == mappings[i].Protocol && state.externalPort == mappings[i].ExternalPort && mappings[i].InternalClient == localIP {
+				mappings[i].Ours = true
+			}
+		}
+	}
+	p.mu.Unlock()
+	plugins.WriteJSON(w, http.StatusOK, mappings)
+}
+
+// handleGetSettings returns the settings
+func (p *PortMapPlugin) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	settings := p.settings
+	p.mu.Unlock()
+	plugins.WriteJSON(w, http.StatusOK, settings)
+}
+
+// handleUpdateSettings replaces the settings
+func (p *PortMapPlugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var settings Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := p.updateSettings(settings); err != nil {
+		apierror.WriteErr(w, r, err, "Failed to save settings")
+		return
+	}
+
+	p.Logger().Printf("[%s] Settings updated, method %s", p.Name(), settings.Method)
+	p.mu.Lock()
+	settings = p.settings
+	p.mu.Unlock()
+	plugins.WriteJSON(w, http.StatusOK, settings)
+}
+
+// info returns the state of a mapping for the API
+func (s *mappingState) info(m Mapping) MappingInfo {
+	info := MappingInfo{Mapping: m, Error: s.err}
+	if s.active {
+		info.Active, info.Method, info.GrantedPort = true, s.method, s.externalPort
+		renewed := s.renewedAt
+		info.RenewedAt = &renewed
+		if s.lifetime > 0 {
+			expires := s.renewedAt.Add(s.lifetime)
+			info.ExpiresAt = &expires
+		}
+	}
+	return info
+}
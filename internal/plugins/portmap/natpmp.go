@@ -0,0 +1,167 @@
+package portmap
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// natpmpPort is the UDP port of NAT-PMP on the gateway (RFC 6886)
+const natpmpPort = 5351
+
+// natpmpResults are the result codes of NAT-PMP responses
+var natpmpResults = map[uint16]string{
+	1: "unsupported version",
+	2: "not authorized or refused",
+	3: "network failure",
+	4: "out of resources",
+	5: "unsupported opcode",
+}
+
+// natpmpGateway maps ports with NAT-PMP, which routers such as Apple
+// AirPort, pfSense and OpenWrt with miniupnpd support. NAT-PMP can't list
+// mappings.
+type natpmpGateway struct {
+	addr string // host:port of the gateway
+}
+
+// Method returns MethodNATPMP
+func (g *natpmpGateway) Method() string { return MethodNATPMP }
+
+// Address returns the gateway address
+func (g *natpmpGateway) Address() string { return g.addr }
+
+// ExternalIP asks the gateway for its public address
+func (g *natpmpGateway) ExternalIP(ctx context.Context) (string, error) {
+	resp, err := g.request(ctx, []byte{0, 0}, 12)
+	if err != nil {
+		return "", err
+	}
+	return net.IP(resp[8:12]).String(), nil
+}
+
+// AddMapping requests a mapping from externalPort to internalPort of this
+// host. The gateway may grant another external port and lifetime.
+func (g *natpmpGateway) AddMapping(ctx context.Context, m Mapping, lifetime time.Duration) (int, time.Duration, error) {
+	req := make([]byte, 12)
+	req[1] = natpmpOpcode(m.Protocol)
+	binary.BigEndian.PutUint16(req[4:], uint16(m.HostPort))
+	binary.BigEndian.PutUint16(req[6:], uint16(m.ExternalPort))
+	binary.BigEndian.PutUint32(req[8:], uint32(lifetime.Seconds()))
+	resp, err := g.request(ctx, req, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	external := int(binary.BigEndian.Uint16(resp[10:]))
+	granted := time.Duration(binary.BigEndian.Uint32(resp[12:])) * time.Second
+	return external, granted, nil
+}
+
+// DeleteMapping removes a mapping by requesting it with a zero lifetime
+func (g *natpmpGateway) DeleteMapping(ctx context.Context, m Mapping) error {
+	req := make([]byte, 12)
+	req[1] = natpmpOpcode(m.Protocol)
+	binary.BigEndian.PutUint16(req[4:], uint16(m.HostPort))
+	_, err := g.request(ctx, req, 16)
+	return err
+}
+
+// ListMappings isn't part of NAT-PMP
+func (g *natpmpGateway) ListMappings(ctx context.Context) ([]RouterMapping, error) {
+	return nil, errListUnsupported
+}
+
+// request sends a request and waits for the response, retrying with a
+// doubling timeout from 250 ms (RFC 6886 section 3.1)
+func (g *natpmpGateway) request(ctx context.Context, req []byte, size int) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp4", g.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp := make([]byte, 16)
+	timeout := 250 * time.Millisecond
+	for try := 0; try < 4; try++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+		for {
+			n, err := conn.Read(resp)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+					break // try again
+				}
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, err
+			}
+			// Skip stray packets, e.g. address change announcements
+			if n < size || resp[0] != 0 || resp[1] != req[1]+128 {
+				continue
+			}
+			if code := binary.BigEndian.Uint16(resp[2:]); code != 0 {
+				if msg, ok := natpmpResults[code]; ok {
+					return nil, fmt.Errorf("NAT-PMP: %s", msg)
+				}
+				return nil, fmt.Errorf("NAT-PMP: result code %d", code)
+			}
+			return resp[:n], nil
+		}
+		timeout *= 2
+	}
+	return nil, errors.New("NAT-PMP: no response from gateway")
+}
+
+// natpmpOpcode returns the opcode mapping a protocol
+func natpmpOpcode(protocol string) byte {
+	if protocol == ProtocolUDP {
+		return 1
+	}
+	return 2
+}
+
+// defaultGateway returns the IPv4 default gateway from /proc/net/route
+func defaultGateway() (net.IP, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Iface Destination Gateway Flags ...
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		// Host byte order, little-endian on the supported platforms
+		if ip := net.IPv4(b[3], b[2], b[1], b[0]); !ip.IsUnspecified() {
+			return ip, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("no default gateway")
+}
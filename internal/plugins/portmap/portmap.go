@@ -0,0 +1,550 @@
+// Package portmap provides a plugin requesting port forwarding from the
+// router with NAT-PMP or UPnP for published container ports
+package portmap
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/plugins"
+	"podmanview/internal/storage"
+)
+
+//go:embed index.html
+var htmlContent []byte
+
+// Port mapping methods
+const (
+	MethodAuto   = "auto" // NAT-PMP, then UPnP
+	MethodNATPMP = "natpmp"
+	MethodUPnP   = "upnp"
+)
+
+// Protocols
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+const (
+	// mappingsKey and settingsKey are the storage keys of the requested
+	// mappings and the settings
+	mappingsKey = "mappings"
+	settingsKey = "settings"
+
+	// tickInterval is how often mappings are checked for renewal
+	tickInterval = 30 * time.Second
+
+	// retryInterval is how long a failed mapping waits for another try
+	retryInterval = time.Minute
+
+	// requestTimeout limits discovery and requests to the router
+	requestTimeout = 10 * time.Second
+
+	defaultLease = 3600
+	minLease     = 120
+	maxLease     = 86400
+)
+
+var (
+	errInvalidMethod   = apierror.New(http.StatusBadRequest, "Invalid method, use auto, natpmp or upnp")
+	errInvalidGateway  = apierror.New(http.StatusBadRequest, "Invalid gateway address")
+	errInvalidUPnPURL  = apierror.New(http.StatusBadRequest, "Invalid UPnP description URL, use http://")
+	errInvalidLease    = apierror.New(http.StatusBadRequest, fmt.Sprintf("Lease must be between %d and %d seconds", minLease, maxLease))
+	errInvalidProtocol = apierror.New(http.StatusBadRequest, "Invalid protocol, use tcp or udp")
+	errInvalidPort     = apierror.New(http.StatusBadRequest, "Invalid port")
+	errNotPublished    = apierror.New(http.StatusBadRequest, "The container doesn't publish this port")
+	errLocalhostOnly   = apierror.New(http.StatusBadRequest, "The port is only published on localhost")
+	errMappingExists   = apierror.New(http.StatusConflict, "The external port is already mapped")
+	errMappingMissing  = apierror.New(http.StatusNotFound, "Mapping not found")
+	errListUnsupported = apierror.New(http.StatusNotImplemented, "The gateway can't list its mappings (NAT-PMP)")
+)
+
+// gateway is a router that maps ports
+type gateway interface {
+	Method() string
+	Address() string
+	ExternalIP(ctx context.Context) (string, error)
+	// AddMapping returns the external port and lifetime granted, a zero
+	// lifetime for a permanent mapping
+	AddMapping(ctx context.Context, m Mapping, lifetime time.Duration) (int, time.Duration, error)
+	DeleteMapping(ctx context.Context, m Mapping) error
+	ListMappings(ctx context.Context) ([]RouterMapping, error)
+}
+
+// Settings selects how the router is found
+type Settings struct {
+	Method       string `json:"method"`            // auto, natpmp or upnp
+	Gateway      string `json:"gateway,omitempty"` // NAT-PMP gateway host[:port], empty for the default gateway
+	UPnPURL      string `json:"upnpUrl,omitempty"` // IGD description URL, empty for SSDP discovery
+	LeaseSeconds int    `json:"leaseSeconds"`      // requested mapping lifetime, renewed at half
+}
+
+// Mapping is a port forwarding requested from the router, from
+// ExternalPort on the router to HostPort, a published container port
+type Mapping struct {
+	ID            string    `json:"id"`
+	ContainerID   string    `json:"containerId"`
+	Container     string    `json:"container"`
+	Protocol      string    `json:"protocol"` // tcp or udp
+	HostPort      int       `json:"hostPort"`
+	ContainerPort int       `json:"containerPort"`
+	ExternalPort  int       `json:"externalPort"`
+	Description   string    `json:"description"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// mappingState is the state of a mapping on the router
+type mappingState struct {
+	active       bool
+	method       string
+	externalPort int           // granted external port
+	lifetime     time.Duration // granted lifetime, 0 for permanent
+	renewedAt    time.Time
+	lastTry      time.Time
+	err          string
+}
+
+// RouterMapping is a port mapping of the router
+type RouterMapping struct {
+	Protocol       string `json:"protocol"`
+	ExternalPort   int    `json:"externalPort"`
+	InternalClient string `json:"internalClient"`
+	InternalPort   int    `json:"internalPort"`
+	Description    string `json:"description"`
+	Enabled        bool   `json:"enabled"`
+	LeaseSeconds   int    `json:"leaseSeconds"` // remaining, 0 for permanent
+	Ours           bool   `json:"ours"`         // requested by this host
+}
+
+// PortMapPlugin keeps port mappings of published container ports on the
+// router
+type PortMapPlugin struct {
+	*plugins.BasePlugin
+
+	mu       sync.Mutex
+	settings Settings
+	mappings map[string]*Mapping
+	states   map[string]*mappingState
+
+	// gwMu serializes discovery and requests to the router
+	gwMu       sync.Mutex
+	gw         gateway
+	httpClient *http.Client
+}
+
+// New creates a new PortMapPlugin instance
+func New() *PortMapPlugin {
+	return &PortMapPlugin{
+		BasePlugin: plugins.NewBasePlugin(
+			"portmap",
+			"Port Mapping — forward published container ports on the router with UPnP or NAT-PMP",
+			"1.0.0",
+			htmlContent,
+		),
+		settings:   Settings{Method: MethodAuto, LeaseSeconds: defaultLease},
+		mappings:   make(map[string]*Mapping),
+		states:     make(map[string]*mappingState),
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Init initializes the plugin
+func (p *PortMapPlugin) Init(ctx context.Context, deps *plugins.PluginDependencies) error {
+	p.SetDependencies(deps)
+
+	if err := p.load(); err != nil {
+		p.Logger().Printf("[%s] Warning: failed to load mappings: %v", p.Name(), err)
+	}
+
+	p.Logger().Printf("[%s] Plugin initialized, %d mappings loaded", p.Name(), len(p.mappings))
+	return nil
+}
+
+// Start starts the plugin
+func (p *PortMapPlugin) Start(ctx context.Context) error {
+	p.Logger().Printf("[%s] Plugin started", p.Name())
+	return nil
+}
+
+// Stop removes the mappings from the router, so ports don't stay open
+// while PodmanView isn't running. They are requested again on start.
+func (p *PortMapPlugin) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	var active []Mapping
+	for id, m := range p.mappings {
+		if state := p.states[id]; state.active {
+			mapping := *m
+			mapping.ExternalPort = state.externalPort
+			active = append(active, mapping)
+			state.active = false
+		}
+	}
+	p.mu.Unlock()
+
+	if len(active) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+		err := p.withGateway(ctx, func(gw gateway) error {
+			for _, m := range active {
+				if err := gw.DeleteMapping(ctx, m); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			p.Logger().Printf("[%s] Failed to remove mappings from the router: %v", p.Name(), err)
+		}
+	}
+
+	p.Logger().Printf("[%s] Plugin stopped", p.Name())
+	return nil
+}
+
+// Routes returns the plugin's HTTP routes
+func (p *PortMapPlugin) Routes() []plugins.Route {
+	return []plugins.Route{
+		{Method: "GET", Path: "/api/plugins/portmap/gateway", Handler: p.handleGateway, RequireAuth: true, Summary: "Find the router and its external address"},
+		{Method: "GET", Path: "/api/plugins/portmap/ports", Handler: p.handlePorts, RequireAuth: true, Summary: "List published container ports that can be mapped"},
+		{Method: "GET", Path: "/api/plugins/portmap/mappings", Handler: p.handleListMappings, RequireAuth: true, Summary: "List requested port mappings with their status"},
+		{Method: "POST", Path: "/api/plugins/portmap/mappings", Handler: p.handleCreateMapping, RequireAuth: true, Summary: "Forward a published container port on the router"},
+		{Method: "DELETE", Path: "/api/plugins/portmap/mappings/{id}", Handler: p.handleDeleteMapping, RequireAuth: true, Summary: "Remove a port mapping from the router"},
+		{Method: "GET", Path: "/api/plugins/portmap/router", Handler: p.handleRouterMappings, RequireAuth: true, Summary: "List the port mappings of the router"},
+		{Method: "GET", Path: "/api/plugins/portmap/settings", Handler: p.handleGetSettings, RequireAuth: true, Summary: "Get port mapping settings"},
+		{Method: "PUT", Path: "/api/plugins/portmap/settings", Handler: p.handleUpdateSettings, RequireAuth: true, Summary: "Update port mapping settings"},
+	}
+}
+
+// IsEnabled checks if the plugin is enabled
+func (p *PortMapPlugin) IsEnabled() bool {
+	if p.Deps() == nil || p.Deps().Storage == nil {
+		return false
+	}
+	enabled, err := p.Deps().Storage.IsPluginEnabled(p.Name())
+	if err != nil {
+		return false
+	}
+	return enabled
+}
+
+// StartBackgroundTasks requests the mappings and renews them before they
+// expire
+func (p *PortMapPlugin) StartBackgroundTasks(ctx context.Context) error {
+	go plugins.RunPeriodic(ctx, tickInterval, p.Logger(), p.Name(), func(ctx context.Context) error {
+		p.renewDue(ctx, time.Now())
+		return nil
+	})
+	return nil
+}
+
+// load reads the settings and mappings from storage
+func (p *PortMapPlugin) load() error {
+	st := p.Deps().Storage
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := st.GetJSON(p.Name(), settingsKey, &p.settings); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	var list []*Mapping
+	if err := st.GetJSON(p.Name(), mappingsKey, &list); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	for _, m := range list {
+		p.mappings[m.ID] = m
+		p.states[m.ID] = &mappingState{}
+	}
+	return nil
+}
+
+// saveMappings writes the mapping list to storage. Call with p.mu held.
+func (p *PortMapPlugin) saveMappings() error {
+	return p.Deps().Storage.SetJSON(p.Name(), mappingsKey, p.sortedMappings())
+}
+
+// sortedMappings returns the mappings ordered by external port. Call with
+// p.mu held.
+func (p *PortMapPlugin) sortedMappings() []*Mapping {
+	list := make([]*Mapping, 0, len(p.mappings))
+	for _, m := range p.mappings {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ExternalPort != list[j].ExternalPort {
+			return list[i].ExternalPort < list[j].ExternalPort
+		}
+		return list[i].Protocol < list[j].Protocol
+	})
+	return list
+}
+
+// validate checks settings and fills in defaults
+func (s *Settings) validate() error {
+	switch s.Method {
+	case "":
+		s.Method = MethodAuto
+	case MethodAuto, MethodNATPMP, MethodUPnP:
+	default:
+		return errInvalidMethod
+	}
+	if s.Gateway != "" {
+		host := s.Gateway
+		if h, port, err := net.SplitHostPort(s.Gateway); err == nil {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return errInvalidGateway
+			}
+			host = h
+		}
+		if net.ParseIP(host).To4() == nil {
+			return errInvalidGateway
+		}
+	}
+	if s.UPnPURL != "" && !strings.HasPrefix(s.UPnPURL, "http://") && !strings.HasPrefix(s.UPnPURL, "https://") {
+		return errInvalidUPnPURL
+	}
+	if s.LeaseSeconds == 0 {
+		s.LeaseSeconds = defaultLease
+	}
+	if s.LeaseSeconds < minLease || s.LeaseSeconds > maxLease {
+		return errInvalidLease
+	}
+	return nil
+}
+
+// updateSettings stores new settings; the router is discovered again
+func (p *PortMapPlugin) updateSettings(s Settings) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if err := p.Deps().Storage.SetJSON(p.Name(), settingsKey, s); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.settings = s
+	p.mu.Unlock()
+
+	p.gwMu.Lock()
+	p.gw = nil
+	p.gwMu.Unlock()
+	return nil
+}
+
+// withGateway runs fn with the router, which is discovered on first use.
+// After an error it is discovered again next time, in case it changed.
+func (p *PortMapPlugin) withGateway(ctx context.Context, fn func(gw gateway) error) error {
+	p.gwMu.Lock()
+	defer p.gwMu.Unlock()
+	if p.gw == nil {
+		gw, err := p.discover(ctx)
+		if err != nil {
+			return err
+		}
+		p.gw = gw
+	}
+	err := fn(p.gw)
+	if err != nil {
+		p.gw = nil
+	}
+	return err
+}
+
+// discover finds the router with the configured method. Call with p.gwMu
+// held.
+func (p *PortMapPlugin) discover(ctx context.Context) (gateway, error) {
+	p.mu.Lock()
+	settings := p.settings
+	p.mu.Unlock()
+
+	var natpmpErr error
+	if settings.Method != MethodUPnP {
+		addr := settings.Gateway
+		if addr == "" {
+			ip, err := defaultGateway()
+			if err != nil {
+				return nil, err
+			}
+			addr = ip.String()
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, strconv.Itoa(natpmpPort))
+		}
+		gw := &natpmpGateway{addr: addr}
+		if _, natpmpErr = gw.ExternalIP(ctx); natpmpErr == nil || settings.Method == MethodNATPMP {
+			return gw, natpmpErr
+		}
+	}
+
+	gw, err := discoverUPnP(ctx, p.httpClient, settings.UPnPURL)
+	if err != nil {
+		if natpmpErr != nil {
+			return nil, fmt.Errorf("%v; %v", natpmpErr, err)
+		}
+		return nil, err
+	}
+	return gw, nil
+}
+
+// addMapping validates a mapping, requests it from the router and stores
+// it. Mappings the router refuses aren't stored.
+func (p *PortMapPlugin) addMapping(ctx context.Context, m *Mapping) error {
+	if m.Protocol != ProtocolTCP && m.Protocol != ProtocolUDP {
+		return errInvalidProtocol
+	}
+	if m.ExternalPort == 0 {
+		m.ExternalPort = m.HostPort
+	}
+	if m.HostPort < 1 || m.HostPort > 65535 || m.ExternalPort < 1 || m.ExternalPort > 65535 {
+		return errInvalidPort
+	}
+	if m.Description == "" {
+		m.Description = "PodmanView " + m.Container
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	m.ID = id
+	m.CreatedAt = time.Now()
+
+	p.mu.Lock()
+	for _, other := range p.mappings {
+		if other.Protocol == m.Protocol && other.ExternalPort == m.ExternalPort {
+			p.mu.Unlock()
+			return errMappingExists
+		}
+	}
+	lease := time.Duration(p.settings.LeaseSeconds) * time.Second
+	p.mu.Unlock()
+
+	state := &mappingState{lastTry: time.Now()}
+	err = p.withGateway(ctx, func(gw gateway) error {
+		external, lifetime, err := gw.AddMapping(ctx, *m, lease)
+		if err != nil {
+			return err
+		}
+		state.active, state.method, state.externalPort, state.lifetime, state.renewedAt = true, gw.Method(), external, lifetime, time.Now()
+		return nil
+	})
+	if err != nil {
+		return apierror.New(http.StatusBadGateway, "The router refused the mapping: "+err.Error())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mappings[m.ID] = m
+	p.states[m.ID] = state
+	if err := p.saveMappings(); err != nil {
+		delete(p.mappings, m.ID)
+		delete(p.states, m.ID)
+		return err
+	}
+	return nil
+}
+
+// removeMapping removes a mapping from the router and from storage. It
+// is removed from storage even if the router can't be reached, the
+// mapping then expires on its own.
+func (p *PortMapPlugin) removeMapping(ctx context.Context, id string) error {
+	p.mu.Lock()
+	m, exists := p.mappings[id]
+	if !exists {
+		p.mu.Unlock()
+		return errMappingMissing
+	}
+	mapping, state := *m, p.states[id]
+	delete(p.mappings, id)
+	delete(p.states, id)
+	if err := p.saveMappings(); err != nil {
+		p.mappings[id], p.states[id] = m, state
+		p.mu.Unlock()
+		return err
+	}
+	p.mu.Unlock()
+
+	if state.active {
+		mapping.ExternalPort = state.externalPort
+		err := p.withGateway(ctx, func(gw gateway) error {
+			return gw.DeleteMapping(ctx, mapping)
+		})
+		if err != nil {
+			p.Logger().Printf("[%s] Failed to remove mapping of port %d from the router: %v", p.Name(), mapping.ExternalPort, err)
+		}
+	}
+	return nil
+}
+
+// renewDue requests the mappings that aren't active yet or are past half
+// of their lifetime. Permanent mappings are requested again every lease,
+// in case the router restarted.
+func (p *PortMapPlugin) renewDue(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	lease := time.Duration(p.settings.LeaseSeconds) * time.Second
+	var due []Mapping
+	for id, m := range p.mappings {
+		state := p.states[id]
+		switch {
+		case !state.active:
+			if now.Sub(state.lastTry) < retryInterval {
+				continue
+			}
+		case state.lifetime == 0:
+			if now.Sub(state.renewedAt) < lease {
+				continue
+			}
+		case now.Sub(state.renewedAt) < state.lifetime/2:
+			continue
+		}
+		state.lastTry = now
+		due = append(due, *m)
+	}
+	p.mu.Unlock()
+
+	for _, m := range due {
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		var method string
+		var external int
+		var lifetime time.Duration
+		err := p.withGateway(ctx, func(gw gateway) error {
+			var err error
+			method = gw.Method()
+			external, lifetime, err = gw.AddMapping(ctx, m, lease)
+			return err
+		})
+		cancel()
+
+		p.mu.Lock()
+		if state, exists := p.states[m.ID]; exists {
+			if err != nil {
+				if state.active || state.err == "" {
+					p.Logger().Printf("[%s] Failed to renew mapping of port %d: %v", p.Name(), m.ExternalPort, err)
+				}
+				state.active, state.err = false, err.Error()
+			} else {
+				state.active, state.method, state.externalPort, state.lifetime, state.renewedAt, state.err = true, method, external, lifetime, time.Now(), ""
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// newID returns a random mapping ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,341 @@
+package portmap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ssdpAddr is the multicast address of SSDP discovery
+const ssdpAddr = "239.255.255.250:1900"
+
+// maxRouterMappings limits the mappings listed from a router
+const maxRouterMappings = 256
+
+// UPnP error codes of WANIPConnection
+const (
+	upnpInvalidIndex   = 713 // SpecifiedArrayIndexInvalid, end of the mapping list
+	upnpPermanentLease = 725 // OnlyPermanentLeasesSupported
+)
+
+// wanServices are the IGD services that manage port mappings, preferred
+// first
+var wanServices = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+// upnpError is a UPnP error returned by a router
+type upnpError struct {
+	Code        int
+	Description string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("UPnP error %d: %s", e.Code, e.Description)
+}
+
+// upnpGateway maps ports with the port mapping service of a UPnP Internet
+// Gateway Device
+type upnpGateway struct {
+	client      *http.Client
+	controlURL  string
+	serviceType string
+	localIP     string // address of this host towards the router
+}
+
+// Method returns MethodUPnP
+func (g *upnpGateway) Method() string { return MethodUPnP }
+
+// Address returns the control URL of the router
+func (g *upnpGateway) Address() string { return g.controlURL }
+
+// ExternalIP asks the router for its public address
+func (g *upnpGateway) ExternalIP(ctx context.Context) (string, error) {
+	resp, err := g.call(ctx, "GetExternalIPAddress", nil)
+	if err != nil {
+		return "", err
+	}
+	return resp["NewExternalIPAddress"], nil
+}
+
+// AddMapping maps externalPort to the host port of this host. Routers
+// that only support permanent mappings get a lease of 0.
+func (g *upnpGateway) AddMapping(ctx context.Context, m Mapping, lifetime time.Duration) (int, time.Duration, error) {
+	args := func(lease time.Duration) [][2]string {
+		return [][2]string{
+			{"NewRemoteHost", ""},
+			{"NewExternalPort", strconv.Itoa(m.ExternalPort)},
+			{"NewProtocol", strings.ToUpper(m.Protocol)},
+			{"NewInternalPort", strconv.Itoa(m.HostPort)},
+			{"NewInternalClient", g.localIP},
+			{"NewEnabled", "1"},
+			{"NewPortMappingDescription", m.Description},
+			{"NewLeaseDuration", strconv.Itoa(int(lease.Seconds()))},
+		}
+	}
+	_, err := g.call(ctx, "AddPortMapping", args(lifetime))
+	var upnpErr *upnpError
+	if errors.As(err, &upnpErr) && upnpErr.Code == upnpPermanentLease {
+		lifetime = 0
+		_, err = g.call(ctx, "AddPortMapping", args(0))
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return m.ExternalPort, lifetime, nil
+}
+
+// DeleteMapping removes a mapping from the router
+func (g *upnpGateway) DeleteMapping(ctx context.Context, m Mapping) error {
+	_, err := g.call(ctx, "DeletePortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(m.ExternalPort)},
+		{"NewProtocol", strings.ToUpper(m.Protocol)},
+	})
+	return err
+}
+
+// ListMappings returns the mappings of the router, of all hosts
+func (g *upnpGateway) ListMappings(ctx context.Context) ([]RouterMapping, error) {
+	mappings := []RouterMapping{}
+	for i := 0; i < maxRouterMappings; i++ {
+		resp, err := g.call(ctx, "GetGenericPortMappingEntry", [][2]string{{"NewPortMappingIndex", strconv.Itoa(i)}})
+		var upnpErr *upnpError
+		if errors.As(err, &upnpErr) && (upnpErr.Code == upnpInvalidIndex || i > 0) {
+			break // end of the list; some routers answer with another code
+		}
+		if err != nil {
+			return nil, err
+		}
+		m := RouterMapping{
+			Protocol:       strings.ToLower(resp["NewProtocol"]),
+			InternalClient: resp["NewInternalClient"],
+			Description:    resp["NewPortMappingDescription"],
+			Enabled:        resp["NewEnabled"] == "1",
+		}
+		m.ExternalPort, _ = strconv.Atoi(resp["NewExternalPort"])
+		m.InternalPort, _ = strconv.Atoi(resp["NewInternalPort"])
+		m.LeaseSeconds, _ = strconv.Atoi(resp["NewLeaseDuration"])
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+// call invokes a SOAP action of the port mapping service and returns the
+// response arguments
+func (g *upnpGateway) call(ctx context.Context, action string, args [][2]string) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, g.serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&body, "<%s>%s</%s>", arg[0], html.EscapeString(arg[1]), arg[0])
+	}
+	fmt.Fprintf(&body, "</u:%s></s:Body></s:Envelope>", action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, g.serviceType, action))
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	values := soapValues(data)
+	if resp.StatusCode != http.StatusOK {
+		if code, err := strconv.Atoi(values["errorCode"]); err == nil {
+			return nil, &upnpError{Code: code, Description: values["errorDescription"]}
+		}
+		return nil, fmt.Errorf("UPnP %s: HTTP %d", action, resp.StatusCode)
+	}
+	return values, nil
+}
+
+// soapValues returns the text of the leaf elements of a SOAP response by
+// local name
+func soapValues(data []byte) map[string]string {
+	values := make(map[string]string)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var name string
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return values
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name = t.Name.Local
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if t.Name.Local == name {
+				values[name] = strings.TrimSpace(text.String())
+			}
+			name = ""
+		}
+	}
+}
+
+// igdDevice is a device in the description of an Internet Gateway Device
+type igdDevice struct {
+	Services []struct {
+		ServiceType string `xml:"serviceType"`
+		ControlURL  string `xml:"controlURL"`
+	} `xml:"serviceList>service"`
+	Devices []igdDevice `xml:"deviceList>device"`
+}
+
+// discoverUPnP finds a router with SSDP, or uses the description URL
+// location when set
+func discoverUPnP(ctx context.Context, client *http.Client, location string) (*upnpGateway, error) {
+	if location == "" {
+		var err error
+		if location, err = ssdpSearch(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return newUPnPGateway(ctx, client, location)
+}
+
+// newUPnPGateway reads the device description at location and finds the
+// port mapping service
+func newUPnPGateway(ctx context.Context, client *http.Client, location string) (*upnpGateway, error) {
+	base, err := url.Parse(location)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid UPnP description URL %q", location)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("UPnP description: HTTP %d", resp.StatusCode)
+	}
+	var desc struct {
+		URLBase string    `xml:"URLBase"`
+		Device  igdDevice `xml:"device"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&desc); err != nil {
+		return nil, fmt.Errorf("UPnP description: %w", err)
+	}
+	if desc.URLBase != "" {
+		if u, err := url.Parse(desc.URLBase); err == nil {
+			base = u
+		}
+	}
+
+	for _, serviceType := range wanServices {
+		if controlURL := findService(desc.Device, serviceType); controlURL != "" {
+			ref, err := url.Parse(controlURL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UPnP control URL %q", controlURL)
+			}
+			control := base.ResolveReference(ref)
+			localIP, err := localAddress(control.Host)
+			if err != nil {
+				return nil, err
+			}
+			return &upnpGateway{client: client, controlURL: control.String(), serviceType: serviceType, localIP: localIP}, nil
+		}
+	}
+	return nil, errors.New("UPnP device has no port mapping service")
+}
+
+// findService returns the control URL of a service of a device or its
+// embedded devices
+func findService(d igdDevice, serviceType string) string {
+	for _, s := range d.Services {
+		if s.ServiceType == serviceType {
+			return s.ControlURL
+		}
+	}
+	for _, child := range d.Devices {
+		if controlURL := findService(child, serviceType); controlURL != "" {
+			return controlURL
+		}
+	}
+	return ""
+}
+
+// ssdpSearch returns the description URL of the first Internet Gateway
+// Device answering an SSDP search
+func ssdpSearch(ctx context.Context) (string, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return "", err
+	}
+
+	for _, target := range []string{"urn:schemas-upnp-org:device:InternetGatewayDevice:1", "urn:schemas-upnp-org:device:InternetGatewayDevice:2"} {
+		msg := "M-SEARCH * HTTP/1.1\r\nHOST: " + ssdpAddr + "\r\nST: " + target + "\r\nMAN: \"ssdp:discover\"\r\nMX: 2\r\n\r\n"
+		if _, err := conn.WriteTo([]byte(msg), dst); err != nil {
+			return "", err
+		}
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return "", errors.New("no UPnP gateway answered")
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if location := resp.Header.Get("Location"); resp.StatusCode == http.StatusOK && location != "" {
+			return location, nil
+		}
+	}
+}
+
+// localAddress returns the address of this host used to reach host, which
+// the router forwards to
+func localAddress(host string) (string, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	conn, err := net.Dial("udp4", net.JoinHostPort(host, "1900"))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
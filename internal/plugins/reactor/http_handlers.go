@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 
+	"podmanview/internal/apierror"
 	"podmanview/internal/mqtt"
 	"podmanview/internal/plugins"
 )
@@ -36,12 +37,12 @@ func (p *Plugin) handleGetBlocks(w http.ResponseWriter, r *http.Request) {
 func (p *Plugin) handleCreateBlock(w http.ResponseWriter, r *http.Request) {
 	var block ReactionBlock
 	if err := json.NewDecoder(r.Body).Decode(&block); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if err := validateBlock(block); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		apierror.Write(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -52,7 +53,7 @@ func (p *Plugin) handleCreateBlock(w http.ResponseWriter, r *http.Request) {
 	p.mu.Unlock()
 
 	if err := p.saveBlocks(); err != nil {
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save blocks"})
+		apierror.Write(w, r, http.StatusInternalServerError, "Failed to save blocks")
 		return
 	}
 
@@ -66,17 +67,17 @@ func (p *Plugin) handleCreateBlock(w http.ResponseWriter, r *http.Request) {
 func (p *Plugin) handleUpdateBlock(w http.ResponseWriter, r *http.Request) {
 	var block ReactionBlock
 	if err := json.NewDecoder(r.Body).Decode(&block); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if block.ID == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Block ID is required"})
+		apierror.Write(w, r, http.StatusBadRequest, "Block ID is required")
 		return
 	}
 
 	if err := validateBlock(block); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		apierror.Write(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -92,12 +93,12 @@ func (p *Plugin) handleUpdateBlock(w http.ResponseWriter, r *http.Request) {
 	p.mu.Unlock()
 
 	if !found {
-		plugins.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Block not found"})
+		apierror.Write(w, r, http.StatusNotFound, "Block not found")
 		return
 	}
 
 	if err := p.saveBlocks(); err != nil {
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save blocks"})
+		apierror.Write(w, r, http.StatusInternalServerError, "Failed to save blocks")
 		return
 	}
 
@@ -113,7 +114,7 @@ func (p *Plugin) handleDeleteBlock(w http.ResponseWriter, r *http.Request) {
 		ID string `json:"id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Block ID is required"})
+		apierror.Write(w, r, http.StatusBadRequest, "Block ID is required")
 		return
 	}
 
@@ -129,12 +130,12 @@ func (p *Plugin) handleDeleteBlock(w http.ResponseWriter, r *http.Request) {
 	p.mu.Unlock()
 
 	if !found {
-		plugins.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Block not found"})
+		apierror.Write(w, r, http.StatusNotFound, "Block not found")
 		return
 	}
 
 	if err := p.saveBlocks(); err != nil {
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save blocks"})
+		apierror.Write(w, r, http.StatusInternalServerError, "Failed to save blocks")
 		return
 	}
 
@@ -151,7 +152,7 @@ func (p *Plugin) handleToggleBlock(w http.ResponseWriter, r *http.Request) {
 		Enabled bool   `json:"enabled"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Block ID is required"})
+		apierror.Write(w, r, http.StatusBadRequest, "Block ID is required")
 		return
 	}
 
@@ -167,12 +168,12 @@ func (p *Plugin) handleToggleBlock(w http.ResponseWriter, r *http.Request) {
 	p.mu.Unlock()
 
 	if !found {
-		plugins.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Block not found"})
+		apierror.Write(w, r, http.StatusNotFound, "Block not found")
 		return
 	}
 
 	if err := p.saveBlocks(); err != nil {
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save blocks"})
+		apierror.Write(w, r, http.StatusInternalServerError, "Failed to save blocks")
 		return
 	}
 
@@ -187,7 +188,7 @@ func (p *Plugin) handleTestBlock(w http.ResponseWriter, r *http.Request) {
 		ID string `json:"id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Block ID is required"})
+		apierror.Write(w, r, http.StatusBadRequest, "Block ID is required")
 		return
 	}
 
@@ -203,7 +204,7 @@ func (p *Plugin) handleTestBlock(w http.ResponseWriter, r *http.Request) {
 	p.mu.RUnlock()
 
 	if block == nil {
-		plugins.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Block not found"})
+		apierror.Write(w, r, http.StatusNotFound, "Block not found")
 		return
 	}
 
@@ -267,13 +268,13 @@ func (p *Plugin) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 func (p *Plugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 	var settings PluginSettings
 	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate MQTT settings if enabled
 	if settings.MQTTEnabled && settings.MQTT.Broker == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "MQTT broker is required when MQTT is enabled"})
+		apierror.Write(w, r, http.StatusBadRequest, "MQTT broker is required when MQTT is enabled")
 		return
 	}
 
@@ -295,11 +296,11 @@ func (p *Plugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 				p.Logger().Printf("[%s] Failed to save MQTT enabled state: %v", p.Name(), err)
 			}
 		}
-		if err := deps.Storage.SetJSON(p.Name(), "mqttSettings", p.mqttSettings); err != nil {
+		if err := deps.Storage.SetSecretJSON(p.Name(), "mqttSettings", p.mqttSettings); err != nil {
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to save MQTT settings: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save MQTT settings"})
+			apierror.Write(w, r, http.StatusInternalServerError, "Failed to save MQTT settings")
 			return
 		}
 	}
@@ -310,7 +311,7 @@ func (p *Plugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to create MQTT client: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Failed to create MQTT client: " + err.Error()})
+			apierror.Write(w, r, http.StatusBadRequest, "Failed to create MQTT client: "+err.Error())
 			return
 		}
 		if p.mqttClient != nil {
@@ -318,7 +319,7 @@ func (p *Plugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 				if p.Logger() != nil {
 					p.Logger().Printf("[%s] Failed to connect to MQTT broker: %v", p.Name(), err)
 				}
-				plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Failed to connect to MQTT broker: " + err.Error()})
+				apierror.Write(w, r, http.StatusBadRequest, "Failed to connect to MQTT broker: "+err.Error())
 				return
 			}
 		}
@@ -340,7 +341,7 @@ func (p *Plugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 func (p *Plugin) handleToggleMQTT(w http.ResponseWriter, r *http.Request) {
 	var req MQTTToggleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -349,7 +350,7 @@ func (p *Plugin) handleToggleMQTT(w http.ResponseWriter, r *http.Request) {
 	p.mu.RUnlock()
 
 	if settings.Broker == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "MQTT is not configured. Please set MQTT broker in settings"})
+		apierror.Write(w, r, http.StatusBadRequest, "MQTT is not configured. Please set MQTT broker in settings")
 		return
 	}
 
@@ -363,7 +364,7 @@ func (p *Plugin) handleToggleMQTT(w http.ResponseWriter, r *http.Request) {
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to save MQTT enabled state: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save settings"})
+			apierror.Write(w, r, http.StatusInternalServerError, "Failed to save settings")
 			return
 		}
 	}
@@ -373,7 +374,7 @@ func (p *Plugin) handleToggleMQTT(w http.ResponseWriter, r *http.Request) {
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to create MQTT client: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create MQTT client"})
+			apierror.Write(w, r, http.StatusInternalServerError, "Failed to create MQTT client")
 			return
 		}
 		if p.mqttClient != nil {
@@ -381,7 +382,7 @@ func (p *Plugin) handleToggleMQTT(w http.ResponseWriter, r *http.Request) {
 				if p.Logger() != nil {
 					p.Logger().Printf("[%s] Failed to connect to MQTT broker: %v", p.Name(), err)
 				}
-				plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to MQTT broker"})
+				apierror.Write(w, r, http.StatusInternalServerError, "Failed to connect to MQTT broker")
 				return
 			}
 		}
@@ -586,7 +586,7 @@ func (p *Plugin) loadSettings() {
 
 	// Load MQTT settings
 	var settings mqtt.Config
-	if err := deps.Storage.GetJSON(p.Name(), "mqttSettings", &settings); err == nil {
+	if err := deps.Storage.GetSecretJSON(p.Name(), "mqttSettings", &settings); err == nil {
 		p.mu.Lock()
 		p.mqttSettings = settings
 		if p.mqttSettings.Prefix == "" {
@@ -597,7 +597,7 @@ func (p *Plugin) loadSettings() {
 			p.Logger().Printf("[%s] Loaded MQTT settings: broker=%s", p.Name(), settings.Broker)
 		}
 	} else {
-		deps.Storage.SetJSON(p.Name(), "mqttSettings", p.mqttSettings)
+		deps.Storage.SetSecretJSON(p.Name(), "mqttSettings", p.mqttSettings)
 	}
 }
 
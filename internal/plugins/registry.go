@@ -112,6 +112,13 @@ func (r *Registry) SetRunning(name string, running bool) {
 	r.running[name] = running
 }
 
+// IsRunning reports whether a plugin is currently started
+func (r *Registry) IsRunning(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.running[name]
+}
+
 // EnablePlugin dynamically enables and starts a plugin
 func (r *Registry) EnablePlugin(ctx context.Context, name string) error {
 	r.mu.Lock()
@@ -0,0 +1,98 @@
+package temperature
+
+// historySize is the number of samples kept per sensor, 15 minutes at the
+// default update interval
+const historySize = 60
+
+// ring is a fixed-size ring buffer of temperature samples
+type ring struct {
+	values []float64
+	next   int // index of the next sample
+	full   bool
+}
+
+// add appends a sample, overwriting the oldest one when the ring is full
+func (r *ring) add(v float64) {
+	r.values[r.next] = v
+	r.next = (r.next + 1) % len(r.values)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// samples returns the samples, oldest first
+func (r *ring) samples() []float64 {
+	if !r.full {
+		return append([]float64(nil), r.values[:r.next]...)
+	}
+	return append(append([]float64(nil), r.values[r.next:]...), r.values[:r.next]...)
+}
+
+// History keeps the recent samples of every sensor in ring buffers, for
+// trend sparklines. Sensors that disappear are dropped. Not safe for
+// concurrent use.
+type History struct {
+	size    int
+	sensors map[string]*ring // by sensor key, see historyKey
+}
+
+// NewHistory creates a history keeping size samples per sensor
+func NewHistory(size int) *History {
+	return &History{size: size, sensors: make(map[string]*ring)}
+}
+
+// historyKey identifies a sensor; CPU sensors have no device
+func historyKey(device, label string) string {
+	return device + "/" + label
+}
+
+// Record adds a sample of every sensor in data
+func (h *History) Record(data *TemperatureData) {
+	seen := make(map[string]bool)
+	add := func(key string, temp float64) {
+		seen[key] = true
+		r, ok := h.sensors[key]
+		if !ok {
+			r = &ring{values: make([]float64, h.size)}
+			h.sensors[key] = r
+		}
+		r.add(temp)
+	}
+	for _, t := range data.Temperatures {
+		add(historyKey("", t.Label), t.Temp)
+	}
+	for _, device := range data.StorageTemps {
+		for _, t := range device.Sensors {
+			add(historyKey(device.Device, t.Label), t.Temp)
+		}
+	}
+	for key := range h.sensors {
+		if !seen[key] {
+			delete(h.sensors, key)
+		}
+	}
+}
+
+// Apply returns a copy of data with the recorded samples of each sensor
+// in its History field
+func (h *History) Apply(data *TemperatureData) *TemperatureData {
+	withHistory := func(device string, temps []Temperature) []Temperature {
+		result := make([]Temperature, len(temps))
+		for i, t := range temps {
+			result[i] = Temperature{Label: t.Label, Temp: t.Temp}
+			if r, ok := h.sensors[historyKey(device, t.Label)]; ok {
+				result[i].History = r.samples()
+			}
+		}
+		return result
+	}
+
+	result := &TemperatureData{
+		Temperatures: withHistory("", data.Temperatures),
+		StorageTemps: make([]StorageTemp, len(data.StorageTemps)),
+	}
+	for i, device := range data.StorageTemps {
+		result.StorageTemps[i] = StorageTemp{Device: device.Device, Sensors: withHistory(device.Device, device.Sensors)}
+	}
+	return result
+}
@@ -6,15 +6,17 @@ import (
 	"strconv"
 	"time"
 
+	"podmanview/internal/apierror"
 	"podmanview/internal/mqtt"
 	"podmanview/internal/plugins"
 )
 
 // PluginSettings represents plugin configuration
 type PluginSettings struct {
-	UpdateInterval int         `json:"updateInterval"` // Update interval in seconds
-	MQTTEnabled    bool        `json:"mqttEnabled"`    // MQTT publishing enabled
-	MQTT           mqtt.Config `json:"mqtt"`           // MQTT configuration
+	UpdateInterval int         `json:"updateInterval"`           // Update interval in seconds
+	AlertThreshold *int        `json:"alertThreshold,omitempty"` // Temperature alert in °C, 0 = off, omitted = unchanged
+	MQTTEnabled    bool        `json:"mqttEnabled"`              // MQTT publishing enabled
+	MQTT           mqtt.Config `json:"mqtt"`                     // MQTT configuration
 }
 
 // MQTTStatus represents MQTT status
@@ -41,12 +43,14 @@ func (p *TemperaturePlugin) handleGetTemperatures(w http.ResponseWriter, r *http
 func (p *TemperaturePlugin) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 	p.mu.RLock()
 	interval := int(p.updatePeriod.Seconds())
+	threshold := p.alertThreshold
 	mqttEnabled := p.mqttEnabled
 	mqttSettings := p.mqttSettings
 	p.mu.RUnlock()
 
 	settings := PluginSettings{
 		UpdateInterval: interval,
+		AlertThreshold: &threshold,
 		MQTTEnabled:    mqttEnabled,
 		MQTT:           mqttSettings,
 	}
@@ -58,13 +62,19 @@ func (p *TemperaturePlugin) handleGetSettings(w http.ResponseWriter, r *http.Req
 func (p *TemperaturePlugin) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 	var settings PluginSettings
 	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Validate interval (5-60 seconds)
 	if settings.UpdateInterval < 5 || settings.UpdateInterval > 60 {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Update interval must be between 5 and 60 seconds"})
+		apierror.Write(w, r, http.StatusBadRequest, "Update interval must be between 5 and 60 seconds")
+		return
+	}
+
+	// Validate alert threshold (0 = off)
+	if settings.AlertThreshold != nil && (*settings.AlertThreshold < 0 || *settings.AlertThreshold > maxAlertThreshold) {
+		apierror.Write(w, r, http.StatusBadRequest, "Alert threshold must be between 0 and 150 °C")
 		return
 	}
 
@@ -72,13 +82,16 @@ func (p *TemperaturePlugin) handleUpdateSettings(w http.ResponseWriter, r *http.
 
 	// Validate MQTT settings if enabled
 	if settings.MQTTEnabled && settings.MQTT.Broker == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "MQTT broker is required when MQTT is enabled"})
+		apierror.Write(w, r, http.StatusBadRequest, "MQTT broker is required when MQTT is enabled")
 		return
 	}
 
 	// Update in-memory interval
 	p.mu.Lock()
 	p.updatePeriod = time.Duration(settings.UpdateInterval) * time.Second
+	if settings.AlertThreshold != nil {
+		p.alertThreshold = *settings.AlertThreshold
+	}
 	mqttWasEnabled := p.mqttEnabled
 	p.mqttEnabled = settings.MQTTEnabled
 	p.mqttSettings = settings.MQTT
@@ -95,6 +108,15 @@ func (p *TemperaturePlugin) handleUpdateSettings(w http.ResponseWriter, r *http.
 			}
 		}
 
+		// Save alert threshold
+		if settings.AlertThreshold != nil {
+			if err := deps.Storage.SetInt(p.Name(), "alertThreshold", *settings.AlertThreshold); err != nil {
+				if p.Logger() != nil {
+					p.Logger().Printf("[%s] Failed to save alert threshold to storage: %v", p.Name(), err)
+				}
+			}
+		}
+
 		// Save MQTT enabled state
 		if err := deps.Storage.SetBool(p.Name(), "mqttEnabled", settings.MQTTEnabled); err != nil {
 			if p.Logger() != nil {
@@ -103,11 +125,11 @@ func (p *TemperaturePlugin) handleUpdateSettings(w http.ResponseWriter, r *http.
 		}
 
 		// Save MQTT settings
-		if err := deps.Storage.SetJSON(p.Name(), "mqttSettings", p.mqttSettings); err != nil {
+		if err := deps.Storage.SetSecretJSON(p.Name(), "mqttSettings", p.mqttSettings); err != nil {
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to save MQTT settings: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save MQTT settings"})
+			apierror.Write(w, r, http.StatusInternalServerError, "Failed to save MQTT settings")
 			return
 		}
 	}
@@ -119,7 +141,7 @@ func (p *TemperaturePlugin) handleUpdateSettings(w http.ResponseWriter, r *http.
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to create MQTT client: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Failed to create MQTT client: " + err.Error()})
+			apierror.Write(w, r, http.StatusBadRequest, "Failed to create MQTT client: "+err.Error())
 			return
 		}
 		if p.mqttClient != nil {
@@ -127,7 +149,7 @@ func (p *TemperaturePlugin) handleUpdateSettings(w http.ResponseWriter, r *http.
 				if p.Logger() != nil {
 					p.Logger().Printf("[%s] Failed to connect to MQTT broker: %v", p.Name(), err)
 				}
-				plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Failed to connect to MQTT broker: " + err.Error()})
+				apierror.Write(w, r, http.StatusBadRequest, "Failed to connect to MQTT broker: "+err.Error())
 				return
 			}
 			p.mqttClient.Publish("sensor/temperature/availability", []byte("online"))
@@ -142,7 +164,7 @@ func (p *TemperaturePlugin) handleUpdateSettings(w http.ResponseWriter, r *http.
 		if p.Logger() != nil {
 			p.Logger().Printf("[%s] Failed to restart background tasks: %v", p.Name(), err)
 		}
-		plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to restart background tasks"})
+		apierror.Write(w, r, http.StatusInternalServerError, "Failed to restart background tasks")
 		return
 	}
 
@@ -176,7 +198,7 @@ func (p *TemperaturePlugin) handleGetMQTTStatus(w http.ResponseWriter, r *http.R
 func (p *TemperaturePlugin) handleToggleMQTT(w http.ResponseWriter, r *http.Request) {
 	var req MQTTToggleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		apierror.Write(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -186,7 +208,7 @@ func (p *TemperaturePlugin) handleToggleMQTT(w http.ResponseWriter, r *http.Requ
 
 	// Check if MQTT is configured
 	if settings.Broker == "" {
-		plugins.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "MQTT is not configured. Please set MQTT broker in settings"})
+		apierror.Write(w, r, http.StatusBadRequest, "MQTT is not configured. Please set MQTT broker in settings")
 		return
 	}
 
@@ -202,7 +224,7 @@ func (p *TemperaturePlugin) handleToggleMQTT(w http.ResponseWriter, r *http.Requ
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to save MQTT enabled state: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save settings"})
+			apierror.Write(w, r, http.StatusInternalServerError, "Failed to save settings")
 			return
 		}
 	}
@@ -213,7 +235,7 @@ func (p *TemperaturePlugin) handleToggleMQTT(w http.ResponseWriter, r *http.Requ
 			if p.Logger() != nil {
 				p.Logger().Printf("[%s] Failed to create MQTT client: %v", p.Name(), err)
 			}
-			plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create MQTT client"})
+			apierror.Write(w, r, http.StatusInternalServerError, "Failed to create MQTT client")
 			return
 		}
 
@@ -222,7 +244,7 @@ func (p *TemperaturePlugin) handleToggleMQTT(w http.ResponseWriter, r *http.Requ
 				if p.Logger() != nil {
 					p.Logger().Printf("[%s] Failed to connect to MQTT broker: %v", p.Name(), err)
 				}
-				plugins.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to MQTT broker"})
+				apierror.Write(w, r, http.StatusInternalServerError, "Failed to connect to MQTT broker")
 				return
 			}
 			p.mqttClient.Publish("sensor/temperature/availability", []byte("online"))
@@ -4,6 +4,7 @@ package temperature
 import (
 	"context"
 	_ "embed"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +14,8 @@ import (
 	"sync"
 	"time"
 
+	"podmanview/internal/events"
+	"podmanview/internal/idle"
 	"podmanview/internal/mqtt"
 	"podmanview/internal/plugins"
 	"podmanview/internal/storage"
@@ -29,6 +32,7 @@ type TemperaturePlugin struct {
 	*plugins.BasePlugin
 	mu               sync.RWMutex
 	cachedData       *TemperatureData
+	history          *History // recent samples per sensor
 	lastUpdate       time.Time
 	updatePeriod     time.Duration
 	backgroundCtx    context.Context
@@ -39,12 +43,27 @@ type TemperaturePlugin struct {
 	mqttClient       *mqtt.Client
 	mqttPublisher    *mqtt.Publisher
 	mqttDiscovery    *mqtt.DiscoveryManager
+	alertThreshold   int  // °C, 0 = no alerts
+	overThreshold    bool // an alert was raised and the temperature hasn't dropped yet
 }
 
+const (
+	// defaultAlertThreshold is the default temperature alert in °C
+	defaultAlertThreshold = 80
+
+	// maxAlertThreshold is the highest accepted alert threshold in °C
+	maxAlertThreshold = 150
+
+	// alertHysteresis is how far below the threshold the temperature
+	// must drop before another alert can be raised
+	alertHysteresis = 5
+)
+
 // Temperature represents a temperature sensor reading
 type Temperature struct {
-	Label string  `json:"label"`
-	Temp  float64 `json:"temp"`
+	Label   string    `json:"label"`
+	Temp    float64   `json:"temp"`
+	History []float64 `json:"history,omitempty"` // recent samples, oldest first
 }
 
 // StorageTemp represents storage device temperatures grouped by device
@@ -73,9 +92,11 @@ func New() *TemperaturePlugin {
 			Temperatures: []Temperature{},
 			StorageTemps: []StorageTemp{},
 		},
+		history: NewHistory(historySize),
 		mqttSettings: mqtt.Config{
 			Prefix: "podmanview",
 		},
+		alertThreshold: defaultAlertThreshold,
 	}
 }
 
@@ -186,6 +207,15 @@ func (p *TemperaturePlugin) IsEnabled() bool {
 	return enabled
 }
 
+// idleMonitor returns the idle monitor, temperatures are read less often
+// while no client is connected
+func (p *TemperaturePlugin) idleMonitor() *idle.Monitor {
+	if p.Deps() == nil {
+		return nil
+	}
+	return p.Deps().Idle
+}
+
 // StartBackgroundTasks starts the background temperature monitoring task
 func (p *TemperaturePlugin) StartBackgroundTasks(ctx context.Context) error {
 	p.bgMutex.Lock()
@@ -200,7 +230,7 @@ func (p *TemperaturePlugin) StartBackgroundTasks(ctx context.Context) error {
 	}
 
 	// Run periodic temperature updates
-	go plugins.RunPeriodic(p.backgroundCtx, p.updatePeriod, p.Logger(), p.Name(), func(ctx context.Context) error {
+	go plugins.RunSampler(p.backgroundCtx, p.updatePeriod, p.idleMonitor(), p.Logger(), p.Name(), func(ctx context.Context) error {
 		p.updateTemperatureData()
 		return nil
 	})
@@ -232,7 +262,7 @@ func (p *TemperaturePlugin) RestartBackgroundTasks() error {
 	}
 
 	// Run periodic temperature updates with new interval
-	go plugins.RunPeriodic(p.backgroundCtx, p.updatePeriod, p.Logger(), p.Name(), func(ctx context.Context) error {
+	go plugins.RunSampler(p.backgroundCtx, p.updatePeriod, p.idleMonitor(), p.Logger(), p.Name(), func(ctx context.Context) error {
 		p.updateTemperatureData()
 		return nil
 	})
@@ -251,6 +281,7 @@ func (p *TemperaturePlugin) updateTemperatureData() {
 	// Update cache with lock
 	p.mu.Lock()
 	p.cachedData = newData
+	p.history.Record(newData)
 	p.lastUpdate = time.Now()
 	mqttEnabled := p.mqttEnabled
 	client := p.mqttClient
@@ -258,6 +289,8 @@ func (p *TemperaturePlugin) updateTemperatureData() {
 	discovery := p.mqttDiscovery
 	p.mu.Unlock()
 
+	p.checkAlertThreshold(newData)
+
 	// Log update
 	if p.Logger() != nil {
 		p.Logger().Printf("[%s] Temperature data updated: %d CPU sensors, %d storage devices",
@@ -286,16 +319,52 @@ func (p *TemperaturePlugin) updateTemperatureData() {
 	}
 }
 
-// GetTemperatureData returns cached temperature data
+// checkAlertThreshold adds a temperature_threshold event when the hottest
+// sensor reaches the alert threshold, once until it cools down again
+func (p *TemperaturePlugin) checkAlertThreshold(data *TemperatureData) {
+	var hottest Temperature
+	for _, t := range data.Temperatures {
+		if t.Temp > hottest.Temp {
+			hottest = t
+		}
+	}
+	for _, device := range data.StorageTemps {
+		for _, t := range device.Sensors {
+			if t.Temp > hottest.Temp {
+				hottest = Temperature{Label: GetFriendlyStorageName(device.Device) + " " + t.Label, Temp: t.Temp}
+			}
+		}
+	}
+
+	p.mu.Lock()
+	threshold := float64(p.alertThreshold)
+	raise := false
+	switch {
+	case threshold <= 0:
+		p.overThreshold = false
+	case !p.overThreshold && hottest.Temp >= threshold:
+		p.overThreshold = true
+		raise = true
+	case p.overThreshold && hottest.Temp < threshold-alertHysteresis:
+		p.overThreshold = false
+	}
+	p.mu.Unlock()
+
+	deps := p.Deps()
+	if raise && deps != nil && deps.EventStore != nil {
+		details := fmt.Sprintf("%s at %.1f°C (threshold %.0f°C)", hottest.Label, hottest.Temp, threshold)
+		deps.EventStore.Add(events.EventTempThreshold, "system", "", false, details)
+	}
+}
+
+// GetTemperatureData returns cached temperature data with the recent
+// samples of each sensor
 func (p *TemperaturePlugin) GetTemperatureData() *TemperatureData {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	// Return a copy of cached data to prevent external modifications
-	return &TemperatureData{
-		Temperatures: p.cachedData.Temperatures,
-		StorageTemps: p.cachedData.StorageTemps,
-	}
+	return p.history.Apply(p.cachedData)
 }
 
 // GetLastUpdateTime returns the time of the last temperature data update
@@ -325,6 +394,14 @@ func (p *TemperaturePlugin) loadSettings(st storage.Storage) {
 		st.SetInt(p.Name(), "updateInterval", 15)
 	}
 
+	// Load alert threshold
+	threshold, err := st.GetInt(p.Name(), "alertThreshold")
+	if err == nil && threshold >= 0 && threshold <= maxAlertThreshold {
+		p.mu.Lock()
+		p.alertThreshold = threshold
+		p.mu.Unlock()
+	}
+
 	// Load MQTT enabled state
 	mqttEnabled, err := st.GetBool(p.Name(), "mqttEnabled")
 	if err == nil {
@@ -341,7 +418,7 @@ func (p *TemperaturePlugin) loadSettings(st storage.Storage) {
 
 	// Load MQTT settings
 	var settings mqtt.Config
-	if err := st.GetJSON(p.Name(), "mqttSettings", &settings); err == nil {
+	if err := st.GetSecretJSON(p.Name(), "mqttSettings", &settings); err == nil {
 		p.mu.Lock()
 		p.mqttSettings = settings
 		if p.mqttSettings.Prefix == "" {
@@ -353,7 +430,7 @@ func (p *TemperaturePlugin) loadSettings(st storage.Storage) {
 		}
 	} else {
 		// Save default settings if not set
-		st.SetJSON(p.Name(), "mqttSettings", p.mqttSettings)
+		st.SetSecretJSON(p.Name(), "mqttSettings", p.mqttSettings)
 	}
 }
 
@@ -364,7 +441,7 @@ func (p *TemperaturePlugin) saveMQTTSettings(settings mqtt.Config) error {
 		return nil
 	}
 
-	if err := deps.Storage.SetJSON(p.Name(), "mqttSettings", settings); err != nil {
+	if err := deps.Storage.SetSecretJSON(p.Name(), "mqttSettings", settings); err != nil {
 		return err
 	}
 
@@ -1,7 +1,11 @@
 package podman
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,14 +13,26 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Client represents a Podman API client
 type Client struct {
-	httpClient *http.Client
-	socketPath string
+	httpClient   *http.Client
+	socketPath   string
+	registryAuth RegistryAuth // logins for pulls, nil for anonymous pulls
+}
+
+// RegistryAuth returns the login for the registry of an image reference,
+// ok false to pull anonymously
+type RegistryAuth func(reference string) (username, password string, ok bool)
+
+// SetRegistryAuth sets the logins sent with pulls. Call it before the
+// client is used.
+func (c *Client) SetRegistryAuth(auth RegistryAuth) {
+	c.registryAuth = auth
 }
 
 // NewClient creates a new Podman client
@@ -66,6 +82,30 @@ func NewClientWithSocket(socketPath string) (*Client, error) {
 	}, nil
 }
 
+// APIError is an error response of the Podman API
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("API error %d", e.StatusCode)
+	}
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// HTTPStatus returns the status for PodmanView API responses: client
+// errors (no such container, conflict) are passed through, errors of
+// Podman itself become 502
+func (e *APIError) HTTPStatus() int {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+		return e.StatusCode
+	}
+	return http.StatusBadGateway
+}
+
 // request makes HTTP request to Podman API
 func (c *Client) request(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
 	url := "http://localhost" + path
@@ -89,7 +129,7 @@ func (c *Client) get(ctx context.Context, path string, result interface{}) error
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return json.NewDecoder(resp.Body).Decode(result)
@@ -114,7 +154,7 @@ func (c *Client) post(ctx context.Context, path string, body interface{}) error
 
 	if resp.StatusCode >= 400 {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	return nil
@@ -130,7 +170,7 @@ func (c *Client) delete(ctx context.Context, path string) error {
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -138,14 +178,26 @@ func (c *Client) delete(ctx context.Context, path string) error {
 
 // Container types
 type Container struct {
-	ID      string   `json:"Id"`
-	Names   []string `json:"Names"`
-	Image   string   `json:"Image"`
-	ImageID string   `json:"ImageID"`
-	Command []string `json:"Command"`
-	State   string   `json:"State"`
-	Status  string   `json:"Status"`
-	Ports   []Port   `json:"Ports"`
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
+	Command []string          `json:"Command"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Ports   []Port            `json:"Ports"`
+	Labels  map[string]string `json:"Labels"`
+	Created time.Time         `json:"Created"`
+
+	// Unix time of the last start and exit, 0 or less when unknown
+	StartedAt int64 `json:"StartedAt"`
+	ExitedAt  int64 `json:"ExitedAt"`
+
+	// Pod membership and attached networks (libpod only)
+	Pod      string   `json:"Pod"`
+	PodName  string   `json:"PodName"`
+	IsInfra  bool     `json:"IsInfra"`
+	Networks []string `json:"Networks"`
 }
 
 type Port struct {
@@ -163,23 +215,85 @@ type ContainerInspect struct {
 		Status     string `json:"Status"`
 		Running    bool   `json:"Running"`
 		Paused     bool   `json:"Paused"`
+		Pid        int    `json:"Pid"`
 		StartedAt  string `json:"StartedAt"`
 		FinishedAt string `json:"FinishedAt"`
+		ExitCode   int    `json:"ExitCode"`
+		OOMKilled  bool   `json:"OOMKilled"`
+		Health     *struct {
+			Status string `json:"Status"` // starting, healthy or unhealthy
+		} `json:"Health,omitempty"`
 	} `json:"State"`
-	Image  string `json:"Image"`
-	Config struct {
-		Hostname string            `json:"Hostname"`
-		Env      []string          `json:"Env"`
-		Cmd      []string          `json:"Cmd"`
-		Labels   map[string]string `json:"Labels"`
+	Image     string `json:"Image"`
+	ImageName string `json:"ImageName"`
+	Config    struct {
+		Hostname   string            `json:"Hostname"`
+		Env        []string          `json:"Env"`
+		Cmd        []string          `json:"Cmd"`
+		Entrypoint StringList        `json:"Entrypoint"`
+		User       string            `json:"User"`
+		WorkingDir string            `json:"WorkingDir"`
+		Labels     map[string]string `json:"Labels"`
+		Tty        bool              `json:"Tty"`
+		OpenStdin  bool              `json:"OpenStdin"`
 	} `json:"Config"`
+	HostConfig struct {
+		Privileged     bool                     `json:"Privileged"`
+		CapAdd         []string                 `json:"CapAdd"`
+		NetworkMode    string                   `json:"NetworkMode"`
+		Memory         int64                    `json:"Memory"`   // limit in bytes, 0 if unlimited
+		NanoCpus       int64                    `json:"NanoCpus"` // CPU limit in billionths of CPUs, 0 if unlimited
+		CpuQuota       int64                    `json:"CpuQuota"` // CPU time per CpuPeriod in microseconds, 0 if unlimited
+		CpuPeriod      int64                    `json:"CpuPeriod"`
+		ReadonlyRootfs bool                     `json:"ReadonlyRootfs"`
+		PortBindings   map[string][]PortBinding `json:"PortBindings"` // by "80/tcp"
+		RestartPolicy  struct {
+			Name              string `json:"Name"`
+			MaximumRetryCount uint   `json:"MaximumRetryCount"`
+		} `json:"RestartPolicy"`
+		LogConfig struct {
+			Type string `json:"Type"` // k8s-file, journald or none
+			Path string `json:"Path"` // log file of k8s-file
+		} `json:"LogConfig"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress           string `json:"IPAddress"`
+			IPPrefixLen         int    `json:"IPPrefixLen"`
+			Gateway             string `json:"Gateway"`
+			GlobalIPv6Address   string `json:"GlobalIPv6Address"`
+			GlobalIPv6PrefixLen int    `json:"GlobalIPv6PrefixLen"`
+			MacAddress          string `json:"MacAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
 	Mounts []struct {
 		Type        string `json:"Type"`
+		Name        string `json:"Name,omitempty"` // volume name, for volume mounts
 		Source      string `json:"Source"`
 		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
 	} `json:"Mounts"`
 }
 
+// PortBinding is the host side of a published port
+type PortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// StringList is a list of strings that is also decoded from a single
+// string, e.g. the entrypoint, which Podman 4 reports as a string
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = strings.Fields(s)
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
 // ListContainers returns list of all containers (running and stopped)
 func (c *Client) ListContainers(ctx context.Context) ([]Container, error) {
 	var containers []Container
@@ -194,6 +308,93 @@ func (c *Client) InspectContainer(ctx context.Context, id string) (*ContainerIns
 	return &info, err
 }
 
+// Change kinds of a container filesystem change
+const (
+	ChangeModified = 0
+	ChangeAdded    = 1
+	ChangeDeleted  = 2
+)
+
+// Change is a file changed in a container compared to its image
+type Change struct {
+	Path string `json:"Path"`
+	Kind int    `json:"Kind"`
+}
+
+// ContainerChanges returns the files changed, added and deleted in the
+// filesystem of a container compared to its image
+func (c *Client) ContainerChanges(ctx context.Context, id string) ([]Change, error) {
+	var changes []Change
+	err := c.get(ctx, fmt.Sprintf("/v4.0.0/libpod/containers/%s/changes", id), &changes)
+	return changes, err
+}
+
+// PathStat describes a file in a container
+type PathStat struct {
+	Name       string      `json:"name"`
+	Size       int64       `json:"size"`
+	Mode       os.FileMode `json:"mode"`
+	LinkTarget string      `json:"linkTarget"`
+}
+
+// StatContainerPath returns the stat of a path in a container, running or not
+func (c *Client) StatContainerPath(ctx context.Context, id, path string) (*PathStat, error) {
+	resp, err := c.request(ctx, http.MethodHead, fmt.Sprintf("/v4.0.0/containers/%s/archive?path=%s", id, url.QueryEscape(path)), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, &APIError{StatusCode: resp.StatusCode}
+	}
+
+	// The stat is a base64-encoded JSON header
+	data, err := base64.StdEncoding.DecodeString(resp.Header.Get("X-Docker-Container-Path-Stat"))
+	if err != nil {
+		return nil, err
+	}
+	var stat PathStat
+	if err := json.Unmarshal(data, &stat); err != nil {
+		return nil, err
+	}
+	return &stat, nil
+}
+
+// CopyFromContainer returns a tar archive of a path in a container, running
+// or not. The caller must close the reader.
+func (c *Client) CopyFromContainer(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	resp, err := c.request(ctx, http.MethodGet, fmt.Sprintf("/v4.0.0/containers/%s/archive?path=%s", id, url.QueryEscape(path)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp.Body, nil
+}
+
+// CopyToContainer extracts a tar archive into a directory of a container
+func (c *Client) CopyToContainer(ctx context.Context, id, dir string, archive io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("http://localhost/v4.0.0/containers/%s/archive?path=%s", id, url.QueryEscape(dir)), archive)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
 // ContainerStats represents resource usage statistics for a container
 type ContainerStats struct {
 	ContainerID string  `json:"ContainerID"`
@@ -218,7 +419,7 @@ func (c *Client) GetContainersStats(ctx context.Context) ([]ContainerStats, erro
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error %d", resp.StatusCode)
+		return nil, &APIError{StatusCode: resp.StatusCode}
 	}
 
 	var result struct {
@@ -255,28 +456,65 @@ func (c *Client) RemoveContainer(ctx context.Context, id string, force bool) err
 	return c.delete(ctx, path)
 }
 
-// ContainerCreateConfig represents container creation options
+// ContainerCreateConfig represents container creation options (a subset of
+// the libpod spec generator)
 type ContainerCreateConfig struct {
-	Name         string            `json:"name,omitempty"`
-	Image        string            `json:"image"`
-	Command      []string          `json:"command,omitempty"`
-	Env          map[string]string `json:"env,omitempty"`
-	PortMappings []PortMapping     `json:"portmappings,omitempty"`
-	Mounts       []Mount           `json:"mounts,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	Image        string                 `json:"image"`
+	Entrypoint   []string               `json:"entrypoint,omitempty"`
+	Command      []string               `json:"command,omitempty"`
+	Env          map[string]string      `json:"env,omitempty"`
+	Labels       map[string]string      `json:"labels,omitempty"`
+	Hostname     string                 `json:"hostname,omitempty"`
+	User         string                 `json:"user,omitempty"`
+	WorkDir      string                 `json:"work_dir,omitempty"`
+	PortMappings []PortMapping          `json:"portmappings,omitempty"`
+	Mounts       []Mount                `json:"mounts,omitempty"`
+	Volumes      []NamedVolume          `json:"volumes,omitempty"`
+	NetNS        *Namespace             `json:"netns,omitempty"`
+	Networks     map[string]interface{} `json:"Networks,omitempty"`
+	Restart      string                 `json:"restart_policy,omitempty"`
+	RestartTries uint                   `json:"restart_tries,omitempty"`
+	Privileged   bool                   `json:"privileged,omitempty"`
+	CapAdd       []string               `json:"cap_add,omitempty"`
+	ReadOnly     bool                   `json:"read_only_filesystem,omitempty"`
+	Resources    *Resources             `json:"resource_limits,omitempty"`
+	Dependencies []string               `json:"dependencies,omitempty"` // containers started before this one
 }
 
 // PortMapping represents a port mapping
 type PortMapping struct {
 	ContainerPort int    `json:"container_port"`
 	HostPort      int    `json:"host_port"`
+	HostIP        string `json:"host_ip,omitempty"`
 	Protocol      string `json:"protocol,omitempty"`
 }
 
 // Mount represents a volume mount
 type Mount struct {
-	Type        string `json:"Type"`
-	Source      string `json:"Source"`
-	Destination string `json:"Destination"`
+	Type        string   `json:"Type"`
+	Source      string   `json:"Source"`
+	Destination string   `json:"Destination"`
+	Options     []string `json:"Options,omitempty"`
+}
+
+// NamedVolume is a named volume mounted into a container
+type NamedVolume struct {
+	Name    string   `json:"Name"`
+	Dest    string   `json:"Dest"`
+	Options []string `json:"Options,omitempty"`
+}
+
+// Namespace selects a namespace mode, e.g. {"nsmode": "host"}
+type Namespace struct {
+	Mode string `json:"nsmode"`
+}
+
+// Resources are the resource limits of a container
+type Resources struct {
+	Memory struct {
+		Limit int64 `json:"limit,omitempty"`
+	} `json:"memory"`
 }
 
 // CreateContainerResponse represents the response from container creation
@@ -300,7 +538,7 @@ func (c *Client) CreateContainer(ctx context.Context, config *ContainerCreateCon
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result CreateContainerResponse
@@ -367,6 +605,123 @@ func stripAnsiCodes(s string) string {
 	return string(result)
 }
 
+// LogOptions selects the lines of ContainerLogs
+type LogOptions struct {
+	Follow bool      // keep the stream open for new lines
+	Tail   int       // number of lines from the end, 0 for all
+	Since  time.Time // only lines after this time, if set
+	Until  time.Time // only lines before this time, if set
+}
+
+// LogLine is a line of container output
+type LogLine struct {
+	Stream string    // stdout or stderr
+	Time   time.Time // when the line was written
+	Text   string
+}
+
+// ContainerLogs opens the log stream of a container, with timestamps, oldest
+// line first. Read it with ReadLogs.
+func (c *Client) ContainerLogs(ctx context.Context, id string, opts LogOptions) (io.ReadCloser, error) {
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}, "timestamps": {"true"}}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+	if opts.Tail > 0 {
+		query.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.Format(time.RFC3339Nano))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost/v4.0.0/libpod/containers/%s/logs?%s", url.PathEscape(id), query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Full logs and followed logs take longer than the client timeout
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp.Body, nil
+}
+
+// ReadLogs calls fn for every line of a log stream from ContainerLogs until
+// the stream ends or fn returns an error. Streams of containers without a
+// TTY are multiplexed (see parseContainerLogs); TTY output is plain text.
+// ANSI escape codes are stripped.
+func ReadLogs(r io.Reader, fn func(LogLine) error) error {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(8)
+	if err != nil && len(header) == 0 {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	emit := func(stream, payload string) error {
+		for _, text := range strings.Split(strings.TrimRight(payload, "\r\n"), "\n") {
+			line := LogLine{Stream: stream, Text: stripAnsiCodes(strings.TrimRight(text, "\r"))}
+			if ts, rest, ok := strings.Cut(line.Text, " "); ok {
+				if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+					line.Time, line.Text = t, rest
+				}
+			}
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Plain text
+	if len(header) < 8 || header[0] > 2 || header[1] != 0 || header[2] != 0 || header[3] != 0 {
+		for {
+			text, err := br.ReadString('\n')
+			if text != "" {
+				if ferr := emit("stdout", text); ferr != nil {
+					return ferr
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	frame := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(br, frame); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(frame[4:]))
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return err
+		}
+		stream := "stdout"
+		if frame[0] == 2 {
+			stream = "stderr"
+		}
+		if err := emit(stream, string(payload)); err != nil {
+			return err
+		}
+	}
+}
+
 // parseContainerLogs parses multiplexed log stream
 // Each frame: [1 byte type][3 bytes padding][4 bytes size BE][payload]
 func parseContainerLogs(data []byte) []string {
@@ -422,12 +777,13 @@ func parseContainerLogs(data []byte) []string {
 
 // Image types
 type Image struct {
-	ID          string   `json:"Id"`
-	RepoTags    []string `json:"RepoTags"`
-	RepoDigests []string `json:"RepoDigests"`
-	Created     int64    `json:"Created"`
-	Size        int64    `json:"Size"`
-	VirtualSize int64    `json:"VirtualSize"`
+	ID          string            `json:"Id"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Created     int64             `json:"Created"`
+	Size        int64             `json:"Size"`
+	VirtualSize int64             `json:"VirtualSize"`
+	Labels      map[string]string `json:"Labels"`
 }
 
 type ImageInspect struct {
@@ -460,10 +816,84 @@ func (c *Client) InspectImage(ctx context.Context, id string) (*ImageInspect, er
 	return &info, err
 }
 
+// ImageHistoryEntry is a step of the build history of an image, newest
+// first. Steps that only change metadata (ENV, CMD...) have size 0, and
+// steps of base images pulled from a registry have the ID "<missing>".
+type ImageHistoryEntry struct {
+	ID        string   `json:"Id"`
+	Created   int64    `json:"Created"`
+	CreatedBy string   `json:"CreatedBy"`
+	Tags      []string `json:"Tags"`
+	Size      int64    `json:"Size"`
+	Comment   string   `json:"Comment"`
+}
+
+// ImageHistory returns the build history of an image
+func (c *Client) ImageHistory(ctx context.Context, id string) ([]ImageHistoryEntry, error) {
+	var history []ImageHistoryEntry
+	err := c.get(ctx, fmt.Sprintf("/v4.0.0/libpod/images/%s/history", id), &history)
+	return history, err
+}
+
+// ImageSearchResult is an image found in a registry
+type ImageSearchResult struct {
+	Index       string `json:"Index"` // registry
+	Name        string `json:"Name"`  // with the registry, e.g. docker.io/library/nginx
+	Description string `json:"Description"`
+	Stars       int    `json:"Stars"`
+	Official    string `json:"Official"` // "[OK]" for official images
+	Automated   string `json:"Automated"`
+}
+
+// SearchImages searches a registry for images; term is prefixed with the
+// registry (quay.io/prometheus), otherwise the registries of
+// registries.conf are searched. tlsVerify false allows registries
+// without TLS.
+func (c *Client) SearchImages(ctx context.Context, term string, limit int, tlsVerify bool) ([]ImageSearchResult, error) {
+	query := url.Values{
+		"term":      {term},
+		"limit":     {strconv.Itoa(limit)},
+		"tlsVerify": {strconv.FormatBool(tlsVerify)},
+	}
+	var results []ImageSearchResult
+	err := c.get(ctx, "/v4.0.0/libpod/images/search?"+query.Encode(), &results)
+	return results, err
+}
+
 // PullImage pulls an image from registry
 func (c *Client) PullImage(ctx context.Context, reference string) error {
-	path := fmt.Sprintf("/v4.0.0/libpod/images/pull?reference=%s", url.QueryEscape(reference))
-	resp, err := c.request(ctx, http.MethodPost, path, nil)
+	return c.PullImagePlatform(ctx, reference, "")
+}
+
+// PullImagePlatform pulls the image of a platform, os/architecture[/variant]
+// such as linux/arm64/v8, from a multi-platform image. An empty platform
+// pulls the one of the host.
+func (c *Client) PullImagePlatform(ctx context.Context, reference, platform string) error {
+	query := url.Values{"reference": {reference}}
+	if platform != "" {
+		parts := strings.SplitN(platform, "/", 3)
+		query.Set("OS", parts[0])
+		if len(parts) > 1 {
+			query.Set("arch", parts[1])
+		}
+		if len(parts) > 2 {
+			query.Set("variant", parts[2])
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/v4.0.0/libpod/images/pull?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.registryAuth != nil {
+		if username, password, ok := c.registryAuth(reference); ok {
+			auth, err := json.Marshal(map[string]string{"username": username, "password": password})
+			if err != nil {
+				return err
+			}
+			req.Header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(auth))
+		}
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -477,6 +907,121 @@ func (c *Client) PullImage(ctx context.Context, reference string) error {
 	return err
 }
 
+// ExportImage streams an image as a docker-archive tar, which docker load
+// and podman load both read. The caller must close the reader.
+func (c *Client) ExportImage(ctx context.Context, id string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost/v4.0.0/libpod/images/%s/get?format=docker-archive", url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Archives of large images take longer than the client timeout
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp.Body, nil
+}
+
+// LoadImage loads the images of a tar archive (docker-archive or oci-archive,
+// optionally compressed) and returns their names
+func (c *Client) LoadImage(ctx context.Context, archive io.Reader) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/v4.0.0/libpod/images/load", archive)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	var result struct {
+		Names []string `json:"Names"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Names, nil
+}
+
+// BuildOptions are the options of BuildImage
+type BuildOptions struct {
+	Tags       []string          // names of the image, e.g. localhost/app:latest
+	Dockerfile string            // path in the context, Containerfile or Dockerfile if empty
+	BuildArgs  map[string]string // ARG values
+	Platform   string            // os/architecture[/variant], the host's if empty
+	Pull       bool              // pull base images even if they exist
+}
+
+// BuildMessage is a line of the output of BuildImage
+type BuildMessage struct {
+	Stream string `json:"stream"` // build output
+	Error  string `json:"error"`  // the build failed
+	Aux    struct {
+		ID string `json:"ID"` // the built image, sent at the end
+	} `json:"aux"`
+}
+
+// BuildImage builds an image from a tar archive of the build context and
+// returns the stream of BuildMessage lines. The caller must close the reader.
+func (c *Client) BuildImage(ctx context.Context, buildContext io.Reader, opts BuildOptions) (io.ReadCloser, error) {
+	query := url.Values{"rm": {"true"}}
+	for _, tag := range opts.Tags {
+		query.Add("t", tag)
+	}
+	if opts.Dockerfile != "" {
+		query.Set("dockerfile", opts.Dockerfile)
+	}
+	if len(opts.BuildArgs) > 0 {
+		args, err := json.Marshal(opts.BuildArgs)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("buildargs", string(args))
+	}
+	if opts.Platform != "" {
+		query.Set("platform", opts.Platform)
+	}
+	if opts.Pull {
+		query.Set("pull", "true")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/v4.0.0/libpod/build?"+query.Encode(), buildContext)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+	// Builds take longer than the client timeout
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp.Body, nil
+}
+
+// streamingClient returns a copy of the HTTP client without the timeout, for
+// requests that stream for an unknown time
+func (c *Client) streamingClient() *http.Client {
+	client := *c.httpClient
+	client.Timeout = 0
+	return &client
+}
+
 // RemoveImage removes an image
 func (c *Client) RemoveImage(ctx context.Context, id string, force bool) error {
 	path := fmt.Sprintf("/v4.0.0/libpod/images/%s", id)
@@ -510,6 +1055,52 @@ func (c *Client) ListVolumes(ctx context.Context) ([]Volume, error) {
 	return result.Volumes, nil
 }
 
+// InspectVolume returns a volume
+func (c *Client) InspectVolume(ctx context.Context, name string) (*Volume, error) {
+	var volume Volume
+	err := c.get(ctx, fmt.Sprintf("/v4.0.0/libpod/volumes/%s/json", url.PathEscape(name)), &volume)
+	return &volume, err
+}
+
+// CreateVolume creates a named volume with the local driver
+func (c *Client) CreateVolume(ctx context.Context, name string) error {
+	return c.post(ctx, "/v4.0.0/libpod/volumes/create", map[string]string{"Name": name})
+}
+
+// RemoveVolume removes a volume and its data. Volumes used by a container
+// are only removed with force, which removes the containers too.
+func (c *Client) RemoveVolume(ctx context.Context, name string, force bool) error {
+	path := fmt.Sprintf("/v4.0.0/libpod/volumes/%s", url.PathEscape(name))
+	if force {
+		path += "?force=true"
+	}
+	return c.delete(ctx, path)
+}
+
+// Pod types
+type Pod struct {
+	ID         string            `json:"Id"`
+	Name       string            `json:"Name"`
+	Status     string            `json:"Status"`
+	Created    time.Time         `json:"Created"`
+	InfraID    string            `json:"InfraId"`
+	Labels     map[string]string `json:"Labels"`
+	Containers []PodContainer    `json:"Containers"`
+}
+
+type PodContainer struct {
+	ID     string `json:"Id"`
+	Names  string `json:"Names"`
+	Status string `json:"Status"`
+}
+
+// ListPods returns list of all pods
+func (c *Client) ListPods(ctx context.Context) ([]Pod, error) {
+	var pods []Pod
+	err := c.get(ctx, "/v4.0.0/libpod/pods/json", &pods)
+	return pods, err
+}
+
 // Network types
 type Network struct {
 	Name        string            `json:"name"`
@@ -523,8 +1114,15 @@ type Network struct {
 }
 
 type Subnet struct {
-	Subnet  string `json:"subnet"`
-	Gateway string `json:"gateway"`
+	Subnet     string      `json:"subnet"`
+	Gateway    string      `json:"gateway"`
+	LeaseRange *LeaseRange `json:"lease_range,omitempty"`
+}
+
+// LeaseRange limits the addresses Podman assigns in a subnet
+type LeaseRange struct {
+	StartIP string `json:"start_ip"`
+	EndIP   string `json:"end_ip"`
 }
 
 // ListNetworks returns list of all networks
@@ -534,6 +1132,79 @@ func (c *Client) ListNetworks(ctx context.Context) ([]Network, error) {
 	return networks, err
 }
 
+// GenerateKube returns Kubernetes YAML (pods, and services if service is
+// set) for the named containers and pods
+func (c *Client) GenerateKube(ctx context.Context, names []string, service bool) ([]byte, error) {
+	query := url.Values{"names": names}
+	if service {
+		query.Set("service", "true")
+	}
+	resp, err := c.request(ctx, http.MethodGet, "/v4.0.0/libpod/generate/kube?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, err
+}
+
+// PlayKubePod is a pod created by PlayKube
+type PlayKubePod struct {
+	ID              string   `json:"ID"`
+	Containers      []string `json:"Containers"`
+	InitContainers  []string `json:"InitContainers"`
+	Logs            []string `json:"Logs"`
+	ContainerErrors []string `json:"ContainerErrors"`
+}
+
+// PlayKubeReport is the result of PlayKube
+type PlayKubeReport struct {
+	Pods    []PlayKubePod `json:"Pods"`
+	Volumes []struct {
+		Name string `json:"Name"`
+	} `json:"Volumes"`
+}
+
+// PlayKubeOptions are the options of PlayKube
+type PlayKubeOptions struct {
+	Start   bool // start the pods
+	Replace bool // remove existing pods and containers of the same name first
+}
+
+// PlayKube creates the pods, containers and volumes of Kubernetes YAML,
+// pulling missing images
+func (c *Client) PlayKube(ctx context.Context, manifest []byte, opts PlayKubeOptions) (*PlayKubeReport, error) {
+	query := url.Values{"start": {strconv.FormatBool(opts.Start)}}
+	if opts.Replace {
+		query.Set("replace", "true")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/v4.0.0/libpod/play/kube?"+query.Encode(), bytes.NewReader(manifest))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-yaml")
+	// Pulling images takes longer than the client timeout
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	var report PlayKubeReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
 // System types
 type SystemInfo struct {
 	Host struct {
@@ -541,6 +1212,9 @@ type SystemInfo struct {
 		Hostname string `json:"hostname"`
 		Kernel   string `json:"kernel"`
 	} `json:"host"`
+	Store struct {
+		GraphRoot string `json:"graphRoot"` // where images and writable layers are stored
+	} `json:"store"`
 	Version struct {
 		Version string `json:"Version"`
 	} `json:"version"`
@@ -549,19 +1223,38 @@ type SystemInfo struct {
 type SystemDF struct {
 	Containers []struct {
 		ContainerID string `json:"ContainerID"`
-		Size        int64  `json:"Size"`
-		RWSize      int64  `json:"RWSize"`
+		Names       string `json:"Names"`
+		Status      string `json:"Status"`
+		Size        int64  `json:"Size"`   // image and writable layer
+		RWSize      int64  `json:"RWSize"` // writable layer
 	} `json:"Containers"`
 	Images []struct {
-		ImageID string `json:"ImageID"`
-		Size    int64  `json:"Size"`
+		ImageID    string `json:"ImageID"`
+		Repository string `json:"Repository"` // "<none>" for dangling images
+		Size       int64  `json:"Size"`
+		Containers int    `json:"Containers"` // containers using the image
 	} `json:"Images"`
 	Volumes []struct {
 		VolumeName string `json:"VolumeName"`
 		Size       int64  `json:"Size"`
+		Links      int    `json:"Links"` // containers using the volume
 	} `json:"Volumes"`
 }
 
+// PruneReport is the result of a system prune
+type PruneReport struct {
+	ContainerPruneReports []PruneEntry `json:"ContainerPruneReports"`
+	ImagePruneReports     []PruneEntry `json:"ImagePruneReports"`
+	VolumePruneReports    []PruneEntry `json:"VolumePruneReports"`
+	ReclaimedSpace        uint64       `json:"ReclaimedSpace"`
+}
+
+// PruneEntry is a resource removed by a prune
+type PruneEntry struct {
+	ID   string `json:"Id"`
+	Size uint64 `json:"Size"`
+}
+
 // GetSystemInfo returns system information
 func (c *Client) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
 	var info SystemInfo
@@ -569,6 +1262,13 @@ func (c *Client) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
 	return &info, err
 }
 
+// RawSystemInfo returns the complete output of podman info, as reported
+func (c *Client) RawSystemInfo(ctx context.Context) (json.RawMessage, error) {
+	var info json.RawMessage
+	err := c.get(ctx, "/v4.0.0/libpod/info", &info)
+	return info, err
+}
+
 // GetSystemDF returns disk usage
 func (c *Client) GetSystemDF(ctx context.Context) (*SystemDF, error) {
 	var df SystemDF
@@ -576,6 +1276,28 @@ func (c *Client) GetSystemDF(ctx context.Context) (*SystemDF, error) {
 	return &df, err
 }
 
+// SystemPrune removes stopped containers, dangling images (all unused images
+// with all) and, with volumes, unused volumes
+func (c *Client) SystemPrune(ctx context.Context, all, volumes bool) (*PruneReport, error) {
+	path := fmt.Sprintf("/v4.0.0/libpod/system/prune?all=%t&volumes=%t", all, volumes)
+	resp, err := c.request(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var report PruneReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
 // Ping checks if Podman API is available
 func (c *Client) Ping(ctx context.Context) error {
 	resp, err := c.request(ctx, http.MethodGet, "/_ping", nil)
@@ -586,6 +1308,51 @@ func (c *Client) Ping(ctx context.Context) error {
 	return nil
 }
 
+// EngineEvent represents a Podman engine event (container, image, pod, volume, ...)
+type EngineEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Time     int64 `json:"time"`
+	TimeNano int64 `json:"timeNano"`
+}
+
+// StreamEvents calls fn for each new engine event until ctx is cancelled
+// or the connection is closed
+func (c *Client) StreamEvents(ctx context.Context, fn func(EngineEvent)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/v4.0.0/libpod/events?stream=true", nil)
+	if err != nil {
+		return err
+	}
+
+	// The stream stays open, so the client timeout must not apply
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event EngineEvent
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		fn(event)
+	}
+}
+
 // ExecConfig represents exec configuration
 type ExecConfig struct {
 	AttachStdin  bool     `json:"AttachStdin"`
@@ -630,7 +1397,7 @@ func (c *Client) CreateExecWithEnv(ctx context.Context, containerID string, cmd
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result ExecCreateResponse
@@ -641,6 +1408,49 @@ func (c *Client) CreateExecWithEnv(ctx context.Context, containerID string, cmd
 	return &result, nil
 }
 
+// AttachContainer attaches to the stdin, stdout and stderr of the main
+// process of a running container, like podman attach. Input written to the
+// connection goes to the process; output is raw with a TTY, multiplexed
+// stdout and stderr frames otherwise. Podman detaches when detachKeys
+// ("ctrl-p,ctrl-q") are typed; closing the connection detaches too, without
+// stopping the container.
+func (c *Client) AttachContainer(ctx context.Context, id, detachKeys string) (io.ReadWriteCloser, error) {
+	query := url.Values{
+		"stream":     {"true"},
+		"stdin":      {"true"},
+		"stdout":     {"true"},
+		"stderr":     {"true"},
+		"detachKeys": {detachKeys},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("http://localhost/v4.0.0/libpod/containers/%s/attach?%s", id, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	// The connection lasts as long as the session
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("attach connection is read-only")
+	}
+	return conn, nil
+}
+
+// ResizeContainer resizes the TTY of a container
+func (c *Client) ResizeContainer(ctx context.Context, id string, cols, rows int) error {
+	return c.post(ctx, fmt.Sprintf("/v4.0.0/libpod/containers/%s/resize?h=%d&w=%d", id, rows, cols), nil)
+}
+
 // GetSocketPath returns the socket path
 func (c *Client) GetSocketPath() string {
 	return c.socketPath
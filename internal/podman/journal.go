@@ -0,0 +1,118 @@
+package podman
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalTimeFormat is a time accepted by --since and --until of journalctl
+const journalTimeFormat = "2006-01-02 15:04:05 UTC"
+
+// journalEntry is an entry of journalctl -o json
+type journalEntry struct {
+	Message   json.RawMessage `json:"MESSAGE"` // a string, or an array of bytes if not valid UTF-8
+	Priority  string          `json:"PRIORITY"`
+	Timestamp string          `json:"__REALTIME_TIMESTAMP"` // microseconds since the epoch
+}
+
+// JournalLogs opens the log of a container using the journald log driver
+// from the systemd journal, with the same options and format as
+// ContainerLogs. Read it with ReadLogs. Needs journalctl on the host.
+func JournalLogs(ctx context.Context, id string, opts LogOptions) (io.ReadCloser, error) {
+	args := []string{"--no-pager", "--quiet", "--output=json", "--output-fields=MESSAGE,PRIORITY", "CONTAINER_ID_FULL=" + id}
+	if opts.Tail > 0 {
+		args = append(args, "--lines="+strconv.Itoa(opts.Tail))
+	} else {
+		args = append(args, "--lines=all") // --follow shows the last 10 lines otherwise
+	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since="+opts.Since.UTC().Format(journalTimeFormat))
+	}
+	if !opts.Until.IsZero() {
+		// --until is exact to the second
+		args = append(args, "--until="+opts.Until.UTC().Add(time.Second).Format(journalTimeFormat))
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	// Entries are converted to frames of a multiplexed log stream
+	pr, pw := io.Pipe()
+	go func() {
+		defer cancel()
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		var werr error
+		for scanner.Scan() && werr == nil {
+			var entry journalEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+				continue
+			}
+			_, werr = pw.Write(entry.frame())
+		}
+		if werr != nil {
+			cancel() // the reader is closed
+		}
+		if err := cmd.Wait(); err != nil && werr == nil && ctx.Err() == nil {
+			pw.CloseWithError(fmt.Errorf("failed to read journal: %s", strings.TrimSpace(stderr.String())))
+			return
+		}
+		pw.Close()
+	}()
+	return &journalReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+// journalReader stops journalctl when the log is closed
+type journalReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *journalReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// frame encodes the entry like a line of a multiplexed Podman log stream
+// with timestamps. Podman logs stderr with priority 3 (error).
+func (e *journalEntry) frame() []byte {
+	var text string
+	if json.Unmarshal(e.Message, &text) != nil {
+		var raw []byte
+		json.Unmarshal(e.Message, &raw)
+		text = string(raw)
+	}
+	if usec, err := strconv.ParseInt(e.Timestamp, 10, 64); err == nil {
+		text = time.UnixMicro(usec).UTC().Format(time.RFC3339Nano) + " " + text
+	}
+
+	frame := make([]byte, 8, 8+len(text)+1)
+	frame[0] = 1
+	if e.Priority == "3" {
+		frame[0] = 2
+	}
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(text)+1))
+	return append(append(frame, text...), '\n')
+}
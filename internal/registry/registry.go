@@ -0,0 +1,428 @@
+// Package registry reads tags and manifests from container registries
+// over the OCI distribution API, with bearer tokens where the registry
+// asks for one, anonymous unless credentials are set, and checks and
+// creates the tokens of registry providers.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// requestTimeout limits each request to a registry
+	requestTimeout = 15 * time.Second
+	// maxTagPages limits the pages of a paginated tag list
+	maxTagPages = 10
+	// maxManifestSize limits manifests and image configs read
+	maxManifestSize = 4 << 20
+	// tokenTTL is how long a token is used when the registry doesn't say
+	tokenTTL = 60 * time.Second
+)
+
+// Manifest media types
+const (
+	MediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// DockerHub is the name of Docker Hub in image references
+const DockerHub = "docker.io"
+
+var (
+	// ErrNotConfigured is returned for a registry that is not in the list
+	// the client was created with
+	ErrNotConfigured = errors.New("registry not configured")
+
+	// ErrNotFound is returned for an unknown repository or tag
+	ErrNotFound = errors.New("repository or tag not found")
+
+	// ErrUnauthorized is returned when the registry requires a login
+	ErrUnauthorized = errors.New("registry requires authentication")
+)
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry   string // docker.io, quay.io, localhost:5000...
+	Repository string // library/nginx
+	Tag        string // empty if not given
+}
+
+// ParseReference parses an image reference the way Podman does: without
+// a registry it is on Docker Hub, where single names are in library/
+func ParseReference(image string) Reference {
+	var ref Reference
+	name := image
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[:i] // digests are not tags
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+
+	// The first component is a registry if it looks like a host
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, name = first, rest
+	} else {
+		ref.Registry = DockerHub
+	}
+	if ref.Registry == DockerHub && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	return ref
+}
+
+// String returns the reference as registry/repository[:tag]
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	return s
+}
+
+// Client reads from the configured registries
+type Client struct {
+	httpClient  *http.Client
+	endpoints   map[string]string // registry name -> base URL
+	credentials Credentials       // nil for anonymous access
+
+	mu     sync.Mutex
+	tokens map[string]token // by endpoint and scope
+}
+
+// Credentials returns the login of a registry, ok false for none
+type Credentials func(registry string) (username, password string, ok bool)
+
+// token is a cached bearer token
+type token struct {
+	value   string
+	expires time.Time
+}
+
+// NewClient creates a client for the given registries: host names such as
+// quay.io (HTTPS) or URLs such as http://localhost:5000 for registries
+// without TLS. Docker Hub is reached at registry-1.docker.io.
+func NewClient(registries []string) *Client {
+	c := &Client{
+		httpClient: &http.Client{Timeout: requestTimeout},
+		endpoints:  make(map[string]string),
+		tokens:     make(map[string]token),
+	}
+	for _, registry := range registries {
+		name, endpoint := Endpoint(registry)
+		c.endpoints[name] = endpoint
+	}
+	return c
+}
+
+// SetCredentials sets the logins used to get tokens. Call it before the
+// client is used.
+func (c *Client) SetCredentials(credentials Credentials) {
+	c.credentials = credentials
+}
+
+// Endpoint returns the name of a configured registry in image references
+// and the base URL of its API
+func Endpoint(registry string) (name, endpoint string) {
+	registry = strings.TrimSuffix(registry, "/")
+	if host, ok := strings.CutPrefix(registry, "http://"); ok {
+		return host, registry
+	}
+	name = strings.TrimPrefix(registry, "https://")
+	if name == DockerHub {
+		return name, "https://registry-1.docker.io"
+	}
+	return name, "https://" + name
+}
+
+// Tags lists the tags of a repository, in the order of the registry
+func (c *Client) Tags(ctx context.Context, ref Reference) ([]string, error) {
+	var tags []string
+	path := "/v2/" + ref.Repository + "/tags/list"
+	for page := 0; path != "" && page < maxTagPages; page++ {
+		resp, err := c.do(ctx, ref, http.MethodGet, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag list: %w", err)
+		}
+		tags = append(tags, list.Tags...)
+		path = nextPage(resp.Header.Get("Link"))
+	}
+	return tags, nil
+}
+
+// nextPage returns the path of the next page from a Link header:
+// </v2/name/tags/list?last=x&n=100>; rel="next"
+func nextPage(link string) string {
+	target, params, ok := strings.Cut(link, ";")
+	if !ok || !strings.Contains(params, `rel="next"`) {
+		return ""
+	}
+	target = strings.Trim(strings.TrimSpace(target), "<>")
+	if u, err := url.Parse(target); err == nil && u.Path != "" {
+		return u.RequestURI()
+	}
+	return ""
+}
+
+// Manifest is the digest and platforms of a tag
+type Manifest struct {
+	Digest    string   `json:"digest"`
+	MediaType string   `json:"mediaType"`
+	Platforms []string `json:"platforms"` // os/architecture[/variant], e.g. linux/arm64/v8
+}
+
+// Inspect returns the digest and platforms of a tag. Multi-platform images
+// list their platforms in the index; for single-platform images the image
+// config is read.
+func (c *Client) Inspect(ctx context.Context, ref Reference) (*Manifest, error) {
+	tag := ref.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	header := http.Header{"Accept": {strings.Join([]string{
+		MediaTypeOCIIndex, MediaTypeDockerList, MediaTypeOCIManifest, MediaTypeDockerManifest,
+	}, ", ")}}
+	resp, err := c.do(ctx, ref, http.MethodGet, "/v2/"+ref.Repository+"/manifests/"+tag, header)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		MediaType string `json:"mediaType"`
+		Manifests []struct {
+			Platform *Platform `json:"platform"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	result := &Manifest{Digest: resp.Header.Get("Docker-Content-Digest"), MediaType: manifest.MediaType, Platforms: []string{}}
+	if result.MediaType == "" {
+		result.MediaType, _, _ = strings.Cut(resp.Header.Get("Content-Type"), ";")
+	}
+	if result.Digest == "" {
+		sum := sha256.Sum256(body)
+		result.Digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	if len(manifest.Manifests) > 0 {
+		for _, m := range manifest.Manifests {
+			// Attestations and signatures are listed as unknown/unknown
+			if m.Platform != nil && m.Platform.OS != "unknown" {
+				result.Platforms = append(result.Platforms, m.Platform.String())
+			}
+		}
+		return result, nil
+	}
+	if manifest.Config.Digest != "" {
+		resp, err := c.do(ctx, ref, http.MethodGet, "/v2/"+ref.Repository+"/blobs/"+manifest.Config.Digest, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		var config Platform
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&config); err == nil && config.OS != "" {
+			result.Platforms = append(result.Platforms, config.String())
+		}
+	}
+	return result, nil
+}
+
+// Platform is the platform of an image in an index or image config
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+}
+
+// String returns os/architecture[/variant]
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// do sends a request to the registry of ref, getting a bearer token first
+// if the registry asks for one
+func (c *Client) do(ctx context.Context, ref Reference, method, path string, header http.Header) (*http.Response, error) {
+	endpoint, ok := c.endpoints[ref.Registry]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotConfigured, ref.Registry)
+	}
+	scope := "repository:" + ref.Repository + ":pull"
+
+	send := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return c.httpClient.Do(req)
+	}
+
+	resp, err := send(c.cachedToken(endpoint, scope))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization, err := c.token(ctx, ref.Registry, endpoint, scope, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = send(authorization); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		// Docker Hub answers 401 for repositories that don't exist
+		resp.Body.Close()
+		return nil, ErrUnauthorized
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+}
+
+// cachedToken returns the Authorization header of a valid cached token
+func (c *Client) cachedToken(endpoint, scope string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tokens[endpoint+" "+scope]; ok && time.Now().Before(t.expires) {
+		return "Bearer " + t.value
+	}
+	return ""
+}
+
+// token gets a bearer token from the realm of a challenge, signing in with
+// the credentials of the registry if there are any:
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io".
+// Registries asking for Basic authentication get the credentials.
+func (c *Client) token(ctx context.Context, registry, endpoint, scope, challenge string) (string, error) {
+	var username, password string
+	login := false
+	if c.credentials != nil {
+		username, password, login = c.credentials(registry)
+	}
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") && login {
+		return basicAuth(username, password), nil
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", ErrUnauthorized
+	}
+	values := parseChallenge(params)
+	if values["realm"] == "" {
+		return "", ErrUnauthorized
+	}
+	query := url.Values{"scope": {scope}}
+	if values["scope"] != "" {
+		query.Set("scope", values["scope"])
+	}
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, values["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if login {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", ErrUnauthorized
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"` // seconds
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	value := result.Token
+	if value == "" {
+		value = result.AccessToken
+	}
+	if value == "" {
+		return "", ErrUnauthorized
+	}
+
+	ttl := tokenTTL
+	if result.ExpiresIn > 0 {
+		ttl = time.Duration(result.ExpiresIn) * time.Second
+	}
+	c.mu.Lock()
+	c.tokens[endpoint+" "+scope] = token{value: value, expires: time.Now().Add(ttl - ttl/10)}
+	c.mu.Unlock()
+	return "Bearer " + value, nil
+}
+
+// parseChallenge parses the comma-separated key="value" parameters of a
+// WWW-Authenticate header
+func parseChallenge(params string) map[string]string {
+	values := make(map[string]string)
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		values[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return values
+}
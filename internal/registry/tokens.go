@@ -0,0 +1,264 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Token providers, the services that issue the tokens of registries
+const (
+	ProviderGeneric = "generic" // a password or token checked by the registry only
+	ProviderGitHub  = "github"  // personal access tokens of GitHub, for ghcr.io
+	ProviderGitea   = "gitea"   // access tokens of Gitea or Forgejo, for their package registry
+)
+
+// GitHubAPI is the API of github.com; GitHub Enterprise serves it at
+// https://host/api/v3
+const GitHubAPI = "https://api.github.com"
+
+// GitHubScope is the scope a classic personal access token needs to pull
+// from ghcr.io
+const GitHubScope = "read:packages"
+
+// GiteaScopes are the scopes of the tokens created on Gitea: pulling and
+// pushing packages, and reading the account to check the token
+var GiteaScopes = []string{"read:user", "write:package"}
+
+var (
+	// ErrTokenRejected is returned when the provider refuses a token or
+	// password
+	ErrTokenRejected = errors.New("token rejected by the provider")
+
+	// ErrTokenScope is returned for a GitHub token without GitHubScope
+	ErrTokenScope = errors.New("token lacks the " + GitHubScope + " scope")
+
+	// ErrNoTokenAPI is returned for providers whose tokens can't be created
+	// through their API
+	ErrNoTokenAPI = errors.New("provider can't create tokens")
+
+	// errNoProviderAPI is returned when the provider API answers 404
+	errNoProviderAPI = errors.New("provider API not found, check the API URL")
+)
+
+// TokenInfo is what a provider tells about a token
+type TokenInfo struct {
+	Login   string     // account of the token
+	Scopes  []string   // empty if the provider doesn't say
+	Expires *time.Time // nil if the token doesn't expire or the provider doesn't say
+}
+
+// DetectProvider returns the provider of a registry known by its name;
+// Gitea instances are self-hosted and chosen explicitly
+func DetectProvider(registry string) string {
+	if registry == "ghcr.io" {
+		return ProviderGitHub
+	}
+	return ProviderGeneric
+}
+
+// ProviderAPI returns the default API URL of the provider of a registry:
+// GitHubAPI for GitHub, the registry host for Gitea, which serves the
+// registry and its API on the same host
+func ProviderAPI(provider, registry string) string {
+	switch provider {
+	case ProviderGitHub:
+		return GitHubAPI
+	case ProviderGitea:
+		_, endpoint := Endpoint(registry)
+		return endpoint
+	}
+	return ""
+}
+
+// NewTokenURL returns the page where users create a token for the
+// registry, empty if the provider has none
+func NewTokenURL(provider, apiURL string) string {
+	switch provider {
+	case ProviderGitHub:
+		host := "https://github.com"
+		if apiURL != "" && apiURL != GitHubAPI {
+			host = strings.TrimSuffix(strings.TrimSuffix(apiURL, "/"), "/api/v3")
+		}
+		return host + "/settings/tokens/new?scopes=" + GitHubScope + "&description=PodmanView"
+	case ProviderGitea:
+		return strings.TrimSuffix(apiURL, "/") + "/user/settings/applications"
+	}
+	return ""
+}
+
+// CheckToken asks the provider about a token: its account, scopes and
+// expiry. Generic tokens can't be checked and return an empty TokenInfo.
+func CheckToken(ctx context.Context, provider, apiURL, token string) (*TokenInfo, error) {
+	switch provider {
+	case ProviderGitHub:
+		return checkGitHubToken(ctx, apiURL, token)
+	case ProviderGitea:
+		return checkGiteaToken(ctx, apiURL, token)
+	}
+	return &TokenInfo{}, nil
+}
+
+// checkGitHubToken reads the account of a token. Classic tokens list their
+// scopes in X-OAuth-Scopes; tokens with an expiry have it in
+// GitHub-Authentication-Token-Expiration.
+func checkGitHubToken(ctx context.Context, apiURL, token string) (*TokenInfo, error) {
+	resp, err := tokenRequest(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/user", "Bearer "+token, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&user); err != nil {
+		return nil, fmt.Errorf("invalid GitHub response: %w", err)
+	}
+	info := &TokenInfo{Login: user.Login}
+	if header, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		info.Scopes = []string{}
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				info.Scopes = append(info.Scopes, scope)
+			}
+		}
+		// write:packages and delete:packages include reading
+		if !strings.Contains(strings.Join(info.Scopes, " "), ":packages") {
+			return info, ErrTokenScope
+		}
+	}
+	if value := resp.Header.Get("GitHub-Authentication-Token-Expiration"); value != "" {
+		for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+			if expires, err := time.Parse(layout, value); err == nil {
+				info.Expires = &expires
+				break
+			}
+		}
+	}
+	return info, nil
+}
+
+// checkGiteaToken reads the account of a Gitea token. Gitea tokens don't
+// expire.
+func checkGiteaToken(ctx context.Context, apiURL, token string) (*TokenInfo, error) {
+	resp, err := tokenRequest(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/api/v1/user", "token "+token, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&user); err != nil {
+		return nil, fmt.Errorf("invalid Gitea response: %w", err)
+	}
+	return &TokenInfo{Login: user.Login}, nil
+}
+
+// CreateGiteaToken creates a token with GiteaScopes named name, signing in
+// with the password of the account, and returns it
+func CreateGiteaToken(ctx context.Context, apiURL, username, password, name string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{"name": name, "scopes": GiteaScopes})
+	if err != nil {
+		return "", err
+	}
+	resp, err := tokenRequest(ctx, http.MethodPost, giteaTokensURL(apiURL, username), basicAuth(username, password), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var created struct {
+		SHA1 string `json:"sha1"` // the token, only returned on creation
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&created); err != nil || created.SHA1 == "" {
+		return "", errors.New("invalid Gitea response")
+	}
+	return created.SHA1, nil
+}
+
+// DeleteGiteaToken deletes a token of the account by its name; tokens that
+// are already gone are not an error
+func DeleteGiteaToken(ctx context.Context, apiURL, username, password, name string) error {
+	resp, err := tokenRequest(ctx, http.MethodDelete, giteaTokensURL(apiURL, username)+"/"+url.PathEscape(name), basicAuth(username, password), nil)
+	if errors.Is(err, errNoProviderAPI) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// giteaTokensURL returns the URL of the tokens of a Gitea account
+func giteaTokensURL(apiURL, username string) string {
+	return strings.TrimSuffix(apiURL, "/") + "/api/v1/users/" + url.PathEscape(username) + "/tokens"
+}
+
+// basicAuth returns a Basic Authorization header
+func basicAuth(username, password string) string {
+	req := http.Request{Header: http.Header{}}
+	req.SetBasicAuth(username, password)
+	return req.Header.Get("Authorization")
+}
+
+// tokenRequest sends a request to the API of a provider and returns the
+// response of a successful request
+func tokenRequest(ctx context.Context, method, target, authorization string, body []byte) (*http.Response, error) {
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid provider API URL %q", target)
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, ErrTokenRejected
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, errNoProviderAPI
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("provider returned status %d", resp.StatusCode)
+	}
+}
+
+// cancelOnClose releases the timeout of a request with its response body
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
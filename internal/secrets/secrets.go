@@ -0,0 +1,177 @@
+// Package secrets encrypts sensitive values (JWT secret, passwords, tokens)
+// stored in the config file and application database.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefix marks an encrypted value: enc:v1:<base64(nonce|ciphertext)>
+const Prefix = "enc:v1:"
+
+const (
+	keySize          = 32 // AES-256
+	pbkdf2Iterations = 200000
+	pbkdf2Salt       = "podmanview/secrets/v1"
+	hkdfInfo         = "podmanview secrets v1"
+)
+
+// machineIDPaths are checked in order for the machine secret
+var machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+var (
+	ErrNoCipher       = errors.New("value is encrypted but no secret key is configured")
+	ErrDecryptFailed  = errors.New("failed to decrypt value (wrong key or corrupted data)")
+	ErrEmptyPassword  = errors.New("secret passphrase is empty")
+	ErrNoMachineID    = errors.New("machine id not found")
+	ErrInvalidPayload = errors.New("invalid encrypted value")
+	ErrInvalidKey     = errors.New("secret key must be 32 bytes, hex or base64 encoded")
+)
+
+// Cipher encrypts and decrypts values with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New creates a cipher from a 32-byte key
+func New(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("secret key must be %d bytes", keySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// FromPassphrase creates a cipher with a key derived from a user-provided passphrase
+func FromPassphrase(passphrase string) (*Cipher, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassword
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, []byte(pbkdf2Salt), pbkdf2Iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	return New(key)
+}
+
+// FromKey creates a cipher from a hex (64 characters) or base64 encoded 32-byte key.
+// Generate one with: openssl rand -hex 32
+func FromKey(encoded string) (*Cipher, error) {
+	key, err := decodeKey(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, err
+	}
+	return New(key)
+}
+
+// FromKeyFile creates a cipher from a key file containing either
+// 32 raw bytes or a hex/base64 encoded key.
+func FromKeyFile(path string) (*Cipher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == keySize {
+		return New(data)
+	}
+	return FromKey(string(data))
+}
+
+// decodeKey decodes a hex or base64 encoded key
+func decodeKey(encoded string) ([]byte, error) {
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// FromMachineID creates a cipher with a key derived from the systemd machine id.
+// Encrypted values can only be read on the same machine.
+func FromMachineID() (*Cipher, error) {
+	for _, path := range machineIDPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		id := strings.TrimSpace(string(data))
+		if id == "" {
+			continue
+		}
+
+		key, err := hkdf.Key(sha256.New, []byte(id), nil, hkdfInfo, keySize)
+		if err != nil {
+			return nil, err
+		}
+		return New(key)
+	}
+
+	return nil, ErrNoMachineID
+}
+
+// IsEncrypted reports whether the value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt encrypts a value. Empty and already encrypted values are returned as is.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt.
+// Values without the prefix are treated as legacy plaintext and returned as is.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrNoCipher
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", ErrInvalidPayload
+	}
+
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrDecryptFailed
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Archive entry names
+const (
+	archiveManifest = "manifest.json"
+	archiveDatabase = "database"
+)
+
+// archiveVersion is the current archive layout version
+const archiveVersion = 1
+
+// maxArchiveSize limits the uncompressed size of an archive being restored
+const maxArchiveSize = 1 << 30 // 1 GB
+
+// ErrInvalidArchive is returned when an uploaded archive can't be restored
+var ErrInvalidArchive = errors.New("invalid backup archive")
+
+// ArchiveManifest describes the contents of a backup archive
+type ArchiveManifest struct {
+	Version   int       `json:"version"`
+	Backend   string    `json:"backend"` // storage backend of the database snapshot
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WriteArchive writes a gzip-compressed tar archive with a consistent snapshot
+// of the database and additional files (e.g. events) to w
+func WriteArchive(w io.Writer, store Storage, backend string, files map[string][]byte) error {
+	tmp, err := os.CreateTemp("", "podmanview-snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := store.Backup(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	manifest, err := json.Marshal(ArchiveManifest{
+		Version:   archiveVersion,
+		Backend:   backend,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	writeEntry := func(name string, size int64, r io.Reader) error {
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: size, ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := io.Copy(tw, r)
+		return err
+	}
+
+	if err := writeEntry(archiveManifest, int64(len(manifest)), bytes.NewReader(manifest)); err != nil {
+		return err
+	}
+	if err := writeEntry(archiveDatabase, size, tmp); err != nil {
+		return err
+	}
+	for name, data := range files {
+		if err := writeEntry(name, int64(len(data)), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// RestoreArchive validates an archive created by WriteArchive and replaces
+// plugin configurations, plugin data and command history in dst with its
+// contents. The snapshot may come from either backend. Additional files
+// are returned by name.
+func RestoreArchive(r io.Reader, dst Storage) (*ArchiveManifest, map[string][]byte, error) {
+	dir, err := os.MkdirTemp("", "podmanview-restore-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	var manifest *ArchiveManifest
+	files := make(map[string][]byte)
+	dbPath := filepath.Join(dir, archiveDatabase)
+	hasDatabase := false
+
+	tr := tar.NewReader(io.LimitReader(gz, maxArchiveSize))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch hdr.Name {
+		case archiveManifest:
+			manifest = &ArchiveManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("%w: bad manifest: %v", ErrInvalidArchive, err)
+			}
+		case archiveDatabase:
+			f, err := os.OpenFile(dbPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				return nil, nil, err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			hasDatabase = true
+		default:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			files[hdr.Name] = data
+		}
+	}
+
+	if manifest == nil || !hasDatabase {
+		return nil, nil, fmt.Errorf("%w: missing manifest or database", ErrInvalidArchive)
+	}
+	if manifest.Version != archiveVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, manifest.Version)
+	}
+
+	src, err := Open(manifest.Backend, dbPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer src.Close()
+
+	// Read everything before touching dst, so a corrupt snapshot leaves it unchanged
+	if _, err := src.ExportSettings(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if _, err := src.GetCommandHistory(maxMigratedHistory); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	if err := Migrate(src, dst); err != nil {
+		return nil, nil, err
+	}
+
+	return manifest, files, nil
+}
@@ -1,12 +1,20 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"go.etcd.io/bbolt"
+
+	"podmanview/internal/secrets"
 )
 
 const (
@@ -18,11 +26,19 @@ const (
 
 	// historyBucket stores command history
 	historyBucket = "_history"
+
+	// expiryBucket stores expiry times of plugin data keys set with a TTL,
+	// one sub-bucket per plugin (key -> big endian Unix nanoseconds)
+	expiryBucket = "_expiry"
 )
 
 // BoltStorage is a bbolt implementation of the Storage interface
 type BoltStorage struct {
-	db *bbolt.DB
+	mu     sync.RWMutex // guards db while Compact swaps the file
+	db     *bbolt.DB
+	cipher *secrets.Cipher // encrypts values written with SetSecretJSON, nil = plaintext
+
+	sensitive map[string]bool // plugin data buckets encrypted as a whole
 }
 
 // NewBoltStorage creates a new BoltStorage instance
@@ -46,6 +62,9 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 		if _, err := tx.CreateBucketIfNotExists([]byte(historyBucket)); err != nil {
 			return fmt.Errorf("failed to create history bucket: %w", err)
 		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(expiryBucket)); err != nil {
+			return fmt.Errorf("failed to create expiry bucket: %w", err)
+		}
 		return nil
 	})
 	if err != nil {
@@ -53,7 +72,46 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 		return nil, err
 	}
 
-	return &BoltStorage{db: db}, nil
+	return &BoltStorage{db: db, sensitive: sensitiveSet(DefaultSensitiveBuckets)}, nil
+}
+
+// view runs fn in a read-only transaction
+func (s *BoltStorage) view(fn func(tx *bbolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.View(fn)
+}
+
+// update runs fn in a read-write transaction
+func (s *BoltStorage) update(fn func(tx *bbolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.Update(fn)
+}
+
+// pluginExpiry returns the expiry sub-bucket of a plugin, nil if it has no keys with a TTL
+func pluginExpiry(tx *bbolt.Tx, pluginName string) *bbolt.Bucket {
+	bucket := tx.Bucket([]byte(expiryBucket))
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Bucket([]byte(pluginName))
+}
+
+// encodeExpiry encodes an expiry time (Unix nanoseconds) for the expiry bucket
+func encodeExpiry(expiresAt int64) []byte {
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(expiresAt))
+	return ts
+}
+
+// isExpired reports whether key has a TTL that passed at now (Unix nanoseconds)
+func isExpired(expiry *bbolt.Bucket, key []byte, now int64) bool {
+	if expiry == nil {
+		return false
+	}
+	v := expiry.Get(key)
+	return len(v) == 8 && int64(binary.BigEndian.Uint64(v)) <= now
 }
 
 // Plugin Configuration Methods
@@ -70,7 +128,7 @@ func (s *BoltStorage) DisablePlugin(name string) error {
 
 // updatePluginEnabled updates the enabled status of a plugin
 func (s *BoltStorage) updatePluginEnabled(name string, enabled bool) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -102,7 +160,7 @@ func (s *BoltStorage) updatePluginEnabled(name string, enabled bool) error {
 // IsPluginEnabled checks if a plugin is enabled
 func (s *BoltStorage) IsPluginEnabled(name string) (bool, error) {
 	var enabled bool
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -130,7 +188,7 @@ func (s *BoltStorage) IsPluginEnabled(name string) (bool, error) {
 // GetPluginConfig returns the configuration for a plugin
 func (s *BoltStorage) GetPluginConfig(name string) (*PluginConfig, error) {
 	var cfg *PluginConfig
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -154,7 +212,7 @@ func (s *BoltStorage) GetPluginConfig(name string) (*PluginConfig, error) {
 
 // SetPluginConfig sets the configuration for a plugin
 func (s *BoltStorage) SetPluginConfig(name string, cfg *PluginConfig) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -172,7 +230,7 @@ func (s *BoltStorage) SetPluginConfig(name string, cfg *PluginConfig) error {
 // ListEnabledPlugins returns a list of all enabled plugin names
 func (s *BoltStorage) ListEnabledPlugins() ([]string, error) {
 	var enabled []string
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -200,7 +258,7 @@ func (s *BoltStorage) ListEnabledPlugins() ([]string, error) {
 // Get retrieves data for a plugin by key
 func (s *BoltStorage) Get(pluginName, key string) ([]byte, error) {
 	var value []byte
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(dataBucket))
 		if bucket == nil {
 			return fmt.Errorf("data bucket not found")
@@ -212,7 +270,7 @@ func (s *BoltStorage) Get(pluginName, key string) ([]byte, error) {
 		}
 
 		data := pluginBucket.Get([]byte(key))
-		if data == nil {
+		if data == nil || isExpired(pluginExpiry(tx, pluginName), []byte(key), time.Now().UnixNano()) {
 			return ErrNotFound
 		}
 
@@ -220,8 +278,11 @@ func (s *BoltStorage) Get(pluginName, key string) ([]byte, error) {
 		copy(value, data)
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return value, err
+	return openValue(s.cipher, value)
 }
 
 // GetString retrieves string data for a plugin by key
@@ -278,8 +339,27 @@ func (s *BoltStorage) GetJSON(pluginName, key string, v interface{}) error {
 }
 
 // Set stores data for a plugin by key
+// Values in sensitive buckets are encrypted when a cipher is set.
 func (s *BoltStorage) Set(pluginName, key string, value []byte) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.put(pluginName, key, value, 0)
+}
+
+// SetWithTTL stores data for a plugin by key that expires after ttl
+func (s *BoltStorage) SetWithTTL(pluginName, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return s.Set(pluginName, key, value)
+	}
+	return s.put(pluginName, key, value, time.Now().Add(ttl).UnixNano())
+}
+
+// put stores a value and its expiry time (Unix nanoseconds, 0 = never)
+func (s *BoltStorage) put(pluginName, key string, value []byte, expiresAt int64) error {
+	value, err := sealValue(s.cipher, s.sensitive[pluginName], value)
+	if err != nil {
+		return err
+	}
+
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(dataBucket))
 		if bucket == nil {
 			return fmt.Errorf("data bucket not found")
@@ -290,8 +370,22 @@ func (s *BoltStorage) Set(pluginName, key string, value []byte) error {
 		if err != nil {
 			return fmt.Errorf("failed to create plugin bucket: %w", err)
 		}
+		if err := pluginBucket.Put([]byte(key), value); err != nil {
+			return err
+		}
+
+		if expiresAt == 0 {
+			if expiry := pluginExpiry(tx, pluginName); expiry != nil {
+				return expiry.Delete([]byte(key))
+			}
+			return nil
+		}
 
-		return pluginBucket.Put([]byte(key), value)
+		expiry, err := tx.Bucket([]byte(expiryBucket)).CreateBucketIfNotExists([]byte(pluginName))
+		if err != nil {
+			return fmt.Errorf("failed to create expiry bucket: %w", err)
+		}
+		return expiry.Put([]byte(key), encodeExpiry(expiresAt))
 	})
 }
 
@@ -320,9 +414,120 @@ func (s *BoltStorage) SetJSON(pluginName, key string, v interface{}) error {
 	return s.Set(pluginName, key, data)
 }
 
+// SetJSONWithTTL marshals and stores JSON data for a plugin by key that expires after ttl
+func (s *BoltStorage) SetJSONWithTTL(pluginName, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return s.SetWithTTL(pluginName, key, data, ttl)
+}
+
+// SetCipher sets the cipher used by SetSecretJSON and GetSecretJSON
+// and for values in sensitive buckets.
+// Must be called before the storage is shared between goroutines.
+func (s *BoltStorage) SetCipher(c *secrets.Cipher) {
+	s.cipher = c
+}
+
+// SetSensitiveBuckets replaces the plugin data buckets encrypted as a whole.
+// Must be called before the storage is shared between goroutines.
+func (s *BoltStorage) SetSensitiveBuckets(names ...string) {
+	s.sensitive = sensitiveSet(names)
+}
+
+// EncryptSensitive encrypts plaintext values left in sensitive buckets,
+// e.g. written before a cipher was configured. Returns the number of values encrypted.
+func (s *BoltStorage) EncryptSensitive() (int, error) {
+	if s.cipher == nil {
+		return 0, nil
+	}
+
+	var count int
+	err := s.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(dataBucket))
+		if bucket == nil {
+			return fmt.Errorf("data bucket not found")
+		}
+
+		for name := range s.sensitive {
+			pluginBucket := bucket.Bucket([]byte(name))
+			if pluginBucket == nil {
+				continue
+			}
+
+			sealed := make(map[string][]byte)
+			err := pluginBucket.ForEach(func(k, v []byte) error {
+				if secrets.IsEncrypted(string(v)) {
+					return nil
+				}
+				enc, err := sealValue(s.cipher, true, v)
+				if err != nil {
+					return err
+				}
+				sealed[string(k)] = enc
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for k, v := range sealed {
+				if err := pluginBucket.Put([]byte(k), v); err != nil {
+					return err
+				}
+				count++
+			}
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// GetSecretJSON retrieves and unmarshals JSON data stored with SetSecretJSON.
+// Plaintext values written before encryption was enabled are still readable.
+func (s *BoltStorage) GetSecretJSON(pluginName, key string, v interface{}) error {
+	data, err := s.Get(pluginName, key)
+	if err != nil {
+		return err
+	}
+
+	plain, err := s.cipher.Decrypt(string(data))
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(plain), v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+// SetSecretJSON marshals and stores JSON data, encrypted if a cipher is set
+func (s *BoltStorage) SetSecretJSON(pluginName, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if s.cipher == nil {
+		return s.Set(pluginName, key, data)
+	}
+
+	enc, err := s.cipher.Encrypt(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	return s.Set(pluginName, key, []byte(enc))
+}
+
 // Delete removes data for a plugin by key
 func (s *BoltStorage) Delete(pluginName, key string) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(dataBucket))
 		if bucket == nil {
 			return fmt.Errorf("data bucket not found")
@@ -333,14 +538,25 @@ func (s *BoltStorage) Delete(pluginName, key string) error {
 			return ErrNotFound
 		}
 
+		if expiry := pluginExpiry(tx, pluginName); expiry != nil {
+			if err := expiry.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
 		return pluginBucket.Delete([]byte(key))
 	})
 }
 
 // List returns all keys and values for a plugin
 func (s *BoltStorage) List(pluginName string) (map[string][]byte, error) {
+	return s.ListPrefix(pluginName, "")
+}
+
+// ListPrefix returns the keys and values for a plugin whose key starts with prefix
+func (s *BoltStorage) ListPrefix(pluginName, prefix string) (map[string][]byte, error) {
 	result := make(map[string][]byte)
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	now := time.Now().UnixNano()
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(dataBucket))
 		if bucket == nil {
 			return fmt.Errorf("data bucket not found")
@@ -352,47 +568,165 @@ func (s *BoltStorage) List(pluginName string) (map[string][]byte, error) {
 			return nil
 		}
 
-		return pluginBucket.ForEach(func(k, v []byte) error {
+		expiry := pluginExpiry(tx, pluginName)
+		c := pluginBucket.Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if v == nil || isExpired(expiry, k, now) {
+				continue
+			}
 			value := make([]byte, len(v))
 			copy(value, v)
 			result[string(k)] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range result {
+		if result[k], err = openValue(s.cipher, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteExpired removes keys whose TTL has passed and returns their number
+func (s *BoltStorage) DeleteExpired() (int, error) {
+	var count int
+	now := time.Now().UnixNano()
+	err := s.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(expiryBucket))
+		data := tx.Bucket([]byte(dataBucket))
+		if bucket == nil || data == nil {
+			return nil
+		}
+
+		return bucket.ForEachBucket(func(name []byte) error {
+			expiry := bucket.Bucket(name)
+			var keys [][]byte
+			err := expiry.ForEach(func(k, _ []byte) error {
+				if isExpired(expiry, k, now) {
+					keys = append(keys, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			pluginBucket := data.Bucket(name)
+			for _, k := range keys {
+				if err := expiry.Delete(k); err != nil {
+					return err
+				}
+				if pluginBucket != nil {
+					if err := pluginBucket.Delete(k); err != nil {
+						return err
+					}
+				}
+				count++
+			}
 			return nil
 		})
 	})
 
-	return result, err
+	return count, err
 }
 
 // Command History Methods
 
-// SaveCommandHistory saves a command to history
+// historyKey formats a history entry ID as a sortable bucket key
+func historyKey(id int64) []byte {
+	return []byte(fmt.Sprintf("%020d", id))
+}
+
+// decodeHistoryEntry unmarshals a history entry, its ID is the key.
+// Entries saved before targets were recorded ran in the host terminal.
+func decodeHistoryEntry(k, v []byte) (CommandHistoryEntry, error) {
+	var entry CommandHistoryEntry
+	if err := json.Unmarshal(v, &entry); err != nil {
+		return entry, err
+	}
+	entry.ID, _ = strconv.ParseInt(string(k), 10, 64)
+	if entry.Target == "" {
+		entry.Target = HostTarget
+	}
+	return entry, nil
+}
+
+// SaveCommandHistory saves a host terminal command to history
 func (s *BoltStorage) SaveCommandHistory(command string, timestamp time.Time) error {
-	// Check if this is a duplicate of the last command
-	lastCmd, err := s.GetLastCommand()
-	if err == nil && lastCmd == command {
-		// Skip duplicate consecutive command
-		return nil
+	return s.AddCommandHistory(&CommandHistoryEntry{Command: command, Timestamp: timestamp})
+}
+
+// AddCommandHistory saves a command with its user and target to history.
+// The ID is the timestamp in Unix nanoseconds, bumped if already taken.
+func (s *BoltStorage) AddCommandHistory(entry *CommandHistoryEntry) error {
+	if entry.Target == "" {
+		entry.Target = HostTarget
 	}
 
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(historyBucket))
 		if bucket == nil {
 			return fmt.Errorf("history bucket not found")
 		}
 
-		entry := CommandHistoryEntry{
-			Command:   command,
-			Timestamp: timestamp,
+		// Skip a repeat of the previous command by the same user on the same target
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			prev, err := decodeHistoryEntry(k, v)
+			if err != nil || prev.User != entry.User || prev.Target != entry.Target {
+				continue
+			}
+			if prev.Command == entry.Command {
+				entry.ID = prev.ID
+				return nil
+			}
+			break
+		}
+
+		id := entry.Timestamp.UnixNano()
+		for bucket.Get(historyKey(id)) != nil {
+			id++
 		}
+		entry.ID = id
 
 		data, err := json.Marshal(entry)
 		if err != nil {
 			return fmt.Errorf("failed to marshal history entry: %w", err)
 		}
 
-		// Use timestamp as key (formatted as Unix nano for sorting)
-		key := []byte(fmt.Sprintf("%020d", timestamp.UnixNano()))
-		return bucket.Put(key, data)
+		return bucket.Put(historyKey(id), data)
+	})
+}
+
+// SetCommandExitCode records the exit status of a command in history
+func (s *BoltStorage) SetCommandExitCode(id int64, code int) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(historyBucket))
+		if bucket == nil {
+			return fmt.Errorf("history bucket not found")
+		}
+
+		v := bucket.Get(historyKey(id))
+		if v == nil {
+			return ErrNotFound
+		}
+		entry, err := decodeHistoryEntry(historyKey(id), v)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal history entry: %w", err)
+		}
+		entry.ExitCode = &code
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		return bucket.Put(historyKey(id), data)
 	})
 }
 
@@ -400,7 +734,7 @@ func (s *BoltStorage) SaveCommandHistory(command string, timestamp time.Time) er
 func (s *BoltStorage) GetCommandHistory(limit int) ([]CommandHistoryEntry, error) {
 	var entries []CommandHistoryEntry
 
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(historyBucket))
 		if bucket == nil {
 			return fmt.Errorf("history bucket not found")
@@ -411,8 +745,8 @@ func (s *BoltStorage) GetCommandHistory(limit int) ([]CommandHistoryEntry, error
 		cursor := bucket.Cursor()
 
 		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var entry CommandHistoryEntry
-			if err := json.Unmarshal(v, &entry); err != nil {
+			entry, err := decodeHistoryEntry(k, v)
+			if err != nil {
 				continue // Skip corrupted entries
 			}
 			allEntries = append(allEntries, entry)
@@ -431,11 +765,39 @@ func (s *BoltStorage) GetCommandHistory(limit int) ([]CommandHistoryEntry, error
 	return entries, err
 }
 
+// SearchCommandHistory returns a page of matching commands, newest first
+func (s *BoltStorage) SearchCommandHistory(q HistoryQuery) (*HistoryPage, error) {
+	page := &HistoryPage{Entries: []CommandHistoryEntry{}}
+	terms := q.terms()
+
+	err := s.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(historyBucket))
+		if bucket == nil {
+			return fmt.Errorf("history bucket not found")
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			entry, err := decodeHistoryEntry(k, v)
+			if err != nil || !q.matches(&entry, terms) {
+				continue
+			}
+			if page.Total >= q.Offset && len(page.Entries) < q.Limit {
+				page.Entries = append(page.Entries, entry)
+			}
+			page.Total++
+		}
+		return nil
+	})
+
+	return page, err
+}
+
 // GetLastCommand returns the most recent command from history
 func (s *BoltStorage) GetLastCommand() (string, error) {
 	var lastCommand string
 
-	err := s.db.View(func(tx *bbolt.Tx) error {
+	err := s.view(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(historyBucket))
 		if bucket == nil {
 			return fmt.Errorf("history bucket not found")
@@ -464,7 +826,7 @@ func (s *BoltStorage) GetLastCommand() (string, error) {
 
 // TrimCommandHistory keeps only the last maxCommands in history
 func (s *BoltStorage) TrimCommandHistory(maxCommands int) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte(historyBucket))
 		if bucket == nil {
 			return fmt.Errorf("history bucket not found")
@@ -496,7 +858,271 @@ func (s *BoltStorage) TrimCommandHistory(maxCommands int) error {
 	})
 }
 
+// Backup writes a consistent snapshot of the database to w
+func (s *BoltStorage) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := s.view(func(tx *bbolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// settingsSnapshot is the JSON layout written by ExportSettings
+type settingsSnapshot struct {
+	Plugins map[string]json.RawMessage   `json:"plugins"`
+	Data    map[string]map[string][]byte `json:"data"`
+	Expiry  map[string]map[string]int64  `json:"expiry,omitempty"` // Unix nanoseconds of keys set with a TTL
+}
+
+// ExportSettings returns plugin configurations and plugin data as JSON.
+// Command history is not included. Encrypted values are exported as stored.
+func (s *BoltStorage) ExportSettings() ([]byte, error) {
+	snapshot := settingsSnapshot{
+		Plugins: make(map[string]json.RawMessage),
+		Data:    make(map[string]map[string][]byte),
+	}
+
+	err := s.view(func(tx *bbolt.Tx) error {
+		cfgBucket := tx.Bucket([]byte(configBucket))
+		if cfgBucket == nil {
+			return fmt.Errorf("config bucket not found")
+		}
+		if err := cfgBucket.ForEach(func(k, v []byte) error {
+			snapshot.Plugins[string(k)] = append(json.RawMessage(nil), v...)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		bucket := tx.Bucket([]byte(dataBucket))
+		if bucket == nil {
+			return fmt.Errorf("data bucket not found")
+		}
+		if err := bucket.ForEachBucket(func(name []byte) error {
+			values := make(map[string][]byte)
+			err := bucket.Bucket(name).ForEach(func(k, v []byte) error {
+				values[string(k)] = append([]byte(nil), v...)
+				return nil
+			})
+			snapshot.Data[string(name)] = values
+			return err
+		}); err != nil {
+			return err
+		}
+
+		expiryBkt := tx.Bucket([]byte(expiryBucket))
+		if expiryBkt == nil {
+			return nil
+		}
+		return expiryBkt.ForEachBucket(func(name []byte) error {
+			return expiryBkt.Bucket(name).ForEach(func(k, v []byte) error {
+				if len(v) != 8 {
+					return nil
+				}
+				if snapshot.Expiry == nil {
+					snapshot.Expiry = make(map[string]map[string]int64)
+				}
+				if snapshot.Expiry[string(name)] == nil {
+					snapshot.Expiry[string(name)] = make(map[string]int64)
+				}
+				snapshot.Expiry[string(name)][string(k)] = int64(binary.BigEndian.Uint64(v))
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(snapshot)
+}
+
+// ImportSettings replaces plugin configurations and plugin data
+// with a snapshot created by ExportSettings.
+// Plaintext values in sensitive buckets are encrypted on import.
+func (s *BoltStorage) ImportSettings(data []byte) error {
+	var snapshot settingsSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to unmarshal settings snapshot: %w", err)
+	}
+
+	return s.update(func(tx *bbolt.Tx) error {
+		for _, name := range []string{configBucket, dataBucket, expiryBucket} {
+			if err := tx.DeleteBucket([]byte(name)); err != nil && err != bbolt.ErrBucketNotFound {
+				return fmt.Errorf("failed to clear %s bucket: %w", name, err)
+			}
+		}
+
+		cfgBucket, err := tx.CreateBucket([]byte(configBucket))
+		if err != nil {
+			return fmt.Errorf("failed to create config bucket: %w", err)
+		}
+		for name, cfg := range snapshot.Plugins {
+			if err := cfgBucket.Put([]byte(name), cfg); err != nil {
+				return err
+			}
+		}
+
+		bucket, err := tx.CreateBucket([]byte(dataBucket))
+		if err != nil {
+			return fmt.Errorf("failed to create data bucket: %w", err)
+		}
+		for pluginName, values := range snapshot.Data {
+			pluginBucket, err := bucket.CreateBucket([]byte(pluginName))
+			if err != nil {
+				return fmt.Errorf("failed to create plugin bucket: %w", err)
+			}
+			for key, value := range values {
+				value, err := sealValue(s.cipher, s.sensitive[pluginName], value)
+				if err != nil {
+					return err
+				}
+				if err := pluginBucket.Put([]byte(key), value); err != nil {
+					return err
+				}
+			}
+		}
+
+		expiryBkt, err := tx.CreateBucket([]byte(expiryBucket))
+		if err != nil {
+			return fmt.Errorf("failed to create expiry bucket: %w", err)
+		}
+		for pluginName, keys := range snapshot.Expiry {
+			expiry, err := expiryBkt.CreateBucket([]byte(pluginName))
+			if err != nil {
+				return fmt.Errorf("failed to create expiry bucket: %w", err)
+			}
+			for key, expiresAt := range keys {
+				if err := expiry.Put([]byte(key), encodeExpiry(expiresAt)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Maintenance Methods
+
+// Stats returns the database file size and space used per bucket.
+// Plugin data buckets are reported as "_data/<plugin>".
+func (s *BoltStorage) Stats() (*Stats, error) {
+	stats := &Stats{Backend: BackendBolt}
+
+	err := s.view(func(tx *bbolt.Tx) error {
+		stats.FileSize = tx.Size()
+
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			stats.Buckets = append(stats.Buckets, boltBucketStats(string(name), b))
+			if string(name) != dataBucket {
+				return nil
+			}
+			return b.ForEachBucket(func(plugin []byte) error {
+				stats.Buckets = append(stats.Buckets, boltBucketStats(dataBucket+"/"+string(plugin), b.Bucket(plugin)))
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// boltBucketStats converts bbolt statistics of a bucket (including nested buckets)
+func boltBucketStats(name string, b *bbolt.Bucket) BucketStats {
+	st := b.Stats()
+	return BucketStats{
+		Name: name,
+		Keys: st.KeyN,
+		Size: int64(st.BranchInuse + st.LeafInuse),
+	}
+}
+
+// Check verifies the consistency of all pages and buckets.
+// Returns nil if the database is intact.
+func (s *BoltStorage) Check() error {
+	return s.view(func(tx *bbolt.Tx) error {
+		var errs []error
+		// Drain the channel: the checker runs until the whole file is visited
+		for err := range tx.Check() {
+			if len(errs) < maxCheckErrors {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// Compact rewrites the database into a new file without free pages and
+// replaces the old file. Other operations wait until it completes.
+// Returns the file size before and after compaction.
+func (s *BoltStorage) Compact() (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.db.Path()
+	before := fileSize(path)
+	tmpPath := path + ".compact"
+	os.Remove(tmpPath)
+
+	dst, err := bbolt.Open(tmpPath, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return before, before, fmt.Errorf("failed to create compacted database: %w", err)
+	}
+	if err := bbolt.Compact(dst, s.db, compactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return before, before, fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return before, before, err
+	}
+
+	if err := s.db.Close(); err != nil {
+		os.Remove(tmpPath)
+		return before, before, err
+	}
+
+	renameErr := os.Rename(tmpPath, path)
+	if renameErr != nil {
+		os.Remove(tmpPath)
+	}
+
+	// Reopen the database (the original file if the rename failed)
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return before, before, fmt.Errorf("failed to reopen database after compaction: %w", err)
+	}
+	s.db = db
+
+	if renameErr != nil {
+		return before, before, fmt.Errorf("failed to replace database file: %w", renameErr)
+	}
+
+	return before, fileSize(path), nil
+}
+
+// compactTxMaxSize is the amount of data copied per transaction during compaction
+const compactTxMaxSize = 64 << 20 // 64 MB
+
+// fileSize returns the size of a file, or 0 if it can't be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
 // Close closes the storage
 func (s *BoltStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.db.Close()
 }
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+
+	"podmanview/internal/secrets"
+)
+
+// DefaultSensitiveBuckets are plugin data namespaces whose values are all
+// encrypted at rest when a cipher is set: JWT signing keys, container
+// registry credentials, API keys, webhook signing secrets,
+// notification channel tokens, the environment of removed containers,
+// notes, which may say where credentials are kept, the launcher
+// access key, the environment files of stacks and the signing key of
+// share links.
+var DefaultSensitiveBuckets = []string{"auth", "registry", "apikeys", "webhooks", "notifications", "trash", "notes", "launcher", "stackenv", "shares"}
+
+// sensitiveSet builds a lookup set of bucket names
+func sensitiveSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// sealValue encrypts a value written to a sensitive bucket.
+// Values are left as is without a cipher, outside sensitive buckets,
+// or if already encrypted (SetSecretJSON).
+func sealValue(c *secrets.Cipher, sensitive bool, value []byte) ([]byte, error) {
+	if c == nil || !sensitive || len(value) == 0 || secrets.IsEncrypted(string(value)) {
+		return value, nil
+	}
+
+	enc, err := c.Encrypt(string(value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt value: %w", err)
+	}
+	return []byte(enc), nil
+}
+
+// openValue decrypts a value read from storage.
+// Plaintext values are returned as is.
+func openValue(c *secrets.Cipher, value []byte) ([]byte, error) {
+	if !secrets.IsEncrypted(string(value)) {
+		return value, nil
+	}
+
+	plain, err := c.Decrypt(string(value))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(plain), nil
+}
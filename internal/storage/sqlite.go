@@ -0,0 +1,859 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"podmanview/internal/secrets"
+)
+
+// sqliteSchema creates the tables used by SQLiteStorage.
+// Values are stored as the same bytes BoltStorage uses, so settings
+// snapshots are interchangeable between backends.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS plugin_config (
+	name    TEXT PRIMARY KEY,
+	enabled INTEGER NOT NULL DEFAULT 0,
+	title   TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS plugin_data (
+	plugin TEXT NOT NULL,
+	key    TEXT NOT NULL,
+	value  BLOB NOT NULL,
+	expires_at INTEGER, -- Unix nanoseconds, NULL = never
+	PRIMARY KEY (plugin, key)
+);
+CREATE TABLE IF NOT EXISTS command_history (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	command   TEXT NOT NULL,
+	timestamp INTEGER NOT NULL, -- Unix nanoseconds
+	user      TEXT NOT NULL DEFAULT '',
+	target    TEXT NOT NULL DEFAULT 'host',
+	exit_code INTEGER -- NULL = not reported
+);
+CREATE INDEX IF NOT EXISTS command_history_timestamp ON command_history (timestamp);
+`
+
+// sqliteMigrations upgrade databases created by older versions.
+// Each entry adds a column if it is missing, then runs its statements.
+var sqliteMigrations = []struct {
+	table, column, definition string
+	after                     string // statement run once the column exists
+}{
+	{
+		table:      "plugin_data",
+		column:     "expires_at",
+		definition: "INTEGER", // Unix nanoseconds, NULL = never
+		after:      `CREATE INDEX IF NOT EXISTS plugin_data_expires_at ON plugin_data (expires_at) WHERE expires_at IS NOT NULL`,
+	},
+	{table: "command_history", column: "user", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "command_history", column: "target", definition: "TEXT NOT NULL DEFAULT 'host'"},
+	{table: "command_history", column: "exit_code", definition: "INTEGER"},
+}
+
+// migrateSQLite applies sqliteMigrations
+func migrateSQLite(db *sql.DB) error {
+	for _, m := range sqliteMigrations {
+		var exists bool
+		err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)`, m.table, m.column).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, m.table, m.column, m.definition)); err != nil {
+				return fmt.Errorf("failed to add %s.%s: %w", m.table, m.column, err)
+			}
+		}
+		if m.after == "" {
+			continue
+		}
+		if _, err := db.Exec(m.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLiteStorage is a SQLite implementation of the Storage interface.
+// The database can be inspected with standard tools (sqlite3 CLI) while
+// the server is running, and allows concurrent readers (WAL mode).
+type SQLiteStorage struct {
+	db     *sql.DB
+	cipher *secrets.Cipher // encrypts values written with SetSecretJSON, nil = plaintext
+
+	sensitive map[string]bool // plugin data buckets encrypted as a whole
+}
+
+// NewSQLiteStorage creates a new SQLiteStorage instance
+// The database file will be created if it doesn't exist
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+	dsn := "file:" + path + "?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sqlite schema: %w", err)
+	}
+
+	// Same permissions as the bbolt database file
+	os.Chmod(path, 0600)
+
+	return &SQLiteStorage{db: db, sensitive: sensitiveSet(DefaultSensitiveBuckets)}, nil
+}
+
+// Plugin Configuration Methods
+
+// EnablePlugin enables a plugin by name
+func (s *SQLiteStorage) EnablePlugin(name string) error {
+	return s.updatePluginEnabled(name, true)
+}
+
+// DisablePlugin disables a plugin by name
+func (s *SQLiteStorage) DisablePlugin(name string) error {
+	return s.updatePluginEnabled(name, false)
+}
+
+// updatePluginEnabled updates the enabled status of a plugin
+func (s *SQLiteStorage) updatePluginEnabled(name string, enabled bool) error {
+	_, err := s.db.Exec(`INSERT INTO plugin_config (name, enabled, title) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET enabled = excluded.enabled`, name, enabled, name)
+	return err
+}
+
+// IsPluginEnabled checks if a plugin is enabled
+func (s *SQLiteStorage) IsPluginEnabled(name string) (bool, error) {
+	var enabled bool
+	err := s.db.QueryRow(`SELECT enabled FROM plugin_config WHERE name = ?`, name).Scan(&enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Plugin not found - default to false
+		return false, nil
+	}
+	return enabled, err
+}
+
+// GetPluginConfig returns the configuration for a plugin
+func (s *SQLiteStorage) GetPluginConfig(name string) (*PluginConfig, error) {
+	cfg := &PluginConfig{}
+	err := s.db.QueryRow(`SELECT enabled, title FROM plugin_config WHERE name = ?`, name).Scan(&cfg.Enabled, &cfg.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPluginNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// SetPluginConfig sets the configuration for a plugin
+func (s *SQLiteStorage) SetPluginConfig(name string, cfg *PluginConfig) error {
+	_, err := s.db.Exec(`INSERT INTO plugin_config (name, enabled, title) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET enabled = excluded.enabled, title = excluded.title`, name, cfg.Enabled, cfg.Name)
+	return err
+}
+
+// ListEnabledPlugins returns a list of all enabled plugin names
+func (s *SQLiteStorage) ListEnabledPlugins() ([]string, error) {
+	rows, err := s.db.Query(`SELECT name FROM plugin_config WHERE enabled ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var enabled []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		enabled = append(enabled, name)
+	}
+	return enabled, rows.Err()
+}
+
+// Plugin Data Methods
+
+// Get retrieves data for a plugin by key
+func (s *SQLiteStorage) Get(pluginName, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRow(`SELECT value FROM plugin_data WHERE plugin = ? AND key = ?
+		AND (expires_at IS NULL OR expires_at > ?)`, pluginName, key, time.Now().UnixNano()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return openValue(s.cipher, value)
+}
+
+// GetString retrieves string data for a plugin by key
+func (s *SQLiteStorage) GetString(pluginName, key string) (string, error) {
+	data, err := s.Get(pluginName, key)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// GetInt retrieves int data for a plugin by key
+func (s *SQLiteStorage) GetInt(pluginName, key string) (int, error) {
+	data, err := s.Get(pluginName, key)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse int: %w", err)
+	}
+
+	return value, nil
+}
+
+// GetBool retrieves bool data for a plugin by key
+func (s *SQLiteStorage) GetBool(pluginName, key string) (bool, error) {
+	data, err := s.Get(pluginName, key)
+	if err != nil {
+		return false, err
+	}
+
+	value, err := strconv.ParseBool(string(data))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse bool: %w", err)
+	}
+
+	return value, nil
+}
+
+// GetJSON retrieves and unmarshals JSON data for a plugin by key
+func (s *SQLiteStorage) GetJSON(pluginName, key string, v interface{}) error {
+	data, err := s.Get(pluginName, key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+// Set stores data for a plugin by key
+// Values in sensitive buckets are encrypted when a cipher is set.
+func (s *SQLiteStorage) Set(pluginName, key string, value []byte) error {
+	return s.put(pluginName, key, value, sql.NullInt64{})
+}
+
+// SetWithTTL stores data for a plugin by key that expires after ttl
+func (s *SQLiteStorage) SetWithTTL(pluginName, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return s.Set(pluginName, key, value)
+	}
+	return s.put(pluginName, key, value, sql.NullInt64{Int64: time.Now().Add(ttl).UnixNano(), Valid: true})
+}
+
+// put stores a value and its expiry time (Unix nanoseconds, NULL = never)
+func (s *SQLiteStorage) put(pluginName, key string, value []byte, expiresAt sql.NullInt64) error {
+	value, err := sealValue(s.cipher, s.sensitive[pluginName], value)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	_, err = s.db.Exec(`INSERT INTO plugin_data (plugin, key, value, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (plugin, key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		pluginName, key, value, expiresAt)
+	return err
+}
+
+// SetString stores string data for a plugin by key
+func (s *SQLiteStorage) SetString(pluginName, key string, value string) error {
+	return s.Set(pluginName, key, []byte(value))
+}
+
+// SetInt stores int data for a plugin by key
+func (s *SQLiteStorage) SetInt(pluginName, key string, value int) error {
+	return s.Set(pluginName, key, []byte(strconv.Itoa(value)))
+}
+
+// SetBool stores bool data for a plugin by key
+func (s *SQLiteStorage) SetBool(pluginName, key string, value bool) error {
+	return s.Set(pluginName, key, []byte(strconv.FormatBool(value)))
+}
+
+// SetJSON marshals and stores JSON data for a plugin by key
+func (s *SQLiteStorage) SetJSON(pluginName, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return s.Set(pluginName, key, data)
+}
+
+// SetJSONWithTTL marshals and stores JSON data for a plugin by key that expires after ttl
+func (s *SQLiteStorage) SetJSONWithTTL(pluginName, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return s.SetWithTTL(pluginName, key, data, ttl)
+}
+
+// SetCipher sets the cipher used by SetSecretJSON and GetSecretJSON
+// and for values in sensitive buckets.
+// Must be called before the storage is shared between goroutines.
+func (s *SQLiteStorage) SetCipher(c *secrets.Cipher) {
+	s.cipher = c
+}
+
+// SetSensitiveBuckets replaces the plugin data buckets encrypted as a whole.
+// Must be called before the storage is shared between goroutines.
+func (s *SQLiteStorage) SetSensitiveBuckets(names ...string) {
+	s.sensitive = sensitiveSet(names)
+}
+
+// EncryptSensitive encrypts plaintext values left in sensitive buckets,
+// e.g. written before a cipher was configured. Returns the number of values encrypted.
+func (s *SQLiteStorage) EncryptSensitive() (int, error) {
+	if s.cipher == nil {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	for name := range s.sensitive {
+		rows, err := tx.Query(`SELECT key, value FROM plugin_data WHERE plugin = ?`, name)
+		if err != nil {
+			return 0, err
+		}
+
+		sealed := make(map[string][]byte)
+		for rows.Next() {
+			var key string
+			var value []byte
+			if err := rows.Scan(&key, &value); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			if secrets.IsEncrypted(string(value)) {
+				continue
+			}
+			enc, err := sealValue(s.cipher, true, value)
+			if err != nil {
+				rows.Close()
+				return 0, err
+			}
+			sealed[key] = enc
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for key, value := range sealed {
+			if _, err := tx.Exec(`UPDATE plugin_data SET value = ? WHERE plugin = ? AND key = ?`, value, name, key); err != nil {
+				return 0, err
+			}
+			count++
+		}
+	}
+
+	return count, tx.Commit()
+}
+
+// GetSecretJSON retrieves and unmarshals JSON data stored with SetSecretJSON.
+// Plaintext values written before encryption was enabled are still readable.
+func (s *SQLiteStorage) GetSecretJSON(pluginName, key string, v interface{}) error {
+	data, err := s.Get(pluginName, key)
+	if err != nil {
+		return err
+	}
+
+	plain, err := s.cipher.Decrypt(string(data))
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(plain), v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+// SetSecretJSON marshals and stores JSON data, encrypted if a cipher is set
+func (s *SQLiteStorage) SetSecretJSON(pluginName, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if s.cipher == nil {
+		return s.Set(pluginName, key, data)
+	}
+
+	enc, err := s.cipher.Encrypt(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	return s.Set(pluginName, key, []byte(enc))
+}
+
+// Delete removes data for a plugin by key
+func (s *SQLiteStorage) Delete(pluginName, key string) error {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM plugin_data WHERE plugin = ?)`, pluginName).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	_, err := s.db.Exec(`DELETE FROM plugin_data WHERE plugin = ? AND key = ?`, pluginName, key)
+	return err
+}
+
+// List returns all keys and values for a plugin
+func (s *SQLiteStorage) List(pluginName string) (map[string][]byte, error) {
+	return s.ListPrefix(pluginName, "")
+}
+
+// ListPrefix returns the keys and values for a plugin whose key starts with prefix
+func (s *SQLiteStorage) ListPrefix(pluginName, prefix string) (map[string][]byte, error) {
+	rows, err := s.db.Query(`SELECT key, value FROM plugin_data WHERE plugin = ?
+		AND substr(key, 1, length(?)) = ? AND (expires_at IS NULL OR expires_at > ?)`,
+		pluginName, prefix, prefix, time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string][]byte)
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		if value, err = openValue(s.cipher, value); err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, rows.Err()
+}
+
+// DeleteExpired removes keys whose TTL has passed and returns their number
+func (s *SQLiteStorage) DeleteExpired() (int, error) {
+	res, err := s.db.Exec(`DELETE FROM plugin_data WHERE expires_at IS NOT NULL AND expires_at <= ?`, time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Command History Methods
+
+// historyColumns are the command_history columns read by scanHistory
+const historyColumns = `id, command, timestamp, user, target, exit_code`
+
+// scanHistory reads command history rows selected with historyColumns
+func scanHistory(rows *sql.Rows) ([]CommandHistoryEntry, error) {
+	entries := []CommandHistoryEntry{}
+	for rows.Next() {
+		var entry CommandHistoryEntry
+		var nanos int64
+		var exitCode sql.NullInt64
+		if err := rows.Scan(&entry.ID, &entry.Command, &nanos, &entry.User, &entry.Target, &exitCode); err != nil {
+			return nil, err
+		}
+		entry.Timestamp = time.Unix(0, nanos)
+		if exitCode.Valid {
+			code := int(exitCode.Int64)
+			entry.ExitCode = &code
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SaveCommandHistory saves a host terminal command to history
+func (s *SQLiteStorage) SaveCommandHistory(command string, timestamp time.Time) error {
+	return s.AddCommandHistory(&CommandHistoryEntry{Command: command, Timestamp: timestamp})
+}
+
+// AddCommandHistory saves a command with its user and target to history
+func (s *SQLiteStorage) AddCommandHistory(entry *CommandHistoryEntry) error {
+	if entry.Target == "" {
+		entry.Target = HostTarget
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Skip a repeat of the previous command by the same user on the same target
+	var prevID int64
+	var prevCommand string
+	err = tx.QueryRow(`SELECT id, command FROM command_history WHERE user = ? AND target = ?
+		ORDER BY timestamp DESC, id DESC LIMIT 1`, entry.User, entry.Target).Scan(&prevID, &prevCommand)
+	if err == nil && prevCommand == entry.Command {
+		entry.ID = prevID
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	var exitCode sql.NullInt64
+	if entry.ExitCode != nil {
+		exitCode = sql.NullInt64{Int64: int64(*entry.ExitCode), Valid: true}
+	}
+	res, err := tx.Exec(`INSERT INTO command_history (command, timestamp, user, target, exit_code) VALUES (?, ?, ?, ?, ?)`,
+		entry.Command, entry.Timestamp.UnixNano(), entry.User, entry.Target, exitCode)
+	if err != nil {
+		return err
+	}
+	if entry.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetCommandExitCode records the exit status of a command in history
+func (s *SQLiteStorage) SetCommandExitCode(id int64, code int) error {
+	res, err := s.db.Exec(`UPDATE command_history SET exit_code = ? WHERE id = ?`, code, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetCommandHistory returns the last N commands from history
+func (s *SQLiteStorage) GetCommandHistory(limit int) ([]CommandHistoryEntry, error) {
+	rows, err := s.db.Query(`SELECT `+historyColumns+` FROM (
+		SELECT `+historyColumns+` FROM command_history ORDER BY timestamp DESC, id DESC LIMIT ?
+	) ORDER BY timestamp, id`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanHistory(rows)
+}
+
+// SearchCommandHistory returns a page of matching commands, newest first
+func (s *SQLiteStorage) SearchCommandHistory(q HistoryQuery) (*HistoryPage, error) {
+	where := `WHERE (? = '' OR user = ?) AND (? = '' OR target = ?)`
+	args := []interface{}{q.User, q.User, q.Target, q.Target}
+	for _, term := range q.terms() {
+		where += ` AND instr(lower(command), ?) > 0`
+		args = append(args, term)
+	}
+
+	page := &HistoryPage{}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM command_history `+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT `+historyColumns+` FROM command_history `+where+`
+		ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?`, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if page.Entries, err = scanHistory(rows); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetLastCommand returns the most recent command from history
+func (s *SQLiteStorage) GetLastCommand() (string, error) {
+	var command string
+	err := s.db.QueryRow(`SELECT command FROM command_history ORDER BY timestamp DESC, id DESC LIMIT 1`).Scan(&command)
+	if errors.Is(err, sql.ErrNoRows) {
+		// No history yet
+		return "", nil
+	}
+	return command, err
+}
+
+// TrimCommandHistory keeps only the last maxCommands in history
+func (s *SQLiteStorage) TrimCommandHistory(maxCommands int) error {
+	_, err := s.db.Exec(`DELETE FROM command_history WHERE id NOT IN (
+		SELECT id FROM command_history ORDER BY timestamp DESC, id DESC LIMIT ?
+	)`, maxCommands)
+	return err
+}
+
+// Snapshot Methods
+
+// ExportSettings returns plugin configurations and plugin data as JSON.
+// Command history is not included. Encrypted values are exported as stored.
+func (s *SQLiteStorage) ExportSettings() ([]byte, error) {
+	snapshot := settingsSnapshot{
+		Plugins: make(map[string]json.RawMessage),
+		Data:    make(map[string]map[string][]byte),
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT name, enabled, title FROM plugin_config`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		var cfg PluginConfig
+		if err := rows.Scan(&name, &cfg.Enabled, &cfg.Name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to marshal plugin config: %w", err)
+		}
+		snapshot.Plugins[name] = data
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(`SELECT plugin, key, value, expires_at FROM plugin_data`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var plugin, key string
+		var value []byte
+		var expiresAt sql.NullInt64
+		if err := rows.Scan(&plugin, &key, &value, &expiresAt); err != nil {
+			return nil, err
+		}
+		if snapshot.Data[plugin] == nil {
+			snapshot.Data[plugin] = make(map[string][]byte)
+		}
+		snapshot.Data[plugin][key] = value
+
+		if expiresAt.Valid {
+			if snapshot.Expiry == nil {
+				snapshot.Expiry = make(map[string]map[string]int64)
+			}
+			if snapshot.Expiry[plugin] == nil {
+				snapshot.Expiry[plugin] = make(map[string]int64)
+			}
+			snapshot.Expiry[plugin][key] = expiresAt.Int64
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(snapshot)
+}
+
+// ImportSettings replaces plugin configurations and plugin data
+// with a snapshot created by ExportSettings.
+// Plaintext values in sensitive buckets are encrypted on import.
+func (s *SQLiteStorage) ImportSettings(data []byte) error {
+	var snapshot settingsSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to unmarshal settings snapshot: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM plugin_config; DELETE FROM plugin_data;`); err != nil {
+		return fmt.Errorf("failed to clear settings: %w", err)
+	}
+
+	for name, raw := range snapshot.Plugins {
+		var cfg PluginConfig
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return fmt.Errorf("failed to unmarshal plugin config: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO plugin_config (name, enabled, title) VALUES (?, ?, ?)`, name, cfg.Enabled, cfg.Name); err != nil {
+			return err
+		}
+	}
+
+	for plugin, values := range snapshot.Data {
+		for key, value := range values {
+			value, err := sealValue(s.cipher, s.sensitive[plugin], value)
+			if err != nil {
+				return err
+			}
+			if value == nil {
+				value = []byte{}
+			}
+			var expiresAt sql.NullInt64
+			if ts, ok := snapshot.Expiry[plugin][key]; ok {
+				expiresAt = sql.NullInt64{Int64: ts, Valid: true}
+			}
+			if _, err := tx.Exec(`INSERT INTO plugin_data (plugin, key, value, expires_at) VALUES (?, ?, ?, ?)`,
+				plugin, key, value, expiresAt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Backup writes a consistent snapshot of the database to w
+func (s *SQLiteStorage) Backup(w io.Writer) (int64, error) {
+	dir, err := os.MkdirTemp("", "podmanview-backup-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshotPath := filepath.Join(dir, "snapshot.sqlite")
+	if _, err := s.db.Exec(`VACUUM INTO ?`, snapshotPath); err != nil {
+		return 0, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return io.Copy(w, f)
+}
+
+// Maintenance Methods
+
+// sqliteTables maps tables to the expression summed for their size
+var sqliteTables = []struct {
+	name string
+	size string
+}{
+	{"plugin_config", "length(name) + length(title)"},
+	{"plugin_data", "length(plugin) + length(key) + length(value)"},
+	{"command_history", "length(command) + 8"},
+}
+
+// Stats returns the database file size and space used per table
+func (s *SQLiteStorage) Stats() (*Stats, error) {
+	var pageCount, pageSize int64
+	if err := s.db.QueryRow(`PRAGMA page_count`).Scan(&pageCount); err != nil {
+		return nil, err
+	}
+	if err := s.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{Backend: BackendSQLite, FileSize: pageCount * pageSize}
+	for _, t := range sqliteTables {
+		b := BucketStats{Name: t.name}
+		query := fmt.Sprintf(`SELECT count(*), coalesce(sum(%s), 0) FROM %s`, t.size, t.name)
+		if err := s.db.QueryRow(query).Scan(&b.Keys, &b.Size); err != nil {
+			return nil, err
+		}
+		stats.Buckets = append(stats.Buckets, b)
+	}
+
+	return stats, nil
+}
+
+// Check runs SQLite's integrity check.
+// Returns nil if the database is intact.
+func (s *SQLiteStorage) Check() error {
+	// PRAGMA arguments can't be bound as parameters
+	rows, err := s.db.Query(fmt.Sprintf(`PRAGMA integrity_check(%d)`, maxCheckErrors))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var errs []error
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return err
+		}
+		if msg != "ok" {
+			errs = append(errs, errors.New(msg))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// Compact rebuilds the database file without free pages (VACUUM)
+// and returns the file size before and after
+func (s *SQLiteStorage) Compact() (int64, int64, error) {
+	before, err := s.Stats()
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		return before.FileSize, before.FileSize, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	// Fold the write-ahead log back into the main file
+	if _, err := s.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return before.FileSize, before.FileSize, err
+	}
+
+	after, err := s.Stats()
+	if err != nil {
+		return before.FileSize, before.FileSize, err
+	}
+	return before.FileSize, after.FileSize, nil
+}
+
+// Close closes the storage
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
@@ -2,7 +2,18 @@ package storage
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"time"
+
+	"podmanview/internal/secrets"
+)
+
+// Storage backends
+const (
+	BackendBolt   = "bolt"   // bbolt key-value file (default)
+	BackendSQLite = "sqlite" // SQLite database, readable with standard tools
 )
 
 var (
@@ -19,12 +30,73 @@ type PluginConfig struct {
 	Name    string `json:"name"`
 }
 
+// HostTarget is the target of commands run in the host terminal
+const HostTarget = "host"
+
 // CommandHistoryEntry represents a single command in history
 type CommandHistoryEntry struct {
+	ID        int64     `json:"id"`
 	Command   string    `json:"command"`
 	Timestamp time.Time `json:"timestamp"`
+	User      string    `json:"user,omitempty"`
+	Target    string    `json:"target"`             // HostTarget or container ID
+	ExitCode  *int      `json:"exitCode,omitempty"` // nil if not reported by the shell
+}
+
+// HistoryQuery selects command history entries for SearchCommandHistory
+type HistoryQuery struct {
+	Search string // words that must all appear in the command, case-insensitive
+	User   string // empty = all users
+	Target string // empty = all targets
+	Offset int
+	Limit  int
+}
+
+// HistoryPage is a page of command history, newest first
+type HistoryPage struct {
+	Entries []CommandHistoryEntry `json:"entries"`
+	Total   int                   `json:"total"` // entries matching the query on all pages
+}
+
+// terms returns the lowercased search words
+func (q *HistoryQuery) terms() []string {
+	return strings.Fields(strings.ToLower(q.Search))
+}
+
+// matches reports whether an entry matches the query filters and search terms
+func (q *HistoryQuery) matches(entry *CommandHistoryEntry, terms []string) bool {
+	if q.User != "" && entry.User != q.User {
+		return false
+	}
+	if q.Target != "" && entry.Target != q.Target {
+		return false
+	}
+	command := strings.ToLower(entry.Command)
+	for _, term := range terms {
+		if !strings.Contains(command, term) {
+			return false
+		}
+	}
+	return true
 }
 
+// BucketStats describes the space used by a bucket (bolt) or table (sqlite)
+type BucketStats struct {
+	Name string `json:"name"`
+	Keys int    `json:"keys"`
+	Size int64  `json:"size"` // bytes used by keys and values
+}
+
+// Stats describes the size of the database
+type Stats struct {
+	Backend  string        `json:"backend"`
+	FileSize int64         `json:"fileSize"`
+	Buckets  []BucketStats `json:"buckets"`
+}
+
+// maxCheckErrors limits the number of errors reported by Check
+const maxCheckErrors = 20
+
 // Storage is the interface for plugin configuration and data storage
 type Storage interface {
 	// Plugin Configuration Methods
@@ -80,18 +152,52 @@ type Storage interface {
 	// SetJSON marshals and stores JSON data for a plugin by key
 	SetJSON(pluginName, key string, v interface{}) error
 
+	// GetSecretJSON retrieves JSON data stored with SetSecretJSON, decrypting it if needed
+	GetSecretJSON(pluginName, key string, v interface{}) error
+
+	// SetSecretJSON stores JSON data containing secrets (passwords, tokens, keys),
+	// encrypted when secret encryption is enabled
+	SetSecretJSON(pluginName, key string, v interface{}) error
+
+	// SetWithTTL stores data for a plugin by key that expires after ttl.
+	// Expired keys are reported as ErrNotFound and removed by DeleteExpired.
+	// A ttl <= 0 stores the value without expiry, like Set.
+	SetWithTTL(pluginName, key string, value []byte, ttl time.Duration) error
+
+	// SetJSONWithTTL marshals and stores JSON data for a plugin by key that expires after ttl
+	SetJSONWithTTL(pluginName, key string, v interface{}, ttl time.Duration) error
+
 	// Delete removes data for a plugin by key
 	Delete(pluginName, key string) error
 
 	// List returns all keys and values for a plugin
 	List(pluginName string) (map[string][]byte, error)
 
+	// ListPrefix returns the keys and values for a plugin whose key starts with prefix
+	ListPrefix(pluginName, prefix string) (map[string][]byte, error)
+
+	// DeleteExpired removes keys whose TTL has passed and returns their number
+	DeleteExpired() (int, error)
+
 	// Command History Methods
 
-	// SaveCommandHistory saves a command to history
+	// SaveCommandHistory saves a host terminal command to history
 	// Automatically prevents duplicate consecutive commands
 	SaveCommandHistory(command string, timestamp time.Time) error
 
+	// AddCommandHistory saves a command with its user and target to history.
+	// A repeat of the previous command by the same user on the same target
+	// is not stored again. entry.ID is set to the ID of the stored entry
+	// (or of the previous one for a repeat).
+	AddCommandHistory(entry *CommandHistoryEntry) error
+
+	// SetCommandExitCode records the exit status of a command in history
+	// Returns ErrNotFound if the entry doesn't exist (e.g. trimmed)
+	SetCommandExitCode(id int64, code int) error
+
+	// SearchCommandHistory returns a page of matching commands, newest first
+	SearchCommandHistory(q HistoryQuery) (*HistoryPage, error)
+
 	// GetCommandHistory returns the last N commands from history
 	// Returns up to limit commands, ordered from oldest to newest
 	GetCommandHistory(limit int) ([]CommandHistoryEntry, error)
@@ -104,8 +210,131 @@ type Storage interface {
 	// Older commands are automatically removed
 	TrimCommandHistory(maxCommands int) error
 
+	// Snapshot Methods
+
+	// ExportSettings returns plugin configurations and plugin data as JSON
+	ExportSettings() ([]byte, error)
+
+	// ImportSettings replaces plugin configurations and plugin data
+	// with a snapshot created by ExportSettings
+	ImportSettings(data []byte) error
+
+	// Backup writes a consistent snapshot of the database to w
+	Backup(w io.Writer) (int64, error)
+
+	// Maintenance Methods
+
+	// Stats returns the database file size and space used per bucket (or table)
+	Stats() (*Stats, error)
+
+	// Check verifies database integrity, returns nil if the database is intact
+	Check() error
+
+	// Compact reclaims free space and returns the file size before and after
+	Compact() (int64, int64, error)
+
 	// Lifecycle Methods
 
 	// Close closes the storage
 	Close() error
 }
+
+// Backend is a Storage kept in a local database file
+type Backend interface {
+	Storage
+
+	// SetCipher sets the cipher used by SetSecretJSON and GetSecretJSON
+	// and for values in sensitive buckets
+	SetCipher(c *secrets.Cipher)
+
+	// SetSensitiveBuckets replaces the plugin data buckets encrypted as a whole
+	// (default: DefaultSensitiveBuckets)
+	SetSensitiveBuckets(names ...string)
+
+	// EncryptSensitive encrypts plaintext values left in sensitive buckets
+	EncryptSensitive() (int, error)
+}
+
+// Open opens the storage backend with the given database file
+func Open(backend, path string) (Backend, error) {
+	switch backend {
+	case BackendBolt:
+		return NewBoltStorage(path)
+	case BackendSQLite:
+		return NewSQLiteStorage(path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s (expected %s or %s)", backend, BackendBolt, BackendSQLite)
+	}
+}
+
+// Migrate copies plugin configurations, plugin data and command history
+// from one storage to another, replacing data already in dst
+func Migrate(src, dst Storage) error {
+	settings, err := src.ExportSettings()
+	if err != nil {
+		return fmt.Errorf("failed to export settings: %w", err)
+	}
+	if err := dst.ImportSettings(settings); err != nil {
+		return fmt.Errorf("failed to import settings: %w", err)
+	}
+
+	history, err := src.GetCommandHistory(maxMigratedHistory)
+	if err != nil {
+		return fmt.Errorf("failed to read command history: %w", err)
+	}
+	if err := dst.TrimCommandHistory(0); err != nil {
+		return fmt.Errorf("failed to clear command history: %w", err)
+	}
+	for _, entry := range history {
+		if err := dst.AddCommandHistory(&entry); err != nil {
+			return fmt.Errorf("failed to copy command history: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// maxMigratedHistory limits the command history copied by Migrate
+const maxMigratedHistory = 10000
+
+// MaintenanceResult reports the outcome of Maintain
+type MaintenanceResult struct {
+	Errors     []string  `json:"errors,omitempty"` // integrity problems, compaction is skipped if any
+	Expired    int       `json:"expired"`          // expired keys removed
+	Compacted  bool      `json:"compacted"`
+	SizeBefore int64     `json:"sizeBefore"`
+	SizeAfter  int64     `json:"sizeAfter"`
+	Time       time.Time `json:"time"`
+}
+
+// Maintain removes expired keys, verifies database integrity and, if the
+// database is intact, compacts it to reclaim space freed by deleted data
+func Maintain(s Storage) (*MaintenanceResult, error) {
+	result := &MaintenanceResult{Time: time.Now()}
+
+	if err := s.Check(); err != nil {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				result.Errors = append(result.Errors, e.Error())
+			}
+		} else {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		return result, nil
+	}
+
+	expired, err := s.DeleteExpired()
+	if err != nil {
+		return result, fmt.Errorf("failed to delete expired keys: %w", err)
+	}
+	result.Expired = expired
+
+	before, after, err := s.Compact()
+	result.SizeBefore, result.SizeAfter = before, after
+	if err != nil {
+		return result, err
+	}
+	result.Compacted = true
+
+	return result, nil
+}
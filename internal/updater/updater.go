@@ -409,9 +409,7 @@ func (u *Updater) SetLogger(l *logger.Logger) {
 	u.logger = l
 }
 
-// logf logs a formatted message if logger is set
+// logf logs a formatted message, discarded without logger
 func (u *Updater) logf(format string, v ...interface{}) {
-	if u.logger != nil {
-		u.logger.Printf("[Updater] "+format, v...)
-	}
+	u.logger.Printf(format, v...)
 }
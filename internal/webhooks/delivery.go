@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"podmanview/internal/events"
+)
+
+// Delivery request headers
+const (
+	EventHeader     = "X-PodmanView-Event"     // event type
+	DeliveryHeader  = "X-PodmanView-Delivery"  // event ID, the same for all attempts
+	SignatureHeader = "X-PodmanView-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+)
+
+const (
+	// deliveryTimeout limits a single delivery attempt
+	deliveryTimeout = 10 * time.Second
+
+	// maxConcurrentDeliveries limits deliveries in flight, including retry waits
+	maxConcurrentDeliveries = 8
+
+	// subscribeBuffer is the number of events queued for delivery
+	subscribeBuffer = 256
+
+	// EventTest is the type of the event sent by Test
+	EventTest events.EventType = "webhook_test"
+)
+
+// sender posts events to webhook URLs
+type sender struct {
+	client *http.Client
+	slots  chan struct{}
+}
+
+// newSender creates a sender
+func newSender() *sender {
+	return &sender{
+		client: &http.Client{Timeout: deliveryTimeout},
+		slots:  make(chan struct{}, maxConcurrentDeliveries),
+	}
+}
+
+// Sign returns the signature header value for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run delivers events added to store to the matching webhooks
+// until ctx is cancelled
+func (m *Manager) Run(ctx context.Context, store *events.Store) {
+	ch, unsubscribe := store.Subscribe(subscribeBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-ch:
+			for _, hook := range m.matching(event.Type) {
+				go func(hook Webhook) {
+					status := m.deliver(ctx, &hook, event)
+					m.setStatus(hook.ID, status)
+					if !status.Success && ctx.Err() == nil {
+						m.logger.Warn("Webhook delivery failed", "webhook", hook.Name, "event_type", event.Type,
+							"event_id", event.ID, "attempts", status.Attempts, "error", status.Error)
+					}
+				}(hook)
+			}
+		}
+	}
+}
+
+// DeliverTo delivers an event to a webhook regardless of its event filter,
+// with the webhook's retry policy
+func (m *Manager) DeliverTo(ctx context.Context, id string, event events.Event) error {
+	hook, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	status := m.deliver(ctx, hook, event)
+	m.setStatus(id, status)
+	if !status.Success {
+		return fmt.Errorf("delivery failed after %d attempts: %s", status.Attempts, status.Error)
+	}
+	return nil
+}
+
+// Test sends a test event to a webhook once, without retries
+func (m *Manager) Test(ctx context.Context, id, username string) (*DeliveryStatus, error) {
+	hook, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	hook.MaxRetries = 0
+
+	status := m.deliver(ctx, hook, events.Event{
+		Type:      EventTest,
+		Timestamp: time.Now(),
+		Username:  username,
+		Success:   true,
+		Details:   "Test delivery from PodmanView",
+	})
+	m.setStatus(id, status)
+	return status, nil
+}
+
+// deliver posts an event to a webhook, retrying with exponential backoff
+func (m *Manager) deliver(ctx context.Context, hook *Webhook, event events.Event) *DeliveryStatus {
+	status := &DeliveryStatus{EventType: event.Type}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		status.Time = time.Now()
+		status.Error = err.Error()
+		return status
+	}
+
+	select {
+	case m.sender.slots <- struct{}{}:
+		defer func() { <-m.sender.slots }()
+	case <-ctx.Done():
+		status.Time = time.Now()
+		status.Error = ctx.Err().Error()
+		return status
+	}
+
+	delay := time.Duration(hook.RetryDelay) * time.Second
+	for attempt := 0; attempt <= hook.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				status.Error = ctx.Err().Error()
+				return status
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		status.Attempts = attempt + 1
+		status.Time = time.Now()
+		code, retry, err := m.sender.post(ctx, hook, event, body)
+		status.StatusCode = code
+		if err == nil {
+			status.Success = true
+			status.Error = ""
+			return status
+		}
+		status.Error = err.Error()
+		if !retry {
+			return status
+		}
+	}
+	return status
+}
+
+// post makes a single delivery attempt. Returns the response status code
+// and whether a failure is worth retrying (network errors, 429 and 5xx).
+func (s *sender) post(ctx context.Context, hook *Webhook, event events.Event, body []byte) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PodmanView-Webhook")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(event.ID, 10))
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return resp.StatusCode, retry, fmt.Errorf("endpoint returned %s", resp.Status)
+}
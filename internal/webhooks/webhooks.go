@@ -0,0 +1,261 @@
+// Package webhooks delivers events to user-registered HTTP endpoints
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+	"podmanview/internal/storage"
+)
+
+// bucket is the storage namespace of webhooks (encrypted at rest, see storage.DefaultSensitiveBuckets)
+const bucket = "webhooks"
+
+const (
+	// DefaultMaxRetries is the number of retries after a failed delivery
+	DefaultMaxRetries = 3
+
+	// DefaultRetryDelay is the delay in seconds before the first retry
+	DefaultRetryDelay = 10
+
+	// maxRetries and maxRetryDelay limit the retry policy of a webhook
+	maxRetries    = 10
+	maxRetryDelay = 3600
+)
+
+var (
+	// ErrNotFound is returned for an unknown webhook ID
+	ErrNotFound = errors.New("webhook not found")
+
+	// ErrInvalid is returned by Validate for an invalid webhook
+	ErrInvalid = errors.New("invalid webhook")
+)
+
+// Webhook is a registered endpoint that receives matching events
+type Webhook struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`           // event type prefixes, e.g. "container_", empty = all events
+	Secret  string   `json:"secret,omitempty"` // HMAC-SHA256 signing key, empty = unsigned
+	Enabled bool     `json:"enabled"`
+
+	// Retry policy: a failed delivery is retried MaxRetries times,
+	// first after RetryDelay seconds, doubling the delay each time
+	MaxRetries int `json:"maxRetries"`
+	RetryDelay int `json:"retryDelay"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Matches reports whether the webhook receives events of type t
+func (w *Webhook) Matches(t events.EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, prefix := range w.Events {
+		if strings.HasPrefix(string(t), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the webhook and fills in the default retry policy
+func (w *Webhook) Validate() error {
+	w.Name = strings.TrimSpace(w.Name)
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: URL %q, expected http(s)://host/path", ErrInvalid, w.URL)
+	}
+	if w.Name == "" {
+		w.Name = u.Host
+	}
+
+	if w.MaxRetries < 0 || w.MaxRetries > maxRetries {
+		return fmt.Errorf("%w: maxRetries must be between 0 and %d", ErrInvalid, maxRetries)
+	}
+	if w.RetryDelay < 0 || w.RetryDelay > maxRetryDelay {
+		return fmt.Errorf("%w: retryDelay must be between 0 and %d seconds", ErrInvalid, maxRetryDelay)
+	}
+	if w.RetryDelay == 0 {
+		w.RetryDelay = DefaultRetryDelay
+	}
+
+	filters := w.Events[:0]
+	for _, prefix := range w.Events {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			filters = append(filters, prefix)
+		}
+	}
+	w.Events = filters
+	return nil
+}
+
+// DeliveryStatus is the result of the last delivery to a webhook
+type DeliveryStatus struct {
+	Time       time.Time        `json:"time"`
+	EventType  events.EventType `json:"eventType"`
+	Attempts   int              `json:"attempts"`
+	StatusCode int              `json:"statusCode,omitempty"`
+	Success    bool             `json:"success"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// Manager stores webhooks and delivers events to them
+type Manager struct {
+	storage storage.Storage
+	logger  *logger.Logger
+	sender  *sender
+
+	mu     sync.RWMutex
+	hooks  map[string]*Webhook
+	status map[string]*DeliveryStatus
+}
+
+// NewManager creates a webhook manager and loads the registered webhooks
+func NewManager(store storage.Storage, appLogger *logger.Logger) (*Manager, error) {
+	m := &Manager{
+		storage: store,
+		logger:  appLogger,
+		sender:  newSender(),
+		hooks:   make(map[string]*Webhook),
+		status:  make(map[string]*DeliveryStatus),
+	}
+
+	data, err := store.List(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	for id, value := range data {
+		var hook Webhook
+		if err := json.Unmarshal(value, &hook); err != nil {
+			return nil, fmt.Errorf("failed to load webhook %s: %w", id, err)
+		}
+		m.hooks[id] = &hook
+	}
+
+	return m, nil
+}
+
+// List returns all webhooks, oldest first
+func (m *Manager) List() []Webhook {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Webhook, 0, len(m.hooks))
+	for _, hook := range m.hooks {
+		list = append(list, *hook)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Get returns a webhook by ID
+func (m *Manager) Get(id string) (*Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hook, ok := m.hooks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *hook
+	return &copied, nil
+}
+
+// Save validates and stores a webhook. A webhook without ID is created.
+func (m *Manager) Save(hook *Webhook) error {
+	if err := hook.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hook.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		hook.ID = id
+		hook.CreatedAt = time.Now()
+	} else if _, ok := m.hooks[hook.ID]; !ok {
+		return ErrNotFound
+	}
+
+	if err := m.storage.SetJSON(bucket, hook.ID, hook); err != nil {
+		return fmt.Errorf("failed to save webhook: %w", err)
+	}
+	copied := *hook
+	m.hooks[hook.ID] = &copied
+	return nil
+}
+
+// Delete removes a webhook
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.hooks[id]; !ok {
+		return ErrNotFound
+	}
+	if err := m.storage.Delete(bucket, id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	delete(m.hooks, id)
+	delete(m.status, id)
+	return nil
+}
+
+// Status returns the result of the last delivery to a webhook, nil if none
+func (m *Manager) Status(id string) *DeliveryStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status[id]
+}
+
+// setStatus records the result of a delivery
+func (m *Manager) setStatus(id string, status *DeliveryStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.hooks[id]; ok {
+		m.status[id] = status
+	}
+}
+
+// matching returns the enabled webhooks that receive events of type t
+func (m *Manager) matching(t events.EventType) []Webhook {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hooks []Webhook
+	for _, hook := range m.hooks {
+		if hook.Enabled && hook.Matches(t) {
+			hooks = append(hooks, *hook)
+		}
+	}
+	return hooks
+}
+
+// newID generates a random webhook ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
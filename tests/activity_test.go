@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+func TestUserActivity(t *testing.T) {
+	var lookups atomic.Int32
+	geo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		if r.URL.Path != "/203.0.113.5/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"ip": "203.0.113.5", "city": "Berlin", "country": "DE", "country_name": "Germany"}`))
+	}))
+	defer geo.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "aaaaaaaaaaaa1111", "Names": ["web"], "State": "running"},
+			{"Id": "bbbbbbbbbbbb2222", "Names": ["db"], "State": "running"}
+		]`))
+	})
+
+	cfg := newTestConfig(t, "PODMANVIEW_GEOIP_URL="+geo.URL+"/{ip}/json\n")
+	store := events.NewStore(50)
+	store.Add(events.EventLogin, "alice", "203.0.113.5", true, "")
+	store.Add(events.EventContainerStart, "alice", "203.0.113.5", true, "aaaaaaaaaaaa")
+	store.Add(events.EventContainerStop, "alice", "203.0.113.5", true, "aaaaaaaaaaaa")
+	store.Add(events.EventContainerTags, "alice", "203.0.113.5", true, "web: prod")
+	store.Add(events.EventContainerRestart, "alice", "203.0.113.5", false, "db: timeout")
+	store.Add(events.EventContainerRemove, "alice", "203.0.113.5", true, "cccccccccccc")
+	store.Add(events.EventLoginFailed, "bob", "192.168.1.20", false, "")
+	store.Add(events.EventLogin, "bob", "127.0.0.1", true, "")
+	store.Add(events.EventContainerDied, "system", "", false, "web: exit code 1")
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withConfig(cfg), withEvents(store))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/events/activity"+path, nil))
+		return rec
+	}
+
+	rec := get("?days=7")
+	var result struct {
+		Days  int                `json:"days"`
+		Users []api.UserActivity `json:"users"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if len(result.Users) != 2 || result.Users[0].Username != "alice" || result.Users[1].Username != "bob" {
+		t.Fatalf("Expected alice and bob, most active first, got %+v", result.Users)
+	}
+
+	alice := result.Users[0]
+	if alice.Actions != 6 || alice.Failed != 1 {
+		t.Errorf("Expected 6 actions with 1 failed, got %d and %d", alice.Actions, alice.Failed)
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+	if len(alice.PerDay) != 7 || alice.PerDay[6].Date != today || alice.PerDay[6].Actions != 6 || alice.PerDay[0].Actions != 0 {
+		t.Errorf("Expected 7 days ending today, got %+v", alice.PerDay)
+	}
+	// IDs and names of the same container are counted together
+	var top []string
+	for _, c := range alice.TopContainers {
+		top = append(top, fmt.Sprintf("%s=%s:%d", c.Name, c.ID, c.Actions))
+	}
+	if got := strings.Join(top, ", "); got != "web=aaaaaaaaaaaa:3, cccccccccccc=:1, db=bbbbbbbbbbbb:1" {
+		t.Errorf("Unexpected top containers %q", got)
+	}
+	if len(alice.RecentLogins) != 1 || alice.RecentLogins[0].Location != "Berlin, Germany" || !alice.RecentLogins[0].Success {
+		t.Errorf("Expected a login from Berlin, got %+v", alice.RecentLogins)
+	}
+
+	bob := result.Users[1]
+	if len(bob.RecentLogins) != 2 || bob.RecentLogins[0].Location != "Local" || bob.RecentLogins[1].Location != "Private network" || bob.RecentLogins[1].Success {
+		t.Errorf("Expected the local login before the failed one, got %+v", bob.RecentLogins)
+	}
+
+	// Locations are cached
+	get("")
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("Expected 1 lookup, got %d", n)
+	}
+
+	for _, days := range []string{"0", "366", "abc"} {
+		if rec := get("?days=" + days); rec.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: expected 400, got %d", days, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/alerts"
+	"podmanview/internal/events"
+	"podmanview/internal/storage"
+)
+
+// recordingActions records the actions run by fired rules
+type recordingActions struct {
+	restarted chan string
+}
+
+func (a *recordingActions) Notify(ctx context.Context, channelID string, event events.Event) error {
+	return nil
+}
+
+func (a *recordingActions) Webhook(ctx context.Context, webhookID string, event events.Event) error {
+	return nil
+}
+
+func (a *recordingActions) RestartContainer(ctx context.Context, nameOrID string) error {
+	a.restarted <- nameOrID
+	return nil
+}
+
+func TestAlertEventRule(t *testing.T) {
+	store, err := storage.Open(storage.BackendBolt, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+
+	eventStore := events.NewStore(100)
+	actions := &recordingActions{restarted: make(chan string, 1)}
+	engine, err := alerts.NewEngine(store, eventStore, nil, actions, nil)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	if err := engine.Save(&alerts.Rule{Name: "bad", Condition: alerts.Condition{Kind: "metric", Metric: "gpu", Operator: ">"}}); err == nil {
+		t.Error("Expected an unknown metric to be rejected")
+	}
+
+	rule := &alerts.Rule{
+		Name:    "nginx flapping",
+		Enabled: true,
+		Condition: alerts.Condition{
+			Kind:      alerts.ConditionEvent,
+			EventType: "podman_container_restart",
+			Match:     "nginx",
+			Window:    600,
+			Operator:  ">",
+			Threshold: 3,
+		},
+		Actions: []alerts.Action{{Type: alerts.ActionRestart, Target: "proxy"}},
+	}
+	if err := engine.Save(rule); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if rule.Severity != events.SeverityWarning {
+		t.Errorf("Expected default severity warning, got %q", rule.Severity)
+	}
+
+	ctx := context.Background()
+	restart := events.Event{Type: "podman_container_restart", Details: "nginx"}
+	for i := 0; i < 3; i++ {
+		engine.Observe(ctx, restart)
+	}
+	engine.Observe(ctx, events.Event{Type: "podman_container_restart", Details: "redis"})
+	if fired, _ := eventStore.Query(events.Query{TypePrefix: "alert_", Limit: 10}); len(fired) != 0 {
+		t.Fatalf("Expected no alert after 3 restarts, got %+v", fired)
+	}
+
+	engine.Observe(ctx, restart)
+	fired, _ := eventStore.Query(events.Query{TypePrefix: string(events.EventAlertFired), Limit: 10})
+	if len(fired) != 1 || !strings.Contains(fired[0].Details, "nginx flapping") {
+		t.Fatalf("Expected one alert_fired event, got %+v", fired)
+	}
+
+	select {
+	case target := <-actions.restarted:
+		if target != "proxy" {
+			t.Errorf("Expected proxy to be restarted, got %q", target)
+		}
+	case <-time.After(time.Second):
+		t.Error("Restart action was not run")
+	}
+
+	// A firing rule doesn't fire again
+	engine.Observe(ctx, restart)
+	if fired, _ := eventStore.Query(events.Query{TypePrefix: string(events.EventAlertFired), Limit: 10}); len(fired) != 1 {
+		t.Errorf("Expected the rule to fire once, got %d events", len(fired))
+	}
+	states := engine.States()
+	if len(states) != 1 || !states[0].Firing || states[0].Value != 5 {
+		t.Errorf("Unexpected states %+v", states)
+	}
+
+	// Rules are persisted
+	reloaded, err := alerts.NewEngine(store, eventStore, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	if got, err := reloaded.Get(rule.ID); err != nil || got.Condition.Match != "nginx" {
+		t.Errorf("Expected the rule to be reloaded, got %+v, %v", got, err)
+	}
+}
+
+func TestAlertSilence(t *testing.T) {
+	until := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	rule := alerts.Rule{
+		Silences: []alerts.Silence{
+			{Until: &until},
+			{DailyFrom: "22:00", DailyUntil: "06:00"},
+		},
+	}
+
+	tests := []struct {
+		at       time.Time
+		silenced bool
+	}{
+		{time.Date(2026, 1, 1, 11, 0, 0, 0, time.Local), true},  // before until
+		{time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local), false}, // daytime
+		{time.Date(2026, 1, 2, 23, 30, 0, 0, time.Local), true}, // nightly window
+		{time.Date(2026, 1, 3, 5, 59, 0, 0, time.Local), true},  // past midnight
+		{time.Date(2026, 1, 3, 6, 0, 0, 0, time.Local), false},  // window end
+	}
+	for _, tt := range tests {
+		if got := rule.Silenced(tt.at); got != tt.silenced {
+			t.Errorf("Silenced(%s) = %v, want %v", tt.at.Format(time.Kitchen), got, tt.silenced)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestSystemAllocations(t *testing.T) {
+	var inspected []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "web-id", "Names": ["web"], "State": "running"},
+			{"Id": "db-id", "Names": ["db"], "State": "running"},
+			{"Id": "job-id", "Names": ["job"], "State": "exited"},
+			{"Id": "infra-id", "Names": ["infra"], "State": "running", "IsInfra": true}
+		]`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		inspected = append(inspected, r.PathValue("id"))
+		switch r.PathValue("id") {
+		case "web-id":
+			// --cpus reported as a quota
+			w.Write([]byte(`{"Id": "web-id", "HostConfig": {"Memory": 268435456, "CpuQuota": 50000, "CpuPeriod": 100000}}`))
+		case "db-id":
+			// More CPUs and memory than any host has
+			w.Write([]byte(`{"Id": "db-id", "HostConfig": {"Memory": 1125899906842624, "NanoCpus": 100000000000000}}`))
+		}
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/allocations", nil))
+	var result api.Allocations
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected allocations, got %d %q", rec.Code, rec.Body)
+	}
+
+	if len(inspected) != 2 {
+		t.Errorf("Expected only running containers to be inspected, got %v", inspected)
+	}
+	if len(result.Containers) != 2 || result.Containers[0].Name != "db" || result.Containers[1] != (api.ContainerAllocation{ID: "web-id", Name: "web", Memory: 256 << 20, CPUs: 0.5}) {
+		t.Errorf("Unexpected containers %+v", result.Containers)
+	}
+	if result.CPU.Capacity != float64(runtime.NumCPU()) || result.CPU.Allocated != 100000.5 || !result.CPU.Overcommitted || result.CPU.Unlimited != 0 {
+		t.Errorf("Unexpected CPU allocation %+v", result.CPU)
+	}
+	if result.Memory.Capacity <= 0 || result.Memory.Allocated != float64(1<<50+256<<20) || !result.Memory.Overcommitted || result.Memory.Percent <= 100 {
+		t.Errorf("Unexpected memory allocation %+v", result.Memory)
+	}
+}
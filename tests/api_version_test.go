@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/config"
+	"podmanview/internal/podman"
+)
+
+func TestAPIVersioning(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	server := newTestServer(t, nil, withConfig(cfg))
+
+	// Versioned paths are served by the same routes, without deprecation headers
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for /api/v1/health, got %d", rec.Code)
+	}
+	for _, header := range []string{"Deprecation", "Sunset", "Link"} {
+		if v := rec.Header().Get(header); v != "" {
+			t.Errorf("Unexpected %s header on versioned path: %s", header, v)
+		}
+	}
+
+	// Unversioned paths still work and point to their successor
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for /api/health, got %d", rec.Code)
+	}
+	if v := rec.Header().Get("Deprecation"); !strings.HasPrefix(v, "@") {
+		t.Errorf("Expected Deprecation date, got %q", v)
+	}
+	if v := rec.Header().Get("Sunset"); !strings.HasSuffix(v, "GMT") {
+		t.Errorf("Expected Sunset HTTP date, got %q", v)
+	}
+	if v := rec.Header().Get("Link"); v != `</api/v1/health>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header: %q", v)
+	}
+
+	// Escaped slashes stay in their path parameter on both paths
+	mux := http.NewServeMux()
+	var inspected []string
+	mux.HandleFunc("GET /v4.0.0/libpod/images/{name}/json", func(w http.ResponseWriter, r *http.Request) {
+		inspected = append(inspected, r.PathValue("name"))
+		w.Write([]byte(`{"Id": "abc"}`))
+	})
+	server = newTestServer(t, podman.NewClientWithHandler(mux))
+	for _, path := range []string{"/api/images/docker.io%2Falpine", "/api/v1/images/docker.io%2Falpine"} {
+		rec = httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d %s", path, rec.Code, rec.Body)
+		}
+	}
+	if len(inspected) != 2 || inspected[0] != "docker.io/alpine" || inspected[1] != inspected[0] {
+		t.Errorf("Expected the same image on both paths, got %q", inspected)
+	}
+
+	// Non-API paths are not versioned
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if v := rec.Header().Get("Deprecation"); v != "" {
+		t.Errorf("Unexpected Deprecation header on /login: %s", v)
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/config"
+	"podmanview/internal/podman"
+)
+
+func TestAPIErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{apierror.New(http.StatusConflict, "Busy"), http.StatusConflict},
+		{fmt.Errorf("open: %w", os.ErrNotExist), http.StatusNotFound},
+		{fmt.Errorf("mkdir: %w", os.ErrExist), http.StatusConflict},
+		{os.ErrPermission, http.StatusForbidden},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{&http.MaxBytesError{Limit: 10}, http.StatusRequestEntityTooLarge},
+		{fmt.Errorf("inspect: %w", &podman.APIError{StatusCode: 404, Body: "no such container"}), http.StatusNotFound},
+		{&podman.APIError{StatusCode: 500}, http.StatusBadGateway},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := apierror.Status(tt.err); got != tt.status {
+			t.Errorf("Status(%v) = %d, expected %d", tt.err, got, tt.status)
+		}
+	}
+}
+
+func TestAPIErrorResponse(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/containers/abc", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "host/req-000001"))
+
+	rec := httptest.NewRecorder()
+	apierror.WriteErr(rec, req, &podman.APIError{StatusCode: 404, Body: "no such container"}, "Failed to inspect container")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got %d", rec.Code)
+	}
+	var resp apierror.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if resp.Code != apierror.CodeNotFound || resp.RequestID != "host/req-000001" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if resp.Message != "Failed to inspect container: API error 404: no such container" || resp.Error != resp.Message {
+		t.Errorf("Unexpected message: %q / %q", resp.Message, resp.Error)
+	}
+
+	// *Error values keep their code and details, the wrapped error is not sent
+	rec = httptest.NewRecorder()
+	cause := errors.New("secret path")
+	apierror.WriteErr(rec, req, apierror.Wrap(cause, http.StatusConflict, "Container is running").
+		WithCode("container_running").WithDetails(map[string]string{"state": "running"}), "")
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if rec.Code != http.StatusConflict || body["code"] != "container_running" || body["message"] != "Container is running" {
+		t.Errorf("Unexpected response %d: %v", rec.Code, body)
+	}
+	if details, ok := body["details"].(map[string]interface{}); !ok || details["state"] != "running" {
+		t.Errorf("Expected details, got %v", body["details"])
+	}
+}
+
+func TestAPIErrorRoutes(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	server := newTestServer(t, nil, withConfig(cfg))
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/api/v1/containers", http.StatusUnauthorized, apierror.CodeUnauthorized},
+		{http.MethodGet, "/api/v1/unknown", http.StatusNotFound, apierror.CodeNotFound},
+		{http.MethodDelete, "/api/v1/health", http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		var resp apierror.Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s %s: invalid JSON %q: %v", tt.method, tt.path, rec.Body, err)
+			continue
+		}
+		if rec.Code != tt.status || resp.Code != tt.code || resp.RequestID == "" {
+			t.Errorf("%s %s: unexpected response %d %+v", tt.method, tt.path, rec.Code, resp)
+		}
+	}
+}
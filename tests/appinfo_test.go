@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestContainerAppInfo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "1", "Names": ["jellyfin"], "Image": "docker.io/jellyfin/jellyfin:10.9"},
+			{"Id": "2", "Names": ["sonarr"], "Image": "ghcr.io/linuxserver/sonarr@sha256:abc", "Labels": {
+				"homepage.name": "TV Shows", "homepage.href": "http://nas:8989", "org.opencontainers.image.url": "https://example.com"}},
+			{"Id": "3", "Names": ["wiki"], "Image": "localhost:5000/wiki:latest", "Labels": {
+				"homepage.icon": "https://wiki.lan/favicon.svg", "org.opencontainers.image.title": "Team Wiki", "org.opencontainers.image.description": "Docs"}},
+			{"Id": "4", "Names": ["blog"], "Image": "blog", "Labels": {"homepage.icon": "ghost.svg"}},
+			{"Id": "5", "Names": ["custom"], "Image": "localhost/custom:1"}
+		]`))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/containers", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("List: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var list []api.ContainerWithStats
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	apps := make(map[string]*api.AppInfo)
+	for _, c := range list {
+		apps[c.Names[0]] = c.App
+	}
+
+	for name, expected := range map[string]api.AppInfo{
+		// Catalog by image name, regardless of registry and tag
+		"jellyfin": {Name: "Jellyfin", Icon: "https://cdn.jsdelivr.net/gh/walkxcode/dashboard-icons/png/jellyfin.png", Homepage: "https://jellyfin.org"},
+		// homepage.* labels override the catalog, OCI labels don't
+		"sonarr": {Name: "TV Shows", Icon: "https://cdn.jsdelivr.net/gh/walkxcode/dashboard-icons/png/sonarr.png", Homepage: "http://nas:8989"},
+		// Unknown images with labels
+		"wiki": {Name: "Team Wiki", Icon: "https://wiki.lan/favicon.svg", Description: "Docs"},
+		"blog": {Name: "blog", Icon: "https://cdn.jsdelivr.net/gh/walkxcode/dashboard-icons/svg/ghost.svg"},
+	} {
+		if app := apps[name]; app == nil || *app != expected {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, app)
+		}
+	}
+	if app := apps["custom"]; app != nil {
+		t.Errorf("Expected no app for an unknown image, got %+v", app)
+	}
+}
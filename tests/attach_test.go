@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestContainerAttach(t *testing.T) {
+	attached := make(chan string, 10) // detach keys of each attach
+	detached := make(chan struct{}, 10)
+	resized := make(chan string, 10)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		running, tty, stdin := true, true, true
+		switch r.PathValue("id") {
+		case "plain":
+			tty = false
+		case "stopped":
+			running = false
+		case "daemon":
+			stdin = false
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Id":     r.PathValue("id"),
+			"Name":   "/" + r.PathValue("id"),
+			"State":  map[string]interface{}{"Running": running},
+			"Config": map[string]interface{}{"Tty": tty, "OpenStdin": stdin},
+		})
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/resize", func(w http.ResponseWriter, r *http.Request) {
+		resized <- r.URL.Query().Get("w") + "x" + r.URL.Query().Get("h")
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/attach", func(w http.ResponseWriter, r *http.Request) {
+		attached <- r.URL.Query().Get("detachKeys")
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n"))
+
+		if r.PathValue("id") == "plain" {
+			// Multiplexed output, then the process exits
+			for _, f := range []struct {
+				stream byte
+				text   string
+			}{{1, "line one\nline two\n"}, {2, "oops\n"}} {
+				frame := make([]byte, 8)
+				frame[0] = f.stream
+				binary.BigEndian.PutUint32(frame[4:], uint32(len(f.text)))
+				conn.Write(append(frame, f.text...))
+			}
+			return
+		}
+
+		// A program echoing its input
+		conn.Write([]byte("welcome\r\n"))
+		buf := make([]byte, 1024)
+		for {
+			n, err := rw.Read(buf)
+			if err != nil {
+				detached <- struct{}{}
+				return
+			}
+			conn.Write([]byte("got:" + string(buf[:n]) + "\r\n"))
+		}
+	})
+
+	eventStore := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withEvents(eventStore))
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+
+	header := http.Header{"User-Agent": {"attach-test"}}
+	dial := func(path string) *websocket.Conn {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/auth/ws-token", nil)
+		req.Header = header
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to get a WebSocket token: %v", err)
+		}
+		var token struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(resp.Body).Decode(&token)
+		resp.Body.Close()
+
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+path+sep+"ws_token="+token.Token, header)
+		if err != nil {
+			t.Fatalf("Failed to attach: %v", err)
+		}
+		ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+		return ws
+	}
+	// readUntil reads output until it contains want, or the connection closes
+	readUntil := func(ws *websocket.Conn, want string) (string, error) {
+		var out strings.Builder
+		for !strings.Contains(out.String(), want) {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return out.String(), err
+			}
+			out.Write(data)
+		}
+		return out.String(), nil
+	}
+	stdin := func(ws *websocket.Conn, data string) {
+		ws.WriteJSON(map[string]string{"type": "stdin", "data": data})
+	}
+	expectClose := func(ws *websocket.Conn, reason string) string {
+		t.Helper()
+		out, err := readUntil(ws, "\x00never")
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != reason {
+			t.Errorf("Expected the session to close with %q, got %v", reason, err)
+		}
+		return out
+	}
+
+	ws := dial("/api/v1/containers/tui/attach")
+	defer ws.Close()
+	if keys := <-attached; keys != "ctrl-p,ctrl-q" {
+		t.Errorf("Expected the default detach keys, got %q", keys)
+	}
+	if _, err := readUntil(ws, "welcome"); err != nil {
+		t.Fatalf("Expected the output of the program: %v", err)
+	}
+	stdin(ws, "ls\r")
+	if _, err := readUntil(ws, "got:ls\r"); err != nil {
+		t.Errorf("Expected the input to reach the program: %v", err)
+	}
+	ws.WriteJSON(map[string]interface{}{"type": "resize", "cols": 120, "rows": 40})
+	select {
+	case size := <-resized:
+		if size != "120x40" {
+			t.Errorf("Expected 120x40, got %s", size)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the TTY to be resized")
+	}
+
+	// A started detach sequence is passed on when it breaks off
+	stdin(ws, "\x10")
+	stdin(ws, "x")
+	if _, err := readUntil(ws, "\x10x"); err != nil {
+		t.Errorf("Expected the held back key: %v", err)
+	}
+	stdin(ws, "\x10\x11")
+	expectClose(ws, "Detached")
+	select {
+	case <-detached:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the attach connection to be closed")
+	}
+	if got := eventStore.GetLast(1); len(got) != 1 || got[0].Type != events.EventTerminalAttach || got[0].Details != "tui" {
+		t.Errorf("Unexpected event %+v", got)
+	}
+
+	// Custom detach keys
+	ws = dial("/api/v1/containers/tui/attach?detachKeys=ctrl-a,d")
+	defer ws.Close()
+	if keys := <-attached; keys != "ctrl-a,d" {
+		t.Errorf("Expected custom detach keys, got %q", keys)
+	}
+	stdin(ws, "q\x01d")
+	if out := expectClose(ws, "Detached"); strings.Contains(out, "\x01") {
+		t.Errorf("Expected the detach keys to stay out of the input, got %q", out)
+	}
+
+	// Output of containers without a TTY is demultiplexed
+	ws = dial("/api/v1/containers/plain/attach")
+	defer ws.Close()
+	<-attached
+	if out := expectClose(ws, "Container detached"); out != "line one\r\nline two\r\noops\r\n" {
+		t.Errorf("Unexpected output %q", out)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/containers/stopped/attach":                 http.StatusConflict,
+		"/api/v1/containers/daemon/attach":                  http.StatusConflict,
+		"/api/v1/containers/tui/attach?detachKeys=ctrl-1":   http.StatusBadRequest,
+		"/api/v1/containers/tui/attach?detachKeys=ctrl-p,,": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+// Attach input can't be checked against a command policy
+func TestContainerAttachPolicy(t *testing.T) {
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := newTestServer(t, podman.NewClientWithHandler(http.NewServeMux()), withStorage(store))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/api/v1/terminal/policy", `{"admin": {"mode": "deny", "rules": ["rm -*r*"]}}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	rec := do(http.MethodGet, "/api/v1/containers/tui/attach", "")
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "terminal command policy") {
+		t.Errorf("Expected attaching to be refused, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+// newGitServer serves a repository with a build context in docker/ over
+// the smart HTTP protocol of git http-backend
+func newGitServer(t *testing.T) *httptest.Server {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	execPath, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Skipf("git --exec-path: %v", err)
+	}
+
+	src, root := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		"README.md":              "app",
+		"docker/Containerfile":   "FROM alpine\nCOPY app.txt /\n",
+		"docker/app.txt":         "hello",
+		"docker/config/app.conf": "port=80",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"-C", src, "-c", "init.defaultBranch=main", "init", "-q"},
+		{"-C", src, "add", "."},
+		{"-C", src, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+		{"clone", "-q", "--bare", src, filepath.Join(root, "app.git")},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+
+	server := httptest.NewServer(&cgi.Handler{
+		Path:   filepath.Join(strings.TrimSpace(string(execPath)), "git-http-backend"),
+		Env:    []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+		Stderr: io.Discard,
+	})
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBuildFromGit(t *testing.T) {
+	git := newGitServer(t)
+
+	var query string
+	var contextFiles []string
+	failBuild := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/build", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		contextFiles = nil
+		tr := tar.NewReader(r.Body)
+		for {
+			header, err := tr.Next()
+			if err != nil {
+				break
+			}
+			contextFiles = append(contextFiles, header.Name)
+		}
+		if failBuild {
+			w.Write([]byte(`{"stream": "STEP 1/2: FROM alpine\n"}` + "\n" + `{"error": "COPY app.txt: no such file", "errorDetail": {}}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"stream": "STEP 1/2: FROM alpine\nSTEP 2/2: COPY app.txt /\n"}` + "\n" + `{"stream": "COMMIT localhost/app:1.0\n"}` + "\n" + `{"aux": {"ID": "sha256:0123456789abcdef"}}` + "\n"))
+	})
+
+	eventStore := events.NewStore(10)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withEvents(eventStore))
+	build := func(body string) (*httptest.ResponseRecorder, []string) {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/images/build", strings.NewReader(body)))
+		var lines []string
+		scanner := bufio.NewScanner(io.Reader(rec.Body))
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return rec, lines
+	}
+
+	rec, lines := build(`{"url": "` + git.URL + `/app.git", "ref": "main", "context": "docker", "tag": "localhost/app:1.0", "buildArgs": {"VERSION": "1.0"}}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected a stream, got %d %v", rec.Code, lines)
+	}
+	output := strings.Join(lines, "\n")
+	for _, want := range []string{
+		`data: {"line":"Cloning ` + git.URL + `/app.git (main)"}`,
+		`data: {"line":"STEP 2/2: COPY app.txt /"}`,
+		"event: done\ndata: {\"id\":\"sha256:0123456789abcdef\",\"tag\":\"localhost/app:1.0\",\"commit\":\"",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in\n%s", want, output)
+		}
+	}
+	if strings.Join(contextFiles, " ") != "Containerfile app.txt config config/app.conf" {
+		t.Errorf("Expected the context directory, got %v", contextFiles)
+	}
+	if !strings.Contains(query, "t=localhost%2Fapp%3A1.0") || !strings.Contains(query, "buildargs=%7B%22VERSION%22%3A%221.0%22%7D") {
+		t.Errorf("Unexpected build query %s", query)
+	}
+
+	// The default branch, with a failing build
+	failBuild = true
+	_, lines = build(`{"url": "` + git.URL + `/app.git", "tag": "localhost/app:2.0"}`)
+	output = strings.Join(lines, "\n")
+	if !strings.Contains(output, "event: error\ndata: {\"message\":\"COPY app.txt: no such file\"}") {
+		t.Errorf("Expected the build error, got\n%s", output)
+	}
+	if !strings.Contains(strings.Join(contextFiles, " "), "docker/app.txt") {
+		t.Errorf("Expected the repository root as context, got %v", contextFiles)
+	}
+
+	_, lines = build(`{"url": "` + git.URL + `/missing.git", "tag": "localhost/app:3.0"}`)
+	if output := strings.Join(lines, "\n"); !strings.Contains(output, "event: error") || !strings.Contains(output, "git fetch") {
+		t.Errorf("Expected a clone error, got\n%s", output)
+	}
+
+	var builds, failed int
+	for _, event := range eventStore.GetLast(10) {
+		if event.Type == events.EventImageBuild {
+			builds++
+			if !event.Success {
+				failed++
+			}
+		}
+	}
+	if builds != 3 || failed != 2 {
+		t.Errorf("Expected 3 build events, 2 failed, got %d and %d", builds, failed)
+	}
+
+	for _, body := range []string{
+		`{"url": "file:///etc", "tag": "app"}`,
+		`{"url": "/srv/repo", "tag": "app"}`,
+		`{"url": "ext::sh -c touch% /tmp/x", "tag": "app"}`,
+		`{"url": "https://example.com/app.git", "ref": "--upload-pack=touch /tmp/x", "tag": "app"}`,
+		`{"url": "https://example.com/app.git", "context": "../..", "tag": "app"}`,
+		`{"url": "https://example.com/app.git", "dockerfile": "/etc/passwd", "tag": "app"}`,
+		`{"url": "https://example.com/app.git", "buildArgs": {"A B": "1"}, "tag": "app"}`,
+		`{"url": "https://example.com/app.git"}`,
+	} {
+		if rec, _ := build(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
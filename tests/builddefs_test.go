@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestSavedBuilds(t *testing.T) {
+	git := newGitServer(t)
+
+	var mu sync.Mutex
+	var calls []string
+	baseID := "base1"
+	call := func(c string) {
+		mu.Lock()
+		calls = append(calls, c)
+		mu.Unlock()
+	}
+	takeCalls := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		result := strings.Join(calls, ", ")
+		calls = nil
+		return result
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/build", func(w http.ResponseWriter, r *http.Request) {
+		call("build pull=" + r.URL.Query().Get("pull"))
+		w.Write([]byte(`{"stream": "STEP 1/2: FROM alpine\nSTEP 2/2: COPY app.txt /\n"}` + "\n" + `{"aux": {"ID": "sha256:built1"}}` + "\n"))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		call("pull " + r.URL.Query().Get("reference"))
+		w.Write([]byte(`{"id": "base"}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Contains(r.URL.Path, "alpine") {
+			w.Write([]byte(`{"Id": "` + baseID + `"}`))
+			return
+		}
+		w.Write([]byte(`{"Id": "old"}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "c1", "Names": ["web"], "Image": "localhost/app:1.0", "ImageID": "old", "State": "running"},
+			{"Id": "c2", "Names": ["db"], "Image": "docker.io/library/postgres:16", "ImageID": "pg", "State": "running"},
+			{"Id": "c3", "Names": ["current"], "Image": "app:1.0", "ImageID": "built1", "State": "running"}
+		]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"Id": "c1", "Name": "web", "Image": "old", "ImageName": "localhost/app:1.0",
+			"State": {"Status": "running", "Running": true},
+			"Config": {"Env": ["PATH=/usr/bin"]}
+		}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		call("stop " + r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		call("remove " + r.PathValue("id"))
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		call("create " + config.Name + " " + config.Image)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "c4"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		call("start " + r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withStorage(store), withEvents(eventStore))
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	get := func(id string) api.BuildDefinition {
+		var def api.BuildDefinition
+		rec := request(http.MethodGet, "/api/v1/builds/"+id, "")
+		if err := json.Unmarshal(rec.Body.Bytes(), &def); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("Expected the build, got %d %s", rec.Code, rec.Body)
+		}
+		return def
+	}
+	runJob := func() map[string]string {
+		var result map[string]string
+		rec := request(http.MethodPost, "/api/v1/system/jobs/builds/run", "")
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the job to run, got %d %s", rec.Code, rec.Body)
+		}
+		return result
+	}
+
+	for _, body := range []string{
+		`{"url": "` + git.URL + `/app.git", "tag": "app:1.0"}`,
+		`{"name": "app", "url": "file:///etc", "tag": "app:1.0"}`,
+		`{"name": "app", "url": "` + git.URL + `/app.git"}`,
+	} {
+		if rec := request(http.MethodPost, "/api/v1/builds", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+
+	rec := request(http.MethodPost, "/api/v1/builds", `{"name": "app", "url": "`+git.URL+`/app.git", "context": "docker", "tag": "app:1.0", "watchBase": true, "redeploy": true, "lastBaseId": "forged"}`)
+	var def api.BuildDefinition
+	if err := json.Unmarshal(rec.Body.Bytes(), &def); err != nil || rec.Code != http.StatusCreated || def.ID == "" {
+		t.Fatalf("Expected 201, got %d %s", rec.Code, rec.Body)
+	}
+	if def.LastBaseID != "" {
+		t.Errorf("Expected the base image ID to be set by builds, got %q", def.LastBaseID)
+	}
+
+	// A watched build that never ran is built, then its containers redeployed
+	if result := runJob(); result["lastResult"] != "1 images built: app" || result["lastError"] != "" {
+		t.Errorf("Unexpected job result %v", result)
+	}
+	if got, want := takeCalls(), "build pull=true, stop c1, remove c1, create web localhost/app:1.0, start c4"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	def = get(def.ID)
+	if def.LastBase != "alpine" || def.LastBaseID != "base1" || def.LastRun == nil || !def.LastRun.Success {
+		t.Fatalf("Expected the base image and last run, got %+v", def)
+	}
+	if def.LastRun.Trigger != api.BuildTriggerBase || strings.Join(def.LastRun.Redeployed, ",") != "web" || def.LastRun.Commit == "" || def.LastRun.Log != "" {
+		t.Errorf("Unexpected last run %+v", def.LastRun)
+	}
+
+	// Unchanged base images are only pulled
+	if result := runJob(); result["lastResult"] != "0 images built" {
+		t.Errorf("Unexpected job result %v", result)
+	}
+	if got := takeCalls(); got != "pull alpine" {
+		t.Errorf("Expected only a pull, got %q", got)
+	}
+
+	mu.Lock()
+	baseID = "base2"
+	mu.Unlock()
+	runJob()
+	if got := takeCalls(); !strings.HasPrefix(got, "pull alpine, build pull=true") {
+		t.Errorf("Expected a rebuild on the new base, got %q", got)
+	}
+	if def = get(def.ID); def.LastBaseID != "base2" {
+		t.Errorf("Expected the new base image ID, got %q", def.LastBaseID)
+	}
+
+	// Updates keep the history and the base image state
+	rec = request(http.MethodPut, "/api/v1/builds/"+def.ID, `{"name": "app", "url": "`+git.URL+`/app.git", "context": "docker", "tag": "app:1.0"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if def = get(def.ID); def.WatchBase || def.Redeploy || def.LastBaseID != "base2" || def.LastRun == nil {
+		t.Errorf("Unexpected updated build %+v", def)
+	}
+	if result := runJob(); result["lastResult"] != "0 images built" {
+		t.Errorf("Expected unwatched builds to be skipped, got %v", result)
+	}
+	takeCalls()
+
+	// Manual runs are in the background
+	if rec := request(http.MethodPost, "/api/v1/builds/"+def.ID+"/run", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d %s", rec.Code, rec.Body)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for def = get(def.ID); def.Running || def.LastRun.Trigger != api.BuildTriggerManual; def = get(def.ID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Manual build did not finish: %+v", def)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := takeCalls(); got != "build pull=true" {
+		t.Errorf("Expected a build without redeploy, got %q", got)
+	}
+
+	var runs []api.BuildRun
+	json.Unmarshal(request(http.MethodGet, "/api/v1/builds/"+def.ID+"/runs", "").Body.Bytes(), &runs)
+	if len(runs) != 3 || runs[0].Trigger != api.BuildTriggerManual || runs[2].Trigger != api.BuildTriggerBase || runs[0].Log != "" {
+		t.Fatalf("Expected 3 runs, newest first, got %+v", runs)
+	}
+	var run api.BuildRun
+	rec = request(http.MethodGet, "/api/v1/builds/"+def.ID+"/runs/"+runs[0].ID, "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &run); err != nil || !strings.Contains(run.Log, "STEP 2/2: COPY app.txt /") {
+		t.Errorf("Expected the run with its log, got %d %s", rec.Code, rec.Body)
+	}
+
+	var builds, redeploys int
+	for _, event := range eventStore.GetLast(20) {
+		switch event.Type {
+		case events.EventImageBuild:
+			builds++
+		case events.EventContainerUpdate:
+			redeploys++
+		}
+	}
+	if builds != 3 || redeploys != 2 {
+		t.Errorf("Expected 3 build and 2 redeploy events, got %d and %d", builds, redeploys)
+	}
+
+	if rec := request(http.MethodDelete, "/api/v1/builds/"+def.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	for _, path := range []string{"/api/v1/builds/" + def.ID, "/api/v1/builds/" + def.ID + "/runs", "/api/v1/builds/" + def.ID + "/runs/" + runs[0].ID} {
+		if rec := request(http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+	if keys, _ := store.ListPrefix("builds", ""); len(keys) != 0 {
+		t.Errorf("Expected the history to be deleted, got %d keys", len(keys))
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"podmanview/internal/storage"
+)
+
+// buildBinary builds the podmanview command
+func buildBinary(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	bin := filepath.Join(t.TempDir(), "podmanview")
+	if out, err := exec.Command("go", "build", "-o", bin, "podmanview/cmd/podmanview").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v %s", err, out)
+	}
+	return bin
+}
+
+// export-backup writes archives that the backup API restores
+func TestExportBackup(t *testing.T) {
+	bin := buildBinary(t)
+	dir := t.TempDir()
+	env := []byte("PODMANVIEW_JWT_SECRET=secret\n")
+	if err := os.WriteFile(filepath.Join(dir, ".env"), env, 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	store, err := storage.NewBoltStorage(filepath.Join(dir, "podmanview.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	store.SetInt("temperature", "updateInterval", 15)
+	store.SaveCommandHistory("podman ps", time.Now())
+	store.Close()
+
+	archive := filepath.Join(dir, "backup.tar.gz")
+	if out, err := exec.Command(bin, "--data-dir", dir, "export-backup", "--output", archive).CombinedOutput(); err != nil {
+		t.Fatalf("export-backup: %v %s", err, out)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatalf("Expected an archive: %v", err)
+	}
+	defer f.Close()
+	dst, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "dst.sqlite"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer dst.Close()
+	manifest, files, err := storage.RestoreArchive(f, dst)
+	if err != nil {
+		t.Fatalf("RestoreArchive failed: %v", err)
+	}
+	if manifest.Backend != storage.BackendBolt {
+		t.Errorf("Expected backend bolt, got %q", manifest.Backend)
+	}
+	if string(files[".env"]) != string(env) {
+		t.Errorf("Expected the config file, got %q", files[".env"])
+	}
+	if v, _ := dst.GetInt("temperature", "updateInterval"); v != 15 {
+		t.Errorf("Expected updateInterval 15, got %d", v)
+	}
+	if history, _ := dst.GetCommandHistory(10); len(history) != 1 || history[0].Command != "podman ps" {
+		t.Errorf("Unexpected history after restore: %+v", history)
+	}
+
+	// A missing config is an error, not created with defaults
+	empty := t.TempDir()
+	if out, err := exec.Command(bin, "--data-dir", empty, "export-backup", "--output", filepath.Join(empty, "backup.tar.gz")).CombinedOutput(); err == nil {
+		t.Errorf("Expected export-backup to fail without a config, got %s", out)
+	}
+	if entries, _ := os.ReadDir(empty); len(entries) != 0 {
+		t.Errorf("Expected no files to be written, got %d", len(entries))
+	}
+}
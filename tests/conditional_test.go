@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/events"
+)
+
+func TestConditionalEventsList(t *testing.T) {
+	store := events.NewStore(10)
+	store.Add(events.EventLogin, "alice", "127.0.0.1", true, "")
+	server := newTestServer(t, nil, withEvents(store))
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/events?limit=10", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("", "")
+	etag, lastModified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+	if rec.Code != http.StatusOK || etag == "" || lastModified == "" {
+		t.Fatalf("Expected 200 with ETag and Last-Modified, got %d %q %q", rec.Code, etag, lastModified)
+	}
+
+	for _, cond := range [][2]string{{"If-None-Match", etag}, {"If-None-Match", `"other", ` + etag}, {"If-Modified-Since", lastModified}} {
+		rec = get(cond[0], cond[1])
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("%s: %s: expected 304 without body, got %d %q", cond[0], cond[1], rec.Code, rec.Body)
+		}
+	}
+
+	// A new event changes the list
+	store.Add(events.EventLogout, "alice", "127.0.0.1", true, "")
+	rec = get("If-None-Match", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("Expected 200 with a new ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// Responses are compressed for clients that accept it
+	rec = get("Accept-Encoding", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip response, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	var resp struct {
+		Events []events.Event `json:"events"`
+	}
+	if err := json.NewDecoder(gz).Decode(&resp); err != nil || len(resp.Events) != 2 {
+		t.Errorf("Expected 2 events, got %d (%v)", len(resp.Events), err)
+	}
+}
@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/config"
+)
+
+func TestConfigYAMLConvertsEnvFile(t *testing.T) {
+	dir := t.TempDir()
+
+	env := "PODMANVIEW_ADDR=:8080\nPODMANVIEW_JWT_SECRET=secret\nPODMANVIEW_JWT_EXPIRATION=3600\nPODMANVIEW_LOG_MAX_BACKUPS=0\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(env), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+
+	yamlPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(yamlPath, nil, 0600); err != nil {
+		t.Fatalf("Failed to write config.yaml: %v", err)
+	}
+
+	// First load converts .env, second load reads the written YAML
+	for i := 0; i < 2; i++ {
+		cfg, err := config.Load(yamlPath)
+		if err != nil {
+			t.Fatalf("Load #%d failed: %v", i+1, err)
+		}
+
+		if cfg.Addr() != ":8080" {
+			t.Errorf("Load #%d: expected addr :8080, got %q", i+1, cfg.Addr())
+		}
+		if cfg.JWTSecret() != "secret" {
+			t.Errorf("Load #%d: expected JWT secret to be preserved", i+1)
+		}
+		if cfg.JWTExpiration() != time.Hour {
+			t.Errorf("Load #%d: expected expiration 1h, got %v", i+1, cfg.JWTExpiration())
+		}
+		if cfg.LogMaxBackups() != 0 {
+			t.Errorf("Load #%d: expected 0 log backups, got %d", i+1, cfg.LogMaxBackups())
+		}
+	}
+}
+
+func TestConfigYAMLSections(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yaml")
+
+	content := `server:
+  addr: ":9090"
+auth:
+  jwt:
+    secret: "abc"
+plugins:
+  temperature:
+    interval: 30
+`
+	if err := os.WriteFile(yamlPath, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config.yaml: %v", err)
+	}
+
+	cfg, err := config.Load(yamlPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Addr() != ":9090" {
+		t.Errorf("Expected addr :9090, got %q", cfg.Addr())
+	}
+	if cfg.JWTExpiration() != config.DefaultJWTExpiration {
+		t.Errorf("Expected default expiration, got %v", cfg.JWTExpiration())
+	}
+
+	settings := cfg.PluginSettings("temperature")
+	if settings["interval"] != 30 {
+		t.Errorf("Expected plugin interval 30, got %v", settings["interval"])
+	}
+	if cfg.PluginSettings("missing") != nil {
+		t.Error("Expected nil settings for unknown plugin")
+	}
+}
+
+func TestConfigEnvOverride(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_ADDR=:8080\nPODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	t.Setenv("PODMANVIEW_ADDR", ":9999")
+	t.Setenv("PODMANVIEW_MAINTENANCE", "true")
+
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Addr() != ":9999" {
+		t.Errorf("Expected env override :9999, got %q", cfg.Addr())
+	}
+
+	sources := make(map[string]string)
+	for _, s := range cfg.Settings() {
+		sources[s.Key] = s.Source
+		if s.Key == config.EnvJWTSecret && s.Value == "secret" {
+			t.Error("JWT secret should be masked")
+		}
+	}
+
+	tests := []struct {
+		key    string
+		source string
+	}{
+		{config.EnvAddr, config.SourceEnv},
+		{config.EnvJWTSecret, config.SourceFile},
+		{config.EnvLogDir, config.SourceDefault},
+	}
+	for _, tt := range tests {
+		if sources[tt.key] != tt.source {
+			t.Errorf("%s: expected source %q, got %q", tt.key, tt.source, sources[tt.key])
+		}
+	}
+
+	// Saving must not leak env overrides into the file
+	if err := cfg.SetMaintenanceMode(false); err != nil {
+		t.Fatalf("SetMaintenanceMode failed: %v", err)
+	}
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		t.Fatalf("Failed to read .env: %v", err)
+	}
+	if !strings.Contains(string(data), "PODMANVIEW_ADDR=:8080") {
+		t.Error("Env override was written to the config file")
+	}
+}
+
+func TestConfigListeners(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    []config.Listener
+		wantErr bool
+	}{
+		{
+			name: "single address",
+			env:  "PODMANVIEW_ADDR=:8080\n",
+			want: []config.Listener{{Addr: ":8080"}},
+		},
+		{
+			name: "http and https",
+			env:  "PODMANVIEW_ADDR=http://127.0.0.1:8080,https://:8443\nPODMANVIEW_TLS_CERT=cert.pem\nPODMANVIEW_TLS_KEY=key.pem\n",
+			want: []config.Listener{{Addr: "127.0.0.1:8080"}, {Addr: ":8443", TLS: true}},
+		},
+		{
+			name: "unix socket stays plain with certificate",
+			env:  "PODMANVIEW_ADDR=unix:///tmp/pv.sock,:8443\nPODMANVIEW_TLS_CERT=cert.pem\nPODMANVIEW_TLS_KEY=key.pem\n",
+			want: []config.Listener{{Addr: "unix:///tmp/pv.sock"}, {Addr: ":8443", TLS: true}},
+		},
+		{
+			name:    "https without certificate",
+			env:     "PODMANVIEW_ADDR=https://:8443\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envPath := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(envPath, []byte(tt.env), 0600); err != nil {
+				t.Fatalf("Failed to write .env: %v", err)
+			}
+
+			cfg, err := config.Load(envPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got := cfg.Listeners()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d listeners, got %d: %+v", len(tt.want), len(got), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Listener %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConfigBackups(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\nPODMANVIEW_CONFIG_BACKUPS=2\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	for _, level := range []string{"debug", "error", "info"} {
+		if err := cfg.Update(map[string]string{config.EnvLogLevel: level}); err != nil {
+			t.Fatalf("Update to %s failed: %v", level, err)
+		}
+	}
+
+	backups, err := cfg.Backups()
+	if err != nil {
+		t.Fatalf("Backups failed: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups after pruning, got %d", len(backups))
+	}
+
+	// Newest backup holds the config as it was before the last update
+	if err := cfg.RestoreBackup(backups[0].ID); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if cfg.LogLevel() != "error" {
+		t.Errorf("Expected restored log level error, got %q", cfg.LogLevel())
+	}
+	if cfg.JWTSecret() != "secret" {
+		t.Error("Expected JWT secret to be preserved")
+	}
+
+	reloaded, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if reloaded.LogLevel() != "error" {
+		t.Errorf("Expected restored log level on disk, got %q", reloaded.LogLevel())
+	}
+
+	for _, id := range []string{"missing", "../.env", "20200101-000000.000"} {
+		if err := cfg.RestoreBackup(id); err != config.ErrBackupNotFound {
+			t.Errorf("RestoreBackup(%q): expected ErrBackupNotFound, got %v", id, err)
+		}
+	}
+}
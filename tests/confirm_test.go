@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestConfirmationTokens(t *testing.T) {
+	var calls []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/system/df", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"Containers": [
+				{"ContainerID": "c1", "Names": "web", "Status": "running", "RWSize": 100},
+				{"ContainerID": "c2", "Names": "old-job", "Status": "exited", "RWSize": 200}
+			],
+			"Images": [
+				{"ImageID": "i1", "Repository": "docker.io/library/nginx", "Size": 1000, "Containers": 1},
+				{"ImageID": "i2", "Repository": "<none>", "Size": 400, "Containers": 0},
+				{"ImageID": "i3", "Repository": "docker.io/library/redis", "Size": 800, "Containers": 0}
+			],
+			"Volumes": [
+				{"VolumeName": "webdata", "Size": 5000, "Links": 1},
+				{"VolumeName": "cache", "Size": 3000, "Links": 0}
+			]
+		}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/system/prune", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "prune "+r.URL.RawQuery)
+		w.Write([]byte(`{"ContainerPruneReports": [{"Id": "c2", "Size": 200}], "ImagePruneReports": [{"Id": "i2", "Size": 400}], "VolumePruneReports": [{"Id": "cache", "Size": 3000}], "ReclaimedSpace": 3600}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/volumes/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Name": "webdata"}, {"Name": "cache"}]`))
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/volumes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "remove "+r.PathValue("name"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "c1", "Names": ["web"], "State": "running"},
+			{"Id": "c2", "Names": ["old-job"], "State": "exited"},
+			{"Id": "p1", "Names": ["pod-infra"], "State": "running", "IsInfra": true}
+		]`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "c1" {
+			w.Write([]byte(`{"Id": "c1", "Mounts": [{"Type": "volume", "Name": "webdata", "Destination": "/data"}]}`))
+			return
+		}
+		w.Write([]byte(`{"Id": "` + r.PathValue("id") + `"}`))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("X-Confirm-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	type confirmation struct {
+		Code    string `json:"code"`
+		Details struct {
+			Token  string          `json:"token"`
+			Action string          `json:"action"`
+			Target string          `json:"target"`
+			Impact json.RawMessage `json:"impact"`
+		} `json:"details"`
+	}
+	ask := func(method, path, token string) confirmation {
+		t.Helper()
+		rec := request(method, path, token)
+		var c confirmation
+		if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil || rec.Code != http.StatusPreconditionRequired || c.Code != "confirmation_required" || c.Details.Token == "" {
+			t.Fatalf("%s %s: expected 428 with a token, got %d %s", method, path, rec.Code, rec.Body)
+		}
+		return c
+	}
+
+	// The first call only describes the prune
+	prune := ask(http.MethodPost, "/api/v1/system/prune?volumes=true", "")
+	var impact api.PruneImpact
+	json.Unmarshal(prune.Details.Impact, &impact)
+	if strings.Join(impact.Containers, ",") != "old-job" || impact.Images != 1 || strings.Join(impact.Volumes, ",") != "cache" || impact.Reclaimable != 3600 {
+		t.Errorf("Unexpected prune impact %+v", impact)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("Expected nothing to be pruned, got %v", calls)
+	}
+
+	// Tokens are bound to the operation and its target
+	other := ask(http.MethodPost, "/api/v1/system/prune?all=true&volumes=true", prune.Details.Token)
+	var all api.PruneImpact
+	json.Unmarshal(other.Details.Impact, &all)
+	if all.Images != 2 || all.Reclaimable != 4400 {
+		t.Errorf("Expected unused images to be pruned with all=true, got %+v", all)
+	}
+	// ...and single-use, so the mismatch consumed it
+	ask(http.MethodPost, "/api/v1/system/prune?volumes=true", prune.Details.Token)
+	ask(http.MethodDelete, "/api/v1/volumes/cache", other.Details.Token)
+
+	rec := request(http.MethodPost, "/api/v1/system/prune?all=true&volumes=true&confirm="+ask(http.MethodPost, "/api/v1/system/prune?all=true&volumes=true", "").Details.Token, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reclaimedSpace":3600`) {
+		t.Fatalf("Expected the prune to run, got %d %s", rec.Code, rec.Body)
+	}
+	if strings.Join(calls, ",") != "prune all=true&volumes=true" {
+		t.Errorf("Unexpected calls %v", calls)
+	}
+
+	// Volume removal
+	calls = nil
+	volume := ask(http.MethodDelete, "/api/v1/volumes/webdata", "")
+	if volume.Details.Target != "webdata" || string(volume.Details.Impact) != `{"size":5000,"usedBy":["web"]}` {
+		t.Errorf("Unexpected volume confirmation %+v %s", volume.Details, volume.Details.Impact)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/volumes/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/volumes/webdata", volume.Details.Token); rec.Code != http.StatusOK {
+		t.Errorf("Expected the volume to be removed, got %d %s", rec.Code, rec.Body)
+	}
+	if strings.Join(calls, ",") != "remove webdata" {
+		t.Errorf("Unexpected calls %v", calls)
+	}
+
+	// Reboot and shutdown list the containers they stop; never confirmed here
+	for _, path := range []string{"/api/v1/system/reboot", "/api/v1/system/shutdown"} {
+		power := ask(http.MethodPost, path, "")
+		if string(power.Details.Impact) != `{"runningContainers":["web"]}` {
+			t.Errorf("%s: unexpected impact %s", path, power.Details.Impact)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+// logFrame encodes a line of a multiplexed log stream
+func logFrame(stream byte, line string) []byte {
+	frame := make([]byte, 8, 8+len(line)+1)
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(line)+1))
+	return append(append(frame, line...), '\n')
+}
+
+func TestContainerLogStreams(t *testing.T) {
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch id := r.PathValue("id"); id {
+		case "web", "db":
+			w.Write([]byte(`{"Id": "` + id + `-id", "Name": "` + id + `"}`))
+		default:
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		queries = append(queries, r.PathValue("id")+" "+r.URL.RawQuery)
+		if query.Get("timestamps") != "true" {
+			http.Error(w, "timestamps expected", http.StatusBadRequest)
+			return
+		}
+		switch r.PathValue("id") {
+		case "web-id":
+			// Multiplexed, with ANSI colors
+			w.Write(logFrame(1, "2026-01-02T10:00:00.000000001Z \x1b[32mstarted\x1b[0m"))
+			w.Write(logFrame(2, "2026-01-02T10:00:02Z warning"))
+			if query.Get("follow") == "true" {
+				// Podman repeats the line at the since time
+				w.Write(logFrame(2, "2026-01-02T10:00:02Z warning"))
+				w.Write(logFrame(1, "2026-01-02T10:00:05Z request"))
+			}
+		case "db-id":
+			// TTY output is plain text
+			w.Write([]byte("2026-01-02T10:00:01Z ready\n"))
+		}
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/v1/containers/web/logs/download")
+	if rec.Code != http.StatusOK || rec.Body.String() != "2026-01-02T10:00:00.000000001Z started\n2026-01-02T10:00:02Z warning\n" {
+		t.Fatalf("Unexpected download %d %q", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Content-Disposition") != `attachment; filename="web.log"` {
+		t.Errorf("Unexpected headers %v", rec.Header())
+	}
+	if rec := get("/api/v1/containers/web/logs/download?timestamps=false"); rec.Body.String() != "started\nwarning\n" {
+		t.Errorf("Expected lines without timestamps, got %q", rec.Body)
+	}
+	if rec := get("/api/v1/containers/missing/logs/download"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+
+	// Both mock logs end after the history, so the stream ends with two end events
+	queries = nil
+	rec = get("/api/v1/containers/logs/stream?ids=web,db,web&tail=50")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected a stream, got %d %q", rec.Code, rec.Body)
+	}
+	var lines, ends []string
+	colors := make(map[string]string)
+	scanner := bufio.NewScanner(rec.Body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var entry api.LogEntry
+		json.Unmarshal([]byte(data), &entry)
+		if event == "end" {
+			ends = append(ends, entry.Container)
+			continue
+		}
+		lines = append(lines, entry.Container+" "+entry.Stream+" "+entry.Line)
+		colors[entry.Container] = entry.Color
+	}
+	want := "web stdout started,db stdout ready,web stderr warning,web stdout request"
+	if got := strings.Join(lines, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if len(ends) != 2 || colors["web"] == colors["db"] || colors["web"] == "" {
+		t.Errorf("Unexpected end events %v or colors %v", ends, colors)
+	}
+	followed := 0
+	for _, q := range queries {
+		if strings.Contains(q, "follow=true") {
+			followed++
+			if strings.HasPrefix(q, "web-id") && !strings.Contains(q, "since=2026-01-02T10%3A00%3A02Z") {
+				t.Errorf("Expected web to be followed from its last line, got %s", q)
+			}
+		} else if !strings.Contains(q, "tail=50") {
+			t.Errorf("Expected the history to be limited, got %s", q)
+		}
+	}
+	if followed != 2 {
+		t.Errorf("Expected 2 followed logs, got %v", queries)
+	}
+
+	for path, status := range map[string]int{
+		"/api/v1/containers/logs/stream":                                   http.StatusBadRequest,
+		"/api/v1/containers/logs/stream?ids=web&tail=-1":                   http.StatusBadRequest,
+		"/api/v1/containers/logs/stream?ids=web,missing":                   http.StatusNotFound,
+		"/api/v1/containers/logs/stream?ids=" + strings.Repeat("web,", 21): http.StatusBadRequest,
+	} {
+		if rec := get(path); rec.Code != status {
+			t.Errorf("Expected %d for %s, got %d", status, path, rec.Code)
+		}
+	}
+}
+
+func TestContainerLogSearch(t *testing.T) {
+	var query string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "web" {
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Id": "web-id", "Name": "web"}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		// One line per second, errors on lines 3, 4, 7 and 9
+		for i := 1; i <= 10; i++ {
+			text := "ok"
+			if i == 3 || i == 4 || i == 7 || i == 9 {
+				text = "ERROR failed"
+			}
+			w.Write(logFrame(1, fmt.Sprintf("2026-01-02T10:00:%02dZ %d %s", i, i, text)))
+		}
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	search := func(params string) (int, []api.LogMatch, int) {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers/web/logs/search?"+params, nil))
+		var result struct {
+			Matches    []api.LogMatch `json:"matches"`
+			NextCursor int            `json:"nextCursor"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return rec.Code, result.Matches, result.NextCursor
+	}
+	numbers := func(lines []api.LogSearchLine) string {
+		var out []string
+		for _, l := range lines {
+			out = append(out, strconv.Itoa(l.Line))
+		}
+		return strings.Join(out, ",")
+	}
+
+	code, matches, next := search("q=error&ignoreCase=true&context=2&limit=2")
+	if code != http.StatusOK || len(matches) != 2 || next != 2 {
+		t.Fatalf("Expected the first page, got %d %+v %d", code, matches, next)
+	}
+	if matches[0].Line != 3 || matches[0].Text != "3 ERROR failed" || numbers(matches[0].Before) != "1,2" || numbers(matches[0].After) != "4,5" {
+		t.Errorf("Unexpected first match %+v", matches[0])
+	}
+	if matches[1].Line != 4 || numbers(matches[1].Before) != "2,3" || numbers(matches[1].After) != "5,6" {
+		t.Errorf("Unexpected second match %+v", matches[1])
+	}
+
+	code, matches, next = search("q=ERR(OR)%3F&context=1&limit=2&cursor=2")
+	if code != http.StatusOK || len(matches) != 2 || next != 0 {
+		t.Fatalf("Expected the last page, got %d %+v %d", code, matches, next)
+	}
+	if matches[0].Line != 7 || matches[1].Line != 9 || numbers(matches[1].After) != "10" {
+		t.Errorf("Unexpected matches %+v", matches)
+	}
+
+	// Case matters by default, and lines out of the range are skipped
+	if _, matches, _ := search("q=error"); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %+v", matches)
+	}
+	_, matches, _ = search("q=ERROR&from=2026-01-02T10:00:04Z&to=2026-01-02T10:00:08Z")
+	if len(matches) != 2 || matches[0].Line != 1 || matches[1].Line != 4 || numbers(matches[0].Before) != "" {
+		t.Errorf("Unexpected matches in range %+v", matches)
+	}
+	if !strings.Contains(query, "since=2026-01-02T10%3A00%3A04Z") || !strings.Contains(query, "until=2026-01-02T10%3A00%3A08Z") {
+		t.Errorf("Expected the range to be passed to Podman, got %s", query)
+	}
+
+	for params, status := range map[string]int{
+		"":                   http.StatusBadRequest,
+		"q=(":                http.StatusBadRequest,
+		"q=x&context=21":     http.StatusBadRequest,
+		"q=x&from=yesterday": http.StatusBadRequest,
+		"q=x&cursor=-1":      http.StatusBadRequest,
+	} {
+		if code, _, _ := search(params); code != status {
+			t.Errorf("Expected %d for %q, got %d", status, params, code)
+		}
+	}
+}
+
+func TestContainerJournalLogs(t *testing.T) {
+	// Fake journalctl recording its arguments
+	bin := t.TempDir()
+	argsPath := filepath.Join(bin, "args")
+	script := "#!/bin/sh\necho \"$@\" >> " + argsPath + "\ncat <<'EOF'\n" +
+		`{"MESSAGE": "starting", "PRIORITY": "6", "__REALTIME_TIMESTAMP": "1767348000000001"}` + "\n" +
+		`{"MESSAGE": [98, 97, 100, 255], "PRIORITY": "3", "__REALTIME_TIMESTAMP": "1767348001000000"}` + "\n" +
+		`{"MESSAGE": "ready", "PRIORITY": "6", "__REALTIME_TIMESTAMP": "1767348002000000"}` + "\nEOF\n"
+	if err := os.WriteFile(filepath.Join(bin, "journalctl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write journalctl: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "0123456789abcdef", "Name": "web", "HostConfig": {"LogConfig": {"Type": "journald"}}}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected the journal to be read, got a Podman request")
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/v1/containers/web/logs/download")
+	want := "2026-01-02T10:00:00.000001Z starting\n2026-01-02T10:00:01Z bad\xff\n2026-01-02T10:00:02Z ready\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Errorf("Unexpected download %d %q", rec.Code, rec.Body)
+	}
+
+	rec = get("/api/v1/containers/web/logs?tail=50")
+	var logs api.LogsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &logs); err != nil || strings.Join(logs.Lines, ",") != "ready,bad�,starting" {
+		t.Errorf("Expected the newest line first, got %d %q", rec.Code, rec.Body)
+	}
+
+	rec = get("/api/v1/containers/web/logs/search?q=bad&from=2026-01-02T10:00:00Z")
+	var search struct {
+		Matches []api.LogMatch `json:"matches"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &search); err != nil || len(search.Matches) != 1 || search.Matches[0].Stream != "stderr" {
+		t.Errorf("Expected a stderr match, got %d %q", rec.Code, rec.Body)
+	}
+
+	args, _ := os.ReadFile(argsPath)
+	calls := strings.Split(strings.TrimSpace(string(args)), "\n")
+	if len(calls) != 3 || !strings.Contains(calls[0], "CONTAINER_ID_FULL=0123456789abcdef") || !strings.Contains(calls[0], "--lines=all") ||
+		!strings.Contains(calls[1], "--lines=50") || !strings.Contains(calls[2], "--since=2026-01-02 10:00:00 UTC") {
+		t.Errorf("Unexpected journalctl calls %q", calls)
+	}
+}
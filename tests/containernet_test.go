@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestContainerNetwork(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		running, pid := false, 0
+		if r.PathValue("id") == "web" {
+			// The test process stands in for the container
+			running, pid = true, os.Getpid()
+		}
+		fmt.Fprintf(w, `{"Id": "c1", "Name": %q, "State": {"Running": %t, "Pid": %d},
+			"HostConfig": {"NetworkMode": "bridge"},
+			"NetworkSettings": {"Networks": {"podman": {"IPAddress": "10.88.0.5", "IPPrefixLen": 16, "Gateway": "10.88.0.1", "MacAddress": "aa:bb:cc:dd:ee:ff"}}}}`,
+			r.PathValue("id"), running, pid)
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+	get := func(id string) api.ContainerNetwork {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers/"+id+"/network", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the network of %s, got %d %s", id, rec.Code, rec.Body)
+		}
+		var result api.ContainerNetwork
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return result
+	}
+
+	// A stopped container is described from the Podman networks
+	job := get("job")
+	if job.Source != "inspect" || job.Error == "" || len(job.Interfaces) != 1 || job.NetworkMode != "bridge" {
+		t.Fatalf("Expected the inspect data, got %+v", job)
+	}
+	if iface := job.Interfaces[0]; iface.Network != "podman" || len(iface.Addresses) != 1 || iface.Addresses[0] != "10.88.0.5/16" {
+		t.Errorf("Unexpected interface %+v", iface)
+	}
+	if len(job.Routes) != 1 || job.Routes[0].Gateway != "10.88.0.1" || job.Routes[0].Destination != "0.0.0.0/0" {
+		t.Errorf("Expected the default route, got %+v", job.Routes)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	web := get("web")
+	if web.Source != "netns" {
+		t.Skipf("Can't enter network namespaces: %s", web.Error)
+	}
+	foundLoopback := false
+	for _, iface := range web.Interfaces {
+		for _, addr := range iface.Addresses {
+			if iface.Name == "lo" && addr == "127.0.0.1/8" && iface.Up {
+				foundLoopback = true
+			}
+		}
+	}
+	if !foundLoopback {
+		t.Errorf("Expected the loopback interface, got %+v", web.Interfaces)
+	}
+	foundSocket := false
+	for _, socket := range web.Listening {
+		if socket.Protocol == "tcp" && socket.Address == "127.0.0.1" && socket.Port == port {
+			foundSocket = true
+		}
+	}
+	if !foundSocket {
+		t.Errorf("Expected the listening socket on port %d, got %+v", port, web.Listening)
+	}
+}
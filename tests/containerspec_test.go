@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestContainerExportImport(t *testing.T) {
+	var created map[string]interface{}
+	var pulled string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "web" {
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+			"Id": "0123456789abcdef", "Name": "web", "Image": "img1", "ImageName": "docker.io/library/nginx:1.27",
+			"Config": {
+				"Hostname": "0123456789ab", "Env": ["PATH=/usr/bin", "NGINX_VERSION=1.27", "HOSTNAME=0123456789ab", "container=podman", "MODE=prod"],
+				"Cmd": ["nginx", "-g", "daemon off;"], "Entrypoint": "/docker-entrypoint.sh", "WorkingDir": "/",
+				"Labels": {"maintainer": "NGINX", "app": "web"}
+			},
+			"HostConfig": {
+				"Memory": 268435456, "NetworkMode": "bridge", "CapAdd": ["CAP_NET_BIND_SERVICE"],
+				"PortBindings": {"80/tcp": [{"HostIp": "", "HostPort": "8080"}], "443/tcp": [{"HostIp": "127.0.0.1", "HostPort": "8443"}]},
+				"RestartPolicy": {"Name": "on-failure", "MaximumRetryCount": 3}
+			},
+			"NetworkSettings": {"Networks": {"shop": {}, "podman": {}}},
+			"Mounts": [
+				{"Type": "bind", "Source": "/srv/html", "Destination": "/usr/share/nginx/html", "RW": false},
+				{"Type": "volume", "Name": "cache", "Source": "/var/lib/containers/storage/volumes/cache/_data", "Destination": "/cache", "RW": true}
+			]
+		}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/images/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "img1" {
+			w.Write([]byte(`{"Id": "img1", "Config": {"Env": ["PATH=/usr/bin", "NGINX_VERSION=1.27"], "Cmd": ["nginx", "-g", "daemon off;"], "Entrypoint": ["/docker-entrypoint.sh"], "Labels": {"maintainer": "NGINX"}}}`))
+			return
+		}
+		http.Error(w, `{"message": "image not known"}`, http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		pulled = r.URL.Query().Get("reference")
+		w.Write([]byte(`{"id": "img2"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		created = nil
+		json.NewDecoder(r.Body).Decode(&created)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "new1"}`))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/api/v1/containers/web/export", "")
+	var spec api.ContainerSpec
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected spec, got %d %q", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="web.json"` {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+	want := api.ContainerSpec{
+		Version:        1,
+		Name:           "web",
+		Image:          "docker.io/library/nginx:1.27",
+		Env:            map[string]string{"MODE": "prod"},
+		Labels:         map[string]string{"app": "web"},
+		Ports:          []api.SpecPort{{HostPort: 8443, HostIP: "127.0.0.1", ContainerPort: 443, Protocol: "tcp"}, {HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}},
+		Mounts:         []api.SpecMount{{Type: "bind", Source: "/srv/html", Destination: "/usr/share/nginx/html", ReadOnly: true}, {Type: "volume", Source: "cache", Destination: "/cache"}},
+		Networks:       []string{"podman", "shop"},
+		RestartPolicy:  "on-failure",
+		RestartRetries: 3,
+		CapAdd:         []string{"CAP_NET_BIND_SERVICE"},
+		MemoryLimit:    256 << 20,
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("Unexpected spec\n got %+v\nwant %+v", spec, want)
+	}
+
+	// Import on another host, where the image is missing
+	spec.Image = "docker.io/library/nginx:1.28"
+	body, _ := json.Marshal(spec)
+	rec = request(http.MethodPost, "/api/v1/containers/import?name=web2", string(body))
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"new1"`) {
+		t.Fatalf("Expected 201, got %d %q", rec.Code, rec.Body)
+	}
+	if pulled != "docker.io/library/nginx:1.28" {
+		t.Errorf("Expected the image to be pulled, got %q", pulled)
+	}
+	wantCreated := `{"Networks":{"podman":{},"shop":{}},"cap_add":["CAP_NET_BIND_SERVICE"],"env":{"MODE":"prod"},"image":"docker.io/library/nginx:1.28",` +
+		`"labels":{"app":"web"},"mounts":[{"Destination":"/usr/share/nginx/html","Options":["ro"],"Source":"/srv/html","Type":"bind"}],"name":"web2",` +
+		`"netns":{"nsmode":"bridge"},"portmappings":[{"container_port":443,"host_ip":"127.0.0.1","host_port":8443,"protocol":"tcp"},{"container_port":80,"host_port":8080,"protocol":"tcp"}],` +
+		`"resource_limits":{"memory":{"limit":268435456}},"restart_policy":"on-failure","restart_tries":3,"volumes":[{"Dest":"/cache","Name":"cache"}]}`
+	if got, _ := json.Marshal(created); string(got) != wantCreated {
+		t.Errorf("Unexpected create request\n got %s\nwant %s", got, wantCreated)
+	}
+
+	for _, tt := range []struct{ body, message string }{
+		{`{"version": 2, "image": "nginx"}`, "Unsupported spec version"},
+		{`{"name": "x"}`, "Image is required"},
+		{`{"image": "nginx", "mounts": [{"type": "tmpfs", "source": "x", "destination": "/tmp"}]}`, "Invalid mount type"},
+		{`{"image": "nginx", "ports": [{"hostPort": 80, "containerPort": 70000}]}`, "Invalid port"},
+	} {
+		rec := request(http.MethodPost, "/api/v1/containers/import", tt.body)
+		data, _ := io.ReadAll(rec.Body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(string(data), tt.message) {
+			t.Errorf("%s: expected 400 %q, got %d %s", tt.body, tt.message, rec.Code, data)
+		}
+	}
+}
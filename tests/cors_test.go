@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/config"
+)
+
+func newCORSServer(t *testing.T, env string) *api.Server {
+	t.Helper()
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"+env), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return newTestServer(t, nil, withConfig(cfg))
+}
+
+func TestCORS(t *testing.T) {
+	server := newCORSServer(t, "PODMANVIEW_CORS_ORIGINS=http://localhost:5173, https://dash.example.com\nPODMANVIEW_CORS_METHODS=get,post\n")
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		status      int
+		allowOrigin string
+		credentials string
+		methods     string
+	}{
+		{"preflight from listed origin", http.MethodOptions, "http://localhost:5173", true, http.StatusNoContent, "http://localhost:5173", "true", "GET, POST"},
+		{"preflight from other origin", http.MethodOptions, "http://evil.example", true, http.StatusNoContent, "", "", ""},
+		{"request from listed origin", http.MethodGet, "https://dash.example.com", false, http.StatusUnauthorized, "https://dash.example.com", "true", ""},
+		{"request from other origin", http.MethodGet, "http://evil.example", false, http.StatusUnauthorized, "", "", ""},
+		{"same-origin request", http.MethodGet, "", false, http.StatusUnauthorized, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/containers", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				req.Header.Set("Access-Control-Request-Headers", "content-type")
+			}
+			rec := httptest.NewRecorder()
+			server.Router().ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin: expected %q, got %q", tt.allowOrigin, got)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Access-Control-Allow-Credentials: expected %q, got %q", tt.credentials, got)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.methods {
+				t.Errorf("Access-Control-Allow-Methods: expected %q, got %q", tt.methods, got)
+			}
+		})
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	server := newCORSServer(t, "PODMANVIEW_CORS_ORIGINS=*\n")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected wildcard origin, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Wildcard origin must not allow credentials, got %q", got)
+	}
+}
+
+func TestCORSDisabledByDefault(t *testing.T) {
+	server := newCORSServer(t, "")
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/containers", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS headers, got Access-Control-Allow-Origin %q", got)
+	}
+}
+
+func TestCORSInvalidOrigin(t *testing.T) {
+	for _, origin := range []string{"localhost:5173", "http://localhost:5173/app", "ftp://example.com"} {
+		envPath := filepath.Join(t.TempDir(), ".env")
+		if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\nPODMANVIEW_CORS_ORIGINS="+origin+"\n"), 0600); err != nil {
+			t.Fatalf("Failed to write .env: %v", err)
+		}
+		if _, err := config.Load(envPath); err == nil {
+			t.Errorf("Expected origin %q to be rejected", origin)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+)
+
+// fakeCPU advances the CPU times by 100 ticks per reading, 25 of them busy
+type fakeCPU struct {
+	reads atomic.Int64
+}
+
+func (f *fakeCPU) read() (total, idle int64) {
+	n := f.reads.Add(1)
+	return n * 100, n * 75
+}
+
+func TestCPUSamplerFirstUsage(t *testing.T) {
+	cpu := &fakeCPU{}
+	sampler := api.NewCPUSampler(cpu.read)
+	if usage := sampler.Usage(); usage != 25 {
+		t.Errorf("Expected the first call to measure 25%%, got %v", usage)
+	}
+
+	// Times that can't be read keep the last usage
+	sampler = api.NewCPUSampler(func() (int64, int64) { return 0, 0 })
+	if usage := sampler.Usage(); usage != 0 {
+		t.Errorf("Expected 0 without CPU times, got %v", usage)
+	}
+}
+
+func TestCPUSamplerConcurrentCallers(t *testing.T) {
+	var mu sync.Mutex
+	var total, idle int64
+	sampler := api.NewCPUSampler(func() (int64, int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		return total, idle
+	})
+	advance := func(busy, free int64) {
+		mu.Lock()
+		total += busy + free
+		idle += free
+		mu.Unlock()
+	}
+
+	advance(50, 50)
+	if usage := sampler.Sample(); usage != 50 {
+		t.Fatalf("Expected 50%%, got %v", usage)
+	}
+
+	// Readers between samples get the collector's value instead of
+	// measuring tiny windows of their own
+	advance(90, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if usage := sampler.Usage(); usage != 50 {
+				t.Errorf("Expected the last sample, got %v", usage)
+			}
+		}()
+	}
+	wg.Wait()
+	if usage := sampler.Sample(); usage != 90 {
+		t.Errorf("Expected the collector to see the whole window (90%%), got %v", usage)
+	}
+}
+
+func TestCPUSamplerRun(t *testing.T) {
+	cpu := &fakeCPU{}
+	sampler := api.NewCPUSampler(cpu.read)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sampler.Run(ctx, time.Millisecond, nil)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cpu.reads.Load() < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if cpu.reads.Load() < 5 || sampler.Usage() != 25 {
+		t.Errorf("Expected periodic samples at 25%%, got %d reads and %v", cpu.reads.Load(), sampler.Usage())
+	}
+}
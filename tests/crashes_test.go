@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestContainerCrashHistory(t *testing.T) {
+	now := time.Now()
+	died := func(id, name, code string, ago time.Duration) podman.EngineEvent {
+		event := podman.EngineEvent{Type: "container", Action: "died", TimeNano: now.Add(-ago).UnixNano()}
+		event.Actor.ID = id
+		event.Actor.Attributes = map[string]string{"name": name, "containerExitCode": code}
+		return event
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/events", func(w http.ResponseWriter, r *http.Request) {
+		// Give the watchers time to subscribe
+		time.Sleep(200 * time.Millisecond)
+		encoder := json.NewEncoder(w)
+		for _, event := range []podman.EngineEvent{
+			died("web-id", "web", "1", 8*time.Minute),
+			died("web-id", "web", "137", 6*time.Minute),
+			died("web-id", "web", "1", 4*time.Minute),
+			died("web-id", "web", "1", 2*time.Minute),
+			died("db-id", "db", "0", time.Minute),
+			died("db-id", "db", "143", 0),
+			{Type: "container", Action: "start"},
+		} {
+			encoder.Encode(event)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "web", "web-id":
+			w.Write([]byte(`{"Id": "web-id", "Name": "web", "State": {"ExitCode": 1, "OOMKilled": true}}`))
+		default:
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+		}
+	})
+
+	cfg := newTestConfig(t, "")
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(50)
+	client := podman.NewClientWithHandler(mux)
+	server := newTestServer(t, client, withConfig(cfg), withStorage(store), withEvents(eventStore))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.StartEngineEvents(ctx)
+	server.StartAlertMonitors(ctx)
+
+	get := func(server *api.Server, path string, v interface{}) int {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		json.Unmarshal(rec.Body.Bytes(), v)
+		return rec.Code
+	}
+	var unstable struct {
+		Containers []api.UnstableContainer `json:"containers"`
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		get(server, "/api/v1/containers/unstable", &unstable)
+		if len(unstable.Containers) == 1 && unstable.Containers[0].Crashes == 4 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+
+	want := api.UnstableContainer{Name: "web", Crashes: 4, OOMKills: 1, Exits: 4, LastExitCode: 1, LastCrash: time.Unix(0, now.Add(-2*time.Minute).UnixNano())}
+	if len(unstable.Containers) != 1 || !unstable.Containers[0].LastCrash.Equal(want.LastCrash) {
+		t.Fatalf("Expected %+v, got %+v", want, unstable.Containers)
+	}
+	if got := unstable.Containers[0]; got.Name != want.Name || got.Crashes != want.Crashes || got.OOMKills != want.OOMKills || got.Exits != want.Exits || got.LastExitCode != want.LastExitCode {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	var history struct {
+		Container string              `json:"container"`
+		Exits     []api.ContainerExit `json:"exits"`
+		Crashes   int                 `json:"crashes"`
+		OOMKills  int                 `json:"oomKills"`
+	}
+	if code := get(server, "/api/v1/containers/web-id/exits", &history); code != http.StatusOK || history.Container != "web" || len(history.Exits) != 4 || history.OOMKills != 1 {
+		t.Fatalf("Unexpected history %d %+v", code, history)
+	}
+	if first := history.Exits[0]; first.ExitCode != 1 || !first.Crash || history.Exits[2].ExitCode != 137 || !history.Exits[2].OOMKilled {
+		t.Errorf("Expected the newest exit first, got %+v", history.Exits)
+	}
+	// Removed containers keep their history
+	if code := get(server, "/api/v1/containers/db/exits", &history); code != http.StatusOK || len(history.Exits) != 2 || history.Crashes != 0 {
+		t.Errorf("Unexpected db history %d %+v", code, history)
+	}
+	if code := get(server, "/api/v1/containers/missing/exits", &history); code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", code)
+	}
+	if code := get(server, "/api/v1/containers/unstable?days=31", &unstable); code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", code)
+	}
+
+	// The loop is reported once, at the third crash
+	var loops []events.Event
+	for _, event := range eventStore.GetLast(50) {
+		if event.Type == events.EventContainerCrash {
+			loops = append(loops, event)
+		}
+	}
+	if len(loops) != 1 || !strings.HasPrefix(loops[0].Details, "web crashed 3 times") || loops[0].Severity != events.SeverityCritical {
+		t.Errorf("Expected one crash loop event, got %+v", loops)
+	}
+
+	// The history is kept in storage
+	restarted := newTestServer(t, client, withConfig(cfg), withStorage(store))
+	if get(restarted, "/api/v1/containers/unstable", &unstable); len(unstable.Containers) != 1 || unstable.Containers[0].Crashes != 4 {
+		t.Errorf("Expected the stored history, got %+v", unstable.Containers)
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestDashboardLayouts(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	client := podman.NewClientWithHandler(http.NewServeMux())
+	server := newTestServer(t, client, withConfig(cfg), withStorage(store))
+
+	// HS256 tokens signed with the configured secret
+	jwtManager := auth.NewJWTManager(cfg.JWTSecret(), time.Hour)
+	tokens := make(map[string]string)
+	for _, user := range []*auth.User{{Username: "admin", UID: "0", Role: auth.RoleAdmin}, {Username: "alice", UID: "1000", Role: auth.RoleReadOnly}} {
+		if tokens[user.Username], err = jwtManager.GenerateToken(user); err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+	}
+
+	do := func(user, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: tokens[user]})
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	list := func(user string) []api.DashboardLayout {
+		rec := do(user, http.MethodGet, "/api/dashboards", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("List: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var layouts []api.DashboardLayout
+		json.Unmarshal(rec.Body.Bytes(), &layouts)
+		return layouts
+	}
+
+	// New users start with a copy of the built-in layout
+	layouts := list("alice")
+	if len(layouts) != 1 || layouts[0].Name != "Overview" || !layouts[0].Default || layouts[0].ID == "" || len(layouts[0].Widgets) != 2 {
+		t.Fatalf("Unexpected initial layouts: %+v", layouts)
+	}
+	overview := layouts[0]
+	if again := list("alice"); len(again) != 1 || again[0].ID != overview.ID {
+		t.Errorf("Expected the copy to be stored once, got %+v", again)
+	}
+
+	for body, code := range map[string]int{
+		`{"name": ""}`:         http.StatusBadRequest,
+		`{"name": "overview"}`: http.StatusConflict,
+		`{"name": "A", "widgets": [{"type": "clock", "w": 1, "h": 1}]}`:                                                                            http.StatusBadRequest,
+		`{"name": "A", "widgets": [{"type": "host_stats", "x": 6, "w": 7, "h": 1}]}`:                                                               http.StatusBadRequest,
+		`{"name": "A", "widgets": [{"type": "host_stats", "w": 4, "h": 0}]}`:                                                                       http.StatusBadRequest,
+		`{"name": "A", "widgets": [{"type": "container", "w": 4, "h": 2}]}`:                                                                        http.StatusBadRequest,
+		`{"name": "A", "widgets": [{"type": "plugin", "plugin": "nope", "w": 4, "h": 2}]}`:                                                         http.StatusBadRequest,
+		`{"name": "A", "widgets": [{"type": "events", "category": "weather", "w": 4, "h": 2}]}`:                                                    http.StatusBadRequest,
+		`{"name": "A", "widgets": [{"type": "events", "limit": 500, "w": 4, "h": 2}]}`:                                                             http.StatusBadRequest,
+		`{"name": "A", "widgets": [{"id": "a", "type": "host_stats", "w": 4, "h": 2}, {"id": "a", "type": "host_stats", "x": 4, "w": 4, "h": 2}]}`: http.StatusBadRequest,
+	} {
+		if rec := do("alice", http.MethodPost, "/api/dashboards", body); rec.Code != code {
+			t.Errorf("Create %s: expected %d, got %d: %s", body, code, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := do("alice", http.MethodPost, "/api/dashboards", `{"name": "Media", "default": true, "widgets": [
+		{"type": "container", "container": "jellyfin", "w": 6, "h": 4},
+		{"type": "events", "category": "container", "x": 6, "w": 6, "h": 4}
+	]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var media api.DashboardLayout
+	json.Unmarshal(rec.Body.Bytes(), &media)
+	if !media.Default || media.Widgets[0].ID == "" || media.Widgets[1].Limit != 10 {
+		t.Errorf("Unexpected layout: %+v", media)
+	}
+
+	// The new default replaces the previous one
+	layouts = list("alice")
+	if len(layouts) != 2 || layouts[0].Name != "Media" || !layouts[0].Default || layouts[1].Default {
+		t.Errorf("Unexpected layouts: %+v", layouts)
+	}
+
+	// Layouts are private
+	if rec := do("admin", http.MethodGet, "/api/dashboards/"+media.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Other user's layout: expected 404, got %d", rec.Code)
+	}
+	if layouts := list("admin"); len(layouts) != 1 || layouts[0].ID == overview.ID {
+		t.Errorf("Expected admin to get an own layout, got %+v", layouts)
+	}
+
+	rec = do("alice", http.MethodPut, "/api/dashboards/"+overview.ID, `{"name": "Host", "widgets": [{"type": "host_stats", "w": 12, "h": 3}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Update: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated api.DashboardLayout
+	json.Unmarshal(rec.Body.Bytes(), &updated)
+	if updated.ID != overview.ID || updated.Name != "Host" || updated.Default || len(updated.Widgets) != 1 || !updated.CreatedAt.Equal(overview.CreatedAt) {
+		t.Errorf("Unexpected updated layout: %+v", updated)
+	}
+
+	// Removing the default layout makes another one the default
+	if rec := do("alice", http.MethodDelete, "/api/dashboards/"+media.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Delete: expected 200, got %d", rec.Code)
+	}
+	if layouts := list("alice"); len(layouts) != 1 || layouts[0].ID != overview.ID || !layouts[0].Default {
+		t.Errorf("Expected the remaining layout to be the default, got %+v", layouts)
+	}
+	if rec := do("alice", http.MethodDelete, "/api/dashboards/"+media.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Delete again: expected 404, got %d", rec.Code)
+	}
+
+	// Only admins set the layout for new users
+	body := `{"name": "Start", "widgets": [{"type": "events", "w": 12, "h": 6}]}`
+	if rec := do("alice", http.MethodPut, "/api/dashboards/default", body); rec.Code != http.StatusForbidden {
+		t.Errorf("Set default as user: expected 403, got %d", rec.Code)
+	}
+	if rec := do("admin", http.MethodPut, "/api/dashboards/default", body); rec.Code != http.StatusOK {
+		t.Fatalf("Set default: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var start api.DashboardLayout
+	json.Unmarshal(do("alice", http.MethodGet, "/api/dashboards/default", "").Body.Bytes(), &start)
+	if start.Name != "Start" || len(start.Widgets) != 1 {
+		t.Errorf("Unexpected default layout: %+v", start)
+	}
+
+	tokens["bob"], _ = jwtManager.GenerateToken(&auth.User{Username: "bob", UID: "1001", Role: auth.RoleReadOnly})
+	if layouts := list("bob"); len(layouts) != 1 || layouts[0].Name != "Start" || !layouts[0].Default {
+		t.Errorf("Expected bob to start with the admin's layout, got %+v", layouts)
+	}
+
+	if rec := do("admin", http.MethodDelete, "/api/dashboards/default", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Reset default: expected 200, got %d", rec.Code)
+	}
+	json.Unmarshal(do("alice", http.MethodGet, "/api/dashboards/default", "").Body.Bytes(), &start)
+	if start.Name != "Overview" {
+		t.Errorf("Expected the built-in layout after reset, got %+v", start)
+	}
+}
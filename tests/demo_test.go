@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/config"
+	"podmanview/internal/demo"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+func TestDemoMode(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\nPODMANVIEW_DEMO=true\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := events.NewStore(10)
+	demo.SeedEvents(store)
+	client := podman.NewClientWithHandler(demo.Podman())
+	server := newTestServer(t, client, withConfig(cfg), withEvents(store))
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	// Mock data is readable without logging in
+	rec := request(http.MethodGet, "/api/v1/containers")
+	var containers []api.ContainerWithStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &containers); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected container list, got %d %q", rec.Code, rec.Body)
+	}
+	running := 0
+	for _, c := range containers {
+		if c.State == "running" {
+			running++
+			if c.MemUsage == 0 {
+				t.Errorf("Expected stats for running container %s", c.Names[0])
+			}
+		}
+	}
+	if len(containers) < 5 || running == 0 || running == len(containers) {
+		t.Errorf("Expected running and stopped mock containers, got %d of %d running", running, len(containers))
+	}
+
+	rec = request(http.MethodGet, "/api/v1/containers/web/logs")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "192.0.2.10") {
+		t.Errorf("Expected mock logs, got %d %q", rec.Code, rec.Body)
+	}
+
+	rec = request(http.MethodGet, "/api/v1/system/dashboard")
+	var dashboard api.DashboardInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &dashboard); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected dashboard, got %d %q", rec.Code, rec.Body)
+	}
+	if dashboard.System.Host.Hostname != demo.Hostname || dashboard.HostStats.MemTotal != 8<<30 || len(dashboard.HostStats.Disks) != 2 {
+		t.Errorf("Expected mock host, got %+v %+v", dashboard.System.Host, dashboard.HostStats)
+	}
+
+	rec = request(http.MethodGet, "/api/v1/auth/me")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"demo"`) {
+		t.Errorf("Expected demo user, got %d %q", rec.Code, rec.Body)
+	}
+
+	// Changes and host access are rejected
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/containers/web/stop"},
+		{http.MethodDelete, "/api/v1/images/abc"},
+		{http.MethodPost, "/api/v1/auth/login"},
+		{http.MethodPost, "/api/v1/system/reboot"},
+		{http.MethodGet, "/api/v1/containers/web/terminal"},
+		{http.MethodGet, "/api/v1/terminal"},
+		{http.MethodGet, "/api/v1/containers/web/attach"},
+		{http.MethodGet, "/api/v1/images/abc/export"},
+		{http.MethodPost, "/api/v1/images/import"},
+		{http.MethodGet, "/api/v1/files/browse"},
+		{http.MethodGet, "/api/v1/system/logs"},
+		{http.MethodGet, "/api/v1/settings"},
+		{http.MethodGet, "/api/v1/history"},
+		{http.MethodGet, "/api/v1/system/backup"},
+		{http.MethodGet, "/api/v1/plugins/temperature/html"},
+		{http.MethodGet, "/proxy/web/80/"},
+	} {
+		if rec := request(tt.method, tt.path); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", tt.method, tt.path, rec.Code)
+		}
+	}
+
+	// Changes are rejected by the mock Podman API as well
+	if err := client.StopContainer(t.Context(), "web"); err == nil {
+		t.Error("Expected mock Podman API to reject changes")
+	}
+}
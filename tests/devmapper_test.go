@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"podmanview/internal/api"
+)
+
+func TestResolveDeviceMapper(t *testing.T) {
+	sysBlock := t.TempDir()
+	addDM := func(kernel, name, uuid string, slaves ...string) {
+		dir := filepath.Join(sysBlock, kernel)
+		for _, d := range []string{"dm", "slaves"} {
+			if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		os.WriteFile(filepath.Join(dir, "dm", "name"), []byte(name+"\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "dm", "uuid"), []byte(uuid+"\n"), 0644)
+		for _, slave := range slaves {
+			os.Mkdir(filepath.Join(dir, "slaves", slave), 0755)
+		}
+	}
+	// LVM on LUKS on an NVMe partition
+	addDM("dm-0", "cryptroot", "CRYPT-LUKS2-2f5e8c3b9d4a4e1f8a6b7c8d9e0f1a2b-cryptroot", "nvme0n1p3")
+	addDM("dm-1", "my--vg-root", "LVM-ZxUeTwB6t0bWq1dRkXhoQvZ9s8r7u6y5mnbvcxzlkjhgfdsapoiuytrewq12", "dm-0")
+	addDM("dm-2", "swap", "")
+
+	luks := api.ResolveDeviceMapper(sysBlock, "/dev/mapper/cryptroot")
+	expected := &api.DeviceMapping{Kernel: "dm-0", Name: "cryptroot", Type: api.MappingLUKS, Backing: []string{"nvme0n1p3"}}
+	if !reflect.DeepEqual(luks, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, luks)
+	}
+
+	lvm := api.ResolveDeviceMapper(sysBlock, "/dev/mapper/my--vg-root")
+	if lvm == nil || lvm.Kernel != "dm-1" || lvm.Type != api.MappingLVM || lvm.VG != "my-vg" || lvm.LV != "root" || lvm.DisplayName() != "my-vg/root" {
+		t.Errorf("Unexpected LVM mapping: %+v", lvm)
+	}
+	if dm := api.ResolveDeviceMapper(sysBlock, "/dev/dm-2"); dm == nil || dm.Type != "" || dm.DisplayName() != "swap" {
+		t.Errorf("Unexpected mapping: %+v", dm)
+	}
+
+	for _, device := range []string{"/dev/sda1", "/dev/mapper/unknown", "/dev/dm-9"} {
+		if m := api.ResolveDeviceMapper(sysBlock, device); m != nil {
+			t.Errorf("%s: expected no mapping, got %+v", device, m)
+		}
+	}
+}
+
+func TestSplitLVMName(t *testing.T) {
+	for name, expected := range map[string][2]string{
+		"vg0-root":              {"vg0", "root"},
+		"my--vg-my--lv":         {"my-vg", "my-lv"},
+		"vg-thin--pool":         {"vg", "thin-pool"},
+		"no_separator":          {"", ""},
+		"ubuntu--vg-ubuntu--lv": {"ubuntu-vg", "ubuntu-lv"},
+	} {
+		if vg, lv := api.SplitLVMName(name); vg != expected[0] || lv != expected[1] {
+			t.Errorf("%s: expected %v, got %s, %s", name, expected, vg, lv)
+		}
+	}
+}
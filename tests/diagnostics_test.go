@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/config"
+	"podmanview/internal/logger"
+	"podmanview/internal/podman"
+)
+
+func TestDiagnosticsBundle(t *testing.T) {
+	const secret = "jwt-secret-0123456789"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"host": {"hostname": "box", "arch": "amd64"}, "version": {"Version": "5.2.0"}}`))
+	})
+
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET="+secret+"\nPODMANVIEW_NO_AUTH=true\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	appLogger, err := logger.New(t.TempDir(), 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer appLogger.Close()
+	appLogger.Info("Webhook sent", "url", "https://example.com/hook", "token", "abc123")
+	appLogger.Warn("Bad secret in request: " + secret)
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withConfig(cfg), withLogger(appLogger))
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/diagnostics", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("Expected a bundle, got %d %s", rec.Code, rec.Body)
+	}
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip: %v", err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+	for _, name := range []string{"summary.json", "config.json", "logs.json", "plugins.json", "podman-info.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Missing %s, got %v", name, files)
+		}
+	}
+
+	var summary api.DiagnosticsSummary
+	json.Unmarshal([]byte(files["summary.json"]), &summary)
+	if summary.Version != "1.2.3" || summary.Checks["podman"] != "ok" || summary.Checks["logs"] != "ok" {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if !strings.Contains(files["podman-info.json"], `"hostname": "box"`) {
+		t.Errorf("Expected podman info, got %s", files["podman-info.json"])
+	}
+
+	var entries []logger.Entry
+	json.Unmarshal([]byte(files["logs.json"]), &entries)
+	found := false
+	for _, e := range entries {
+		if e.Message == "Webhook sent" {
+			found = true
+			if e.Fields["token"] != "[redacted]" || e.Fields["url"] != "https://example.com/hook" {
+				t.Errorf("Expected only the token to be redacted, got %v", e.Fields)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected the log entries, got %s", files["logs.json"])
+	}
+	for name, data := range files {
+		if strings.Contains(data, secret) || strings.Contains(data, "abc123") {
+			t.Errorf("%s contains a secret: %s", name, data)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/diagnostics?logs=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid logs parameter, got %d", rec.Code)
+	}
+}
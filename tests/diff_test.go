@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestContainerDiff(t *testing.T) {
+	stats := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/changes", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "web" {
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[
+			{"Path": "/etc", "Kind": 0},
+			{"Path": "/etc/nginx.conf", "Kind": 0},
+			{"Path": "/etc/motd", "Kind": 2},
+			{"Path": "/data", "Kind": 1},
+			{"Path": "/data/cache.db", "Kind": 1},
+			{"Path": "/tmp/gone", "Kind": 1}
+		]`))
+	})
+	mux.HandleFunc("HEAD /v4.0.0/containers/{id}/archive", func(w http.ResponseWriter, r *http.Request) {
+		stats++
+		var stat string
+		switch r.URL.Query().Get("path") {
+		case "/etc", "/data":
+			stat = `{"name": "dir", "size": 4096, "mode": 2147484141}`
+		case "/etc/nginx.conf":
+			stat = `{"name": "nginx.conf", "size": 1200, "mode": 420}`
+		case "/data/cache.db":
+			stat = `{"name": "cache.db", "size": 50000, "mode": 420}`
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(stat)))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	get := func(path string) (*httptest.ResponseRecorder, api.ContainerDiff) {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var diff api.ContainerDiff
+		json.Unmarshal(rec.Body.Bytes(), &diff)
+		return rec, diff
+	}
+
+	rec, diff := get("/api/v1/containers/web/diff")
+	if rec.Code != http.StatusOK || len(diff.Changes) != 6 {
+		t.Fatalf("Expected 6 changes, got %d %q", rec.Code, rec.Body)
+	}
+	if diff.Added != 3 || diff.Modified != 2 || diff.Deleted != 1 || diff.Size != 51200 || diff.SizesTruncated {
+		t.Errorf("Unexpected summary %+v", diff)
+	}
+	byPath := make(map[string]api.ContainerChange)
+	for _, c := range diff.Changes {
+		byPath[c.Path] = c
+	}
+	if c := byPath["/etc/nginx.conf"]; c.Kind != "modified" || c.Size == nil || *c.Size != 1200 || c.Dir {
+		t.Errorf("Unexpected file change %+v", c)
+	}
+	if c := byPath["/data"]; c.Kind != "added" || !c.Dir || c.Size != nil {
+		t.Errorf("Expected a directory without size, got %+v", c)
+	}
+	if c := byPath["/etc/motd"]; c.Kind != "deleted" || c.Size != nil {
+		t.Errorf("Unexpected deleted change %+v", c)
+	}
+	if c := byPath["/tmp/gone"]; c.Size != nil {
+		t.Errorf("Expected no size for a vanished file, got %+v", c)
+	}
+	if stats != 5 {
+		t.Errorf("Expected 5 stat lookups without deleted files, got %d", stats)
+	}
+
+	stats = 0
+	if _, diff := get("/api/v1/containers/web/diff?sizes=false"); stats != 0 || diff.Size != 0 || len(diff.Changes) != 6 {
+		t.Errorf("Expected no size lookups, got %d", stats)
+	}
+
+	if rec, _ := get("/api/v1/containers/missing/diff"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
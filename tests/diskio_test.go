@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+)
+
+func TestParseDiskStats(t *testing.T) {
+	counters := api.ParseDiskStats(`   7       0 loop0 10 0 80 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+ 259       0 nvme0n1 5000 10 200000 900 3000 20 100000 1200 0 2500 2100 0 0 0 0 0 0
+ 259       1 nvme0n1p1 4000 10 150000 800 2000 20 60000 1000 0 2000 1800
+ 179       0 mmcblk0 1 2 3
+`)
+	if len(counters) != 2 {
+		t.Fatalf("Expected nvme0n1 and nvme0n1p1, got %+v", counters)
+	}
+	expected := api.DiskCounters{ReadBytes: 200000 * 512, WrittenBytes: 100000 * 512, IOTime: 2500 * time.Millisecond}
+	if counters["nvme0n1"] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, counters["nvme0n1"])
+	}
+}
+
+func TestDiskIOSampler(t *testing.T) {
+	var mu sync.Mutex
+	counters := map[string]api.DiskCounters{"sda": {}}
+	sampler := api.NewDiskIOSampler(func() map[string]api.DiskCounters {
+		mu.Lock()
+		defer mu.Unlock()
+		read := make(map[string]api.DiskCounters, len(counters))
+		for device, c := range counters {
+			read[device] = c
+		}
+		return read
+	})
+	advance := func(device string, read, written uint64, busy time.Duration) {
+		mu.Lock()
+		c := counters[device]
+		c.ReadBytes += read
+		c.WrittenBytes += written
+		c.IOTime += busy
+		counters[device] = c
+		mu.Unlock()
+	}
+
+	// The first read measures a short window instead of returning nothing
+	advance("sda", 1<<20, 0, 0)
+	start := time.Now()
+	usage := sampler.Usage()
+	if time.Since(start) < 50*time.Millisecond || usage["sda"].ReadBytesPerSec == 0 {
+		t.Fatalf("Expected a measured first read, got %+v", usage)
+	}
+
+	sampler.Sample()
+	time.Sleep(200 * time.Millisecond)
+	advance("sda", 0, 10<<20, time.Second) // busy longer than the window
+	mu.Lock()
+	counters["sdb"] = api.DiskCounters{ReadBytes: 1 << 30}
+	mu.Unlock()
+	usage = sampler.Sample()
+	sda := usage["sda"]
+	if sda.ReadBytesPerSec != 0 || sda.WriteBytesPerSec < 5<<20 || sda.WriteBytesPerSec > 50<<20 || sda.Utilization != 100 {
+		t.Errorf("Unexpected sda throughput: %+v", sda)
+	}
+	if _, ok := usage["sdb"]; ok {
+		t.Errorf("Expected no throughput for a new device before its second reading")
+	}
+
+	// Readers get the last sample; a reset counter counts as no I/O
+	mu.Lock()
+	counters["sda"] = api.DiskCounters{}
+	mu.Unlock()
+	if sampler.Usage()["sda"] != sda {
+		t.Errorf("Expected the last sample for readers")
+	}
+	if usage := sampler.Sample()["sda"]; usage.Utilization != 0 {
+		t.Errorf("Expected no utilization after a counter reset, got %+v", usage)
+	}
+}
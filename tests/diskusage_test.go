@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestContainerDiskUsage(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "ctr.log")
+	if err := os.WriteFile(logPath, make([]byte, 5000), 0600); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/system/df", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Containers": [
+			{"ContainerID": "small-id", "Names": "small", "Status": "exited", "Size": 9000, "RWSize": 1000},
+			{"ContainerID": "chatty-id", "Names": "chatty", "Status": "running", "Size": 9000, "RWSize": 2000},
+			{"ContainerID": "big-id", "Names": "big", "Status": "running", "Size": 90000, "RWSize": 10000},
+			{"ContainerID": "gone-id", "Names": "gone", "Status": "exited", "Size": 100, "RWSize": 100}
+		]}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"store": {"graphRoot": "/var/lib/containers/storage"}}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "chatty-id":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"Id": "chatty-id", "Name": "chatty",
+				"HostConfig": map[string]interface{}{"LogConfig": map[string]string{"Type": "k8s-file", "Path": logPath}},
+			})
+		case "big-id", "small-id":
+			fmt.Fprintf(w, `{"Id": %q, "Name": "/%s", "HostConfig": {"LogConfig": {"Type": "journald"}}}`, r.PathValue("id"), strings.TrimSuffix(r.PathValue("id"), "-id"))
+		default:
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+		}
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/df/containers", nil))
+	var result struct {
+		Writable   int64               `json:"writable"`
+		Logs       int64               `json:"logs"`
+		GraphRoot  string              `json:"graphRoot"`
+		Containers []api.ContainerDisk `json:"containers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected disk usage, got %d %q", rec.Code, rec.Body)
+	}
+
+	var order string
+	for _, c := range result.Containers {
+		order += c.Name + ":" + strconv.FormatInt(c.Total, 10) + " "
+	}
+	if order != "big:10000 chatty:7000 small:1000 gone:100 " {
+		t.Errorf("Unexpected containers %s", order)
+	}
+	if chatty := result.Containers[1]; chatty != (api.ContainerDisk{ID: "chatty-id", Name: "chatty", Status: "running", Writable: 2000, LogDriver: "k8s-file", LogSize: 5000, Total: 7000}) {
+		t.Errorf("Unexpected usage %+v", chatty)
+	}
+	if result.Writable != 13100 || result.Logs != 5000 || result.GraphRoot != "/var/lib/containers/storage" {
+		t.Errorf("Unexpected totals %+v", result)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrainEventStreams(t *testing.T) {
+	server := newTestServer(t, nil)
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/events/stream")
+	if err != nil {
+		t.Fatalf("Stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	// Open streams end when the server starts shutting down
+	server.CloseConnections()
+	ended := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, resp.Body)
+		close(ended)
+	}()
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream was not closed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.WaitConnections(ctx); err != nil {
+		t.Fatalf("WaitConnections failed: %v", err)
+	}
+
+	// New streams are rejected, other requests keep working until the
+	// listener is closed
+	resp, err = http.Get(ts.URL + "/api/v1/events/stream")
+	if err != nil {
+		t.Fatalf("Stream request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while shutting down, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get(ts.URL + "/api/v1/health")
+	if err != nil {
+		t.Fatalf("Health request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for health, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+func TestContainerEnv(t *testing.T) {
+	var calls []string
+	var created []podman.ContainerCreateConfig
+	failCreate := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "web" {
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+			"Id": "0123456789abcdef", "Name": "web", "Image": "img1", "ImageName": "docker.io/library/nginx:1.27",
+			"State": {"Status": "running", "Running": true},
+			"Config": {"Hostname": "0123456789ab", "Env": ["PATH=/usr/bin", "HOSTNAME=0123456789ab", "DB_PASSWORD=hunter2", "api_token=abc", "MODE=prod"]},
+			"HostConfig": {"PortBindings": {"80/tcp": [{"HostPort": "8080"}]}}
+		}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/images/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "img1", "Config": {"Env": ["PATH=/usr/bin"]}}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "stop "+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "remove "+r.PathValue("id"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		calls = append(calls, "create")
+		created = append(created, config)
+		if failCreate && len(created) == 1 {
+			http.Error(w, `{"message": "invalid config"}`, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "fedcba9876543210"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "start "+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cfg := newTestConfig(t, "PODMANVIEW_SECRET_ENV=*password*,*TOKEN\n")
+	store := events.NewStore(10)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withConfig(cfg), withEvents(store))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/api/v1/containers/web/env", "")
+	var result struct {
+		Env []api.EnvVar `json:"env"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected env, got %d %q", rec.Code, rec.Body)
+	}
+	want := []api.EnvVar{
+		{Name: "DB_PASSWORD", Value: "********", Secret: true},
+		{Name: "MODE", Value: "prod"},
+		{Name: "PATH", Value: "/usr/bin", Inherited: true},
+		{Name: "api_token", Value: "********", Secret: true},
+	}
+	if len(result.Env) != len(want) {
+		t.Fatalf("Expected %d variables, got %+v", len(want), result.Env)
+	}
+	for i := range want {
+		if result.Env[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], result.Env[i])
+		}
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("Secret value leaked: %s", rec.Body)
+	}
+
+	rec = request(http.MethodPut, "/api/v1/containers/web/env", `{"env": {"PATH": "/usr/bin", "DB_PASSWORD": "********", "MODE": "dev", "NEW": "1"}}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"started"`) {
+		t.Fatalf("Expected the container to be recreated, got %d %q", rec.Code, rec.Body)
+	}
+	if got := strings.Join(calls, ","); got != "stop 0123456789abcdef,remove 0123456789abcdef,create,start fedcba9876543210" {
+		t.Errorf("Unexpected calls %s", got)
+	}
+	config := created[0]
+	if len(config.Env) != 3 || config.Env["DB_PASSWORD"] != "hunter2" || config.Env["MODE"] != "dev" || config.Env["NEW"] != "1" {
+		t.Errorf("Unexpected env %v", config.Env)
+	}
+	if config.Name != "web" || config.Image != "docker.io/library/nginx:1.27" || len(config.PortMappings) != 1 {
+		t.Errorf("Expected the spec to be kept, got %+v", config)
+	}
+
+	// A failed create restores the original container
+	calls, created, failCreate = nil, nil, true
+	rec = request(http.MethodPut, "/api/v1/containers/web/env", `{"env": {"MODE": "dev"}}`)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502, got %d %q", rec.Code, rec.Body)
+	}
+	if len(created) != 2 || created[1].Env["DB_PASSWORD"] != "hunter2" || created[1].Env["MODE"] != "prod" {
+		t.Errorf("Expected the original to be restored, got %+v", created)
+	}
+	if calls[len(calls)-1] != "start fedcba9876543210" {
+		t.Errorf("Expected the restored container to be started, got %v", calls)
+	}
+
+	calls = nil
+	for body, status := range map[string]int{
+		`{"env": {"BAD NAME": "x"}}`:     http.StatusBadRequest,
+		`{"env": {"OTHER": "********"}}`: http.StatusBadRequest,
+		`not json`:                       http.StatusBadRequest,
+	} {
+		if rec := request(http.MethodPut, "/api/v1/containers/web/env", body); rec.Code != status {
+			t.Errorf("Expected %d for %s, got %d", status, body, rec.Code)
+		}
+	}
+	if len(calls) != 0 {
+		t.Errorf("Expected no changes for invalid requests, got %v", calls)
+	}
+	if rec := request(http.MethodGet, "/api/v1/containers/missing/env", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+
+	if last := store.GetLast(10); len(last) != 2 || last[0].Type != events.EventContainerEnv || last[0].Success || !last[1].Success {
+		t.Errorf("Unexpected events %+v", last)
+	}
+}
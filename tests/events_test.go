@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"podmanview/internal/events"
+)
+
+func TestEventRetentionArchive(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	archiver, err := events.NewFileArchiver(dir)
+	if err != nil {
+		t.Fatalf("NewFileArchiver failed: %v", err)
+	}
+
+	store := events.NewStore(10)
+	store.SetArchiver(archiver)
+	store.SetRetention(3, time.Hour)
+
+	// Restored events keep their timestamps, so they can be expired
+	old := time.Now().Add(-2 * time.Hour)
+	store.Restore([]events.Event{
+		{ID: 1, Type: events.EventLogin, Timestamp: old, Username: "alice"},
+		{ID: 2, Type: events.EventLogout, Timestamp: old, Username: "alice"},
+	})
+	store.Add(events.EventLogin, "bob", "127.0.0.1", true, "")
+	store.Add(events.EventLogin, "carol", "127.0.0.1", true, "") // evicts event 1
+
+	expired, err := store.Prune()
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if expired != 1 {
+		t.Errorf("Expected 1 expired event, got %d", expired)
+	}
+	if got := store.GetLast(10); len(got) != 2 || got[1].Username != "bob" {
+		t.Errorf("Unexpected events after prune: %+v", got)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := store.GetLast(10); len(got) != 0 {
+		t.Errorf("Expected no events after close, got %d", len(got))
+	}
+
+	// Archive files have one gzip member per write
+	var archived []events.Event
+	files, _ := filepath.Glob(filepath.Join(dir, "events-*.jsonl.gz"))
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open archive: %v", err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			var e events.Event
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				t.Fatalf("Invalid archived event: %v", err)
+			}
+			archived = append(archived, e)
+		}
+		f.Close()
+	}
+
+	if len(archived) != 4 {
+		t.Fatalf("Expected 4 archived events, got %d", len(archived))
+	}
+	seen := make(map[int64]bool)
+	for _, e := range archived {
+		seen[e.ID] = true
+	}
+	for id := int64(1); id <= 4; id++ {
+		if !seen[id] {
+			t.Errorf("Event %d was not archived", id)
+		}
+	}
+}
+
+func TestEventQuery(t *testing.T) {
+	store := events.NewStore(100)
+	store.Add(events.EventLogin, "alice", "10.0.0.1", true, "")
+	store.Add(events.EventContainerStart, "alice", "10.0.0.1", true, "nginx-proxy")
+	store.Add(events.EventContainerStop, "bob", "10.0.0.2", false, "Nginx: permission denied")
+	store.Add(events.EventContainerStart, "bob", "10.0.0.2", true, "postgres")
+	store.Add(events.EventLoginFailed, "mallory", "10.0.0.9", false, "")
+
+	failed := false
+	tests := []struct {
+		name  string
+		query events.Query
+		ids   []int64
+	}{
+		{"type prefix", events.Query{TypePrefix: "container_", Limit: 10}, []int64{4, 3, 2}},
+		{"user", events.Query{Username: "alice", Limit: 10}, []int64{2, 1}},
+		{"failures", events.Query{Success: &failed, Limit: 10}, []int64{5, 3}},
+		{"search details", events.Query{Search: "NGINX", Limit: 10}, []int64{3, 2}},
+		{"search all words", events.Query{Search: "nginx denied", Limit: 10}, []int64{3}},
+		{"time range", events.Query{From: time.Now().Add(time.Hour), Limit: 10}, nil},
+		{"cursor", events.Query{Before: 3, Limit: 10}, []int64{2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := store.Query(tt.query)
+			if len(got) != len(tt.ids) {
+				t.Fatalf("Expected %d events, got %+v", len(tt.ids), got)
+			}
+			for i, id := range tt.ids {
+				if got[i].ID != id {
+					t.Errorf("Expected event %d at %d, got %d", id, i, got[i].ID)
+				}
+			}
+		})
+	}
+
+	// Page through all events two at a time
+	var ids []int64
+	q := events.Query{Limit: 2}
+	for {
+		page, next := store.Query(q)
+		for _, e := range page {
+			ids = append(ids, e.ID)
+		}
+		if next == 0 {
+			break
+		}
+		q.Before = next
+	}
+	if len(ids) != 5 || ids[0] != 5 || ids[4] != 1 {
+		t.Errorf("Expected all 5 events newest first, got %v", ids)
+	}
+}
+
+func TestEventSubscribe(t *testing.T) {
+	store := events.NewStore(10)
+
+	ch, unsubscribe := store.Subscribe(1)
+	store.Add(events.EventLogin, "alice", "10.0.0.1", true, "first")
+	store.Add(events.EventLogin, "bob", "10.0.0.2", true, "dropped, buffer full")
+
+	select {
+	case event := <-ch:
+		if event.Username != "alice" || event.ID != 1 {
+			t.Errorf("Expected first event from alice, got %+v", event)
+		}
+	default:
+		t.Fatal("Expected an event on the subscription")
+	}
+	select {
+	case event := <-ch:
+		t.Errorf("Expected the second event to be dropped, got %+v", event)
+	default:
+	}
+
+	// A slow subscriber catches up from the store
+	if missed := store.GetSince(1); len(missed) != 1 || missed[0].Username != "bob" {
+		t.Errorf("Expected GetSince to return the dropped event, got %+v", missed)
+	}
+
+	unsubscribe()
+	unsubscribe() // safe to call twice
+	if _, ok := <-ch; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+	store.Add(events.EventLogout, "alice", "10.0.0.1", true, "")
+}
+
+func TestEventCatalog(t *testing.T) {
+	store := events.NewStore(10)
+	store.Add(events.EventContainerStart, "alice", "10.0.0.1", true, "nginx")
+	store.Add(events.EventContainerStart, "alice", "10.0.0.1", false, "redis")
+	store.Add(events.EventDiskFull, "system", "", false, "/ is 95% full")
+	store.AddWithSeverity(events.EventAlertFired, events.SeverityCritical, "system", "", false, "cpu")
+	store.Add("plugin_custom", "bob", "", true, "")
+
+	want := []struct {
+		category events.Category
+		severity events.Severity
+	}{
+		{events.CategoryOther, events.SeverityInfo},
+		{events.CategoryAlert, events.SeverityCritical},
+		{events.CategorySystem, events.SeverityCritical},
+		{events.CategoryContainer, events.SeverityError}, // failed info event
+		{events.CategoryContainer, events.SeverityInfo},
+	}
+	got := store.GetLast(10) // newest first
+	for i, w := range want {
+		if got[i].Category != w.category || got[i].Severity != w.severity {
+			t.Errorf("Event %d: expected %s/%s, got %s/%s", got[i].ID, w.category, w.severity, got[i].Category, got[i].Severity)
+		}
+	}
+
+	severe, _ := store.Query(events.Query{Severity: events.SeverityError, Limit: 10})
+	if len(severe) != 3 {
+		t.Errorf("Expected 3 events of severity error or higher, got %d", len(severe))
+	}
+	containers, _ := store.Query(events.Query{Category: events.CategoryContainer, Limit: 10})
+	if len(containers) != 2 {
+		t.Errorf("Expected 2 container events, got %d", len(containers))
+	}
+
+	// Every event type constant is in the catalog
+	for _, info := range events.Types() {
+		if info.Label == "" || info.Severity.Level() < 0 {
+			t.Errorf("Incomplete catalog entry %+v", info)
+		}
+	}
+	if _, ok := events.Lookup(events.EventFileWrite); !ok {
+		t.Error("Expected file_write in the catalog")
+	}
+
+	// Restored events from before the catalog are classified
+	store.Restore([]events.Event{{ID: 20, Type: events.EventLoginFailed, Success: false}})
+	if restored := store.GetLast(1); restored[0].Severity != events.SeverityWarning || restored[0].Category != events.CategoryAuth {
+		t.Errorf("Expected restored login_failed to be auth/warning, got %+v", restored[0])
+	}
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/plugins"
+	"podmanview/internal/plugins/fileshare"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestFileSharePlugin(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared")
+	os.MkdirAll(filepath.Join(shared, "sub"), 0755)
+	os.WriteFile(filepath.Join(shared, "a.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(shared, "sub", "b.txt"), []byte("nested"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644)
+	if err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(shared, "escape.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.SetPluginConfig("fileshare", &storage.PluginConfig{Enabled: true, Name: "File Sharing"}); err != nil {
+		t.Fatalf("Failed to enable plugin: %v", err)
+	}
+
+	newServer := func() *api.Server {
+		plugin := fileshare.New()
+		deps := &plugins.PluginDependencies{Storage: store, Logger: log.New(io.Discard, "", 0)}
+		if err := plugin.Init(context.Background(), deps); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+		client := podman.NewClientWithHandler(http.NewServeMux())
+		return newTestServer(t, client, withPlugins(plugin), withStorage(store))
+	}
+	server := newServer()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{"name": "docs", "path": "` + shared + `"}`, http.StatusCreated},
+		{`{"name": "private", "path": "` + shared + `", "password": "pw"}`, http.StatusCreated},
+		{`{"name": "docs", "path": "` + shared + `"}`, http.StatusConflict},
+		{`{"name": "docs"`, http.StatusBadRequest},
+		{`{"name": "Bad Name", "path": "` + shared + `"}`, http.StatusBadRequest},
+		{`{"name": "relative", "path": "shared"}`, http.StatusBadRequest},
+		{`{"name": "file", "path": "` + filepath.Join(shared, "a.txt") + `"}`, http.StatusBadRequest},
+		{`{"name": "missing", "path": "` + filepath.Join(dir, "missing") + `"}`, http.StatusNotFound},
+	} {
+		if rec := do(http.MethodPost, "/api/plugins/fileshare/shares", tc.body); rec.Code != tc.want {
+			t.Errorf("POST %s: expected %d, got %d: %s", tc.body, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	var shares []fileshare.ShareInfo
+	rec := do(http.MethodGet, "/api/plugins/fileshare/shares", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &shares); err != nil || len(shares) != 2 {
+		t.Fatalf("Expected 2 shares, got %d %s", rec.Code, rec.Body.String())
+	}
+	if shares[0].Name != "docs" || shares[0].URL != "/share/docs/" || shares[0].Protected || !shares[1].Protected {
+		t.Errorf("Unexpected shares %+v", shares)
+	}
+	if strings.Contains(rec.Body.String(), `"pw"`) {
+		t.Error("The password must not be listed")
+	}
+
+	if rec := do(http.MethodGet, "/share/docs", ""); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "docs/" {
+		t.Errorf("Expected a redirect to docs/, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := do(http.MethodGet, "/share/docs/", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "a.txt") || !strings.Contains(rec.Body.String(), "sub/") {
+		t.Errorf("Expected a listing, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/share/docs/sub/b.txt", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "nested" {
+		t.Errorf("Expected the file, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Errorf("Expected a sandbox policy, got %q", rec.Header().Get("Content-Security-Policy"))
+	}
+	// Symlinks can't leave the shared directory
+	if rec := do(http.MethodGet, "/share/docs/escape.txt", ""); rec.Code == http.StatusOK || strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("Expected the symlink to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/share/docs/a.txt", ""); rec.Code == http.StatusOK {
+		t.Error("Shares must be read-only")
+	}
+
+	if rec := do(http.MethodGet, "/share/private/a.txt", ""); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected 401 with a challenge, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/share/private/a.txt", nil)
+	req.SetBasicAuth("anyone", "pw")
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+		t.Errorf("Expected the file with the password, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Shares are kept in storage
+	server = newServer()
+	if rec := do(http.MethodGet, "/share/docs/a.txt", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the stored share, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/plugins/fileshare/shares/docs", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/share/docs/a.txt", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after removal, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/plugins/fileshare/shares/docs", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+
+	// Disabled plugins serve nothing
+	store.DisablePlugin("fileshare")
+	if rec := do(http.MethodGet, "/share/private/a.txt", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestContainerGraph(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "infra1", "Names": ["shop-infra"], "State": "running", "Pod": "pod1", "PodName": "shop", "IsInfra": true, "Networks": ["shop"]},
+			{"Id": "web1", "Names": ["web"], "Image": "nginx", "State": "running", "Networks": ["podman", "shop"],
+			 "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.depends_on": "api:service_started:false,missing:service_started:false"}},
+			{"Id": "api1", "Names": ["api"], "Image": "api", "State": "running", "Pod": "pod1", "PodName": "shop", "Networks": ["shop"],
+			 "Labels": {"io.podman.compose.project": "shop", "com.docker.compose.service": "api", "com.docker.compose.depends_on": "db:service_healthy:false"}},
+			{"Id": "db1", "Names": ["db"], "Image": "postgres", "State": "exited", "Pod": "pod1", "PodName": "shop",
+			 "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "db"}},
+			{"Id": "backup1", "Names": ["backup"], "Image": "restic", "State": "exited"}
+		]`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "db1", "backup1":
+			w.Write([]byte(`{"Mounts": [{"Type": "volume", "Name": "pgdata", "Source": "/var/lib/containers/storage/volumes/pgdata/_data"}, {"Type": "bind", "Source": "/srv"}]}`))
+		default:
+			w.Write([]byte(`{"Mounts": []}`))
+		}
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers/graph", nil))
+	var graph api.ContainerGraph
+	if err := json.Unmarshal(rec.Body.Bytes(), &graph); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected graph, got %d %q", rec.Code, rec.Body)
+	}
+
+	nodes := make(map[string]api.GraphNode)
+	for _, n := range graph.Nodes {
+		nodes[n.ID] = n
+	}
+	for _, id := range []string{
+		"container:web1", "container:api1", "container:db1", "container:backup1",
+		"pod:pod1", "network:podman", "network:shop", "volume:pgdata", "project:shop",
+	} {
+		if _, ok := nodes[id]; !ok {
+			t.Errorf("Missing node %s", id)
+		}
+	}
+	if _, ok := nodes["container:infra1"]; ok || len(nodes) != 9 {
+		t.Errorf("Expected 9 nodes without the infra container, got %v", graph.Nodes)
+	}
+	if n := nodes["pod:pod1"]; n.Name != "shop" || n.Type != "pod" {
+		t.Errorf("Unexpected pod node %+v", n)
+	}
+	if n := nodes["container:db1"]; n.Name != "db" || n.State != "exited" || n.Image != "postgres" {
+		t.Errorf("Unexpected container node %+v", n)
+	}
+
+	edges := make(map[api.GraphEdge]bool)
+	for _, e := range graph.Edges {
+		edges[e] = true
+	}
+	for _, e := range []api.GraphEdge{
+		{Source: "container:web1", Target: "network:podman", Type: "network"},
+		{Source: "container:web1", Target: "network:shop", Type: "network"},
+		{Source: "container:api1", Target: "pod:pod1", Type: "pod"},
+		{Source: "container:db1", Target: "volume:pgdata", Type: "volume"},
+		{Source: "container:backup1", Target: "volume:pgdata", Type: "volume"},
+		{Source: "container:api1", Target: "project:shop", Type: "project"},
+		{Source: "container:web1", Target: "container:api1", Type: "depends_on"},
+		{Source: "container:api1", Target: "container:db1", Type: "depends_on"},
+	} {
+		if !edges[e] {
+			t.Errorf("Missing edge %+v", e)
+		}
+	}
+	if len(graph.Edges) != 12 {
+		t.Errorf("Expected 12 edges, got %d: %v", len(graph.Edges), graph.Edges)
+	}
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/events"
+)
+
+func TestGraphQL(t *testing.T) {
+	cfg := newTestConfig(t, "PODMANVIEW_GRAPHQL=true\n")
+	store := events.NewStore(10)
+	server := newTestServer(t, fakePodman(t), withConfig(cfg), withEvents(store))
+
+	post := func(body string) string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		server.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %q", rec.Code, rec.Body)
+		}
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	// Only the selected fields are returned, in selection order
+	got := post(`{
+		"query": "query Running($state: [String!]) { running: containers(status: $state, sort: \"name\") { ...C } host { __typename } } fragment C on Container { id name labels { key value } }",
+		"variables": {"state": "running"}
+	}`)
+	expected := `{"data":{"running":[` +
+		`{"id":"c3","name":"api","labels":[{"key":"app","value":"api"},{"key":"tier","value":"back"}]},` +
+		`{"id":"a1","name":"web","labels":[{"key":"app","value":"web"},{"key":"tier","value":"front"}]}],` +
+		`"host":{"__typename":"Host"}}}`
+	if got != expected {
+		t.Errorf("Unexpected result:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	got = post(`{"query": "{ container(id: \"web\") { state created stats { cpu } } missing: container(id: \"none\") { id } }"}`)
+	expected = `{"data":{"container":{"state":"running","created":"2026-01-03T00:00:00Z","stats":{"cpu":5}},"missing":null}}`
+	if got != expected {
+		t.Errorf("Unexpected result:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	// Invalid queries are rejected before execution
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	for query, message := range map[string]string{
+		`{ containers { nope } }`:            `Cannot query field "nope" on type "Container".`,
+		`{ containers }`:                     `Field "containers" of type "[Container!]!" must have a selection of subfields.`,
+		`{ container { id } }`:               `Field "container" argument "id" of type "ID!" is required, but it was not provided.`,
+		`{ events(limit: "ten") { id } }`:    `Argument "limit" has invalid value: expected Int, got ten`,
+		`subscription { host { cpuUsage } }`: `Subscriptions must be requested as an event stream (Accept: text/event-stream).`,
+		`{ containers { id }`:                `Syntax Error: Unexpected <EOF>.`,
+	} {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		if err := json.Unmarshal([]byte(post(string(body))), &resp); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		if len(resp.Errors) != 1 || resp.Errors[0].Message != message || resp.Data != nil {
+			t.Errorf("%s: expected error %q, got %+v", query, message, resp)
+		}
+	}
+
+	// Fragments are validated and collected once, however often they are
+	// spread
+	var fragments strings.Builder
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&fragments, " fragment F%d on Host { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	start := time.Now()
+	body, _ := json.Marshal(map[string]string{"query": "{ host { ...F0 } }" + fragments.String() + " fragment F40 on Host { __typename }"})
+	if got := post(string(body)); got != `{"data":{"host":{"__typename":"Host"}}}` {
+		t.Errorf("Unexpected result %s", got)
+	}
+	body, _ = json.Marshal(map[string]string{"query": "{ host { ...F0 } }" + fragments.String() + " fragment F40 on Host { nope }"})
+	if err := json.Unmarshal([]byte(post(string(body))), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != `Cannot query field "nope" on type "Host".` {
+		t.Errorf("Expected one error, got %+v", resp.Errors)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Repeated fragments took %v", elapsed)
+	}
+
+	// The schema is published as SDL
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/graphql/schema", nil))
+	if !strings.HasPrefix(rec.Body.String(), "type Query {") || !strings.Contains(rec.Body.String(), "type Subscription {") {
+		t.Errorf("Unexpected schema: %q", rec.Body)
+	}
+}
+
+func TestGraphQLSubscription(t *testing.T) {
+	cfg := newTestConfig(t, "PODMANVIEW_GRAPHQL=true\n")
+	store := events.NewStore(10)
+	server := newTestServer(t, nil, withConfig(cfg), withEvents(store))
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+
+	query := url.QueryEscape(`subscription { events(type: "login") { type username } }`)
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/graphql?query="+query, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected event stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	store.Add(events.EventLogout, "alice", "127.0.0.1", true, "")
+	store.Add(events.EventLogin, "bob", "127.0.0.1", true, "")
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+				lines <- strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	select {
+	case line := <-lines:
+		if expected := `{"data":{"events":{"type":"login","username":"bob"}}}`; line != expected {
+			t.Errorf("Expected %s, got %s", expected, line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event received")
+	}
+}
+
+func TestGraphQLDisabled(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(`{"query": "{ host { cpuUsage } }"}`)))
+	if rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GraphQL to be disabled by default, got %d", rec.Code)
+	}
+}
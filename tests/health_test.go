@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/config"
+	"podmanview/internal/plugins"
+	"podmanview/internal/plugins/temperature"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// unreachablePodman returns a client whose socket no longer exists
+func unreachablePodman(t *testing.T) *podman.Client {
+	dir, err := os.MkdirTemp("", "podman")
+	if err != nil {
+		t.Fatalf("MkdirTemp failed: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	client, err := podman.NewClientWithSocket(socket)
+	if err != nil {
+		t.Fatalf("NewClientWithSocket failed: %v", err)
+	}
+	listener.Close()
+	return client
+}
+
+func TestHealthProbes(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store, err := storage.Open(storage.BackendBolt, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+	if err := store.EnablePlugin("temperature"); err != nil {
+		t.Fatalf("EnablePlugin failed: %v", err)
+	}
+	registry := plugins.NewRegistry()
+	if err := registry.Register(temperature.New()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	probe := func(server *api.Server, path string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid JSON %q: %v", path, rec.Body, err)
+		}
+		return rec.Code, body
+	}
+
+	// Enabled plugin not running yet
+	server := newTestServer(t, fakePodman(t), withConfig(cfg), withRegistry(registry), withStorage(store))
+	code, body := probe(server, "/readyz")
+	if code != http.StatusOK || body["status"] != "degraded" {
+		t.Errorf("Expected 200 degraded, got %d %v", code, body)
+	}
+
+	registry.SetRunning("temperature", true)
+	code, body = probe(server, "/readyz")
+	checks, _ := body["checks"].(map[string]interface{})
+	if code != http.StatusOK || body["status"] != "ok" || checks["podman"] != "ok" || checks["storage"] != "ok" || checks["plugins"] != "ok" {
+		t.Errorf("Expected all checks ok, got %d %v", code, body)
+	}
+	if _, ok := body["version"]; ok {
+		t.Error("Probes must not report the version")
+	}
+
+	// Podman down: not ready, but still live
+	server = newTestServer(t, unreachablePodman(t), withConfig(cfg), withRegistry(registry), withStorage(store))
+	code, body = probe(server, "/readyz")
+	checks, _ = body["checks"].(map[string]interface{})
+	if code != http.StatusServiceUnavailable || body["status"] != "fail" || checks["podman"] != "fail" {
+		t.Errorf("Expected 503 with podman fail, got %d %v", code, body)
+	}
+	code, body = probe(server, "/healthz")
+	if code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("Expected healthz 200 ok, got %d %v", code, body)
+	}
+
+	// Shutting down
+	server.CloseConnections()
+	code, body = probe(server, "/readyz")
+	checks, _ = body["checks"].(map[string]interface{})
+	if code != http.StatusServiceUnavailable || checks["server"] != "fail" {
+		t.Errorf("Expected 503 while shutting down, got %d %v", code, body)
+	}
+}
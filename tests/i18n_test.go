@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"podmanview/internal/apierror"
+	"podmanview/internal/config"
+	"podmanview/internal/i18n"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"ru", "ru"},
+		{"ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7", "ru"},
+		{"en-US,en;q=0.9,ru;q=0.8", "en"},
+		{"de-DE,ru;q=0.5", "ru"},
+		{"ru;q=0.2,en;q=0.8", "en"},
+		{"ru;q=0", "en"},
+		{"fr, *;q=0.5", "en"},
+		{"RU-ru", "ru"},
+	}
+	for _, tt := range tests {
+		if got := i18n.Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+
+	if langs := i18n.Languages(); len(langs) < 2 || langs[0] != "en" {
+		t.Errorf("Expected en first in %v", langs)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := i18n.T("ru", "Plugin not found"); got != "Плагин не найден" {
+		t.Errorf("Unexpected translation %q", got)
+	}
+	if got := i18n.T("ru", "Failed to save keys: permission denied"); got != "Не удалось сохранить ключи: permission denied" {
+		t.Errorf("Expected the prefix to be translated, got %q", got)
+	}
+	if got := i18n.T("ru", "Something new"); got != "Something new" {
+		t.Errorf("Expected missing messages unchanged, got %q", got)
+	}
+	if got := i18n.T("en", "Plugin not found"); got != "Plugin not found" {
+		t.Errorf("Expected English unchanged, got %q", got)
+	}
+
+	html := `<h1 title="Back to Plugins">
+    Temperature Monitoring
+</h1><button>Refresh</button><span>Custom</span><script>el.textContent = x>1 ? "a" :"b";const s = "<b>Refresh</b>";</script>`
+	want := `<h1 title="К плагинам">
+    Мониторинг температуры
+</h1><button>Обновить</button><span>Custom</span><script>el.textContent = x>1 ? "a" :"b";const s = "<b>Refresh</b>";</script>`
+	if got := i18n.TranslateHTML("ru", html); got != want {
+		t.Errorf("Unexpected HTML translation:\n%s", got)
+	}
+	if got := i18n.TranslateHTML("en", html); got != html {
+		t.Errorf("Expected English HTML unchanged, got %s", got)
+	}
+}
+
+func TestAPIErrorLanguage(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	server := newTestServer(t, fakePodman(t), withConfig(cfg))
+
+	request := func(lang string) (*httptest.ResponseRecorder, apierror.Response) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/containers", nil)
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		var resp apierror.Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid error response %q: %v", rec.Body, err)
+		}
+		return rec, resp
+	}
+
+	rec, resp := request("ru-RU,ru;q=0.9")
+	if rec.Code != http.StatusUnauthorized || resp.Message != "Требуется авторизация" || resp.Error != resp.Message {
+		t.Errorf("Expected Russian message, got %d %+v", rec.Code, resp)
+	}
+	if resp.Code != apierror.CodeUnauthorized {
+		t.Errorf("Expected untranslated code, got %q", resp.Code)
+	}
+	if rec.Header().Get("Content-Language") != "ru" || rec.Header().Get("Vary") == "" {
+		t.Errorf("Expected Content-Language and Vary headers, got %v", rec.Header())
+	}
+
+	rec, resp = request("")
+	if resp.Message != "Unauthorized" || rec.Header().Get("Content-Language") != "en" {
+		t.Errorf("Expected English message, got %+v", resp)
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"podmanview/internal/idle"
+	"podmanview/internal/podman"
+)
+
+func TestIdleMonitor(t *testing.T) {
+	if m := idle.New(0); m != nil || m.Idle() || m.Interval(time.Second) != time.Second {
+		t.Fatalf("Expected a disabled monitor to never be idle")
+	}
+
+	m := idle.New(50 * time.Millisecond)
+	if m.Idle() {
+		t.Fatalf("Expected a new monitor to be active")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !m.Idle() || m.Interval(time.Second) != idle.Slowdown*time.Second {
+		t.Fatalf("Expected the monitor to be idle with stretched intervals")
+	}
+
+	// A client connecting wakes sleepers at once
+	woken := m.Woken()
+	done := make(chan bool)
+	go func() { done <- m.Sleep(context.Background(), time.Hour) }()
+	time.Sleep(10 * time.Millisecond)
+	m.Begin()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Sleep to end when a client connects")
+	}
+	select {
+	case <-woken:
+	default:
+		t.Errorf("Expected the wake channel to be closed")
+	}
+
+	// Open requests keep the monitor active
+	time.Sleep(60 * time.Millisecond)
+	if m.Idle() {
+		t.Errorf("Expected an open request to keep the monitor active")
+	}
+	m.End()
+	if m.Idle() {
+		t.Errorf("Expected the monitor to be active right after a request")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if m.Sleep(ctx, time.Hour) {
+		t.Errorf("Expected Sleep to report a cancelled context")
+	}
+}
+
+func TestIdleRunPeriodic(t *testing.T) {
+	m := idle.New(30 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	go m.RunPeriodic(ctx, 10*time.Millisecond, func() { runs.Add(1) })
+
+	// Runs while active, not while idle
+	time.Sleep(100 * time.Millisecond)
+	before := runs.Load()
+	if before == 0 {
+		t.Fatalf("Expected runs while active")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if after := runs.Load(); after != before {
+		t.Fatalf("Expected no runs while idle, got %d more", after-before)
+	}
+
+	// A postponed run happens when a client connects
+	m.Begin()
+	defer m.End()
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() == before && time.Now().Before(deadline) {
+		time.Sleep(2 * time.Millisecond)
+	}
+	if runs.Load() == before {
+		t.Errorf("Expected the postponed run after a client connected")
+	}
+}
+
+func TestIdleHealth(t *testing.T) {
+	cfg := newTestConfig(t, "PODMANVIEW_IDLE_AFTER=5\n")
+	if cfg.IdleAfter() != 5*time.Minute {
+		t.Errorf("Expected 5 minutes, got %v", cfg.IdleAfter())
+	}
+
+	server := newTestServer(t, podman.NewClientWithHandler(http.NewServeMux()), withConfig(cfg))
+	server.SetIdleMonitor(idle.New(30 * time.Millisecond))
+
+	health := func() bool {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+		var resp struct {
+			Idle bool `json:"idle"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp.Idle
+	}
+
+	// Health checks of monitors don't count as clients
+	time.Sleep(40 * time.Millisecond)
+	if !health() || !health() {
+		t.Fatalf("Expected idle without clients")
+	}
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+	if health() {
+		t.Errorf("Expected a client request to end idle mode")
+	}
+}
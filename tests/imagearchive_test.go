@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+func TestImageExportImport(t *testing.T) {
+	var loaded []byte
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/images/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "nginx" {
+			http.Error(w, `{"message": "image not known"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Id": "abcdef0123456789", "RepoTags": ["docker.io/library/nginx:1.27"]}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/images/{id}/get", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "docker-archive" {
+			http.Error(w, "unexpected format", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("TARDATA"))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/images/load", func(w http.ResponseWriter, r *http.Request) {
+		loaded, _ = io.ReadAll(r.Body)
+		if len(loaded) == 0 {
+			http.Error(w, `{"message": "payload does not match any of the supported image formats"}`, http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"Names": ["docker.io/library/nginx:1.27"]}`))
+	})
+
+	store := events.NewStore(10)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withEvents(store))
+
+	request := func(method, path, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/api/v1/images/nginx/export", "", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "TARDATA" {
+		t.Fatalf("Expected archive, got %d %q", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Content-Type") != "application/x-tar" || rec.Header().Get("Content-Disposition") != `attachment; filename="nginx_1.27.tar"` {
+		t.Errorf("Unexpected headers %v", rec.Header())
+	}
+	if rec := request(http.MethodGet, "/api/v1/images/missing/export", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing image, got %d", rec.Code)
+	}
+
+	// Raw tar body
+	rec = request(http.MethodPost, "/api/v1/images/import", "application/x-tar", bytes.NewReader([]byte("RAWTAR")))
+	var result struct {
+		Images []string `json:"images"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK || len(result.Images) != 1 {
+		t.Fatalf("Expected loaded images, got %d %q", rec.Code, rec.Body)
+	}
+	if string(loaded) != "RAWTAR" {
+		t.Errorf("Expected the body to be passed on, got %q", loaded)
+	}
+
+	// Multipart upload
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	writer.WriteField("note", "ignored")
+	part, _ := writer.CreateFormFile("file", "nginx.tar")
+	part.Write([]byte("FORMTAR"))
+	writer.Close()
+	rec = request(http.MethodPost, "/api/v1/images/import", writer.FormDataContentType(), &form)
+	if rec.Code != http.StatusOK || string(loaded) != "FORMTAR" {
+		t.Errorf("Expected the file field to be loaded, got %d %q", rec.Code, loaded)
+	}
+
+	rec = request(http.MethodPost, "/api/v1/images/import", "application/x-tar", nil)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected the Podman error, got %d %q", rec.Code, rec.Body)
+	}
+
+	types := make(map[events.EventType]int)
+	for _, e := range store.GetLast(10) {
+		types[e.Type]++
+	}
+	if types[events.EventImageExport] != 1 || types[events.EventImageImport] != 3 {
+		t.Errorf("Unexpected events %v", types)
+	}
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestImageLayers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/images/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "app" {
+			http.Error(w, `{"message": "image not known"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Id": "abcdef0123456789"}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/images/{id}/history", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "abcdef0123456789", "Created": 1700000300, "CreatedBy": "/bin/sh -c #(nop)  CMD [\"./app\"]", "Tags": ["localhost/app:latest"], "Size": 0},
+			{"Id": "<missing>", "Created": 1700000200, "CreatedBy": "RUN /bin/sh -c make build # buildkit", "Size": 600},
+			{"Id": "<missing>", "Created": 1700000100, "CreatedBy": "|2 VERSION=1.2 TARGET=arm64 /bin/sh -c apk add --no-cache curl", "Size": 300},
+			{"Id": "<missing>", "Created": 1700000000, "CreatedBy": "/bin/sh -c #(nop) ADD file:0123abcd in / ", "Size": 100, "Comment": "base"}
+		]`))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/images/app/layers", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	var layers api.ImageLayers
+	if err := json.NewDecoder(rec.Body).Decode(&layers); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if layers.ID != "abcdef0123456789" || layers.Size != 1000 || layers.Layers != 3 || len(layers.History) != 4 {
+		t.Fatalf("Unexpected layers: %+v", layers)
+	}
+
+	expected := []struct {
+		id, command string
+		percent     float64
+		empty       bool
+	}{
+		{"abcdef0123456789", `CMD ["./app"]`, 0, true},
+		{"", "RUN make build", 60, false},
+		{"", "RUN apk add --no-cache curl", 30, false},
+		{"", "ADD file:0123abcd in /", 10, false},
+	}
+	for i, e := range expected {
+		layer := layers.History[i]
+		if layer.ID != e.id || layer.Command != e.command || layer.Percent != e.percent || layer.Empty != e.empty {
+			t.Errorf("Layer %d: expected %+v, got %+v", i, e, layer)
+		}
+	}
+	if layers.History[3].Comment != "base" || layers.History[0].Tags[0] != "localhost/app:latest" {
+		t.Errorf("Expected comments and tags, got %+v", layers.History)
+	}
+
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/images/missing/layers", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing image, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestPullPlatform(t *testing.T) {
+	// An image of another platform than the host, run under emulation
+	foreign := "arm64"
+	if runtime.GOARCH == foreign {
+		foreign = "amd64"
+	}
+
+	var pulls []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		pulls = append(pulls, strings.Join([]string{query.Get("reference"), query.Get("OS"), query.Get("arch"), query.Get("variant")}, " "))
+		w.Write([]byte(`{"id": "img2"}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "0123456789abcdef", "Name": "app", "Image": "img1", "ImageName": "docker.io/team/app:1.0", "Config": {"Hostname": "0123456789ab"}}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/images/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "img1" {
+			http.Error(w, `{"message": "image not known"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Id": "img1", "Os": "linux", "Architecture": "` + foreign + `"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "new1"}`))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	for _, tc := range []struct {
+		body string
+		want int
+		pull string
+	}{
+		{`{"reference": "docker.io/library/nginx:1.27", "platform": "linux/arm/v7"}`, http.StatusOK, "docker.io/library/nginx:1.27 linux arm v7"},
+		{`{"reference": "docker.io/library/nginx:1.27", "platform": "linux/arm64"}`, http.StatusOK, "docker.io/library/nginx:1.27 linux arm64 "},
+		{`{"reference": "docker.io/library/nginx:1.27"}`, http.StatusOK, "docker.io/library/nginx:1.27   "},
+		{`{"reference": "docker.io/library/nginx:1.27", "platform": "arm64"}`, http.StatusBadRequest, ""},
+		{`{"reference": "docker.io/library/nginx:1.27", "platform": "linux/arm64&tlsVerify=false"}`, http.StatusBadRequest, ""},
+	} {
+		pulls = nil
+		if rec := request(http.MethodPost, "/api/v1/images/pull", tc.body); rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d %s", tc.body, tc.want, rec.Code, rec.Body)
+		}
+		if tc.pull != "" && (len(pulls) != 1 || pulls[0] != tc.pull) {
+			t.Errorf("%s: expected pull %q, got %q", tc.body, tc.pull, pulls)
+		}
+	}
+
+	// The platform of an emulated image is kept in the exported spec
+	rec := request(http.MethodGet, "/api/v1/containers/app/export", "")
+	var spec api.ContainerSpec
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected spec, got %d %q", rec.Code, rec.Body)
+	}
+	if spec.Platform != "linux/"+foreign {
+		t.Errorf("Expected platform linux/%s, got %q", foreign, spec.Platform)
+	}
+
+	// and pulled on import
+	pulls = nil
+	spec.Image = "docker.io/team/app:1.1"
+	body, _ := json.Marshal(spec)
+	if rec := request(http.MethodPost, "/api/v1/containers/import?name=app2", string(body)); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rec.Code, rec.Body)
+	}
+	if want := "docker.io/team/app:1.1 linux " + foreign + " "; len(pulls) != 1 || pulls[0] != want {
+		t.Errorf("Expected pull %q, got %q", want, pulls)
+	}
+
+	spec.Platform = "Linux ARM"
+	body, _ = json.Marshal(spec)
+	if rec := request(http.MethodPost, "/api/v1/containers/import?name=app3", string(body)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid platform, got %d", rec.Code)
+	}
+}
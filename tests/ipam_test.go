@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestNetworkIPAM(t *testing.T) {
+	ips := map[string]string{"web": "10.89.0.2", "db": "10.89.0.3", "proxy": "10.88.0.7"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/networks/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "podman", "driver": "bridge", "subnets": [{"subnet": "10.88.0.0/16", "gateway": "10.88.0.1"}]},
+			{"name": "small", "driver": "bridge", "subnets": [
+				{"subnet": "10.89.0.0/29", "gateway": "10.89.0.1", "lease_range": {"start_ip": "10.89.0.2", "end_ip": "10.89.0.3"}},
+				{"subnet": "fd00::/64", "gateway": "fd00::1"}
+			]},
+			{"name": "clash", "driver": "bridge", "subnets": [{"subnet": "10.88.128.0/24"}]}
+		]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "c1", "Names": ["web"], "State": "running", "Networks": ["small"]},
+			{"Id": "c2", "Names": ["db"], "State": "running", "Networks": ["small"], "PodName": "backend"},
+			{"Id": "c3", "Names": ["proxy"], "State": "running", "Networks": ["podman"]},
+			{"Id": "c4", "Names": ["old"], "State": "exited", "Networks": ["podman"]}
+		]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		names := map[string]string{"c1": "web", "c2": "db", "c3": "proxy"}
+		name := names[r.PathValue("id")]
+		network := "small"
+		if name == "proxy" {
+			network = "podman"
+		}
+		v6 := ""
+		if name == "web" {
+			v6 = "fd00::2"
+		}
+		fmt.Fprintf(w, `{"Id": %q, "Name": %q, "NetworkSettings": {"Networks": {%q: {"IPAddress": %q, "GlobalIPv6Address": %q, "MacAddress": "aa:bb:cc:dd:ee:0%d"}}}}`,
+			r.PathValue("id"), name, network, ips[name], v6, len(name))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/networks/ipam", nil))
+	var overview api.IPAMOverview
+	if err := json.Unmarshal(rec.Body.Bytes(), &overview); err != nil || rec.Code != http.StatusOK || len(overview.Networks) != 3 {
+		t.Fatalf("Expected three networks, got %d %s", rec.Code, rec.Body)
+	}
+	networks := make(map[string]api.NetworkIPAM)
+	for _, n := range overview.Networks {
+		networks[n.Name] = n
+	}
+
+	// The lease range holds two addresses, both taken
+	small := networks["small"]
+	if len(small.Allocations) != 3 || small.Allocations[0].Container != "web" || small.Allocations[1].Pod != "backend" || small.Allocations[2].IP != "fd00::2" {
+		t.Errorf("Unexpected allocations %+v", small.Allocations)
+	}
+	v4 := small.Subnets[0]
+	if v4.Capacity == nil || *v4.Capacity != 2 || v4.Allocated != 2 || *v4.Free != 0 || v4.Status != "exhausted" {
+		t.Errorf("Expected an exhausted subnet, got %+v", v4)
+	}
+	if v6 := small.Subnets[1]; v6.Capacity != nil || v6.Allocated != 1 || v6.Status != "ok" {
+		t.Errorf("Expected an unlimited IPv6 subnet, got %+v", v6)
+	}
+
+	// 65534 addresses without network and broadcast, minus the gateway
+	podmanNet := networks["podman"].Subnets[0]
+	if *podmanNet.Capacity != 65533 || podmanNet.Allocated != 1 || podmanNet.Status != "ok" || len(podmanNet.Overlaps) != 1 || podmanNet.Overlaps[0] != "clash" {
+		t.Errorf("Unexpected usage %+v", podmanNet)
+	}
+	if len(networks["podman"].Allocations) != 1 {
+		t.Errorf("Expected stopped containers to be left out, got %+v", networks["podman"].Allocations)
+	}
+
+	warnings := strings.Join(overview.Warnings, "\n")
+	if !strings.Contains(warnings, "small 10.89.0.0/29: no free addresses left") || !strings.Contains(warnings, "podman 10.88.0.0/16: overlaps clash") {
+		t.Errorf("Unexpected warnings %v", overview.Warnings)
+	}
+}
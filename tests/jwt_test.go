@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"podmanview/internal/auth"
+)
+
+func TestJWTKeyRotation(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		wantJWKS  bool
+	}{
+		{"HS256", auth.AlgHS256, false},
+		{"RS256", auth.AlgRS256, true},
+		{"EdDSA", auth.AlgEdDSA, true},
+	}
+
+	user := &auth.User{Username: "admin", UID: "0", Role: auth.RoleAdmin}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := auth.NewJWTManagerWithAlgorithm("test-secret", time.Hour, tt.algorithm)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			if _, err := manager.EnsureSigningKey(); err != nil {
+				t.Fatalf("Failed to ensure signing key: %v", err)
+			}
+
+			oldToken, err := manager.GenerateToken(user)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			if _, err := manager.Rotate(); err != nil {
+				t.Fatalf("Failed to rotate key: %v", err)
+			}
+
+			newToken, err := manager.GenerateToken(user)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			for _, token := range []string{oldToken, newToken} {
+				claims, err := manager.ValidateToken(token)
+				if err != nil {
+					t.Fatalf("Token should be valid after rotation: %v", err)
+				}
+				if claims.Username != user.Username {
+					t.Errorf("Expected username %q, got %q", user.Username, claims.Username)
+				}
+			}
+
+			if got := len(manager.PublicKeys()) > 0; got != tt.wantJWKS {
+				t.Errorf("Expected JWKS published = %v, got %v", tt.wantJWKS, got)
+			}
+		})
+	}
+}
+
+func TestJWTKeysSurviveRestart(t *testing.T) {
+	user := &auth.User{Username: "admin", UID: "0", Role: auth.RoleAdmin}
+
+	first, err := auth.NewJWTManagerWithAlgorithm("test-secret", time.Hour, auth.AlgEdDSA)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := first.EnsureSigningKey(); err != nil {
+		t.Fatalf("Failed to ensure signing key: %v", err)
+	}
+
+	token, err := first.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	stored, err := first.ExportKeys()
+	if err != nil {
+		t.Fatalf("Failed to export keys: %v", err)
+	}
+
+	second, err := auth.NewJWTManagerWithAlgorithm("test-secret", time.Hour, auth.AlgEdDSA)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := second.LoadKeys(stored); err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+
+	changed, err := second.EnsureSigningKey()
+	if err != nil {
+		t.Fatalf("Failed to ensure signing key: %v", err)
+	}
+	if changed {
+		t.Error("Loaded key ring should not need a new signing key")
+	}
+
+	if _, err := second.ValidateToken(token); err != nil {
+		t.Errorf("Token should be valid after restart: %v", err)
+	}
+
+	// Forged token signed by an unrelated key must be rejected
+	other, _ := auth.NewJWTManagerWithAlgorithm("test-secret", time.Hour, auth.AlgEdDSA)
+	other.EnsureSigningKey()
+	forged, _ := other.GenerateToken(user)
+	if _, err := second.ValidateToken(forged); err == nil {
+		t.Error("Token signed by an unknown key should be rejected")
+	}
+}
+
+func TestJWTSecretKeyDroppedAfterSwitch(t *testing.T) {
+	user := &auth.User{Username: "admin", UID: "0", Role: auth.RoleAdmin}
+
+	hmac := auth.NewJWTManager("test-secret", time.Hour)
+	token, err := hmac.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	stored, err := hmac.ExportKeys()
+	if err != nil {
+		t.Fatalf("Failed to export keys: %v", err)
+	}
+
+	for _, algorithm := range []string{auth.AlgRS256, auth.AlgEdDSA} {
+		// Restart with an asymmetric algorithm
+		manager, err := auth.NewJWTManagerWithAlgorithm("test-secret", time.Hour, algorithm)
+		if err != nil {
+			t.Fatalf("Failed to create manager: %v", err)
+		}
+		if err := manager.LoadKeys(stored); err != nil {
+			t.Fatalf("Failed to load keys: %v", err)
+		}
+		if _, err := manager.EnsureSigningKey(); err != nil {
+			t.Fatalf("Failed to ensure signing key: %v", err)
+		}
+		if _, err := manager.ValidateToken(token); err == nil {
+			t.Errorf("%s: expected a token signed with the secret to be rejected", algorithm)
+		}
+		for _, key := range manager.Keys() {
+			if key.ID == auth.DefaultKeyID {
+				t.Errorf("%s: expected the secret key to leave the ring", algorithm)
+			}
+		}
+
+		// Also after the ring is saved and loaded again
+		saved, _ := manager.ExportKeys()
+		if err := manager.LoadKeys(append(saved, auth.StoredKey{ID: auth.DefaultKeyID, Algorithm: auth.AlgHS256})); err != nil {
+			t.Fatalf("Failed to load keys: %v", err)
+		}
+		if _, err := manager.ValidateToken(token); err == nil {
+			t.Errorf("%s: expected the secret key to stay out of a loaded ring", algorithm)
+		}
+	}
+
+	// HS256 keeps accepting it
+	if _, err := hmac.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if _, err := hmac.ValidateToken(token); err != nil {
+		t.Errorf("Expected the token to stay valid with HS256: %v", err)
+	}
+}
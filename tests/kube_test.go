@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+func TestKubeGeneratePlay(t *testing.T) {
+	var played, playQuery string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/generate/kube", func(w http.ResponseWriter, r *http.Request) {
+		names := r.URL.Query()["names"]
+		if len(names) == 0 || names[0] == "missing" {
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: " + strings.Join(names, "-") + "\n# service=" + r.URL.Query().Get("service") + "\n"))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/play/kube", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		played, playQuery = string(body), r.URL.RawQuery
+		w.Write([]byte(`{"Pods": [{"ID": "0123456789abcdef", "Containers": ["c1"], "ContainerErrors": []}], "Volumes": [{"Name": "data"}]}`))
+	})
+
+	store := events.NewStore(10)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withEvents(store))
+
+	request := func(method, path, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/api/v1/kube?names=web,%20db&service=true", "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "name: web-db\n# service=true") {
+		t.Fatalf("Expected YAML, got %d %q", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Content-Type") != "application/yaml" || rec.Header().Get("Content-Disposition") != `attachment; filename="podmanview.yaml"` {
+		t.Errorf("Unexpected headers %v", rec.Header())
+	}
+	rec = request(http.MethodGet, "/api/v1/kube?names=web", "", nil)
+	if rec.Header().Get("Content-Disposition") != `attachment; filename="web.yaml"` || strings.Contains(rec.Body.String(), "service=true") {
+		t.Errorf("Unexpected single export %v %q", rec.Header(), rec.Body)
+	}
+	if rec := request(http.MethodGet, "/api/v1/kube", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without names, got %d", rec.Code)
+	}
+	if rec := request(http.MethodGet, "/api/v1/kube?names=missing", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+
+	const manifest = "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n"
+	rec = request(http.MethodPost, "/api/v1/kube/play?replace=true", "application/yaml", strings.NewReader(manifest))
+	var report podman.PlayKubeReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK || len(report.Pods) != 1 || report.Volumes[0].Name != "data" {
+		t.Fatalf("Expected report, got %d %q", rec.Code, rec.Body)
+	}
+	if played != manifest || playQuery != "replace=true&start=true" {
+		t.Errorf("Unexpected play request %q %q", playQuery, played)
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("file", "web.yaml")
+	part.Write([]byte(manifest))
+	writer.Close()
+	rec = request(http.MethodPost, "/api/v1/kube/play?start=false", writer.FormDataContentType(), &form)
+	if rec.Code != http.StatusOK || played != manifest || playQuery != "start=false" {
+		t.Errorf("Expected multipart upload, got %d %q %q", rec.Code, playQuery, played)
+	}
+
+	if rec := request(http.MethodPost, "/api/v1/kube/play", "application/yaml", strings.NewReader("  \n")); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty manifest, got %d", rec.Code)
+	}
+
+	if last := store.GetLast(10); len(last) != 2 || last[0].Type != events.EventKubePlay || !strings.Contains(last[0].Details, "0123456789ab") {
+		t.Errorf("Unexpected events %+v", last)
+	}
+}
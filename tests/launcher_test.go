@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestLauncher(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "1", "Names": ["jellyfin"], "Image": "jellyfin/jellyfin", "State": "running"},
+			{"Id": "2", "Names": ["paperless"], "Image": "paperless", "State": "exited", "Labels": {
+				"homepage.href": "https://docs.home.lan", "homepage.name": "Documents", "homepage.group": "Office"}},
+			{"Id": "3", "Names": ["db"], "Image": "postgres", "State": "running", "Labels": {"homepage.href": "javascript:alert(1)"}}
+		]`))
+	})
+
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withConfig(cfg), withStorage(store))
+
+	jwtManager := auth.NewJWTManager(cfg.JWTSecret(), time.Hour)
+	tokens := make(map[string]string)
+	for _, user := range []*auth.User{{Username: "admin", UID: "0", Role: auth.RoleAdmin}, {Username: "alice", UID: "1000", Role: auth.RoleReadOnly}} {
+		if tokens[user.Username], err = jwtManager.GenerateToken(user); err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+	}
+	// Visitors of the launcher have no token
+	do := func(user, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if user != "" {
+			req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: tokens[user]})
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	type launcher struct {
+		Title string              `json:"title"`
+		Links []api.LauncherEntry `json:"links"`
+	}
+
+	// Off by default
+	if rec := do("", http.MethodGet, "/api/launcher", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Disabled launcher: expected 404, got %d", rec.Code)
+	}
+	if rec := do("", http.MethodGet, "/launcher", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Disabled launcher page: expected 404, got %d", rec.Code)
+	}
+	if rec := do("alice", http.MethodPut, "/api/launcher/settings", `{"enabled": true}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Read-only user: expected 403, got %d", rec.Code)
+	}
+	for body, code := range map[string]int{
+		`{"links": [{"name": "A", "url": "ftp://nas"}]}`: http.StatusBadRequest,
+		`{"links": [{"url": "https://nas"}]}`:            http.StatusBadRequest,
+		`{"title": "` + strings.Repeat("a", 65) + `"}`:   http.StatusBadRequest,
+	} {
+		if rec := do("admin", http.MethodPut, "/api/launcher/settings", body); rec.Code != code {
+			t.Errorf("Settings %s: expected %d, got %d", body, code, rec.Code)
+		}
+	}
+
+	rec := do("admin", http.MethodPut, "/api/launcher/settings", `{"enabled": true, "title": "Our home", "labels": true, "links": [
+		{"name": "Movies", "url": "https://jellyfin.home.lan", "container": "jellyfin", "group": "Media"},
+		{"name": "Router", "url": "http://192.168.1.1", "icon": "https://192.168.1.1/logo.png"}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Settings: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do("", http.MethodGet, "/api/launcher", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Launcher: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var page launcher
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	if page.Title != "Our home" || len(page.Links) != 3 {
+		t.Fatalf("Unexpected launcher: %+v", page)
+	}
+	router, movies, docs := page.Links[0], page.Links[1], page.Links[2]
+	if router.Name != "Router" || router.Running != nil || router.Icon != "https://192.168.1.1/logo.png" {
+		t.Errorf("Unexpected plain link: %+v", router)
+	}
+	if movies.Name != "Movies" || movies.Running == nil || !*movies.Running || !strings.HasSuffix(movies.Icon, "/jellyfin.png") {
+		t.Errorf("Unexpected container link: %+v", movies)
+	}
+	if docs.Name != "Documents" || docs.Group != "Office" || docs.URL != "https://docs.home.lan" || docs.Running == nil || *docs.Running {
+		t.Errorf("Unexpected labeled link: %+v", docs)
+	}
+	if rec := do("", http.MethodGet, "/launcher", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/launcher") {
+		t.Errorf("Launcher page: expected 200, got %d", rec.Code)
+	}
+
+	// With a key, visitors need it
+	rec = do("admin", http.MethodPut, "/api/launcher/settings", `{"enabled": true, "requireKey": true, "key": "chosen"}`)
+	var settings api.LauncherSettings
+	json.Unmarshal(rec.Body.Bytes(), &settings)
+	if settings.Key == "" || settings.Key == "chosen" || settings.Title != "Home" {
+		t.Fatalf("Expected a generated key, got %+v", settings)
+	}
+	for path, code := range map[string]int{
+		"/api/launcher":                     http.StatusUnauthorized,
+		"/api/launcher?key=chosen":          http.StatusUnauthorized,
+		"/launcher?key=nope":                http.StatusUnauthorized,
+		"/api/launcher?key=" + settings.Key: http.StatusOK,
+		"/launcher?key=" + settings.Key:     http.StatusOK,
+	} {
+		if rec := do("", http.MethodGet, path, ""); rec.Code != code {
+			t.Errorf("GET %s: expected %d, got %d", path, code, rec.Code)
+		}
+	}
+
+	// Rotating the key breaks old links
+	rec = do("admin", http.MethodPost, "/api/launcher/settings/key", "")
+	var rotated api.LauncherSettings
+	json.Unmarshal(rec.Body.Bytes(), &rotated)
+	if rotated.Key == "" || rotated.Key == settings.Key {
+		t.Errorf("Expected a new key, got %+v", rotated)
+	}
+	if rec := do("", http.MethodGet, "/api/launcher?key="+settings.Key, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Old key: expected 401, got %d", rec.Code)
+	}
+	if rec := do("", http.MethodGet, "/api/launcher?key="+rotated.Key, ""); rec.Code != http.StatusOK {
+		t.Errorf("New key: expected 200, got %d", rec.Code)
+	}
+}
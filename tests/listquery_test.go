@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+// fakePodman serves a fixed container list on a Unix socket
+func fakePodman(t *testing.T) *podman.Client {
+	dir, err := os.MkdirTemp("", "podman")
+	if err != nil {
+		t.Fatalf("MkdirTemp failed: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "a1", "Names": ["web"], "Image": "nginx:latest", "State": "running", "Labels": {"app": "web", "tier": "front"}, "Created": "2026-01-03T00:00:00Z"},
+			{"Id": "b2", "Names": ["db"], "Image": "postgres:16", "State": "exited", "Labels": {"app": "db"}, "Created": "2026-01-01T00:00:00Z"},
+			{"Id": "c3", "Names": ["api"], "Image": "nginx:alpine", "State": "running", "Labels": {"app": "api", "tier": "back"}, "Created": "2026-01-02T00:00:00Z"}
+		]`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Stats": [{"ContainerID": "a1", "CPU": 5}, {"ContainerID": "c3", "CPU": 50}]}`))
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	client, err := podman.NewClientWithSocket(socket)
+	if err != nil {
+		t.Fatalf("NewClientWithSocket failed: %v", err)
+	}
+	return client
+}
+
+func TestContainerListQuery(t *testing.T) {
+	server := newTestServer(t, fakePodman(t))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers"+query, nil))
+		return rec
+	}
+	ids := func(containers []api.ContainerWithStats) []string {
+		var result []string
+		for _, c := range containers {
+			result = append(result, c.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		query string
+		ids   []string
+	}{
+		{"", []string{"a1", "b2", "c3"}},
+		{"?status=running", []string{"a1", "c3"}},
+		{"?label=tier", []string{"a1", "c3"}},
+		{"?label=app!=web,tier", []string{"c3"}},
+		{"?label=!tier", []string{"b2"}},
+		{"?q=NGINX&sort=name", []string{"c3", "a1"}},
+		{"?sort=-created", []string{"a1", "c3", "b2"}},
+		{"?sort=-cpu", []string{"c3", "a1", "b2"}},
+	}
+	for _, tt := range tests {
+		rec := get(tt.query)
+		var containers []api.ContainerWithStats
+		if err := json.Unmarshal(rec.Body.Bytes(), &containers); err != nil || rec.Code != http.StatusOK {
+			t.Errorf("%q: unexpected response %d %q", tt.query, rec.Code, rec.Body)
+			continue
+		}
+		if got := ids(containers); !slices.Equal(got, tt.ids) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.ids, got)
+		}
+	}
+
+	// Pages are wrapped with the total count
+	rec := get("?sort=name&page=2&limit=2")
+	var page struct {
+		Items []api.ContainerWithStats `json:"items"`
+		Total int                      `json:"total"`
+		Page  int                      `json:"page"`
+		Limit int                      `json:"limit"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", rec.Body, err)
+	}
+	if page.Total != 3 || page.Page != 2 || page.Limit != 2 || !slices.Equal(ids(page.Items), []string{"a1"}) {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	for _, query := range []string{"?page=0", "?limit=5000", "?sort=size", "?label=="} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
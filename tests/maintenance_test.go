@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/podman"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+
+	cfg := newTestConfig(t, "")
+	if err := cfg.SetMaintenanceMode(true); err != nil {
+		t.Fatalf("SetMaintenanceMode failed: %v", err)
+	}
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withConfig(cfg))
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/v1/containers", http.StatusOK},
+		{http.MethodGet, "/api/v1/system/maintenance", http.StatusOK},
+		{http.MethodPost, "/api/v1/containers/c1/start", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/v1/containers/c1", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v1/terminal", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v1/containers/c1/terminal", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v1/containers/c1/attach", http.StatusServiceUnavailable},
+	} {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.want, rec.Code)
+		}
+	}
+}
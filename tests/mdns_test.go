@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"podmanview/internal/config"
+	"podmanview/internal/mdns"
+)
+
+func TestMDNSHostLabel(t *testing.T) {
+	for name, want := range map[string]string{
+		"PodmanView":       "podmanview",
+		"My Server (home)": "my-server-home",
+		"  --  ":           "podmanview",
+		"Kitchen Pi 4":     "kitchen-pi-4",
+	} {
+		if got := mdns.HostLabel(name); got != want {
+			t.Errorf("HostLabel(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMDNSReply(t *testing.T) {
+	responder := mdns.NewResponder(mdns.Service{Instance: "Home Lab", Host: "home-lab", Port: 8080, Path: "/podmanview/"})
+	addrs := []net.IP{net.ParseIP("192.168.1.20"), net.ParseIP("fd00::20")}
+
+	query := func(id uint16, name string, qtype dnsmessage.Type, class dnsmessage.Class) []byte {
+		msg := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: id},
+			Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: class}},
+		}
+		b, err := msg.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	parse := func(b []byte) dnsmessage.Message {
+		var msg dnsmessage.Message
+		if err := msg.Unpack(b); err != nil {
+			t.Fatalf("Failed to parse reply: %v", err)
+		}
+		return msg
+	}
+
+	// Browsing the service type answers with the instance, its SRV and
+	// TXT records and the host addresses
+	reply, unicast, err := responder.Reply(query(0, "_http._tcp.local.", dnsmessage.TypePTR, dnsmessage.ClassINET), addrs, false)
+	if err != nil || reply == nil || unicast {
+		t.Fatalf("Expected a multicast reply, got %v %v", unicast, err)
+	}
+	msg := parse(reply)
+	if len(msg.Answers) != 1 || msg.Answers[0].Body.(*dnsmessage.PTRResource).PTR.String() != "Home Lab._http._tcp.local." {
+		t.Errorf("Expected the instance PTR, got %+v", msg.Answers)
+	}
+	found := make(map[dnsmessage.Type]dnsmessage.Resource)
+	for _, rr := range msg.Additionals {
+		found[rr.Header.Type] = rr
+	}
+	if srv, ok := found[dnsmessage.TypeSRV].Body.(*dnsmessage.SRVResource); !ok || srv.Port != 8080 || srv.Target.String() != "home-lab.local." {
+		t.Errorf("Expected the SRV record, got %+v", msg.Additionals)
+	}
+	if txt, ok := found[dnsmessage.TypeTXT].Body.(*dnsmessage.TXTResource); !ok || len(txt.TXT) != 1 || txt.TXT[0] != "path=/podmanview/" {
+		t.Errorf("Expected the TXT record, got %+v", msg.Additionals)
+	}
+	if a, ok := found[dnsmessage.TypeA].Body.(*dnsmessage.AResource); !ok || net.IP(a.A[:]).String() != "192.168.1.20" {
+		t.Errorf("Expected the A record, got %+v", msg.Additionals)
+	}
+	if _, ok := found[dnsmessage.TypeAAAA]; !ok {
+		t.Errorf("Expected the AAAA record, got %+v", msg.Additionals)
+	}
+
+	// Host names match case-insensitively; a legacy resolver gets its ID,
+	// the question and short TTLs back
+	reply, unicast, err = responder.Reply(query(42, "Home-Lab.local.", dnsmessage.TypeA, dnsmessage.ClassINET), addrs, true)
+	if err != nil || reply == nil || !unicast {
+		t.Fatalf("Expected a unicast reply, got %v %v", unicast, err)
+	}
+	msg = parse(reply)
+	if msg.ID != 42 || len(msg.Questions) != 1 || len(msg.Answers) != 1 || len(msg.Additionals) != 1 || msg.Answers[0].Header.TTL > 10 || msg.Answers[0].Header.Class != dnsmessage.ClassINET {
+		t.Errorf("Expected a legacy answer, got %+v", msg)
+	}
+
+	// The QU bit asks for a unicast reply
+	if _, unicast, _ := responder.Reply(query(0, "home-lab.local.", dnsmessage.TypeA, dnsmessage.ClassINET|1<<15), addrs, false); !unicast {
+		t.Error("Expected a unicast reply to a QU question")
+	}
+
+	// Other names are left to other responders
+	for _, name := range []string{"other.local.", "_ssh._tcp.local."} {
+		if reply, _, err := responder.Reply(query(0, name, dnsmessage.TypeALL, dnsmessage.ClassINET), addrs, false); err != nil || reply != nil {
+			t.Errorf("%s: expected no reply, got %v", name, err)
+		}
+	}
+
+	if reply, _, _ := responder.Reply(query(0, "home-lab.local.", dnsmessage.TypeAAAA, dnsmessage.ClassINET), addrs[:1], false); reply != nil {
+		t.Error("Expected no reply without an IPv6 address")
+	}
+
+	// A goodbye withdraws every record
+	goodbye, err := responder.Announcement(addrs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = parse(goodbye)
+	if len(msg.Answers) != 6 {
+		t.Errorf("Expected 6 records, got %d", len(msg.Answers))
+	}
+	for _, rr := range msg.Answers {
+		if rr.Header.TTL != 0 {
+			t.Errorf("Expected TTL 0 in a goodbye, got %+v", rr.Header)
+		}
+	}
+}
+
+func TestConfigMDNS(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.MDNSEnabled() || cfg.MDNSName() != config.DefaultMDNSName {
+		t.Errorf("Expected mDNS to be off by default, got %v %q", cfg.MDNSEnabled(), cfg.MDNSName())
+	}
+
+	yamlPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(yamlPath, []byte("server:\n  mdns:\n    enabled: true\n    name: Home Lab\nauth:\n  jwt:\n    secret: secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = config.Load(yamlPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.MDNSEnabled() || cfg.MDNSName() != "Home Lab" {
+		t.Errorf("Expected mDNS settings from config.yaml, got %v %q", cfg.MDNSEnabled(), cfg.MDNSName())
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"testing"
+
+	"podmanview/internal/api"
+)
+
+func TestParseMdstat(t *testing.T) {
+	arrays := api.ParseMdstat(`Personalities : [raid1] [raid6] [raid5] [raid4] [raid0]
+md1 : active raid5 sdd1[3] sdc1[1] sdb1[0]
+      3906762752 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [UU_]
+      [=>...................]  recovery =  8.5% (166123456/1953381376) finish=152.1min speed=195840K/sec
+      bitmap: 0/15 pages [0KB], 65536KB chunk
+
+md0 : active raid1 sdb2[1](F) sda2[0] sde2[2](S)
+      1048512 blocks super 1.2 [2/1] [U_]
+
+md3 : active (auto-read-only) raid1 sdf1[1](W) sdg1[0]
+      976630488 blocks super 1.2 [2/2] [UU]
+      	resync=PENDING
+
+md4 : active raid0 sdh1[1] sdi1[0]
+      1953260544 blocks super 1.2 512k chunks
+
+md127 : inactive sdj1[0](S)
+      976630488 blocks super 1.2
+
+unused devices: <none>
+`)
+	if len(arrays) != 5 {
+		t.Fatalf("Expected 5 arrays, got %+v", arrays)
+	}
+
+	md1 := arrays[0]
+	if md1.Name != "md1" || md1.Level != "raid5" || md1.State != "active" || md1.Size != 3906762752*1024 ||
+		md1.Devices != 3 || md1.Active != 2 || md1.Status != "UU_" || !md1.Degraded || len(md1.Members) != 3 {
+		t.Errorf("Unexpected md1: %+v", md1)
+	}
+	if md1.Sync == nil || *md1.Sync != (api.RaidSync{Action: "recovery", Progress: 8.5, Remaining: 9126, Speed: 195840 * 1024}) {
+		t.Errorf("Unexpected md1 recovery: %+v", md1.Sync)
+	}
+
+	md0 := arrays[1]
+	expected := []api.RaidMember{
+		{Name: "sdb2", State: api.RaidMemberFaulty},
+		{Name: "sda2", State: api.RaidMemberActive},
+		{Name: "sde2", State: api.RaidMemberSpare},
+	}
+	if !md0.Degraded || md0.Sync != nil || len(md0.Members) != 3 {
+		t.Fatalf("Unexpected md0: %+v", md0)
+	}
+	for i, member := range expected {
+		if md0.Members[i] != member {
+			t.Errorf("Expected %+v, got %+v", member, md0.Members[i])
+		}
+	}
+
+	md3 := arrays[2]
+	if md3.Degraded || !md3.ReadOnly || md3.Level != "raid1" || !md3.Members[0].WriteMostly ||
+		md3.Sync == nil || !md3.Sync.Pending || md3.Sync.Action != "resync" {
+		t.Errorf("Unexpected md3: %+v %+v", md3, md3.Sync)
+	}
+
+	// No redundancy, so never degraded
+	if md4 := arrays[3]; md4.Level != "raid0" || md4.Devices != 0 || md4.Degraded || len(md4.Members) != 2 {
+		t.Errorf("Unexpected md4: %+v", md4)
+	}
+	if md127 := arrays[4]; md127.State != "inactive" || md127.Level != "" || md127.Members[0].State != api.RaidMemberSpare {
+		t.Errorf("Unexpected md127: %+v", md127)
+	}
+
+	if arrays := api.ParseMdstat("Personalities : \nunused devices: <none>\n"); len(arrays) != 0 {
+		t.Errorf("Expected no arrays, got %+v", arrays)
+	}
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/plugins"
+	"podmanview/internal/plugins/monitors"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestMonitorsPlugin(t *testing.T) {
+	healthy := true
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer target.Close()
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := listener.Addr().String()
+	listener.Close()
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.SetPluginConfig("monitors", &storage.PluginConfig{Enabled: true, Name: "Endpoint Monitors"}); err != nil {
+		t.Fatalf("Failed to enable plugin: %v", err)
+	}
+
+	eventStore := events.NewStore(100)
+	newServer := func() *api.Server {
+		plugin := monitors.New()
+		deps := &plugins.PluginDependencies{Storage: store, EventStore: eventStore, Logger: log.New(io.Discard, "", 0)}
+		if err := plugin.Init(context.Background(), deps); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+		client := podman.NewClientWithHandler(http.NewServeMux())
+		return newTestServer(t, client, withPlugins(plugin), withStorage(store), withEvents(eventStore))
+	}
+	server := newServer()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	list := func() map[string]monitors.MonitorInfo {
+		var infos []monitors.MonitorInfo
+		json.Unmarshal(do(http.MethodGet, "/api/plugins/monitors/monitors", "").Body.Bytes(), &infos)
+		result := make(map[string]monitors.MonitorInfo)
+		for _, info := range infos {
+			result[info.Name] = info
+		}
+		return result
+	}
+	checkNow := func(id string) monitors.Result {
+		var result monitors.Result
+		rec := do(http.MethodPost, "/api/plugins/monitors/monitors/"+id+"/check", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Check failed: %d %s", rec.Code, rec.Body)
+		}
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return result
+	}
+	monitorEvents := func() []events.Event {
+		found, _ := eventStore.Query(events.Query{TypePrefix: "monitor_", Limit: 10})
+		return found
+	}
+
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"name": "x", "type": "http", "target": "ftp://example.com"}`, http.StatusBadRequest},
+		{`{"name": "x", "type": "tcp", "target": "localhost"}`, http.StatusBadRequest},
+		{`{"name": "x", "type": "ping", "target": "-f example.com"}`, http.StatusBadRequest},
+		{`{"name": "x", "type": "dns", "target": "example.com"}`, http.StatusBadRequest},
+		{`{"name": "x", "type": "http", "target": "http://localhost", "intervalSeconds": 5}`, http.StatusBadRequest},
+		{`{"name": "", "type": "http", "target": "http://localhost"}`, http.StatusBadRequest},
+	} {
+		if rec := do(http.MethodPost, "/api/plugins/monitors/monitors", tc.body); rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d %s", tc.body, tc.code, rec.Code, rec.Body)
+		}
+	}
+
+	var web, db monitors.MonitorInfo
+	rec := do(http.MethodPost, "/api/plugins/monitors/monitors", `{"name": "web", "type": "http", "target": "`+target.URL+`"}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &web); err != nil || rec.Code != http.StatusCreated || web.IntervalSeconds != 60 || web.Status != "pending" {
+		t.Fatalf("Expected the monitor to be created, got %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodPost, "/api/plugins/monitors/monitors", `{"name": "db", "type": "tcp", "target": "`+closedPort+`", "timeoutSeconds": 2}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &db); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the monitor to be created, got %d %s", rec.Code, rec.Body)
+	}
+
+	if result := checkNow(web.ID); !result.Up || result.Status != http.StatusOK {
+		t.Errorf("Expected web to be up, got %+v", result)
+	}
+	if result := checkNow(db.ID); result.Up || result.Error == "" {
+		t.Errorf("Expected db to be down, got %+v", result)
+	}
+
+	// One failure doesn't alert yet
+	if got := list()["db"]; got.Status != "pending" || len(monitorEvents()) != 0 {
+		t.Errorf("Expected no alert after one failure, got %+v %v", got, monitorEvents())
+	}
+	checkNow(db.ID)
+	if got := list()["db"]; got.Status != "down" || got.Uptime == nil || *got.Uptime != 0 {
+		t.Errorf("Expected db to be down, got %+v", got)
+	}
+	if found := monitorEvents(); len(found) != 1 || found[0].Type != events.EventMonitorDown || !strings.Contains(found[0].Details, "db") {
+		t.Errorf("Expected a monitor_down event, got %+v", found)
+	}
+	checkNow(db.ID)
+	if found := monitorEvents(); len(found) != 1 {
+		t.Errorf("Expected a single monitor_down event, got %+v", found)
+	}
+
+	// An unexpected status is a failure, and recovery raises monitor_up
+	healthy = false
+	if result := checkNow(web.ID); result.Up || result.Status != http.StatusBadGateway {
+		t.Errorf("Expected web to fail on 502, got %+v", result)
+	}
+	checkNow(web.ID)
+	healthy = true
+	checkNow(web.ID)
+	found := monitorEvents()
+	if len(found) != 3 || found[0].Type != events.EventMonitorUp || !strings.Contains(found[0].Details, "web") {
+		t.Errorf("Expected a monitor_up event for web, got %+v", found)
+	}
+	if got := list()["web"]; got.Status != "up" || got.Uptime == nil || *got.Uptime != 50 || got.AvgResponseMs == nil {
+		t.Errorf("Expected 50%% uptime for web, got %+v", got)
+	}
+
+	var history []monitors.Result
+	json.Unmarshal(do(http.MethodGet, "/api/plugins/monitors/monitors/"+web.ID+"/history?limit=2", "").Body.Bytes(), &history)
+	if len(history) != 2 || history[0].Up || !history[1].Up {
+		t.Errorf("Expected the last two results, got %+v", history)
+	}
+
+	// Monitors, results and the down state survive a restart
+	server = newServer()
+	if got := list()["db"]; got.Status != "down" || got.LastCheck == nil {
+		t.Errorf("Expected db to be down after a restart, got %+v", got)
+	}
+	checkNow(db.ID)
+	if found := monitorEvents(); len(found) != 3 {
+		t.Errorf("Expected no new alert after a restart, got %+v", found)
+	}
+
+	// Pausing keeps the history
+	rec = do(http.MethodPut, "/api/plugins/monitors/monitors/"+web.ID, `{"name": "web", "type": "http", "target": "`+target.URL+`", "enabled": false}`)
+	if rec.Code != http.StatusOK || list()["web"].Status != "paused" {
+		t.Errorf("Expected web to be paused, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(http.MethodDelete, "/api/plugins/monitors/monitors/"+db.ID, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected db to be removed, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/api/plugins/monitors/monitors/"+db.ID+"/history", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed monitor, got %d", rec.Code)
+	}
+	if got := list(); len(got) != 1 {
+		t.Errorf("Expected one monitor, got %+v", got)
+	}
+}
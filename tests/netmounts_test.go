@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/config"
+)
+
+func TestParseNetworkMounts(t *testing.T) {
+	mounts := api.ParseNetworkMounts(`/dev/sda1 / ext4 rw,relatime 0 0
+nas:/export/media /mnt/media nfs4 rw,relatime,vers=4.2 0 0
+//nas/backup /mnt/backup cifs rw,relatime,vers=3.1.1 0 0
+nas:/export/media /mnt/media nfs4 rw,relatime,vers=4.2 0 0
+tmpfs /tmp tmpfs rw 0 0
+`)
+	expected := []api.DiskInfo{
+		{Device: "nas:/export/media", MountPoint: "/mnt/media", Network: "nfs4"},
+		{Device: "//nas/backup", MountPoint: "/mnt/backup", Network: "cifs"},
+	}
+	if len(mounts) != len(expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, mounts)
+	}
+	for i := range expected {
+		if mounts[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], mounts[i])
+		}
+	}
+}
+
+func TestMountStatter(t *testing.T) {
+	release := make(chan struct{})
+	calls := make(chan string, 10)
+	statter := api.NewMountStatter(func(path string, stat *syscall.Statfs_t) error {
+		calls <- path
+		if path == "/mnt/hung" {
+			<-release
+		}
+		stat.Blocks, stat.Bsize = 100, 4096
+		return nil
+	}, 50*time.Millisecond)
+
+	if stat, err := statter.Statfs("/mnt/ok"); err != nil || stat.Blocks != 100 {
+		t.Fatalf("Expected stats, got %+v, %v", stat, err)
+	}
+
+	start := time.Now()
+	if _, err := statter.Statfs("/mnt/hung"); !errors.Is(err, api.ErrStaleMount) {
+		t.Fatalf("Expected a stale mount, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the timeout, took %v", elapsed)
+	}
+
+	// A mount still hanging is not called again
+	<-calls
+	<-calls
+	if _, err := statter.Statfs("/mnt/hung"); !errors.Is(err, api.ErrStaleMount) {
+		t.Errorf("Expected a stale mount, got %v", err)
+	}
+	select {
+	case path := <-calls:
+		t.Errorf("Expected no second statfs of %s while the first hangs", path)
+	default:
+	}
+
+	// Until it answers
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := statter.Statfs("/mnt/hung"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the mount to recover")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNetworkMountsConfig(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\nPODMANVIEW_NETWORK_MOUNTS=true\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.NetworkMounts() {
+		t.Error("Expected network mounts enabled")
+	}
+}
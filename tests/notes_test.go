@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestNotes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id": "jellyfin-id", "Names": ["jellyfin"], "Labels": {"com.docker.compose.project": "media"}}]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if id := r.PathValue("id"); id != "jellyfin" && id != "jellyfin-id" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "no such container"}`))
+			return
+		}
+		w.Write([]byte(`{"Id": "jellyfin-id", "Name": "jellyfin"}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/volumes/{name}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") != "media-config" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "no such volume"}`))
+			return
+		}
+		w.Write([]byte(`{"Name": "media-config"}`))
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(100)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withStorage(store), withEvents(eventStore))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	for path, code := range map[string]int{
+		"/api/notes/pod/jellyfin":       http.StatusBadRequest,
+		"/api/notes/container/nope":     http.StatusNotFound,
+		"/api/notes/volume/nope":        http.StatusNotFound,
+		"/api/notes/stack/nope":         http.StatusNotFound,
+		"/api/notes/container/jellyfin": http.StatusOK,
+	} {
+		if rec := do(http.MethodPut, path, `{"content": "x"}`); rec.Code != code {
+			t.Errorf("PUT %s: expected %d, got %d: %s", path, code, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(http.MethodPut, "/api/notes/volume/media-config", `{"content": "`+strings.Repeat("a", 65<<10)+`"}`); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Large note: expected 413, got %d", rec.Code)
+	}
+
+	// Containers given by ID are stored by name, each save is a version
+	rec := do(http.MethodPut, "/api/notes/container/jellyfin-id", `{"content": "# Jellyfin\nAPI key in the vault", "version": 1}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Update: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var note api.Note
+	json.Unmarshal(rec.Body.Bytes(), &note)
+	if note.Name != "jellyfin" || note.Version != 2 || note.Author != "dev" {
+		t.Errorf("Unexpected note: %+v", note)
+	}
+	if evs := eventStore.GetLast(1); len(evs) != 1 || evs[0].Type != events.EventNoteUpdate || evs[0].Details != "container jellyfin: version 2" {
+		t.Errorf("Expected a note event, got %+v", evs)
+	}
+
+	// Saving over a stale version is a conflict
+	if rec := do(http.MethodPut, "/api/notes/container/jellyfin", `{"content": "stale", "version": 1}`); rec.Code != http.StatusConflict {
+		t.Errorf("Stale version: expected 409, got %d", rec.Code)
+	}
+	do(http.MethodPut, "/api/notes/stack/media", `{"content": "Start with podman-compose up"}`)
+	do(http.MethodPut, "/api/notes/volume/media-config", `{"content": "Backed up nightly"}`)
+
+	json.Unmarshal(do(http.MethodGet, "/api/notes/container/jellyfin", "").Body.Bytes(), &note)
+	if note.Version != 2 || !strings.HasPrefix(note.Content, "# Jellyfin") {
+		t.Errorf("Unexpected current note: %+v", note)
+	}
+	json.Unmarshal(do(http.MethodGet, "/api/notes/container/jellyfin/versions/1", "").Body.Bytes(), &note)
+	if note.Version != 1 || note.Content != "x" {
+		t.Errorf("Unexpected first version: %+v", note)
+	}
+	var versions []api.NoteVersion
+	json.Unmarshal(do(http.MethodGet, "/api/notes/container/jellyfin/versions", "").Body.Bytes(), &versions)
+	if len(versions) != 2 || versions[0].Version != 2 || versions[0].Content != "" {
+		t.Errorf("Unexpected versions: %+v", versions)
+	}
+
+	var notes []api.Note
+	json.Unmarshal(do(http.MethodGet, "/api/notes", "").Body.Bytes(), &notes)
+	if len(notes) != 3 || notes[0].Kind != "container" || notes[1].Name != "media" || notes[2].Name != "media-config" || notes[0].Content != "" {
+		t.Errorf("Unexpected notes: %+v", notes)
+	}
+	json.Unmarshal(do(http.MethodGet, "/api/notes?kind=volume", "").Body.Bytes(), &notes)
+	if len(notes) != 1 || notes[0].Name != "media-config" {
+		t.Errorf("Unexpected volume notes: %+v", notes)
+	}
+
+	if rec := do(http.MethodDelete, "/api/notes/stack/media", ""); rec.Code != http.StatusOK {
+		t.Errorf("Delete: expected 200, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/notes/stack/media", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Deleted note: expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/events"
+	"podmanview/internal/notify"
+	"podmanview/internal/storage"
+)
+
+func TestNotificationChannels(t *testing.T) {
+	store, err := storage.Open(storage.BackendSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+
+	type message struct{ title, body string }
+	received := make(chan message, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer tk" {
+			t.Errorf("Expected the ntfy token, got %q", r.Header.Get("Authorization"))
+		}
+		received <- message{r.Header.Get("Title"), string(body)}
+	}))
+	defer srv.Close()
+
+	manager, err := notify.NewManager(store, nil)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	if err := manager.Save(&notify.Channel{Type: "pager"}); err == nil {
+		t.Error("Expected an unknown channel type to be rejected")
+	}
+	if err := manager.Save(&notify.Channel{Type: notify.TypeTelegram, Settings: map[string]string{"token": "x"}}); err == nil {
+		t.Error("Expected a missing required setting to be rejected")
+	}
+
+	ch := &notify.Channel{
+		Type:     notify.TypeNtfy,
+		Enabled:  true,
+		Settings: map[string]string{"url": srv.URL, "token": "tk"},
+	}
+	if err := manager.Save(ch); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Secrets are masked, saving the mask keeps them
+	masked := ch.Masked()
+	if masked.Settings["token"] != notify.MaskedValue || masked.Settings["url"] != srv.URL {
+		t.Errorf("Unexpected masked settings: %v", masked.Settings)
+	}
+	if err := manager.Save(&masked); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got, _ := manager.Get(ch.ID); got.Settings["token"] != "tk" {
+		t.Errorf("Expected the token to be kept, got %q", got.Settings["token"])
+	}
+
+	eventStore := events.NewStore(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Run(ctx, eventStore)
+	time.Sleep(50 * time.Millisecond) // let Run subscribe
+
+	// Only alert-class events are routed by default
+	eventStore.Add(events.EventLogin, "alice", "10.0.0.1", true, "")
+	eventStore.Add(events.EventDiskFull, "system", "", false, "/ is 95% full")
+
+	select {
+	case msg := <-received:
+		if msg.title == "" || !strings.HasPrefix(msg.body, "/ is 95% full") {
+			t.Errorf("Unexpected notification %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notification was not sent")
+	}
+	select {
+	case msg := <-received:
+		t.Errorf("Expected no notification for a login, got %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
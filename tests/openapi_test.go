@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"podmanview/internal/config"
+	"podmanview/internal/plugins/led"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	server := newTestServer(t, nil, withConfig(cfg), withPlugins(led.New()))
+
+	// The document requires authentication like the rest of the API
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without a session, got %d", rec.Code)
+	}
+
+	if err := cfg.ApplyOverrides(map[string]string{config.EnvNoAuth: "true"}, config.SourceFlag); err != nil {
+		t.Fatalf("ApplyOverrides failed: %v", err)
+	}
+	server = newTestServer(t, nil, withConfig(cfg), withPlugins(led.New()))
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	type operation struct {
+		Tags        []string `json:"tags"`
+		Summary     string   `json:"summary"`
+		OperationID string   `json:"operationId"`
+		Parameters  []struct {
+			Name string `json:"name"`
+			In   string `json:"in"`
+		} `json:"parameters"`
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]operation `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Version != "1.2.3" {
+		t.Errorf("Unexpected header: %s %s", doc.OpenAPI, doc.Info.Version)
+	}
+
+	logs := doc.Paths["/api/v1/containers/{id}/logs"]["get"]
+	if logs.Summary != "Container logs" || logs.OperationID != "getContainersIdLogs" || len(logs.Tags) != 1 || logs.Tags[0] != "containers" {
+		t.Errorf("Unexpected container logs operation: %+v", logs)
+	}
+	if len(logs.Parameters) != 1 || logs.Parameters[0].Name != "id" || logs.Parameters[0].In != "path" {
+		t.Errorf("Expected the id path parameter, got %+v", logs.Parameters)
+	}
+
+	// Plugin routes are tagged with the plugin, summaries default to the handler name
+	status, ok := doc.Paths["/api/v1/plugins/led/status"]["get"]
+	if !ok || status.Tags[0] != "plugin: led" || status.Summary != "Get status" {
+		t.Errorf("Unexpected plugin operation: %+v", status)
+	}
+	if control := doc.Paths["/api/v1/plugins/led/control"]["post"]; control.Summary != "Toggle LEDs" {
+		t.Errorf("Expected acronyms to be kept in summaries, got %q", control.Summary)
+	}
+
+	if _, ok := doc.Paths["/static/*"]; ok {
+		t.Error("Expected only API routes")
+	}
+}
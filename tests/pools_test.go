@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+)
+
+func TestParseZFS(t *testing.T) {
+	pools := api.ParseZpoolList("tank\t4000000000000\t1000000000000\t3000000000000\tDEGRADED\nbackup\t100\t10\t90\tONLINE\n")
+	if len(pools) != 2 || pools[0].Name != "tank" || pools[0].Type != api.PoolZFS || pools[0].Health != "DEGRADED" ||
+		pools[0].Total != 4000000000000 || pools[0].Used != 1000000000000 || pools[0].Free != 3000000000000 {
+		t.Fatalf("Unexpected pools: %+v", pools)
+	}
+
+	api.ParseZpoolStatus(`  pool: tank
+ state: DEGRADED
+status: One or more devices has experienced an unrecoverable error.
+  scan: scrub in progress since Sun Oct 13 00:24:02 2024
+	1.23T scanned at 100M/s, 500G issued at 50M/s, 1T total
+	0B repaired, 48.83% done, 02:03:00 to go
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        DEGRADED     0     0     0
+	  mirror-0  DEGRADED     0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     FAULTED      3     1    12
+
+errors: No known data errors
+`, &pools[0])
+	tank := pools[0]
+	if tank.Scrub == nil || tank.Scrub.State != api.ScrubRunning || tank.Scrub.Progress != 48.83 || tank.Scrub.Time == nil || tank.Scrub.Time.Day() != 13 {
+		t.Errorf("Unexpected scrub: %+v", tank.Scrub)
+	}
+	if len(tank.Devices) != 3 || tank.Devices[2] != (api.PoolDevice{Name: "sdb", State: "FAULTED", ReadErrors: 3, WriteErrors: 1, ChecksumErrors: 12}) {
+		t.Errorf("Unexpected devices: %+v", tank.Devices)
+	}
+
+	for scan, expected := range map[string]api.ScrubStatus{
+		"scrub repaired 0B in 00:00:01 with 2 errors on Sun Oct 13 00:24:02 2024": {State: api.ScrubFinished, Errors: 2},
+		"scrub canceled on Sun Oct 13 00:24:02 2024":                              {State: api.ScrubCanceled},
+		"none requested": {State: api.ScrubNone},
+	} {
+		pool := api.StoragePool{Name: "backup"}
+		api.ParseZpoolStatus("  scan: "+scan+"\n", &pool)
+		if pool.Scrub == nil || pool.Scrub.State != expected.State || pool.Scrub.Errors != expected.Errors {
+			t.Errorf("%s: expected %+v, got %+v", scan, expected, pool.Scrub)
+		}
+	}
+	pool := api.StoragePool{Name: "backup"}
+	api.ParseZpoolStatus("  scan: resilvered 1G in 00:10:00 with 0 errors on Sun Oct 13 00:24:02 2024\n", &pool)
+	if pool.Scrub != nil {
+		t.Errorf("Expected no scrub status for a resilver, got %+v", pool.Scrub)
+	}
+
+	volumes := api.ParseZFSList("tank\t1000\t3000\t/tank\ntank/media\t800\t3000\t/srv/media\ntank/vm\t100\t3000\tnone\n")
+	if len(volumes) != 3 || volumes[1] != (api.PoolVolume{Name: "tank/media", MountPoint: "/srv/media", Used: 800, Available: 3000}) || volumes[2].MountPoint != "" {
+		t.Errorf("Unexpected datasets: %+v", volumes)
+	}
+}
+
+func TestParseBtrfs(t *testing.T) {
+	total, used, free := api.ParseBtrfsUsage(`Overall:
+    Device size:                 200000000000
+    Device allocated:             20000000000
+    Device unallocated:          180000000000
+    Device missing:                         0
+    Used:                         10000000000
+    Free (estimated):             95000000000      (min: 95000000000)
+    Free (statfs, df):            95000000000
+    Data ratio:                          2.00
+    Metadata ratio:                      2.00
+
+Data,RAID1: Size:9.00GiB, Used:4.50GiB (50.00%)
+`)
+	if total != 100000000000 || used != 5000000000 || free != 95000000000 {
+		t.Errorf("Expected the usable size of a RAID1, got %d, %d, %d", total, used, free)
+	}
+
+	devices := api.ParseBtrfsDeviceStats(`[/dev/sda].write_io_errs    1
+[/dev/sda].read_io_errs     2
+[/dev/sda].flush_io_errs    1
+[/dev/sda].corruption_errs  3
+[/dev/sda].generation_errs  1
+[/dev/sdb].write_io_errs    0
+[/dev/sdb].read_io_errs     0
+`)
+	if len(devices) != 2 || devices[0] != (api.PoolDevice{Name: "sda", ReadErrors: 2, WriteErrors: 2, ChecksumErrors: 4}) || devices[1].Name != "sdb" {
+		t.Errorf("Unexpected devices: %+v", devices)
+	}
+
+	scrub := api.ParseBtrfsScrubStatus(`UUID:             1b8a1c3e-0000-4000-8000-000000000000
+Scrub started:    Sun Oct 13 00:24:02 2024
+Status:           finished
+Duration:         1:02:03
+Total to scrub:   9.00GiB
+Rate:             2.47MiB/s
+Error summary:    csum=2 verify=1
+  Corrected:      3
+  Uncorrectable:  0
+`)
+	finished := time.Date(2024, 10, 13, 1, 26, 5, 0, time.Local)
+	if scrub == nil || scrub.State != api.ScrubFinished || scrub.Errors != 3 || scrub.Time == nil || !scrub.Time.Equal(finished) {
+		t.Errorf("Unexpected scrub: %+v", scrub)
+	}
+	if scrub := api.ParseBtrfsScrubStatus("UUID: x\n\tno stats available\n"); scrub == nil || scrub.State != api.ScrubNone {
+		t.Errorf("Expected no scrub yet, got %+v", scrub)
+	}
+}
+
+func TestGraphQLHostFields(t *testing.T) {
+	cfg := newTestConfig(t, "PODMANVIEW_GRAPHQL=true\nPODMANVIEW_DEMO=true\n")
+	server := newTestServer(t, nil, withConfig(cfg))
+
+	query := `{"query": "{ host { disks { device readBytesPerSec utilization } pools { name devices { name } } throttling { underVoltageOccurred } } }"}`
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(query)))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Contains(body, `"errors"`) {
+		t.Fatalf("Query failed: %d %s", rec.Code, body)
+	}
+	for _, expected := range []string{`"readBytesPerSec":49152`, `"pools":[]`, `"underVoltageOccurred":true`} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %s in %s", expected, body)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"podmanview/internal/events"
+	"podmanview/internal/plugins"
+	"podmanview/internal/plugins/portmap"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// fakeIGD is a UPnP Internet Gateway Device keeping port mappings
+type fakeIGD struct {
+	mu       sync.Mutex
+	mappings map[string]map[string]string // by protocol/external port
+	order    []string
+}
+
+func (d *fakeIGD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/desc.xml" {
+		w.Write([]byte(`<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0"><device>
+<deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+<deviceList><device><deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+<deviceList><device><deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+<serviceList><service><serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType><controlURL>/ctl</controlURL></service></serviceList>
+</device></deviceList></device></deviceList></device></root>`))
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	arg := func(name string) string {
+		m := regexp.MustCompile("<" + name + ">([^<]*)</" + name + ">").FindSubmatch(body)
+		if m == nil {
+			return ""
+		}
+		return string(m[1])
+	}
+	fault := func(code int) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault><detail><UPnPError><errorCode>%d</errorCode><errorDescription>error</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`, code)
+	}
+	reply := func(action string, values map[string]string) {
+		var b strings.Builder
+		for k, v := range values {
+			fmt.Fprintf(&b, "<%s>%s</%s>", k, v, k)
+		}
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:%sResponse>%s</u:%sResponse></s:Body></s:Envelope>`, action, b.String(), action)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	action := r.Header.Get("SOAPAction")
+	action = strings.Trim(action[strings.Index(action, "#")+1:], `"`)
+	key := arg("NewProtocol") + "/" + arg("NewExternalPort")
+	switch action {
+	case "GetExternalIPAddress":
+		reply(action, map[string]string{"NewExternalIPAddress": "203.0.113.7"})
+	case "AddPortMapping":
+		if _, exists := d.mappings[key]; !exists {
+			d.order = append(d.order, key)
+		}
+		d.mappings[key] = map[string]string{
+			"NewProtocol":               arg("NewProtocol"),
+			"NewExternalPort":           arg("NewExternalPort"),
+			"NewInternalClient":         arg("NewInternalClient"),
+			"NewInternalPort":           arg("NewInternalPort"),
+			"NewPortMappingDescription": arg("NewPortMappingDescription"),
+			"NewLeaseDuration":          arg("NewLeaseDuration"),
+			"NewEnabled":                "1",
+		}
+		reply(action, nil)
+	case "DeletePortMapping":
+		if _, exists := d.mappings[key]; !exists {
+			fault(714)
+			return
+		}
+		delete(d.mappings, key)
+		for i, k := range d.order {
+			if k == key {
+				d.order = append(d.order[:i], d.order[i+1:]...)
+				break
+			}
+		}
+		reply(action, nil)
+	case "GetGenericPortMappingEntry":
+		var i int
+		fmt.Sscan(arg("NewPortMappingIndex"), &i)
+		if i >= len(d.order) {
+			fault(713)
+			return
+		}
+		reply(action, d.mappings[d.order[i]])
+	default:
+		fault(401)
+	}
+}
+
+func TestPortMapPlugin(t *testing.T) {
+	igd := &fakeIGD{mappings: make(map[string]map[string]string)}
+	router := httptest.NewServer(igd)
+	defer router.Close()
+	// Another host's mapping
+	igd.mappings["UDP/51820"] = map[string]string{"NewProtocol": "UDP", "NewExternalPort": "51820", "NewInternalClient": "192.168.1.20", "NewInternalPort": "51820", "NewPortMappingDescription": "wireguard", "NewLeaseDuration": "0", "NewEnabled": "1"}
+	igd.order = []string{"UDP/51820"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "web-id", "Names": ["web"], "State": "running", "Ports": [
+				{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+				{"IP": "::", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}
+			]},
+			{"Id": "db-id", "Names": ["db"], "State": "running", "Ports": [
+				{"IP": "127.0.0.1", "PrivatePort": 5432, "PublicPort": 5432, "Type": "tcp"},
+				{"PrivatePort": 5433, "Type": "tcp"}
+			]}
+		]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "web-id", "web":
+			w.Write([]byte(`{"Id": "web-id", "Name": "web", "HostConfig": {"PortBindings": {"80/tcp": [{"HostIp": "", "HostPort": "8080"}]}}}`))
+		case "db-id", "db":
+			w.Write([]byte(`{"Id": "db-id", "Name": "db", "HostConfig": {"PortBindings": {"5432/tcp": [{"HostIp": "127.0.0.1", "HostPort": "5432"}]}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "no such container"}`))
+		}
+	})
+
+	cfg := newTestConfig(t, "")
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.SetPluginConfig("portmap", &storage.PluginConfig{Enabled: true, Name: "Port Mapping"}); err != nil {
+		t.Fatalf("Failed to enable plugin: %v", err)
+	}
+
+	client := podman.NewClientWithHandler(mux)
+	eventStore := events.NewStore(100)
+	plugin := portmap.New()
+	deps := &plugins.PluginDependencies{Storage: store, EventStore: eventStore, Logger: log.New(io.Discard, "", 0), PodmanClient: client}
+	if err := plugin.Init(context.Background(), deps); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	server := newTestServer(t, client, withConfig(cfg), withPlugins(plugin), withStorage(store), withEvents(eventStore))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	// Settings are validated
+	for _, body := range []string{
+		`{"method": "pcp"}`,
+		`{"method": "natpmp", "gateway": "router.lan"}`,
+		`{"method": "upnp", "upnpUrl": "ftp://router/desc.xml"}`,
+		`{"method": "auto", "leaseSeconds": 30}`,
+	} {
+		if rec := do(http.MethodPut, "/api/plugins/portmap/settings", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Settings %s: expected 400, got %d", body, rec.Code)
+		}
+	}
+	rec := do(http.MethodPut, "/api/plugins/portmap/settings", `{"method": "upnp", "upnpUrl": "`+router.URL+`/desc.xml"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Settings: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var settings portmap.Settings
+	json.Unmarshal(rec.Body.Bytes(), &settings)
+	if settings.LeaseSeconds != 3600 {
+		t.Errorf("Expected default lease 3600, got %d", settings.LeaseSeconds)
+	}
+
+	var gw portmap.GatewayInfo
+	json.Unmarshal(do(http.MethodGet, "/api/plugins/portmap/gateway", "").Body.Bytes(), &gw)
+	if gw.Method != portmap.MethodUPnP || gw.ExternalIP != "203.0.113.7" || gw.Address != router.URL+"/ctl" || gw.Error != "" {
+		t.Errorf("Unexpected gateway: %+v", gw)
+	}
+
+	// Ports published on IPv4 and IPv6 are listed once, unpublished ones not at all
+	var ports []portmap.PublishedPort
+	json.Unmarshal(do(http.MethodGet, "/api/plugins/portmap/ports", "").Body.Bytes(), &ports)
+	if len(ports) != 2 || ports[0].HostPort != 5432 || ports[1].HostPort != 8080 || ports[1].Container != "web" || ports[1].Mapped {
+		t.Errorf("Unexpected ports: %+v", ports)
+	}
+
+	for body, code := range map[string]int{
+		`{"containerId": "db", "hostPort": 5432}`:                      http.StatusBadRequest, // localhost only
+		`{"containerId": "web", "hostPort": 9090}`:                     http.StatusBadRequest, // not published
+		`{"containerId": "web", "hostPort": 8080, "protocol": "udp"}`:  http.StatusBadRequest,
+		`{"containerId": "web", "hostPort": 8080, "protocol": "sctp"}`: http.StatusBadRequest,
+		`{"containerId": "nope", "hostPort": 8080}`:                    http.StatusNotFound,
+	} {
+		if rec := do(http.MethodPost, "/api/plugins/portmap/mappings", body); rec.Code != code {
+			t.Errorf("Mapping %s: expected %d, got %d: %s", body, code, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = do(http.MethodPost, "/api/plugins/portmap/mappings", `{"containerId": "web", "hostPort": 8080, "externalPort": 80}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Mapping: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created portmap.MappingInfo
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if !created.Active || created.GrantedPort != 80 || created.ContainerPort != 80 || created.Container != "web" || created.ExpiresAt == nil {
+		t.Errorf("Unexpected mapping: %+v", created)
+	}
+	igd.mu.Lock()
+	entry := igd.mappings["TCP/80"]
+	igd.mu.Unlock()
+	if entry == nil || entry["NewInternalPort"] != "8080" || entry["NewInternalClient"] != "127.0.0.1" || entry["NewLeaseDuration"] != "3600" || entry["NewPortMappingDescription"] != "PodmanView web" {
+		t.Errorf("Unexpected router mapping: %v", entry)
+	}
+
+	if rec := do(http.MethodPost, "/api/plugins/portmap/mappings", `{"containerId": "web", "hostPort": 8080, "externalPort": 80}`); rec.Code != http.StatusConflict {
+		t.Errorf("Duplicate mapping: expected 409, got %d", rec.Code)
+	}
+
+	json.Unmarshal(do(http.MethodGet, "/api/plugins/portmap/ports", "").Body.Bytes(), &ports)
+	if len(ports) != 2 || !ports[1].Mapped {
+		t.Errorf("Expected port 8080 to be mapped: %+v", ports)
+	}
+
+	var routerMappings []portmap.RouterMapping
+	json.Unmarshal(do(http.MethodGet, "/api/plugins/portmap/router", "").Body.Bytes(), &routerMappings)
+	if len(routerMappings) != 2 {
+		t.Fatalf("Expected 2 router mappings, got %+v", routerMappings)
+	}
+	for _, m := range routerMappings {
+		if m.Ours != (m.ExternalPort == 80) {
+			t.Errorf("Unexpected ours for %+v", m)
+		}
+	}
+
+	// Stopping the plugin removes its mappings from the router and
+	// leaves the others
+	if err := plugin.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	igd.mu.Lock()
+	if _, exists := igd.mappings["TCP/80"]; exists || len(igd.mappings) != 1 {
+		t.Errorf("Expected the mapping to be removed on stop: %v", igd.mappings)
+	}
+	igd.mu.Unlock()
+
+	// Mappings are kept and requested again by the next instance
+	plugin = portmap.New()
+	if err := plugin.Init(context.Background(), deps); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	server = newTestServer(t, client, withConfig(cfg), withPlugins(plugin), withStorage(store), withEvents(eventStore))
+	var mappings []portmap.MappingInfo
+	json.Unmarshal(do(http.MethodGet, "/api/plugins/portmap/mappings", "").Body.Bytes(), &mappings)
+	if len(mappings) != 1 || mappings[0].Active || mappings[0].ExternalPort != 80 {
+		t.Fatalf("Unexpected mappings after restart: %+v", mappings)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	plugin.StartBackgroundTasks(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		json.Unmarshal(do(http.MethodGet, "/api/plugins/portmap/mappings", "").Body.Bytes(), &mappings)
+		if len(mappings) == 1 && mappings[0].Active || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	igd.mu.Lock()
+	if igd.mappings["TCP/80"] == nil {
+		t.Errorf("Expected the mapping to be requested again")
+	}
+	igd.mu.Unlock()
+
+	if rec := do(http.MethodDelete, "/api/plugins/portmap/mappings/"+mappings[0].ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Delete: expected 200, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/plugins/portmap/mappings/"+mappings[0].ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Delete again: expected 404, got %d", rec.Code)
+	}
+	igd.mu.Lock()
+	if _, exists := igd.mappings["TCP/80"]; exists {
+		t.Errorf("Expected the mapping to be removed from the router")
+	}
+	igd.mu.Unlock()
+}
+
+func TestPortMapPluginNATPMP(t *testing.T) {
+	// A NAT-PMP gateway granting the external port plus 1000 and half the
+	// requested lifetime
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var mu sync.Mutex
+	var requests [][]byte
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req := append([]byte(nil), buf[:n]...)
+			mu.Lock()
+			requests = append(requests, req)
+			mu.Unlock()
+			if req[1] == 0 {
+				resp := []byte{0, 128, 0, 0, 0, 0, 0, 1, 198, 51, 100, 9}
+				conn.WriteTo(resp, addr)
+				continue
+			}
+			resp := make([]byte, 16)
+			resp[1] = req[1] + 128
+			copy(resp[8:10], req[4:6])
+			external := binary.BigEndian.Uint16(req[6:])
+			if external != 0 {
+				external += 1000
+			}
+			binary.BigEndian.PutUint16(resp[10:], external)
+			binary.BigEndian.PutUint32(resp[12:], binary.BigEndian.Uint32(req[8:])/2)
+			conn.WriteTo(resp, addr)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "dns-id", "Name": "dns", "HostConfig": {"PortBindings": {"53/udp": [{"HostIp": "0.0.0.0", "HostPort": "5353"}]}}}`))
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.SetPluginConfig("portmap", &storage.PluginConfig{Enabled: true, Name: "Port Mapping"}); err != nil {
+		t.Fatalf("Failed to enable plugin: %v", err)
+	}
+
+	client := podman.NewClientWithHandler(mux)
+	eventStore := events.NewStore(100)
+	plugin := portmap.New()
+	deps := &plugins.PluginDependencies{Storage: store, EventStore: eventStore, Logger: log.New(io.Discard, "", 0), PodmanClient: client}
+	if err := plugin.Init(context.Background(), deps); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	server := newTestServer(t, client, withPlugins(plugin), withStorage(store), withEvents(eventStore))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/api/plugins/portmap/settings", `{"method": "natpmp", "gateway": "`+conn.LocalAddr().String()+`", "leaseSeconds": 600}`); rec.Code != http.StatusOK {
+		t.Fatalf("Settings: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var gw portmap.GatewayInfo
+	json.Unmarshal(do(http.MethodGet, "/api/plugins/portmap/gateway", "").Body.Bytes(), &gw)
+	if gw.Method != portmap.MethodNATPMP || gw.ExternalIP != "198.51.100.9" {
+		t.Errorf("Unexpected gateway: %+v", gw)
+	}
+
+	rec := do(http.MethodPost, "/api/plugins/portmap/mappings", `{"containerId": "dns", "protocol": "udp", "hostPort": 5353, "externalPort": 53}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Mapping: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created portmap.MappingInfo
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if !created.Active || created.Method != portmap.MethodNATPMP || created.ExternalPort != 53 || created.GrantedPort != 1053 {
+		t.Errorf("Unexpected mapping: %+v", created)
+	}
+	if created.RenewedAt == nil || created.ExpiresAt == nil || created.ExpiresAt.Sub(*created.RenewedAt).Seconds() != 300 {
+		t.Errorf("Expected the granted lifetime of 300s: %+v", created)
+	}
+
+	// NAT-PMP can't list mappings
+	if rec := do(http.MethodGet, "/api/plugins/portmap/router", ""); rec.Code != http.StatusNotImplemented {
+		t.Errorf("Router mappings: expected 501, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodDelete, "/api/plugins/portmap/mappings/"+created.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Delete: expected 200, got %d", rec.Code)
+	}
+	mu.Lock()
+	last := requests[len(requests)-1]
+	mu.Unlock()
+	if last[1] != 1 || binary.BigEndian.Uint16(last[4:]) != 5353 || binary.BigEndian.Uint32(last[8:]) != 0 {
+		t.Errorf("Expected a UDP delete request for port 5353, got %v", last)
+	}
+}
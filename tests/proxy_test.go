@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"podmanview/internal/podman"
+)
+
+func TestContainerProxy(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "podmanview_token", Value: "evil"})
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "app"})
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":   r.URL.RequestURI(),
+			"method": r.Method,
+			"prefix": r.Header.Get("X-Forwarded-Prefix"),
+			"cookie": r.Header.Get("Cookie"),
+			"auth":   r.Header.Get("Authorization"),
+		})
+	}))
+	defer app.Close()
+	_, appPort, _ := net.SplitHostPort(app.Listener.Addr().String())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "published":
+			// Container port 80 published on the port of the test app
+			fmt.Fprintf(w, `{"Id": "p1", "State": {"Running": true}, "HostConfig": {"PortBindings": {"80/tcp": [{"HostIp": "", "HostPort": %q}]}}}`, appPort)
+		case "bridged":
+			w.Write([]byte(`{"Id": "b1", "State": {"Running": true}, "NetworkSettings": {"Networks": {"podman": {"IPAddress": "127.0.0.1"}}}}`))
+		case "isolated":
+			w.Write([]byte(`{"Id": "i1", "State": {"Running": true}, "HostConfig": {"NetworkMode": "none"}}`))
+		case "stopped":
+			w.Write([]byte(`{"Id": "s1", "State": {"Running": false}}`))
+		default:
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+		}
+	})
+
+	cfg := newTestConfig(t, "PODMANVIEW_BASE_PATH=/pv\n")
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withConfig(cfg))
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Cookie", "podmanview_token=jwt; theme=dark")
+		req.Header.Set("Authorization", "Bearer jwt")
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodPost, "/pv/proxy/published/80/api/items?page=2")
+	var seen map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &seen); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected the app response, got %d %q", rec.Code, rec.Body)
+	}
+	want := map[string]string{"path": "/api/items?page=2", "method": "POST", "prefix": "/pv/proxy/published/80", "cookie": "theme=dark", "auth": ""}
+	for key, value := range want {
+		if seen[key] != value {
+			t.Errorf("Expected %s %q, got %q", key, value, seen[key])
+		}
+	}
+	if cookies := rec.Header().Values("Set-Cookie"); len(cookies) != 1 || !strings.HasPrefix(cookies[0], "session=app") {
+		t.Errorf("Expected only the app cookie, got %v", cookies)
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.HasPrefix(csp, "sandbox ") {
+		t.Errorf("Expected a sandboxed response, got %q", csp)
+	}
+
+	if rec := request(http.MethodGet, "/pv/proxy/bridged/"+appPort+"/login"); rec.Code != http.StatusFound || rec.Header().Get("Location") != "/pv/proxy/bridged/"+appPort+"/home" {
+		t.Errorf("Expected the redirect below the proxy path, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := request(http.MethodGet, "/pv/proxy/published/80?x=1"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/pv/proxy/published/80/?x=1" {
+		t.Errorf("Expected a redirect to the trailing slash, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	for path, status := range map[string]int{
+		"/pv/proxy/missing/80/":  http.StatusNotFound,
+		"/pv/proxy/stopped/80/":  http.StatusConflict,
+		"/pv/proxy/isolated/80/": http.StatusBadGateway,
+		"/pv/proxy/bridged/0/":   http.StatusBadRequest,
+		"/pv/proxy/bridged/x/":   http.StatusBadRequest,
+	} {
+		if rec := request(http.MethodGet, path); rec.Code != status {
+			t.Errorf("Expected %d for %s, got %d %q", status, path, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+	"podmanview/internal/registry"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected registry.Reference
+	}{
+		{"nginx", registry.Reference{Registry: "docker.io", Repository: "library/nginx"}},
+		{"nginx:1.27", registry.Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.27"}},
+		{"grafana/grafana", registry.Reference{Registry: "docker.io", Repository: "grafana/grafana"}},
+		{"quay.io/prometheus/node-exporter:v1.8.0", registry.Reference{Registry: "quay.io", Repository: "prometheus/node-exporter", Tag: "v1.8.0"}},
+		{"localhost:5000/app", registry.Reference{Registry: "localhost:5000", Repository: "app"}},
+		{"localhost/app:dev", registry.Reference{Registry: "localhost", Repository: "app", Tag: "dev"}},
+		{"ghcr.io/org/app@sha256:0123", registry.Reference{Registry: "ghcr.io", Repository: "org/app"}},
+	}
+	for _, tt := range tests {
+		if ref := registry.ParseReference(tt.image); ref != tt.expected {
+			t.Errorf("ParseReference(%q): expected %+v, got %+v", tt.image, tt.expected, ref)
+		}
+	}
+}
+
+// newTestRegistry serves team/app behind an anonymous bearer token:
+// tags in two pages, latest as a multi-platform index and 1.0 as a
+// single-platform image
+func newTestRegistry(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var tokens atomic.Int32
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:team/app:pull" || r.URL.Query().Get("service") != "test" {
+			http.Error(w, "bad scope", http.StatusBadRequest)
+			return
+		}
+		tokens.Add(1)
+		w.Write([]byte(`{"token": "abc", "expires_in": 300}`))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test",scope="repository:team/app:pull"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/team/app/tags/list":
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/team/app/tags/list?last=1.9&n=4>; rel="next"`)
+				w.Write([]byte(`{"name": "team/app", "tags": ["1.0", "dev", "1.9"]}`))
+				return
+			}
+			w.Write([]byte(`{"name": "team/app", "tags": ["latest", "1.10", "v2-rc"]}`))
+		case "/v2/team/app/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:index")
+			w.Write([]byte(`{"mediaType": "` + registry.MediaTypeOCIIndex + `", "manifests": [
+				{"platform": {"os": "linux", "architecture": "amd64"}},
+				{"platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+				{"platform": {"os": "unknown", "architecture": "unknown"}}
+			]}`))
+		case "/v2/team/app/manifests/1.0":
+			w.Header().Set("Content-Type", registry.MediaTypeDockerManifest)
+			w.Write([]byte(`{"config": {"digest": "sha256:config"}}`))
+		case "/v2/team/app/blobs/sha256:config":
+			w.Write([]byte(`{"os": "linux", "architecture": "arm", "variant": "v7"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &tokens
+}
+
+func TestRegistryClient(t *testing.T) {
+	server, tokens := newTestRegistry(t)
+	client := registry.NewClient([]string{server.URL})
+	ref := registry.ParseReference(strings.TrimPrefix(server.URL, "http://") + "/team/app")
+
+	tags, err := client.Tags(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Tags failed: %v", err)
+	}
+	if strings.Join(tags, " ") != "1.0 dev 1.9 latest 1.10 v2-rc" {
+		t.Errorf("Expected the tags of both pages, got %v", tags)
+	}
+
+	ref.Tag = "latest"
+	manifest, err := client.Inspect(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if manifest.Digest != "sha256:index" || manifest.MediaType != registry.MediaTypeOCIIndex ||
+		strings.Join(manifest.Platforms, " ") != "linux/amd64 linux/arm64/v8" {
+		t.Errorf("Unexpected index: %+v", manifest)
+	}
+
+	ref.Tag = "1.0"
+	manifest, err = client.Inspect(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if !strings.HasPrefix(manifest.Digest, "sha256:") || manifest.MediaType != registry.MediaTypeDockerManifest ||
+		strings.Join(manifest.Platforms, " ") != "linux/arm/v7" {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	if n := tokens.Load(); n != 1 {
+		t.Errorf("Expected the token to be cached, got %d tokens", n)
+	}
+
+	ref.Tag = "missing"
+	if _, err := client.Inspect(context.Background(), ref); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+	if _, err := client.Tags(context.Background(), registry.ParseReference("quay.io/team/app")); !errors.Is(err, registry.ErrNotConfigured) {
+		t.Errorf("Expected a registry not configured, got %v", err)
+	}
+}
+
+func TestRegistryAPI(t *testing.T) {
+	server, _ := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/images/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("term") != host+"/app" || r.URL.Query().Get("tlsVerify") != "false" || r.URL.Query().Get("limit") != "25" {
+			http.Error(w, `{"message": "unexpected query"}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[{"Index": "` + host + `", "Name": "` + host + `/team/app", "Description": "An app", "Stars": 3, "Official": "[OK]"}]`))
+	})
+
+	cfg := newTestConfig(t, "PODMANVIEW_REGISTRIES="+server.URL+",quay.io\n")
+	router := newTestServer(t, podman.NewClientWithHandler(mux), withConfig(cfg)).Router()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/v1/registry")
+	var list struct {
+		Registries []string `json:"registries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || strings.Join(list.Registries, " ") != host+" quay.io" {
+		t.Errorf("Expected the configured registries, got %+v %v", list, err)
+	}
+
+	rec = get("/api/v1/registry/search?q=app")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	var search struct {
+		Registry string                     `json:"registry"`
+		Results  []api.RegistrySearchResult `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&search); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	expected := api.RegistrySearchResult{Name: host + "/team/app", Description: "An app", Stars: 3, Official: true}
+	if search.Registry != host || len(search.Results) != 1 || search.Results[0] != expected {
+		t.Errorf("Unexpected search: %+v", search)
+	}
+
+	rec = get("/api/v1/registry/tags?limit=4&image=" + host + "/team/app")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	var tags struct {
+		Image string            `json:"image"`
+		Total int               `json:"total"`
+		Tags  []api.RegistryTag `json:"tags"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&tags); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if tags.Image != host+"/team/app" || tags.Total != 6 || len(tags.Tags) != 4 {
+		t.Fatalf("Unexpected tags: %+v", tags)
+	}
+	var names []string
+	for _, tag := range tags.Tags {
+		names = append(names, tag.Name)
+	}
+	if strings.Join(names, " ") != "latest v2-rc 1.10 1.9" {
+		t.Errorf("Expected latest and the newest versions first, got %v", names)
+	}
+	if tags.Tags[0].Digest != "sha256:index" || strings.Join(tags.Tags[0].Platforms, " ") != "linux/amd64 linux/arm64/v8" {
+		t.Errorf("Expected the digest and platforms of latest, got %+v", tags.Tags[0])
+	}
+	if tags.Tags[3].Error == "" {
+		t.Errorf("Expected an error for a tag without a manifest, got %+v", tags.Tags[3])
+	}
+
+	for path, code := range map[string]int{
+		"/api/v1/registry/search?q=app&registry=example.com":   http.StatusBadRequest,
+		"/api/v1/registry/search?q=app&limit=1000":             http.StatusBadRequest,
+		"/api/v1/registry/search":                              http.StatusBadRequest,
+		"/api/v1/registry/tags?image=example.com/app":          http.StatusBadRequest,
+		"/api/v1/registry/tags?image=" + host + "/team/absent": http.StatusNotFound,
+	} {
+		if rec := get(path); rec.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/registry"
+	"podmanview/internal/storage"
+)
+
+func TestRegistryCredentials(t *testing.T) {
+	expires := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+
+	// GitHub API: the account of a token, its scopes and expiry
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer ghp_good":
+			w.Header().Set("X-OAuth-Scopes", "read:packages, repo")
+			w.Header().Set("GitHub-Authentication-Token-Expiration", expires.Format("2006-01-02 15:04:05 MST"))
+		case "Bearer ghp_repo":
+			w.Header().Set("X-OAuth-Scopes", "repo")
+		default:
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"login": "octo"}`))
+	}))
+	defer github.Close()
+
+	// Gitea API: tokens created and deleted with the password
+	var giteaTokens []string
+	var deleted []string
+	gitea := http.NewServeMux()
+	gitea.HandleFunc("POST /api/v1/users/alice/tokens", func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "alice" || password != "secret" {
+			http.Error(w, `{"message": "unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Name   string   `json:"name"`
+			Scopes []string `json:"scopes"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		giteaTokens = append(giteaTokens, req.Name)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1, "name": "` + req.Name + `", "sha1": "gitea-token-` + req.Name + `"}`))
+	})
+	gitea.HandleFunc("DELETE /api/v1/users/alice/tokens/{name}", func(w http.ResponseWriter, r *http.Request) {
+		deleted = append(deleted, r.PathValue("name"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	giteaServer := httptest.NewServer(gitea)
+	defer giteaServer.Close()
+
+	// Podman: the login sent with pulls
+	pulls := make(map[string]string)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		login := ""
+		if header := r.Header.Get("X-Registry-Auth"); header != "" {
+			data, _ := base64.URLEncoding.DecodeString(header)
+			var auth map[string]string
+			json.Unmarshal(data, &auth)
+			login = auth["username"] + ":" + auth["password"]
+		}
+		pulls[r.URL.Query().Get("reference")] = login
+		w.Write([]byte(`{"id": "img"}`))
+	})
+
+	cfg := newTestConfig(t, "")
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	client := podman.NewClientWithHandler(mux)
+	eventStore := events.NewStore(20)
+	server := newTestServer(t, client, withConfig(cfg), withStorage(store), withEvents(eventStore))
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	pull := func(reference string) string {
+		if rec := request(http.MethodPost, "/api/v1/images/pull", `{"reference": "`+reference+`"}`); rec.Code != http.StatusOK {
+			t.Fatalf("Pull %s: expected 200, got %d %s", reference, rec.Code, rec.Body)
+		}
+		return pulls[reference]
+	}
+
+	// GitHub tokens are checked: the account, the packages scope and expiry
+	for _, tc := range []struct {
+		registry, body string
+		want           int
+	}{
+		{"ghcr.io", `{"token": "ghp_bad", "apiUrl": "` + github.URL + `"}`, http.StatusBadRequest},
+		{"ghcr.io", `{"token": "ghp_repo", "apiUrl": "` + github.URL + `"}`, http.StatusBadRequest},
+		{"ghcr.io", `{"token": "ghp_good", "apiUrl": "ftp://example.com"}`, http.StatusBadRequest},
+		{"registry.example.com", `{"token": "secret"}`, http.StatusBadRequest},
+		{"-bad", `{"username": "a", "token": "secret"}`, http.StatusBadRequest},
+		{"ghcr.io", `{"token": "ghp_good", "apiUrl": "` + github.URL + `"}`, http.StatusOK},
+	} {
+		if rec := request(http.MethodPut, "/api/v1/registry/credentials/"+tc.registry, tc.body); rec.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d %s", tc.registry, tc.body, tc.want, rec.Code, rec.Body)
+		}
+	}
+
+	var creds []api.RegistryCredential
+	json.Unmarshal(request(http.MethodGet, "/api/v1/registry/credentials", "").Body.Bytes(), &creds)
+	if len(creds) != 1 {
+		t.Fatalf("Expected 1 credential, got %+v", creds)
+	}
+	ghcr := creds[0]
+	if ghcr.Provider != registry.ProviderGitHub || ghcr.Username != "octo" || ghcr.Token != "[set]" || ghcr.ExpiresAt == nil || !ghcr.ExpiresAt.Equal(expires) {
+		t.Errorf("Unexpected credential %+v", ghcr)
+	}
+	if !strings.HasPrefix(ghcr.NewTokenURL, github.URL+"/settings/tokens/new?scopes=read:packages") {
+		t.Errorf("Expected the token page of GitHub, got %q", ghcr.NewTokenURL)
+	}
+
+	if login := pull("ghcr.io/octo/app:1.0"); login != "octo:ghp_good" {
+		t.Errorf("Expected the GitHub login with the pull, got %q", login)
+	}
+	if login := pull("docker.io/library/nginx:latest"); login != "" {
+		t.Errorf("Expected an anonymous pull, got %q", login)
+	}
+
+	// The masked token keeps the stored one
+	if rec := request(http.MethodPut, "/api/v1/registry/credentials/ghcr.io", `{"username": "octo", "token": "[set]", "apiUrl": "`+github.URL+`"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if login := pull("ghcr.io/octo/app:1.1"); login != "octo:ghp_good" {
+		t.Errorf("Expected the stored token, got %q", login)
+	}
+
+	// GitHub tokens can't be created through the API
+	rec := request(http.MethodPost, "/api/v1/registry/credentials/ghcr.io/token", `{"username": "octo", "password": "pw"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "/settings/tokens/new") {
+		t.Errorf("Expected a link to create the token, got %d %s", rec.Code, rec.Body)
+	}
+
+	// Gitea tokens are created with the password, replacing the previous one
+	path := "/api/v1/registry/credentials/git.example.com/token"
+	if rec := request(http.MethodPost, path, `{"username": "alice", "password": "wrong", "apiUrl": "`+giteaServer.URL+`"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a wrong password, got %d", rec.Code)
+	}
+	for i := 0; i < 2; i++ {
+		if rec := request(http.MethodPost, path, `{"username": "alice", "password": "secret", "apiUrl": "`+giteaServer.URL+`"}`); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+		}
+	}
+	if len(giteaTokens) != 2 || len(deleted) != 1 || deleted[0] != giteaTokens[0] {
+		t.Errorf("Expected the first token to be deleted, created %v, deleted %v", giteaTokens, deleted)
+	}
+	if login := pull("git.example.com/alice/app:1.0"); login != "alice:gitea-token-"+giteaTokens[1] {
+		t.Errorf("Expected the new Gitea token, got %q", login)
+	}
+
+	// Expiry warnings, once before and once after the token expired
+	registries := api.NewRegistryHandler(client, cfg, store, eventStore)
+	warnings := func() []string {
+		var details []string
+		for _, event := range eventStore.GetLast(20) {
+			if event.Type == events.EventRegistryToken {
+				details = append(details, event.Details)
+			}
+		}
+		return details
+	}
+	registries.CheckTokenExpiry(time.Now())
+	registries.CheckTokenExpiry(time.Now())
+	if got := warnings(); len(got) != 1 || !strings.Contains(got[0], "ghcr.io: token of octo expires in 3 days") {
+		t.Errorf("Expected one expiry warning, got %q", got)
+	}
+	registries.CheckTokenExpiry(time.Now().Add(96 * time.Hour))
+	if got := warnings(); len(got) != 2 || !strings.Contains(got[0]+got[1], "expired on") {
+		t.Errorf("Expected an expired warning, got %q", got)
+	}
+
+	if rec := request(http.MethodDelete, "/api/v1/registry/credentials/ghcr.io", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if login := pull("ghcr.io/octo/app:1.2"); login != "" {
+		t.Errorf("Expected an anonymous pull after delete, got %q", login)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/registry/credentials/ghcr.io", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
+
+func TestRegistryCredentialsBrowse(t *testing.T) {
+	// A private registry whose token service needs the login
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "bot" || password != "pat" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token": "private"}`))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer private" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name": "team/app", "tags": ["1.0"]}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+	name, _ := registry.Endpoint(server.URL)
+
+	client := registry.NewClient([]string{server.URL})
+	ref := registry.Reference{Registry: name, Repository: "team/app"}
+	if _, err := client.Tags(t.Context(), ref); err != registry.ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized without a login, got %v", err)
+	}
+	client = registry.NewClient([]string{server.URL})
+	client.SetCredentials(func(registry string) (string, string, bool) {
+		return "bot", "pat", registry == name
+	})
+	if tags, err := client.Tags(t.Context(), ref); err != nil || len(tags) != 1 {
+		t.Errorf("Expected the tags with the login, got %v %v", tags, err)
+	}
+}
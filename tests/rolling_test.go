@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+func TestRollingRestart(t *testing.T) {
+	var mu sync.Mutex
+	var steps []string
+	inspects := make(map[string]int)
+	health := map[string]string{"w1": "healthy", "w2": "healthy"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "w2", "Names": ["shop_web_2"], "State": "running", "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.container-number": "2"}},
+			{"Id": "w10", "Names": ["shop_web_10"], "State": "exited", "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.container-number": "10"}},
+			{"Id": "w1", "Names": ["shop_web_1"], "State": "running", "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.container-number": "1"}},
+			{"Id": "db", "Names": ["shop_db_1"], "State": "running", "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "db"}},
+			{"Id": "x1", "Names": ["blog_web_1"], "State": "running", "Labels": {"io.podman.compose.project": "blog", "com.docker.compose.service": "web"}}
+		]`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/restart", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, "restart "+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	// Replicas are starting on the first inspect after their restart
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := r.PathValue("id")
+		inspects[id]++
+		status := health[id]
+		if inspects[id] == 1 {
+			status = "starting"
+		}
+		steps = append(steps, id+" "+status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Id":    id,
+			"State": map[string]interface{}{"Status": "running", "Running": true, "Health": map[string]string{"Status": status}},
+		})
+	})
+
+	store := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withEvents(store))
+	restart := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/stacks/"+path, nil))
+		return rec
+	}
+
+	rec := restart("shop/services/web/restart")
+	var result struct {
+		Replicas []api.StackResult `json:"replicas"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	// Each replica is healthy before the next one restarts
+	if got := strings.Join(steps, ", "); got != "restart w1, w1 starting, w1 healthy, restart w2, w2 starting, w2 healthy" {
+		t.Errorf("Unexpected steps %q", got)
+	}
+	var statuses []string
+	for _, r := range result.Replicas {
+		statuses = append(statuses, r.Name+" "+r.Status)
+	}
+	if got := strings.Join(statuses, ", "); got != "shop_web_1 restarted, shop_web_2 restarted, shop_web_10 skipped" {
+		t.Errorf("Unexpected replicas %q", got)
+	}
+
+	// An unhealthy canary stops the rollout
+	mu.Lock()
+	steps = nil
+	inspects = make(map[string]int)
+	health["w1"] = "unhealthy"
+	mu.Unlock()
+	rec = restart("shop/services/web/restart?timeout=5")
+	var failure struct {
+		Code    string `json:"code"`
+		Details struct {
+			Replicas []api.StackResult `json:"replicas"`
+		} `json:"details"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &failure)
+	if rec.Code != http.StatusFailedDependency || failure.Code != "dependency_failed" {
+		t.Fatalf("Expected 424, got %d %s", rec.Code, rec.Body)
+	}
+	if got := strings.Join(steps, ", "); got != "restart w1, w1 starting, w1 unhealthy" {
+		t.Errorf("Expected the other replicas to be left alone, got %q", got)
+	}
+	if len(failure.Details.Replicas) != 3 || failure.Details.Replicas[0].Status != "failed" || failure.Details.Replicas[1].Status != "pending" {
+		t.Errorf("Unexpected replicas %+v", failure.Details.Replicas)
+	}
+
+	for path, want := range map[string]int{
+		"shop/services/cache/restart":           http.StatusNotFound,
+		"blog/services/db/restart":              http.StatusNotFound,
+		"shop/services/web/restart?timeout=abc": http.StatusBadRequest,
+	} {
+		if rec := restart(path); rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestScaleService(t *testing.T) {
+	var calls []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "w2", "Names": ["shop_web_2"], "State": "running", "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.container-number": "2"}},
+			{"Id": "w1", "Names": ["shop_web_1"], "State": "running", "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.container-number": "1"}}
+		]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"Id": "w1", "Name": "shop_web_1", "Image": "img1", "ImageName": "myapp:1.0",
+			"State": {"Status": "running", "Running": true},
+			"Config": {"Hostname": "web", "Env": ["PATH=/usr/bin", "MODE=prod"], "Labels": {"com.docker.compose.project": "shop", "com.docker.compose.service": "web", "com.docker.compose.container-number": "1"}},
+			"HostConfig": {"PortBindings": {"80/tcp": [{"HostPort": "8080"}], "9000/tcp": [{"HostPort": "0"}]}}
+		}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/images/{name}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "img1", "Config": {"Env": ["PATH=/usr/bin"]}}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		var ports []string
+		for _, p := range config.PortMappings {
+			ports = append(ports, fmt.Sprintf("%d:%d", p.HostPort, p.ContainerPort))
+		}
+		calls = append(calls, fmt.Sprintf("create %s #%s host=%q env=%s ports=%s", config.Name, config.Labels["com.docker.compose.container-number"], config.Hostname, config.Env["MODE"], strings.Join(ports, ",")))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "` + config.Name + `"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.PathValue("action")+" "+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "remove "+r.PathValue("id"))
+		w.Write([]byte(`[]`))
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+	scale := func(path, body string) (*httptest.ResponseRecorder, string) {
+		calls = nil
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/stacks/"+path, strings.NewReader(body)))
+		return rec, strings.Join(calls, ", ")
+	}
+
+	// New replicas follow the last one, with ports shifted from the first
+	rec, got := scale("shop/services/web/scale", `{"replicas": 4, "portStep": 10}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	want := `create shop_web_3 #3 host="" env=prod ports=8100:80,0:9000, start shop_web_3, ` +
+		`create shop_web_4 #4 host="" env=prod ports=8110:80,0:9000, start shop_web_4`
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	var result struct {
+		Replicas []api.StackResult `json:"replicas"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	var statuses []string
+	for _, r := range result.Replicas {
+		statuses = append(statuses, r.Name+" "+r.Status)
+	}
+	if got := strings.Join(statuses, ", "); got != "shop_web_1 unchanged, shop_web_2 unchanged, shop_web_3 started, shop_web_4 started" {
+		t.Errorf("Unexpected replicas %q", got)
+	}
+
+	if _, got := scale("shop/services/web/scale", `{"replicas": 3, "ports": "none"}`); got != `create shop_web_3 #3 host="" env=prod ports=, start shop_web_3` {
+		t.Errorf("Expected a replica without ports, got %q", got)
+	}
+
+	// Scaling down removes the last replicas
+	if rec, got := scale("shop/services/web/scale", `{"replicas": 1}`); rec.Code != http.StatusOK || got != "stop w2, remove w2" {
+		t.Errorf("Expected the last replica to be removed, got %d %q", rec.Code, got)
+	}
+	if _, got := scale("shop/services/web/scale", `{"replicas": 2}`); got != "" {
+		t.Errorf("Expected nothing to change, got %q", got)
+	}
+
+	for body, want := range map[string]int{
+		`{"replicas": 0}`:                    http.StatusBadRequest,
+		`{"replicas": 21}`:                   http.StatusBadRequest,
+		`{"replicas": 2, "ports": "random"}`: http.StatusBadRequest,
+		`{"replicas": 2, "portStep": -1}`:    http.StatusBadRequest,
+	} {
+		if rec, _ := scale("shop/services/web/scale", body); rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, rec.Code)
+		}
+	}
+	if rec, _ := scale("shop/services/db/scale", `{"replicas": 2}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown service, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestScheduledJobs(t *testing.T) {
+	var calls []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/system/prune", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "prune")
+		w.Write([]byte(`{"ContainerPruneReports": [{"Id": "c9"}], "ReclaimedSpace": 100}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "c1", "Names": ["web"], "State": "running", "Labels": {"io.containers.autoupdate": "registry"}},
+			{"Id": "c2", "Names": ["db"], "State": "running"}
+		]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"Id": "c1", "Name": "web", "Image": "old", "ImageName": "docker.io/library/nginx:latest",
+			"State": {"Status": "running", "Running": true},
+			"Config": {"Env": ["PATH=/usr/bin"]}
+		}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "pull "+r.URL.Query().Get("reference"))
+	})
+	// Image references contain slashes
+	mux.HandleFunc("GET /v4.0.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "nginx") {
+			w.Write([]byte(`{"Id": "new"}`))
+			return
+		}
+		w.Write([]byte(`{"Id": "old"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "stop "+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "remove "+r.PathValue("id"))
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		calls = append(calls, "create "+config.Name+" "+config.Image)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "c3"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "start "+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cfg := newTestConfig(t, "")
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	client := podman.NewClientWithHandler(mux)
+	eventStore := events.NewStore(10)
+	server := newTestServer(t, client, withConfig(cfg), withStorage(store), withEvents(eventStore))
+	scheduler := api.NewSchedulerHandler(client, store, api.NewBackupHandler(store, cfg, eventStore, nil), eventStore, nil)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	jobs := func() map[string]api.JobStatus {
+		var list []api.JobStatus
+		json.Unmarshal(request(http.MethodGet, "/api/v1/system/jobs", "").Body.Bytes(), &list)
+		result := make(map[string]api.JobStatus, len(list))
+		for _, job := range list {
+			result[job.Name] = job
+		}
+		return result
+	}
+
+	if got := jobs(); len(got) != 4 || got["prune"].Settings.Enabled || got["backup"].Settings.Keep != 7 {
+		t.Fatalf("Expected 4 disabled jobs, got %+v", got)
+	}
+
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	for _, tc := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/api/v1/system/jobs/prune", `{"enabled": true, "intervalHours": 24, "windows": [{"days": ["sat"], "start": "02:00", "end": "05:00"}]}`, http.StatusOK},
+		{"/api/v1/system/jobs/backup", `{"enabled": true, "intervalHours": 1, "windows": [{"days": ["fri"], "start": "23:00", "end": "01:00"}], "dir": "` + backupDir + `", "keep": 1}`, http.StatusOK},
+		{"/api/v1/system/jobs/prune", `{"enabled": true, "intervalHours": 0}`, http.StatusBadRequest},
+		{"/api/v1/system/jobs/prune", `{"enabled": true, "intervalHours": 24, "windows": [{"days": ["someday"], "start": "02:00", "end": "05:00"}]}`, http.StatusBadRequest},
+		{"/api/v1/system/jobs/prune", `{"enabled": true, "intervalHours": 24, "windows": [{"start": "25:00", "end": "05:00"}]}`, http.StatusBadRequest},
+		{"/api/v1/system/jobs/backup", `{"enabled": true, "intervalHours": 24, "dir": "backups"}`, http.StatusBadRequest},
+		{"/api/v1/system/jobs/reboot", `{"enabled": true, "intervalHours": 24}`, http.StatusNotFound},
+	} {
+		if rec := request(http.MethodPut, tc.path, tc.body); rec.Code != tc.want {
+			t.Errorf("PUT %s %s: expected %d, got %d %s", tc.path, tc.body, tc.want, rec.Code, rec.Body)
+		}
+	}
+
+	ctx := context.Background()
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	saturday := time.Date(2026, 10, 17, 3, 0, 0, 0, time.Local)
+
+	// Due, but outside the windows
+	scheduler.RunDue(ctx, friday)
+	if len(calls) != 0 {
+		t.Fatalf("Expected the jobs to be deferred, got %v", calls)
+	}
+	entries, _ := os.ReadDir(backupDir)
+	if len(entries) != 0 {
+		t.Fatalf("Expected no backup, got %d", len(entries))
+	}
+
+	// The backup window of Friday runs past midnight
+	scheduler.RunDue(ctx, time.Date(2026, 10, 17, 0, 30, 0, 0, time.Local))
+	scheduler.RunDue(ctx, saturday)
+	scheduler.RunDue(ctx, saturday.Add(time.Hour)) // prune is not due again
+	if strings.Join(calls, ",") != "prune" {
+		t.Errorf("Expected one prune, got %v", calls)
+	}
+	if entries, _ := os.ReadDir(backupDir); len(entries) != 1 {
+		t.Errorf("Expected one backup, got %d", len(entries))
+	}
+	status := jobs()
+	if prune := status["prune"]; prune.LastRun == nil || !prune.LastRun.Equal(saturday) || prune.LastResult != "1 containers, 0 images, 100 bytes reclaimed" {
+		t.Errorf("Unexpected prune status %+v", prune)
+	}
+	if backup := status["backup"]; backup.LastError != "" || !strings.HasPrefix(backup.LastResult, backupDir) {
+		t.Errorf("Unexpected backup status %+v", backup)
+	}
+
+	// Only the newest archives are kept
+	scheduler.RunDue(ctx, time.Date(2026, 10, 23, 23, 30, 0, 0, time.Local))
+	entries, _ = os.ReadDir(backupDir)
+	if len(entries) != 1 || !strings.Contains(entries[0].Name(), "20261023-233000") {
+		t.Errorf("Expected only the newest backup, got %v", entries)
+	}
+
+	// A window on another day defers the job, with its start as next window
+	day := time.Now().AddDate(0, 0, 3)
+	weekday := strings.ToLower(day.Format("Mon"))
+	request(http.MethodPut, "/api/v1/system/jobs/auto-update", `{"enabled": true, "intervalHours": 24, "windows": [{"days": ["`+weekday+`"], "start": "00:00", "end": "00:01"}]}`)
+	autoUpdate := jobs()["auto-update"]
+	next := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	if !autoUpdate.Deferred || autoUpdate.NextWindow == nil || !autoUpdate.NextWindow.Equal(next) {
+		t.Errorf("Expected the job to be deferred to %s, got %+v", next, autoUpdate)
+	}
+
+	// Jobs can run now, regardless of their windows
+	calls = nil
+	rec := request(http.MethodPost, "/api/v1/system/jobs/auto-update/run", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "1 containers updated: web") {
+		t.Fatalf("Expected the auto-update to run, got %d %s", rec.Code, rec.Body)
+	}
+	want := "pull docker.io/library/nginx:latest,stop c1,remove c1,create web docker.io/library/nginx:latest,start c3"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Unexpected calls\n got: %s\nwant: %s", got, want)
+	}
+}
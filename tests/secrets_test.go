@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/config"
+	"podmanview/internal/secrets"
+)
+
+func TestSecretsCipher(t *testing.T) {
+	c, err := secrets.FromPassphrase("correct horse")
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	other, _ := secrets.FromPassphrase("battery staple")
+
+	enc, err := c.Encrypt("s3cret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !secrets.IsEncrypted(enc) || strings.Contains(enc, "s3cret") {
+		t.Fatalf("Expected encrypted value, got %q", enc)
+	}
+
+	tests := []struct {
+		name    string
+		cipher  *secrets.Cipher
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"same key", c, enc, "s3cret", false},
+		{"wrong key", other, enc, "", true},
+		{"no cipher", nil, enc, "", true},
+		{"legacy plaintext", c, "plain", "plain", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfigEncryptsJWTSecret(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+
+	content := "PODMANVIEW_JWT_SECRET=plainsecret\nPODMANVIEW_SECRET_KEY_SOURCE=passphrase\n"
+	if err := os.WriteFile(envPath, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+
+	t.Setenv(config.EnvSecretPassphrase, "test-passphrase")
+
+	// First load encrypts the plaintext secret, second load decrypts it
+	for i := 0; i < 2; i++ {
+		cfg, err := config.Load(envPath)
+		if err != nil {
+			t.Fatalf("Load #%d failed: %v", i+1, err)
+		}
+		if cfg.JWTSecret() != "plainsecret" {
+			t.Errorf("Load #%d: expected decrypted secret, got %q", i+1, cfg.JWTSecret())
+		}
+
+		data, _ := os.ReadFile(envPath)
+		if strings.Contains(string(data), "plainsecret") {
+			t.Errorf("Load #%d: JWT secret stored in plaintext", i+1)
+		}
+	}
+
+	// Wrong passphrase must fail instead of silently generating a new secret
+	t.Setenv(config.EnvSecretPassphrase, "wrong")
+	if _, err := config.Load(envPath); err == nil {
+		t.Error("Expected error with wrong passphrase")
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestContainerSecurityReport(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "hardened", "Names": ["api"], "Image": "ghcr.io/example/api:2.3.1", "State": "running"},
+			{"Id": "risky", "Names": ["ha"], "Image": "ghcr.io/home-assistant/home-assistant", "State": "running"},
+			{"Id": "caps", "Names": ["vpn"], "Image": "localhost:5000/vpn:latest", "State": "running"},
+			{"Id": "infra", "Names": ["pod-infra"], "State": "running", "IsInfra": true}
+		]`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "hardened":
+			w.Write([]byte(`{"HostConfig": {"Memory": 268435456, "ReadonlyRootfs": true, "NetworkMode": "bridge"}}`))
+		case "risky":
+			w.Write([]byte(`{"HostConfig": {"Privileged": true, "NetworkMode": "host"}}`))
+		case "caps":
+			w.Write([]byte(`{"HostConfig": {"CapAdd": ["CAP_NET_ADMIN", "CAP_NET_RAW"], "Memory": 134217728}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers/security", nil))
+	var report api.SecurityReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected report, got %d %q", rec.Code, rec.Body)
+	}
+	if len(report.Containers) != 3 {
+		t.Fatalf("Expected 3 containers without infra, got %+v", report.Containers)
+	}
+
+	checks := func(c api.ContainerSecurity) map[string]string {
+		result := make(map[string]string)
+		for _, f := range c.Findings {
+			if f.Message == "" || f.Remediation == "" {
+				t.Errorf("Finding %s of %s without message or remediation", f.Check, c.Name)
+			}
+			result[f.Check] = f.Severity
+		}
+		return result
+	}
+
+	// Sorted by score, lowest first
+	risky, caps, hardened := report.Containers[0], report.Containers[1], report.Containers[2]
+	if risky.Name != "ha" || caps.Name != "vpn" || hardened.Name != "api" {
+		t.Fatalf("Unexpected order %s, %s, %s", risky.Name, caps.Name, hardened.Name)
+	}
+
+	got := checks(risky)
+	want := map[string]string{"privileged": "critical", "host_network": "high", "memory_limit": "medium", "latest_tag": "low", "writable_rootfs": "low"}
+	if len(got) != len(want) {
+		t.Errorf("Expected findings %v, got %v", want, got)
+	}
+	for check, severity := range want {
+		if got[check] != severity {
+			t.Errorf("Expected %s %s, got %q", check, severity, got[check])
+		}
+	}
+	if risky.Score != 10 {
+		t.Errorf("Expected score 10, got %d", risky.Score)
+	}
+
+	got = checks(caps)
+	if got["capabilities"] != "high" || got["latest_tag"] != "low" || got["writable_rootfs"] != "low" || len(got) != 3 || caps.Score != 65 {
+		t.Errorf("Unexpected findings %v with score %d", got, caps.Score)
+	}
+
+	if len(hardened.Findings) != 0 || hardened.Score != 100 {
+		t.Errorf("Expected no findings, got %+v", hardened.Findings)
+	}
+	if report.Score != 58 {
+		t.Errorf("Expected average score 58, got %d", report.Score)
+	}
+}
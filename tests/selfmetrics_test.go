@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestSelfMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "broken" {
+			http.Error(w, `{"message": "boom"}`, http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"Id": "` + r.PathValue("id") + `", "Name": "web", "State": {"Status": "running"}}`))
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withStorage(store))
+
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+	get := func(path string) (int, string) {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	self := func() api.SelfStats {
+		code, body := get("/api/system/self")
+		if code != http.StatusOK {
+			t.Fatalf("Self: expected 200, got %d: %s", code, body)
+		}
+		var stats api.SelfStats
+		json.Unmarshal([]byte(body), &stats)
+		return stats
+	}
+
+	for _, id := range []string{"a", "b", "broken"} {
+		get("/api/containers/" + id)
+	}
+	server.PruneEvents()
+
+	// An open event stream counts as a connection
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/events/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	stats := self()
+	if stats.Version != "1.2.3" || stats.Runtime.Goroutines == 0 || stats.Storage == nil || stats.Storage.FileSize == 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.Connections.Streams != 1 || stats.Connections.WebSockets != 0 || stats.Connections.Total != 1 {
+		t.Errorf("Expected one open stream, got %+v", stats.Connections)
+	}
+	var inspect *api.TimingStats
+	for i, route := range stats.Routes {
+		if route.Method == http.MethodGet && route.Name == "/api/containers/{id}" {
+			inspect = &stats.Routes[i]
+		}
+	}
+	if inspect == nil || inspect.Count != 3 || inspect.Errors != 1 {
+		t.Errorf("Expected 3 requests with 1 error by route pattern, got %+v", inspect)
+	}
+	if len(stats.Jobs) != 1 || stats.Jobs[0].Name != "event_pruning" || stats.Jobs[0].Count != 1 || stats.Jobs[0].LastRun == nil {
+		t.Errorf("Expected one event pruning run, got %+v", stats.Jobs)
+	}
+
+	// The stream is no longer counted once closed
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for self().Connections.Streams != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if streams := self().Connections.Streams; streams != 0 {
+		t.Errorf("Expected no open streams, got %d", streams)
+	}
+
+	code, body := get("/api/metrics")
+	if code != http.StatusOK {
+		t.Fatalf("Metrics: expected 200, got %d", code)
+	}
+	for _, line := range []string{
+		"# TYPE podmanview_http_request_duration_seconds histogram",
+		`podmanview_http_requests_total{method="GET",route="/api/containers/{id}"} 3`,
+		`podmanview_http_request_errors_total{method="GET",route="/api/containers/{id}"} 1`,
+		`podmanview_http_request_duration_seconds_count{method="GET",route="/api/containers/{id}"} 3`,
+		`podmanview_http_request_duration_seconds_bucket{method="GET",route="/api/containers/{id}",le="+Inf"} 3`,
+		`podmanview_job_runs_total{job="event_pruning"} 1`,
+		`podmanview_storage_bucket_bytes{bucket=`,
+		"podmanview_websocket_connections 0",
+		`podmanview_build_info{version="1.2.3"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in metrics:\n%s", line, body)
+		}
+	}
+}
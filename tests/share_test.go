@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/plugins"
+	"podmanview/internal/plugins/fileshare"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestShareLinks(t *testing.T) {
+	var tails []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if id := r.PathValue("id"); id != "web" && id != "c1" {
+			http.Error(w, `{"cause": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Id": "c1", "Name": "web", "ImageName": "nginx:1.27",
+			"State": {"Status": "running", "Running": true, "StartedAt": "2026-01-01T00:00:00Z", "Health": {"Status": "healthy"}},
+			"Config": {"Env": ["API_TOKEN=secret"]}}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Stats": [{"ContainerID": "c0", "CPU": 50}, {"ContainerID": "c1", "CPU": 1.5, "MemUsage": 1048576}]}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		tails = append(tails, r.URL.Query().Get("tail"))
+		w.Write([]byte("started\nready\n"))
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withStorage(store), withEvents(eventStore))
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	share := func(body string) api.ShareLink {
+		t.Helper()
+		rec := request(http.MethodPost, "/api/v1/containers/c1/share", body)
+		var link api.ShareLink
+		if err := json.Unmarshal(rec.Body.Bytes(), &link); err != nil || rec.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d %s", rec.Code, rec.Body)
+		}
+		return link
+	}
+
+	link := share(`{"expiresIn": 2}`)
+	if link.Container != "web" || link.Logs || !strings.HasPrefix(link.URL, "/shared/"+link.ID+".") {
+		t.Errorf("Unexpected link %+v", link)
+	}
+	if d := time.Until(link.ExpiresAt); d < time.Hour || d > 2*time.Hour {
+		t.Errorf("Expected the link to expire in 2 hours, got %v", d)
+	}
+	token := strings.TrimPrefix(link.URL, "/shared/")
+
+	if rec := request(http.MethodGet, link.URL, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/share/") {
+		t.Errorf("Expected the shared page, got %d", rec.Code)
+	}
+	rec := request(http.MethodGet, "/api/share/"+token, "")
+	var shared api.SharedContainer
+	if err := json.Unmarshal(rec.Body.Bytes(), &shared); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if shared.Name != "web" || shared.State != "running" || shared.Health != "healthy" || shared.Stats == nil || shared.Stats.CPU != 1.5 {
+		t.Errorf("Unexpected shared container %+v", shared)
+	}
+	if strings.Contains(rec.Body.String(), "API_TOKEN") {
+		t.Errorf("Expected the environment to stay private, got %s", rec.Body)
+	}
+	if rec := request(http.MethodGet, "/api/share/"+token+"/logs", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected logs of a link without logs to be forbidden, got %d", rec.Code)
+	}
+
+	// Tampered tokens and unknown links are rejected alike
+	for _, bad := range []string{token[:len(token)-2] + "xx", "0123456789abcdef.sig", "nodot"} {
+		if rec := request(http.MethodGet, "/api/share/"+bad, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", bad, rec.Code)
+		}
+		if rec := request(http.MethodGet, "/shared/"+bad, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected the page to be 404, got %d", bad, rec.Code)
+		}
+	}
+
+	withLogs := share(`{"logs": true}`)
+	logsPath := "/api/share/" + strings.TrimPrefix(withLogs.URL, "/shared/") + "/logs?tail=9999"
+	rec = request(http.MethodGet, logsPath, "")
+	var logs api.LogsResponse
+	json.Unmarshal(rec.Body.Bytes(), &logs)
+	if rec.Code != http.StatusOK || strings.Join(logs.Lines, ",") != "ready,started" || strings.Join(tails, ",") != "500" {
+		t.Errorf("Expected the last 500 lines at most, got %d %v (tail %v)", rec.Code, logs.Lines, tails)
+	}
+
+	rec = request(http.MethodGet, "/api/v1/shares", "")
+	var links []api.ShareLink
+	json.Unmarshal(rec.Body.Bytes(), &links)
+	if len(links) != 2 || links[0].URL != "" {
+		t.Errorf("Expected 2 links without URLs, got %+v", links)
+	}
+
+	// Revoked links stop working
+	if rec := request(http.MethodDelete, "/api/v1/shares/"+link.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request(http.MethodGet, "/api/share/"+token, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected a revoked link to be 404, got %d", rec.Code)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/shares/"+link.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a revoked link, got %d", rec.Code)
+	}
+	if got := eventStore.GetLast(1); len(got) != 1 || got[0].Type != events.EventShareUpdate || got[0].Details != "web: link "+link.ID+" revoked" {
+		t.Errorf("Unexpected event %+v", got)
+	}
+
+	for body, want := range map[string]int{
+		`{"expiresIn": -1}`:  http.StatusBadRequest,
+		`{"expiresIn": 721}`: http.StatusBadRequest,
+		`not json`:           http.StatusBadRequest,
+	} {
+		if rec := request(http.MethodPost, "/api/v1/containers/c1/share", body); rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, rec.Code)
+		}
+	}
+	if rec := request(http.MethodPost, "/api/v1/containers/missing/share", `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing container, got %d", rec.Code)
+	}
+}
+
+// The shared page doesn't clash with the file shares of the fileshare plugin
+func TestShareLinksWithFileShare(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "c1", "Name": "web", "State": {"Status": "running", "Running": true}}`))
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.SetPluginConfig("fileshare", &storage.PluginConfig{Enabled: true, Name: "File Sharing"}); err != nil {
+		t.Fatalf("Failed to enable plugin: %v", err)
+	}
+	plugin := fileshare.New()
+	if err := plugin.Init(context.Background(), &plugins.PluginDependencies{Storage: store, Logger: log.New(io.Discard, "", 0)}); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withPlugins(plugin), withStorage(store))
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := request(http.MethodPost, "/api/v1/containers/c1/share", `{}`)
+	var link api.ShareLink
+	if err := json.Unmarshal(rec.Body.Bytes(), &link); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request(http.MethodGet, link.URL, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/share/") {
+		t.Errorf("Expected the shared page, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request(http.MethodGet, "/share/missing", ""); rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "/api/share/") {
+		t.Errorf("Expected file shares to stay with the plugin, got %d", rec.Code)
+	}
+}
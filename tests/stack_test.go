@@ -0,0 +1,296 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+)
+
+func TestCreateStack(t *testing.T) {
+	var mu sync.Mutex
+	var created, started []string
+	deps := make(map[string][]string)
+	inspects := make(map[string]int)
+	migrateExit := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		mu.Lock()
+		defer mu.Unlock()
+		created = append(created, config.Name)
+		deps[config.Name] = config.Dependencies
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "` + config.Name + `"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		started = append(started, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := r.PathValue("id")
+		inspects[id]++
+		switch id {
+		case "db":
+			// Healthy on the second inspect
+			health := "starting"
+			if inspects[id] > 1 {
+				health = "healthy"
+			}
+			w.Write([]byte(`{"Id": "db", "State": {"Status": "running", "Running": true, "Health": {"Status": "` + health + `"}}}`))
+		case "migrate":
+			if inspects[id] == 1 {
+				w.Write([]byte(`{"Id": "migrate", "State": {"Status": "running", "Running": true}}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"Id":    "migrate",
+				"State": map[string]interface{}{"Status": "exited", "ExitCode": migrateExit},
+			})
+		}
+	})
+
+	store := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withEvents(store))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/containers/stack", strings.NewReader(body)))
+		return rec
+	}
+	reset := func() {
+		created, started = nil, nil
+		deps = make(map[string][]string)
+		inspects = make(map[string]int)
+	}
+
+	const stack = `{"start": true, "containers": [
+		{"name": "app", "image": "myapp", "requires": "db, migrate, external"},
+		{"name": "worker", "image": "myapp", "after": "app"},
+		{"name": "migrate", "image": "myapp", "requires": "db", "init": true},
+		{"name": "db", "image": "postgres"}
+	]}`
+	rec := post(stack)
+	var result struct {
+		Containers []api.StackResult `json:"containers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %q", rec.Code, rec.Body)
+	}
+	if got := strings.Join(created, ","); got != "db,migrate,app,worker" {
+		t.Errorf("Unexpected create order %s", got)
+	}
+	if got := strings.Join(started, ","); got != "db,migrate,app,worker" {
+		t.Errorf("Unexpected start order %s", got)
+	}
+	if got := strings.Join(deps["app"], ","); got != "db,external" {
+		t.Errorf("Expected init containers to be left out of the Podman dependencies, got %s", got)
+	}
+	if len(deps["worker"]) != 0 || inspects["db"] != 3 || inspects["migrate"] != 2 {
+		t.Errorf("Unexpected dependencies %v or inspects %v", deps, inspects)
+	}
+	for _, c := range result.Containers {
+		if c.Status != "started" || c.ID != c.Name {
+			t.Errorf("Unexpected result %+v", c)
+		}
+	}
+
+	// A failed init container stops the stack before its dependents start
+	reset()
+	migrateExit = 3
+	rec = post(stack)
+	var failure struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details struct {
+			Containers []api.StackResult `json:"containers"`
+		} `json:"details"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &failure)
+	if rec.Code != http.StatusFailedDependency || failure.Code != "dependency_failed" || !strings.Contains(failure.Message, "migrate exited with code 3") {
+		t.Fatalf("Expected 424, got %d %q", rec.Code, rec.Body)
+	}
+	if got := strings.Join(started, ","); got != "db,migrate" {
+		t.Errorf("Unexpected start order %s", got)
+	}
+	statuses := make(map[string]string)
+	for _, c := range failure.Details.Containers {
+		statuses[c.Name] = c.Status
+	}
+	if statuses["app"] != "failed" || statuses["worker"] != "created" || statuses["db"] != "started" {
+		t.Errorf("Unexpected statuses %v", statuses)
+	}
+
+	// Only created
+	reset()
+	if rec := post(`{"containers": [{"name": "db", "image": "postgres"}]}`); rec.Code != http.StatusCreated || len(created) != 1 || len(started) != 0 {
+		t.Errorf("Expected the container to be created only, got %d %v", rec.Code, started)
+	}
+
+	for body, message := range map[string]string{
+		`{"containers": []}`: "Containers are required",
+		`{"containers": [{"name": "a", "image": "x", "requires": "b"}, {"name": "b", "image": "x", "after": "a"}]}`: "Dependency cycle between containers: a, b",
+		`{"containers": [{"name": "a", "image": "x", "after": "b"}]}`:                                               "Unknown container in after: b",
+		`{"containers": [{"name": "a", "image": "x"}, {"name": "a", "image": "x"}]}`:                                "Duplicate container name: a",
+		`{"containers": [{"image": "x"}]}`:                                                                          "Name is required",
+	} {
+		reset()
+		rec := post(body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), message) || len(created) != 0 {
+			t.Errorf("Expected 400 %q for %s, got %d %q", message, body, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestRedeployStack(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	call := func(c string) {
+		mu.Lock()
+		calls = append(calls, c)
+		mu.Unlock()
+	}
+	takeCalls := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		result := strings.Join(calls, ", ")
+		calls = nil
+		return result
+	}
+
+	// db is unchanged, app has a new environment, proxy a new image and
+	// ports, and worker doesn't exist yet
+	containers := map[string]string{
+		"db": `{"Id": "db1", "Name": "db", "Image": "pg1", "ImageName": "postgres:16",
+			"State": {"Status": "running", "Running": true},
+			"Config": {"Env": ["PATH=/usr/bin", "POSTGRES_PASSWORD=secret", "HOSTNAME=db1"]},
+			"HostConfig": {"PortBindings": {"5432/tcp": [{"HostPort": "5432"}]}}}`,
+		"app": `{"Id": "app1", "Name": "app", "Image": "my1", "ImageName": "myapp:1.0",
+			"State": {"Status": "running", "Running": true},
+			"Config": {"Env": ["PATH=/usr/bin", "DEBUG=true", "OLD=1"]},
+			"HostConfig": {"PortBindings": {"80/tcp": [{"HostPort": "8080"}]}}}`,
+		"proxy": `{"Id": "proxy1", "Name": "proxy", "Image": "ng1", "ImageName": "nginx:1.27",
+			"State": {"Status": "exited"},
+			"Config": {"Env": ["PATH=/usr/bin"]},
+			"HostConfig": {"PortBindings": {"80/tcp": [{"HostPort": "80"}]}}}`,
+	}
+	images := map[string]string{"pg1": "pg1", "postgres:16": "pg1", "my1": "my1", "myapp:1.0": "my1", "ng1": "ng1", "nginx:1.27": "ng2", "worker:1": "wk1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		info, ok := containers[r.PathValue("id")]
+		if !ok {
+			http.Error(w, `{"cause": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(info))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		call("pull " + r.URL.Query().Get("reference"))
+		w.Write([]byte(`{"id": "x"}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod/images/"), "/json")
+		w.Write([]byte(`{"Id": "` + images[name] + `", "Config": {"Env": ["PATH=/usr/bin"]}}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		call("stop " + r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		call("remove " + r.PathValue("id"))
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		call("create " + config.Name + " " + config.Image)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "` + config.Name + `2"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		call("start " + r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	store := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withEvents(store))
+	redeploy := func(query string) (int, map[string]api.StackChange) {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/containers/stack/redeploy"+query, strings.NewReader(`{"start": true, "containers": [
+			{"name": "app", "image": "myapp:1.0", "env": "DEBUG=false", "ports": "8080:80", "requires": "db"},
+			{"name": "db", "image": "postgres:16", "env": "POSTGRES_PASSWORD=secret", "ports": "5432:5432"},
+			{"name": "proxy", "image": "nginx:1.27", "ports": "443:443", "after": "app"},
+			{"name": "worker", "image": "worker:1", "after": "db"}
+		]}`)))
+		var result struct {
+			Containers []api.StackChange `json:"containers"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		changes := make(map[string]api.StackChange)
+		for _, c := range result.Containers {
+			changes[c.Name] = c
+		}
+		return rec.Code, changes
+	}
+
+	// The plan pulls the images but changes nothing
+	code, plan := redeploy("?dryRun=true")
+	if code != http.StatusOK || len(plan) != 4 {
+		t.Fatalf("Expected the plan, got %d %+v", code, plan)
+	}
+	for name, want := range map[string]string{
+		"db":     "keep: ",
+		"app":    "recreate: env DEBUG changed, env OLD removed",
+		"proxy":  "recreate: image digest ng1 → ng2, ports 80:80/tcp → 443:443/tcp",
+		"worker": "create: ",
+	} {
+		if got := plan[name].Action + ": " + strings.Join(plan[name].Changes, ", "); got != want || plan[name].Status != "pending" {
+			t.Errorf("%s: expected %q, got %q (%s)", name, want, got, plan[name].Status)
+		}
+	}
+	if got := takeCalls(); got != "pull postgres:16, pull myapp:1.0, pull nginx:1.27" {
+		t.Errorf("Expected only pulls of the existing containers, got %q", got)
+	}
+
+	// Only the changed containers are recreated, keeping their state, in
+	// dependency order
+	code, result := redeploy("")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %+v", code, result)
+	}
+	want := "pull postgres:16, pull myapp:1.0, pull nginx:1.27, " +
+		"create worker worker:1, start worker2, " +
+		"stop app1, remove app1, create app myapp:1.0, start app2, " +
+		"remove proxy1, create proxy nginx:1.27"
+	if got := takeCalls(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	for name, want := range map[string]string{"db": "unchanged", "app": "recreated", "proxy": "recreated", "worker": "started"} {
+		if result[name].Status != want {
+			t.Errorf("%s: expected %s, got %+v", name, want, result[name])
+		}
+	}
+
+	var updates int
+	for _, event := range store.GetLast(20) {
+		if event.Type == events.EventContainerUpdate && event.Success {
+			updates++
+		}
+	}
+	if updates != 2 {
+		t.Errorf("Expected 2 update events, got %d", updates)
+	}
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestStackEnvFiles(t *testing.T) {
+	created := make(map[string]map[string]string)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		created[config.Name] = config.Env
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "` + config.Name + `"}`))
+	})
+	// app runs with the first version of the file
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "app" {
+			http.Error(w, `{"cause": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"Id": "app", "Name": "app", "Image": "my1", "ImageName": "myapp:1.0",
+			"Config": {"Env": ["PATH=/usr/bin", "DB_PASSWORD=hunter2", "MODE=prod", "GREETING=hello world"]}}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "my1"}`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "my1", "Config": {"Env": ["PATH=/usr/bin"]}}`))
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withStorage(store), withEvents(eventStore))
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	put := func(content string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"content": content})
+		return request(http.MethodPut, "/api/v1/stacks/shop/env", string(body))
+	}
+
+	if rec := request(http.MethodGet, "/api/v1/stacks/shop/env", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 before the file is saved, got %d", rec.Code)
+	}
+	if rec := request(http.MethodPut, "/api/v1/stacks/-shop/env", `{"content": ""}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid stack name, got %d", rec.Code)
+	}
+	if rec := put("API_TOKEN=********\n"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a masked new variable, got %d", rec.Code)
+	}
+
+	rec := put("# shop\nDB_PASSWORD=hunter2\nMODE=prod\nGREETING=\"hello world\"\n")
+	var file api.StackEnvFile
+	if err := json.Unmarshal(rec.Body.Bytes(), &file); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if want := "DB_PASSWORD=********\nGREETING=hello world\nMODE=prod\n"; file.Content != want || len(file.Vars) != 3 || !file.Vars[0].Secret {
+		t.Errorf("Expected the secret to be masked, got %+v", file)
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("Secret value returned: %s", rec.Body)
+	}
+
+	// Containers get the file, their own variables take precedence
+	rec = request(http.MethodPost, "/api/v1/containers/stack", `{"name": "shop", "containers": [
+		{"name": "app", "image": "myapp:1.0"},
+		{"name": "worker", "image": "myapp:1.0", "env": "MODE=worker"}
+	]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rec.Code, rec.Body)
+	}
+	if env := created["app"]; env["DB_PASSWORD"] != "hunter2" || env["MODE"] != "prod" || env["GREETING"] != "hello world" {
+		t.Errorf("Expected the file in app, got %v", env)
+	}
+	if env := created["worker"]; env["DB_PASSWORD"] != "hunter2" || env["MODE"] != "worker" {
+		t.Errorf("Expected the variable of worker to win, got %v", env)
+	}
+
+	// Saving the masked content back keeps the secret
+	if rec := put(file.Content + "DEBUG=1\n"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	var plan struct {
+		Containers []api.StackChange `json:"containers"`
+	}
+	rec = request(http.MethodPost, "/api/v1/containers/stack/redeploy?dryRun=true", `{"name": "shop", "containers": [{"name": "app", "image": "myapp:1.0"}]}`)
+	json.Unmarshal(rec.Body.Bytes(), &plan)
+	if len(plan.Containers) != 1 || strings.Join(plan.Containers[0].Changes, ", ") != "env DEBUG added" {
+		t.Errorf("Expected only the new variable to change, got %d %s", rec.Code, rec.Body)
+	}
+
+	// The audit log masks secrets too
+	if rec := put("DB_PASSWORD=swordfish\nMODE=prod\n"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	var details []string
+	for _, event := range eventStore.GetLast(20) {
+		if event.Type == events.EventStackEnv {
+			details = append(details, event.Details)
+		}
+	}
+	if len(details) != 3 || details[0] != "shop: DB_PASSWORD=********, DEBUG removed, GREETING removed" {
+		t.Errorf("Unexpected events %q", details)
+	}
+	for _, d := range details {
+		if strings.Contains(d, "hunter2") || strings.Contains(d, "swordfish") {
+			t.Errorf("Secret value in the event log: %q", d)
+		}
+	}
+
+	var files []api.StackEnvFile
+	json.Unmarshal(request(http.MethodGet, "/api/v1/stacks/env", "").Body.Bytes(), &files)
+	if len(files) != 1 || files[0].Stack != "shop" || files[0].UpdatedBy == "" {
+		t.Errorf("Unexpected files %+v", files)
+	}
+
+	if rec := request(http.MethodDelete, "/api/v1/stacks/shop/env", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/stacks/shop/env", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
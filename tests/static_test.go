@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/config"
+	"podmanview/web/static"
+)
+
+func TestEmbeddedStatic(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	version := static.Version()
+	server := newTestServer(t, nil, withConfig(cfg), withStaticVersion(version))
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Served from the binary, not from the working directory
+	rec := get("/static/js/app.js?v="+version, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), "javascript") {
+		t.Fatalf("Expected 200 JavaScript, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("Expected immutable caching for versioned asset, got %q", cc)
+	}
+
+	// Unversioned or outdated URLs and the service worker are revalidated
+	for _, path := range []string{"/static/js/app.js", "/static/js/app.js?v=old", "/static/sw.js?v=" + version} {
+		rec = get(path, "")
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-cache" || etag == "" {
+			t.Errorf("%s: expected 200 no-cache with ETag, got %d %q %q", path, rec.Code, rec.Header().Get("Cache-Control"), etag)
+			continue
+		}
+		if rec = get(path, etag); rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 for matching ETag, got %d", path, rec.Code)
+		}
+	}
+
+	for _, path := range []string{"/static/missing.js", "/static/embed.go", "/static/js/"} {
+		if rec = get(path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+
+	// The page references assets with the same version
+	rec = get("/", "")
+	if !strings.Contains(rec.Body.String(), "/static/js/app.js?v="+version) {
+		t.Errorf("Index does not reference versioned app.js")
+	}
+}
@@ -1,12 +1,16 @@
 package tests
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"podmanview/internal/secrets"
 	"podmanview/internal/storage"
 )
 
@@ -264,3 +268,446 @@ func TestBoltStorage(t *testing.T) {
 		}
 	})
 }
+
+func TestBoltStorageSettingsSnapshot(t *testing.T) {
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	store.EnablePlugin("temperature")
+	store.SetInt("temperature", "updateInterval", 30)
+
+	snapshot, err := store.ExportSettings()
+	if err != nil {
+		t.Fatalf("ExportSettings failed: %v", err)
+	}
+
+	store.DisablePlugin("temperature")
+	store.SetInt("temperature", "updateInterval", 60)
+	store.SetString("reactor", "mode", "auto")
+
+	if err := store.ImportSettings(snapshot); err != nil {
+		t.Fatalf("ImportSettings failed: %v", err)
+	}
+
+	if enabled, _ := store.IsPluginEnabled("temperature"); !enabled {
+		t.Error("Expected temperature plugin to be enabled after import")
+	}
+	if interval, _ := store.GetInt("temperature", "updateInterval"); interval != 30 {
+		t.Errorf("Expected updateInterval 30, got %d", interval)
+	}
+	if _, err := store.GetString("reactor", "mode"); err != storage.ErrNotFound {
+		t.Errorf("Expected data added after export to be removed, got %v", err)
+	}
+}
+
+func TestStorageBackends(t *testing.T) {
+	for _, backend := range []string{storage.BackendBolt, storage.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := storage.Open(backend, filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("Failed to open storage: %v", err)
+			}
+			defer store.Close()
+
+			if err := store.SetPluginConfig("temperature", &storage.PluginConfig{Enabled: true, Name: "Temperature"}); err != nil {
+				t.Fatalf("SetPluginConfig failed: %v", err)
+			}
+			store.DisablePlugin("temperature")
+			store.EnablePlugin("led")
+
+			cfg, err := store.GetPluginConfig("temperature")
+			if err != nil || cfg.Enabled || cfg.Name != "Temperature" {
+				t.Errorf("Unexpected plugin config: %+v, %v", cfg, err)
+			}
+			if enabled, _ := store.ListEnabledPlugins(); len(enabled) != 1 || enabled[0] != "led" {
+				t.Errorf("Expected only led enabled, got %v", enabled)
+			}
+			if _, err := store.GetPluginConfig("missing"); err != storage.ErrPluginNotFound {
+				t.Errorf("Expected ErrPluginNotFound, got %v", err)
+			}
+
+			store.SetInt("temperature", "interval", 30)
+			store.SetBool("temperature", "mqtt", true)
+			if v, _ := store.GetInt("temperature", "interval"); v != 30 {
+				t.Errorf("Expected interval 30, got %d", v)
+			}
+			if v, _ := store.GetBool("temperature", "mqtt"); !v {
+				t.Error("Expected mqtt true")
+			}
+			if err := store.Delete("temperature", "mqtt"); err != nil {
+				t.Errorf("Delete failed: %v", err)
+			}
+			if _, err := store.Get("temperature", "mqtt"); err != storage.ErrNotFound {
+				t.Errorf("Expected ErrNotFound after delete, got %v", err)
+			}
+			if data, _ := store.List("temperature"); len(data) != 1 {
+				t.Errorf("Expected 1 key, got %d", len(data))
+			}
+
+			base := time.Now()
+			for i, cmd := range []string{"ls", "ls", "pwd", "whoami"} {
+				store.SaveCommandHistory(cmd, base.Add(time.Duration(i)*time.Second))
+			}
+			store.TrimCommandHistory(2)
+			history, err := store.GetCommandHistory(10)
+			if err != nil || len(history) != 2 || history[0].Command != "pwd" || history[1].Command != "whoami" {
+				t.Errorf("Unexpected history: %+v, %v", history, err)
+			}
+
+			var buf bytes.Buffer
+			if n, err := store.Backup(&buf); err != nil || n == 0 {
+				t.Errorf("Backup failed: %d bytes, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestStorageMigrate(t *testing.T) {
+	dir := t.TempDir()
+
+	src, err := storage.NewBoltStorage(filepath.Join(dir, "podmanview.db"))
+	if err != nil {
+		t.Fatalf("Failed to open bolt storage: %v", err)
+	}
+	defer src.Close()
+
+	dst, err := storage.NewSQLiteStorage(filepath.Join(dir, "podmanview.sqlite"))
+	if err != nil {
+		t.Fatalf("Failed to open sqlite storage: %v", err)
+	}
+	defer dst.Close()
+
+	src.EnablePlugin("reactor")
+	src.SetJSON("reactor", "blocks", []string{"a", "b"})
+	src.SaveCommandHistory("uptime", time.Now())
+
+	if err := storage.Migrate(src, dst); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	if enabled, _ := dst.IsPluginEnabled("reactor"); !enabled {
+		t.Error("Expected reactor plugin enabled after migration")
+	}
+	var blocks []string
+	if err := dst.GetJSON("reactor", "blocks", &blocks); err != nil || len(blocks) != 2 {
+		t.Errorf("Unexpected blocks: %v, %v", blocks, err)
+	}
+	if last, _ := dst.GetLastCommand(); last != "uptime" {
+		t.Errorf("Expected last command uptime, got %q", last)
+	}
+}
+
+func TestStorageArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	src, err := storage.NewBoltStorage(filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer src.Close()
+
+	src.EnablePlugin("temperature")
+	src.SetInt("temperature", "updateInterval", 15)
+	src.SaveCommandHistory("podman ps", time.Now())
+
+	var archive bytes.Buffer
+	extra := map[string][]byte{"events.json": []byte("[]")}
+	if err := storage.WriteArchive(&archive, src, storage.BackendBolt, extra); err != nil {
+		t.Fatalf("WriteArchive failed: %v", err)
+	}
+
+	// Restore into the other backend with existing data that must be replaced
+	dst, err := storage.NewSQLiteStorage(filepath.Join(dir, "dst.sqlite"))
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer dst.Close()
+	dst.SetString("led", "mode", "on")
+	dst.SaveCommandHistory("rm -rf /tmp/x", time.Now())
+
+	manifest, files, err := storage.RestoreArchive(bytes.NewReader(archive.Bytes()), dst)
+	if err != nil {
+		t.Fatalf("RestoreArchive failed: %v", err)
+	}
+	if manifest.Backend != storage.BackendBolt {
+		t.Errorf("Expected backend bolt, got %q", manifest.Backend)
+	}
+	if string(files["events.json"]) != "[]" {
+		t.Errorf("Expected events file to be returned, got %q", files["events.json"])
+	}
+	if v, _ := dst.GetInt("temperature", "updateInterval"); v != 15 {
+		t.Errorf("Expected updateInterval 15, got %d", v)
+	}
+	if _, err := dst.Get("led", "mode"); err != storage.ErrNotFound {
+		t.Errorf("Expected old data to be replaced, got %v", err)
+	}
+	if history, _ := dst.GetCommandHistory(10); len(history) != 1 || history[0].Command != "podman ps" {
+		t.Errorf("Unexpected history after restore: %+v", history)
+	}
+
+	// Corrupt archives are rejected without touching the storage
+	for name, data := range map[string][]byte{
+		"not gzip":  []byte("hello"),
+		"truncated": archive.Bytes()[:archive.Len()/2],
+	} {
+		if _, _, err := storage.RestoreArchive(bytes.NewReader(data), dst); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if v, _ := dst.GetInt("temperature", "updateInterval"); v != 15 {
+		t.Errorf("Storage changed by a rejected restore: updateInterval %d", v)
+	}
+}
+
+func TestStorageMaintenance(t *testing.T) {
+	for _, backend := range []string{storage.BackendBolt, storage.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := storage.Open(backend, filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("Failed to open storage: %v", err)
+			}
+			defer store.Close()
+
+			// Write and delete enough data to leave free pages behind
+			value := bytes.Repeat([]byte("x"), 4096)
+			for i := 0; i < 200; i++ {
+				store.Set("bulk", fmt.Sprintf("key%d", i), value)
+			}
+			for i := 0; i < 200; i++ {
+				store.Delete("bulk", fmt.Sprintf("key%d", i))
+			}
+			store.SetString("temperature", "unit", "C")
+
+			result, err := storage.Maintain(store)
+			if err != nil {
+				t.Fatalf("Maintain failed: %v", err)
+			}
+			if len(result.Errors) > 0 || !result.Compacted {
+				t.Fatalf("Unexpected result: %+v", result)
+			}
+			if result.SizeAfter >= result.SizeBefore {
+				t.Errorf("Expected compaction to shrink the file: %d -> %d", result.SizeBefore, result.SizeAfter)
+			}
+
+			// Storage keeps working after the file was replaced
+			if v, err := store.GetString("temperature", "unit"); err != nil || v != "C" {
+				t.Errorf("Expected unit C after compaction, got %q, %v", v, err)
+			}
+
+			stats, err := store.Stats()
+			if err != nil {
+				t.Fatalf("Stats failed: %v", err)
+			}
+			if stats.Backend != backend || stats.FileSize == 0 || len(stats.Buckets) == 0 {
+				t.Errorf("Unexpected stats: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestStorageSensitiveBuckets(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+	c, err := secrets.FromKey(key)
+	if err != nil {
+		t.Fatalf("FromKey failed: %v", err)
+	}
+	if _, err := secrets.FromKey("tooshort"); err != secrets.ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+
+	for _, backend := range []string{storage.BackendBolt, storage.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := storage.Open(backend, filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("Failed to open storage: %v", err)
+			}
+			defer store.Close()
+
+			// Written before encryption was enabled
+			store.Set("registry", "legacy", []byte("old-password"))
+
+			store.SetCipher(c)
+			if n, err := store.EncryptSensitive(); err != nil || n != 1 {
+				t.Errorf("Expected 1 value encrypted, got %d, %v", n, err)
+			}
+			store.Set("registry", "docker.io", []byte("hunter2"))
+			store.Set("temperature", "unit", []byte("celsius"))
+
+			if v, err := store.Get("registry", "docker.io"); err != nil || string(v) != "hunter2" {
+				t.Errorf("Expected transparent decryption, got %q, %v", v, err)
+			}
+			if data, _ := store.List("registry"); string(data["legacy"]) != "old-password" {
+				t.Errorf("Expected legacy value decrypted, got %q", data["legacy"])
+			}
+
+			raw, err := store.ExportSettings()
+			if err != nil {
+				t.Fatalf("ExportSettings failed: %v", err)
+			}
+			var snapshot struct {
+				Data map[string]map[string][]byte `json:"data"`
+			}
+			if err := json.Unmarshal(raw, &snapshot); err != nil {
+				t.Fatalf("Failed to parse snapshot: %v", err)
+			}
+			for k, v := range snapshot.Data["registry"] {
+				if !secrets.IsEncrypted(string(v)) {
+					t.Errorf("Expected registry/%s encrypted at rest, got %q", k, v)
+				}
+			}
+			if string(snapshot.Data["temperature"]["unit"]) != "celsius" {
+				t.Errorf("Expected non-sensitive value in plaintext, got %q", snapshot.Data["temperature"]["unit"])
+			}
+		})
+	}
+}
+
+func TestStorageTTLAndPrefix(t *testing.T) {
+	for _, backend := range []string{storage.BackendBolt, storage.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := storage.Open(backend, filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("Failed to open storage: %v", err)
+			}
+			defer store.Close()
+
+			store.SetString("cache", "session:a", "1")
+			store.SetJSON("cache", "session:b", map[string]int{"n": 2})
+			store.SetString("cache", "other", "3")
+			store.SetWithTTL("cache", "session:old", []byte("gone"), time.Nanosecond)
+			store.SetJSONWithTTL("cache", "token", "abc", time.Hour)
+			time.Sleep(time.Millisecond)
+
+			if _, err := store.Get("cache", "session:old"); err != storage.ErrNotFound {
+				t.Errorf("Expected expired key to be ErrNotFound, got %v", err)
+			}
+			var token string
+			if err := store.GetJSON("cache", "token", &token); err != nil || token != "abc" {
+				t.Errorf("Expected token abc, got %q, %v", token, err)
+			}
+
+			data, err := store.ListPrefix("cache", "session:")
+			if err != nil || len(data) != 2 || string(data["session:a"]) != "1" {
+				t.Errorf("Unexpected prefix scan: %v, %v", data, err)
+			}
+			if all, _ := store.List("cache"); len(all) != 4 {
+				t.Errorf("Expected 4 live keys, got %d", len(all))
+			}
+
+			// Overwriting without a TTL clears the expiry
+			store.SetWithTTL("cache", "other", []byte("4"), time.Nanosecond)
+			store.SetString("cache", "other", "5")
+			time.Sleep(time.Millisecond)
+			if v, err := store.GetString("cache", "other"); err != nil || v != "5" {
+				t.Errorf("Expected other=5, got %q, %v", v, err)
+			}
+
+			if n, err := store.DeleteExpired(); err != nil || n != 1 {
+				t.Errorf("Expected 1 expired key removed, got %d, %v", n, err)
+			}
+			if n, _ := store.DeleteExpired(); n != 0 {
+				t.Errorf("Expected nothing left to expire, got %d", n)
+			}
+
+			// TTLs survive migration to the other backend
+			other := storage.BackendSQLite
+			if backend == storage.BackendSQLite {
+				other = storage.BackendBolt
+			}
+			dst, err := storage.Open(other, filepath.Join(t.TempDir(), "dst.db"))
+			if err != nil {
+				t.Fatalf("Failed to open destination: %v", err)
+			}
+			defer dst.Close()
+			store.SetWithTTL("cache", "session:a", []byte("1"), time.Nanosecond)
+			if err := storage.Migrate(store, dst); err != nil {
+				t.Fatalf("Migrate failed: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+			if _, err := dst.Get("cache", "session:a"); err != storage.ErrNotFound {
+				t.Errorf("Expected migrated key to keep its TTL, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStorageCommandHistorySearch(t *testing.T) {
+	for _, backend := range []string{storage.BackendBolt, storage.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := storage.Open(backend, filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("Failed to open storage: %v", err)
+			}
+			defer store.Close()
+
+			base := time.Now()
+			add := func(i int, user, target, command string) *storage.CommandHistoryEntry {
+				entry := &storage.CommandHistoryEntry{
+					Command:   command,
+					Timestamp: base.Add(time.Duration(i) * time.Second),
+					User:      user,
+					Target:    target,
+				}
+				if err := store.AddCommandHistory(entry); err != nil {
+					t.Fatalf("AddCommandHistory failed: %v", err)
+				}
+				return entry
+			}
+
+			store.SaveCommandHistory("uptime", base)
+			add(1, "alice", "", "podman ps -a")
+			failed := add(2, "alice", "abc123", "cat /etc/Hosts")
+			add(3, "bob", "", "podman images")
+			add(4, "bob", "", "ls -la /etc")
+			repeat := add(5, "bob", "", "ls -la /etc")
+
+			if err := store.SetCommandExitCode(failed.ID, 1); err != nil {
+				t.Errorf("SetCommandExitCode failed: %v", err)
+			}
+			if err := store.SetCommandExitCode(-1, 0); err != storage.ErrNotFound {
+				t.Errorf("Expected ErrNotFound for unknown entry, got %v", err)
+			}
+
+			tests := []struct {
+				name  string
+				query storage.HistoryQuery
+				total int
+				first string
+			}{
+				{"all newest first", storage.HistoryQuery{Limit: 10}, 5, "ls -la /etc"},
+				{"search words", storage.HistoryQuery{Search: "PODMAN ps", Limit: 10}, 1, "podman ps -a"},
+				{"search etc", storage.HistoryQuery{Search: "etc", Limit: 10}, 2, "ls -la /etc"},
+				{"by user", storage.HistoryQuery{User: "alice", Limit: 10}, 2, "cat /etc/Hosts"},
+				{"host target", storage.HistoryQuery{Target: storage.HostTarget, Limit: 10}, 4, "ls -la /etc"},
+				{"container target", storage.HistoryQuery{Target: "abc123", Limit: 10}, 1, "cat /etc/Hosts"},
+				{"second page", storage.HistoryQuery{Offset: 1, Limit: 1}, 5, "podman images"},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					page, err := store.SearchCommandHistory(tt.query)
+					if err != nil {
+						t.Fatalf("SearchCommandHistory failed: %v", err)
+					}
+					if page.Total != tt.total || len(page.Entries) == 0 || page.Entries[0].Command != tt.first {
+						t.Errorf("Expected %d entries starting with %q, got %+v", tt.total, tt.first, page)
+					}
+				})
+			}
+
+			page, _ := store.SearchCommandHistory(storage.HistoryQuery{Target: "abc123", Limit: 1})
+			if len(page.Entries) != 1 || page.Entries[0].ExitCode == nil || *page.Entries[0].ExitCode != 1 || page.Entries[0].User != "alice" {
+				t.Errorf("Expected exit code and user recorded, got %+v", page.Entries)
+			}
+			if page, _ := store.SearchCommandHistory(storage.HistoryQuery{Search: "uptime", Limit: 1}); page.Total != 1 || page.Entries[0].Target != storage.HostTarget {
+				t.Errorf("Expected legacy entry on host target, got %+v", page)
+			}
+			if repeat.ID == 0 {
+				t.Error("Expected repeated command to report the previous entry ID")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestContainerTags(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "jellyfin-id", "Names": ["jellyfin"], "Image": "jellyfin", "State": "running"},
+			{"Id": "sonarr-id", "Names": ["sonarr"], "Image": "sonarr", "State": "running"},
+			{"Id": "db-id", "Names": ["db"], "Image": "postgres", "State": "exited"}
+		]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(r.PathValue("id"), "-id")
+		if name != "jellyfin" && name != "sonarr" && name != "db" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "no such container"}`))
+			return
+		}
+		w.Write([]byte(`{"Id": "` + name + `-id", "Name": "` + name + `"}`))
+	})
+
+	cfg := newTestConfig(t, "")
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	client := podman.NewClientWithHandler(mux)
+	eventStore := events.NewStore(100)
+	server := newTestServer(t, client, withConfig(cfg), withStorage(store), withEvents(eventStore))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	names := func(path string) []string {
+		var list []api.ContainerWithStats
+		json.Unmarshal(do(http.MethodGet, path, "").Body.Bytes(), &list)
+		result := []string{}
+		for _, c := range list {
+			result = append(result, c.Names[0])
+		}
+		return result
+	}
+
+	for body, code := range map[string]int{
+		`{"tags": ["Media Server"]}`: http.StatusBadRequest,
+		`{"tags": [""]}`:             http.StatusBadRequest,
+		`not json`:                   http.StatusBadRequest,
+	} {
+		if rec := do(http.MethodPut, "/api/containers/jellyfin/tags", body); rec.Code != code {
+			t.Errorf("Tags %s: expected %d, got %d", body, code, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPut, "/api/containers/nope/tags", `{"tags": ["media"]}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Unknown container: expected 404, got %d", rec.Code)
+	}
+
+	// Tags are normalized, and containers given by ID are stored by name
+	rec := do(http.MethodPut, "/api/containers/jellyfin-id/tags", `{"tags": ["Public", "media", "media"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Tags: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var tagged struct {
+		Container string   `json:"container"`
+		Tags      []string `json:"tags"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &tagged)
+	if tagged.Container != "jellyfin" || strings.Join(tagged.Tags, ",") != "media,public" {
+		t.Errorf("Unexpected tags: %+v", tagged)
+	}
+	do(http.MethodPut, "/api/containers/sonarr/tags", `{"tags": ["media"]}`)
+	do(http.MethodPut, "/api/containers/db/tags", `{"tags": ["backend"]}`)
+	if evs := eventStore.GetLast(1); len(evs) != 1 || evs[0].Type != events.EventContainerTags || evs[0].Details != "db: backend" {
+		t.Errorf("Expected a tags event, got %+v", evs)
+	}
+
+	var groups []api.TagGroup
+	json.Unmarshal(do(http.MethodGet, "/api/containers/tags", "").Body.Bytes(), &groups)
+	if len(groups) != 3 || groups[0].Tag != "backend" || groups[1].Tag != "media" || strings.Join(groups[1].Containers, ",") != "jellyfin,sonarr" {
+		t.Errorf("Unexpected groups: %+v", groups)
+	}
+
+	if got := names("/api/containers?tag=media"); strings.Join(got, ",") != "jellyfin,sonarr" {
+		t.Errorf("Expected the media containers, got %v", got)
+	}
+	if got := names("/api/containers?tag=PUBLIC,backend"); strings.Join(got, ",") != "jellyfin,db" {
+		t.Errorf("Expected containers with any tag, got %v", got)
+	}
+	if got := names("/api/containers?tag=media&status=exited"); len(got) != 0 {
+		t.Errorf("Expected tag and status filters to combine, got %v", got)
+	}
+
+	// Favorites
+	if rec := do(http.MethodPut, "/api/containers/sonarr/favorite", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Favorite: expected 200, got %d", rec.Code)
+	}
+	do(http.MethodPut, "/api/containers/db/favorite", "")
+	do(http.MethodPut, "/api/containers/db/favorite", "")
+	var favorites []string
+	json.Unmarshal(do(http.MethodGet, "/api/containers/favorites", "").Body.Bytes(), &favorites)
+	if strings.Join(favorites, ",") != "db,sonarr" {
+		t.Errorf("Unexpected favorites: %v", favorites)
+	}
+	if got := names("/api/containers?favorite=true&tag=media"); strings.Join(got, ",") != "sonarr" {
+		t.Errorf("Expected favorite media containers, got %v", got)
+	}
+	do(http.MethodDelete, "/api/containers/db/favorite", "")
+
+	var list []api.ContainerWithStats
+	json.Unmarshal(do(http.MethodGet, "/api/containers", "").Body.Bytes(), &list)
+	for _, c := range list {
+		if c.Favorite != (c.Names[0] == "sonarr") || c.Tags == nil {
+			t.Errorf("Unexpected container %s: favorite %v, tags %v", c.Names[0], c.Favorite, c.Tags)
+		}
+	}
+
+	// Tags and favorites are kept across restarts; an empty list removes
+	// the tags
+	do(http.MethodPut, "/api/containers/db/tags", `{"tags": []}`)
+	server = newTestServer(t, client, withConfig(cfg), withStorage(store), withEvents(eventStore))
+	json.Unmarshal(do(http.MethodGet, "/api/containers/tags", "").Body.Bytes(), &groups)
+	if len(groups) != 2 || groups[0].Tag != "media" {
+		t.Errorf("Unexpected groups after restart: %+v", groups)
+	}
+	var info struct {
+		Tags     []string `json:"tags"`
+		Favorite bool     `json:"favorite"`
+	}
+	json.Unmarshal(do(http.MethodGet, "/api/containers/sonarr/tags", "").Body.Bytes(), &info)
+	if strings.Join(info.Tags, ",") != "media" || !info.Favorite {
+		t.Errorf("Unexpected tags after restart: %+v", info)
+	}
+}
@@ -193,3 +193,35 @@ func TestTemperaturePluginLifecycle(t *testing.T) {
 		t.Error("GetTemperatureData() should still return cached data after Stop()")
 	}
 }
+
+func TestTemperatureHistory(t *testing.T) {
+	history := temperature.NewHistory(3)
+	sample := func(cpu, nvme float64) *temperature.TemperatureData {
+		return &temperature.TemperatureData{
+			Temperatures: []temperature.Temperature{{Label: "CPU", Temp: cpu}},
+			StorageTemps: []temperature.StorageTemp{{Device: "nvme0", Sensors: []temperature.Temperature{{Label: "Composite", Temp: nvme}}}},
+		}
+	}
+	for i := 1; i <= 4; i++ {
+		history.Record(sample(float64(40+i), float64(30+i)))
+	}
+
+	// The ring keeps the last 3 samples, oldest first
+	data := history.Apply(sample(44, 34))
+	if got := data.Temperatures[0].History; len(got) != 3 || got[0] != 42 || got[2] != 44 {
+		t.Errorf("Unexpected CPU history: %v", got)
+	}
+	if got := data.StorageTemps[0].Sensors[0].History; len(got) != 3 || got[0] != 32 || got[2] != 34 {
+		t.Errorf("Unexpected NVMe history: %v", got)
+	}
+
+	// Sensors that disappear are dropped
+	history.Record(&temperature.TemperatureData{Temperatures: []temperature.Temperature{{Label: "CPU", Temp: 45}}})
+	data = history.Apply(sample(45, 35))
+	if got := data.StorageTemps[0].Sensors[0].History; got != nil {
+		t.Errorf("Expected no history for a removed sensor, got %v", got)
+	}
+	if got := data.Temperatures[0].History; len(got) != 3 || got[2] != 45 {
+		t.Errorf("Unexpected CPU history: %v", got)
+	}
+}
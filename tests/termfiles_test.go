@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestTerminalFileTransfer(t *testing.T) {
+	if _, err := os.Stat("/bin/bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(http.NewServeMux()), withStorage(store), withEvents(eventStore))
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+
+	// WebSocket tokens are bound to the client
+	header := http.Header{"User-Agent": {"terminal-test"}}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/auth/ws-token", nil)
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to get a WebSocket token: %v", err)
+	}
+	var token struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&token)
+	resp.Body.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/terminal?ws_token="+token.Token, header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer ws.Close()
+
+	send := func(msg map[string]interface{}) {
+		t.Helper()
+		if err := ws.WriteJSON(msg); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+	}
+	// reply skips terminal output until the reply to a transfer
+	reply := func() api.TransferMessage {
+		t.Helper()
+		ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			var msg api.TransferMessage
+			if json.Unmarshal(data, &msg) == nil && msg.Type == "transfer" {
+				return msg
+			}
+		}
+	}
+
+	// Relative paths are in the working directory of the shell, known
+	// once it shows its next prompt
+	send(map[string]interface{}{"type": "stdin", "data": "cd " + dir + "\r"})
+	var got api.TransferMessage
+	for i := 0; i < 50; i++ {
+		send(map[string]interface{}{"type": "download", "path": "existing.txt"})
+		if got = reply(); got.Error == "" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(got.Data); got.Error != "" || got.Path != filepath.Join(dir, "existing.txt") || string(data) != "hello" || got.Size != 5 {
+		t.Fatalf("Unexpected download %+v", got)
+	}
+
+	upload := func(path, content string, overwrite bool) api.TransferMessage {
+		t.Helper()
+		send(map[string]interface{}{"type": "upload", "path": path, "data": base64.StdEncoding.EncodeToString([]byte(content)), "overwrite": overwrite})
+		return reply()
+	}
+	if got := upload("new.txt", "uploaded\x00binary", false); got.Error != "" || got.Path != filepath.Join(dir, "new.txt") || got.Size != 15 {
+		t.Errorf("Unexpected upload %+v", got)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "new.txt")); err != nil || string(data) != "uploaded\x00binary" {
+		t.Errorf("Expected the uploaded file, got %q %v", data, err)
+	}
+	if got := eventStore.GetLast(1); len(got) != 1 || got[0].Type != events.EventTerminalTransfer || got[0].Details != "host: upload "+filepath.Join(dir, "new.txt")+" (15 bytes)" {
+		t.Errorf("Unexpected event %+v", got)
+	}
+
+	// Existing files are only replaced on request
+	if got := upload("new.txt", "second", false); got.Error != "File already exists" {
+		t.Errorf("Expected the file to exist, got %+v", got)
+	}
+	if got := upload(filepath.Join(dir, "new.txt"), "second", true); got.Error != "" {
+		t.Errorf("Unexpected upload %+v", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "new.txt")); string(data) != "second" {
+		t.Errorf("Expected the file to be replaced, got %q", data)
+	}
+
+	// Replaced files keep their mode
+	if err := os.Chmod(filepath.Join(dir, "new.txt"), 0750); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	if got := upload("new.txt", "#!/bin/sh", true); got.Error != "" {
+		t.Errorf("Unexpected upload %+v", got)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "new.txt")); err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("Expected the mode to be kept, got %v %v", info.Mode(), err)
+	}
+
+	send(map[string]interface{}{"type": "upload", "path": "bad.txt", "data": "not base64!"})
+	if got := reply(); got.Error != "Invalid file data, expected base64" {
+		t.Errorf("Expected invalid data, got %+v", got)
+	}
+	send(map[string]interface{}{"type": "download", "path": dir})
+	if got := reply(); got.Error != "Not a regular file" {
+		t.Errorf("Expected a directory to be refused, got %+v", got)
+	}
+	send(map[string]interface{}{"type": "download", "path": "missing.txt"})
+	if got := reply(); got.Error != "No such file or directory" || got.Data != "" {
+		t.Errorf("Expected a missing file, got %+v", got)
+	}
+	send(map[string]interface{}{"type": "upload", "path": "big.bin", "data": strings.Repeat("A", 6<<20)})
+	if got := reply(); got.Error != "Files of up to 4 MB can be transferred" {
+		t.Errorf("Expected a large file to be refused, got %+v", got.Error)
+	}
+
+	// Sessions under a command policy can't upload
+	req, _ = http.NewRequest(http.MethodPut, ts.URL+"/api/v1/terminal/policy", strings.NewReader(`{"admin": {"mode": "deny", "rules": ["rm -*r*"]}}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to set the policy: %v", err)
+	}
+	resp.Body.Close()
+	ws.Close()
+	ws, _, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/terminal?ws_token="+token.Token, header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer ws.Close()
+	if got := upload(filepath.Join(dir, "script.sh"), "rm -rf /", false); got.Error != "Uploads are not available under a terminal command policy" {
+		t.Errorf("Expected the upload to be refused, got %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "script.sh")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file, got %v", err)
+	}
+	send(map[string]interface{}{"type": "download", "path": filepath.Join(dir, "existing.txt")})
+	if got := reply(); got.Error != "" {
+		t.Errorf("Expected downloads to work, got %+v", got)
+	}
+}
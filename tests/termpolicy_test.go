@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/auth"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestTerminalPolicy(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(20)
+	server := newTestServer(t, podman.NewClientWithHandler(http.NewServeMux()), withConfig(cfg), withStorage(store), withEvents(eventStore))
+
+	jwtManager := auth.NewJWTManager(cfg.JWTSecret(), time.Hour)
+	tokens := make(map[string]string)
+	for _, user := range []*auth.User{{Username: "admin", UID: "0", Role: auth.RoleAdmin}, {Username: "alice", UID: "1000", Role: auth.RoleReadOnly}} {
+		if tokens[user.Username], err = jwtManager.GenerateToken(user); err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+	}
+	do := func(user, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: tokens[user]})
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	check := func(role, command string) (bool, string) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"role": role, "command": command})
+		rec := do("admin", http.MethodPost, "/api/v1/terminal/policy/check", string(body))
+		var result struct {
+			Allowed bool   `json:"allowed"`
+			Reason  string `json:"reason"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+		}
+		return result.Allowed, result.Reason
+	}
+
+	// No restrictions and no terminals for read-only users by default
+	if allowed, _ := check("admin", "rm -rf /"); !allowed {
+		t.Error("Expected commands to be allowed without a policy")
+	}
+	if rec := do("alice", http.MethodGet, "/api/v1/auth/ws-token", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected read-only users to get no WebSocket token, got %d", rec.Code)
+	}
+	if rec := do("alice", http.MethodGet, "/api/v1/containers/c1/terminal", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected read-only users to have no terminal, got %d", rec.Code)
+	}
+	if rec := do("alice", http.MethodPut, "/api/v1/terminal/policy", `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a read-only user, got %d", rec.Code)
+	}
+
+	policy := `{
+		"admin": {"mode": "deny", "rules": ["rm  -*r*", "mkfs*", "git push"]},
+		"readonly": {"mode": "allow", "rules": ["ls", "cat", "grep", "tail -n", "/usr/bin/ps"]}
+	}`
+	rec := do("admin", http.MethodPut, "/api/v1/terminal/policy", policy)
+	var saved api.TerminalPolicies
+	if err := json.Unmarshal(rec.Body.Bytes(), &saved); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if saved.Admin.Rules[0] != "rm -*r*" || saved.UpdatedBy != "admin" {
+		t.Errorf("Unexpected policies %+v", saved)
+	}
+	if got := eventStore.GetLast(1); len(got) != 1 || got[0].Type != events.EventSettingsUpdate ||
+		got[0].Details != "terminal policy: admin deny (3 rules), readonly allow (5 rules)" {
+		t.Errorf("Unexpected event %+v", got)
+	}
+
+	for _, tc := range []struct {
+		role, command string
+		allowed       bool
+	}{
+		{"admin", "rm -rf /", false},
+		{"admin", "rm -fr /tmp/x", false},
+		{"admin", "/bin/rm -r x", false},
+		{"admin", "sudo -u root rm -rf x", false},
+		{"admin", "echo ok; rm -rf /", false},
+		{"admin", "ls | xargs rm -r", false},
+		{"admin", "timeout 5 'rm' -rf x", false},
+		{"admin", "mkfs.ext4 /dev/sda1", false},
+		{"admin", "git push --force", false},
+		{"admin", "rm file.txt", true},
+		{"admin", "echo 'rm -rf /'", true},
+		{"admin", "git pull", true},
+		{"readonly", "ls -la /etc", true},
+		{"readonly", "cat /etc/hosts | grep localhost", true},
+		{"readonly", "tail -n 20 /var/log/app.log", true},
+		{"readonly", "ps aux", true},
+		{"readonly", "cat a 2>&1", true},
+		{"readonly", "tail -f /var/log/app.log", false},
+		{"readonly", "rm -rf /", false},
+		{"readonly", "ls && rm x", false},
+		{"readonly", "cat $(which rm)", false},
+		{"readonly", "cat `id`", false},
+		{"readonly", "ls > /etc/passwd", false},
+		{"readonly", "(rm x)", false},
+		{"readonly", "$CMD x", false},
+		{"readonly", "PATH=/tmp ls", false},
+		{"readonly", "!!", false},
+		{"readonly", "cat 'unterminated", false},
+	} {
+		if allowed, reason := check(tc.role, tc.command); allowed != tc.allowed {
+			t.Errorf("%s %q: expected allowed=%v, got %v (%s)", tc.role, tc.command, tc.allowed, allowed, reason)
+		}
+	}
+
+	// Read-only users with an allow list can open container terminals
+	if rec := do("alice", http.MethodGet, "/api/v1/auth/ws-token", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected a WebSocket token, got %d %s", rec.Code, rec.Body)
+	}
+	rec = do("alice", http.MethodGet, "/api/v1/auth/me", "")
+	if !strings.Contains(rec.Body.String(), `"containerTerminal":true`) {
+		t.Errorf("Expected container terminal access, got %s", rec.Body)
+	}
+	if rec := do("alice", http.MethodGet, "/api/v1/terminal", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the host terminal to stay admin-only, got %d", rec.Code)
+	}
+
+	for _, body := range []string{
+		`{"admin": {"mode": "block"}}`,
+		`{"readonly": {"mode": "deny", "rules": ["rm"]}}`,
+		`{"admin": {"mode": "deny", "rules": ["rm [a-"]}}`,
+		`{"admin": {"mode": "deny", "rules": ["` + strings.Repeat("x", 257) + `"]}}`,
+		`not json`,
+	} {
+		if rec := do("admin", http.MethodPut, "/api/v1/terminal/policy", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%.40s: expected 400, got %d", body, rec.Code)
+		}
+	}
+	body := `{"role": "operator", "command": "ls"}`
+	if rec := do("admin", http.MethodPost, "/api/v1/terminal/policy/check", body); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown role, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/config"
+	"podmanview/internal/events"
+	"podmanview/internal/logger"
+	"podmanview/internal/plugins"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+// testServer collects the settings of a server built by newTestServer
+type testServer struct {
+	config   *config.Config
+	static   string
+	plugins  []plugins.Plugin
+	registry *plugins.Registry
+	storage  storage.Storage
+	events   *events.Store
+	logger   *logger.Logger
+}
+
+// serverOption changes a server built by newTestServer
+type serverOption func(*testServer)
+
+// withConfig uses cfg instead of the config of newTestConfig
+func withConfig(cfg *config.Config) serverOption {
+	return func(s *testServer) { s.config = cfg }
+}
+
+// withStaticVersion sets the version of the embedded static files
+func withStaticVersion(version string) serverOption {
+	return func(s *testServer) { s.static = version }
+}
+
+// withPlugins starts the server with plugins
+func withPlugins(list ...plugins.Plugin) serverOption {
+	return func(s *testServer) { s.plugins = list }
+}
+
+// withRegistry uses a plugin registry
+func withRegistry(registry *plugins.Registry) serverOption {
+	return func(s *testServer) { s.registry = registry }
+}
+
+// withStorage gives the server a storage, it has none by default
+func withStorage(store storage.Storage) serverOption {
+	return func(s *testServer) { s.storage = store }
+}
+
+// withEvents uses an event store the test reads, instead of a new one
+func withEvents(store *events.Store) serverOption {
+	return func(s *testServer) { s.events = store }
+}
+
+// withLogger gives the server an application logger
+func withLogger(appLogger *logger.Logger) serverOption {
+	return func(s *testServer) { s.logger = appLogger }
+}
+
+// newTestConfig loads a config without authentication from a .env with
+// the given extra lines
+func newTestConfig(t *testing.T, env string) *config.Config {
+	t.Helper()
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("PODMANVIEW_JWT_SECRET=secret\nPODMANVIEW_NO_AUTH=true\n"+env), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	cfg, err := config.Load(envPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return cfg
+}
+
+// newTestServer builds an API server using client for Podman, without
+// authentication, storage or plugins unless options add them
+func newTestServer(t *testing.T, client *podman.Client, opts ...serverOption) *api.Server {
+	t.Helper()
+	s := &testServer{static: "1"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.config == nil {
+		s.config = newTestConfig(t, "")
+	}
+	if s.events == nil {
+		s.events = events.NewStore(10)
+	}
+	return api.NewServerWithPlugins(client, s.config, "1.2.3", s.static, s.plugins, s.registry, s.storage, s.events, s.logger)
+}
@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"testing"
+
+	"podmanview/internal/api"
+)
+
+func TestParseThrottled(t *testing.T) {
+	// Under-voltage now, and under-voltage and throttling since boot
+	throttling, err := api.ParseThrottled("throttled=0x50005\n")
+	if err != nil {
+		t.Fatalf("ParseThrottled failed: %v", err)
+	}
+	expected := api.Throttling{Raw: "0x50005", UnderVoltage: true, Throttled: true, UnderVoltageOccurred: true, ThrottledOccurred: true}
+	if *throttling != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *throttling)
+	}
+
+	// The sysfs file has no prefix
+	throttling, err = api.ParseThrottled("80000\n")
+	if err != nil || throttling.SoftTempLimitOccurred != true || throttling.SoftTempLimit || throttling.Raw != "0x80000" {
+		t.Errorf("Unexpected sysfs result: %+v, %v", throttling, err)
+	}
+	if throttling, err := api.ParseThrottled("0x0"); err != nil || *throttling != (api.Throttling{Raw: "0x0"}) {
+		t.Errorf("Expected no flags, got %+v, %v", throttling, err)
+	}
+
+	for _, value := range []string{"", "error=1 error_msg=\"Command not registered\"", "throttled=zz"} {
+		if _, err := api.ParseThrottled(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
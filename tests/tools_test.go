@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+)
+
+func TestNetworkTools(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "web":
+			// The test process stands in for the container
+			fmt.Fprintf(w, `{"Id": "c1", "Name": "web", "State": {"Running": true, "Pid": %d}}`, os.Getpid())
+		case "job":
+			w.Write([]byte(`{"Id": "c2", "Name": "job", "State": {"Running": false}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"cause": "no such container", "message": "no such container", "response": 404}`))
+		}
+	})
+
+	server := newTestServer(t, podman.NewClientWithHandler(mux))
+
+	get := func(path string, v interface{}) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tools/"+path, nil))
+		if v != nil {
+			json.Unmarshal(rec.Body.Bytes(), v)
+		}
+		return rec
+	}
+
+	open := httptest.NewServer(http.NotFoundHandler())
+	defer open.Close()
+	openPort := open.Listener.Addr().(*net.TCPAddr).Port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	var port api.PortResult
+	if rec := get(fmt.Sprintf("port?host=127.0.0.1&port=%d", openPort), &port); rec.Code != http.StatusOK || !port.Open {
+		t.Errorf("Expected an open port, got %d %s", rec.Code, rec.Body)
+	}
+	port = api.PortResult{}
+	if rec := get(fmt.Sprintf("port?host=localhost&port=%d&timeout=2", closedPort), &port); rec.Code != http.StatusOK || port.Open || port.Error == "" || !strings.HasPrefix(port.Address, "127.0.0.1:") {
+		t.Errorf("Expected a closed port, got %d %s", rec.Code, rec.Body)
+	}
+
+	var dns api.DNSResult
+	if rec := get("dns?name=localhost&type=a", &dns); rec.Code != http.StatusOK || dns.Type != "A" || len(dns.Records) == 0 || dns.Records[0] != "127.0.0.1" {
+		t.Errorf("Expected localhost to resolve, got %d %s", rec.Code, rec.Body)
+	}
+	// Nothing answers on the closed port, the failure is part of the result
+	dns = api.DNSResult{}
+	if rec := get(fmt.Sprintf("dns?name=example.test&type=MX&server=127.0.0.1:%d&timeout=2", closedPort), &dns); rec.Code != http.StatusOK || dns.Error == "" || dns.Server != fmt.Sprintf("127.0.0.1:%d", closedPort) {
+		t.Errorf("Expected a failed lookup, got %d %s", rec.Code, rec.Body)
+	}
+
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{"dns?name=-f", http.StatusBadRequest},
+		{"dns?name=example.com&type=ANY", http.StatusBadRequest},
+		{"dns?name=example.com&server=dns.google", http.StatusBadRequest},
+		{"port?host=localhost&port=70000", http.StatusBadRequest},
+		{"port?host=localhost&port=80&timeout=120", http.StatusBadRequest},
+		{"traceroute?host=--help", http.StatusBadRequest},
+		{"port?host=localhost&port=80&container=job", http.StatusConflict},
+		{"port?host=localhost&port=80&container=missing", http.StatusNotFound},
+	} {
+		if rec := get(tc.path, nil); rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d %s", tc.path, tc.code, rec.Code, rec.Body)
+		}
+	}
+
+	// Entering a network namespace needs root
+	port = api.PortResult{}
+	get(fmt.Sprintf("port?host=127.0.0.1&port=%d&container=web", openPort), &port)
+	if port.Error != "" && strings.Contains(port.Error, "operation not permitted") {
+		t.Skipf("Can't enter network namespaces: %s", port.Error)
+	}
+	if !port.Open || port.Container != "web" {
+		t.Errorf("Expected an open port from the container, got %+v", port)
+	}
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/events"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestContainerTrash(t *testing.T) {
+	var calls []string
+	var created []podman.ContainerCreateConfig
+	removed := false
+	failRemove := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "web" || removed {
+			http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+			"Id": "0123456789abcdef", "Name": "web", "Image": "img1", "ImageName": "docker.io/library/nginx:1.27",
+			"State": {"Status": "running", "Running": true},
+			"Config": {"Hostname": "0123456789ab", "Env": ["PATH=/usr/bin", "DB_PASSWORD=hunter2"]},
+			"HostConfig": {"PortBindings": {"80/tcp": [{"HostPort": "8080"}]}},
+			"Mounts": [
+				{"Type": "volume", "Name": "webdata", "Source": "/var/lib/containers/storage/volumes/webdata/_data", "Destination": "/data", "RW": true},
+				{"Type": "volume", "Name": "cache", "Source": "/var/lib/containers/storage/volumes/cache/_data", "Destination": "/cache", "RW": true}
+			]
+		}`))
+	})
+	// Image references contain slashes
+	mux.HandleFunc("GET /v4.0.0/libpod/images/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "img1", "Config": {"Env": ["PATH=/usr/bin"]}}`))
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "remove "+r.PathValue("id"))
+		if failRemove {
+			http.Error(w, `{"message": "container is running"}`, http.StatusConflict)
+			return
+		}
+		removed = true
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/volumes/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Name": "webdata"}]`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var config podman.ContainerCreateConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		calls = append(calls, "create")
+		created = append(created, config)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "fedcba9876543210"}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "start "+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	eventStore := events.NewStore(10)
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withStorage(store), withEvents(eventStore))
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	type trash struct {
+		Containers    []api.TrashEntry `json:"containers"`
+		RetentionDays int              `json:"retentionDays"`
+	}
+	list := func() trash {
+		var result trash
+		json.Unmarshal(request(http.MethodGet, "/api/v1/containers/trash").Body.Bytes(), &result)
+		return result
+	}
+
+	// A failed removal leaves nothing in the trash
+	failRemove = true
+	if rec := request(http.MethodDelete, "/api/v1/containers/web?trash=true"); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409, got %d %s", rec.Code, rec.Body)
+	}
+	if got := list(); len(got.Containers) != 0 || got.RetentionDays != 7 {
+		t.Errorf("Expected an empty trash, got %+v", got)
+	}
+	failRemove = false
+
+	rec := request(http.MethodDelete, "/api/v1/containers/web?force=true&trash=true")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"trashId":"0123456789ab"`) {
+		t.Fatalf("Expected the container to be removed, got %d %s", rec.Code, rec.Body)
+	}
+	entries := list().Containers
+	if len(entries) != 1 {
+		t.Fatalf("Expected one container in the trash, got %+v", entries)
+	}
+	entry := entries[0]
+	if entry.Name != "web" || !entry.WasRunning || entry.RemovedBy != "dev" || entry.Spec.Env["DB_PASSWORD"] != "hunter2" || strings.Join(entry.Volumes, ",") != "webdata,cache" {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if d := entry.ExpiresAt.Sub(entry.RemovedAt); d.Hours() != 7*24 {
+		t.Errorf("Expected a 7 day retention, got %s", d)
+	}
+
+	calls = nil
+	rec = request(http.MethodPost, "/api/v1/containers/trash/0123456789ab/restore?start=true")
+	var restored struct {
+		ID             string   `json:"id"`
+		Name           string   `json:"name"`
+		Status         string   `json:"status"`
+		MissingVolumes []string `json:"missingVolumes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &restored); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the container to be restored, got %d %s", rec.Code, rec.Body)
+	}
+	if restored.Name != "web" || restored.Status != "started" || strings.Join(restored.MissingVolumes, ",") != "cache" {
+		t.Errorf("Unexpected restore result %+v", restored)
+	}
+	if got := strings.Join(calls, ","); got != "create,start fedcba9876543210" {
+		t.Errorf("Unexpected calls %s", got)
+	}
+	config := created[0]
+	if config.Name != "web" || config.Image != "docker.io/library/nginx:1.27" || config.Env["DB_PASSWORD"] != "hunter2" || len(config.Volumes) != 2 || len(config.PortMappings) != 1 {
+		t.Errorf("Expected the spec to be restored, got %+v", config)
+	}
+	if len(list().Containers) != 0 {
+		t.Error("Expected restored containers to leave the trash")
+	}
+	if rec := request(http.MethodPost, "/api/v1/containers/trash/0123456789ab/restore"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+
+	// Restoring under another name, then deleting for good
+	removed = false
+	request(http.MethodDelete, "/api/v1/containers/web?trash=true")
+	removed = false
+	if rec := request(http.MethodPost, "/api/v1/containers/trash/0123456789ab/restore?name=web-old"); rec.Code != http.StatusCreated || created[1].Name != "web-old" {
+		t.Errorf("Expected a restore as web-old, got %d %s", rec.Code, rec.Body)
+	}
+	request(http.MethodDelete, "/api/v1/containers/web?trash=true")
+	if rec := request(http.MethodDelete, "/api/v1/containers/trash/0123456789ab"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/containers/trash/0123456789ab"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+
+	// Without trash=true nothing is kept
+	removed = false
+	request(http.MethodDelete, "/api/v1/containers/web")
+	if len(list().Containers) != 0 {
+		t.Error("Expected an empty trash")
+	}
+
+	var restores int
+	for _, event := range eventStore.GetLast(10) {
+		if event.Type == events.EventContainerRestore && event.Success {
+			restores++
+		}
+	}
+	if restores != 2 {
+		t.Errorf("Expected 2 restore events, got %d", restores)
+	}
+}
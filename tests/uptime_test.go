@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestContainerUptime(t *testing.T) {
+	now := time.Now()
+	event := func(name, action string, ago time.Duration) podman.EngineEvent {
+		event := podman.EngineEvent{Type: "container", Action: action, TimeNano: now.Add(-ago).UnixNano()}
+		event.Actor.ID = name + "-id"
+		event.Actor.Attributes = map[string]string{"name": name}
+		return event
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/events", func(w http.ResponseWriter, r *http.Request) {
+		// Give the watchers time to subscribe
+		time.Sleep(200 * time.Millisecond)
+		encoder := json.NewEncoder(w)
+		for _, event := range []podman.EngineEvent{
+			event("web", "start", 20*time.Hour),
+			event("web", "died", 8*time.Hour),
+			event("web", "stop", 8*time.Hour), // repeats the state
+			event("web", "start", 2*time.Hour),
+			event("web", "exec", time.Hour),
+		} {
+			encoder.Encode(event)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[
+			{"Id": "web-id", "Names": ["web"], "State": "running", "StartedAt": %d},
+			{"Id": "db-id", "Names": ["db"], "State": "running", "StartedAt": %d},
+			{"Id": "job-id", "Names": ["job"], "State": "created", "ExitedAt": -62135596800}
+		]`, now.Add(-2*time.Hour).Unix(), now.Add(-time.Hour).Unix())
+	})
+
+	cfg := newTestConfig(t, "")
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	client := podman.NewClientWithHandler(mux)
+	server := newTestServer(t, client, withConfig(cfg), withStorage(store))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.StartEngineEvents(ctx)
+	server.StartAlertMonitors(ctx)
+
+	list := func(server *api.Server, query string) []api.ContainerWithStats {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/containers"+query, nil))
+		var containers []api.ContainerWithStats
+		json.Unmarshal(rec.Body.Bytes(), &containers)
+		return containers
+	}
+	uptimes := func(containers []api.ContainerWithStats) map[string]*api.ContainerUptime {
+		result := make(map[string]*api.ContainerUptime)
+		for _, c := range containers {
+			result[c.Names[0]] = c.Uptime
+		}
+		return result
+	}
+
+	var got map[string]*api.ContainerUptime
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		got = uptimes(list(server, ""))
+		if web := got["web"]; web != nil && web.Day != nil && *web.Day < 100 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// web ran 12 of the 20 recorded hours and again for the last 2
+	for _, percent := range []*float64{got["web"].Day, got["web"].Week, got["web"].Month} {
+		if percent == nil || *percent != 70 {
+			t.Errorf("Expected 70%% uptime for web, got %+v", got["web"])
+		}
+	}
+	// db is seeded from its start time, job never ran
+	if db := got["db"]; db == nil || db.Day == nil || *db.Day != 100 {
+		t.Errorf("Expected 100%% uptime for db, got %+v", db)
+	}
+	if got["job"] != nil {
+		t.Errorf("Expected no uptime for job, got %+v", got["job"])
+	}
+
+	if sorted := list(server, "?sort=uptime"); len(sorted) != 3 || sorted[0].Names[0] != "job" || sorted[1].Names[0] != "web" || sorted[2].Names[0] != "db" {
+		t.Errorf("Unexpected order %+v", sorted)
+	}
+
+	// The history survives a restart
+	restarted := newTestServer(t, client, withConfig(cfg), withStorage(store))
+	if web := uptimes(list(restarted, ""))["web"]; web == nil || web.Week == nil || *web.Week != 70 {
+		t.Errorf("Expected the stored uptime after a restart, got %+v", web)
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/podman"
+	"podmanview/internal/storage"
+)
+
+func TestVolumeBackups(t *testing.T) {
+	volumeDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(volumeDir, "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(volumeDir, "db", "data.sql"), []byte("CREATE TABLE t;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("db/data.sql", filepath.Join(volumeDir, "latest")); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	running := true
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id": "c1", "Names": ["web"], "State": "exited"}]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Id":    "c1",
+			"Name":  "web",
+			"State": map[string]interface{}{"Running": running},
+			"Mounts": []map[string]string{
+				{"Type": "volume", "Name": "webdata", "Source": volumeDir, "Destination": "/data"},
+				{"Type": "bind", "Source": "/etc/hosts", "Destination": "/etc/hosts"},
+			},
+		})
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "stop")
+		running = false
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /v4.0.0/libpod/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "remove")
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/volumes/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Name": "webdata"}]`))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/volumes/{name}/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Name": "webdata", "Mountpoint": "` + volumeDir + `"}`))
+	})
+
+	store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := newTestServer(t, podman.NewClientWithHandler(mux), withStorage(store))
+
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("X-Confirm-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Snapshots need the directory of the backup job
+	if rec := request(http.MethodDelete, "/api/v1/containers/c1?force=true&backupVolumes=true", "", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without a backup directory, got %d %s", rec.Code, rec.Body)
+	}
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	if rec := request(http.MethodPut, "/api/v1/system/jobs/backup", "", `{"intervalHours": 24, "dir": "`+backupDir+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Failed to configure the backup job: %d %s", rec.Code, rec.Body)
+	}
+
+	// A running container is only removed with force, after it was stopped
+	if rec := request(http.MethodDelete, "/api/v1/containers/c1?backupVolumes=true", "", ""); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 without force, got %d %s", rec.Code, rec.Body)
+	}
+	rec := request(http.MethodDelete, "/api/v1/containers/c1?force=true&backupVolumes=true", "", "")
+	var removed struct {
+		VolumeBackups []api.VolumeBackup `json:"volumeBackups"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &removed); err != nil || rec.Code != http.StatusOK || len(removed.VolumeBackups) != 1 {
+		t.Fatalf("Expected one volume backup, got %d %s", rec.Code, rec.Body)
+	}
+	if strings.Join(calls, ",") != "stop,remove" {
+		t.Errorf("Unexpected calls %v", calls)
+	}
+	backup := removed.VolumeBackups[0]
+	if backup.Volume != "webdata" || backup.Container != "web" || backup.Reason != "remove" || backup.RestorePath != "/api/volumes/backups/"+backup.ID+"/restore" {
+		t.Errorf("Unexpected backup %+v", backup)
+	}
+	if info, err := os.Stat(backup.Path); err != nil || info.Size() != backup.Size || filepath.Dir(backup.Path) != filepath.Join(backupDir, "volumes") {
+		t.Errorf("Expected the archive in the backup directory, got %s: %v", backup.Path, err)
+	}
+
+	var list []api.VolumeBackup
+	json.Unmarshal(request(http.MethodGet, "/api/v1/volumes/backups", "", "").Body.Bytes(), &list)
+	if len(list) != 1 || list[0].ID != backup.ID {
+		t.Errorf("Expected the backup to be listed, got %+v", list)
+	}
+
+	// Restoring replaces the data of the volume, once confirmed
+	os.RemoveAll(filepath.Join(volumeDir, "db"))
+	os.WriteFile(filepath.Join(volumeDir, "new.txt"), []byte("new"), 0644)
+	restore := "/api/v1" + strings.TrimPrefix(backup.RestorePath, "/api")
+	rec = request(http.MethodPost, restore, "", "")
+	var confirmation struct {
+		Details struct {
+			Token string `json:"token"`
+		} `json:"details"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &confirmation)
+	if rec.Code != http.StatusPreconditionRequired || confirmation.Details.Token == "" {
+		t.Fatalf("Expected a confirmation, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request(http.MethodPost, restore, confirmation.Details.Token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the volume to be restored, got %d %s", rec.Code, rec.Body)
+	}
+	if data, err := os.ReadFile(filepath.Join(volumeDir, "latest")); err != nil || string(data) != "CREATE TABLE t;" {
+		t.Errorf("Expected the files to be restored, got %q %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(volumeDir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected files missing from the snapshot to be removed, got %v", err)
+	}
+
+	if rec := request(http.MethodDelete, "/api/v1/volumes/backups/"+backup.ID, "", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the backup to be deleted, got %d %s", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(backup.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected the archive to be deleted, got %v", err)
+	}
+	if rec := request(http.MethodPost, restore, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted backup, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"podmanview/internal/events"
+	"podmanview/internal/storage"
+	"podmanview/internal/webhooks"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	store, err := storage.Open(storage.BackendBolt, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+
+	var attempts atomic.Int32
+	received := make(chan events.Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(webhooks.SignatureHeader); got != webhooks.Sign("s3cret", body) {
+			t.Errorf("Bad signature %q", got)
+		}
+		// Fail the first attempt to exercise the retry
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var event events.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("Bad payload: %v", err)
+		}
+		received <- event
+	}))
+	defer srv.Close()
+
+	manager, err := webhooks.NewManager(store, nil)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	if err := manager.Save(&webhooks.Webhook{URL: "ftp://example.com"}); err == nil {
+		t.Error("Expected an invalid URL to be rejected")
+	}
+
+	hook := &webhooks.Webhook{
+		URL:        srv.URL,
+		Events:     []string{"container_"},
+		Secret:     "s3cret",
+		Enabled:    true,
+		MaxRetries: 1,
+		RetryDelay: 1,
+	}
+	if err := manager.Save(hook); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Webhooks are persisted
+	reloaded, err := webhooks.NewManager(store, nil)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if got, err := reloaded.Get(hook.ID); err != nil || got.Secret != "s3cret" {
+		t.Errorf("Expected the webhook to be reloaded, got %+v, %v", got, err)
+	}
+
+	eventStore := events.NewStore(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Run(ctx, eventStore)
+	time.Sleep(50 * time.Millisecond) // let Run subscribe
+
+	eventStore.Add(events.EventLogin, "alice", "10.0.0.1", true, "not matching")
+	eventStore.Add(events.EventContainerStart, "alice", "10.0.0.1", true, "nginx")
+
+	select {
+	case event := <-received:
+		if event.Type != events.EventContainerStart || event.Details != "nginx" {
+			t.Errorf("Expected the container_start event, got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Webhook was not delivered")
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+
+	// The status is recorded after the handler returns
+	deadline := time.Now().Add(time.Second)
+	for manager.Status(hook.ID) == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := manager.Status(hook.ID); status == nil || !status.Success || status.Attempts != 2 {
+		t.Errorf("Expected a successful delivery after 2 attempts, got %+v", status)
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"podmanview/internal/api"
+	"podmanview/internal/auth"
+	"podmanview/internal/podman"
+)
+
+func TestWSTokenFingerprintBinding(t *testing.T) {
+	store := auth.NewWSTokenStore()
+	owner := auth.ClientFingerprint{IP: "192.168.1.10", UserAgent: "Mozilla/5.0"}
+
+	tests := []struct {
+		name        string
+		fingerprint auth.ClientFingerprint
+		wantValid   bool
+	}{
+		{"same client", owner, true},
+		{"different IP", auth.ClientFingerprint{IP: "192.168.1.11", UserAgent: owner.UserAgent}, false},
+		{"different user agent", auth.ClientFingerprint{IP: owner.IP, UserAgent: "curl/8.0"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := store.Generate("admin", owner)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			username, valid := store.Validate(token, tt.fingerprint)
+			if valid != tt.wantValid {
+				t.Fatalf("Validate() valid = %v; want %v", valid, tt.wantValid)
+			}
+			if valid && username != "admin" {
+				t.Errorf("Validate() username = %q; want %q", username, "admin")
+			}
+		})
+	}
+}
+
+func TestWSTokenRevokedAfterRelay(t *testing.T) {
+	store := auth.NewWSTokenStore()
+	owner := auth.ClientFingerprint{IP: "10.0.0.1", UserAgent: "Mozilla/5.0"}
+	attacker := auth.ClientFingerprint{IP: "10.0.0.2", UserAgent: "Mozilla/5.0"}
+
+	token, err := store.Generate("admin", owner)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if _, valid := store.Validate(token, attacker); valid {
+		t.Fatal("Expected relayed token to be rejected")
+	}
+
+	// Token must be revoked, so even the owner can no longer use it
+	if _, valid := store.Validate(token, owner); valid {
+		t.Error("Expected token to be revoked after relay attempt")
+	}
+}
+
+func TestWSTokenForwardedFor(t *testing.T) {
+	newServer := func(env string) *api.Server {
+		t.Helper()
+		return newTestServer(t, podman.NewClientWithHandler(http.NewServeMux()), withConfig(newTestConfig(t, env)))
+	}
+	request := func(server *api.Server, path, remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	token := func(server *api.Server, remoteAddr string, header map[string]string) string {
+		t.Helper()
+		var body struct {
+			Token string `json:"token"`
+		}
+		rec := request(server, "/api/v1/auth/ws-token", remoteAddr, header)
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Token == "" {
+			t.Fatalf("Failed to get a WebSocket token: %d %s", rec.Code, rec.Body)
+		}
+		return body.Token
+	}
+	// connect opens the host terminal; a token that doesn't match the client
+	// is refused with 403 before the upgrade
+	connect := func(server *api.Server, token, remoteAddr string, header map[string]string) int {
+		h := map[string]string{
+			"Connection":            "Upgrade",
+			"Upgrade":               "websocket",
+			"Sec-WebSocket-Version": "13",
+			"Sec-WebSocket-Key":     "dGhlIHNhbXBsZSBub25jZQ==",
+		}
+		for k, v := range header {
+			h[k] = v
+		}
+		return request(server, "/api/v1/terminal?ws_token="+token, remoteAddr, h).Code
+	}
+
+	// Without trusted proxies the headers of the client are ignored
+	server := newServer("")
+	victim := token(server, "192.0.2.10:40000", nil)
+	spoofed := map[string]string{"X-Forwarded-For": "192.0.2.10", "X-Real-IP": "192.0.2.10"}
+	if code := connect(server, victim, "198.51.100.7:50000", spoofed); code != http.StatusForbidden {
+		t.Errorf("Expected a spoofed X-Forwarded-For to be refused, got %d", code)
+	}
+
+	// Behind a trusted proxy the client IP comes from the proxy
+	server = newServer("PODMANVIEW_TRUSTED_PROXIES=10.0.0.0/8\n")
+	victim = token(server, "10.0.0.1:40000", map[string]string{"X-Real-IP": "192.0.2.10"})
+	if code := connect(server, victim, "10.0.0.2:40000", map[string]string{"X-Forwarded-For": "192.0.2.10, 10.0.0.3"}); code == http.StatusForbidden {
+		t.Error("Expected the token to be accepted from the same client through the proxy")
+	}
+	victim = token(server, "10.0.0.1:40000", map[string]string{"X-Real-IP": "192.0.2.10"})
+	if code := connect(server, victim, "10.0.0.1:40000", map[string]string{"X-Forwarded-For": "192.0.2.10, 198.51.100.7"}); code != http.StatusForbidden {
+		t.Errorf("Expected an address added by the client to be ignored, got %d", code)
+	}
+	victim = token(server, "10.0.0.1:40000", map[string]string{"X-Real-IP": "192.0.2.10"})
+	if code := connect(server, victim, "198.51.100.7:50000", spoofed); code != http.StatusForbidden {
+		t.Errorf("Expected the headers of a client outside the proxies to be ignored, got %d", code)
+	}
+}
@@ -0,0 +1,32 @@
+// Package static embeds the web UI assets (styles, scripts, images, manifest
+// and service worker), so the binary runs without the web directory.
+package static
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+)
+
+//go:embed css img js manifest.json sw.js
+var Files embed.FS
+
+// Version returns a short hash of all embedded files. It only changes when
+// the assets change, so browsers keep their cached files across restarts.
+func Version() string {
+	h := sha256.New()
+	fs.WalkDir(Files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := Files.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(path))
+		h.Write(data)
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
@@ -1,5 +1,36 @@
 // PodmanView - Podman Web Management
 
+// URL prefix for subpath deployments (set by the server in index.html)
+const BASE_PATH = window.BASE_PATH || '';
+
+// API version used by the web UI, unversioned /api paths are deprecated
+const API_PREFIX = '/api/v1';
+
+// Prefix root-relative app URLs with the base path, API URLs also with the version
+function appUrl(path) {
+    if (path.startsWith('/api/') && !path.startsWith(API_PREFIX + '/')) {
+        path = API_PREFIX + path.slice(4);
+    }
+    return path.startsWith('/') && !path.startsWith('//') ? BASE_PATH + path : path;
+}
+
+// Route all root-relative fetch() calls (app and plugins) through the base path and API version
+const nativeFetch = window.fetch.bind(window);
+window.fetch = (input, init) => nativeFetch(typeof input === 'string' ? appUrl(input) : input, init);
+
+// Message of an API error response ({code, message, details, requestId}),
+// falls back to the response text for non-JSON errors
+async function responseError(response, fallback) {
+    const text = await response.text();
+    try {
+        const data = JSON.parse(text);
+        if (data && (data.message || data.error)) return data.message || data.error;
+    } catch (e) {
+        // not an API error response
+    }
+    return text || fallback;
+}
+
 // User roles (enum-like constants)
 const UserRole = Object.freeze({
     ADMIN: 'admin',
@@ -12,6 +43,7 @@ const App = {
     terminal: null,
     terminalSocket: null,
     terminalFitAddon: null,
+    terminalAttach: false,
     hostTerminal: null,
     hostTerminalSocket: null,
     hostTerminalFitAddon: null,
@@ -24,6 +56,11 @@ const App = {
     eventsLastId: 0,
     eventsOpen: false,
     eventsCheckInterval: null,
+    eventsSource: null,
+    eventTypes: null, // event type catalog: type -> {label, category, severity}
+    systemLogsCursor: 0, // next page of the Logs page, 0 = no more
+    systemLogsSource: null, // live tail of the Logs page
+    engineRefreshTimer: null,
 
     // Command history for terminal
     commandHistory: [],
@@ -187,7 +224,7 @@ const App = {
                 // Load CSS
                 const link = document.createElement('link');
                 link.rel = 'stylesheet';
-                link.href = '/static/css/xterm.min.css';
+                link.href = appUrl('/static/css/xterm.min.css');
                 document.head.appendChild(link);
 
                 // Load xterm.js
@@ -212,7 +249,7 @@ const App = {
     loadScript(src) {
         return new Promise((resolve, reject) => {
             const script = document.createElement('script');
-            script.src = src;
+            script.src = appUrl(src);
             script.onload = resolve;
             script.onerror = reject;
             document.body.appendChild(script);
@@ -313,8 +350,8 @@ const App = {
         // Dashboard
         document.getElementById('refresh-dashboard').addEventListener('click', () => this.loadDashboard());
         document.getElementById('auto-refresh-toggle').addEventListener('change', (e) => this.setAutoRefresh('dashboard', e.target.checked));
-        document.getElementById('system-reboot-btn').addEventListener('click', () => this.confirmAction('Reboot Host', 'Are you sure you want to reboot the host system? All containers will be stopped.', () => this.systemReboot()));
-        document.getElementById('system-shutdown-btn').addEventListener('click', () => this.confirmAction('Shutdown Host', 'Are you sure you want to shutdown the host system? All containers will be stopped and the system will power off.', () => this.systemShutdown()));
+        document.getElementById('system-reboot-btn').addEventListener('click', () => this.confirmDestructive('/api/system/reboot', 'Reboot Host', (impact) => `Are you sure you want to reboot the host system? ${this.describeRunning(impact)}`, (token) => this.systemReboot(token)));
+        document.getElementById('system-shutdown-btn').addEventListener('click', () => this.confirmDestructive('/api/system/shutdown', 'Shutdown Host', (impact) => `Are you sure you want to shutdown the host system? ${this.describeRunning(impact)} The system will power off.`, (token) => this.systemShutdown(token)));
 
         // Containers page
         document.getElementById('refresh-containers').addEventListener('click', () => this.loadContainers());
@@ -328,11 +365,35 @@ const App = {
         // Images page
         document.getElementById('refresh-images').addEventListener('click', () => this.loadImages());
         document.getElementById('auto-refresh-images').addEventListener('change', (e) => this.setAutoRefresh('images', e.target.checked));
-        document.getElementById('pull-image-btn').addEventListener('click', () => this.showModal('modal-pull'));
+        document.getElementById('pull-image-btn').addEventListener('click', () => this.showPullModal());
+        document.getElementById('registry-search').addEventListener('keydown', (e) => {
+            if (e.key === 'Enter') {
+                e.preventDefault();
+                this.searchRegistry();
+            }
+        });
         document.getElementById('pull-form').addEventListener('submit', (e) => {
             e.preventDefault();
             this.pullImage();
         });
+        document.getElementById('build-image-btn').addEventListener('click', () => {
+            this.showModal('modal-build');
+            this.loadSavedBuilds();
+        });
+        document.getElementById('build-save-btn').addEventListener('click', () => this.saveBuild());
+        document.getElementById('build-form').addEventListener('submit', (e) => {
+            e.preventDefault();
+            this.buildImage();
+        });
+
+        // Logs page
+        document.getElementById('refresh-system-logs').addEventListener('click', () => this.loadSystemLogs());
+        document.getElementById('system-logs-more').addEventListener('click', () => this.loadSystemLogs(true));
+        document.getElementById('system-logs-live').addEventListener('change', (e) => this.setSystemLogsLive(e.target.checked));
+        document.getElementById('system-logs-level').addEventListener('change', () => this.loadSystemLogs());
+        document.querySelectorAll('.system-logs-filter').forEach(input => {
+            input.addEventListener('change', () => this.loadSystemLogs());
+        });
 
         // Close dropdowns on click outside
         document.addEventListener('click', (e) => {
@@ -403,7 +464,9 @@ const App = {
             if (response.ok) {
                 const data = await response.json();
                 this.user = data.user;
+                this.containerTerminal = data.containerTerminal;
                 this.showApp();
+                this.checkDemo();
             } else {
                 this.showLogin();
             }
@@ -413,6 +476,19 @@ const App = {
         }
     },
 
+    // Tell visitors of a demo instance that changes are disabled
+    async checkDemo() {
+        try {
+            const response = await fetch('/api/health');
+            const data = await response.json();
+            if (data.demo) {
+                this.showToast('Demo mode: mock data, changes are disabled', 'info');
+            }
+        } catch {
+            // Not important enough to report
+        }
+    },
+
     // Login
     async login() {
         const username = document.getElementById('username').value;
@@ -431,6 +507,11 @@ const App = {
 
             if (data.success) {
                 this.user = data.user;
+                this.containerTerminal = this.isAdmin();
+                // Read-only users may have container terminals too
+                fetch('/api/auth/me').then(r => r.ok ? r.json() : {}).then(me => {
+                    this.containerTerminal = !!me.containerTerminal;
+                }).catch(() => {});
                 errorEl.textContent = '';
                 this.showApp();
             } else {
@@ -509,6 +590,9 @@ const App = {
         if (this.currentPage === 'terminal') {
             this.cleanupHostTerminal();
         }
+        if (this.currentPage === 'logs') {
+            this.setSystemLogsLive(false);
+        }
         // Stop auto-refresh on previous page
         this.stopAutoRefresh(this.currentPage);
 
@@ -563,12 +647,103 @@ const App = {
             case 'files':
                 this.initFileManager();
                 break;
+            case 'logs':
+                this.loadSystemLogs();
+                break;
             case 'plugins':
                 console.log('[App] Loading plugins page');
                 break;
         }
     },
 
+    // Logs page: query parameters from the filters
+    systemLogsParams() {
+        const params = new URLSearchParams();
+        params.set('level', document.getElementById('system-logs-level').value);
+        const search = document.getElementById('system-logs-search').value.trim();
+        if (search) params.set('q', search);
+        for (const name of ['from', 'to']) {
+            const value = document.getElementById('system-logs-' + name).value;
+            if (value) params.set(name, new Date(value).toISOString());
+        }
+        return params;
+    },
+
+    // Load the application's own logs, newest first (append = next page)
+    async loadSystemLogs(append = false) {
+        const list = document.getElementById('system-logs-list');
+        const more = document.getElementById('system-logs-more');
+        const params = this.systemLogsParams();
+        params.set('limit', '200');
+        if (append && this.systemLogsCursor) {
+            params.set('cursor', this.systemLogsCursor);
+        }
+
+        try {
+            const response = await this.authFetch('/api/system/logs?' + params);
+            const data = await response.json();
+            if (!response.ok) {
+                list.innerHTML = `<div class="system-logs-empty">${this.escapeHtml(data.error || 'Failed to load logs')}</div>`;
+                more.classList.add('hidden');
+                return;
+            }
+
+            const html = (data.entries || []).map(entry => this.renderSystemLogEntry(entry)).join('');
+            if (append) {
+                list.insertAdjacentHTML('beforeend', html);
+            } else {
+                list.innerHTML = html || '<div class="system-logs-empty">No log entries</div>';
+            }
+            this.systemLogsCursor = data.nextCursor || 0;
+            more.classList.toggle('hidden', !this.systemLogsCursor);
+
+            // Restart the live tail with the new filters
+            if (!append && this.systemLogsSource) {
+                this.setSystemLogsLive(true);
+            }
+        } catch (error) {
+            console.error('Failed to load logs:', error);
+        }
+    },
+
+    renderSystemLogEntry(entry) {
+        const time = entry.time ? new Date(entry.time).toLocaleString() : '';
+        const fields = Object.entries(entry.fields || {})
+            .map(([key, value]) => `<span class="system-log-field">${this.escapeHtml(key)}=${this.escapeHtml(value)}</span>`)
+            .join(' ');
+        return `
+            <div class="system-log-entry level-${entry.level}">
+                <span class="system-log-time">${time}</span>
+                <span class="system-log-level">${entry.level.toUpperCase()}</span>
+                <span class="system-log-message">${this.escapeHtml(entry.message)}</span>
+                ${fields}
+            </div>`;
+    },
+
+    // Live tail: new entries matching the filters are added on top
+    setSystemLogsLive(enabled) {
+        if (this.systemLogsSource) {
+            this.systemLogsSource.close();
+            this.systemLogsSource = null;
+        }
+        document.getElementById('system-logs-live').checked = enabled;
+        if (!enabled || !window.EventSource) return;
+
+        this.systemLogsSource = new EventSource(appUrl('/api/system/logs/stream?' + this.systemLogsParams()));
+        this.systemLogsSource.addEventListener('log', (e) => {
+            let entry;
+            try {
+                entry = JSON.parse(e.data);
+            } catch (error) {
+                return;
+            }
+            const list = document.getElementById('system-logs-list');
+            const empty = list.querySelector('.system-logs-empty');
+            if (empty) empty.remove();
+            list.insertAdjacentHTML('afterbegin', this.renderSystemLogEntry(entry));
+        });
+    },
+
     // Cleanup when leaving terminal page
     cleanupHostTerminal() {
         // Clear reconnect timer
@@ -634,21 +809,86 @@ const App = {
         }
     },
 
-    // Start periodic check for new events
+    // Start live event stream, falls back to periodic check
     startEventsCheck() {
         // Initial check
         this.checkNewEvents();
+
+        if (window.EventSource) {
+            this.eventsSource = new EventSource(appUrl('/api/events/stream'));
+            this.eventsSource.addEventListener('audit', (e) => this.handleStreamEvent(e));
+            this.eventsSource.addEventListener('engine', (e) => this.handleEngineEvent(e));
+            this.eventsSource.onerror = () => {
+                // EventSource reconnects by itself unless the stream was refused
+                if (this.eventsSource && this.eventsSource.readyState === EventSource.CLOSED) {
+                    this.eventsSource = null;
+                    this.startEventsPolling();
+                }
+            };
+            return;
+        }
+        this.startEventsPolling();
+    },
+
+    startEventsPolling() {
+        if (this.eventsCheckInterval) return;
         // Check every 30 seconds
         this.eventsCheckInterval = setInterval(() => this.checkNewEvents(), 30000);
     },
 
     stopEventsCheck() {
+        if (this.eventsSource) {
+            this.eventsSource.close();
+            this.eventsSource = null;
+        }
         if (this.eventsCheckInterval) {
             clearInterval(this.eventsCheckInterval);
             this.eventsCheckInterval = null;
         }
     },
 
+    // New event log entry from the stream
+    handleStreamEvent(e) {
+        let event;
+        try {
+            event = JSON.parse(e.data);
+        } catch (error) {
+            return;
+        }
+        if (event.id <= this.eventsLastId) return;
+        this.eventsLastId = event.id;
+
+        if (this.eventsOpen) {
+            this.loadEvents();
+            return;
+        }
+        const badge = document.getElementById('events-badge');
+        if (badge) {
+            badge.classList.remove('hidden');
+        }
+    },
+
+    // Podman engine event from the stream: refresh the affected page
+    handleEngineEvent(e) {
+        let event;
+        try {
+            event = JSON.parse(e.data);
+        } catch (error) {
+            return;
+        }
+        const pages = { container: 'containers', image: 'images' };
+        const page = pages[event.Type];
+        if (!page || page !== this.currentPage || document.hidden) return;
+
+        // Coalesce bursts of events (e.g. create + start) into one reload
+        clearTimeout(this.engineRefreshTimer);
+        this.engineRefreshTimer = setTimeout(() => {
+            if (this.currentPage === page) {
+                this[this.autoRefreshConfig[page].loader]();
+            }
+        }, 500);
+    },
+
     async checkNewEvents() {
         if (!this.user) return;
 
@@ -687,7 +927,22 @@ const App = {
         }
     },
 
+    // Load the event type catalog once, for event labels
+    async loadEventTypes() {
+        if (this.eventTypes) return;
+        try {
+            const response = await this.authFetch('/api/events/types');
+            if (!response.ok) return;
+            const data = await response.json();
+            this.eventTypes = {};
+            (data.types || []).forEach(t => { this.eventTypes[t.type] = t; });
+        } catch (error) {
+            console.error('Failed to load event types:', error);
+        }
+    },
+
     async loadEvents() {
+        await this.loadEventTypes();
         try {
             const response = await this.authFetch('/api/events?limit=50');
             if (!response.ok) return;
@@ -715,30 +970,15 @@ const App = {
             return;
         }
 
-        const eventLabels = {
-            'login': 'Login',
-            'login_failed': 'Login Failed',
-            'logout': 'Logout',
-            'terminal_host': 'Host Terminal',
-            'terminal_container': 'Container Terminal',
-            'container_start': 'Container Start',
-            'container_stop': 'Container Stop',
-            'container_restart': 'Container Restart',
-            'container_remove': 'Container Remove',
-            'container_create': 'Container Create',
-            'image_pull': 'Image Pull',
-            'image_remove': 'Image Remove',
-            'system_reboot': 'System Reboot',
-            'system_shutdown': 'System Shutdown'
-        };
-
         list.innerHTML = events.map(event => {
             const time = new Date(event.timestamp).toLocaleString();
-            const label = eventLabels[event.type] || event.type;
+            const info = this.eventTypes && this.eventTypes[event.type];
+            const label = info ? info.label : event.type;
             const statusIcon = event.success ? '' : ' (failed)';
+            const severity = event.severity || 'info';
 
             return `
-                <div class="event-item">
+                <div class="event-item severity-${severity}" title="${event.category || ''} / ${severity}">
                     <div class="event-row">
                         <span class="event-type ${event.type}">${label}${statusIcon}</span>
                         <span class="event-user">${event.username || 'unknown'}</span>
@@ -776,8 +1016,9 @@ const App = {
         }
     },
 
-    // Add command to history for container terminals (saves to localStorage)
-    addToHistoryLocal(command) {
+    // Add command to history for container terminals (saves to localStorage
+    // for arrow key navigation and to the server's searchable history)
+    addToHistoryLocal(command, socket) {
         // Don't add empty commands or duplicates of the last command
         if (!command.trim() || command === this.commandHistory[this.commandHistory.length - 1]) {
             return;
@@ -796,6 +1037,17 @@ const App = {
                 console.warn('Failed to save to localStorage:', e);
             }
         }
+
+        if (socket && socket.readyState === WebSocket.OPEN) {
+            try {
+                socket.send(JSON.stringify({
+                    type: 'save_command',
+                    command: command
+                }));
+            } catch (e) {
+                console.warn('Failed to save command via WebSocket:', e);
+            }
+        }
     },
 
     // Load container terminal history from localStorage
@@ -980,7 +1232,7 @@ const App = {
 
         // Connect WebSocket with CSRF token
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const wsUrl = `${protocol}//${window.location.host}/api/terminal?ws_token=${encodeURIComponent(wsToken)}`;
+        const wsUrl = `${protocol}//${window.location.host}${appUrl('/api/terminal')}?ws_token=${encodeURIComponent(wsToken)}`;
 
         try {
             const socket = new WebSocket(wsUrl);
@@ -1007,6 +1259,8 @@ const App = {
             };
 
             socket.onmessage = (event) => {
+                if (this.handleTerminalTransfer('host', event.data)) return;
+
                 // Try to parse as JSON (for history message)
                 try {
                     const msg = JSON.parse(event.data);
@@ -1273,6 +1527,7 @@ const App = {
             if (data.hostStats) {
                 document.getElementById('info-cpu').textContent = data.hostStats.cpuUsage.toFixed(1) + '%';
                 document.getElementById('info-uptime').textContent = this.formatUptime(data.hostStats.uptime);
+                this.renderThrottling(data.hostStats.throttling);
 
                 // Update memory (using MemAvailable for accurate "free" memory)
                 if (data.hostStats.memTotal) {
@@ -1289,7 +1544,7 @@ const App = {
                     if (data.hostStats.disks.length === 1) {
                         // Single disk - show inline
                         const d = data.hostStats.disks[0];
-                        singleDisk.textContent = `${this.formatBytes(d.used)} / ${this.formatBytes(d.total)}`;
+                        singleDisk.textContent = `${this.formatBytes(d.used)} / ${this.formatBytes(d.total)} · ${this.formatDiskIO(d)}`;
                         singleDisk.parentElement.style.display = '';
                         disksList.style.display = 'none';
                     } else {
@@ -1298,12 +1553,24 @@ const App = {
                         disksList.innerHTML = data.hostStats.disks.map(d => this.renderDiskItem(d)).join('');
                         disksList.style.display = '';
                     }
-                } else if (data.hostStats.diskTotal) {
-                    // Fallback to old format
-                    const used = data.hostStats.diskTotal - data.hostStats.diskFree;
-                    singleDisk.textContent = `${this.formatBytes(used)} / ${this.formatBytes(data.hostStats.diskTotal)}`;
-                    singleDisk.parentElement.style.display = '';
-                    disksList.style.display = 'none';
+                }
+
+                // Update ZFS pools and btrfs filesystems
+                const poolsList = document.getElementById('pools-list');
+                if (data.hostStats.pools && data.hostStats.pools.length > 0) {
+                    poolsList.innerHTML = data.hostStats.pools.map(p => this.renderPoolItem(p)).join('');
+                    poolsList.style.display = '';
+                } else {
+                    poolsList.style.display = 'none';
+                }
+
+                // Update md software RAID arrays
+                const raidList = document.getElementById('raid-list');
+                if (data.hostStats.raid && data.hostStats.raid.length > 0) {
+                    raidList.innerHTML = data.hostStats.raid.map(a => this.renderRaidItem(a)).join('');
+                    raidList.style.display = '';
+                } else {
+                    raidList.style.display = 'none';
                 }
 
                 // Update temperatures section
@@ -1349,7 +1616,7 @@ const App = {
         const isInitialLoad = existingRows.length === 0;
 
         if (isInitialLoad) {
-            tbody.innerHTML = '<tr><td colspan="5">Loading...</td></tr>';
+            tbody.innerHTML = '<tr><td colspan="6">Loading...</td></tr>';
         }
 
         try {
@@ -1359,7 +1626,7 @@ const App = {
             const containers = await response.json();
 
             if (!containers || containers.length === 0) {
-                tbody.innerHTML = '<tr><td colspan="5">No containers found</td></tr>';
+                tbody.innerHTML = '<tr><td colspan="6">No containers found</td></tr>';
                 return;
             }
 
@@ -1391,6 +1658,10 @@ const App = {
                         ? `${c.CPU.toFixed(1)}% / ${this.formatBytes(c.MemUsage)}`
                         : '-';
                     statsCell.textContent = statsDisplay;
+
+                    const uptimeCell = existingRow.querySelector('.uptime-cell');
+                    uptimeCell.textContent = this.formatUptime(c.Uptime && c.Uptime.week);
+                    uptimeCell.title = this.getUptimeTitle(c.Uptime);
                 } else {
                     // Add new row
                     const tr = document.createElement('tr');
@@ -1417,7 +1688,7 @@ const App = {
         } catch (error) {
             if (error.message !== 'Session expired') {
                 if (isInitialLoad) {
-                    tbody.innerHTML = '<tr><td colspan="5">Error loading containers</td></tr>';
+                    tbody.innerHTML = '<tr><td colspan="6">Error loading containers</td></tr>';
                 }
                 this.showToast('Failed to load containers', 'error');
             }
@@ -1434,12 +1705,24 @@ const App = {
             <td class="truncate">${this.getContainerName(c)}</td>
             <td class="truncate">${c.Image}</td>
             <td><span class="status ${c.State}">${c.State}</span></td>
+            <td class="uptime-cell" title="${this.getUptimeTitle(c.Uptime)}">${this.formatUptime(c.Uptime && c.Uptime.week)}</td>
             <td class="stats-cell">${statsDisplay}</td>
             <td class="actions">
                 ${this.getContainerActions(c)}
             </td>`;
     },
 
+    // Format an uptime percentage, '-' when unknown
+    formatUptime(percent) {
+        return percent === null || percent === undefined ? '-' : `${percent}%`;
+    },
+
+    // Get the uptime periods of a container for the uptime cell tooltip
+    getUptimeTitle(uptime) {
+        if (!uptime) return 'No state recorded yet';
+        return `24h: ${this.formatUptime(uptime.day)}, 7d: ${this.formatUptime(uptime.week)}, 30d: ${this.formatUptime(uptime.month)}`;
+    },
+
     // Get container name from Names array
     getContainerName(container) {
         if (container.Names && container.Names.length > 0) {
@@ -1460,9 +1743,14 @@ const App = {
             menuItems += `<button class="dropdown-item" onclick="App.viewStats('${id}')">Stats</button>`;
         }
 
+        if (!isAdmin && this.containerTerminal && container.State === 'running') {
+            menuItems += `<button class="dropdown-item" onclick="App.openTerminal('${id}')">Terminal</button>`;
+        }
+
         if (isAdmin) {
             if (container.State === 'running') {
                 menuItems += `<button class="dropdown-item" onclick="App.openTerminal('${id}')">Terminal</button>`;
+                menuItems += `<button class="dropdown-item" onclick="App.openTerminal('${id}', true)">Attach</button>`;
                 menuItems += `<div class="dropdown-divider"></div>`;
                 menuItems += `<button class="dropdown-item" onclick="App.stopContainer('${id}')">Stop</button>`;
                 menuItems += `<button class="dropdown-item" onclick="App.restartContainer('${id}')">Restart</button>`;
@@ -1563,7 +1851,7 @@ const App = {
         this.confirmAction('Remove Container', 'Are you sure you want to remove this container?', async () => {
             this.showToast('Removing container...', 'info');
             try {
-                const response = await this.authFetch(`/api/containers/${id}?force=true`, { method: 'DELETE' });
+                const response = await this.authFetch(`/api/containers/${id}?force=true&trash=true`, { method: 'DELETE' });
                 if (!response.ok) throw new Error('Failed to remove container');
                 this.showToast('Container removed', 'success');
                 this.loadContainers();
@@ -1859,17 +2147,47 @@ const App = {
             <td>${this.formatDate(img.Created)}</td>
             <td>${usageStatus}</td>
             <td class="actions">
-                ${this.isAdmin()
-                    ? `<div class="dropdown">
-                        <button class="btn btn-small" onclick="App.toggleDropdown(this)">...</button>
-                        <div class="dropdown-menu">
-                            <button class="dropdown-item btn-danger" onclick="App.removeImage('${imgId}')">Remove</button>
-                        </div>
-                    </div>`
-                    : ''}
+                <div class="dropdown">
+                    <button class="btn btn-small" onclick="App.toggleDropdown(this)">...</button>
+                    <div class="dropdown-menu">
+                        <button class="dropdown-item" onclick="App.showImageLayers('${imgId}')">Layers</button>
+                        ${this.isAdmin() ? `<button class="dropdown-item btn-danger" onclick="App.removeImage('${imgId}')">Remove</button>` : ''}
+                    </div>
+                </div>
             </td>`;
     },
 
+    // Show the build history of an image with the size of each layer
+    async showImageLayers(id) {
+        const content = document.getElementById('layers-content');
+        content.innerHTML = '<div class="stats-loading">Loading...</div>';
+        this.showModal('modal-layers');
+
+        try {
+            const response = await this.authFetch(`/api/images/${id}/layers`);
+            if (!response.ok) throw new Error('Failed to load layers');
+            const data = await response.json();
+
+            const rows = data.history.map(layer => `
+                <tr class="${layer.empty ? 'text-muted' : ''}">
+                    <td class="layer-size">${layer.empty ? '-' : this.formatBytes(layer.size)}</td>
+                    <td class="layer-percent">
+                        ${layer.empty ? '' : `<div class="disk-bar"><div class="disk-bar-fill ${layer.percent > 50 ? 'warning' : 'normal'}" style="width: ${layer.percent}%"></div></div>`}
+                    </td>
+                    <td class="layer-command" title="${this.escapeHtml(layer.createdBy)}">${this.escapeHtml(layer.command)}</td>
+                </tr>`).join('');
+            content.innerHTML = `
+                <p>${this.formatBytes(data.size)} in ${data.layers} layers, newest first</p>
+                <table class="layers-table">
+                    <tbody>${rows}</tbody>
+                </table>`;
+        } catch (error) {
+            if (error.message !== 'Session expired') {
+                content.innerHTML = '<div class="stats-loading">Failed to load layers</div>';
+            }
+        }
+    },
+
     // Parse image repository and tag
     parseImageTag(image) {
         if (image.RepoTags && image.RepoTags.length > 0 && image.RepoTags[0] !== '<none>:<none>') {
@@ -1880,9 +2198,107 @@ const App = {
         return ['<none>', '<none>'];
     },
 
+    // Show the pull dialog with the configured registries to search
+    async showPullModal() {
+        document.getElementById('registry-results').innerHTML = '';
+        this.showModal('modal-pull');
+
+        const select = document.getElementById('registry-select');
+        if (select.options.length > 0) return;
+        try {
+            const response = await this.authFetch('/api/registry');
+            if (!response.ok) throw new Error('Failed to load registries');
+            const data = await response.json();
+            select.innerHTML = data.registries.map(name =>
+                `<option value="${this.escapeHtml(name)}">${this.escapeHtml(name)}</option>`).join('');
+        } catch (error) {
+            // Searching is optional, the reference can still be typed
+        }
+    },
+
+    // Search the selected registry for images
+    async searchRegistry() {
+        const query = document.getElementById('registry-search').value.trim();
+        if (!query) return;
+        const registry = document.getElementById('registry-select').value;
+        const results = document.getElementById('registry-results');
+        results.innerHTML = '<div class="stats-loading">Searching...</div>';
+
+        try {
+            const params = new URLSearchParams({ q: query, registry });
+            const response = await this.authFetch(`/api/registry/search?${params}`);
+            if (!response.ok) throw new Error('Failed to search registry');
+            const data = await response.json();
+            if (data.results.length === 0) {
+                results.innerHTML = '<div class="text-muted">No images found</div>';
+                return;
+            }
+            results.innerHTML = data.results.map(image => `
+                <div class="registry-result" onclick="App.showRegistryTags('${this.escapeHtml(image.name)}')">
+                    <div>
+                        <strong>${this.escapeHtml(image.name)}</strong>
+                        ${image.official ? '<span class="badge">official</span>' : ''}
+                        ${image.stars ? `<span class="text-muted">&#9733; ${image.stars}</span>` : ''}
+                    </div>
+                    <div class="text-muted">${this.escapeHtml(image.description)}</div>
+                </div>`).join('');
+        } catch (error) {
+            if (error.message !== 'Session expired') {
+                results.innerHTML = '<div class="text-muted">Failed to search registry</div>';
+            }
+        }
+    },
+
+    // List the tags of an image found in a registry to pick one to pull
+    async showRegistryTags(image) {
+        const results = document.getElementById('registry-results');
+        document.getElementById('image-reference').value = image;
+        results.innerHTML = '<div class="stats-loading">Loading tags...</div>';
+
+        try {
+            const response = await this.authFetch(`/api/registry/tags?${new URLSearchParams({ image })}`);
+            if (!response.ok) throw new Error('Failed to load tags');
+            const data = await response.json();
+            if (data.tags.length === 0) {
+                results.innerHTML = '<div class="text-muted">No tags found</div>';
+                return;
+            }
+            const rows = data.tags.map(tag => `
+                <tr class="registry-result" onclick="App.selectRegistryTag('${this.escapeHtml(data.image)}', '${this.escapeHtml(tag.name)}', '${this.escapeHtml((tag.platforms || []).join(','))}')">
+                    <td><strong>${this.escapeHtml(tag.name)}</strong></td>
+                    <td class="text-muted" title="${this.escapeHtml(tag.digest || '')}">${tag.digest ? this.escapeHtml(tag.digest.slice(7, 19)) : ''}</td>
+                    <td>${tag.error ? `<span class="text-muted">${this.escapeHtml(tag.error)}</span>` : this.escapeHtml((tag.platforms || []).join(', '))}</td>
+                </tr>`).join('');
+            const more = data.total > data.tags.length ? `<div class="text-muted">${data.tags.length} of ${data.total} tags</div>` : '';
+            results.innerHTML = `<table class="layers-table"><tbody>${rows}</tbody></table>${more}`;
+        } catch (error) {
+            if (error.message !== 'Session expired') {
+                results.innerHTML = '<div class="text-muted">Failed to load tags</div>';
+            }
+        }
+    },
+
+    // Fill the reference to pull with a tag picked from the registry and
+    // offer the platforms it is built for
+    selectRegistryTag(image, tag, platforms) {
+        document.getElementById('image-reference').value = `${image}:${tag}`;
+        this.setPullPlatforms(platforms ? platforms.split(',') : []);
+    },
+
+    // Set the platforms offered in the pull dialog, keeping the selected one
+    // if the image has it
+    setPullPlatforms(platforms) {
+        const select = document.getElementById('pull-platform');
+        const selected = select.value;
+        select.innerHTML = '<option value="">Host default</option>' + platforms.map(platform =>
+            `<option value="${this.escapeHtml(platform)}">${this.escapeHtml(platform)}</option>`).join('');
+        if (platforms.includes(selected)) select.value = selected;
+    },
+
     // Pull image
     async pullImage() {
         const reference = document.getElementById('image-reference').value;
+        const platform = document.getElementById('pull-platform').value;
         if (!reference) return;
 
         const btn = document.querySelector('#pull-form button[type="submit"]');
@@ -1894,7 +2310,7 @@ const App = {
             const response = await this.authFetch('/api/images/pull', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ reference })
+                body: JSON.stringify({ reference, platform })
             });
 
             if (!response.ok) throw new Error('Failed to pull image');
@@ -1903,6 +2319,7 @@ const App = {
             this.closeModal('modal-pull');
             this.loadImages();
             document.getElementById('image-reference').value = '';
+            this.setPullPlatforms([]);
         } catch (error) {
             if (error.message !== 'Session expired') this.showToast('Failed to pull image', 'error');
         } finally {
@@ -1911,6 +2328,201 @@ const App = {
         }
     },
 
+    // The build in the build dialog
+    buildRequest() {
+        const value = (id) => document.getElementById(id).value.trim();
+        const buildArgs = {};
+        for (const line of value('build-args').split('\n')) {
+            const i = line.indexOf('=');
+            if (i > 0) buildArgs[line.slice(0, i).trim()] = line.slice(i + 1).trim();
+        }
+        return {
+            url: value('build-url'),
+            ref: value('build-ref'),
+            context: value('build-context'),
+            dockerfile: value('build-dockerfile'),
+            tag: value('build-tag'),
+            buildArgs
+        };
+    },
+
+    // Save the build in the build dialog, updating the saved build of the
+    // same name
+    async saveBuild() {
+        const name = document.getElementById('build-name').value.trim();
+        if (!name) {
+            this.showToast('Enter a name to save the build', 'error');
+            return;
+        }
+        const existing = (this.savedBuilds || []).find(build => build.name === name);
+        try {
+            const response = await this.authFetch(existing ? `/api/builds/${existing.id}` : '/api/builds', {
+                method: existing ? 'PUT' : 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({
+                    name,
+                    ...this.buildRequest(),
+                    scheduled: document.getElementById('build-scheduled').checked,
+                    watchBase: document.getElementById('build-watch-base').checked,
+                    redeploy: document.getElementById('build-redeploy').checked
+                })
+            });
+            if (!response.ok) {
+                const data = await response.json().catch(() => ({}));
+                throw new Error(data.error || 'Failed to save build');
+            }
+            this.showToast('Build saved', 'success');
+            this.loadSavedBuilds();
+        } catch (error) {
+            if (error.message !== 'Session expired') this.showToast(error.message, 'error');
+        }
+    },
+
+    // List the saved builds in the build dialog with their last run
+    async loadSavedBuilds() {
+        const list = document.getElementById('saved-builds');
+        try {
+            const response = await this.authFetch('/api/builds');
+            if (!response.ok) throw new Error('Failed to load builds');
+            this.savedBuilds = await response.json();
+            if (this.savedBuilds.length === 0) {
+                list.innerHTML = '<div class="text-muted">No saved builds</div>';
+                return;
+            }
+            const rows = this.savedBuilds.map(build => {
+                const run = build.lastRun;
+                let status = '<span class="text-muted">never run</span>';
+                if (build.running) status = 'running';
+                else if (run) status = `<span title="${this.escapeHtml(run.error || '')}">${run.success && !run.error ? 'succeeded' : 'failed'}</span> ${new Date(run.finished).toLocaleString()}`;
+                return `
+                <tr>
+                    <td><strong class="saved-build-name" onclick="App.editSavedBuild('${build.id}')">${this.escapeHtml(build.name)}</strong></td>
+                    <td>${this.escapeHtml(build.tag)}</td>
+                    <td>${status}</td>
+                    <td>
+                        <button class="btn btn-sm" onclick="App.runSavedBuild('${build.id}')" ${build.running ? 'disabled' : ''}>Run</button>
+                        <button class="btn btn-sm btn-danger" onclick="App.deleteSavedBuild('${build.id}')">Delete</button>
+                    </td>
+                </tr>`;
+            }).join('');
+            list.innerHTML = `<table class="layers-table"><tbody>${rows}</tbody></table>`;
+        } catch (error) {
+            if (error.message !== 'Session expired') {
+                list.innerHTML = '<div class="text-muted">Failed to load builds</div>';
+            }
+        }
+    },
+
+    // Fill the build dialog with a saved build
+    editSavedBuild(id) {
+        const build = (this.savedBuilds || []).find(build => build.id === id);
+        if (!build) return;
+        const set = (field, value) => { document.getElementById(field).value = value || ''; };
+        set('build-name', build.name);
+        set('build-url', build.url);
+        set('build-ref', build.ref);
+        set('build-context', build.context);
+        set('build-dockerfile', build.dockerfile);
+        set('build-tag', build.tag);
+        set('build-args', Object.entries(build.buildArgs || {}).map(([name, value]) => `${name}=${value}`).join('\n'));
+        document.getElementById('build-scheduled').checked = build.scheduled;
+        document.getElementById('build-watch-base').checked = build.watchBase;
+        document.getElementById('build-redeploy').checked = build.redeploy;
+    },
+
+    // Run a saved build in the background
+    async runSavedBuild(id) {
+        try {
+            const response = await this.authFetch(`/api/builds/${id}/run`, { method: 'POST' });
+            if (!response.ok) {
+                const data = await response.json().catch(() => ({}));
+                throw new Error(data.error || 'Failed to run build');
+            }
+            this.showToast('Build started', 'info');
+            this.loadSavedBuilds();
+        } catch (error) {
+            if (error.message !== 'Session expired') this.showToast(error.message, 'error');
+        }
+    },
+
+    // Delete a saved build and its history
+    deleteSavedBuild(id) {
+        this.confirmAction('Delete Build', 'Delete this saved build and its history? Its images are kept.', async () => {
+            try {
+                const response = await this.authFetch(`/api/builds/${id}`, { method: 'DELETE' });
+                if (!response.ok) throw new Error('Failed to delete build');
+                this.showToast('Build deleted', 'success');
+                this.loadSavedBuilds();
+            } catch (error) {
+                if (error.message !== 'Session expired') this.showToast('Failed to delete build', 'error');
+            }
+        });
+    },
+
+    // Build an image from a Git repository, showing the output as it streams
+    async buildImage() {
+        const output = document.getElementById('build-output');
+        output.textContent = '';
+        output.classList.remove('hidden');
+        const append = (line) => {
+            output.textContent += line + '\n';
+            output.scrollTop = output.scrollHeight;
+        };
+
+        const btn = document.querySelector('#build-form button[type="submit"]');
+        btn.disabled = true;
+        btn.textContent = 'Building...';
+        try {
+            const response = await this.authFetch('/api/images/build', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(this.buildRequest())
+            });
+            if (!response.ok) {
+                const data = await response.json().catch(() => ({}));
+                throw new Error(data.error || 'Failed to build image');
+            }
+
+            // Server-sent events: log lines, then done or error
+            const reader = response.body.getReader();
+            const decoder = new TextDecoder();
+            let buffer = '';
+            let result = null;
+            for (;;) {
+                const { value: chunk, done } = await reader.read();
+                if (done) break;
+                buffer += decoder.decode(chunk, { stream: true });
+                let end;
+                while ((end = buffer.indexOf('\n\n')) >= 0) {
+                    const message = buffer.slice(0, end);
+                    buffer = buffer.slice(end + 2);
+                    const event = (message.match(/^event: (.*)$/m) || [])[1];
+                    const data = (message.match(/^data: (.*)$/m) || [])[1];
+                    if (!event || !data) continue;
+                    const payload = JSON.parse(data);
+                    if (event === 'log') append(payload.line);
+                    else result = { event, ...payload };
+                }
+            }
+
+            if (result && result.event === 'done') {
+                append(`Built ${result.tag} (${result.id.slice(0, 12)})`);
+                this.showToast('Image built successfully', 'success');
+                this.loadImages();
+            } else {
+                throw new Error(result ? result.message : 'Build interrupted');
+            }
+        } catch (error) {
+            if (error.message !== 'Session expired') {
+                append(error.message);
+                this.showToast('Failed to build image', 'error');
+            }
+        } finally {
+            btn.disabled = false;
+            btn.textContent = 'Build';
+        }
+    },
+
     // Remove image
     removeImage(id) {
         this.confirmAction('Remove Image', 'Are you sure you want to remove this image?', async () => {
@@ -1938,14 +2550,35 @@ const App = {
         this.showModal('modal-confirm');
     },
 
+    // Destructive operations need a confirmation token: the first call
+    // returns it with the impact of the operation, the callback gets it once
+    // the user confirmed
+    async confirmDestructive(url, title, describe, callback) {
+        try {
+            const response = await this.authFetch(url, { method: 'POST' });
+            const data = await response.json().catch(() => ({}));
+            if (response.status !== 428 || !data.details) throw new Error(data.error || 'Request failed');
+            this.confirmAction(title, describe(data.details.impact || {}), () => callback(data.details.token));
+        } catch (error) {
+            if (error.message !== 'Session expired') this.showToast(error.message, 'error');
+        }
+    },
+
+    // Describes the running containers stopped by a reboot or shutdown
+    describeRunning(impact) {
+        const names = impact.runningContainers || [];
+        if (names.length === 0) return 'No containers are running.';
+        return `${names.length} running container(s) will be stopped: ${names.join(', ')}.`;
+    },
+
     // System reboot
-    async systemReboot() {
+    async systemReboot(token) {
         const btn = document.getElementById('system-reboot-btn');
         btn.disabled = true;
         btn.textContent = 'Rebooting...';
 
         try {
-            const response = await this.authFetch('/api/system/reboot', { method: 'POST' });
+            const response = await this.authFetch('/api/system/reboot', { method: 'POST', headers: { 'X-Confirm-Token': token } });
             if (!response.ok) throw new Error('Failed to reboot');
             this.showToast('System is rebooting...', 'success');
         } catch (error) {
@@ -1956,13 +2589,13 @@ const App = {
     },
 
     // System shutdown
-    async systemShutdown() {
+    async systemShutdown(token) {
         const btn = document.getElementById('system-shutdown-btn');
         btn.disabled = true;
         btn.textContent = 'Shutting down...';
 
         try {
-            const response = await this.authFetch('/api/system/shutdown', { method: 'POST' });
+            const response = await this.authFetch('/api/system/shutdown', { method: 'POST', headers: { 'X-Confirm-Token': token } });
             if (!response.ok) throw new Error('Failed to shutdown');
             this.showToast('System is shutting down...', 'success');
         } catch (error) {
@@ -2057,7 +2690,8 @@ const App = {
 
         // Connect WebSocket with CSRF token
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const wsUrl = `${protocol}//${window.location.host}/api/containers/${this.currentContainerId}/terminal?ws_token=${encodeURIComponent(wsToken)}`;
+        const endpoint = this.terminalAttach ? 'attach' : 'terminal';
+        const wsUrl = `${protocol}//${window.location.host}${appUrl(`/api/containers/${this.currentContainerId}/${endpoint}`)}?ws_token=${encodeURIComponent(wsToken)}`;
 
         try {
             const socket = new WebSocket(wsUrl);
@@ -2069,9 +2703,17 @@ const App = {
                 this.containerTerminalReconnectAttempts = 0;
 
                 if (this.terminal) this.terminal.writeln('Connected!\r\n');
+
+                // Attached programs redraw at their new size
+                if (this.terminalAttach && this.terminal) {
+                    this.terminal.writeln('Detach with Ctrl+P, Ctrl+Q\r\n');
+                    socket.send(JSON.stringify({ type: 'resize', cols: this.terminal.cols, rows: this.terminal.rows }));
+                }
             };
 
             socket.onmessage = (event) => {
+                if (this.handleTerminalTransfer('container', event.data)) return;
+
                 // Write to terminal
                 if (this.terminal) this.terminal.write(event.data);
             };
@@ -2086,7 +2728,7 @@ const App = {
                 if (isModalOpen && !event.wasClean && event.code !== 1000) {
                     this.scheduleContainerTerminalReconnect();
                 } else if (this.terminal) {
-                    this.terminal.writeln('\r\n\x1b[31mConnection closed\x1b[0m');
+                    this.terminal.writeln(`\r\n\x1b[31m${event.reason || 'Connection closed'}\x1b[0m`);
                 }
             };
 
@@ -2095,12 +2737,21 @@ const App = {
                 // onclose will be called after onerror, so reconnection will be handled there
             };
 
-            // Setup terminal input handler with localStorage history support
-            this.setupTerminalInputHandler(
-                this.terminal,
-                this.terminalSocket,
-                (cmd) => this.addToHistoryLocal(cmd)
-            );
+            if (this.terminalAttach) {
+                // Keys go to the attached program as they are
+                this.terminal.onData(data => {
+                    if (socket.readyState === WebSocket.OPEN) {
+                        socket.send(JSON.stringify({ type: 'stdin', data: data }));
+                    }
+                });
+            } else {
+                // Setup terminal input handler with localStorage history support
+                this.setupTerminalInputHandler(
+                    this.terminal,
+                    this.terminalSocket,
+                    (cmd) => this.addToHistoryLocal(cmd, this.terminalSocket)
+                );
+            }
 
         } catch (error) {
             this.terminal.writeln('\r\n\x1b[31mFailed to connect: ' + error.message + '\x1b[0m');
@@ -2131,8 +2782,12 @@ const App = {
         }, delay);
     },
 
-    // Open terminal
-    async openTerminal(containerId) {
+    // Open terminal, or attach to the main process of the container
+    async openTerminal(containerId, attach = false) {
+        this.terminalAttach = attach;
+        document.querySelectorAll('#modal-terminal .terminal-transfer').forEach(btn => {
+            btn.style.display = attach ? 'none' : '';
+        });
         this.showModal('modal-terminal');
         const container = document.getElementById('terminal-container');
         container.innerHTML = '<p style="color: var(--text-secondary); padding: 20px;">Loading terminal...</p>';
@@ -2213,6 +2868,82 @@ const App = {
         this.closeModal('modal-terminal');
     },
 
+    // Upload a file to the working directory of a terminal session ('host' or 'container')
+    uploadTerminalFile(which) {
+        const input = document.createElement('input');
+        input.type = 'file';
+        input.onchange = () => {
+            const file = input.files[0];
+            if (!file) return;
+            if (file.size > 4 * 1024 * 1024) {
+                this.showToast('Files of up to 4 MB can be transferred', 'error');
+                return;
+            }
+            const reader = new FileReader();
+            reader.onload = () => {
+                // Data URL: "data:<type>;base64,<data>"
+                const data = String(reader.result).split(',')[1] || '';
+                this.sendTerminalTransfer(which, { type: 'upload', path: file.name, data });
+            };
+            reader.readAsDataURL(file);
+        };
+        input.click();
+    },
+
+    // Download a file of a terminal session, relative to its working directory
+    downloadTerminalFile(which) {
+        const path = prompt('File to download (absolute, or relative to the current directory):');
+        if (path) this.sendTerminalTransfer(which, { type: 'download', path });
+    },
+
+    // Send a transfer request over the WebSocket of a terminal session
+    sendTerminalTransfer(which, msg) {
+        const socket = which === 'host' ? this.hostTerminalSocket : this.terminalSocket;
+        if (!socket || socket.readyState !== WebSocket.OPEN) {
+            this.showToast('Terminal is not connected', 'error');
+            return;
+        }
+        this.pendingTransfer = msg;
+        if (msg.type === 'upload') this.showToast(`Uploading ${msg.path}...`, 'info');
+        socket.send(JSON.stringify(msg));
+    },
+
+    // Handle the reply to a file transfer, returns false for other messages
+    handleTerminalTransfer(which, data) {
+        if (typeof data !== 'string' || !data.startsWith('{"type":"transfer"')) return false;
+        let msg;
+        try {
+            msg = JSON.parse(data);
+        } catch {
+            return false;
+        }
+        const pending = this.pendingTransfer;
+        this.pendingTransfer = null;
+
+        if (msg.error) {
+            if (msg.error === 'File already exists' && pending && pending.type === 'upload' &&
+                confirm(`${msg.path} already exists. Replace it?`)) {
+                this.sendTerminalTransfer(which, { ...pending, overwrite: true });
+                return true;
+            }
+            this.showToast(`${msg.op === 'upload' ? 'Upload' : 'Download'} failed: ${msg.error}`, 'error');
+            return true;
+        }
+        if (msg.op === 'upload') {
+            this.showToast(`Uploaded to ${msg.path}`, 'success');
+            return true;
+        }
+
+        const bytes = Uint8Array.from(atob(msg.data || ''), c => c.charCodeAt(0));
+        const url = URL.createObjectURL(new Blob([bytes], { type: 'application/octet-stream' }));
+        const link = document.createElement('a');
+        link.href = url;
+        link.download = msg.path.split('/').pop();
+        link.click();
+        setTimeout(() => URL.revokeObjectURL(url), 1000);
+        return true;
+    },
+
     // Modal helpers
     showModal(id) {
         document.getElementById(id).classList.remove('hidden');
@@ -2276,6 +3007,30 @@ const App = {
         return parts.join(' ');
     },
 
+    // Raspberry Pi under-voltage and throttling flags, hidden on other hosts
+    renderThrottling(throttling) {
+        const item = document.getElementById('info-throttling-item');
+        const value = document.getElementById('info-throttling');
+        if (!throttling) {
+            item.style.display = 'none';
+            return;
+        }
+        const flags = [
+            ['underVoltage', 'under-voltage'],
+            ['frequencyCapped', 'frequency capped'],
+            ['throttled', 'throttled'],
+            ['softTempLimit', 'soft temperature limit'],
+        ];
+        const now = flags.filter(([key]) => throttling[key]).map(([, label]) => label);
+        const past = flags.filter(([key]) => !throttling[key] && throttling[key + 'Occurred']).map(([, label]) => label);
+        let text = now.length > 0 ? now.join(', ') : 'OK';
+        if (past.length > 0) text += ` (since boot: ${past.join(', ')})`;
+        value.textContent = text;
+        value.className = 'info-value' + (now.length > 0 ? ' throttling-active' : past.length > 0 ? ' throttling-past' : '');
+        value.title = `get_throttled=${throttling.raw}`;
+        item.style.display = '';
+    },
+
     renderTempItem(t) {
         // Temperature thresholds for Orange Pi RV2 (SpacemiT K1)
         // < 50°C = cool, 50-65°C = normal, 65-75°C = warm, 75-85°C = hot, > 85°C = critical
@@ -2288,11 +3043,28 @@ const App = {
         return `
             <div class="temp-item">
                 <span class="temp-label">${t.label}</span>
+                ${this.renderSparkline(t.history)}
                 <span class="temp-value ${tempClass}">${t.temp.toFixed(1)}°C</span>
             </div>
         `;
     },
 
+    // Small SVG trend line of recent samples, empty with fewer than two
+    renderSparkline(values) {
+        if (!values || values.length < 2) return '';
+        const width = 60, height = 20;
+        const min = Math.min(...values), max = Math.max(...values);
+        const range = max - min || 1;
+        const points = values.map((v, i) => {
+            const x = (i / (values.length - 1)) * width;
+            const y = height - 1 - ((v - min) / range) * (height - 2);
+            return `${x.toFixed(1)},${y.toFixed(1)}`;
+        }).join(' ');
+        return `<svg class="temp-sparkline" width="${width}" height="${height}" viewBox="0 0 ${width} ${height}" aria-hidden="true">
+                    <polyline points="${points}" fill="none" stroke="currentColor" stroke-width="1.5"/>
+                </svg>`;
+    },
+
     renderStorageDevice(device) {
         const sensorsHtml = device.sensors.map(t => this.renderTempItem(t)).join('');
         return `
@@ -2304,6 +3076,19 @@ const App = {
     },
 
     renderDiskItem(disk) {
+        if (disk.stale) {
+            return `
+            <div class="disk-item">
+                <div class="disk-header">
+                    <span class="disk-device">${disk.device} <span class="pool-type">${disk.network}</span></span>
+                    <span class="disk-mount">${disk.mountPoint}</span>
+                </div>
+                <div class="disk-info pool-errors">
+                    <span>Stale: not responding</span>
+                </div>
+            </div>
+        `;
+        }
         const usedPercent = disk.total > 0 ? ((disk.used / disk.total) * 100).toFixed(1) : 0;
         let progressClass = 'normal';
         if (usedPercent > 90) progressClass = 'critical';
@@ -2312,7 +3097,7 @@ const App = {
         return `
             <div class="disk-item">
                 <div class="disk-header">
-                    <span class="disk-device">${disk.device}</span>
+                    <span class="disk-device">${disk.device}${this.formatDiskMapping(disk.mapping)}${disk.network ? ` <span class="pool-type">${disk.network}</span>` : ''}</span>
                     <span class="disk-mount">${disk.mountPoint}</span>
                 </div>
                 <div class="disk-bar">
@@ -2322,10 +3107,114 @@ const App = {
                     <span>${this.formatBytes(disk.used)} / ${this.formatBytes(disk.total)}</span>
                     <span>${usedPercent}%</span>
                 </div>
+                ${disk.network ? '' : `<div class="disk-info">
+                    <span>${this.formatDiskIO(disk)}</span>
+                </div>`}
+            </div>
+        `;
+    },
+
+    // ZFS pool or btrfs filesystem with health, scrub and device errors
+    renderPoolItem(pool) {
+        const usedPercent = pool.total > 0 ? ((pool.used / pool.total) * 100).toFixed(1) : 0;
+        let progressClass = 'normal';
+        if (usedPercent > 90) progressClass = 'critical';
+        else if (usedPercent > 75) progressClass = 'warning';
+
+        const health = pool.health ? ` · <span class="pool-health ${pool.health === 'ONLINE' ? 'ok' : 'bad'}">${pool.health}</span>` : '';
+        const errors = (pool.devices || [])
+            .filter(d => d.readErrors + d.writeErrors + d.checksumErrors > 0)
+            .map(d => `${d.name}: ${d.readErrors} read, ${d.writeErrors} write, ${d.checksumErrors} checksum`);
+
+        return `
+            <div class="disk-item">
+                <div class="disk-header">
+                    <span class="disk-device">${pool.name} <span class="pool-type">${pool.type}</span>${health}</span>
+                    <span class="disk-mount">${pool.mountPoint || ''}</span>
+                </div>
+                <div class="disk-bar">
+                    <div class="disk-bar-fill ${progressClass}" style="width: ${usedPercent}%"></div>
+                </div>
+                <div class="disk-info">
+                    <span>${this.formatBytes(pool.used)} / ${this.formatBytes(pool.total)}</span>
+                    <span>${usedPercent}%</span>
+                </div>
+                <div class="disk-info">
+                    <span>${this.formatScrub(pool.scrub)}</span>
+                    <span>${(pool.devices || []).length} devices</span>
+                </div>
+                ${errors.length > 0 ? `<div class="disk-info pool-errors">${errors.join('; ')}</div>` : ''}
             </div>
         `;
     },
 
+    formatScrub(scrub) {
+        if (!scrub) return 'Scrub: unknown';
+        const when = scrub.time ? ` ${new Date(scrub.time).toLocaleString()}` : '';
+        switch (scrub.state) {
+            case 'running': return `Scrub: ${(scrub.progress || 0).toFixed(1)}% done, since${when}`;
+            case 'finished': return `Scrub: ${scrub.errors} errors,${when}`;
+            case 'canceled': return `Scrub: canceled${when}`;
+            default: return 'Scrub: never';
+        }
+    },
+
+    // md array with its state, members and a running resync or recovery
+    renderRaidItem(array) {
+        let state = array.state;
+        if (array.devices > 0) state += ` [${array.active}/${array.devices}] ${array.status}`;
+        if (array.readOnly) state += ' read-only';
+        const health = array.degraded
+            ? '<span class="pool-health bad">DEGRADED</span>'
+            : `<span class="pool-health ${array.state === 'active' ? 'ok' : 'bad'}">${state}</span>`;
+        const members = array.members.map(m => {
+            const label = m.state === 'active' ? m.name : `${m.name} (${m.state})`;
+            return m.state === 'faulty' ? `<span class="pool-health bad">${label}</span>` : label;
+        }).join(', ');
+
+        let sync = '';
+        if (array.sync) {
+            const s = array.sync;
+            sync = s.pending
+                ? `${s.action} pending`
+                : `${s.action} ${s.progress.toFixed(1)}%` +
+                  (s.speed ? ` · ${this.formatBytes(s.speed)}/s` : '') +
+                  (s.remaining ? ` · ${this.formatUptime(s.remaining)} left` : '');
+        }
+
+        return `
+            <div class="disk-item">
+                <div class="disk-header">
+                    <span class="disk-device">${array.name} <span class="pool-type">${array.level || 'md'}</span> · ${health}</span>
+                    <span class="disk-mount">${array.size ? this.formatBytes(array.size) : ''}</span>
+                </div>
+                ${array.sync && !array.sync.pending ? `
+                <div class="disk-bar">
+                    <div class="disk-bar-fill warning" style="width: ${array.sync.progress}%"></div>
+                </div>` : ''}
+                <div class="disk-info">
+                    <span>${members}</span>
+                    <span>${sync}</span>
+                </div>
+            </div>
+        `;
+    },
+
+    // LVM or LUKS tag of a device-mapper disk, with the devices below it
+    formatDiskMapping(mapping) {
+        if (!mapping) return '';
+        const type = mapping.type ? mapping.type.toUpperCase() : mapping.kernel;
+        const backing = mapping.backing && mapping.backing.length > 0 ? ` on ${mapping.backing.join(', ')}` : '';
+        return ` <span class="pool-type" title="${mapping.kernel} (${mapping.name})">${type}${backing}</span>`;
+    },
+
+    // Read and write throughput and utilization of a disk
+    formatDiskIO(disk) {
+        const read = this.formatBytes(disk.readBytesPerSec || 0);
+        const write = this.formatBytes(disk.writeBytesPerSec || 0);
+        return `R ${read}/s · W ${write}/s · ${(disk.utilization || 0).toFixed(0)}% busy`;
+    },
+
     // Update system
     updateInfo: null,
     updatePollingInterval: null,
@@ -2828,7 +3717,7 @@ const App = {
 
     // Download file
     downloadFile(path, name) {
-        window.location.href = `/api/files/download?path=${encodeURIComponent(path)}`;
+        window.location.href = appUrl(`/api/files/download?path=${encodeURIComponent(path)}`);
     },
 
     // Confirm delete
@@ -2882,8 +3771,7 @@ const App = {
             });
 
             if (!response.ok) {
-                const error = await response.text();
-                throw new Error(error || 'Failed to create folder');
+                throw new Error(await responseError(response, 'Failed to create folder'));
             }
 
             this.showToast('Folder created', 'success');
@@ -2917,8 +3805,7 @@ const App = {
             });
 
             if (!response.ok) {
-                const error = await response.text();
-                throw new Error(error || 'Failed to create file');
+                throw new Error(await responseError(response, 'Failed to create file'));
             }
 
             this.showToast('File created', 'success');
@@ -2952,8 +3839,7 @@ const App = {
             });
 
             if (!response.ok) {
-                const error = await response.text();
-                throw new Error(error || 'Failed to rename');
+                throw new Error(await responseError(response, 'Failed to rename'));
             }
 
             this.showToast('Renamed successfully', 'success');
@@ -3029,8 +3915,7 @@ const App = {
                 // Cache miss - fetch from server
                 const response = await this.authFetch(`/api/files/read?path=${encodeURIComponent(path)}`);
                 if (!response.ok) {
-                    const error = await response.text();
-                    throw new Error(error || 'Failed to load file');
+                    throw new Error(await responseError(response, 'Failed to load file'));
                 }
 
                 fileData = await response.json();
@@ -3039,7 +3924,7 @@ const App = {
                 // Handle streaming for large files
                 if (fileData.streamingRequired && fileData.encoding === 'stream') {
                     // For large binary files, use streaming endpoint
-                    fileData.streamUrl = `/api/files/stream?path=${encodeURIComponent(path)}`;
+                    fileData.streamUrl = appUrl(`/api/files/stream?path=${encodeURIComponent(path)}`);
                     console.log('[FileViewer] Large file detected, using streaming mode');
                 }
 
@@ -3202,8 +4087,7 @@ const App = {
             });
 
             if (!response.ok) {
-                const error = await response.text();
-                throw new Error(error || 'Failed to save file');
+                throw new Error(await responseError(response, 'Failed to save file'));
             }
 
             const result = await response.json();
@@ -3241,7 +4125,7 @@ document.addEventListener('DOMContentLoaded', () => App.init());
 // Register Service Worker for PWA
 if ('serviceWorker' in navigator) {
     window.addEventListener('load', () => {
-        navigator.serviceWorker.register('/static/sw.js')
+        navigator.serviceWorker.register(appUrl('/static/sw.js'))
             .then((registration) => {
                 console.log('SW registered:', registration.scope);
             })
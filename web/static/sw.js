@@ -1,6 +1,9 @@
 // Service Worker for PodmanView PWA
 const CACHE_NAME = 'podmanview-v1';
 
+// URL prefix for subpath deployments, derived from this script's location
+const BASE_PATH = self.location.pathname.replace(/\/static\/sw\.js$/, '');
+
 // Static assets to cache
 const STATIC_ASSETS = [
     '/',
@@ -8,7 +11,7 @@ const STATIC_ASSETS = [
     '/static/js/app.js',
     '/static/img/logo.svg',
     '/static/img/favicon.ico'
-];
+].map((path) => BASE_PATH + path);
 
 // Install event - cache static assets
 self.addEventListener('install', (event) => {
@@ -39,14 +42,15 @@ self.addEventListener('activate', (event) => {
 // Fetch event - network first, fallback to cache for static assets
 self.addEventListener('fetch', (event) => {
     const url = new URL(event.request.url);
+    const path = url.pathname.startsWith(BASE_PATH) ? url.pathname.slice(BASE_PATH.length) || '/' : url.pathname;
 
     // Skip API requests - always go to network
-    if (url.pathname.startsWith('/api/')) {
+    if (path.startsWith('/api/')) {
         return;
     }
 
     // For static assets - network first, fallback to cache
-    if (url.pathname.startsWith('/static/') || url.pathname === '/') {
+    if (path.startsWith('/static/') || path === '/') {
         event.respondWith(
             fetch(event.request)
                 .then((response) => {
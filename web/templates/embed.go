@@ -4,3 +4,12 @@ import _ "embed"
 
 //go:embed index.html
 var IndexHTML []byte
+
+//go:embed docs.html
+var DocsHTML []byte
+
+//go:embed launcher.html
+var LauncherHTML []byte
+
+//go:embed share.html
+var ShareHTML []byte